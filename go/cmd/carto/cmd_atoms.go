@@ -0,0 +1,235 @@
+package main
+
+// cmd_atoms.go -- standalone atom extraction.
+//
+// Runs only the scan, chunk and fast-tier atom phases of the pipeline and
+// emits the resulting atoms as a stable JSON array. Deep analysis, synthesis
+// and storage are skipped entirely. With --no-llm, chunks are emitted
+// without summaries for a cheap structural map of the codebase.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/atoms"
+	"github.com/divyekant/carto/internal/chunker"
+	"github.com/divyekant/carto/internal/config"
+	"github.com/divyekant/carto/internal/llm"
+	"github.com/divyekant/carto/internal/scanner"
+)
+
+func atomsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "atoms <path>",
+		Short: "Extract per-atom summaries without deep analysis",
+		Long: `Scan, chunk and analyze a codebase with the fast tier only, emitting
+one record per code unit (function, type, class, ...).
+
+Deep analysis, synthesis and storage are skipped. Use --no-llm to emit
+chunks without summaries, which makes no LLM calls at all.
+
+Examples:
+  carto atoms . --json
+  carto atoms . --no-llm --json | jq '.data[].name'
+  carto atoms . --module api`,
+		Args: cobra.ExactArgs(1),
+		RunE: runAtoms,
+	}
+	cmd.Flags().Bool("no-llm", false, "Emit chunks without summaries (no LLM calls)")
+	cmd.Flags().String("module", "", "Only extract atoms from a single module")
+	return cmd
+}
+
+// atomRecord is the stable output shape of a single atom. Imports and
+// exports are always arrays (never null) so consumers can rely on them.
+type atomRecord struct {
+	Module    string   `json:"module"`
+	File      string   `json:"file"`
+	StartLine int      `json:"start_line"`
+	EndLine   int      `json:"end_line"`
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Summary   string   `json:"summary"`
+	Imports   []string `json:"imports"`
+	Exports   []string `json:"exports"`
+}
+
+func runAtoms(cmd *cobra.Command, args []string) error {
+	absPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("resolve path: %w", err)
+	}
+
+	noLLM, _ := cmd.Flags().GetBool("no-llm")
+	moduleFilter, _ := cmd.Flags().GetString("module")
+
+	cfg := config.Load()
+
+	var analyzer *atoms.Analyzer
+	if !noLLM {
+		apiKey := cfg.LLMApiKey
+		if apiKey == "" {
+			apiKey = cfg.AnthropicKey
+		}
+		if apiKey == "" && cfg.LLMProvider != "ollama" {
+			return newConfigError("no API key set (set LLM_API_KEY or ANTHROPIC_API_KEY, or use --no-llm)")
+		}
+
+		llmClient := llm.NewClient(llm.Options{
+			APIKey:          apiKey,
+			FastModel:       cfg.FastModel,
+			DeepModel:       cfg.DeepModel,
+			MaxConcurrent:   cfg.MaxConcurrent,
+			FastConcurrency: cfg.FastConcurrency,
+			DeepConcurrency: cfg.DeepConcurrency,
+			IsOAuth:         config.IsOAuthToken(apiKey),
+			BaseURL:         cfg.LLMBaseURL,
+			FastMaxTokens:   cfg.FastMaxTokens,
+			DeepMaxTokens:   cfg.DeepMaxTokens,
+			ExtraHeaders:    cfg.LLMHeaders,
+		})
+		analyzer = atoms.NewAnalyzer(llmClient, cfg.FastMaxTokens)
+		analyzer.SetSummaryDetail(cfg.SummaryDetail)
+	}
+
+	records, err := collectAtoms(cmd.Context(), absPath, moduleFilter, analyzer, cfg.MaxConcurrent)
+	if err != nil {
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+
+	writeEnvelopeHuman(cmd, records, nil, func() {
+		fmt.Printf("%s%sAtoms in %s%s\n\n", bold, gold, absPath, reset)
+		if len(records) == 0 {
+			fmt.Println("  No atoms found.")
+			return
+		}
+		for _, r := range records {
+			fmt.Printf("  %s:%d-%d  %s%s%s %s\n", r.File, r.StartLine, r.EndLine, stone, r.Kind, reset, r.Name)
+			if r.Summary != "" {
+				fmt.Printf("    %s\n", truncateText(r.Summary, 120))
+			}
+		}
+		fmt.Printf("\n  %sTotal:%s %d atom(s)\n", bold, reset, len(records))
+	})
+
+	return nil
+}
+
+// collectAtoms scans rootPath, chunks every file and, when analyzer is
+// non-nil, summarizes each chunk with the fast tier. A nil analyzer yields
+// structural records only. Records are sorted by file then start line so
+// the output is stable across runs.
+func collectAtoms(ctx context.Context, rootPath, moduleFilter string, analyzer *atoms.Analyzer, maxWorkers int) ([]atomRecord, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	scanResult, err := scanner.Scan(rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	records := []atomRecord{}
+	for _, mod := range scanResult.Modules {
+		if moduleFilter != "" && mod.Name != moduleFilter {
+			continue
+		}
+
+		var chunks []atoms.Chunk
+		for _, relPath := range mod.Files {
+			absPath := filepath.Join(scanResult.Root, relPath)
+			code, readErr := os.ReadFile(absPath)
+			if readErr != nil {
+				log.Printf("atoms: warning: cannot read %s: %v", relPath, readErr)
+				continue
+			}
+
+			lang := scanner.DetectLanguage(filepath.Base(relPath))
+			fileChunks, chunkErr := chunker.ChunkFile(relPath, code, lang, nil)
+			// On a parser panic the whole file comes back as one chunk,
+			// already logged by the chunker.
+			if chunkErr != nil && !errors.Is(chunkErr, chunker.ErrParsePanic) {
+				log.Printf("atoms: warning: chunking failed for %s: %v", relPath, chunkErr)
+				continue
+			}
+			for _, c := range fileChunks {
+				chunks = append(chunks, atoms.Chunk{
+					Name:      c.Name,
+					Kind:      c.Kind,
+					Language:  c.Language,
+					FilePath:  filepath.ToSlash(relPath),
+					StartLine: c.StartLine,
+					EndLine:   c.EndLine,
+					Code:      c.Code,
+					Doc:       c.Doc,
+				})
+			}
+		}
+
+		if analyzer == nil {
+			for _, c := range chunks {
+				records = append(records, atomRecord{
+					Module:    mod.Name,
+					File:      c.FilePath,
+					StartLine: c.StartLine,
+					EndLine:   c.EndLine,
+					Kind:      c.Kind,
+					Name:      c.Name,
+					Imports:   []string{},
+					Exports:   []string{},
+				})
+			}
+			continue
+		}
+
+		analyzed, batchErr := analyzer.AnalyzeBatchCtx(ctx, chunks, maxWorkers, nil)
+		if batchErr != nil {
+			return nil, fmt.Errorf("analyze atoms: %w", batchErr)
+		}
+		for _, a := range analyzed {
+			records = append(records, atomRecord{
+				Module:    mod.Name,
+				File:      a.FilePath,
+				StartLine: a.StartLine,
+				EndLine:   a.EndLine,
+				Kind:      a.Kind,
+				Name:      a.Name,
+				Summary:   a.Summary,
+				Imports:   nonNilStrings(a.Imports),
+				Exports:   nonNilStrings(a.Exports),
+			})
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].File != records[j].File {
+			return records[i].File < records[j].File
+		}
+		if records[i].StartLine != records[j].StartLine {
+			return records[i].StartLine < records[j].StartLine
+		}
+		return records[i].Name < records[j].Name
+	})
+
+	return records, nil
+}
+
+// nonNilStrings returns s, or an empty slice when s is nil.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/divyekant/carto/internal/atoms"
+	"github.com/divyekant/carto/internal/llm"
+)
+
+// countingLLM is a fake atoms.LLMClient that counts calls and returns a
+// canned fast-tier response.
+type countingLLM struct {
+	calls atomic.Int32
+}
+
+func (c *countingLLM) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error) {
+	c.calls.Add(1)
+	return json.RawMessage(`{"clarified_code": "", "summary": "Does a thing.", "imports": ["fmt"], "exports": null}`), nil
+}
+
+func writeAtomsProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/demo\n\ngo 1.21\n",
+		"main.go": `package main
+
+func main() {
+	helper()
+}
+
+func helper() {}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCollectAtoms_WithLLM(t *testing.T) {
+	dir := writeAtomsProject(t)
+	fake := &countingLLM{}
+
+	records, err := collectAtoms(context.Background(), dir, "", atoms.NewAnalyzer(fake), 2)
+	if err != nil {
+		t.Fatalf("collectAtoms: %v", err)
+	}
+	if len(records) == 0 {
+		t.Fatal("expected at least one atom")
+	}
+	if int(fake.calls.Load()) != len(records) {
+		t.Errorf("expected one LLM call per atom, got %d calls for %d atoms", fake.calls.Load(), len(records))
+	}
+	for _, r := range records {
+		if r.Summary != "Does a thing." {
+			t.Errorf("expected summary from LLM, got %q", r.Summary)
+		}
+		if r.Exports == nil {
+			t.Error("exports must never be nil")
+		}
+	}
+}
+
+func TestAtomsCmd_NoLLM_JSONShape(t *testing.T) {
+	withCleanEnv(t)
+	dir := writeAtomsProject(t)
+
+	// Any request reaching this server means an LLM call was attempted.
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Error(w, "unexpected", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	t.Setenv("ANTHROPIC_API_KEY", "sk-test")
+	t.Setenv("LLM_BASE_URL", srv.URL)
+
+	out, err := execCmd(t, testRoot(atomsCmd()), []string{"atoms", dir, "--no-llm", "--json"})
+	if err != nil {
+		t.Fatalf("atoms --no-llm failed: %v\n%s", err, out)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("expected zero LLM calls with --no-llm, got %d", hits.Load())
+	}
+
+	var env struct {
+		OK   bool             `json:"ok"`
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	if !env.OK || len(env.Data) == 0 {
+		t.Fatalf("expected ok envelope with atoms, got %s", out)
+	}
+
+	for _, key := range []string{"module", "file", "start_line", "end_line", "kind", "name", "summary", "imports", "exports"} {
+		if _, ok := env.Data[0][key]; !ok {
+			t.Errorf("atom record missing key %q", key)
+		}
+	}
+	if _, ok := env.Data[0]["imports"].([]any); !ok {
+		t.Errorf("imports should be a JSON array, got %T", env.Data[0]["imports"])
+	}
+
+	// Records are sorted by file then start line.
+	prev := -1.0
+	for _, rec := range env.Data {
+		if rec["file"] != "main.go" {
+			continue
+		}
+		line := rec["start_line"].(float64)
+		if line < prev {
+			t.Errorf("records not sorted by start_line: %v after %v", line, prev)
+		}
+		prev = line
+	}
+}
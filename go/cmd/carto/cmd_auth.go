@@ -184,13 +184,8 @@ func runAuthSetKey(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unknown provider %q — valid: anthropic, openai, memories, github, jira, linear, notion, slack, server", provider)
 	}
 
-	if config.ConfigPath == "" {
-		printWarn("No persisted config path is set. Key stored for this session only.")
-		printWarn("To persist, run 'carto serve --projects-dir <dir>' which sets the config path.")
-	} else {
-		if err := config.Save(cfg); err != nil {
-			return fmt.Errorf("save config: %w", err)
-		}
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("save config: %w", err)
 	}
 
 	masked := config.MaskSecret(key)
@@ -2,21 +2,32 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/manifest"
+	"github.com/divyekant/carto/internal/sources"
 )
 
 func completionsCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "completions <bash|zsh|fish|powershell>",
-		Short: "Generate shell completion scripts",
+		Use:     "completions <bash|zsh|fish|powershell>",
+		Aliases: []string{"completion"},
+		Short:   "Generate shell completion scripts",
 		Long: `Generate autocompletion scripts for your shell.
 
 To load completions:
 
   bash:  source <(carto completions bash)
   zsh:   carto completions zsh > "${fpath[1]}/_carto"
-  fish:  carto completions fish > ~/.config/fish/completions/carto.fish`,
+  fish:  carto completions fish > ~/.config/fish/completions/carto.fish
+
+Besides commands and flags, the scripts complete indexed project names
+(from PROJECTS_DIR), config keys, source types and query tiers.`,
 		Args:      cobra.ExactArgs(1),
 		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -36,3 +47,78 @@ To load completions:
 		},
 	}
 }
+
+// indexedProjects returns the names of the indexed projects under
+// projectsDir: each directory holding a non-empty manifest, plus the
+// project name recorded in that manifest when it differs. Sorted.
+func indexedProjects(projectsDir string) []string {
+	if projectsDir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(projectsDir)
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		mf, err := manifest.Load(filepath.Join(projectsDir, entry.Name()))
+		if err != nil || mf.IsEmpty() {
+			continue
+		}
+		seen[entry.Name()] = true
+		if mf.Project != "" {
+			seen[mf.Project] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withPrefix returns the candidates that start with prefix.
+func withPrefix(candidates []string, prefix string) []string {
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// completeProjects completes indexed project names, for --project flags.
+func completeProjects(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return withPrefix(indexedProjects(os.Getenv("PROJECTS_DIR")), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProjectArg completes a project name as the first argument only.
+func completeProjectArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeProjects(cmd, args, toComplete)
+}
+
+// completeProjectAndSourceType completes "<project> <type>" arguments.
+func completeProjectAndSourceType(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completeProjects(cmd, args, toComplete)
+	case 1:
+		return withPrefix(sources.ConfigurableTypes, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// fixedCompletion completes a flag or argument from a fixed set of values.
+func fixedCompletion(values ...string) cobra.CompletionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return withPrefix(values, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
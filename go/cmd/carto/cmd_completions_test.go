@@ -2,10 +2,15 @@ package main
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/manifest"
 )
 
 func newRootWithCompletions(t *testing.T) *cobra.Command {
@@ -86,3 +91,75 @@ func TestCompletionsCmd_NoArgs(t *testing.T) {
 		t.Error("expected error when no shell specified")
 	}
 }
+
+func TestCompletionsCmd_EveryShell(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		for _, name := range []string{"completions", "completion"} {
+			root := newRootWithCompletions(t)
+			var buf bytes.Buffer
+			root.SetOut(&buf)
+			root.SetArgs([]string{name, shell})
+			if err := root.Execute(); err != nil {
+				t.Fatalf("%s %s: %v", name, shell, err)
+			}
+			if buf.Len() == 0 {
+				t.Errorf("%s %s: expected a completion script", name, shell)
+			}
+		}
+	}
+}
+
+// completeArgs runs cobra's hidden completion command for args and returns
+// the candidates it prints, without the trailing directive line.
+func completeArgs(t *testing.T, root *cobra.Command, args ...string) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	root.SetOut(&buf)
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs(append([]string{cobra.ShellCompRequestCmd}, args...))
+	if err := root.Execute(); err != nil {
+		t.Fatalf("complete %v: %v", args, err)
+	}
+	var out []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line != "" && !strings.HasPrefix(line, ":") {
+			out = append(out, strings.SplitN(line, "\t", 2)[0])
+		}
+	}
+	return out
+}
+
+func TestCompletion_ProjectNames(t *testing.T) {
+	projectsDir := t.TempDir()
+	t.Setenv("PROJECTS_DIR", projectsDir)
+	for _, name := range []string{"billing", "backend"} {
+		mf := manifest.NewManifest(filepath.Join(projectsDir, name), name)
+		mf.UpdateFile("main.go", "abc", 10)
+		if err := mf.Save(); err != nil {
+			t.Fatalf("save manifest: %v", err)
+		}
+	}
+	// A directory without an index is not offered.
+	os.MkdirAll(filepath.Join(projectsDir, "scratch"), 0o755)
+
+	root := testRoot(hotspotsCmd(), queryCmd(), sourcesCmd(), configCmdGroup())
+
+	if got, want := completeArgs(t, root, "hotspots", ""), []string{"backend", "billing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hotspots <project> = %v, want %v", got, want)
+	}
+	if got, want := completeArgs(t, root, "query", "--project", "bi"), []string{"billing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("query --project bi = %v, want %v", got, want)
+	}
+	if got, want := completeArgs(t, root, "query", "--tier", ""), []string{"mini", "standard", "full", "auto"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("query --tier = %v, want %v", got, want)
+	}
+	if got, want := completeArgs(t, root, "sources", "rm", "billing", "j"), []string{"jira"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sources rm billing j = %v, want %v", got, want)
+	}
+	if got, want := completeArgs(t, root, "config", "set", "summary_detail", ""), []string{"brief", "normal", "detailed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("config set summary_detail = %v, want %v", got, want)
+	}
+	if got := completeArgs(t, root, "config", "set", "prof"); len(got) != 0 {
+		t.Errorf("config set offers read-only keys: %v", got)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/divyekant/carto/internal/atoms"
 	"github.com/divyekant/carto/internal/config"
 )
 
@@ -24,15 +26,17 @@ file set by the serve --projects-dir flag).`,
 	cmd.AddCommand(configSetCmd())
 	cmd.AddCommand(configValidateCmd())
 	cmd.AddCommand(configPathCmd())
+	cmd.AddCommand(configProfileCmd())
 	return cmd
 }
 
 func configGetCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "get [key]",
-		Short: "Show configuration values",
-		Args:  cobra.MaximumNArgs(1),
-		RunE:  runConfigGet,
+		Use:               "get [key]",
+		Short:             "Show configuration values",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeConfigGet,
+		RunE:              runConfigGet,
 	}
 }
 
@@ -40,7 +44,7 @@ func runConfigGet(cmd *cobra.Command, args []string) error {
 	cfg := config.Load()
 	profile := resolveProfile(cmd)
 
-	verboseLog(cmd, "loading config for profile %q, file: %q", profile, config.ConfigPath)
+	verboseLog(cmd, "loading config for profile %q, file: %q", profile, config.EffectiveConfigPath())
 
 	// Non-sensitive config fields for display.
 	// Sensitive fields (keys/tokens) are never printed in plain text.
@@ -49,12 +53,27 @@ func runConfigGet(cmd *cobra.Command, args []string) error {
 		"fast_model":       cfg.FastModel,
 		"deep_model":       cfg.DeepModel,
 		"max_concurrent":   fmt.Sprintf("%d", cfg.MaxConcurrent),
+		"fast_concurrency": fmt.Sprintf("%d", cfg.FastConcurrency),
+		"deep_concurrency": fmt.Sprintf("%d", cfg.DeepConcurrency),
+		"max_cost":         fmt.Sprintf("%g", cfg.MaxCost),
+		"otlp_endpoint":    cfg.OTLPEndpoint,
+		"signal_cache_ttl": cfg.SignalCacheDuration().String(),
+		"auto_tier_min_score": fmt.Sprintf("%g", cfg.AutoTierMinScore),
 		"fast_max_tokens":  fmt.Sprintf("%d", cfg.FastMaxTokens),
 		"deep_max_tokens":  fmt.Sprintf("%d", cfg.DeepMaxTokens),
 		"llm_provider":     cfg.LLMProvider,
 		"llm_base_url":     cfg.LLMBaseURL,
+		"llm_headers":      config.FormatHeaders(config.MaskHeaders(cfg.LLMHeaders)),
 		"profile":          profile,
 		"audit_log":        cfg.AuditLogFile,
+		"chunk_kinds":      strings.Join(cfg.ChunkKinds, ","),
+		"chunk_min_lines":  fmt.Sprintf("%d", cfg.ChunkMinLines),
+		"history_since":    cfg.HistorySince,
+		"history_max_commits": fmt.Sprintf("%d", cfg.HistoryMaxCommits),
+		"atom_instructions":      cfg.Instructions.Atom,
+		"module_instructions":    cfg.Instructions.Module,
+		"synthesis_instructions": cfg.Instructions.Synthesis,
+		"summary_detail":         cfg.SummaryDetail,
 		// Show credential presence (masked, not the actual values).
 		"anthropic_key":    maskPresence(cfg.AnthropicKey),
 		"llm_api_key":      maskPresence(cfg.LLMApiKey),
@@ -81,33 +100,21 @@ func runConfigGet(cmd *cobra.Command, args []string) error {
 
 	writeEnvelopeHuman(cmd, configMap, nil, func() {
 		fmt.Printf("%s%sConfiguration%s  profile: %s\n\n", bold, gold, reset, profile)
-		if config.ConfigPath != "" {
-			fmt.Printf("  %sfile:%s %s\n\n", gold, reset, config.ConfigPath)
-		}
+		fmt.Printf("  %sfile:%s %s\n\n", gold, reset, config.EffectiveConfigPath())
 
 		// ── Non-sensitive settings ────────────────────────────────────────
 		fmt.Printf("  %s%sSettings%s\n", bold, gold, reset)
-		settingKeys := []string{
-			"llm_provider", "fast_model", "deep_model",
-			"max_concurrent", "fast_max_tokens", "deep_max_tokens",
-			"llm_base_url", "memories_url", "profile", "audit_log",
-		}
-		for _, k := range settingKeys {
+		for _, k := range configSettingKeys {
 			v := configMap[k]
 			if v == "" {
 				v = dimmed("(not set)")
 			}
-			fmt.Printf("  %-18s %s\n", k, v)
+			fmt.Printf("  %-19s %s\n", k, v)
 		}
 
 		// ── Credential presence ───────────────────────────────────────────
 		fmt.Printf("\n  %s%sCredentials%s  (masked — use 'carto auth status' for details)\n", bold, gold, reset)
-		credKeys := []string{
-			"anthropic_key", "llm_api_key", "memories_key",
-			"github_token", "jira_token", "linear_token",
-			"notion_token", "slack_token", "server_token",
-		}
-		for _, k := range credKeys {
+		for _, k := range configCredentialKeys {
 			v := configMap[k]
 			fmt.Printf("  %-18s %s\n", k, v)
 		}
@@ -117,6 +124,62 @@ func runConfigGet(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// configSettingKeys are the non-secret keys 'config get' lists, in order.
+var configSettingKeys = []string{
+	"llm_provider", "fast_model", "deep_model",
+	"max_concurrent", "fast_concurrency", "deep_concurrency",
+	"fast_max_tokens", "deep_max_tokens", "max_cost",
+	"llm_base_url", "llm_headers", "memories_url", "profile", "audit_log",
+	"chunk_kinds", "chunk_min_lines",
+	"history_since", "history_max_commits",
+	"atom_instructions", "module_instructions", "synthesis_instructions",
+	"summary_detail", "otlp_endpoint", "signal_cache_ttl",
+	"auto_tier_min_score",
+}
+
+// configCredentialKeys are the secret keys 'config get' shows masked.
+var configCredentialKeys = []string{
+	"anthropic_key", "llm_api_key", "memories_key",
+	"github_token", "jira_token", "linear_token",
+	"notion_token", "slack_token", "server_token",
+}
+
+// readOnlyConfigKeys are settings 'config set' cannot change.
+var readOnlyConfigKeys = map[string]bool{"profile": true, "audit_log": true}
+
+// configValueChoices are the allowed values of enumerated settings.
+var configValueChoices = map[string][]string{
+	"llm_provider":   {"anthropic", "openai", "ollama"},
+	"summary_detail": {atoms.SummaryBrief, atoms.SummaryNormal, atoms.SummaryDetailed},
+}
+
+// completeConfigGet completes the key argument of 'config get'.
+func completeConfigGet(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	keys := append(append([]string{}, configSettingKeys...), configCredentialKeys...)
+	return withPrefix(keys, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigSet completes the key, then for enumerated settings the
+// value, of 'config set'.
+func completeConfigSet(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		var keys []string
+		for _, k := range configSettingKeys {
+			if !readOnlyConfigKeys[k] {
+				keys = append(keys, k)
+			}
+		}
+		return withPrefix(keys, toComplete), cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		return withPrefix(configValueChoices[args[0]], toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
 // maskPresence returns a masked form of the key if set, or a dimmed placeholder.
 func maskPresence(val string) string {
 	if val == "" {
@@ -139,14 +202,41 @@ Writable keys:
   fast_model        LLM model used for high-volume fast operations
   deep_model        LLM model used for low-volume deep analysis
   max_concurrent    Maximum concurrent LLM calls (integer ≥ 1)
+  fast_concurrency  Maximum concurrent fast-tier LLM calls (0 means max_concurrent)
+  deep_concurrency  Maximum concurrent deep-tier LLM calls (0 means max_concurrent)
   fast_max_tokens   Max output tokens for fast model calls (integer)
   deep_max_tokens   Max output tokens for deep model calls (integer)
+  max_cost          Stop index runs once estimated LLM spend crosses this many
+                    dollars (0 means no cap)
   llm_provider      LLM provider: anthropic | openai | ollama
   llm_base_url      Base URL for OpenAI-compatible providers
+  llm_headers       Extra headers for every LLM request, as comma-separated
+                    Name=Value pairs, e.g. "X-Corp-Auth=abc,User-Agent=corp/1.0"
+                    (auth, version and beta headers can't be overridden)
+  chunk_kinds       Comma-separated chunk kinds to analyze, e.g. function,class,method
+                    (empty analyzes every kind)
+  chunk_min_lines   Merge or skip declarations shorter than this many lines (0 disables)
+  history_since     Only extract git history newer than this git date, e.g. "1 year ago"
+                    (empty means "6 months ago")
+  history_max_commits
+                    Commits of git history to extract per file (0 means 50)
+  atom_instructions, module_instructions, synthesis_instructions
+                    Extra guidance appended to the atom, module or synthesis
+                    analysis prompt, e.g. "focus on security implications"
+  summary_detail    Atom summary length: brief (one line) | normal (2-3 sentences)
+                    | detailed (a paragraph); brief also lowers the token cap
+  otlp_endpoint     OpenTelemetry collector to send index run trace spans to over
+                    OTLP/HTTP, e.g. http://localhost:4318 (empty disables tracing)
+  signal_cache_ttl  How long index runs reuse artifacts fetched from external sources,
+                    e.g. "30m" (empty means 6h, 0 disables the cache)
+  auto_tier_min_score
+                    Top result score a --tier auto query settles for before moving
+                    to the next tier (0 means 0.5)
 
 Use 'carto auth set-key' to store API keys and tokens securely.`,
-		Args: cobra.ExactArgs(2),
-		RunE: runConfigSet,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeConfigSet,
+		RunE:              runConfigSet,
 	}
 }
 
@@ -171,6 +261,38 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 		if cfg.MaxConcurrent < 1 {
 			return fmt.Errorf("max_concurrent must be ≥ 1")
 		}
+	case "fast_concurrency":
+		n, err := fmt.Sscanf(value, "%d", &cfg.FastConcurrency)
+		if n != 1 || err != nil {
+			return fmt.Errorf("fast_concurrency must be an integer")
+		}
+		if cfg.FastConcurrency < 0 {
+			return fmt.Errorf("fast_concurrency must be ≥ 0")
+		}
+	case "deep_concurrency":
+		n, err := fmt.Sscanf(value, "%d", &cfg.DeepConcurrency)
+		if n != 1 || err != nil {
+			return fmt.Errorf("deep_concurrency must be an integer")
+		}
+		if cfg.DeepConcurrency < 0 {
+			return fmt.Errorf("deep_concurrency must be ≥ 0")
+		}
+	case "max_cost":
+		n, err := fmt.Sscanf(value, "%g", &cfg.MaxCost)
+		if n != 1 || err != nil {
+			return fmt.Errorf("max_cost must be a number")
+		}
+		if cfg.MaxCost < 0 {
+			return fmt.Errorf("max_cost must be ≥ 0")
+		}
+	case "auto_tier_min_score":
+		n, err := fmt.Sscanf(value, "%g", &cfg.AutoTierMinScore)
+		if n != 1 || err != nil {
+			return fmt.Errorf("auto_tier_min_score must be a number")
+		}
+		if cfg.AutoTierMinScore < 0 {
+			return fmt.Errorf("auto_tier_min_score must be ≥ 0")
+		}
 	case "fast_max_tokens":
 		n, err := fmt.Sscanf(value, "%d", &cfg.FastMaxTokens)
 		if n != 1 || err != nil {
@@ -191,6 +313,56 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 		cfg.LLMProvider = value
 	case "llm_base_url":
 		cfg.LLMBaseURL = value
+	case "otlp_endpoint":
+		if value != "" && !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+			return fmt.Errorf("otlp_endpoint must start with http:// or https://")
+		}
+		cfg.OTLPEndpoint = value
+	case "signal_cache_ttl":
+		if value != "" {
+			if d, err := time.ParseDuration(value); err != nil || d < 0 {
+				return fmt.Errorf("signal_cache_ttl must be a duration like \"6h\"")
+			}
+		}
+		cfg.SignalCacheTTL = value
+	case "llm_headers":
+		headers, err := config.ParseHeaders(value)
+		if err != nil {
+			return fmt.Errorf("llm_headers: %w", err)
+		}
+		cfg.LLMHeaders = headers
+		value = config.FormatHeaders(config.MaskHeaders(headers)) // don't echo proxy credentials
+	case "chunk_kinds":
+		cfg.ChunkKinds = config.SplitList(value)
+	case "chunk_min_lines":
+		n, err := fmt.Sscanf(value, "%d", &cfg.ChunkMinLines)
+		if n != 1 || err != nil {
+			return fmt.Errorf("chunk_min_lines must be an integer")
+		}
+		if cfg.ChunkMinLines < 0 {
+			return fmt.Errorf("chunk_min_lines must be ≥ 0")
+		}
+	case "history_since":
+		cfg.HistorySince = value
+	case "history_max_commits":
+		n, err := fmt.Sscanf(value, "%d", &cfg.HistoryMaxCommits)
+		if n != 1 || err != nil {
+			return fmt.Errorf("history_max_commits must be an integer")
+		}
+		if cfg.HistoryMaxCommits < 0 {
+			return fmt.Errorf("history_max_commits must be ≥ 0")
+		}
+	case "atom_instructions":
+		cfg.Instructions.Atom = value
+	case "module_instructions":
+		cfg.Instructions.Module = value
+	case "synthesis_instructions":
+		cfg.Instructions.Synthesis = value
+	case "summary_detail":
+		if !atoms.IsSummaryDetail(value) {
+			return fmt.Errorf("summary_detail must be brief, normal or detailed")
+		}
+		cfg.SummaryDetail = value
 	default:
 		return fmt.Errorf("unknown or read-only config key: %q — run 'carto config get' for all keys, 'carto auth set-key' for credentials", key)
 	}
@@ -274,7 +446,7 @@ Useful for debugging which config file Carto is reading.`,
 func runConfigPath(cmd *cobra.Command, _ []string) error {
 	cfgDir := config.ConfigDir()
 	cfgFile := config.DefaultConfigFilePath()
-	active := config.ConfigPath
+	active := config.ActiveConfigPath()
 
 	type paths struct {
 		ConfigDir     string `json:"config_dir"`
@@ -301,11 +473,8 @@ func runConfigPath(cmd *cobra.Command, _ []string) error {
 		fileStatus := checkMark(out.FileExists)
 		fmt.Printf("  %s Config dir:    %s\n", dirStatus, cfgDir)
 		fmt.Printf("  %s Default file:  %s\n", fileStatus, cfgFile)
-		if active != "" {
-			fmt.Printf("  %s Active file:   %s\n", checkMark(true), active)
-		} else {
-			fmt.Printf("    Active file:   %s\n", dimmed("(none — using environment variables only)"))
-		}
+		_, activeErr := os.Stat(active)
+		fmt.Printf("  %s Active file:   %s\n", checkMark(activeErr == nil), active)
 	})
 	return nil
 }
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/config"
+)
+
+// profileEntry is one row of 'carto config profile list'.
+type profileEntry struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+}
+
+func configProfileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage named config profiles",
+		Long: `Named profiles are full config snapshots stored next to the config file,
+e.g. one for a personal Anthropic key, one for a work gateway and one for a
+local Ollama setup.
+
+'carto config profile use <name>' switches the default; '--profile <name>'
+(or CARTO_PROFILE) selects one for a single invocation. 'config set' writes
+to the active profile. The "default" profile is the flat config file, so
+nothing changes until a profile is added and selected.`,
+	}
+	cmd.AddCommand(configProfileAddCmd())
+	cmd.AddCommand(configProfileListCmd())
+	cmd.AddCommand(configProfileUseCmd())
+	cmd.AddCommand(configProfileRemoveCmd())
+	return cmd
+}
+
+func configProfileAddCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "add <name>",
+		Short:   "Save the current configuration as a named profile",
+		Args:    cobra.ExactArgs(1),
+		Example: "  carto config profile add work\n  carto config profile add local --use",
+		RunE:    runConfigProfileAdd,
+	}
+	cmd.Flags().Bool("force", false, "Overwrite an existing profile")
+	cmd.Flags().Bool("use", false, "Switch to the new profile")
+	return cmd
+}
+
+func runConfigProfileAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := config.ValidateProfileName(name); err != nil {
+		return err
+	}
+	force, _ := cmd.Flags().GetBool("force")
+	if config.ProfileExists(name) && !force {
+		return fmt.Errorf("profile %q already exists (use --force to overwrite)", name)
+	}
+
+	if err := config.SaveProfile(name, config.Load()); err != nil {
+		return fmt.Errorf("save profile: %w", err)
+	}
+	use, _ := cmd.Flags().GetBool("use")
+	if use {
+		if err := config.UseProfile(name); err != nil {
+			return fmt.Errorf("use profile: %w", err)
+		}
+	}
+
+	writeEnvelopeHuman(cmd, map[string]any{"profile": name, "status": "saved", "active": use}, nil, func() {
+		fmt.Printf("%s✓%s Saved profile %s\n", green, reset, name)
+		if use {
+			fmt.Printf("  Now using profile %s\n", name)
+		} else {
+			fmt.Printf("  Switch with: carto config profile use %s\n", name)
+		}
+	})
+	logAuditEvent(cmd, "ok", "", map[string]any{"profile": name})
+	return nil
+}
+
+func configProfileListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List config profiles",
+		Args:  cobra.NoArgs,
+		RunE:  runConfigProfileList,
+	}
+}
+
+func runConfigProfileList(cmd *cobra.Command, _ []string) error {
+	names, err := config.ListProfiles()
+	if err != nil {
+		return fmt.Errorf("list profiles: %w", err)
+	}
+	active := config.ActiveProfile()
+	if active == "" {
+		active = config.DefaultProfile
+	}
+
+	entries := []profileEntry{{Name: config.DefaultProfile, Active: active == config.DefaultProfile}}
+	for _, n := range names {
+		entries = append(entries, profileEntry{Name: n, Active: n == active})
+	}
+
+	data := map[string]any{"active": active, "profiles": entries}
+	writeEnvelopeHuman(cmd, data, nil, func() {
+		fmt.Printf("%s%sConfig Profiles%s\n\n", bold, gold, reset)
+		for _, e := range entries {
+			marker := " "
+			if e.Active {
+				marker = green + "*" + reset
+			}
+			label := e.Name
+			if e.Name == config.DefaultProfile {
+				label += " " + dimmed("(flat config file)")
+			}
+			fmt.Printf("  %s %s\n", marker, label)
+		}
+	})
+	return nil
+}
+
+func configProfileUseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <name>",
+		Short: "Switch the default config profile",
+		Long: `Makes <name> the profile used when neither --profile nor CARTO_PROFILE
+is set. 'carto config profile use default' switches back to the flat
+config file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runConfigProfileUse,
+	}
+}
+
+func runConfigProfileUse(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := config.UseProfile(name); err != nil {
+		return newNotFoundError(err.Error())
+	}
+
+	writeEnvelopeHuman(cmd, map[string]string{"profile": name, "status": "active"}, nil, func() {
+		fmt.Printf("%s✓%s Using profile %s\n", green, reset, name)
+	})
+	logAuditEvent(cmd, "ok", "", map[string]any{"profile": name})
+	return nil
+}
+
+func configProfileRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Delete a config profile",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runConfigProfileRemove,
+	}
+}
+
+func runConfigProfileRemove(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := config.RemoveProfile(name); err != nil {
+		return newNotFoundError(err.Error())
+	}
+
+	writeEnvelopeHuman(cmd, map[string]string{"profile": name, "status": "removed"}, nil, func() {
+		fmt.Printf("%s✓%s Removed profile %s\n", green, reset, name)
+	})
+	logAuditEvent(cmd, "ok", "", map[string]any{"profile": name})
+	return nil
+}
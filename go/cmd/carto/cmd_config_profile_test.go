@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/config"
+)
+
+// profileRoot is a test root that applies --profile the way main's
+// PersistentPreRunE does.
+func profileRoot(t *testing.T) *cobra.Command {
+	t.Helper()
+	orig := config.ProfileName
+	t.Cleanup(func() { config.ProfileName = orig })
+	config.ProfileName = ""
+
+	root := testRoot(configCmdGroup())
+	root.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
+		return applyConfigFlag(cmd)
+	}
+	return root
+}
+
+func configGetValue(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := execCmd(t, profileRoot(t), append([]string{"config", "get", "fast_model", "--json"}, args...))
+	if err != nil {
+		t.Fatalf("config get %v: %v\n%s", args, err, out)
+	}
+	var env struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, out)
+	}
+	return env.Data["fast_model"]
+}
+
+func TestConfigProfileCmd_AddListUseRemove(t *testing.T) {
+	withCleanEnv(t)
+	t.Setenv("CARTO_FAST_MODEL", "")
+
+	run := func(args ...string) string {
+		t.Helper()
+		out, err := execCmd(t, profileRoot(t), append([]string{"config"}, args...))
+		if err != nil {
+			t.Fatalf("config %v: %v\n%s", args, err, out)
+		}
+		return out
+	}
+
+	run("set", "fast_model", "flat-model")
+	run("profile", "add", "work")
+	if _, err := execCmd(t, profileRoot(t), []string{"config", "profile", "add", "work"}); err == nil {
+		t.Error("adding an existing profile without --force should fail")
+	}
+
+	// Edits go to the active profile only.
+	run("profile", "use", "work")
+	run("set", "fast_model", "work-model")
+	if got := configGetValue(t); got != "work-model" {
+		t.Errorf("fast_model with work active = %q", got)
+	}
+	run("profile", "use", "default")
+	if got := configGetValue(t); got != "flat-model" {
+		t.Errorf("fast_model with default active = %q", got)
+	}
+
+	out := run("profile", "list", "--json")
+	var env struct {
+		Data struct {
+			Active   string         `json:"active"`
+			Profiles []profileEntry `json:"profiles"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("parse list: %v\n%s", err, out)
+	}
+	want := []profileEntry{{Name: "default", Active: true}, {Name: "work"}}
+	if env.Data.Active != "default" || len(env.Data.Profiles) != 2 || env.Data.Profiles[0] != want[0] || env.Data.Profiles[1] != want[1] {
+		t.Errorf("list = %+v", env.Data)
+	}
+
+	run("profile", "remove", "work")
+	if _, err := execCmd(t, profileRoot(t), []string{"config", "profile", "use", "work"}); err == nil {
+		t.Error("using a removed profile should fail")
+	}
+}
+
+func TestConfigProfileCmd_PerInvocationFlag(t *testing.T) {
+	withCleanEnv(t)
+	t.Setenv("CARTO_FAST_MODEL", "")
+
+	config.Save(config.Config{FastModel: "flat-model", MaxConcurrent: 1})
+	config.SaveProfile("local", config.Config{FastModel: "llama3", MaxConcurrent: 1})
+
+	if got := configGetValue(t, "--profile", "local"); got != "llama3" {
+		t.Errorf("--profile local: fast_model = %q", got)
+	}
+	// The flag applies to one invocation; the default stays the flat file.
+	if got := configGetValue(t); got != "flat-model" {
+		t.Errorf("without --profile: fast_model = %q", got)
+	}
+
+	_, err := execCmd(t, profileRoot(t), []string{"config", "get", "--profile", "missing"})
+	if err == nil || !strings.Contains(err.Error(), `profile "missing" not found`) {
+		t.Errorf("unknown --profile: err = %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/duplicates"
+	"github.com/divyekant/carto/internal/scanner"
+)
+
+func duplicatesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "duplicates <path>",
+		Short: "Find copy-pasted code across files and modules",
+		Long: `Fingerprint every function, method and type in the codebase and report
+groups that are identical once identifiers, literals, comments and
+whitespace are ignored, i.e. code that was copied and then renamed or
+reformatted. Works from the source tree alone; no LLM or Memories calls.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runDuplicates,
+	}
+	cmd.Flags().Int("min-lines", duplicates.DefaultMinLines, "Ignore code units shorter than this many lines")
+	cmd.Flags().Bool("cross-module", false, "Only report duplicates that span more than one module")
+	cmd.Flags().IntP("limit", "n", 20, "Number of clusters to show (0 for all)")
+	return cmd
+}
+
+func runDuplicates(cmd *cobra.Command, args []string) error {
+	absPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("resolve path: %w", err)
+	}
+	minLines, _ := cmd.Flags().GetInt("min-lines")
+	crossModule, _ := cmd.Flags().GetBool("cross-module")
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	result, err := scanner.Scan(absPath)
+	if err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	clusters := duplicates.Find(absPath, result.Modules, duplicates.Options{MinLines: minLines})
+	if crossModule {
+		kept := clusters[:0]
+		for _, cl := range clusters {
+			if cl.CrossModule {
+				kept = append(kept, cl)
+			}
+		}
+		clusters = kept
+	}
+	total := len(clusters)
+	if limit > 0 && len(clusters) > limit {
+		clusters = clusters[:limit]
+	}
+	if clusters == nil {
+		clusters = []duplicates.Cluster{}
+	}
+
+	data := struct {
+		Total    int                  `json:"total"`
+		Clusters []duplicates.Cluster `json:"clusters"`
+	}{total, clusters}
+
+	writeEnvelopeHuman(cmd, data, nil, func() {
+		fmt.Printf("%s%sDuplicates in %s%s\n\n", bold, gold, absPath, reset)
+
+		if len(clusters) == 0 {
+			fmt.Println("  No duplicated code found.")
+			return
+		}
+
+		for i, cl := range clusters {
+			scope := "same module"
+			if cl.CrossModule {
+				scope = amber + "cross-module" + reset
+			}
+			fmt.Printf("  %s#%d%s  %d copies, %d lines  %s  %s\n", bold, i+1, reset, len(cl.Members), cl.Lines, scope, stoneText(cl.Fingerprint))
+			for _, m := range cl.Members {
+				fmt.Printf("    %-50s %-20s %s\n",
+					truncateText(fmt.Sprintf("%s:%d-%d", m.FilePath, m.StartLine, m.EndLine), 50),
+					truncateText(m.Module, 20), m.Name)
+			}
+			fmt.Println()
+		}
+		if total > len(clusters) {
+			fmt.Printf("  %s… %d more (use --limit 0 to show all)%s\n\n", stone, total-len(clusters), reset)
+		}
+		fmt.Printf("  %sTotal:%s %d duplicate cluster(s)\n", bold, reset, total)
+	})
+
+	return nil
+}
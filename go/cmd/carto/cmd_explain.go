@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/config"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+func explainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "explain <project>",
+		Short:             "Print an overview of an indexed project: blueprint, patterns and module intents",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProjectArg,
+		RunE:              runExplain,
+	}
+	cmd.Flags().Int("patterns", 5, "Number of patterns to show; 0 for all")
+	return cmd
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	project := args[0]
+	maxPatterns, _ := cmd.Flags().GetInt("patterns")
+	if maxPatterns < 0 {
+		err := newConfigError("--patterns must not be negative")
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+
+	cfg := config.Load()
+	memoriesClient := storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey)
+	store := storage.NewStore(memoriesClient, project, cfg.MemoriesNamespace)
+
+	overview, err := store.Overview(maxPatterns)
+	if err != nil {
+		err = newConnectionError("failed to read blueprint: " + err.Error())
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+	if !overview.Indexed() {
+		err := newNotFoundError(fmt.Sprintf("project %q is not indexed yet; run carto index first", project))
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+
+	writeEnvelopeHuman(cmd, overview, nil, func() {
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "%s%s%s%s\n\n", bold, gold, project, reset)
+
+		if overview.Blueprint != "" {
+			fmt.Fprintf(out, "%s\n\n", strings.TrimSpace(overview.Blueprint))
+		} else {
+			fmt.Fprintf(out, "%sNo blueprint stored; the last index run did not reach synthesis.%s\n\n", stone, reset)
+		}
+
+		if len(overview.Patterns) > 0 {
+			fmt.Fprintf(out, "%sPatterns%s\n", bold, reset)
+			for _, p := range overview.Patterns {
+				fmt.Fprintf(out, "  • %s\n", p)
+			}
+			fmt.Fprintln(out)
+		}
+
+		if len(overview.Modules) > 0 {
+			width := 0
+			for _, m := range overview.Modules {
+				width = max(width, len(m.Name))
+			}
+			fmt.Fprintf(out, "%sModules%s\n", bold, reset)
+			for _, m := range overview.Modules {
+				fmt.Fprintf(out, "  %s%-*s%s  %s\n", green, width, m.Name, reset, m.Intent)
+			}
+		}
+	})
+
+	return nil
+}
@@ -2,13 +2,15 @@ package main
 
 // cmd_export.go -- export index data as NDJSON.
 //
-// Streams memories from the Memories store for a given project, optionally
+// Reads memories from the Memories store for a given project, optionally
 // filtered by layer. Default output is NDJSON (one JSON object per line)
-// for piping. With --json, outputs an envelope with export count.
+// for piping, sorted by source so exports of the same index diff cleanly.
+// With --json, outputs an envelope with export count.
 
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/spf13/cobra"
 
@@ -35,6 +37,7 @@ Examples:
 	cmd.Flags().StringP("project", "p", "", "Project name (required)")
 	cmd.Flags().String("layer", "", "Filter to specific layer (atoms, wiring, zones, blueprint, patterns)")
 	cmd.MarkFlagRequired("project")
+	cmd.RegisterFlagCompletionFunc("project", completeProjects)
 
 	return cmd
 }
@@ -59,9 +62,9 @@ func runExport(cmd *cobra.Command, _ []string) error {
 	client := storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey)
 
 	// Build source prefix filter.
-	sourcePrefix := "carto/" + project + "/"
+	sourcePrefix := storage.ProjectPrefix(cfg.MemoriesNamespace, project)
 	if layer != "" {
-		sourcePrefix = "carto/" + project + "/layer:" + layer
+		sourcePrefix += "layer:" + layer
 	}
 
 	// Stream mode (default) vs envelope mode (--json).
@@ -70,7 +73,7 @@ func runExport(cmd *cobra.Command, _ []string) error {
 	const pageSize = 100
 	offset := 0
 	exported := 0
-	enc := json.NewEncoder(cmd.OutOrStdout())
+	var entries []exportEntry
 
 	for {
 		results, err := client.ListBySource(sourcePrefix, pageSize, offset)
@@ -88,15 +91,13 @@ func runExport(cmd *cobra.Command, _ []string) error {
 		}
 
 		if !jsonMode {
-			// Stream NDJSON: one entry per line.
 			for _, r := range results {
-				entry := exportEntry{
+				entries = append(entries, exportEntry{
 					ID:       r.ID,
 					Text:     r.Text,
 					Source:   r.Source,
 					Metadata: r.Meta,
-				}
-				enc.Encode(entry) //nolint:errcheck
+				})
 			}
 		}
 
@@ -108,6 +109,19 @@ func runExport(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
+	// NDJSON: one entry per line, in a stable order regardless of the
+	// order Memories returned them in.
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Source != entries[j].Source {
+			return entries[i].Source < entries[j].Source
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	for _, entry := range entries {
+		enc.Encode(entry) //nolint:errcheck
+	}
+
 	if jsonMode {
 		// Envelope mode: summary only.
 		data := map[string]any{
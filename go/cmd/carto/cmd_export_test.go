@@ -151,3 +151,36 @@ func TestExportCmd_LayerFilter(t *testing.T) {
 		t.Errorf("expected source filter to contain 'layer:atoms', got %q", capturedSource)
 	}
 }
+
+func TestExportCmd_SortsBySource(t *testing.T) {
+	withCleanEnv(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"memories": []map[string]any{
+				{"id": 3, "text": "zones", "source": "carto/myapp/web/layer:zones"},
+				{"id": 2, "text": "atom b", "source": "carto/myapp/api/layer:atoms"},
+				{"id": 1, "text": "atom a", "source": "carto/myapp/api/layer:atoms"},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	t.Setenv("MEMORIES_URL", srv.URL)
+
+	out, err := execCmd(t, buildRootCmd(), []string{"export", "--project", "myapp", "--pretty"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var texts []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var entry exportEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue // stderr summary line
+		}
+		texts = append(texts, entry.Text)
+	}
+	if got := strings.Join(texts, ","); got != "atom a,atom b,zones" {
+		t.Errorf("export order = %s, want atom a,atom b,zones", got)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/config"
+	"github.com/divyekant/carto/internal/history"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+func hotspotsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "hotspots <project>",
+		Short:             "Show the most-churned, most recently active files",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProjectArg,
+		RunE:              runHotspots,
+	}
+	cmd.Flags().IntP("limit", "n", 10, "Number of files to show")
+	return cmd
+}
+
+func runHotspots(cmd *cobra.Command, args []string) error {
+	project := args[0]
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	cfg := config.Load()
+	memoriesClient := storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey)
+	store := storage.NewStore(memoriesClient, project, cfg.MemoriesNamespace)
+
+	histories, err := store.RetrieveHistory()
+	if err != nil {
+		err = newConnectionError("failed to read history: " + err.Error())
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+
+	spots := history.RankHotspots(histories, time.Now(), limit)
+	if spots == nil {
+		spots = []history.Hotspot{}
+	}
+
+	writeEnvelopeHuman(cmd, spots, nil, func() {
+		fmt.Printf("%s%sHotspots in %s%s\n\n", bold, gold, project, reset)
+
+		if len(spots) == 0 {
+			fmt.Println("  No history found. Index the project first.")
+			return
+		}
+
+		fmt.Printf("  %-50s %-20s %6s %8s %s\n", "FILE", "MODULE", "CHURN", "AUTHORS", "LAST COMMIT")
+		fmt.Printf("  %-50s %-20s %6s %8s %s\n",
+			strings.Repeat("-", 50),
+			strings.Repeat("-", 20),
+			strings.Repeat("-", 6),
+			strings.Repeat("-", 8),
+			strings.Repeat("-", 11))
+
+		for _, h := range spots {
+			last := "-"
+			if !h.LastCommit.IsZero() {
+				last = h.LastCommit.Format("2006-01-02")
+			}
+			fmt.Printf("  %-50s %-20s %6.0f %8d %s\n", truncateText(h.FilePath, 50), truncateText(h.Module, 20), h.Churn, h.Authors, last)
+		}
+	})
+
+	return nil
+}
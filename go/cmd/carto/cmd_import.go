@@ -39,6 +39,7 @@ Examples:
 	cmd.Flags().StringP("project", "p", "", "Project name (required)")
 	cmd.Flags().String("strategy", "add", "Import strategy: add or replace")
 	cmd.MarkFlagRequired("project")
+	cmd.RegisterFlagCompletionFunc("project", completeProjects)
 
 	return cmd
 }
@@ -72,7 +73,7 @@ func runImport(cmd *cobra.Command, _ []string) error {
 			return nil
 		}
 
-		prefix := "carto/" + project + "/"
+		prefix := storage.ProjectPrefix(cfg.MemoriesNamespace, project)
 		deleted, err := client.DeleteBySource(prefix)
 		if err != nil {
 			return newConnectionError("failed to delete existing entries: " + err.Error())
@@ -109,7 +110,7 @@ func runImport(cmd *cobra.Command, _ []string) error {
 		// Ensure source has the project prefix.
 		source := rec.Source
 		if source == "" {
-			source = "carto/" + project + "/import"
+			source = storage.ProjectPrefix(cfg.MemoriesNamespace, project) + "import"
 		}
 
 		batch = append(batch, storage.Memory{
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -16,6 +20,7 @@ import (
 	"github.com/divyekant/carto/internal/pipeline"
 	"github.com/divyekant/carto/internal/sources"
 	"github.com/divyekant/carto/internal/storage"
+	"github.com/divyekant/carto/internal/tracing"
 )
 
 func indexCmd() *cobra.Command {
@@ -28,9 +33,38 @@ func indexCmd() *cobra.Command {
 	cmd.Flags().Bool("full", false, "Force full re-index")
 	cmd.Flags().String("module", "", "Index a single module")
 	cmd.Flags().Bool("incremental", false, "Only re-index changed files")
+	cmd.Flags().Bool("rehash-all", false, "With --incremental, hash every file instead of skipping those with unchanged size and mtime")
+	cmd.Flags().String("files-from", "", "Index only the files listed in this file, one path relative to the project per line ('-' reads stdin), instead of detecting changes")
+	cmd.Flags().Bool("only-changed-modules", false, "With --incremental, synthesize only changed modules and their wiring neighbors, updating the stored blueprint")
 	cmd.Flags().String("project", "", "Project name (defaults to directory name)")
+	cmd.RegisterFlagCompletionFunc("project", completeProjects)
 	cmd.Flags().Bool("all", false, "Re-index all projects")
 	cmd.Flags().Bool("changed", false, "Re-index only modified projects")
+	cmd.Flags().String("filter", "", "With --all or --changed, only projects whose name matches this glob (e.g. 'billing-*')")
+	cmd.Flags().StringArray("tag", nil, "With --all or --changed, only projects with this tag, as key=value (repeatable; all must match)")
+	cmd.Flags().StringArray("include", nil, "Only index files matching this glob (repeatable, e.g. '**/*.go')")
+	cmd.Flags().StringArray("exclude", nil, "Skip files matching this glob (repeatable, e.g. '**/generated/**')")
+	cmd.Flags().Bool("include-generated", false, "Analyze generated files (*.pb.go, 'Code generated ... DO NOT EDIT.') instead of skipping them")
+	cmd.Flags().Bool("include-submodules", false, "Index the git submodules listed in .gitmodules as separate modules instead of skipping them")
+	cmd.Flags().String("history-since", "", "Only extract git history newer than this git date, e.g. '1 year ago' (default from config, else '6 months ago')")
+	cmd.Flags().Bool("full-history", false, "Extract each file's entire git history instead of a recent window (dates stale zones for 'carto stale')")
+	cmd.Flags().Bool("modules-only", false, "Skip per-unit atom analysis; derive module analyses from unit names and kinds only (faster, cheaper, shallower)")
+	cmd.Flags().Bool("no-file-fallback", false, "Report files without declarations (e.g. only imports) as empty instead of analyzing each as one whole-file unit")
+	cmd.Flags().Bool("no-store-source", false, "Don't store each atom's original source code in Memories (summaries only)")
+	cmd.Flags().Bool("retry-failed", false, "Retry files skipped for failing to chunk or analyze on each of the last 2 runs")
+	cmd.Flags().Bool("save-phases", false, "Debugging: save atoms, module contexts and module analyses to .carto/debug for --resume-from")
+	cmd.Flags().String("resume-from", "", "Debugging: re-run only 'analysis' or 'synthesis' onward from the outputs saved by --save-phases; nothing is stored")
+	cmd.RegisterFlagCompletionFunc("resume-from", fixedCompletion(pipeline.ResumeAnalysis, pipeline.ResumeSynthesis))
+	cmd.Flags().String("atom-instructions", "", "Extra guidance appended to the atom analysis prompt (default from config)")
+	cmd.Flags().String("module-instructions", "", "Extra guidance appended to the module analysis prompt (default from config)")
+	cmd.Flags().String("synthesis-instructions", "", "Extra guidance appended to the system synthesis prompt (default from config)")
+	cmd.Flags().Int("history-max-commits", 0, "Commits of git history to extract per file (default from config, else 50)")
+	cmd.Flags().Duration("timeout", 0, "Stop the run after this long (e.g. 30m) and report what was indexed; 0 means no limit")
+	cmd.Flags().Bool("refresh-signals", false, "Fetch every external source again instead of reusing artifacts cached under .carto/signalcache")
+	cmd.Flags().Float64("max-cost", 0, "Stop the run once estimated LLM spend crosses this many dollars and report what was indexed (default from config; 0 means no cap)")
+	cmd.Flags().String("validate-links", "", "Check that wiring edges refer to known code units, and 'flag' (the default when given alone) or 'drop' those that don't")
+	cmd.Flags().Lookup("validate-links").NoOptDefVal = pipeline.LinksFlag
+	cmd.RegisterFlagCompletionFunc("validate-links", fixedCompletion(pipeline.LinksFlag, pipeline.LinksDrop))
 	return cmd
 }
 
@@ -41,6 +75,9 @@ func runIndex(cmd *cobra.Command, args []string) error {
 	if allFlag || changedFlag {
 		return runIndexAll(cmd, changedFlag)
 	}
+	if cmd.Flags().Changed("filter") || cmd.Flags().Changed("tag") {
+		return newConfigError("--filter and --tag require --all or --changed")
+	}
 
 	if len(args) == 0 {
 		return fmt.Errorf("path argument is required (or use --all / --changed)")
@@ -51,7 +88,11 @@ func runIndex(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("resolve path: %w", err)
 	}
 
-	cfg := config.Load()
+	// Per-project .carto/config.yaml overrides the global model settings.
+	cfg, err := config.ForProject(config.Load(), absPath)
+	if err != nil {
+		return newConfigError(err.Error())
+	}
 
 	// Determine API key — LLM_API_KEY takes priority, falls back to ANTHROPIC_API_KEY.
 	apiKey := cfg.LLMApiKey
@@ -61,13 +102,73 @@ func runIndex(cmd *cobra.Command, args []string) error {
 
 	if apiKey == "" && cfg.LLMProvider != "ollama" {
 		fmt.Fprintf(os.Stderr, "%serror:%s No API key set. Set LLM_API_KEY or ANTHROPIC_API_KEY.\n", red, reset)
-		return fmt.Errorf("API key not set")
+		return withCause(newConfigError("API key not set"), llm.ErrNoAPIKey)
 	}
 
 	full, _ := cmd.Flags().GetBool("full")
 	moduleFilter, _ := cmd.Flags().GetString("module")
 	incremental, _ := cmd.Flags().GetBool("incremental")
+	rehashAll, _ := cmd.Flags().GetBool("rehash-all")
+	onlyChangedModules, _ := cmd.Flags().GetBool("only-changed-modules")
 	projectName, _ := cmd.Flags().GetString("project")
+	includeGlobs, _ := cmd.Flags().GetStringArray("include")
+	excludeGlobs, _ := cmd.Flags().GetStringArray("exclude")
+	includeGenerated, _ := cmd.Flags().GetBool("include-generated")
+	includeSubmodules, _ := cmd.Flags().GetBool("include-submodules")
+	if cmd.Flags().Changed("history-since") {
+		cfg.HistorySince, _ = cmd.Flags().GetString("history-since")
+		if strings.TrimSpace(cfg.HistorySince) == "" {
+			return newConfigError("--history-since must not be empty")
+		}
+	}
+	fullHistory, _ := cmd.Flags().GetBool("full-history")
+	if fullHistory && cmd.Flags().Changed("history-since") {
+		return newConfigError("--full-history and --history-since cannot be used together")
+	}
+	modulesOnly, _ := cmd.Flags().GetBool("modules-only")
+	noFileFallback, _ := cmd.Flags().GetBool("no-file-fallback")
+	noStoreSource, _ := cmd.Flags().GetBool("no-store-source")
+	retryFailed, _ := cmd.Flags().GetBool("retry-failed")
+	savePhases, _ := cmd.Flags().GetBool("save-phases")
+	resumeFrom, _ := cmd.Flags().GetString("resume-from")
+	if resumeFrom != "" && resumeFrom != pipeline.ResumeAnalysis && resumeFrom != pipeline.ResumeSynthesis {
+		return newConfigError(fmt.Sprintf("--resume-from must be %s or %s", pipeline.ResumeAnalysis, pipeline.ResumeSynthesis))
+	}
+	validateLinks, _ := cmd.Flags().GetString("validate-links")
+	if validateLinks != "" && validateLinks != pipeline.LinksFlag && validateLinks != pipeline.LinksDrop {
+		return newConfigError(fmt.Sprintf("--validate-links must be %s or %s", pipeline.LinksFlag, pipeline.LinksDrop))
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if timeout < 0 {
+		return newConfigError("--timeout must not be negative")
+	}
+	if cmd.Flags().Changed("max-cost") {
+		cfg.MaxCost, _ = cmd.Flags().GetFloat64("max-cost")
+		if cfg.MaxCost < 0 {
+			return newConfigError("--max-cost must not be negative")
+		}
+	}
+	if cmd.Flags().Changed("atom-instructions") {
+		cfg.Instructions.Atom, _ = cmd.Flags().GetString("atom-instructions")
+	}
+	if cmd.Flags().Changed("module-instructions") {
+		cfg.Instructions.Module, _ = cmd.Flags().GetString("module-instructions")
+	}
+	if cmd.Flags().Changed("synthesis-instructions") {
+		cfg.Instructions.Synthesis, _ = cmd.Flags().GetString("synthesis-instructions")
+	}
+	if cmd.Flags().Changed("history-max-commits") {
+		cfg.HistoryMaxCommits, _ = cmd.Flags().GetInt("history-max-commits")
+		if cfg.HistoryMaxCommits < 1 {
+			return newConfigError("--history-max-commits must be ≥ 1")
+		}
+	}
+	var fileList []string
+	if filesFrom, _ := cmd.Flags().GetString("files-from"); filesFrom != "" {
+		if fileList, err = loadFileList(cmd, filesFrom); err != nil {
+			return newConfigError(fmt.Sprintf("--files-from: %v", err))
+		}
+	}
 
 	if projectName == "" {
 		projectName = filepath.Base(absPath)
@@ -80,20 +181,28 @@ func runIndex(cmd *cobra.Command, args []string) error {
 
 	// Create LLM client.
 	llmClient := llm.NewClient(llm.Options{
-		APIKey:        apiKey,
-		FastModel:     cfg.FastModel,
-		DeepModel:     cfg.DeepModel,
-		MaxConcurrent: cfg.MaxConcurrent,
-		IsOAuth:       config.IsOAuthToken(apiKey),
-		BaseURL:       cfg.LLMBaseURL,
+		APIKey:          apiKey,
+		FastModel:       cfg.FastModel,
+		DeepModel:       cfg.DeepModel,
+		MaxConcurrent:   cfg.MaxConcurrent,
+		FastConcurrency: cfg.FastConcurrency,
+		DeepConcurrency: cfg.DeepConcurrency,
+		IsOAuth:         config.IsOAuthToken(apiKey),
+		BaseURL:         cfg.LLMBaseURL,
+		FastMaxTokens:   cfg.FastMaxTokens,
+		DeepMaxTokens:   cfg.DeepMaxTokens,
+		ExtraHeaders:    cfg.LLMHeaders,
 	})
 
 	// Create Memories client.
 	memoriesClient := storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey)
 
-	// Create unified source registry and register git source.
-	registry := sources.NewRegistry()
-	registry.Register(sources.NewGitSource(absPath))
+	// Build the unified source registry from .carto/sources.yaml (if present)
+	// and auto-detected sources, reusing recently fetched artifacts.
+	srcCfg, _ := sources.LoadSourcesConfig(absPath)
+	registry := projectRegistry(projectName, absPath, srcCfg)
+	refreshSignals, _ := cmd.Flags().GetBool("refresh-signals")
+	registry.SetCache(sources.NewCache(absPath, cfg.SignalCacheDuration(), refreshSignals))
 
 	// Progress display state.
 	spinIdx := 0
@@ -114,39 +223,131 @@ func runIndex(cmd *cobra.Command, args []string) error {
 	if moduleFilter != "" {
 		fmt.Printf("  module filter: %s\n", moduleFilter)
 	}
-	if incremental {
+	if len(includeGlobs) > 0 {
+		fmt.Printf("  include: %s\n", strings.Join(includeGlobs, ", "))
+	}
+	if len(excludeGlobs) > 0 {
+		fmt.Printf("  exclude: %s\n", strings.Join(excludeGlobs, ", "))
+	}
+	if resumeFrom != "" {
+		fmt.Printf("  mode: resume from %s (saved phase outputs; nothing is stored)\n", resumeFrom)
+	} else if fileList != nil {
+		fmt.Printf("  mode: listed files (%d)\n", len(fileList))
+	} else if incremental {
 		fmt.Printf("  mode: incremental\n")
 	} else if full {
 		fmt.Printf("  mode: full\n")
 	}
 	fmt.Println()
 
+	stopTracing, err := startTracing(cfg.OTLPEndpoint)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
 	result, err := pipeline.Run(pipeline.Config{
-		ProjectName:    projectName,
-		RootPath:       absPath,
-		LLMClient:      llmClient,
-		MemoriesClient: memoriesClient,
-		SourceRegistry: registry,
-		MaxWorkers:     cfg.MaxConcurrent,
-		ProgressFn:     progressFn,
-		Incremental:    incremental,
-		ModuleFilter:   moduleFilter,
+		ProjectName:       projectName,
+		RootPath:          absPath,
+		LLMClient:         llmClient,
+		MemoriesClient:    memoriesClient,
+		SourceRegistry:    registry,
+		MaxWorkers:        cfg.MaxConcurrent,
+		ProgressFn:        progressFn,
+		Incremental:       incremental,
+		RehashAll:         rehashAll,
+		ScopedSynthesis:   onlyChangedModules,
+		ModuleFilter:      moduleFilter,
+		FileList:          fileList,
+		FastMaxTokens:     cfg.FastMaxTokens,
+		DeepMaxTokens:     cfg.DeepMaxTokens,
+		IncludeGlobs:      includeGlobs,
+		ExcludeGlobs:      excludeGlobs,
+		IncludeGenerated:  includeGenerated,
+		IncludeSubmodules: includeSubmodules,
+		ChunkKinds:        cfg.ChunkKinds,
+		ChunkMinLines:     cfg.ChunkMinLines,
+		MemoriesNamespace: cfg.MemoriesNamespace,
+		CompressThreshold: cfg.CompressThreshold,
+		HistorySince:      cfg.HistorySince,
+		HistoryMaxCommits: cfg.HistoryMaxCommits,
+		FullHistory:       fullHistory,
+		ModulesOnly:       modulesOnly,
+		Instructions:      pipeline.Instructions(cfg.Instructions),
+		SummaryDetail:     cfg.SummaryDetail,
+		NoFileFallback:    noFileFallback,
+		NoStoreSource:     noStoreSource,
+		RetryFailed:       retryFailed,
+		SavePhases:        savePhases,
+		ResumeFrom:        resumeFrom,
+		Timeout:           timeout,
+		MaxCost:           cfg.MaxCost,
+		ValidateLinks:     validateLinks,
 	})
 	if err != nil {
-		return fmt.Errorf("pipeline failed: %w", err)
+		if errors.Is(err, pipeline.ErrTimedOut) && result != nil {
+			fmt.Printf("\n%s⚠ Timed out after %s — partial results:%s\n", amber, timeout, reset)
+			printIndexSummary(result, time.Since(startTime))
+		}
+		if errors.Is(err, pipeline.ErrBudgetExceeded) && result != nil {
+			fmt.Printf("\n%s⚠ Budget of $%.2f exceeded after $%.2f — partial results:%s\n", amber, cfg.MaxCost, llmClient.Spend(), reset)
+			printIndexSummary(result, time.Since(startTime))
+		}
+		return indexError(err)
 	}
 
-	elapsed := time.Since(startTime)
+	printIndexSummary(result, time.Since(startTime))
+	return nil
+}
 
-	// Print summary.
+// printIndexSummary prints the outcome of an index run.
+func printIndexSummary(result *pipeline.Result, elapsed time.Duration) {
 	fmt.Println()
 	fmt.Printf("%s%s=== Summary ===%s\n", bold, green, reset)
 	fmt.Printf("  modules:  %d\n", result.Modules)
 	fmt.Printf("  files:    %d\n", result.FilesIndexed)
 	fmt.Printf("  atoms:    %d\n", result.AtomsCreated)
+	if result.Truncated > 0 {
+		fmt.Printf("  %struncated: %d (content cut to fit the Memories limit)%s\n", amber, result.Truncated, reset)
+	}
+	if result.Unstored > 0 {
+		fmt.Printf("  %sunstored: %d (memories Memories did not accept)%s\n", red, result.Unstored, reset)
+	}
+	if len(result.EmptyFiles) > 0 {
+		fmt.Printf("  empty:    %d (files that produced no atoms)\n", len(result.EmptyFiles))
+	}
+	if len(result.FailedFiles) > 0 {
+		fmt.Printf("  %sfailed:   %d (files that failed to chunk or analyze)%s\n", red, len(result.FailedFiles), reset)
+	}
+	if len(result.SkippedFailed) > 0 {
+		fmt.Printf("  %sskipped:  %d (failed on earlier runs; --retry-failed to retry)%s\n", amber, len(result.SkippedFailed), reset)
+	}
+	if len(result.MissingFiles) > 0 {
+		fmt.Printf("  %smissing:  %d (listed files not found in the scan)%s\n", amber, len(result.MissingFiles), reset)
+	}
+	if result.InvalidLinks > 0 {
+		fmt.Printf("  %slinks:    %d (wiring edges referring to no known unit)%s\n", amber, result.InvalidLinks, reset)
+	}
 	fmt.Printf("  errors:   %d\n", len(result.Errors))
 	fmt.Printf("  elapsed:  %s\n", elapsed.Round(time.Millisecond))
 
+	if !result.Changes.Empty() {
+		printChanges(result.Changes)
+	}
+
+	if len(result.Cycles) > 0 {
+		printCycles(result.Cycles)
+	}
+
+	printFailedFiles("Failed files:", red, result.FailedFiles)
+	printFailedFiles("Skipped after repeated failures:", amber, result.SkippedFailed)
+	if len(result.MissingFiles) > 0 {
+		fmt.Printf("\n%s%sListed files not found:%s\n", bold, amber, reset)
+		for _, f := range result.MissingFiles {
+			fmt.Printf("  - %s\n", f)
+		}
+	}
+
 	if len(result.Errors) > 0 {
 		fmt.Printf("\n%s%sWarnings:%s\n", bold, amber, reset)
 		for i, e := range result.Errors {
@@ -157,8 +358,107 @@ func runIndex(cmd *cobra.Command, args []string) error {
 			fmt.Printf("  - %v\n", e)
 		}
 	}
+}
 
-	return nil
+// printFailedFiles lists files that failed, with each one's reason and
+// how many runs in a row it has failed.
+func printFailedFiles(title, color string, files []pipeline.FailedFile) {
+	if len(files) == 0 {
+		return
+	}
+	fmt.Printf("\n%s%s%s%s\n", bold, color, title, reset)
+	for _, f := range files {
+		fmt.Printf("  - %s %s(attempt %d: %s)%s\n", f.Path, stone, f.Attempts, f.Reason, reset)
+	}
+}
+
+// loadFileList reads the newline-separated paths of --files-from from path,
+// or from stdin when path is "-". Blank lines are ignored; an empty list
+// indexes nothing.
+func loadFileList(cmd *cobra.Command, path string) ([]string, error) {
+	var r io.Reader = cmd.InOrStdin()
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	files := []string{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, sc.Err()
+}
+
+// indexError classifies a pipeline failure so its JSON error code and exit
+// status say why the run failed; the cause stays visible to errors.Is.
+func indexError(err error) error {
+	msg := "pipeline failed: " + err.Error()
+	switch {
+	case errors.Is(err, pipeline.ErrMemoriesUnreachable), errors.Is(err, storage.ErrIncompatibleServer):
+		return withCause(newConnectionError(msg), err)
+	case errors.Is(err, pipeline.ErrModuleNotFound), errors.Is(err, pipeline.ErrNoSavedPhases):
+		return withCause(newNotFoundError(msg), err)
+	case errors.Is(err, llm.ErrNoAPIKey):
+		return withCause(newConfigError(msg), err)
+	case errors.Is(err, pipeline.ErrTimedOut):
+		return withCause(newTimeoutError(msg), err)
+	case errors.Is(err, pipeline.ErrBudgetExceeded):
+		return withCause(newBudgetError(msg), err)
+	}
+	return fmt.Errorf("pipeline failed: %w", err)
+}
+
+// printChanges prints the architecture-level differences from the previous
+// index of the project.
+func printChanges(c *pipeline.Changes) {
+	fmt.Printf("\n%s%sArchitecture changes:%s\n", bold, gold, reset)
+	for _, p := range c.PatternsAdded {
+		fmt.Printf("  %s+%s pattern: %s\n", green, reset, p)
+	}
+	for _, p := range c.PatternsRemoved {
+		fmt.Printf("  %s-%s pattern: %s\n", red, reset, p)
+	}
+	for _, z := range c.ZonesAdded {
+		fmt.Printf("  %s+%s zone %s in %s\n", green, reset, z.Zone, z.Module)
+	}
+	for _, z := range c.ZonesRemoved {
+		fmt.Printf("  %s-%s zone %s in %s\n", red, reset, z.Zone, z.Module)
+	}
+	for _, ic := range c.IntentsChanged {
+		fmt.Printf("  %s~%s intent of %s: %s\n", amber, reset, ic.Module, ic.After)
+	}
+}
+
+// startTracing turns on exporting trace spans to the OTLP endpoint from
+// config, if any, and returns the func that flushes the remaining spans.
+func startTracing(endpoint string) (func(), error) {
+	shutdown, err := tracing.Setup(endpoint)
+	if err != nil {
+		return nil, newConfigError(err.Error())
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "%sWARN:%s %v\n", amber, reset, err)
+		}
+	}, nil
+}
+
+// printCycles prints the circular dependencies found in the wiring, each
+// closed back onto its first node.
+func printCycles(cycles [][]string) {
+	fmt.Printf("\n%s%sPotential circular dependencies:%s\n", bold, amber, reset)
+	for _, c := range cycles {
+		fmt.Printf("  %s → %s\n", strings.Join(c, " → "), c[0])
+	}
 }
 
 // runIndexAll lists projects that would be indexed when --all or --changed is used.
@@ -173,6 +473,14 @@ func runIndexAll(cmd *cobra.Command, changedOnly bool) error {
 		return fmt.Errorf("PROJECTS_DIR environment variable is not set")
 	}
 
+	nameGlob, _ := cmd.Flags().GetString("filter")
+	tagFlags, _ := cmd.Flags().GetStringArray("tag")
+	tags, err := manifest.ParseTags(tagFlags)
+	if err != nil {
+		return newConfigError(fmt.Sprintf("--tag: %v", err))
+	}
+	filter := manifest.ProjectFilter{Name: nameGlob, Tags: tags}
+
 	entries, err := os.ReadDir(projectsDir)
 	if err != nil {
 		return fmt.Errorf("read projects dir: %w", err)
@@ -202,6 +510,10 @@ func runIndexAll(cmd *cobra.Command, changedOnly bool) error {
 		if name == "" {
 			name = entry.Name()
 		}
+		if !filter.Selects(name, projectPath) {
+			verboseLog(cmd, "skipping %q — does not match --filter/--tag", name)
+			continue
+		}
 
 		// For --changed, skip projects with no modifications since last index.
 		hasChanges := true
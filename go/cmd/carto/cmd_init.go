@@ -57,12 +57,8 @@ func runInit(cmd *cobra.Command, _ []string) error {
 	// Load current config for defaults.
 	cfg := config.Load()
 
-	// Ensure ConfigPath is set so Save() has somewhere to write.
-	cfgPath := config.ConfigPath
-	if cfgPath == "" {
-		cfgPath = config.DefaultConfigFilePath()
-		config.ConfigPath = cfgPath
-	}
+	// Save() writes to the --config file, CARTO_CONFIG, or the XDG default.
+	cfgPath := config.ActiveConfigPath()
 
 	if nonInteractive {
 		return runInitNonInteractive(cmd, cfg, cfgPath, flagProvider, flagAPIKey, flagMemURL, flagMemKey, flagProjDir)
@@ -42,14 +42,16 @@ func execCmd(t *testing.T, cmd *cobra.Command, args []string) (string, error) {
 }
 
 // withCleanEnv unsets env vars that affect config loading for the duration
-// of a test, then restores them.
+// of a test, then restores them. The default config file is redirected to
+// a fresh temp dir so settings saved by one test don't leak into another.
 func withCleanEnv(t *testing.T) {
 	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 	keys := []string{
 		"ANTHROPIC_API_KEY", "LLM_API_KEY", "LLM_PROVIDER",
 		"MEMORIES_URL", "CARTO_SERVER_TOKEN", "CARTO_CORS_ORIGINS",
 		"CARTO_FAST_MAX_TOKENS", "CARTO_DEEP_MAX_TOKENS",
-		"CARTO_PROFILE", "CARTO_AUDIT_LOG", "PROJECTS_DIR",
+		"CARTO_PROFILE", "CARTO_AUDIT_LOG", "PROJECTS_DIR", "CARTO_CONFIG",
 	}
 	saved := map[string]string{}
 	for _, k := range keys {
@@ -234,7 +236,7 @@ func TestAuthSetKey_EmptyKey_Errors(t *testing.T) {
 
 func TestAuthSetKey_ValidProvider_MasksOutput(t *testing.T) {
 	withCleanEnv(t)
-	// No ConfigPath set → stores for session only, prints warning but returns nil.
+	// No ConfigPath set → saves to the default config file under XDG_CONFIG_HOME.
 	cmd := authCmd()
 	out, err := execCmd(t, cmd, []string{"set-key", "anthropic", "sk-ant-REDACTED"})
 	if err != nil {
@@ -266,6 +268,7 @@ func TestAuthSetKey_AllProviders_Accepted(t *testing.T) {
 
 	for _, tt := range providers {
 		t.Run(tt.provider, func(t *testing.T) {
+			withCleanEnv(t)
 			cmd := authCmd()
 			_, err := execCmd(t, cmd, []string{"set-key", tt.provider, tt.key})
 			if err != nil {
@@ -2,21 +2,36 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/divyekant/carto/internal/analyzer"
+	"github.com/divyekant/carto/internal/config"
 	"github.com/divyekant/carto/internal/scanner"
+	"github.com/divyekant/carto/internal/storage"
 )
 
+// notIndexed is shown in place of an intent that has not been stored.
+const notIndexed = "(not indexed)"
+
 func modulesCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "modules <path>",
 		Short: "List detected modules",
 		Args:  cobra.ExactArgs(1),
 		RunE:  runModules,
 	}
+	cmd.Flags().Bool("intent", false, "Show each module's analyzed intent from the index")
+	cmd.Flags().String("project", "", "Project name to read intents from (defaults to directory name)")
+	cmd.RegisterFlagCompletionFunc("project", completeProjects)
+	cmd.Flags().Bool("include-submodules", false, "Also list the git submodules in .gitmodules as modules")
+	cmd.Flags().String("graph", "", "Print a graph of the modules, their directory nesting and Go imports between them, as 'dot' or 'mermaid'")
+	cmd.RegisterFlagCompletionFunc("graph", fixedCompletion(graphDOT, graphMermaid))
+	return cmd
 }
 
 func runModules(cmd *cobra.Command, args []string) error {
@@ -25,16 +40,47 @@ func runModules(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("resolve path: %w", err)
 	}
 
-	result, err := scanner.Scan(absPath)
+	graph, _ := cmd.Flags().GetString("graph")
+	if graph != "" && graph != graphDOT && graph != graphMermaid {
+		return newConfigError(fmt.Sprintf("--graph must be %s or %s", graphDOT, graphMermaid))
+	}
+
+	includeSubmodules, _ := cmd.Flags().GetBool("include-submodules")
+	result, err := scanner.ScanWithOptions(absPath, scanner.Options{IncludeSubmodules: includeSubmodules})
 	if err != nil {
 		return fmt.Errorf("scan: %w", err)
 	}
 
+	if graph != "" {
+		edges := moduleGraphEdges(result.Root, result.Modules)
+		if graph == graphMermaid {
+			writeMermaidGraph(cmd.OutOrStdout(), result.Modules, edges)
+		} else {
+			writeDOTGraph(cmd.OutOrStdout(), result.Modules, edges)
+		}
+		return nil
+	}
+
+	showIntent, _ := cmd.Flags().GetBool("intent")
+	projectName, _ := cmd.Flags().GetString("project")
+	if projectName == "" {
+		projectName = filepath.Base(absPath)
+	}
+
+	var store *storage.Store
+	if showIntent {
+		cfg := config.Load()
+		store = storage.NewStore(storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey), projectName, cfg.MemoriesNamespace)
+	}
+
 	type moduleInfo struct {
-		Name  string `json:"name"`
-		Type  string `json:"type"`
-		Path  string `json:"path"`
-		Files int    `json:"files"`
+		Name       string   `json:"name"`
+		Type       string   `json:"type"`
+		Path       string   `json:"path"`
+		Files      int      `json:"files"`
+		Intent     string   `json:"intent,omitempty"`
+		Submodule  string   `json:"submodule,omitempty"`
+		Frameworks []string `json:"frameworks,omitempty"`
 	}
 
 	modules := make([]moduleInfo, 0, len(result.Modules))
@@ -43,15 +89,29 @@ func runModules(cmd *cobra.Command, args []string) error {
 		if relPath == "" {
 			relPath = "."
 		}
-		modules = append(modules, moduleInfo{
-			Name:  mod.Name,
-			Type:  mod.Type,
-			Path:  relPath,
-			Files: len(mod.Files),
-		})
+		info := moduleInfo{
+			Name:       mod.Name,
+			Type:       mod.Type,
+			Path:       relPath,
+			Files:      len(mod.Files),
+			Submodule:  mod.Submodule,
+			Frameworks: mod.Frameworks,
+		}
+		if store != nil {
+			info.Intent = storedIntent(cmd, store, mod.Name)
+		}
+		modules = append(modules, info)
+	}
+
+	data := struct {
+		Modules   []moduleInfo            `json:"modules"`
+		Languages []scanner.LanguageStats `json:"languages"`
+	}{modules, result.LanguageStats}
+	if data.Languages == nil {
+		data.Languages = []scanner.LanguageStats{}
 	}
 
-	writeEnvelopeHuman(cmd, modules, nil, func() {
+	writeEnvelopeHuman(cmd, data, nil, func() {
 		fmt.Printf("%s%sDetected modules in %s%s\n\n", bold, gold, absPath, reset)
 
 		if len(modules) == 0 {
@@ -68,10 +128,209 @@ func runModules(cmd *cobra.Command, args []string) error {
 
 		for _, mod := range modules {
 			fmt.Printf("  %-30s %-15s %-40s %d\n", mod.Name, mod.Type, mod.Path, mod.Files)
+			if mod.Submodule != "" {
+				fmt.Printf("    %ssubmodule: %s%s\n", stone, mod.Submodule, reset)
+			}
+			if len(mod.Frameworks) > 0 {
+				fmt.Printf("    %sstack: %s%s\n", stone, strings.Join(mod.Frameworks, ", "), reset)
+			}
+			if showIntent {
+				color := stone
+				if mod.Intent != notIndexed {
+					color = reset
+				}
+				fmt.Printf("    %s%s%s\n", color, truncateText(mod.Intent, 200), reset)
+			}
 		}
 
 		fmt.Printf("\n  %sTotal:%s %d module(s), %d file(s)\n", bold, reset, len(result.Modules), len(result.Files))
+
+		printLanguageStats(result.LanguageStats)
 	})
 
 	return nil
 }
+
+// Formats of 'modules --graph'.
+const (
+	graphDOT     = "dot"
+	graphMermaid = "mermaid"
+)
+
+// moduleEdge is an edge of the module graph between two indexes into the
+// modules: from's directory holds to's (contains), or from's Go files
+// import to's packages (imports).
+type moduleEdge struct {
+	from, to int
+	imports  bool
+}
+
+// moduleGraphEdges returns the edges of the module graph, without any LLM
+// call: each module to the modules nested directly in its directory, then
+// each Go module to the Go modules its files import, from a static parse
+// of its files (see analyzer.StaticGoWiring). Edges are sorted.
+func moduleGraphEdges(root string, modules []scanner.Module) []moduleEdge {
+	var edges []moduleEdge
+	for j, child := range modules {
+		parent := -1
+		for i, m := range modules {
+			if i == j || m.RelPath == child.RelPath || !isSubdir(m.RelPath, child.RelPath) {
+				continue
+			}
+			if parent < 0 || len(m.RelPath) > len(modules[parent].RelPath) {
+				parent = i
+			}
+		}
+		if parent >= 0 {
+			edges = append(edges, moduleEdge{from: parent, to: j})
+		}
+	}
+
+	var goModulePaths []string
+	for _, m := range modules {
+		if m.Type == "go" {
+			goModulePaths = append(goModulePaths, m.Name)
+		}
+	}
+	seen := make(map[moduleEdge]bool)
+	for i, m := range modules {
+		if m.Type != "go" {
+			continue
+		}
+		for _, dep := range analyzer.StaticGoWiring(root, m.Files, goModulePaths) {
+			if !strings.HasPrefix(dep.Reason, "imports ") {
+				continue // a reference between files of one package
+			}
+			if k := goModuleOf(modules, dep.To); k >= 0 && k != i {
+				e := moduleEdge{from: i, to: k, imports: true}
+				if !seen[e] {
+					seen[e] = true
+					edges = append(edges, e)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(edges, func(a, b int) bool {
+		if edges[a].imports != edges[b].imports {
+			return !edges[a].imports
+		}
+		if edges[a].from != edges[b].from {
+			return edges[a].from < edges[b].from
+		}
+		return edges[a].to < edges[b].to
+	})
+	return edges
+}
+
+// isSubdir reports whether the relative directory sub lies inside dir,
+// where "" is the scan root.
+func isSubdir(dir, sub string) bool {
+	return dir == "" || strings.HasPrefix(sub, dir+"/")
+}
+
+// goModuleOf returns the index of the Go module whose module path is the
+// longest prefix of importPath, or -1 if it is in none of them.
+func goModuleOf(modules []scanner.Module, importPath string) int {
+	best := -1
+	for i, m := range modules {
+		if m.Type != "go" || (importPath != m.Name && !strings.HasPrefix(importPath, m.Name+"/")) {
+			continue
+		}
+		if best < 0 || len(m.Name) > len(modules[best].Name) {
+			best = i
+		}
+	}
+	return best
+}
+
+// moduleGraphLabel is a module's node label: its name, type and path.
+func moduleGraphLabel(m scanner.Module) (name, detail string) {
+	path := m.RelPath
+	if path == "" {
+		path = "."
+	}
+	return m.Name, m.Type + ", " + path
+}
+
+// writeDOTGraph writes the module graph in Graphviz DOT. Nesting edges
+// are dashed.
+func writeDOTGraph(w io.Writer, modules []scanner.Module, edges []moduleEdge) {
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	fmt.Fprintln(w, "digraph modules {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for i, m := range modules {
+		name, detail := moduleGraphLabel(m)
+		fmt.Fprintf(w, "  m%d [label=\"%s\\n%s\"];\n", i, quote.Replace(name), quote.Replace(detail))
+	}
+	for _, e := range edges {
+		if e.imports {
+			fmt.Fprintf(w, "  m%d -> m%d [label=\"imports\"];\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(w, "  m%d -> m%d [label=\"contains\", style=dashed];\n", e.from, e.to)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// writeMermaidGraph writes the module graph as a Mermaid flowchart.
+// Nesting edges are dotted.
+func writeMermaidGraph(w io.Writer, modules []scanner.Module, edges []moduleEdge) {
+	quote := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+	fmt.Fprintln(w, "graph LR")
+	for i, m := range modules {
+		name, detail := moduleGraphLabel(m)
+		fmt.Fprintf(w, "  m%d[\"%s<br/>%s\"]\n", i, quote.Replace(name), quote.Replace(detail))
+	}
+	for _, e := range edges {
+		if e.imports {
+			fmt.Fprintf(w, "  m%d -- imports --> m%d\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(w, "  m%d -. contains .-> m%d\n", e.from, e.to)
+		}
+	}
+}
+
+// printLanguageStats prints a table of file counts and sizes per language,
+// with each language's share of the total bytes.
+func printLanguageStats(stats []scanner.LanguageStats) {
+	if len(stats) == 0 {
+		return
+	}
+	var total int64
+	for _, s := range stats {
+		total += s.Bytes
+	}
+
+	fmt.Printf("\n%s%sLanguages%s\n\n", bold, gold, reset)
+	fmt.Printf("  %-20s %8s %10s %6s\n", "LANGUAGE", "FILES", "SIZE", "SHARE")
+	fmt.Printf("  %-20s %8s %10s %6s\n",
+		strings.Repeat("-", 20),
+		strings.Repeat("-", 8),
+		strings.Repeat("-", 10),
+		strings.Repeat("-", 6))
+	for _, s := range stats {
+		share := 0.0
+		if total > 0 {
+			share = float64(s.Bytes) * 100 / float64(total)
+		}
+		fmt.Printf("  %-20s %8d %10s %5.1f%%\n", s.Language, s.Files, formatBytes(s.Bytes), share)
+	}
+}
+
+// storedIntent returns the most recently stored intent of a module, or
+// notIndexed when none is stored or it cannot be read.
+func storedIntent(cmd *cobra.Command, store *storage.Store, module string) string {
+	results, err := store.RetrieveLayer(module, storage.LayerIntent)
+	if err != nil {
+		verboseLog(cmd, "read intent for %s: %v", module, err)
+		return notIndexed
+	}
+	for i := len(results) - 1; i >= 0; i-- {
+		if intent := strings.TrimSpace(results[i].Text); intent != "" {
+			return intent
+		}
+	}
+	return notIndexed
+}
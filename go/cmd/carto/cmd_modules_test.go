@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/divyekant/carto/internal/scanner"
+)
+
+func TestModulesCmd_Intent(t *testing.T) {
+	withCleanEnv(t)
+
+	// Only project "myproj" has stored intents.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source := r.URL.Query().Get("source")
+		var memories []map[string]any
+		if strings.HasPrefix(source, "carto/myproj/") && strings.HasSuffix(source, "/layer:intent") {
+			memories = append(memories, map[string]any{"id": 1, "text": "Serves the demo API.", "source": source})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"memories": memories})
+	}))
+	defer srv.Close()
+	t.Setenv("MEMORIES_URL", srv.URL)
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/test\n\ngo 1.21\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+
+	intents := func(args ...string) []string {
+		t.Helper()
+		out, err := execCmd(t, testRoot(modulesCmd()), append([]string{"modules", dir, "--intent", "--json"}, args...))
+		if err != nil {
+			t.Fatalf("modules: %v\n%s", err, out)
+		}
+		var env struct {
+			Data struct {
+				Modules []struct {
+					Intent string `json:"intent"`
+				} `json:"modules"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(out), &env); err != nil {
+			t.Fatalf("parse output: %v\n%s", err, out)
+		}
+		var got []string
+		for _, m := range env.Data.Modules {
+			got = append(got, m.Intent)
+		}
+		return got
+	}
+
+	if got := intents("--project", "myproj"); len(got) != 1 || got[0] != "Serves the demo API." {
+		t.Errorf("indexed intents = %q", got)
+	}
+	if got := intents(); len(got) != 1 || got[0] != notIndexed {
+		t.Errorf("unindexed intents = %q, want %q", got, notIndexed)
+	}
+}
+
+func TestModulesCmd_LanguageStats(t *testing.T) {
+	withCleanEnv(t)
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/test\n\ngo 1.21\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "util.go"), []byte("package main\n"), 0o644)
+
+	out, err := execCmd(t, testRoot(modulesCmd()), []string{"modules", dir, "--json"})
+	if err != nil {
+		t.Fatalf("modules: %v\n%s", err, out)
+	}
+	var env struct {
+		Data struct {
+			Languages []scanner.LanguageStats `json:"languages"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, out)
+	}
+	var goStats *scanner.LanguageStats
+	for i := range env.Data.Languages {
+		if env.Data.Languages[i].Language == "go" {
+			goStats = &env.Data.Languages[i]
+		}
+	}
+	if goStats == nil || goStats.Files != 2 || goStats.Bytes != 26 {
+		t.Errorf("go stats = %+v, want 2 files, 26 bytes (all: %+v)", goStats, env.Data.Languages)
+	}
+}
+
+func TestModulesCmd_GraphDOT(t *testing.T) {
+	withCleanEnv(t)
+
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, rel)), 0o755)
+		os.WriteFile(filepath.Join(dir, rel), []byte(content), 0o644)
+	}
+	write("go.mod", "module example.com/app\n\ngo 1.21\n")
+	write("main.go", "package main\n\nimport \"example.com/lib/util\"\n\nfunc main() { util.Do() }\n")
+	write("lib/go.mod", "module example.com/lib\n\ngo 1.21\n")
+	write("lib/util/util.go", "package util\n\nfunc Do() {}\n")
+	write("web/package.json", `{"name": "web"}`)
+	write("web/index.js", "console.log('hi')\n")
+
+	out, err := execCmd(t, testRoot(modulesCmd()), []string{"modules", dir, "--graph", "dot"})
+	if err != nil {
+		t.Fatalf("modules --graph: %v\n%s", err, out)
+	}
+
+	// Node IDs follow scan order, so map each module name to its ID.
+	ids := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		id, label, ok := strings.Cut(strings.TrimSpace(line), ` [label="`)
+		if ok {
+			name, _, _ := strings.Cut(label, `\n`)
+			ids[name] = id
+		}
+	}
+	for _, name := range []string{"example.com/app", "example.com/lib", "web"} {
+		if ids[name] == "" {
+			t.Fatalf("no node for module %s in:\n%s", name, out)
+		}
+	}
+	app, lib, web := ids["example.com/app"], ids["example.com/lib"], ids["web"]
+	for _, want := range []string{
+		"digraph modules {",
+		app + " -> " + lib + ` [label="contains", style=dashed];`,
+		app + " -> " + web + ` [label="contains", style=dashed];`,
+		app + " -> " + lib + ` [label="imports"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("graph is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, lib+" -> "+app) || strings.Contains(out, web+" -> ") {
+		t.Errorf("graph has an edge out of a leaf module:\n%s", out)
+	}
+
+	if _, err := execCmd(t, testRoot(modulesCmd()), []string{"modules", dir, "--graph", "svg"}); err == nil {
+		t.Error("--graph svg should be rejected")
+	}
+}
@@ -44,7 +44,7 @@ func runPatterns(cmd *cobra.Command, args []string) error {
 	projectName := filepath.Base(absPath)
 
 	// Try to load existing analysis from Memories.
-	store := storage.NewStore(memoriesClient, projectName)
+	store := storage.NewStore(memoriesClient, projectName, cfg.MemoriesNamespace)
 
 	// Build module summaries from scan.
 	var moduleSummaries []patterns.ModuleSummary
@@ -84,6 +84,7 @@ func runPatterns(cmd *cobra.Command, args []string) error {
 
 	input := patterns.Input{
 		ProjectName: projectName,
+		Namespace:   cfg.MemoriesNamespace,
 		Blueprint:   blueprint,
 		Patterns:    pats,
 		Zones:       zones,
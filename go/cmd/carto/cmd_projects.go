@@ -4,13 +4,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/divyekant/carto/internal/config"
 	"github.com/divyekant/carto/internal/manifest"
 	"github.com/divyekant/carto/internal/sources"
+	"github.com/divyekant/carto/internal/storage"
 )
 
 func projectsCmd() *cobra.Command {
@@ -21,15 +24,18 @@ func projectsCmd() *cobra.Command {
 	cmd.AddCommand(projectsListCmd())
 	cmd.AddCommand(projectsShowCmd())
 	cmd.AddCommand(projectsDeleteCmd())
+	cmd.AddCommand(projectsTagCmd())
 	return cmd
 }
 
 func projectsListCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all indexed projects",
 		RunE:  runProjectsList,
 	}
+	cmd.Flags().StringArray("filter", nil, "Only list projects with this tag, as key=value (repeatable; all must match)")
+	return cmd
 }
 
 func runProjectsList(cmd *cobra.Command, args []string) error {
@@ -38,16 +44,23 @@ func runProjectsList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("PROJECTS_DIR environment variable is not set")
 	}
 
+	filterFlags, _ := cmd.Flags().GetStringArray("filter")
+	filters, err := manifest.ParseTags(filterFlags)
+	if err != nil {
+		return newConfigError(fmt.Sprintf("--filter: %v", err))
+	}
+
 	entries, err := os.ReadDir(projectsDir)
 	if err != nil {
 		return fmt.Errorf("read projects dir: %w", err)
 	}
 
 	type projectInfo struct {
-		Name      string `json:"name"`
-		Path      string `json:"path"`
-		Files     int    `json:"files"`
-		IndexedAt string `json:"indexed_at"`
+		Name      string            `json:"name"`
+		Path      string            `json:"path"`
+		Files     int               `json:"files"`
+		IndexedAt string            `json:"indexed_at"`
+		Tags      map[string]string `json:"tags,omitempty"`
 	}
 
 	var projects []projectInfo
@@ -60,6 +73,10 @@ func runProjectsList(cmd *cobra.Command, args []string) error {
 		if err != nil || mf.IsEmpty() {
 			continue
 		}
+		meta, err := manifest.LoadMeta(projectPath)
+		if err != nil || !meta.Matches(filters) {
+			continue
+		}
 		name := mf.Project
 		if name == "" {
 			name = entry.Name()
@@ -69,6 +86,7 @@ func runProjectsList(cmd *cobra.Command, args []string) error {
 			Path:      projectPath,
 			Files:     len(mf.Files),
 			IndexedAt: mf.IndexedAt.Format(time.RFC3339),
+			Tags:      meta.Tags,
 		})
 	}
 
@@ -78,13 +96,14 @@ func runProjectsList(cmd *cobra.Command, args []string) error {
 			return
 		}
 		fmt.Printf("%s%sIndexed projects%s\n\n", bold, gold, reset)
-		fmt.Printf("  %-25s %-8s %s\n", "NAME", "FILES", "INDEXED AT")
-		fmt.Printf("  %-25s %-8s %s\n",
+		fmt.Printf("  %-25s %-8s %-25s %s\n", "NAME", "FILES", "INDEXED AT", "TAGS")
+		fmt.Printf("  %-25s %-8s %-25s %s\n",
 			strings.Repeat("-", 25),
 			strings.Repeat("-", 8),
+			strings.Repeat("-", 25),
 			strings.Repeat("-", 20))
 		for _, p := range projects {
-			fmt.Printf("  %-25s %-8d %s\n", p.Name, p.Files, p.IndexedAt)
+			fmt.Printf("  %-25s %-8d %-25s %s\n", p.Name, p.Files, p.IndexedAt, formatTags(p.Tags))
 		}
 		fmt.Printf("\n  %sTotal:%s %d project(s)\n", bold, reset, len(projects))
 	})
@@ -93,10 +112,14 @@ func runProjectsList(cmd *cobra.Command, args []string) error {
 
 func projectsShowCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "show <name>",
-		Short: "Show details of an indexed project",
-		Args:  cobra.ExactArgs(1),
-		RunE:  runProjectsShow,
+		Use:               "show <name>",
+		Short:             "Show details of an indexed project",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProjectArg,
+		RunE:              runProjectsShow,
+		Long: "Show details of an indexed project. With --verbose, also query Memories\n" +
+			"for the depth of the stored analysis: analyzed modules, atoms, zones,\n" +
+			"wiring edges, blueprint and patterns.",
 	}
 }
 
@@ -138,6 +161,10 @@ func runProjectsShow(cmd *cobra.Command, args []string) error {
 		TotalSize string   `json:"total_size"`
 		IndexedAt string   `json:"indexed_at"`
 		Sources   []string `json:"sources,omitempty"`
+
+		Tags map[string]string `json:"tags,omitempty"`
+
+		Analysis *storage.ProjectStats `json:"analysis,omitempty"` // --verbose only
 	}
 
 	data := showData{
@@ -148,6 +175,20 @@ func runProjectsShow(cmd *cobra.Command, args []string) error {
 		IndexedAt: mf.IndexedAt.Format(time.RFC3339),
 		Sources:   sourceNames,
 	}
+	if meta, err := manifest.LoadMeta(projectPath); err == nil {
+		data.Tags = meta.Tags
+	}
+
+	// The global --verbose flag adds the analysis depth stored in Memories.
+	if isVerbose(cmd) {
+		cfg := config.Load()
+		store := storage.NewStore(storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey), mf.Project, cfg.MemoriesNamespace)
+		stats, err := store.Stats()
+		if err != nil {
+			return fmt.Errorf("read analysis from memories: %w", err)
+		}
+		data.Analysis = stats
+	}
 
 	writeEnvelopeHuman(cmd, data, nil, func() {
 		fmt.Printf("%s%sProject: %s%s\n\n", bold, gold, data.Name, reset)
@@ -158,16 +199,33 @@ func runProjectsShow(cmd *cobra.Command, args []string) error {
 		if len(data.Sources) > 0 {
 			fmt.Printf("  %sSources:%s     %s\n", gold, reset, strings.Join(data.Sources, ", "))
 		}
+		if len(data.Tags) > 0 {
+			fmt.Printf("  %sTags:%s        %s\n", gold, reset, formatTags(data.Tags))
+		}
+		if a := data.Analysis; a != nil {
+			blueprint := "no"
+			if a.Blueprint {
+				blueprint = "yes"
+			}
+			fmt.Printf("\n  %sAnalysis%s\n", bold, reset)
+			fmt.Printf("  %sModules:%s     %d analyzed\n", gold, reset, a.ModulesAnalyzed)
+			fmt.Printf("  %sAtoms:%s       %d\n", gold, reset, a.Atoms)
+			fmt.Printf("  %sZones:%s       %d\n", gold, reset, a.Zones)
+			fmt.Printf("  %sWiring:%s      %d edge(s)\n", gold, reset, a.WiringEdges)
+			fmt.Printf("  %sBlueprint:%s   %s\n", gold, reset, blueprint)
+			fmt.Printf("  %sPatterns:%s    %d\n", gold, reset, a.Patterns)
+		}
 	})
 	return nil
 }
 
 func projectsDeleteCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "delete <name>",
-		Short: "Delete a project's .carto directory",
-		Args:  cobra.ExactArgs(1),
-		RunE:  runProjectsDelete,
+		Use:               "delete <name>",
+		Short:             "Delete a project's .carto directory",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProjectArg,
+		RunE:              runProjectsDelete,
 	}
 }
 
@@ -203,3 +261,77 @@ func runProjectsDelete(cmd *cobra.Command, args []string) error {
 	})
 	return nil
 }
+
+func projectsTagCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "tag <name> key=value...",
+		Short:             "Set or remove tags on an indexed project",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeProjectArg,
+		RunE:              runProjectsTag,
+		Long: "Set tags on an indexed project, such as team=payments. Tags are stored in\n" +
+			".carto/meta.json, survive re-indexing, and can be used to filter\n" +
+			"'projects list --filter key=value'. An empty value (key=) removes the tag.",
+	}
+}
+
+func runProjectsTag(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	projectsDir := os.Getenv("PROJECTS_DIR")
+	if projectsDir == "" {
+		return fmt.Errorf("PROJECTS_DIR environment variable is not set")
+	}
+
+	tags, err := manifest.ParseTags(args[1:])
+	if err != nil {
+		return newConfigError(err.Error())
+	}
+
+	projectPath := filepath.Join(projectsDir, name)
+	mf, err := manifest.Load(projectPath)
+	if err != nil {
+		return fmt.Errorf("load manifest: %w", err)
+	}
+	if mf.IsEmpty() {
+		return fmt.Errorf("project %q not found or has no index", name)
+	}
+
+	meta, err := manifest.LoadMeta(projectPath)
+	if err != nil {
+		return err
+	}
+	for k, v := range tags {
+		if v == "" {
+			delete(meta.Tags, k)
+		} else {
+			meta.Tags[k] = v
+		}
+	}
+	if err := manifest.SaveMeta(projectPath, meta); err != nil {
+		return err
+	}
+
+	type tagResult struct {
+		Name string            `json:"name"`
+		Tags map[string]string `json:"tags"`
+	}
+
+	writeEnvelopeHuman(cmd, tagResult{Name: name, Tags: meta.Tags}, nil, func() {
+		if len(meta.Tags) == 0 {
+			fmt.Printf("%s✓%s Project %q has no tags\n", green, reset, name)
+			return
+		}
+		fmt.Printf("%s✓%s Tags for project %q: %s\n", green, reset, name, formatTags(meta.Tags))
+	})
+	return nil
+}
+
+// formatTags renders tags as "key=value" pairs sorted by key.
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
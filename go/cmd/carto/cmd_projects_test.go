@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/divyekant/carto/internal/manifest"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+func TestProjectsShow_VerboseReportsAnalysis(t *testing.T) {
+	withCleanEnv(t)
+
+	stored := []storage.SearchResult{
+		{Text: "1 atom", Source: "carto/demo/api/layer:atoms"},
+		{Text: "func A()", Source: "carto/demo/api/layer:atoms/a.go:1"},
+		{Text: "func B()", Source: "carto/demo/api/layer:atoms/b.go:1"},
+		{Text: `[{"name": "http"}]`, Source: "carto/demo/api/layer:zones"},
+		{Text: `[{"from": "api", "to": "db"}]`, Source: "carto/demo/api/layer:wiring"},
+		{Text: "Serves HTTP.", Source: "carto/demo/api/layer:intent"},
+		{Text: "# Blueprint", Source: "carto/demo/_system/layer:blueprint"},
+		{Text: `["repository", "middleware", "cqrs"]`, Source: "carto/demo/_system/layer:patterns"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page []storage.SearchResult
+		if r.URL.Query().Get("offset") == "0" {
+			for _, m := range stored {
+				if strings.HasPrefix(m.Source, r.URL.Query().Get("source")) {
+					page = append(page, m)
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"memories": page})
+	}))
+	defer srv.Close()
+	t.Setenv("MEMORIES_URL", srv.URL)
+
+	projectsDir := t.TempDir()
+	t.Setenv("PROJECTS_DIR", projectsDir)
+	mf := manifest.NewManifest(filepath.Join(projectsDir, "demo"), "demo")
+	mf.UpdateFile("main.go", "abc", 42)
+	if err := mf.Save(); err != nil {
+		t.Fatalf("mf.Save: %v", err)
+	}
+
+	out, err := execCmd(t, testRoot(projectsCmd()), []string{"projects", "show", "demo", "--verbose", "--json"})
+	if err != nil {
+		t.Fatalf("projects show: %v\n%s", err, out)
+	}
+	var env struct {
+		Data struct {
+			Analysis *storage.ProjectStats `json:"analysis"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, out)
+	}
+	want := storage.ProjectStats{ModulesAnalyzed: 1, Atoms: 2, Zones: 1, WiringEdges: 1, Blueprint: true, Patterns: 3}
+	if env.Data.Analysis == nil || *env.Data.Analysis != want {
+		t.Errorf("analysis = %+v, want %+v", env.Data.Analysis, want)
+	}
+
+	// Without --verbose, Memories is not consulted.
+	out, err = execCmd(t, testRoot(projectsCmd()), []string{"projects", "show", "demo", "--json"})
+	if err != nil {
+		t.Fatalf("projects show: %v\n%s", err, out)
+	}
+	if strings.Contains(out, `"analysis"`) {
+		t.Errorf("analysis reported without --verbose:\n%s", out)
+	}
+}
+
+func TestProjectsTag_SetsTagsAndFiltersList(t *testing.T) {
+	withCleanEnv(t)
+	projectsDir := t.TempDir()
+	t.Setenv("PROJECTS_DIR", projectsDir)
+	for _, name := range []string{"payments-api", "search-api"} {
+		mf := manifest.NewManifest(filepath.Join(projectsDir, name), name)
+		mf.UpdateFile("main.go", "abc", 42)
+		if err := mf.Save(); err != nil {
+			t.Fatalf("mf.Save: %v", err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"projects", "tag", "payments-api", "team=payments", "env=prod"},
+		{"projects", "tag", "search-api", "team=search", "env=prod"},
+		{"projects", "tag", "search-api", "env="}, // removes env
+	} {
+		if out, err := execCmd(t, testRoot(projectsCmd()), append(args, "--json")); err != nil {
+			t.Fatalf("%v: %v\n%s", args, err, out)
+		}
+	}
+
+	// Tags persist in .carto/meta.json, apart from the manifest.
+	meta, err := manifest.LoadMeta(filepath.Join(projectsDir, "search-api"))
+	if err != nil {
+		t.Fatalf("LoadMeta: %v", err)
+	}
+	if len(meta.Tags) != 1 || meta.Tags["team"] != "search" {
+		t.Errorf("search-api tags = %v, want only team=search", meta.Tags)
+	}
+
+	list := func(filters ...string) []string {
+		t.Helper()
+		args := []string{"projects", "list", "--json"}
+		for _, f := range filters {
+			args = append(args, "--filter", f)
+		}
+		out, err := execCmd(t, testRoot(projectsCmd()), args)
+		if err != nil {
+			t.Fatalf("projects list: %v\n%s", err, out)
+		}
+		var env struct {
+			Data []struct {
+				Name string            `json:"name"`
+				Tags map[string]string `json:"tags"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(out), &env); err != nil {
+			t.Fatalf("parse output: %v\n%s", err, out)
+		}
+		var names []string
+		for _, p := range env.Data {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	if got := list(); len(got) != 2 {
+		t.Errorf("unfiltered list = %v, want both projects", got)
+	}
+	if got := list("team=payments"); len(got) != 1 || got[0] != "payments-api" {
+		t.Errorf("list --filter team=payments = %v, want [payments-api]", got)
+	}
+	if got := list("team=search", "env=prod"); len(got) != 0 {
+		t.Errorf("list --filter team=search --filter env=prod = %v, want none", got)
+	}
+
+	if out, err := execCmd(t, testRoot(projectsCmd()), []string{"projects", "tag", "payments-api", "team", "--json"}); err == nil {
+		t.Errorf("tag without '=' should fail:\n%s", out)
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -13,28 +17,178 @@ func queryCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "query <question>",
 		Short: "Query the indexed codebase",
-		Args:  cobra.ExactArgs(1),
-		RunE:  runQuery,
+		Long: `Query the indexed codebase.
+
+With --interactive, the argument is a project name and a session starts in
+which each line is a query. Slash-commands adjust the session:
+/tier mini|standard|full, /k N, /module NAME, and /quit.
+
+With --batch FILE, no argument is taken. Each line of FILE ("-" for stdin)
+is a JSON object {"text": ..., "tier": ..., "k": ..., "project": ...,
+"module": ...}; only text is required, the rest default to the flags. One
+JSON result per line is written to stdout in input order, with an "error"
+field instead of results for lines that fail. Queries without a project
+search every project and ignore the tier.
+
+With --tier auto and a project, the search starts at the mini tier and
+moves up to standard, then full, while the top result scores below
+--min-score or fewer than 3 results come back. The output reports the
+tier the results were taken from as tier_used.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if batch, _ := cmd.Flags().GetString("batch"); batch != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: runQuery,
 	}
 	cmd.Flags().String("project", "", "Project name to search within")
-	cmd.Flags().String("tier", "standard", "Context tier: mini, standard, full")
+	cmd.Flags().String("tier", "standard", "Context tier: mini, standard, full, or auto to escalate from mini on weak results")
+	cmd.RegisterFlagCompletionFunc("project", completeProjects)
+	cmd.RegisterFlagCompletionFunc("tier", fixedCompletion("mini", "standard", "full", "auto"))
+	cmd.Flags().Float64("min-score", 0, "With --tier auto, the top result score to settle for before escalating (default from config, else 0.5)")
 	cmd.Flags().IntP("count", "k", 10, "Number of results")
+	cmd.Flags().Bool("explain", false, "Show where each result came from and how it was scored")
+	cmd.Flags().Bool("group", false, "Collapse results about the same atom across layers into one, listing the contributing layers")
+	cmd.Flags().String("zone", "", "Only return results within this business-domain zone of --project (its files' atoms, its modules' other layers)")
+	cmd.Flags().BoolP("interactive", "i", false, "Start an interactive query session for the project given as the argument")
+	cmd.Flags().String("batch", "", "Run the JSON Lines queries in this file (\"-\" for stdin), writing one JSON result per line")
 	return cmd
 }
 
-func runQuery(cmd *cobra.Command, args []string) error {
-	query := args[0]
+// explainedResult is a search result annotated for --explain output.
+type explainedResult struct {
+	storage.SearchResult
+	Explain storage.Explanation `json:"explain"`
+}
+
+func explainResults(namespace string, results []storage.SearchResult) []explainedResult {
+	out := make([]explainedResult, len(results))
+	for i, r := range results {
+		out[i] = explainedResult{SearchResult: r, Explain: storage.ExplainIn(namespace, r)}
+	}
+	return out
+}
 
+// printExplanation writes the --explain detail lines for one result.
+func printExplanation(indent string, e storage.Explanation) {
+	if e.Module != "" {
+		fmt.Printf("%s%smodule:%s %s  %slayer:%s %s", indent, gold, reset, e.Module, gold, reset, e.Layer)
+		if e.Tier != "" {
+			fmt.Printf("  %stier:%s %s", gold, reset, e.Tier)
+		}
+		fmt.Println()
+	}
+	if e.VectorScore != nil || e.LexicalScore != nil {
+		fmt.Printf("%s%scomponents:%s vector=%s lexical=%s\n", indent, gold, reset,
+			formatScore(e.VectorScore), formatScore(e.LexicalScore))
+	}
+}
+
+// groupedResult is a grouped search result, annotated for --explain output.
+type groupedResult struct {
+	storage.GroupedResult
+	Explain *storage.Explanation `json:"explain,omitempty"`
+}
+
+func groupResults(namespace string, results []storage.SearchResult, explain bool) []groupedResult {
+	groups := storage.GroupResults(namespace, results)
+	out := make([]groupedResult, len(groups))
+	for i, g := range groups {
+		out[i] = groupedResult{GroupedResult: g}
+		if explain {
+			e := storage.ExplainIn(namespace, g.SearchResult)
+			out[i].Explain = &e
+		}
+	}
+	return out
+}
+
+// printGroupedResults writes --group results as a ranked list.
+func printGroupedResults(groups []groupedResult) {
+	if len(groups) == 0 {
+		fmt.Println("  No results found.")
+		return
+	}
+	for i, g := range groups {
+		fmt.Printf("%s%d.%s %ssource:%s %s  %sscore:%s %.4f\n", bold, i+1, reset, gold, reset, g.Source, gold, reset, g.Score)
+		if len(g.Layers) > 0 {
+			fmt.Printf("   %slayers:%s %s\n", gold, reset, strings.Join(g.Layers, ", "))
+		}
+		if g.Explain != nil {
+			printExplanation("   ", *g.Explain)
+		}
+		fmt.Printf("   %s\n\n", truncateText(g.Text, 200))
+	}
+}
+
+func formatScore(v *float64) string {
+	if v == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.4f", *v)
+}
+
+func runQuery(cmd *cobra.Command, args []string) error {
 	project, _ := cmd.Flags().GetString("project")
 	tier, _ := cmd.Flags().GetString("tier")
 	count, _ := cmd.Flags().GetInt("count")
+	explain, _ := cmd.Flags().GetBool("explain")
+	group, _ := cmd.Flags().GetBool("group")
+	zoneName, _ := cmd.Flags().GetString("zone")
+	if zoneName != "" && project == "" {
+		return newConfigError("--zone requires --project")
+	}
 
 	cfg := config.Load()
 	memoriesClient := storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey)
 
+	if batch, _ := cmd.Flags().GetString("batch"); batch != "" {
+		defaults := batchQuery{Tier: tier, K: count, Project: project}
+		return runQueryBatch(cmd, batch, defaults, memoriesClient, cfg.MemoriesNamespace)
+	}
+
+	query := args[0]
+
+	if interactive, _ := cmd.Flags().GetBool("interactive"); interactive {
+		switch storage.Tier(tier) {
+		case storage.TierMini, storage.TierStandard, storage.TierFull:
+		default:
+			return newConfigError("invalid tier: " + tier + " (use mini, standard, or full)")
+		}
+		project := args[0]
+		return runQueryInteractive(&querySession{
+			memories:  memoriesClient,
+			store:     storage.NewStore(memoriesClient, project, cfg.MemoriesNamespace),
+			namespace: cfg.MemoriesNamespace,
+			project:   project,
+			tier:      storage.Tier(tier),
+			k:         count,
+		})
+	}
+
+	// Path weights come from the project being worked in, if it has any.
+	ranking, err := storage.LoadRanking(".")
+	if err != nil {
+		return newConfigError(err.Error())
+	}
+
+	if storage.Tier(tier) == storage.TierAuto {
+		if project == "" || zoneName != "" {
+			return newConfigError("--tier auto requires --project and does not support --zone")
+		}
+		opts := storage.AutoOptions{MinScore: cfg.AutoTierMinScore}
+		if cmd.Flags().Changed("min-score") {
+			opts.MinScore, _ = cmd.Flags().GetFloat64("min-score")
+		}
+		store := storage.NewStore(memoriesClient, project, cfg.MemoriesNamespace)
+		store.SetRanking(ranking)
+		return runQueryAuto(cmd, store, cfg.MemoriesNamespace, project, query, count, opts, explain, group)
+	}
+
 	// If a project is provided, try tier-based retrieval.
 	if project != "" {
-		store := storage.NewStore(memoriesClient, project)
+		store := storage.NewStore(memoriesClient, project, cfg.MemoriesNamespace)
 
 		storageTier := storage.Tier(tier)
 		results, err := store.RetrieveByTier(query, storageTier)
@@ -42,7 +196,50 @@ func runQuery(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("retrieve by tier: %w", err)
 		}
 
-		writeEnvelopeHuman(cmd, results, nil, func() {
+		if zoneName != "" {
+			zone, err := store.ResolveZone(zoneName)
+			if errors.Is(err, storage.ErrZoneNotFound) {
+				err = newNotFoundError(fmt.Sprintf("zone %q not found in project %s", zoneName, project))
+				writeEnvelope(cmd, nil, err)
+				return err
+			}
+			if err != nil {
+				return fmt.Errorf("resolve zone: %w", err)
+			}
+			for layer, entries := range results {
+				var inZone []storage.SearchResult
+				for _, r := range entries {
+					if zone.Contains(cfg.MemoriesNamespace, r) {
+						inZone = append(inZone, r)
+					}
+				}
+				results[layer] = inZone
+			}
+		}
+
+		if group {
+			var flat []storage.SearchResult
+			for _, layer := range slices.Sorted(maps.Keys(results)) {
+				flat = append(flat, results[layer]...)
+			}
+			groups := groupResults(cfg.MemoriesNamespace, flat, explain)
+			writeEnvelopeHuman(cmd, groups, nil, func() {
+				fmt.Printf("%s%sResults for project %q (tier: %s, grouped)%s\n\n", bold, gold, project, tier, reset)
+				printGroupedResults(groups)
+			})
+			return nil
+		}
+
+		var data any = results
+		if explain {
+			explained := make(map[string][]explainedResult, len(results))
+			for layer, entries := range results {
+				explained[layer] = explainResults(cfg.MemoriesNamespace, entries)
+			}
+			data = explained
+		}
+
+		writeEnvelopeHuman(cmd, data, nil, func() {
 			fmt.Printf("%s%sResults for project %q (tier: %s)%s\n\n", bold, gold, project, tier, reset)
 
 			for layer, entries := range results {
@@ -54,6 +251,9 @@ func runQuery(cmd *cobra.Command, args []string) error {
 					snippet := truncateText(entry.Text, 200)
 					fmt.Printf("  %ssource:%s %s\n", gold, reset, entry.Source)
 					fmt.Printf("  %sscore:%s  %.4f\n", gold, reset, entry.Score)
+					if explain {
+						printExplanation("  ", storage.ExplainIn(cfg.MemoriesNamespace, entry))
+					}
 					fmt.Printf("  %s\n\n", snippet)
 				}
 			}
@@ -61,16 +261,39 @@ func runQuery(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Free-form search across all projects.
+	// Free-form search across all projects. Grouping collapses results, so
+	// fetch extra to still fill count groups.
+	searchK := count
+	if group {
+		searchK = count * 3
+	}
 	results, err := memoriesClient.Search(query, storage.SearchOptions{
-		K:      count,
+		K:      searchK,
 		Hybrid: true,
 	})
 	if err != nil {
 		return fmt.Errorf("search: %w", err)
 	}
+	results = ranking.Apply(cfg.MemoriesNamespace, results)
 
-	writeEnvelopeHuman(cmd, results, nil, func() {
+	if group {
+		groups := groupResults(cfg.MemoriesNamespace, results, explain)
+		if len(groups) > count {
+			groups = groups[:count]
+		}
+		writeEnvelopeHuman(cmd, groups, nil, func() {
+			fmt.Printf("%s%sSearch results for: %q%s (k=%d, grouped)\n\n", bold, gold, query, reset, count)
+			printGroupedResults(groups)
+		})
+		return nil
+	}
+
+	var data any = results
+	if explain {
+		data = explainResults(cfg.MemoriesNamespace, results)
+	}
+
+	writeEnvelopeHuman(cmd, data, nil, func() {
 		fmt.Printf("%s%sSearch results for: %q%s (k=%d)\n\n", bold, gold, query, reset, count)
 
 		if len(results) == 0 {
@@ -81,6 +304,9 @@ func runQuery(cmd *cobra.Command, args []string) error {
 		for i, r := range results {
 			snippet := truncateText(r.Text, 200)
 			fmt.Printf("%s%d.%s %ssource:%s %s  %sscore:%s %.4f\n", bold, i+1, reset, gold, reset, r.Source, gold, reset, r.Score)
+			if explain {
+				printExplanation("   ", storage.ExplainIn(cfg.MemoriesNamespace, r))
+			}
 			fmt.Printf("   %s\n\n", snippet)
 		}
 	})
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/storage"
+)
+
+// autoQueryResult is the output of a --tier auto query: the results and
+// the tier they were taken from.
+type autoQueryResult struct {
+	TierUsed storage.Tier `json:"tier_used"`
+	Results  any          `json:"results"`
+}
+
+// runQueryAuto answers a --tier auto query within a project, searching at
+// mini first and escalating while the results fall short of opts.
+func runQueryAuto(cmd *cobra.Command, store *storage.Store, namespace, project, query string, k int, opts storage.AutoOptions, explain, group bool) error {
+	searchK := k
+	if group {
+		searchK = k * 3
+	}
+	results, tier, err := store.SearchAuto(query, searchK, opts)
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+
+	if group {
+		groups := groupResults(namespace, results, explain)
+		if len(groups) > k {
+			groups = groups[:k]
+		}
+		writeEnvelopeHuman(cmd, autoQueryResult{TierUsed: tier, Results: groups}, nil, func() {
+			fmt.Printf("%s%sResults for project %q (tier: auto → %s, grouped)%s\n\n", bold, gold, project, tier, reset)
+			printGroupedResults(groups)
+		})
+		return nil
+	}
+
+	var data any = results
+	if explain {
+		data = explainResults(namespace, results)
+	}
+	writeEnvelopeHuman(cmd, autoQueryResult{TierUsed: tier, Results: data}, nil, func() {
+		fmt.Printf("%s%sResults for project %q (tier: auto → %s)%s\n\n", bold, gold, project, tier, reset)
+		if len(results) == 0 {
+			fmt.Println("  No results found.")
+			return
+		}
+		for i, r := range results {
+			fmt.Printf("%s%d.%s %ssource:%s %s  %sscore:%s %.4f\n", bold, i+1, reset, gold, reset, r.Source, gold, reset, r.Score)
+			if explain {
+				printExplanation("   ", storage.ExplainIn(namespace, r))
+			}
+			fmt.Printf("   %s\n\n", truncateText(r.Text, 200))
+		}
+	})
+	return nil
+}
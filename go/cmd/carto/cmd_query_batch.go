@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/storage"
+)
+
+// batchQuery is one line of a query --batch file. Fields left out take
+// the value of the matching command-line flag.
+type batchQuery struct {
+	Text    string `json:"text"`
+	Tier    string `json:"tier,omitempty"`
+	K       int    `json:"k,omitempty"`
+	Project string `json:"project,omitempty"`
+	Module  string `json:"module,omitempty"`
+}
+
+// batchResult is written to stdout for each query line, in input order.
+// Results is null when Error is set.
+type batchResult struct {
+	Line    int                    `json:"line"`
+	Text    string                 `json:"text,omitempty"`
+	Results []storage.SearchResult `json:"results"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// runQueryBatch runs every query in the JSON Lines file at path ("-" for
+// stdin) and writes one batchResult per non-blank line. A line that fails
+// to parse or query gets an error result; the rest of the batch still runs.
+func runQueryBatch(cmd *cobra.Command, path string, defaults batchQuery, memories *storage.MemoriesClient, namespace string) error {
+	var in io.Reader = cmd.InOrStdin()
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return newConfigError(fmt.Sprintf("open batch file: %v", err))
+		}
+		defer f.Close()
+		in = f
+	}
+
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	ran, failed := 0, 0
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		ran++
+		res := batchResult{Line: line}
+		q, err := parseBatchQuery(text, defaults)
+		if err == nil {
+			res.Text = q.Text
+			res.Results, err = runBatchQuery(q, memories, namespace)
+		}
+		if err != nil {
+			failed++
+			res.Results = nil
+			res.Error = err.Error()
+		} else if res.Results == nil {
+			res.Results = []storage.SearchResult{}
+		}
+		if err := enc.Encode(res); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read batch file: %w", err)
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "%sRan %d queries, %d failed%s\n", stone, ran, failed, reset)
+	logAuditEvent(cmd, "ok", "", map[string]any{"queries": ran, "failed": failed})
+	return nil
+}
+
+// parseBatchQuery decodes one batch line and fills unset fields from
+// defaults.
+func parseBatchQuery(line string, defaults batchQuery) (batchQuery, error) {
+	var q batchQuery
+	if err := json.Unmarshal([]byte(line), &q); err != nil {
+		return q, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if strings.TrimSpace(q.Text) == "" {
+		return q, fmt.Errorf(`missing "text"`)
+	}
+	if q.Tier == "" {
+		q.Tier = defaults.Tier
+	}
+	switch storage.Tier(q.Tier) {
+	case storage.TierMini, storage.TierStandard, storage.TierFull:
+	default:
+		return q, fmt.Errorf("invalid tier: %s (use mini, standard, or full)", q.Tier)
+	}
+	if q.K < 0 {
+		return q, fmt.Errorf("k must be > 0")
+	}
+	if q.K == 0 {
+		q.K = defaults.K
+	}
+	if q.Project == "" {
+		q.Project = defaults.Project
+	}
+	if q.Module != "" && q.Project == "" {
+		return q, fmt.Errorf(`"module" requires a project`)
+	}
+	return q, nil
+}
+
+// runBatchQuery answers one query. Within a project it uses the same tier
+// retrieval as query --interactive; otherwise it searches every project.
+func runBatchQuery(q batchQuery, memories *storage.MemoriesClient, namespace string) ([]storage.SearchResult, error) {
+	if q.Project == "" {
+		return memories.Search(q.Text, storage.SearchOptions{K: q.K, Hybrid: true})
+	}
+	s := &querySession{
+		memories:  memories,
+		store:     storage.NewStore(memories, q.Project, namespace),
+		namespace: namespace,
+		project:   q.Project,
+		tier:      storage.Tier(q.Tier),
+		k:         q.K,
+		module:    q.Module,
+	}
+	return s.query(q.Text)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/divyekant/carto/internal/storage"
+)
+
+// replPrompt is shown before each line in query --interactive.
+const replPrompt = "carto> "
+
+// lineReader yields one line of input at a time. *term.Terminal satisfies
+// it for interactive sessions; scannerLines does for piped input.
+type lineReader interface {
+	ReadLine() (string, error)
+}
+
+// scannerLines adapts a bufio.Scanner to lineReader.
+type scannerLines struct {
+	sc *bufio.Scanner
+}
+
+func (s scannerLines) ReadLine() (string, error) {
+	if !s.sc.Scan() {
+		if err := s.sc.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return s.sc.Text(), nil
+}
+
+// querySession is the state of a query --interactive session. The
+// Memories client is shared by every query in the session.
+type querySession struct {
+	memories  *storage.MemoriesClient
+	store     *storage.Store
+	namespace string
+	project   string
+	tier      storage.Tier
+	k         int
+	module    string
+}
+
+// loop reads lines until /quit or end of input, running each as a query
+// or slash-command and writing results to out.
+func (s *querySession) loop(in lineReader, out io.Writer) error {
+	for {
+		line, err := in.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if s.handle(line, out) {
+			return nil
+		}
+	}
+}
+
+// handle runs one line of input. It reports whether the session should end.
+func (s *querySession) handle(line string, out io.Writer) (quit bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+	if !strings.HasPrefix(line, "/") {
+		s.runQuery(line, out)
+		return false
+	}
+
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case "/quit", "/exit", "/q":
+		return true
+	case "/tier":
+		switch storage.Tier(arg) {
+		case storage.TierMini, storage.TierStandard, storage.TierFull:
+			s.tier = storage.Tier(arg)
+			fmt.Fprintf(out, "tier: %s\n", s.tier)
+		default:
+			fmt.Fprintf(out, "%serror:%s usage: /tier mini|standard|full\n", red, reset)
+		}
+	case "/k":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			fmt.Fprintf(out, "%serror:%s usage: /k N (N > 0)\n", red, reset)
+			return false
+		}
+		s.k = n
+		fmt.Fprintf(out, "k: %d\n", s.k)
+	case "/module":
+		s.module = arg
+		if s.module == "" {
+			fmt.Fprintln(out, "module: (all)")
+		} else {
+			fmt.Fprintf(out, "module: %s\n", s.module)
+		}
+	case "/help":
+		fmt.Fprintln(out, "Type a question to query, or:")
+		fmt.Fprintln(out, "  /tier mini|standard|full   set the context tier")
+		fmt.Fprintln(out, "  /k N                       set the number of results")
+		fmt.Fprintln(out, "  /module NAME               restrict to a module (no name clears it)")
+		fmt.Fprintln(out, "  /quit                      end the session")
+	default:
+		fmt.Fprintf(out, "%serror:%s unknown command %s (try /help)\n", red, reset, cmd)
+	}
+	return false
+}
+
+// runQuery runs a query and prints its results.
+func (s *querySession) runQuery(text string, out io.Writer) {
+	results, err := s.query(text)
+	if err != nil {
+		fmt.Fprintf(out, "%serror:%s %v\n", red, reset, err)
+		return
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(out, "  No results found.")
+		return
+	}
+	for i, r := range results {
+		_, module, layer, _ := storage.ParseSourceTagIn(s.namespace, r.Source)
+		fmt.Fprintf(out, "%s%d.%s %s[%s]%s %s", bold, i+1, reset, gold, layer, reset, module)
+		if r.Score != 0 {
+			fmt.Fprintf(out, "  %sscore:%s %.4f", gold, reset, r.Score)
+		}
+		fmt.Fprintf(out, "\n   %s\n", truncateText(r.Text, 200))
+	}
+}
+
+// query returns up to k results for text at the session's tier. With a
+// module set, the module's tier context from RetrieveByTier is ranked by
+// how many query terms each entry contains; otherwise the project is
+// searched and results from layers outside the tier are dropped.
+func (s *querySession) query(text string) ([]storage.SearchResult, error) {
+	if s.module != "" {
+		layers, err := s.store.RetrieveByTier(s.module, s.tier)
+		if err != nil {
+			return nil, err
+		}
+		return rankByTerms(layers, text, s.k), nil
+	}
+
+	// Over-fetch so enough results survive the tier filter.
+	results, err := s.memories.Search(text, storage.SearchOptions{
+		K:            s.k * 3,
+		Hybrid:       true,
+		SourcePrefix: storage.ProjectPrefix(s.namespace, s.project),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var kept []storage.SearchResult
+	for _, r := range results {
+		if _, _, layer, ok := storage.ParseSourceTagIn(s.namespace, r.Source); ok && s.tier.Includes(layer) {
+			kept = append(kept, r)
+			if len(kept) == s.k {
+				break
+			}
+		}
+	}
+	return kept, nil
+}
+
+// rankByTerms flattens tier results and keeps the k entries matching the
+// most query terms, dropping entries that match none.
+func rankByTerms(layers map[string][]storage.SearchResult, text string, k int) []storage.SearchResult {
+	terms := strings.Fields(strings.ToLower(text))
+
+	names := make([]string, 0, len(layers))
+	for layer := range layers {
+		names = append(names, layer)
+	}
+	sort.Strings(names)
+
+	type scored struct {
+		r     storage.SearchResult
+		score int
+	}
+	var matches []scored
+	for _, layer := range names {
+		for _, r := range layers[layer] {
+			lower := strings.ToLower(r.Text)
+			score := 0
+			for _, t := range terms {
+				if strings.Contains(lower, t) {
+					score++
+				}
+			}
+			if score > 0 {
+				matches = append(matches, scored{r, score})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	var out []storage.SearchResult
+	for _, m := range matches {
+		if len(out) == k {
+			break
+		}
+		out = append(out, m.r)
+	}
+	return out
+}
+
+// runQueryInteractive starts a query session for project. On a terminal it
+// runs a line-editing loop with history; otherwise it answers the first
+// line of stdin and exits.
+func runQueryInteractive(s *querySession) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := scannerLines{bufio.NewScanner(os.Stdin)}.ReadLine()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		s.handle(line, os.Stdout)
+		return nil
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("interactive mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, replPrompt)
+	fmt.Fprintf(t, "%s%sCarto query session: %s%s (tier: %s, k: %d). /help for commands.\n", bold, gold, s.project, reset, s.tier, s.k)
+	return s.loop(t, t)
+}
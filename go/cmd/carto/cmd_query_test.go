@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/divyekant/carto/internal/storage"
+)
+
+func TestQueryCmd_ExplainShowsComponentScores(t *testing.T) {
+	withCleanEnv(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{{
+				"id":            1,
+				"text":          "func Serve()",
+				"source":        "carto/myapp/internal/api/layer:atoms",
+				"score":         0.91,
+				"vector_score":  0.84,
+				"lexical_score": 0.42,
+			}},
+		})
+	}))
+	defer srv.Close()
+	t.Setenv("MEMORIES_URL", srv.URL)
+
+	out, err := execCmd(t, testRoot(queryCmd()), []string{"query", "serve", "--explain", "--json"})
+	if err != nil {
+		t.Fatalf("query --explain: %v", err)
+	}
+
+	var env struct {
+		Data []explainedResult `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, out)
+	}
+	if len(env.Data) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(env.Data))
+	}
+	e := env.Data[0].Explain
+	if e.Module != "internal/api" || e.Layer != "atoms" || e.Tier != "standard" {
+		t.Errorf("unexpected origin: %+v", e)
+	}
+	if e.VectorScore == nil || *e.VectorScore != 0.84 {
+		t.Errorf("vector score = %v, want 0.84", e.VectorScore)
+	}
+	if e.LexicalScore == nil || *e.LexicalScore != 0.42 {
+		t.Errorf("lexical score = %v, want 0.42", e.LexicalScore)
+	}
+}
+
+func TestQueryCmd_NoExplainOmitsDetail(t *testing.T) {
+	withCleanEnv(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{{"id": 1, "text": "x", "source": "carto/myapp/m/layer:atoms", "score": 0.5}},
+		})
+	}))
+	defer srv.Close()
+	t.Setenv("MEMORIES_URL", srv.URL)
+
+	out, err := execCmd(t, testRoot(queryCmd()), []string{"query", "x", "--json"})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	var env struct {
+		Data []map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, out)
+	}
+	if len(env.Data) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(env.Data))
+	}
+	if _, ok := env.Data[0]["explain"]; ok {
+		t.Error("explain should be absent without --explain")
+	}
+}
+
+func TestQueryCmd_GroupCollapsesLayers(t *testing.T) {
+	withCleanEnv(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{
+				{"id": 1, "text": "func Serve()", "source": "carto/myapp/api/layer:atoms/serve.go:8", "score": 0.9},
+				{"id": 2, "text": "api wiring", "source": "carto/myapp/api/layer:wiring", "score": 0.7},
+				{"id": 3, "text": "api zones", "source": "carto/myapp/api/layer:zones", "score": 0.5},
+			},
+		})
+	}))
+	defer srv.Close()
+	t.Setenv("MEMORIES_URL", srv.URL)
+
+	out, err := execCmd(t, testRoot(queryCmd()), []string{"query", "serve", "--group", "--json"})
+	if err != nil {
+		t.Fatalf("query --group: %v", err)
+	}
+	var env struct {
+		Data []groupedResult `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, out)
+	}
+	if len(env.Data) != 1 {
+		t.Fatalf("expected 1 grouped result, got %d: %s", len(env.Data), out)
+	}
+	if got := strings.Join(env.Data[0].Layers, ","); got != "atoms,wiring,zones" {
+		t.Errorf("layers = %s, want atoms,wiring,zones", got)
+	}
+	if env.Data[0].Text != "func Serve()" {
+		t.Errorf("representative = %q, want the best-scoring atom", env.Data[0].Text)
+	}
+}
+
+func TestQuerySession_ScriptedCommandsChangeQueries(t *testing.T) {
+	var ks []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			var body struct {
+				K            int    `json:"k"`
+				SourcePrefix string `json:"source_prefix"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			ks = append(ks, body.K)
+			if body.SourcePrefix != "carto/myapp/" {
+				t.Errorf("source_prefix = %q", body.SourcePrefix)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"results": []map[string]any{
+				{"id": 1, "text": "zone: auth", "source": "carto/myapp/api/layer:zones", "score": 0.9},
+				{"id": 2, "text": "atom: Login", "source": "carto/myapp/api/layer:atoms", "score": 0.8},
+				{"id": 3, "text": "history: auth.go", "source": "carto/myapp/api/layer:history", "score": 0.7},
+				{"id": 4, "text": "zone: billing", "source": "carto/myapp/billing/layer:zones", "score": 0.6},
+			}})
+		case "/memories":
+			json.NewEncoder(w).Encode(map[string]any{"memories": []map[string]any{
+				{"id": 5, "text": "wiring: auth calls db", "source": r.URL.Query().Get("source")},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := storage.NewMemoriesClient(srv.URL, "")
+	s := &querySession{
+		memories:  client,
+		store:     storage.NewStore(client, "myapp"),
+		namespace: storage.DefaultNamespace,
+		project:   "myapp",
+		tier:      storage.TierStandard,
+		k:         10,
+	}
+
+	// The acknowledgement each slash-command prints splits the output into
+	// steps that can be checked on their own.
+	script := strings.Join([]string{
+		"auth",
+		"/tier mini",
+		"auth",
+		"/k 1",
+		"auth",
+		"/module api",
+		"/tier standard",
+		"auth",
+		"/quit",
+		"never run",
+	}, "\n")
+	var out bytes.Buffer
+	if err := s.loop(scannerLines{bufio.NewScanner(strings.NewReader(script))}, &out); err != nil {
+		t.Fatalf("loop: %v", err)
+	}
+
+	if fmt.Sprint(ks) != "[30 30 3]" {
+		t.Errorf("search k values = %v, want [30 30 3]", ks)
+	}
+
+	steps := strings.Split(out.String(), "tier: mini")
+	if len(steps) != 2 {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	standard, rest := steps[0], steps[1]
+	if !strings.Contains(standard, "atom: Login") || strings.Contains(standard, "history:") {
+		t.Errorf("standard tier should include atoms but not history:\n%s", standard)
+	}
+
+	mini, rest, _ := strings.Cut(rest, "k: 1")
+	if strings.Contains(mini, "atom: Login") || !strings.Contains(mini, "zone: billing") {
+		t.Errorf("mini tier should only include zones:\n%s", mini)
+	}
+
+	k1, moduleOut, _ := strings.Cut(rest, "module: api")
+	if strings.Count(k1, "zone:") != 1 {
+		t.Errorf("k=1 should print one result:\n%s", k1)
+	}
+
+	if !strings.Contains(moduleOut, "wiring: auth calls db") {
+		t.Errorf("module query should use the module's tier context:\n%s", moduleOut)
+	}
+	if strings.Contains(out.String(), "never run") {
+		t.Error("input after /quit should not be processed")
+	}
+}
+
+func TestQueryCmd_BatchReportsPerLineErrors(t *testing.T) {
+	withCleanEnv(t)
+
+	var searches []storage.SearchOptions
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Query        string `json:"query"`
+			K            int    `json:"k"`
+			SourcePrefix string `json:"source_prefix"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		searches = append(searches, storage.SearchOptions{K: body.K, SourcePrefix: body.SourcePrefix})
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{
+				{"id": 1, "text": "answer to " + body.Query, "source": "carto/myapp/api/layer:zones", "score": 0.9},
+				{"id": 2, "text": "history of " + body.Query, "source": "carto/myapp/api/layer:history", "score": 0.8},
+			},
+		})
+	}))
+	defer srv.Close()
+	t.Setenv("MEMORIES_URL", srv.URL)
+
+	batch := filepath.Join(t.TempDir(), "queries.jsonl")
+	os.WriteFile(batch, []byte(
+		`{"text": "where is auth", "k": 5}`+"\n"+
+			`{"text": "broken`+"\n"+
+			`{"text": "how are zones stored", "tier": "mini", "project": "myapp"}`+"\n",
+	), 0o644)
+
+	cmd := testRoot(queryCmd())
+	var stdout bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"query", "--batch", batch})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("query --batch: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 result lines, got %d:\n%s", len(lines), stdout.String())
+	}
+	var results []batchResult
+	for _, l := range lines {
+		var r batchResult
+		if err := json.Unmarshal([]byte(l), &r); err != nil {
+			t.Fatalf("parse %q: %v", l, err)
+		}
+		results = append(results, r)
+	}
+
+	if r := results[0]; r.Line != 1 || r.Error != "" || len(r.Results) != 2 || r.Results[0].Text != "answer to where is auth" {
+		t.Errorf("line 1 = %+v", r)
+	}
+	if r := results[1]; r.Line != 2 || r.Error == "" || r.Results != nil {
+		t.Errorf("line 2 should carry an error: %+v", r)
+	}
+	// The project query is filtered to the mini tier, dropping history.
+	if r := results[2]; r.Line != 3 || r.Error != "" || len(r.Results) != 1 || r.Results[0].Text != "answer to how are zones stored" {
+		t.Errorf("line 3 = %+v", r)
+	}
+
+	if len(searches) != 2 || searches[0].K != 5 || searches[1].SourcePrefix != "carto/myapp/" {
+		t.Errorf("searches = %+v", searches)
+	}
+}
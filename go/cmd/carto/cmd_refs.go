@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/analyzer"
+	"github.com/divyekant/carto/internal/config"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+func refsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "refs <project> <symbol>",
+		Short: "Find what a symbol depends on and what depends on it, from stored wiring",
+		Long: `Find references to a symbol in a project's stored wiring.
+
+Every wiring edge whose source or target matches the symbol is listed under
+its module: "uses" edges start at the symbol, "used by" edges end at it.
+Matching ignores case and accepts any node name containing the symbol;
+--exact requires the whole name to match.`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeProjectArg,
+		RunE:              runRefs,
+	}
+	cmd.Flags().Bool("exact", false, "Match whole node names only instead of substrings")
+	return cmd
+}
+
+func runRefs(cmd *cobra.Command, args []string) error {
+	project, symbol := args[0], args[1]
+	exact, _ := cmd.Flags().GetBool("exact")
+
+	cfg := config.Load()
+	memoriesClient := storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey)
+	store := storage.NewStore(memoriesClient, project, cfg.MemoriesNamespace)
+
+	layers, err := store.RetrieveLayerAllModules(storage.LayerWiring)
+	if err != nil {
+		err = newConnectionError("failed to read wiring: " + err.Error())
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+
+	wiring := make(map[string][]analyzer.Dependency, len(layers))
+	for module, results := range layers {
+		for _, res := range results {
+			var deps []analyzer.Dependency
+			if jsonErr := json.Unmarshal([]byte(res.Text), &deps); jsonErr != nil {
+				continue
+			}
+			wiring[module] = append(wiring[module], deps...)
+		}
+	}
+
+	refs := analyzer.FindReferences(wiring, symbol, exact)
+	if refs == nil {
+		refs = []analyzer.ModuleRefs{}
+	}
+
+	writeEnvelopeHuman(cmd, refs, nil, func() {
+		fmt.Printf("%s%sReferences to %q in %s%s\n\n", bold, gold, symbol, project, reset)
+
+		if len(refs) == 0 {
+			if len(wiring) == 0 {
+				fmt.Println("  No wiring found. Index the project first.")
+			} else {
+				fmt.Println("  No references found.")
+			}
+			return
+		}
+
+		for _, r := range refs {
+			fmt.Printf("%s%s%s\n", bold, r.Module, reset)
+			for _, e := range r.Uses {
+				fmt.Printf("  %suses%s     %s → %s", green, reset, e.From, e.To)
+				printReason(e.Reason)
+			}
+			for _, e := range r.UsedBy {
+				fmt.Printf("  %sused by%s  %s → %s", amber, reset, e.From, e.To)
+				printReason(e.Reason)
+			}
+			fmt.Println()
+		}
+	})
+
+	return nil
+}
+
+// printReason ends a refs edge line with the edge's reason, if any.
+func printReason(reason string) {
+	if reason == "" {
+		fmt.Println()
+		return
+	}
+	fmt.Printf("  %s%s%s\n", stone, reason, reset)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/config"
+	"github.com/divyekant/carto/internal/report"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+func reportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report <project>",
+		Short: "Generate a self-contained HTML architecture report",
+		Long: `Generate a static HTML report for an indexed project from its stored
+layers: blueprint, patterns, module dependency graph, hotspots, and
+per-module zones and wiring. The file has no external dependencies and
+can be opened offline.
+
+Examples:
+  carto report myapp
+  carto report myapp --out docs/architecture.html`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProjectArg,
+		RunE:              runReport,
+	}
+	cmd.Flags().StringP("out", "o", "report.html", "Output file path")
+	return cmd
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	project := args[0]
+	out, _ := cmd.Flags().GetString("out")
+
+	cfg := config.Load()
+	memoriesClient := storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey)
+	store := storage.NewStore(memoriesClient, project, cfg.MemoriesNamespace)
+
+	rep, err := report.Load(store, project, time.Now())
+	if err != nil {
+		err = newConnectionError("failed to read stored layers: " + err.Error())
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+
+	absOut, err := filepath.Abs(out)
+	if err != nil {
+		return fmt.Errorf("resolve output path: %w", err)
+	}
+	f, err := os.Create(absOut)
+	if err != nil {
+		return fmt.Errorf("create report: %w", err)
+	}
+	if err := report.Render(f, rep); err != nil {
+		f.Close()
+		return fmt.Errorf("render report: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	data := map[string]any{
+		"project":  project,
+		"path":     absOut,
+		"modules":  len(rep.Modules),
+		"hotspots": len(rep.Hotspots),
+	}
+	writeEnvelopeHuman(cmd, data, nil, func() {
+		fmt.Printf("%s✓%s Report for %s%s%s written to %s\n", green, reset, bold, project, reset, absOut)
+		fmt.Printf("  modules: %d, hotspots: %d\n", len(rep.Modules), len(rep.Hotspots))
+		if rep.Blueprint == "" {
+			fmt.Printf("  %snote:%s no blueprint stored — run a full index first\n", amber, reset)
+		}
+	})
+	return nil
+}
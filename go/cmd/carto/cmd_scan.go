@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/scanner"
+)
+
+func scanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan <path>",
+		Short: "List the files and modules a scan finds, without indexing",
+		Long: `Scan a codebase and list every file Carto would index, grouped by the
+module it belongs to. No LLM or Memories server is needed.
+
+With --json the output is the full file/module graph: each file's relative
+path, language, size and module, and each module's name, type, path, file
+count and manifest.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runScan,
+	}
+	cmd.Flags().Bool("include-submodules", false, "Also scan the git submodules in .gitmodules as modules")
+	return cmd
+}
+
+type scanFile struct {
+	RelPath  string `json:"rel_path"`
+	Language string `json:"language"`
+	Size     int64  `json:"size"`
+	Module   string `json:"module"`
+}
+
+type scanModule struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	RelPath   string `json:"rel_path"`
+	FileCount int    `json:"file_count"`
+	Manifest  string `json:"manifest"`
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	absPath, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("resolve path: %w", err)
+	}
+
+	includeSubmodules, _ := cmd.Flags().GetBool("include-submodules")
+	result, err := scanner.ScanWithOptions(absPath, scanner.Options{IncludeSubmodules: includeSubmodules})
+	if err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+
+	moduleOf := make(map[string]string, len(result.Files))
+	modules := make([]scanModule, 0, len(result.Modules))
+	for _, mod := range result.Modules {
+		for _, f := range mod.Files {
+			moduleOf[f] = mod.Name
+		}
+		modules = append(modules, scanModule{
+			Name:      mod.Name,
+			Type:      mod.Type,
+			RelPath:   mod.RelPath,
+			FileCount: len(mod.Files),
+			Manifest:  mod.Manifest,
+		})
+	}
+
+	files := make([]scanFile, 0, len(result.Files))
+	byPath := make(map[string]scanFile, len(result.Files))
+	for _, f := range result.Files {
+		sf := scanFile{
+			RelPath:  f.RelPath,
+			Language: f.Language,
+			Size:     f.Size,
+			Module:   moduleOf[f.RelPath],
+		}
+		files = append(files, sf)
+		byPath[f.RelPath] = sf
+	}
+
+	data := struct {
+		Root    string       `json:"root"`
+		Files   []scanFile   `json:"files"`
+		Modules []scanModule `json:"modules"`
+	}{absPath, files, modules}
+
+	writeEnvelopeHuman(cmd, data, nil, func() {
+		fmt.Printf("%s%sScan of %s%s\n\n", bold, gold, absPath, reset)
+
+		if len(files) == 0 {
+			fmt.Println("  No files found.")
+			return
+		}
+
+		for _, mod := range result.Modules {
+			relPath := mod.RelPath
+			if relPath == "" {
+				relPath = "."
+			}
+			fmt.Printf("%s%s%s %s(%s, %s)%s\n", bold, mod.Name, reset, stone, mod.Type, relPath, reset)
+			for _, rel := range mod.Files {
+				f := byPath[rel]
+				fmt.Printf("  %-60s %-12s %10s\n", f.RelPath, f.Language, formatBytes(f.Size))
+			}
+			fmt.Println()
+		}
+
+		fmt.Printf("  %sTotal:%s %d file(s), %d module(s)\n", bold, reset, len(files), len(modules))
+	})
+
+	return nil
+}
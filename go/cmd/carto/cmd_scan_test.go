@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanCmd_JSON(t *testing.T) {
+	withCleanEnv(t)
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "api"), 0o755)
+	os.MkdirAll(filepath.Join(dir, "web"), 0o755)
+	os.WriteFile(filepath.Join(dir, "api", "go.mod"), []byte("module example.com/api\n\ngo 1.21\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "api", "main.go"), []byte("package main\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "web", "package.json"), []byte(`{"name": "web"}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "web", "index.js"), []byte("export {}\n"), 0o644)
+
+	out, err := execCmd(t, testRoot(scanCmd()), []string{"scan", dir, "--json"})
+	if err != nil {
+		t.Fatalf("scan: %v\n%s", err, out)
+	}
+	var env struct {
+		Data struct {
+			Root    string       `json:"root"`
+			Files   []scanFile   `json:"files"`
+			Modules []scanModule `json:"modules"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, out)
+	}
+
+	if env.Data.Root != dir {
+		t.Errorf("root = %q, want %q", env.Data.Root, dir)
+	}
+	modules := map[string]scanModule{}
+	for _, m := range env.Data.Modules {
+		modules[m.RelPath] = m
+	}
+	if api := modules["api"]; api.Type != "go" || api.Manifest != "api/go.mod" || api.FileCount != 2 {
+		t.Errorf("api module = %+v", api)
+	}
+	if web := modules["web"]; web.Name != "web" || web.Type != "node" {
+		t.Errorf("web module = %+v", web)
+	}
+
+	files := map[string]scanFile{}
+	for _, f := range env.Data.Files {
+		files[f.RelPath] = f
+	}
+	if f := files["api/main.go"]; f.Module != modules["api"].Name || f.Language != "go" || f.Size != 13 {
+		t.Errorf("api/main.go = %+v", f)
+	}
+	if f := files["web/index.js"]; f.Module != "web" || f.Language != "javascript" {
+		t.Errorf("web/index.js = %+v", f)
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"runtime"
 	"syscall"
 	"time"
 
@@ -28,22 +31,41 @@ func serveCmd() *cobra.Command {
 		RunE:  runServe,
 	}
 	cmd.Flags().String("port", "8950", "Port to listen on")
+	cmd.Flags().String("host", "", "Interface to listen on (default: all interfaces)")
+	cmd.Flags().Bool("open", false, "Open the web UI in the default browser once the server is listening")
 	cmd.Flags().String("projects-dir", "", "Directory containing indexed projects")
+	cmd.Flags().Bool("metrics", false, "Expose Prometheus metrics at /metrics")
+	cmd.Flags().String("base-path", "", "URL path prefix to serve under behind a reverse proxy, e.g. /carto")
+	cmd.Flags().Int("query-cache-size", server.DefaultQueryCacheSize, "Query results to cache in memory (0 disables the cache)")
+	cmd.Flags().Duration("query-cache-ttl", server.DefaultQueryCacheTTL, "How long a cached query result stays fresh")
 	return cmd
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
 	port, _ := cmd.Flags().GetString("port")
+	host, _ := cmd.Flags().GetString("host")
+	openBrowser, _ := cmd.Flags().GetBool("open")
 	projectsDir, _ := cmd.Flags().GetString("projects-dir")
+	enableMetrics, _ := cmd.Flags().GetBool("metrics")
+	basePath, _ := cmd.Flags().GetString("base-path")
+	cacheSize, _ := cmd.Flags().GetInt("query-cache-size")
+	cacheTTL, _ := cmd.Flags().GetDuration("query-cache-ttl")
 
 	// Set config persistence path inside the projects directory so it
-	// survives container restarts (the projects dir is a mounted volume).
-	if projectsDir != "" {
+	// survives container restarts (the projects dir is a mounted volume),
+	// unless --config or CARTO_CONFIG names a file explicitly.
+	if projectsDir != "" && config.ConfigPath == "" && os.Getenv("CARTO_CONFIG") == "" {
 		config.ConfigPath = filepath.Join(projectsDir, ".carto-server.json")
 	}
 
 	cfg := config.Load()
 
+	stopTracing, err := startTracing(cfg.OTLPEndpoint)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
 	memoriesClient := storage.NewMemoriesClient(config.ResolveURL(cfg.MemoriesURL), cfg.MemoriesKey)
 
 	// Extract the dist subdirectory from the embedded FS.
@@ -53,6 +75,12 @@ func runServe(cmd *cobra.Command, args []string) error {
 	}
 
 	srv := server.New(cfg, memoriesClient, projectsDir, distFS)
+	if enableMetrics {
+		srv.EnableMetrics()
+	}
+	srv.SetBasePath(basePath)
+	basePath = srv.BasePath()
+	srv.SetQueryCache(cacheSize, cacheTTL)
 
 	// Warn operators when auth is disabled so it is not overlooked in production.
 	if cfg.ServerToken == "" {
@@ -62,27 +90,44 @@ func runServe(cmd *cobra.Command, args []string) error {
 		)
 	}
 
-	fmt.Printf("%s%sCarto server%s starting on http://localhost:%s\n", bold, gold, reset, port)
-
 	// Build an http.Server with sane production timeouts.
 	// WriteTimeout is generous (10 min) because SSE progress streams for large
 	// codebases can legitimately run for several minutes.
 	httpSrv := &http.Server{
-		Addr:         ":" + port,
 		Handler:      srv,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 10 * time.Minute,
 		IdleTimeout:  120 * time.Second,
 	}
 
+	// Listen before announcing the URL so it carries the real port (--port 0
+	// picks a free one) and the browser never opens on a dead address.
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	_, port, _ = net.SplitHostPort(ln.Addr().String())
+	url := serveURL(host, port, basePath)
+
+	fmt.Printf("%s%sCarto server%s listening on %s\n", bold, gold, reset, url)
+	if enableMetrics {
+		fmt.Printf("  metrics: %smetrics\n", url)
+	}
+
 	// Start the server in a goroutine so we can listen for OS shutdown signals.
 	serverErr := make(chan error, 1)
 	go func() {
-		if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := httpSrv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			serverErr <- fmt.Errorf("server error: %w", err)
 		}
 	}()
 
+	if openBrowser {
+		if err := openURL(url); err != nil {
+			fmt.Fprintf(os.Stderr, "%snote:%s not opening a browser (%v); visit %s\n", amber, reset, err, url)
+		}
+	}
+
 	// Block until we receive SIGINT/SIGTERM or the server errors out.
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -111,3 +156,37 @@ func runServe(cmd *cobra.Command, args []string) error {
 	slog.Info("server_stopped", "status", "clean")
 	return nil
 }
+
+// serveURL returns the URL the web UI is reachable at when the server
+// listens on host and port under basePath. A server bound to every
+// interface (no host, 0.0.0.0 or ::) is reached through localhost.
+func serveURL(host, port, basePath string) string {
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port) + basePath + "/"
+}
+
+// openURL opens url in the default browser. Where no browser can be
+// started, such as a headless Linux box or a container, it returns an
+// error saying why instead.
+func openURL(url string) error {
+	var name string
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		name = "open"
+	case "windows":
+		name, args = "rundll32", []string{"url.dll,FileProtocolHandler"}
+	default:
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return errors.New("no display")
+		}
+		name = "xdg-open"
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("%s not found", name)
+	}
+	return exec.Command(path, append(args, url)...).Start()
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestServeURL(t *testing.T) {
+	tests := []struct {
+		host, port, basePath string
+		want                 string
+	}{
+		{"", "8950", "", "http://localhost:8950/"},
+		{"0.0.0.0", "8950", "", "http://localhost:8950/"},
+		{"::", "9000", "/carto", "http://localhost:9000/carto/"},
+		{"127.0.0.1", "8950", "/carto", "http://127.0.0.1:8950/carto/"},
+		{"carto.internal", "80", "", "http://carto.internal:80/"},
+		{"::1", "8950", "", "http://[::1]:8950/"},
+	}
+	for _, tt := range tests {
+		if got := serveURL(tt.host, tt.port, tt.basePath); got != tt.want {
+			t.Errorf("serveURL(%q, %q, %q) = %q, want %q", tt.host, tt.port, tt.basePath, got, tt.want)
+		}
+	}
+}
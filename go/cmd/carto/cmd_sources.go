@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/divyekant/carto/internal/config"
+	"github.com/divyekant/carto/internal/gitclone"
 	"github.com/divyekant/carto/internal/sources"
 )
 
@@ -19,16 +23,26 @@ func sourcesCmd() *cobra.Command {
 	cmd.AddCommand(sourcesListCmd())
 	cmd.AddCommand(sourcesSetCmd())
 	cmd.AddCommand(sourcesRmCmd())
+	cmd.AddCommand(sourcesTestCmd())
 	return cmd
 }
 
 func sourcesListCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list <project>",
 		Short: "List configured sources for a project",
-		Args:  cobra.ExactArgs(1),
-		RunE:  runSourcesList,
+		Long: `List the sources configured in a project's .carto/sources.yaml.
+
+With --effective, list the sources an index would actually use instead:
+the registry is built with the current credentials, so it includes
+auto-detected sources (git always; github, local-pdf and adr when there is
+no sources.yaml) and notes the configured sources skipped for bad config.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProjectArg,
+		RunE:              runSourcesList,
 	}
+	cmd.Flags().Bool("effective", false, "List the sources an index would actually use, including auto-detected ones")
+	return cmd
 }
 
 func runSourcesList(cmd *cobra.Command, args []string) error {
@@ -43,6 +57,10 @@ func runSourcesList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("load sources: %w", err)
 	}
 
+	if effective, _ := cmd.Flags().GetBool("effective"); effective {
+		return listEffectiveSources(cmd, args[0], projectPath, srcCfg)
+	}
+
 	if srcCfg == nil || len(srcCfg.Sources) == 0 {
 		writeEnvelopeHuman(cmd, map[string]interface{}{"sources": map[string]interface{}{}}, nil, func() {
 			fmt.Println("No sources configured.")
@@ -78,12 +96,88 @@ func runSourcesList(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// effectiveSource is one source of a project's built registry.
+type effectiveSource struct {
+	Name   string `json:"name"`
+	Scope  string `json:"scope"`  // "project" or "module"
+	Origin string `json:"origin"` // "sources.yaml" or "auto-detected"
+}
+
+// skippedSource is a configured source the registry left out.
+type skippedSource struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// listEffectiveSources prints the sources an index of the project would
+// use, and the configured ones skipped for failing to configure.
+func listEffectiveSources(cmd *cobra.Command, projectName, projectPath string, srcCfg *sources.SourcesYAML) error {
+	if info, err := os.Stat(projectPath); err != nil || !info.IsDir() {
+		err := newNotFoundError(fmt.Sprintf("project %q not found", projectName))
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+
+	reg := projectRegistry(projectName, projectPath, srcCfg)
+
+	active := []effectiveSource{}
+	for _, src := range reg.Sources() {
+		s := effectiveSource{Name: src.Name(), Scope: "project", Origin: "auto-detected"}
+		if src.Scope() == sources.ModuleScope {
+			s.Scope = "module"
+		}
+		if srcCfg != nil {
+			if _, ok := srcCfg.Sources[src.Name()]; ok {
+				s.Origin = "sources.yaml"
+			}
+		}
+		active = append(active, s)
+	}
+	skipped := []skippedSource{}
+	for name, err := range reg.Skipped() {
+		skipped = append(skipped, skippedSource{Name: name, Error: err.Error()})
+	}
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Name < skipped[j].Name })
+
+	data := map[string]any{"project": projectName, "sources": active, "skipped": skipped}
+	writeEnvelopeHuman(cmd, data, nil, func() {
+		fmt.Printf("%s%sEffective sources for %s%s\n\n", bold, gold, projectName, reset)
+		for _, s := range active {
+			fmt.Printf("  %s✓%s %-10s %-8s %s%s%s\n", green, reset, s.Name, s.Scope, stone, s.Origin, reset)
+		}
+		for _, s := range skipped {
+			fmt.Printf("  %s✗%s %-10s %sskipped: %s%s\n", red, reset, s.Name, amber, s.Error, reset)
+		}
+	})
+	return nil
+}
+
+// projectRegistry builds the project's source registry as an index would,
+// with the current credentials and the GitHub repo of its origin remote.
+func projectRegistry(projectName, projectPath string, srcCfg *sources.SourcesYAML) *sources.Registry {
+	cfg := config.Load()
+	owner, repo := gitclone.ParseOwnerRepo(gitclone.OriginURL(projectPath))
+	return sources.BuildRegistry(projectPath, srcCfg, sources.Credentials{
+		GitHubToken: cfg.GitHubToken,
+		GitHubOwner: owner,
+		GitHubRepo:  repo,
+		JiraToken:   cfg.JiraToken,
+		JiraEmail:   cfg.JiraEmail,
+		JiraBaseURL: cfg.JiraBaseURL,
+		LinearToken: cfg.LinearToken,
+		NotionToken: cfg.NotionToken,
+		SlackToken:  cfg.SlackToken,
+		Project:     projectName,
+	})
+}
+
 func sourcesSetCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "set <project> <type> [key=value ...]",
-		Short: "Set or update a source for a project",
-		Args:  cobra.MinimumNArgs(2),
-		RunE:  runSourcesSet,
+		Use:               "set <project> <type> [key=value ...]",
+		Short:             "Set or update a source for a project",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeProjectAndSourceType,
+		RunE:              runSourcesSet,
 	}
 }
 
@@ -142,10 +236,11 @@ func runSourcesSet(cmd *cobra.Command, args []string) error {
 
 func sourcesRmCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "rm <project> <type>",
-		Short: "Remove a source from a project",
-		Args:  cobra.ExactArgs(2),
-		RunE:  runSourcesRm,
+		Use:               "rm <project> <type>",
+		Short:             "Remove a source from a project",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeProjectAndSourceType,
+		RunE:              runSourcesRm,
 	}
 }
 
@@ -181,3 +276,71 @@ func runSourcesRm(cmd *cobra.Command, args []string) error {
 	})
 	return nil
 }
+
+func sourcesTestCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "test <project>",
+		Short:             "Check credentials and connectivity for each configured source",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProjectArg,
+		RunE:              runSourcesTest,
+	}
+}
+
+func runSourcesTest(cmd *cobra.Command, args []string) error {
+	projectsDir := os.Getenv("PROJECTS_DIR")
+	if projectsDir == "" {
+		return fmt.Errorf("PROJECTS_DIR environment variable is not set")
+	}
+
+	projectName := args[0]
+	projectPath := filepath.Join(projectsDir, projectName)
+	if info, err := os.Stat(projectPath); err != nil || !info.IsDir() {
+		return newNotFoundError(fmt.Sprintf("project %q not found", projectName))
+	}
+
+	srcCfg, err := sources.LoadSourcesConfig(projectPath)
+	if err != nil {
+		return fmt.Errorf("load sources: %w", err)
+	}
+
+	reg := projectRegistry(projectName, projectPath, srcCfg)
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	results := reg.ValidateAll(ctx)
+
+	var failed int
+	for _, r := range results {
+		if !r.OK {
+			failed++
+		}
+	}
+
+	// Per-source failures are part of the report, so the envelope is always
+	// written as data; the returned error only sets the exit status.
+	writeEnvelopeHuman(cmd, map[string]any{"project": projectName, "results": results}, nil, func() {
+		if len(results) == 0 {
+			fmt.Println("No sources configured.")
+			return
+		}
+		fmt.Printf("%sSources for %s:%s\n", bold, projectName, reset)
+		for _, r := range results {
+			switch {
+			case !r.OK:
+				fmt.Printf("  %s✗ FAIL%s  %-8s %s\n", red, reset, r.Source, r.Error)
+			case !r.Probed:
+				fmt.Printf("  %s✓ OK%s    %-8s %s(no remote check)%s\n", green, reset, r.Source, stone, reset)
+			default:
+				fmt.Printf("  %s✓ OK%s    %s\n", green, reset, r.Source)
+			}
+		}
+	})
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sources failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSourcesList_EffectiveIncludesAutoDetected(t *testing.T) {
+	withCleanEnv(t)
+	projectsDir := t.TempDir()
+	t.Setenv("PROJECTS_DIR", projectsDir)
+	if err := os.MkdirAll(filepath.Join(projectsDir, "demo", "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := execCmd(t, testRoot(sourcesCmd()), []string{"sources", "list", "demo", "--effective", "--json"})
+	if err != nil {
+		t.Fatalf("sources list --effective: %v\n%s", err, out)
+	}
+	var env struct {
+		Data struct {
+			Sources []effectiveSource `json:"sources"`
+			Skipped []skippedSource   `json:"skipped"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, out)
+	}
+
+	got := map[string]effectiveSource{}
+	for _, s := range env.Data.Sources {
+		got[s.Name] = s
+	}
+	if s := got["git"]; s.Scope != "module" || s.Origin != "auto-detected" {
+		t.Errorf("git = %+v, want an auto-detected module source", s)
+	}
+	if s := got["local-pdf"]; s.Scope != "project" || s.Origin != "auto-detected" {
+		t.Errorf("local-pdf = %+v, want an auto-detected project source", s)
+	}
+	if len(env.Data.Sources) != 2 || len(env.Data.Skipped) != 0 {
+		t.Errorf("sources = %+v, skipped = %+v; want just git and local-pdf", env.Data.Sources, env.Data.Skipped)
+	}
+}
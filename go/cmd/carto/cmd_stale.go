@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/config"
+	"github.com/divyekant/carto/internal/history"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+func staleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stale <project>",
+		Short: "List zones whose files have not been committed to in a long time",
+		Long: `List zones whose files were all last committed to longer ago than
+--older-than, as candidates for removal. Dates come from the stored history
+layer; index with --full-history so files untouched for longer than the
+default history window are dated too.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeProjectArg,
+		RunE:              runStale,
+	}
+	cmd.Flags().String("older-than", "1y", "Age threshold, e.g. 1y, 6mo, 90d or 2w")
+	return cmd
+}
+
+func runStale(cmd *cobra.Command, args []string) error {
+	project := args[0]
+	olderThan, _ := cmd.Flags().GetString("older-than")
+	threshold, err := history.ParseAge(olderThan)
+	if err != nil {
+		err = newConfigError("--older-than: " + err.Error())
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+
+	cfg := config.Load()
+	memoriesClient := storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey)
+	store := storage.NewStore(memoriesClient, project, cfg.MemoriesNamespace)
+
+	zoneLayers, err := store.RetrieveLayerAllModules(storage.LayerZones)
+	if err != nil {
+		err = newConnectionError("failed to read zones: " + err.Error())
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+	histories, err := store.RetrieveHistory()
+	if err != nil {
+		err = newConnectionError("failed to read history: " + err.Error())
+		writeEnvelope(cmd, nil, err)
+		return err
+	}
+
+	var zones []history.ZoneFiles
+	for module, results := range zoneLayers {
+		for _, res := range results {
+			var zs []history.ZoneFiles
+			if jsonErr := json.Unmarshal([]byte(res.Text), &zs); jsonErr != nil {
+				continue
+			}
+			for _, z := range zs {
+				z.Module = module
+				zones = append(zones, z)
+			}
+		}
+	}
+
+	stale := []history.ZoneStaleness{}
+	undated := 0
+	for _, z := range history.ClassifyZones(zones, histories, time.Now(), threshold) {
+		if z.Stale {
+			stale = append(stale, z)
+		} else if z.Unknown > 0 {
+			undated++
+		}
+	}
+
+	writeEnvelopeHuman(cmd, stale, nil, func() {
+		fmt.Printf("%s%sZones untouched for over %s in %s%s\n\n", bold, gold, olderThan, project, reset)
+
+		if len(zones) == 0 {
+			fmt.Println("  No zones found. Index the project first.")
+			return
+		}
+		if len(stale) == 0 {
+			fmt.Println("  No stale zones.")
+		} else {
+			fmt.Printf("  %-30s %-20s %5s %s\n", "ZONE", "MODULE", "FILES", "LAST COMMIT")
+			fmt.Printf("  %-30s %-20s %5s %s\n",
+				strings.Repeat("-", 30),
+				strings.Repeat("-", 20),
+				strings.Repeat("-", 5),
+				strings.Repeat("-", 11))
+			for _, z := range stale {
+				fmt.Printf("  %-30s %-20s %5d %s (%dd ago)\n", truncateText(z.Zone, 30), truncateText(z.Module, 20), len(z.Files), z.LastCommit.Format("2006-01-02"), z.AgeDays)
+			}
+		}
+		if undated > 0 {
+			fmt.Printf("\n  %s%d zone(s) have files without recorded commits; re-index with --full-history to date them.%s\n", stone, undated, reset)
+		}
+	})
+
+	return nil
+}
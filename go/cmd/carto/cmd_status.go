@@ -8,15 +8,53 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/divyekant/carto/internal/manifest"
+	"github.com/divyekant/carto/internal/scanner"
 )
 
 func statusCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "status <path>",
 		Short: "Show index status",
 		Args:  cobra.ExactArgs(1),
 		RunE:  runStatus,
 	}
+	cmd.Flags().Bool("drift", false, "Scan the working tree and report files changed since the last index")
+	return cmd
+}
+
+// driftData counts the files that changed on disk since the last index.
+type driftData struct {
+	Added        int  `json:"added"`
+	Modified     int  `json:"modified"`
+	Removed      int  `json:"removed"`
+	NeedsReindex int  `json:"needs_reindex"`
+	IgnoreRules  bool `json:"ignore_rules_changed"`
+}
+
+// detectDrift scans absPath and compares it against mf the way an
+// incremental index would, without indexing or calling the LLM or Memories.
+// Include and exclude globs given to a past index run are not known here,
+// so files they filtered out count as added.
+func detectDrift(absPath string, mf *manifest.Manifest) (*driftData, error) {
+	scanResult, err := scanner.Scan(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+	files := make([]string, len(scanResult.Files))
+	for i, f := range scanResult.Files {
+		files[i] = f.RelPath
+	}
+	changes, err := mf.DetectChanges(files, scanResult.Root)
+	if err != nil {
+		return nil, fmt.Errorf("detect changes: %w", err)
+	}
+	return &driftData{
+		Added:        len(changes.Added),
+		Modified:     len(changes.Modified),
+		Removed:      len(changes.Removed),
+		NeedsReindex: len(changes.Added) + len(changes.Modified),
+		IgnoreRules:  mf.IgnoreHash != scanResult.IgnoreHash,
+	}, nil
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
@@ -50,10 +88,11 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	type statusData struct {
-		Project   string `json:"project"`
-		Files     int    `json:"files"`
-		TotalSize string `json:"total_size"`
-		IndexedAt string `json:"indexed_at"`
+		Project   string     `json:"project"`
+		Files     int        `json:"files"`
+		TotalSize string     `json:"total_size"`
+		IndexedAt string     `json:"indexed_at"`
+		Drift     *driftData `json:"drift,omitempty"`
 	}
 
 	data := statusData{
@@ -62,6 +101,11 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		TotalSize: formatBytes(totalSize),
 		IndexedAt: mf.IndexedAt.Format(time.RFC3339),
 	}
+	if drift, _ := cmd.Flags().GetBool("drift"); drift {
+		if data.Drift, err = detectDrift(absPath, mf); err != nil {
+			return err
+		}
+	}
 
 	writeEnvelopeHuman(cmd, data, nil, func() {
 		fmt.Printf("%s%sIndex status for %s%s\n\n", bold, gold, absPath, reset)
@@ -69,6 +113,21 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  %sLast indexed:%s %s\n", gold, reset, data.IndexedAt)
 		fmt.Printf("  %sFiles:%s       %d\n", gold, reset, data.Files)
 		fmt.Printf("  %sTotal size:%s  %s\n", gold, reset, data.TotalSize)
+		if d := data.Drift; d != nil {
+			fmt.Printf("  %sDrift:%s       %d added, %d modified, %d removed\n", gold, reset, d.Added, d.Modified, d.Removed)
+			if d.NeedsReindex == 0 && d.Removed == 0 && !d.IgnoreRules {
+				fmt.Printf("\n  %sIndex is up to date.%s\n", green, reset)
+				return
+			}
+			fmt.Println()
+			if d.NeedsReindex > 0 {
+				fmt.Printf("  %s%d files need reindexing.%s\n", amber, d.NeedsReindex, reset)
+			}
+			if d.IgnoreRules {
+				fmt.Printf("  %sIgnore rules changed; the next index runs a full rescan.%s\n", amber, reset)
+			}
+			fmt.Printf("  Run %scarto index %s%s to update.\n", bold, absPath, reset)
+		}
 	})
 
 	return nil
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/divyekant/carto/internal/manifest"
+)
+
+func TestStatusCmd_Drift(t *testing.T) {
+	withCleanEnv(t)
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":  "package main\n",
+		"util.go":  "package main\n\nfunc util() {}\n",
+		"extra.go": "package main\n\nfunc extra() {}\n",
+	} {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+	}
+
+	mf := manifest.NewManifest(dir, "proj")
+	for _, name := range []string{"main.go", "util.go", "extra.go"} {
+		path := filepath.Join(dir, name)
+		hash, err := mf.ComputeHash(path)
+		if err != nil {
+			t.Fatalf("hash %s: %v", name, err)
+		}
+		info, _ := os.Stat(path)
+		mf.UpdateFileInfo(name, hash, info)
+	}
+	if err := mf.Save(); err != nil {
+		t.Fatalf("save manifest: %v", err)
+	}
+
+	// One modified, one removed, one added; main.go is untouched.
+	os.WriteFile(filepath.Join(dir, "util.go"), []byte("package main\n\nfunc util() { panic(1) }\n"), 0o644)
+	os.Remove(filepath.Join(dir, "extra.go"))
+	os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0o644)
+
+	out, err := execCmd(t, testRoot(statusCmd()), []string{"status", dir, "--drift", "--json"})
+	if err != nil {
+		t.Fatalf("status: %v\n%s", err, out)
+	}
+	var env struct {
+		Data struct {
+			Drift *driftData `json:"drift"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("parse output: %v\n%s", err, out)
+	}
+	want := driftData{Added: 1, Modified: 1, Removed: 1, NeedsReindex: 2}
+	if env.Data.Drift == nil || *env.Data.Drift != want {
+		t.Errorf("drift = %+v, want %+v", env.Data.Drift, want)
+	}
+
+	out, err = execCmd(t, testRoot(statusCmd()), []string{"status", dir, "--json"})
+	if err != nil {
+		t.Fatalf("status: %v\n%s", err, out)
+	}
+	env.Data.Drift = nil
+	json.Unmarshal([]byte(out), &env)
+	if env.Data.Drift != nil {
+		t.Errorf("drift reported without --drift: %+v", env.Data.Drift)
+	}
+}
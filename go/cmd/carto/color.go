@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// ─── ANSI colour codes ─────────────────────────────────────────────────────
+
+// ANSI escape codes for colored output.
+// Maps to the Carto gold brand palette for terminal rendering.
+const (
+	ansiBold  = "\033[1m"
+	ansiGold  = "\033[33m"       // brand gold #d4af37 — primary accent
+	ansiGreen = "\033[32m"       // success #10B981
+	ansiAmber = "\033[38;5;214m" // warnings #F59E0B — distinct from gold
+	ansiRed   = "\033[31m"       // errors #F43F5E
+	ansiStone = "\033[38;5;249m" // de-emphasis — warm neutral
+	ansiReset = "\033[0m"
+)
+
+// The palette commands print with. setColor(false) blanks every entry, so
+// output code uses them unconditionally.
+var (
+	bold  = ansiBold
+	gold  = ansiGold
+	green = ansiGreen
+	amber = ansiAmber
+	red   = ansiRed
+	stone = ansiStone
+	reset = ansiReset
+)
+
+// stdoutIsTerminal reports whether stdout is a terminal. Tests replace it.
+var stdoutIsTerminal = func() bool { return term.IsTerminal(int(os.Stdout.Fd())) }
+
+// colorEnabled reports whether output should be colored: not when the
+// NO_COLOR environment variable is set to any non-empty value
+// (https://no-color.org), when --no-color is passed, or when stdout is not
+// a terminal.
+func colorEnabled(cmd *cobra.Command) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if f := cmd.Root().PersistentFlags().Lookup("no-color"); f != nil && f.Value.String() == "true" {
+		return false
+	}
+	return stdoutIsTerminal()
+}
+
+// setColor turns the palette on or off for the rest of the process.
+func setColor(enabled bool) {
+	if !enabled {
+		bold, gold, green, amber, red, stone, reset = "", "", "", "", "", "", ""
+		return
+	}
+	bold, gold, green, amber, red, stone, reset = ansiBold, ansiGold, ansiGreen, ansiAmber, ansiRed, ansiStone, ansiReset
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/divyekant/carto/internal/pipeline"
+)
+
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	fn()
+	os.Stdout = orig
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestColor_SummaryHonorsNoColor(t *testing.T) {
+	origTTY := stdoutIsTerminal
+	t.Cleanup(func() {
+		stdoutIsTerminal = origTTY
+		setColor(true)
+	})
+	t.Setenv("NO_COLOR", "")
+
+	result := &pipeline.Result{
+		Modules:   2,
+		Truncated: 1,
+		Cycles:    [][]string{{"api", "store"}},
+		Errors:    []error{errors.New("boom")},
+	}
+	summary := func(tty bool, args ...string) string {
+		t.Helper()
+		stdoutIsTerminal = func() bool { return tty }
+		root := testRoot()
+		root.PersistentFlags().Bool("no-color", false, "")
+		if err := root.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		setColor(colorEnabled(root))
+		return captureStdout(t, func() { printIndexSummary(result, time.Second) })
+	}
+
+	if out := summary(true); !strings.Contains(out, "\033[") {
+		t.Errorf("terminal output should be colored:\n%q", out)
+	}
+	if out := summary(true, "--no-color"); strings.Contains(out, "\033[") {
+		t.Errorf("--no-color output contains escape sequences:\n%q", out)
+	}
+	if out := summary(false); strings.Contains(out, "\033[") {
+		t.Errorf("non-terminal output contains escape sequences:\n%q", out)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	out := summary(true)
+	if strings.Contains(out, "\033[") {
+		t.Errorf("NO_COLOR output contains escape sequences:\n%q", out)
+	}
+	if !strings.Contains(out, "=== Summary ===") || !strings.Contains(out, "api → store → api") {
+		t.Errorf("summary content missing:\n%s", out)
+	}
+}
@@ -23,6 +23,8 @@ const (
 	ErrCodeConnection = "CONNECTION_ERROR"
 	ErrCodeAuth       = "AUTH_FAILURE"
 	ErrCodeConfig     = "CONFIG_ERROR"
+	ErrCodeTimeout    = "TIMEOUT"
+	ErrCodeBudget     = "BUDGET_EXCEEDED"
 )
 
 // ─── Exit code constant ──────────────────────────────────────────────────
@@ -31,6 +33,8 @@ const (
 
 const (
 	ExitNotFound = 2 // resource not found
+	ExitTimeout  = 6 // operation exceeded its time limit
+	ExitBudget   = 7 // operation exceeded its spending cap
 )
 
 // ─── cliError type ────────────────────────────────────────────────────────
@@ -41,11 +45,15 @@ type cliError struct {
 	msg  string
 	code string
 	exit int
+	err  error // underlying cause, if any (see withCause)
 }
 
 // Error implements the error interface.
 func (e *cliError) Error() string { return e.msg }
 
+// Unwrap returns the underlying cause so errors.Is sees through a cliError.
+func (e *cliError) Unwrap() error { return e.err }
+
 // ─── Constructor functions ────────────────────────────────────────────────
 
 func newConnectionError(msg string) error {
@@ -64,6 +72,23 @@ func newConfigError(msg string) error {
 	return &cliError{msg: msg, code: ErrCodeConfig, exit: ExitConfig}
 }
 
+func newTimeoutError(msg string) error {
+	return &cliError{msg: msg, code: ErrCodeTimeout, exit: ExitTimeout}
+}
+
+func newBudgetError(msg string) error {
+	return &cliError{msg: msg, code: ErrCodeBudget, exit: ExitBudget}
+}
+
+// withCause records cause as the error ce wraps, so callers can match it
+// with errors.Is. ce must come from one of the constructors above.
+func withCause(ce, cause error) error {
+	if c, ok := ce.(*cliError); ok {
+		c.err = cause
+	}
+	return ce
+}
+
 // ─── Classifier ───────────────────────────────────────────────────────────
 
 // toCliError extracts a *cliError from err using errors.As.
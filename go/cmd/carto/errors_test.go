@@ -2,7 +2,11 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"testing"
+
+	"github.com/divyekant/carto/internal/llm"
+	"github.com/divyekant/carto/internal/pipeline"
 )
 
 // =========================================================================
@@ -138,3 +142,41 @@ func TestClassifyError_GenericError(t *testing.T) {
 		t.Errorf("expected msg %q, got %q", "something went wrong", ce.Error())
 	}
 }
+
+// =========================================================================
+// Wrapped causes
+// =========================================================================
+
+func TestIndexError_ClassifiesPipelineFailures(t *testing.T) {
+	tests := []struct {
+		cause error
+		code  string
+		exit  int
+	}{
+		{pipeline.ErrMemoriesUnreachable, ErrCodeConnection, ExitConnRefused},
+		{pipeline.ErrModuleNotFound, ErrCodeNotFound, ExitNotFound},
+		{llm.ErrNoAPIKey, ErrCodeConfig, ExitConfig},
+		{errors.New("disk full"), ErrCodeGeneral, ExitErr},
+	}
+	for _, tt := range tests {
+		err := indexError(fmt.Errorf("pipeline: %w", tt.cause))
+		if !errors.Is(err, tt.cause) {
+			t.Errorf("errors.Is(%v, %v) = false", err, tt.cause)
+		}
+		if ce := toCliError(err); ce.code != tt.code || ce.exit != tt.exit {
+			t.Errorf("%v: code %q exit %d, want %q exit %d", tt.cause, ce.code, ce.exit, tt.code, tt.exit)
+		}
+	}
+}
+
+func TestIndexCmd_NoAPIKey(t *testing.T) {
+	withCleanEnv(t)
+
+	_, err := execCmd(t, testRoot(indexCmd()), []string{"index", t.TempDir()})
+	if !errors.Is(err, llm.ErrNoAPIKey) {
+		t.Fatalf("err = %v, want ErrNoAPIKey", err)
+	}
+	if ce := toCliError(err); ce.exit != ExitConfig {
+		t.Errorf("exit = %d, want %d", ce.exit, ExitConfig)
+	}
+}
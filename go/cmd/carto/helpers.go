@@ -8,20 +8,8 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
-)
-
-// ─── ANSI colour codes ─────────────────────────────────────────────────────
 
-// ANSI escape codes for colored output.
-// Maps to the Carto gold brand palette for terminal rendering.
-const (
-	bold  = "\033[1m"
-	gold  = "\033[33m"        // brand gold #d4af37 — primary accent
-	green = "\033[32m"        // success #10B981
-	amber = "\033[38;5;214m"  // warnings #F59E0B — distinct from gold
-	red   = "\033[31m"        // errors #F43F5E
-	stone = "\033[38;5;249m"  // de-emphasis — warm neutral
-	reset = "\033[0m"
+	"github.com/divyekant/carto/internal/config"
 )
 
 // ─── Exit codes (Unix convention) ─────────────────────────────────────────
@@ -159,15 +147,13 @@ func logAuditEvent(cmd *cobra.Command, result, errMsg string, extra map[string]a
 // resolveProfile returns the active config profile. Priority:
 //  1. --profile flag
 //  2. CARTO_PROFILE env var
-//  3. "default"
+//  3. the profile chosen with 'carto config profile use'
+//  4. "default"
 func resolveProfile(cmd *cobra.Command) string {
 	if p, _ := cmd.Root().PersistentFlags().GetString("profile"); p != "" {
 		return p
 	}
-	if p := os.Getenv("CARTO_PROFILE"); p != "" {
-		return p
-	}
-	return "default"
+	return config.SelectedProfile()
 }
 
 // ─── Status-indicator helpers ─────────────────────────────────────────────
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -26,7 +27,8 @@ Run 'carto about' for full product information and brand color palette.
 
 Environment variables:
   ANTHROPIC_API_KEY    API key for Anthropic (Claude)
-  LLM_API_KEY          Generic LLM API key (overrides ANTHROPIC_API_KEY)
+  LLM_API_KEY          Generic LLM API key (overrides ANTHROPIC_API_KEY);
+                       file:/path or cmd:<command> reads it from a file or command
   LLM_PROVIDER         Provider: anthropic | openai | ollama (default: anthropic)
   LLM_BASE_URL         Base URL for OpenAI-compatible providers
   MEMORIES_URL         URL of the Memories vector store (default: http://localhost:8900)
@@ -35,7 +37,10 @@ Environment variables:
   CARTO_SERVER_TOKEN   Bearer token for the web server (empty = dev mode, no auth)
   CARTO_CORS_ORIGINS   Comma-separated allowed CORS origins
   CARTO_AUDIT_LOG      File path for structured JSON audit logs
-  CARTO_PROFILE        Config profile name (default: "default")`,
+  CARTO_PROFILE        Config profile name (default: "default")
+  CARTO_CONFIG         Config file path (default: $XDG_CONFIG_HOME/carto/config.json)
+  CARTO_MEMORIES_NAMESPACE
+                       Source tag prefix in Memories (default: carto)`,
 		Version: version,
 	}
 
@@ -54,12 +59,28 @@ Environment variables:
 	root.PersistentFlags().Bool("pretty", false, "Force human-readable output even when piped")
 	// --yes skips confirmation prompts for automation and agent usage.
 	root.PersistentFlags().BoolP("yes", "y", false, "Skip confirmation prompts")
+	// --config points Load/Save at a specific config file.
+	root.PersistentFlags().String("config", "", "Config file to use (overrides CARTO_CONFIG and the XDG default)")
+	// --no-color disables ANSI colors (as does NO_COLOR or a non-terminal stdout).
+	root.PersistentFlags().Bool("no-color", false, "Disable colored output (also honors NO_COLOR)")
+	root.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
+		setColor(colorEnabled(cmd))
+		return applyConfigFlag(cmd)
+	}
 
 	// ── Subcommands ────────────────────────────────────────────────────────
 	root.AddCommand(indexCmd())
 	root.AddCommand(queryCmd())
 	root.AddCommand(modulesCmd())
+	root.AddCommand(scanCmd())
+	root.AddCommand(atomsCmd())
+	root.AddCommand(explainCmd())
+	root.AddCommand(hotspotsCmd())
+	root.AddCommand(duplicatesCmd())
+	root.AddCommand(refsCmd())
+	root.AddCommand(staleCmd())
 	root.AddCommand(patternsCmd())
+	root.AddCommand(reportCmd())
 	root.AddCommand(statusCmd())
 	root.AddCommand(serveCmd())
 	root.AddCommand(projectsCmd())
@@ -77,6 +98,24 @@ Environment variables:
 	root.AddCommand(upgradeCmd())        // check for and install new versions
 
 	if err := root.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(toCliError(err).exit)
+	}
+}
+
+// applyConfigFlag sets config.ConfigPath from --config and
+// config.ProfileName from --profile. Without the flags,
+// config.ActiveConfigPath falls back to CARTO_CONFIG and then the XDG
+// default, and the profile to CARTO_PROFILE and then the one chosen with
+// 'carto config profile use'.
+func applyConfigFlag(cmd *cobra.Command) error {
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		config.ConfigPath = path
+	}
+	if name, _ := cmd.Flags().GetString("profile"); name != "" {
+		if name != config.DefaultProfile && !config.ProfileExists(name) {
+			return newConfigError(fmt.Sprintf("profile %q not found — run 'carto config profile list'", name))
+		}
+		config.ProfileName = name
 	}
+	return nil
 }
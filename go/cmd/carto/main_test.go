@@ -6,8 +6,24 @@ import (
 	"testing"
 
 	"github.com/spf13/cobra"
+
+	"github.com/divyekant/carto/internal/config"
 )
 
+// TestMain points the default config file at a temporary directory so
+// commands that persist settings never touch the user's real config.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "carto-config-")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.Unsetenv("CARTO_CONFIG")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestRunPatterns_WritesFiles(t *testing.T) {
 	dir := t.TempDir()
 	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/test\n\ngo 1.21\n"), 0o644)
@@ -81,3 +97,24 @@ func TestCLI_IndexNoArgs(t *testing.T) {
 		t.Error("index with no args should error")
 	}
 }
+
+func TestApplyConfigFlag_SetsConfigPath(t *testing.T) {
+	orig := config.ConfigPath
+	t.Cleanup(func() { config.ConfigPath = orig })
+	config.ConfigPath = ""
+	t.Setenv("CARTO_CONFIG", "/env/carto.json")
+
+	cmd := &cobra.Command{Use: "x"}
+	cmd.Flags().String("config", "", "")
+
+	applyConfigFlag(cmd)
+	if got := config.ActiveConfigPath(); got != "/env/carto.json" {
+		t.Errorf("without --config: got %q, want the CARTO_CONFIG path", got)
+	}
+
+	cmd.Flags().Set("config", "/flag/carto.json")
+	applyConfigFlag(cmd)
+	if got := config.ActiveConfigPath(); got != "/flag/carto.json" {
+		t.Errorf("with --config: got %q, want /flag/carto.json", got)
+	}
+}
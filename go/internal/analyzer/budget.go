@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/divyekant/carto/internal/atoms"
+)
+
+// elisionReserve is the room kept per section for its elision note.
+const elisionReserve = 96
+
+// promptSection is one list in a module prompt: rendered entries, each
+// with a priority, of which fitSections marks the ones to keep.
+type promptSection struct {
+	title    string
+	intro    string // optional text before the entries
+	noun     string // what the entries are, for the elision note
+	optional bool   // leave the whole section out when it has no entries
+
+	items    []string
+	priority []float64
+	keep     []bool
+}
+
+// add appends an entry with the given priority; higher is kept first.
+func (s *promptSection) add(item string, priority float64) {
+	s.items = append(s.items, item)
+	s.priority = append(s.priority, priority)
+	s.keep = append(s.keep, true)
+}
+
+// size is the length of the kept entries.
+func (s *promptSection) size() int {
+	n := 0
+	for i, item := range s.items {
+		if s.keep[i] {
+			n += len(item)
+		}
+	}
+	return n
+}
+
+// frame is the length of everything around the entries.
+func (s *promptSection) frame() int {
+	if s.optional && len(s.items) == 0 {
+		return 0
+	}
+	return len("## "+s.title+"\n\n") + len(s.intro) + len("(none)\n\n")
+}
+
+// byPriority returns entry indexes, highest priority first, ties in order.
+func (s *promptSection) byPriority() []int {
+	idx := make([]int, len(s.items))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return s.priority[idx[a]] > s.priority[idx[b]] })
+	return idx
+}
+
+// render writes the section: its kept entries in their original order,
+// then a note of how many were elided.
+func (s *promptSection) render(b *strings.Builder) {
+	if s.optional && len(s.items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "## %s\n\n", s.title)
+	if len(s.items) == 0 {
+		b.WriteString("(none)\n\n")
+		return
+	}
+	b.WriteString(s.intro)
+	elided := 0
+	for i, item := range s.items {
+		if s.keep[i] {
+			b.WriteString(item)
+		} else {
+			elided++
+		}
+	}
+	if elided > 0 {
+		fmt.Fprintf(b, "- (%d more %s elided to fit the prompt budget)\n", elided, s.noun)
+	}
+	b.WriteString("\n")
+}
+
+// fitSections marks which entries of sections to keep so all of them
+// together take at most budget characters. When they don't fit, every
+// section is scaled to the same fraction of its size, keeping its
+// highest-priority entries, and the room left over goes to the remaining
+// entries in section order.
+func fitSections(sections []*promptSection, budget int) {
+	total := 0
+	for _, s := range sections {
+		budget -= s.frame()
+		total += s.size()
+	}
+	if total <= budget {
+		return
+	}
+	for _, s := range sections {
+		if len(s.items) > 0 {
+			budget -= elisionReserve
+		}
+	}
+	if budget < 0 {
+		budget = 0
+	}
+
+	used := 0
+	for _, s := range sections {
+		share := int(float64(s.size()) * float64(budget) / float64(total))
+		for i := range s.keep {
+			s.keep[i] = false
+		}
+		for _, i := range s.byPriority() {
+			if len(s.items[i]) <= share {
+				s.keep[i] = true
+				share -= len(s.items[i])
+			}
+		}
+		used += s.size()
+	}
+
+	left := budget - used
+	for _, s := range sections {
+		for _, i := range s.byPriority() {
+			if !s.keep[i] && len(s.items[i]) <= left {
+				s.keep[i] = true
+				left -= len(s.items[i])
+			}
+		}
+	}
+}
+
+// atomComplexity estimates how much an atom matters to a module's
+// analysis: its length in lines plus the imports and exports it connects.
+// Atoms without a line range count one line.
+func atomComplexity(a *atoms.Atom) float64 {
+	lines := max(a.EndLine-a.StartLine+1, 1)
+	return float64(lines + len(a.Imports) + len(a.Exports))
+}
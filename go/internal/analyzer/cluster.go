@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"path"
+	"strings"
+)
+
+// clusterSimilarity is the Jaccard similarity at or above which two modules
+// are considered built from the same template.
+const clusterSimilarity = 0.7
+
+// minClusterSize is the smallest group of similar modules that is collapsed
+// into a single entry in the synthesis prompt. Smaller groups are listed
+// module by module.
+const minClusterSize = 3
+
+// moduleFeatures returns the structural fingerprint of a module analysis:
+// its zone names plus the shape of its file layout. Files are reduced to
+// their last directory and base name so that "svc-a/internal/handler.go"
+// and "svc-b/internal/handler.go" compare equal.
+func moduleFeatures(m ModuleAnalysis) map[string]bool {
+	features := make(map[string]bool)
+	for _, z := range m.Zones {
+		features["zone:"+strings.ToLower(strings.TrimSpace(z.Name))] = true
+		for _, f := range z.Files {
+			f = path.Clean(strings.ReplaceAll(f, "\\", "/"))
+			features["file:"+path.Join(path.Base(path.Dir(f)), path.Base(f))] = true
+		}
+	}
+	return features
+}
+
+// jaccard returns |a ∩ b| / |a ∪ b|, or 0 when both sets are empty.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for k := range a {
+		if b[k] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// clusterModules groups modules by structural similarity. Each module joins
+// the first cluster whose first member (the representative) is at least
+// clusterSimilarity similar to it; otherwise it starts a new cluster.
+// Clusters are returned as index lists in order of first appearance.
+func clusterModules(modules []ModuleAnalysis) [][]int {
+	features := make([]map[string]bool, len(modules))
+	for i, m := range modules {
+		features[i] = moduleFeatures(m)
+	}
+
+	var clusters [][]int
+	for i := range modules {
+		placed := false
+		for c, members := range clusters {
+			if jaccard(features[members[0]], features[i]) >= clusterSimilarity {
+				clusters[c] = append(clusters[c], i)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			clusters = append(clusters, []int{i})
+		}
+	}
+	return clusters
+}
+
+// dedupePatterns removes repeated patterns, comparing case- and
+// whitespace-insensitively and keeping the first occurrence.
+func dedupePatterns(patterns []string) []string {
+	seen := make(map[string]bool, len(patterns))
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		key := strings.ToLower(strings.Join(strings.Fields(p), " "))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, p)
+	}
+	return out
+}
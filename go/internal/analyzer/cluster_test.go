@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// templateService returns an analysis of a service generated from a shared
+// template, differing from its siblings only in name and paths.
+func templateService(name string) ModuleAnalysis {
+	return ModuleAnalysis{
+		ModuleName:   name,
+		ModuleIntent: name + " microservice",
+		Zones: []Zone{
+			{Name: "HTTP API", Intent: "request handlers", Files: []string{
+				"services/" + name + "/internal/handler.go",
+				"services/" + name + "/internal/routes.go",
+			}},
+			{Name: "Persistence", Intent: "database access", Files: []string{
+				"services/" + name + "/store/repo.go",
+			}},
+		},
+		Wiring: []Dependency{{From: "handler.go", To: "repo.go", Reason: "loads records"}},
+	}
+}
+
+func TestClusterModules_GroupsTemplateServices(t *testing.T) {
+	modules := []ModuleAnalysis{
+		templateService("billing"),
+		{ModuleName: "web", Zones: []Zone{{Name: "UI", Files: []string{"web/src/App.tsx"}}}},
+		templateService("orders"),
+		templateService("users"),
+	}
+
+	clusters := clusterModules(modules)
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %d: %v", len(clusters), clusters)
+	}
+	if fmt.Sprint(clusters[0]) != "[0 2 3]" || fmt.Sprint(clusters[1]) != "[1]" {
+		t.Errorf("unexpected clusters: %v", clusters)
+	}
+}
+
+func TestBuildSynthesisPrompt_CollapsesNearIdenticalModules(t *testing.T) {
+	var modules []ModuleAnalysis
+	for _, name := range []string{"billing", "orders", "users", "search", "notify"} {
+		modules = append(modules, templateService(name))
+	}
+	modules = append(modules, ModuleAnalysis{
+		ModuleName:   "gateway",
+		ModuleIntent: "edge proxy",
+		Zones:        []Zone{{Name: "Routing", Files: []string{"gateway/proxy.go"}}},
+	})
+
+	prompt := buildSynthesisPrompt(modules, nil)
+
+	if !strings.Contains(prompt, "## Module group: 5 modules following the same template") {
+		t.Errorf("expected a collapsed group of 5:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "Modules: billing, orders, users, search, notify") {
+		t.Errorf("group should list its members:\n%s", prompt)
+	}
+	if n := strings.Count(prompt, "  - HTTP API:"); n != 1 {
+		t.Errorf("template zone listed %d times, want 1", n)
+	}
+	if !strings.Contains(prompt, "## Module: gateway") {
+		t.Errorf("distinct module should be listed on its own:\n%s", prompt)
+	}
+}
+
+func TestBuildSynthesisPrompt_SmallGroupsNotCollapsed(t *testing.T) {
+	prompt := buildSynthesisPrompt([]ModuleAnalysis{templateService("a"), templateService("b")}, nil)
+	if strings.Contains(prompt, "Module group") {
+		t.Errorf("pairs should not be collapsed:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "## Module: a") || !strings.Contains(prompt, "## Module: b") {
+		t.Errorf("both modules should be listed:\n%s", prompt)
+	}
+}
+
+func TestSynthesizeSystem_DedupesPatterns(t *testing.T) {
+	var prompt string
+	client := &promptCapture{
+		resp:   `{"blueprint": "b", "patterns": ["Handlers return JSON", "handlers  return json", "Repos wrap SQL", ""]}`,
+		prompt: &prompt,
+	}
+	result, err := NewDeepAnalyzer(client).SynthesizeSystem([]ModuleAnalysis{templateService("a")})
+	if err != nil {
+		t.Fatalf("SynthesizeSystem: %v", err)
+	}
+	if fmt.Sprint(result.Patterns) != "[Handlers return JSON Repos wrap SQL]" {
+		t.Errorf("Patterns = %q", result.Patterns)
+	}
+}
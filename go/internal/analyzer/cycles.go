@@ -0,0 +1,92 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// FindCycles returns the circular dependencies in the wiring graph formed
+// by edges, which may combine several modules' wiring so cross-module
+// cycles are found too. Each cycle lists its nodes in dependency order
+// starting from its lexically smallest node, with the edge back to the
+// first node implied: A→B→C→A is reported as [A B C]. Self-edges are
+// ignored. Cycles are found by depth-first search with a recursion stack,
+// so every graph with a cycle yields at least one, though not every
+// elementary cycle of a densely connected graph is listed. Results are
+// sorted and free of duplicates.
+func FindCycles(edges []Dependency) [][]string {
+	adj := make(map[string][]string)
+	for _, e := range edges {
+		if e.From == "" || e.To == "" || e.From == e.To {
+			continue
+		}
+		adj[e.From] = append(adj[e.From], e.To)
+	}
+	nodes := make([]string, 0, len(adj))
+	for n, next := range adj {
+		nodes = append(nodes, n)
+		sort.Strings(next)
+	}
+	sort.Strings(nodes)
+
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	seen := make(map[string]bool)
+	var cycles [][]string
+
+	var visit func(n string)
+	visit = func(n string) {
+		state[n] = onStack
+		stack = append(stack, n)
+		for _, m := range adj[n] {
+			switch state[m] {
+			case unvisited:
+				visit(m)
+			case onStack:
+				cycle := rotateToMin(stackFrom(stack, m))
+				if key := strings.Join(cycle, "\x00"); !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[n] = done
+	}
+	for _, n := range nodes {
+		if state[n] == unvisited {
+			visit(n)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return strings.Join(cycles[i], "\x00") < strings.Join(cycles[j], "\x00")
+	})
+	return cycles
+}
+
+// stackFrom returns a copy of the recursion stack from node n to the top.
+func stackFrom(stack []string, n string) []string {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == n {
+			return append([]string(nil), stack[i:]...)
+		}
+	}
+	return nil
+}
+
+// rotateToMin rotates cycle so that it starts at its smallest node.
+func rotateToMin(cycle []string) []string {
+	first := 0
+	for i, n := range cycle {
+		if n < cycle[first] {
+			first = i
+		}
+	}
+	return append(cycle[first:len(cycle):len(cycle)], cycle[:first]...)
+}
@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges []Dependency
+		want  [][]string
+	}{
+		{
+			name: "three-node cycle",
+			edges: []Dependency{
+				{From: "B", To: "C"},
+				{From: "A", To: "B"},
+				{From: "C", To: "A"},
+				{From: "C", To: "D"},
+			},
+			want: [][]string{{"A", "B", "C"}},
+		},
+		{
+			name: "acyclic",
+			edges: []Dependency{
+				{From: "A", To: "B"},
+				{From: "B", To: "C"},
+				{From: "A", To: "C"},
+				{From: "C", To: "C"}, // self-edge ignored
+			},
+			want: nil,
+		},
+		{
+			name: "cycles across modules",
+			edges: []Dependency{
+				{From: "api", To: "store"},
+				{From: "store", To: "api"},
+				{From: "x", To: "y"},
+				{From: "y", To: "z"},
+				{From: "z", To: "y"},
+			},
+			want: [][]string{{"api", "store"}, {"y", "z"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindCycles(tt.edges); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindCycles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/divyekant/carto/internal/history"
 	"github.com/divyekant/carto/internal/llm"
 	"github.com/divyekant/carto/internal/sources"
+	"github.com/divyekant/carto/internal/tracing"
 )
 
 // LLMClient is the interface needed for deep-tier calls.
@@ -26,6 +27,12 @@ type ModuleInput struct {
 	Atoms   []*atoms.Atom
 	History []*history.FileHistory
 	Signals []sources.Artifact
+	// KnownImports holds ground-truth edges from static analysis (see
+	// StaticGoWiring). Optional; empty for non-Go modules.
+	KnownImports []Dependency
+	// Frameworks the module is built on, detected by the scanner from its
+	// dependency files, such as "Spring Boot". Optional.
+	Frameworks []string
 }
 
 // Dependency represents a cross-unit connection with intent.
@@ -33,6 +40,9 @@ type Dependency struct {
 	From   string `json:"from"`
 	To     string `json:"to"`
 	Reason string `json:"reason"`
+	// Unresolved marks an edge ValidateLinks could not match to a known
+	// unit at one end.
+	Unresolved bool `json:"unresolved,omitempty"`
 }
 
 // Zone represents a business domain grouping.
@@ -48,6 +58,9 @@ type ModuleAnalysis struct {
 	Wiring       []Dependency `json:"wiring"`
 	Zones        []Zone       `json:"zones"`
 	ModuleIntent string       `json:"module_intent"`
+	// Frameworks is copied from the ModuleInput, not produced by the
+	// model, so synthesis knows each module's stack.
+	Frameworks []string `json:"frameworks,omitempty"`
 }
 
 // SystemSynthesis is the output of system-wide deep-tier synthesis.
@@ -56,104 +69,183 @@ type SystemSynthesis struct {
 	Patterns  []string `json:"patterns"`
 }
 
-// maxPromptChars is the approximate character budget for module analysis prompts.
+// Shapes the deep tier's responses must have to be decoded into a
+// ModuleAnalysis or SystemSynthesis; see llm.CompleteValidJSON.
+var (
+	moduleSchema = llm.Schema{
+		{Name: "module_name", Type: llm.TypeString},
+		{Name: "wiring", Type: llm.TypeArray, Items: llm.TypeObject},
+		{Name: "zones", Type: llm.TypeArray, Items: llm.TypeObject},
+		{Name: "module_intent", Type: llm.TypeString, Required: true},
+	}
+	synthesisSchema = llm.Schema{
+		{Name: "blueprint", Type: llm.TypeString, Required: true},
+		{Name: "patterns", Type: llm.TypeArray, Items: llm.TypeString},
+	}
+)
+
+// maxPromptChars is the default character budget for module analysis prompts.
 // ~100K chars ≈ ~25K tokens, well within model context limits.
 const maxPromptChars = 100000
 
+// DefaultModuleAttempts is how many times AnalyzeModules tries each module
+// before giving up on it.
+const DefaultModuleAttempts = 2
+
+// FailedModuleIntent is the ModuleIntent of the placeholder analysis
+// AnalyzeModules returns for a module whose every attempt failed.
+const FailedModuleIntent = "analysis failed"
+
 // DeepAnalyzer runs deep-tier analysis on modules and system-wide.
 type DeepAnalyzer struct {
-	llm       LLMClient
-	maxTokens int
+	llm                   LLMClient
+	maxTokens             int
+	moduleAttempts        int
+	promptBudget          int
+	moduleInstructions    string
+	synthesisInstructions string
 }
 
 // NewDeepAnalyzer creates a DeepAnalyzer that uses the given LLM client.
 // Optional maxTokens overrides the default 8192 output token limit.
 func NewDeepAnalyzer(client LLMClient, maxTokens ...int) *DeepAnalyzer {
-	mt := 8192
+	mt := llm.DefaultDeepMaxTokens
 	if len(maxTokens) > 0 && maxTokens[0] > 0 {
 		mt = maxTokens[0]
 	}
-	return &DeepAnalyzer{llm: client, maxTokens: mt}
+	return &DeepAnalyzer{llm: client, maxTokens: mt, moduleAttempts: DefaultModuleAttempts, promptBudget: maxPromptChars}
 }
 
-// buildModulePrompt constructs the user prompt for per-module analysis.
-func buildModulePrompt(input ModuleInput) string {
-	var b strings.Builder
-
-	fmt.Fprintf(&b, "Analyze the module %q (path: %s).\n\n", input.Name, input.Path)
-
-	// Atom summaries.
-	b.WriteString("## Code Units (Atoms)\n\n")
-	if len(input.Atoms) == 0 {
-		b.WriteString("(none)\n\n")
-	} else {
-		for _, a := range input.Atoms {
-			fmt.Fprintf(&b, "- **%s** (%s) in `%s`\n", a.Name, a.Kind, a.FilePath)
-			fmt.Fprintf(&b, "  Summary: %s\n", a.Summary)
-			if len(a.Imports) > 0 {
-				fmt.Fprintf(&b, "  Imports: %s\n", strings.Join(a.Imports, ", "))
-			}
-			if len(a.Exports) > 0 {
-				fmt.Fprintf(&b, "  Exports: %s\n", strings.Join(a.Exports, ", "))
-			}
-		}
-		b.WriteString("\n")
+// SetPromptBudget sets the character budget of module analysis prompts.
+// Values < 1 restore the default of 100K characters.
+func (d *DeepAnalyzer) SetPromptBudget(chars int) {
+	if chars < 1 {
+		chars = maxPromptChars
 	}
+	d.promptBudget = chars
+}
 
-	// File history.
-	b.WriteString("## File History\n\n")
-	if len(input.History) == 0 {
-		b.WriteString("(none)\n\n")
-	} else {
-		for _, h := range input.History {
-			commitCount := len(h.Commits)
-			authors := strings.Join(h.Authors, ", ")
-			fmt.Fprintf(&b, "- `%s`: %d commits, churn=%.0f, authors=[%s]\n",
-				h.FilePath, commitCount, h.ChurnScore, authors)
-		}
-		b.WriteString("\n")
+// SetModuleAttempts sets how many times AnalyzeModules tries each module.
+// Values < 1 restore DefaultModuleAttempts.
+func (d *DeepAnalyzer) SetModuleAttempts(n int) {
+	if n < 1 {
+		n = DefaultModuleAttempts
 	}
+	d.moduleAttempts = n
+}
 
-	// Signals.
-	b.WriteString("## External Signals\n\n")
-	if len(input.Signals) == 0 {
-		b.WriteString("(none)\n\n")
-	} else {
-		for _, s := range input.Signals {
-			sType := s.Tags["type"]
-			if sType == "" {
-				sType = string(s.Category)
-			}
-			fmt.Fprintf(&b, "- [%s] %s: %s\n", sType, s.ID, s.Title)
+// SetInstructions appends custom guidance to the system prompts of module
+// analysis and system synthesis respectively. Either may be empty.
+func (d *DeepAnalyzer) SetInstructions(module, synthesis string) {
+	d.moduleInstructions = module
+	d.synthesisInstructions = synthesis
+}
+
+// hasAtomSummaries reports whether any atom carries a summary.
+func hasAtomSummaries(list []*atoms.Atom) bool {
+	for _, a := range list {
+		if a.Summary != "" {
+			return true
 		}
-		b.WriteString("\n")
 	}
+	return false
+}
 
-	b.WriteString(`Produce a JSON object with these fields:
+// buildModulePrompt renders a module's atoms, history, static imports and
+// signals as a deep-analysis prompt of at most budget characters. Over
+// budget, each list is cut by the same proportion, keeping its most
+// important entries (see fitSections), and ends with a note of how many
+// entries were elided. The JSON instructions are always kept.
+func buildModulePrompt(input ModuleInput, budget int) string {
+	header := fmt.Sprintf("Analyze the module %q (path: %s).\n\n", input.Name, input.Path)
+	if len(input.Frameworks) > 0 {
+		header += fmt.Sprintf("Stack (from its dependency files): %s. Describe the module in terms of these frameworks' conventions where they apply.\n\n", strings.Join(input.Frameworks, ", "))
+	}
+	const instructions = `Produce a JSON object with these fields:
 - "module_name": the module name
 - "wiring": array of {"from": "<unit>", "to": "<unit>", "reason": "<why connected>"}
 - "zones": array of {"name": "<domain>", "intent": "<purpose statement>", "files": ["<path>", ...]}
 - "module_intent": a 1-3 sentence summary of the module's purpose
-`)
+`
 
-	// Truncate if prompt exceeds the character budget.
-	result := b.String()
-	if len(result) > maxPromptChars {
-		result = result[:maxPromptChars]
+	// Atom summaries. Modules-only runs pass atoms with no summary, so the
+	// model is told to work from names, kinds and files alone.
+	atomSec := &promptSection{title: "Code Units (Atoms)", noun: "code units"}
+	if len(input.Atoms) > 0 && !hasAtomSummaries(input.Atoms) {
+		atomSec.intro = "Summaries are unavailable; only the name, kind and file of each unit are listed. Infer responsibilities from these and the file layout.\n\n"
+	}
+	for _, a := range input.Atoms {
+		var b strings.Builder
+		fmt.Fprintf(&b, "- **%s** (%s) in `%s`\n", a.Name, a.Kind, a.FilePath)
+		if a.Summary != "" {
+			fmt.Fprintf(&b, "  Summary: %s\n", a.Summary)
+		}
+		if len(a.Imports) > 0 {
+			fmt.Fprintf(&b, "  Imports: %s\n", strings.Join(a.Imports, ", "))
+		}
+		if len(a.Exports) > 0 {
+			fmt.Fprintf(&b, "  Exports: %s\n", strings.Join(a.Exports, ", "))
+		}
+		atomSec.add(b.String(), atomComplexity(a))
+	}
+
+	// File history, most churned files first when over budget.
+	historySec := &promptSection{title: "File History", noun: "files"}
+	for _, h := range input.History {
+		historySec.add(fmt.Sprintf("- `%s`: %d commits, churn=%.0f, authors=[%s]\n",
+			h.FilePath, len(h.Commits), h.ChurnScore, strings.Join(h.Authors, ", ")), h.ChurnScore)
+	}
+
+	// Static import edges, when available.
+	importSec := &promptSection{title: "Known Imports (static analysis)", noun: "imports", optional: true}
+	for i, d := range input.KnownImports {
+		importSec.add(fmt.Sprintf("- %s -> %s: %s\n", d.From, d.To, d.Reason), -float64(i))
+	}
+
+	// Signals, in the order the sources returned them.
+	signalSec := &promptSection{title: "External Signals", noun: "signals"}
+	for i, sig := range input.Signals {
+		sType := sig.Tags["type"]
+		if sType == "" {
+			sType = string(sig.Category)
+		}
+		signalSec.add(fmt.Sprintf("- [%s] %s: %s\n", sType, sig.ID, sig.Title), -float64(i))
 	}
 
+	sections := []*promptSection{atomSec, historySec, importSec, signalSec}
+	fitSections(sections, budget-len(header)-len(instructions))
+
+	var b strings.Builder
+	b.WriteString(header)
+	for _, sec := range sections {
+		sec.render(&b)
+	}
+	b.WriteString(instructions)
+
+	// Last resort for a header alone over budget, e.g. a huge module path.
+	result := b.String()
+	if len(result) > budget {
+		result = result[:budget]
+	}
 	return result
 }
 
 // AnalyzeModule sends a single module's data to the deep tier and returns wiring,
 // zones, and intent analysis.
 func (d *DeepAnalyzer) AnalyzeModule(module ModuleInput) (*ModuleAnalysis, error) {
-	prompt := buildModulePrompt(module)
+	return d.analyzeModule(context.Background(), module)
+}
+
+// analyzeModule is AnalyzeModule with the LLM call traced under ctx's
+// span. Canceling ctx does not abort the call.
+func (d *DeepAnalyzer) analyzeModule(ctx context.Context, module ModuleInput) (*ModuleAnalysis, error) {
+	prompt := buildModulePrompt(module, d.promptBudget)
 
-	raw, err := d.llm.CompleteJSON(prompt, llm.TierDeep, &llm.CompleteOptions{
-		System:    "You are a software architecture analyst. Analyze this module and respond with JSON.",
+	raw, err := llm.CompleteValidJSON(d.llm.CompleteJSON, prompt, llm.TierDeep, &llm.CompleteOptions{
+		System:    llm.WithInstructions("You are a software architecture analyst. Analyze this module and respond with JSON.", d.moduleInstructions),
 		MaxTokens: d.maxTokens,
-	})
+		Context:   context.WithoutCancel(ctx),
+	}, moduleSchema)
 	if err != nil {
 		return nil, fmt.Errorf("analyzer: LLM call failed for module %q: %w", module.Name, err)
 	}
@@ -167,54 +259,141 @@ func (d *DeepAnalyzer) AnalyzeModule(module ModuleInput) (*ModuleAnalysis, error
 	if result.ModuleName == "" {
 		result.ModuleName = module.Name
 	}
+	result.Frameworks = module.Frameworks
+
+	// Static edges are ground truth; keep any the LLM missed.
+	result.Wiring = mergeWiring(result.Wiring, module.KnownImports)
 
 	return &result, nil
 }
 
 // buildSynthesisPrompt constructs the user prompt for system-level synthesis.
-func buildSynthesisPrompt(modules []ModuleAnalysis) string {
+// Modules are listed in dependency order (see orderByDependencies). Decision records (ADRs), if any, are listed so the blueprint reflects
+// documented architectural decisions.
+func buildSynthesisPrompt(modules []ModuleAnalysis, decisions []sources.Artifact) string {
 	var b strings.Builder
 
 	b.WriteString("Synthesize the following module analyses into a system-level understanding.\n\n")
+	writeSynthesisBody(&b, modules, decisions)
+	return b.String()
+}
+
+// buildSynthesisUpdatePrompt constructs the user prompt for revising a prior
+// synthesis when only some modules changed: the prior blueprint and
+// patterns, then the changed modules and their neighbors as in
+// buildSynthesisPrompt.
+func buildSynthesisUpdatePrompt(prior SystemSynthesis, modules []ModuleAnalysis, decisions []sources.Artifact) string {
+	var b strings.Builder
 
-	for _, m := range modules {
-		fmt.Fprintf(&b, "## Module: %s\n", m.ModuleName)
-		fmt.Fprintf(&b, "Intent: %s\n", m.ModuleIntent)
+	b.WriteString("Update the following system-level understanding for the modules that changed.\n\n")
+	fmt.Fprintf(&b, "## Current blueprint\n\n%s\n\n", strings.TrimSpace(prior.Blueprint))
+	b.WriteString("## Current patterns\n\n")
+	if len(prior.Patterns) == 0 {
+		b.WriteString("(none)\n")
+	}
+	for _, p := range prior.Patterns {
+		fmt.Fprintf(&b, "- %s\n", p)
+	}
+	b.WriteString("\nThe modules below changed since the blueprint was written, or are wired directly to one that did; the rest of the system is unchanged. ")
+	b.WriteString("Revise the parts of the blueprint that concern these modules, keep everything else as written, and return the whole updated blueprint. ")
+	b.WriteString("Return the whole patterns list too, adding or dropping patterns only as these modules warrant.\n\n")
+	writeSynthesisBody(&b, modules, decisions)
+	return b.String()
+}
 
-		if len(m.Zones) > 0 {
-			b.WriteString("Zones:\n")
-			for _, z := range m.Zones {
-				fmt.Fprintf(&b, "  - %s: %s (files: %s)\n", z.Name, z.Intent, strings.Join(z.Files, ", "))
+// writeSynthesisBody writes the module analyses, decision records and
+// output contract shared by the synthesis prompts.
+func writeSynthesisBody(b *strings.Builder, modules []ModuleAnalysis, decisions []sources.Artifact) {
+	// Foundational modules are described first. Modules built from the
+	// same template (e.g. microservices in a monorepo) are collapsed into
+	// one entry so the prompt is not flooded with near-identical zones and
+	// wiring.
+	modules = orderByDependencies(modules)
+	for _, cluster := range clusterModules(modules) {
+		if len(cluster) < minClusterSize {
+			for _, i := range cluster {
+				writeModuleSection(b, modules[i])
 			}
+			continue
 		}
 
-		if len(m.Wiring) > 0 {
-			b.WriteString("Wiring:\n")
-			for _, w := range m.Wiring {
-				fmt.Fprintf(&b, "  - %s -> %s: %s\n", w.From, w.To, w.Reason)
-			}
+		names := make([]string, len(cluster))
+		for j, i := range cluster {
+			names[j] = modules[i].ModuleName
 		}
-
+		rep := modules[cluster[0]]
+		fmt.Fprintf(b, "## Module group: %d modules following the same template\n", len(cluster))
+		fmt.Fprintf(b, "Modules: %s\n", strings.Join(names, ", "))
+		fmt.Fprintf(b, "Representative module %s is shown; describe the group once rather than each member.\n", rep.ModuleName)
+		writeModuleDetails(b, rep)
 		b.WriteString("\n")
 	}
 
+	if len(decisions) > 0 {
+		b.WriteString("## Architecture Decision Records\n\n")
+		for _, d := range decisions {
+			status := d.Tags["status"]
+			if status == "" {
+				status = "unknown"
+			}
+			fmt.Fprintf(b, "- %s [status: %s] (%s)\n", d.Title, status, d.ID)
+		}
+		b.WriteString("\nReflect accepted decisions in the blueprint and note any that the code appears to contradict.\n\n")
+	}
+
 	b.WriteString(`Produce a JSON object with these fields:
 - "blueprint": a narrative description of the overall system architecture, cross-module interactions, and business purpose
 - "patterns": an array of strings, each describing a coding convention or architectural pattern discovered across the codebase
 `)
+}
 
-	return b.String()
+// writeModuleSection writes one module's analysis to the synthesis prompt.
+func writeModuleSection(b *strings.Builder, m ModuleAnalysis) {
+	fmt.Fprintf(b, "## Module: %s\n", m.ModuleName)
+	writeModuleDetails(b, m)
+	b.WriteString("\n")
+}
+
+// writeModuleDetails writes a module's intent, stack, zones and wiring.
+func writeModuleDetails(b *strings.Builder, m ModuleAnalysis) {
+	fmt.Fprintf(b, "Intent: %s\n", m.ModuleIntent)
+	if len(m.Frameworks) > 0 {
+		fmt.Fprintf(b, "Stack: %s\n", strings.Join(m.Frameworks, ", "))
+	}
+
+	if len(m.Zones) > 0 {
+		b.WriteString("Zones:\n")
+		for _, z := range m.Zones {
+			fmt.Fprintf(b, "  - %s: %s (files: %s)\n", z.Name, z.Intent, strings.Join(z.Files, ", "))
+		}
+	}
+
+	if len(m.Wiring) > 0 {
+		b.WriteString("Wiring:\n")
+		for _, w := range m.Wiring {
+			fmt.Fprintf(b, "  - %s -> %s: %s\n", w.From, w.To, w.Reason)
+		}
+	}
 }
 
 // SynthesizeSystem takes all module analyses, sends them to the deep tier, and returns
-// a system-level blueprint and discovered patterns.
-func (d *DeepAnalyzer) SynthesizeSystem(modules []ModuleAnalysis) (*SystemSynthesis, error) {
-	prompt := buildSynthesisPrompt(modules)
+// a system-level blueprint and discovered patterns. Optional decisions are
+// ADR artifacts (tagged type: adr) whose titles and statuses are included in
+// the prompt.
+func (d *DeepAnalyzer) SynthesizeSystem(modules []ModuleAnalysis, decisions ...sources.Artifact) (*SystemSynthesis, error) {
+	return d.SynthesizeSystemCtx(context.Background(), modules, decisions...)
+}
 
-	raw, err := d.llm.CompleteJSON(prompt, llm.TierDeep, &llm.CompleteOptions{
-		System:    "You are a senior software architect. Synthesize these module analyses into a system-level understanding. Respond with JSON.",
+// SynthesizeSystemCtx is like SynthesizeSystem but traces the LLM call
+// under ctx's span. Canceling ctx does not abort the call.
+func (d *DeepAnalyzer) SynthesizeSystemCtx(ctx context.Context, modules []ModuleAnalysis, decisions ...sources.Artifact) (*SystemSynthesis, error) {
+	prompt := buildSynthesisPrompt(modules, decisions)
+
+	raw, err := llm.CompleteValidJSON(d.llm.CompleteJSON, prompt, llm.TierDeep, &llm.CompleteOptions{
+		System:    llm.WithInstructions("You are a senior software architect. Synthesize these module analyses into a system-level understanding. Respond with JSON.", d.synthesisInstructions),
 		MaxTokens: d.maxTokens,
-	})
+		Context:   context.WithoutCancel(ctx),
+	}, synthesisSchema)
 	if err != nil {
 		return nil, fmt.Errorf("analyzer: LLM call failed for system synthesis: %w", err)
 	}
@@ -223,15 +402,53 @@ func (d *DeepAnalyzer) SynthesizeSystem(modules []ModuleAnalysis) (*SystemSynthe
 	if err := json.Unmarshal(raw, &result); err != nil {
 		return nil, fmt.Errorf("analyzer: failed to parse LLM synthesis response: %w", err)
 	}
+	result.Patterns = dedupePatterns(result.Patterns)
+
+	return &result, nil
+}
+
+// UpdateSynthesis revises prior, the synthesis of an earlier run, for the
+// given modules: the ones that changed since plus their direct wiring
+// neighbors (see Neighbors). Unlike SynthesizeSystem it does not need the
+// analyses of the unchanged rest of the system, which the prior blueprint
+// already describes.
+func (d *DeepAnalyzer) UpdateSynthesis(prior SystemSynthesis, modules []ModuleAnalysis, decisions ...sources.Artifact) (*SystemSynthesis, error) {
+	return d.UpdateSynthesisCtx(context.Background(), prior, modules, decisions...)
+}
+
+// UpdateSynthesisCtx is like UpdateSynthesis but traces the LLM call under
+// ctx's span. Canceling ctx does not abort the call.
+func (d *DeepAnalyzer) UpdateSynthesisCtx(ctx context.Context, prior SystemSynthesis, modules []ModuleAnalysis, decisions ...sources.Artifact) (*SystemSynthesis, error) {
+	prompt := buildSynthesisUpdatePrompt(prior, modules, decisions)
+
+	raw, err := llm.CompleteValidJSON(d.llm.CompleteJSON, prompt, llm.TierDeep, &llm.CompleteOptions{
+		System:    llm.WithInstructions("You are a senior software architect. Update this system-level understanding for the modules that changed. Respond with JSON.", d.synthesisInstructions),
+		MaxTokens: d.maxTokens,
+		Context:   context.WithoutCancel(ctx),
+	}, synthesisSchema)
+	if err != nil {
+		return nil, fmt.Errorf("analyzer: LLM call failed for system synthesis update: %w", err)
+	}
+
+	var result SystemSynthesis
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("analyzer: failed to parse LLM synthesis response: %w", err)
+	}
+	if strings.TrimSpace(result.Blueprint) == "" {
+		result.Blueprint = prior.Blueprint
+	}
+	result.Patterns = dedupePatterns(result.Patterns)
 
 	return &result, nil
 }
 
 // AnalyzeModules processes multiple modules in parallel using up to maxWorkers
 // goroutines. The progress callback, if non-nil, is called after each module
-// completes with (done, total) counts. Modules that fail analysis are skipped
-// with a logged warning; successfully analyzed modules are returned along with
-// any accumulated errors.
+// completes with (done, total) counts. Each module is retried up to the
+// configured number of attempts (see SetModuleAttempts). A module that still
+// fails is logged and returned as a placeholder with no wiring or zones and
+// FailedModuleIntent, so it stays visible to synthesis and storage; the
+// failures are returned as an aggregated error.
 func (d *DeepAnalyzer) AnalyzeModules(modules []ModuleInput, maxWorkers int, progress func(done, total int)) ([]ModuleAnalysis, error) {
 	return d.AnalyzeModulesCtx(context.Background(), modules, maxWorkers, progress)
 }
@@ -277,17 +494,20 @@ func (d *DeepAnalyzer) AnalyzeModulesCtx(ctx context.Context, modules []ModuleIn
 				return
 			}
 
-			analysis, err := d.AnalyzeModule(m)
+			mctx, span := tracing.Start(ctx, "module", tracing.String("carto.module", m.Name))
+			analysis, err := d.analyzeWithRetry(mctx, m)
+			span.RecordError(err)
+			span.End()
 
 			mu.Lock()
 			defer mu.Unlock()
 
 			if err != nil {
-				log.Printf("analyzer: warning: skipping module %q: %v", m.Name, err)
+				log.Printf("analyzer: warning: module %q failed after %d attempt(s): %v", m.Name, d.moduleAttempts, err)
 				errs = append(errs, err)
-			} else {
-				results[idx] = analysis
+				analysis = failedAnalysis(m.Name)
 			}
+			results[idx] = analysis
 
 			done++
 			if progress != nil {
@@ -298,7 +518,7 @@ func (d *DeepAnalyzer) AnalyzeModulesCtx(ctx context.Context, modules []ModuleIn
 
 	wg.Wait()
 
-	// Compact results: remove nil entries from skipped modules.
+	// Compact results: remove nil entries from modules never started.
 	compact := make([]ModuleAnalysis, 0, total)
 	for _, r := range results {
 		if r != nil {
@@ -307,7 +527,7 @@ func (d *DeepAnalyzer) AnalyzeModulesCtx(ctx context.Context, modules []ModuleIn
 	}
 
 	// If there were errors, return them joined. The caller still gets
-	// partial results for modules that succeeded.
+	// results for every module, with placeholders for the failed ones.
 	if len(errs) > 0 {
 		msgs := make([]string, len(errs))
 		for i, e := range errs {
@@ -318,3 +538,33 @@ func (d *DeepAnalyzer) AnalyzeModulesCtx(ctx context.Context, modules []ModuleIn
 
 	return compact, nil
 }
+
+// analyzeWithRetry runs AnalyzeModule up to d.moduleAttempts times,
+// returning the last error if none succeed. It stops early once ctx is done.
+func (d *DeepAnalyzer) analyzeWithRetry(ctx context.Context, m ModuleInput) (*ModuleAnalysis, error) {
+	var err error
+	for attempt := 1; attempt <= d.moduleAttempts; attempt++ {
+		var analysis *ModuleAnalysis
+		if analysis, err = d.analyzeModule(ctx, m); err == nil {
+			return analysis, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if attempt < d.moduleAttempts {
+			log.Printf("analyzer: warning: module %q attempt %d failed, retrying: %v", m.Name, attempt, err)
+		}
+	}
+	return nil, err
+}
+
+// failedAnalysis is the placeholder returned for a module whose analysis
+// failed on every attempt.
+func failedAnalysis(name string) *ModuleAnalysis {
+	return &ModuleAnalysis{
+		ModuleName:   name,
+		Wiring:       []Dependency{},
+		Zones:        []Zone{},
+		ModuleIntent: FailedModuleIntent,
+	}
+}
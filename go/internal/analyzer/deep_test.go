@@ -2,6 +2,7 @@ package analyzer
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -9,7 +10,9 @@ import (
 	"testing"
 
 	"github.com/divyekant/carto/internal/atoms"
+	"github.com/divyekant/carto/internal/history"
 	"github.com/divyekant/carto/internal/llm"
+	"github.com/divyekant/carto/internal/sources"
 )
 
 // mockLLM implements LLMClient for testing.
@@ -383,7 +386,7 @@ func TestBuildModulePrompt_TruncatesLargeInput(t *testing.T) {
 		Atoms: largeAtoms,
 	}
 
-	prompt := buildModulePrompt(input)
+	prompt := buildModulePrompt(input, maxPromptChars)
 
 	// The prompt should be capped at a reasonable size.
 	// With 500 atoms, each ~300 chars of summary+meta, the uncapped prompt would be ~150KB.
@@ -401,10 +404,172 @@ func TestBuildModulePrompt_TruncatesLargeInput(t *testing.T) {
 	}
 }
 
-func TestAnalyzeModules_SkipsErrors(t *testing.T) {
-	// Error on the second call (index 1).
+func TestBuildModulePrompt_ElidesLeastImportantWithinBudget(t *testing.T) {
+	input := ModuleInput{Name: "big", Path: "internal/big"}
+	for i := 0; i < 200; i++ {
+		input.Atoms = append(input.Atoms, &atoms.Atom{
+			Name:      fmt.Sprintf("Unit%d", i),
+			Kind:      "function",
+			FilePath:  "internal/big/big.go",
+			Summary:   strings.Repeat("does things ", 8),
+			StartLine: 1,
+			EndLine:   1 + i, // Unit199 is the longest
+		})
+	}
+	for i := 0; i < 200; i++ {
+		input.History = append(input.History, &history.FileHistory{
+			FilePath:   fmt.Sprintf("internal/big/f%d.go", i),
+			ChurnScore: float64(i), // f199.go churns most
+		})
+	}
+	for i := 0; i < 200; i++ {
+		input.Signals = append(input.Signals, sources.Artifact{
+			ID: fmt.Sprintf("#%d", i), Title: "an issue", Category: sources.Signal,
+		})
+	}
+
+	const budget = 8000
+	prompt := buildModulePrompt(input, budget)
+
+	if len(prompt) > budget {
+		t.Errorf("prompt length %d exceeds budget %d", len(prompt), budget)
+	}
+	for _, want := range []string{
+		"**Unit199**",            // most complex atom
+		"`internal/big/f199.go`", // most churned file
+		"#0: an issue",           // first signal
+		"code units elided to fit the prompt budget",
+		"files elided to fit the prompt budget",
+		"signals elided to fit the prompt budget",
+		`"module_intent"`, // instructions survive
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"**Unit0**", "`internal/big/f0.go`", "#199: an issue"} {
+		if strings.Contains(prompt, unwanted) {
+			t.Errorf("prompt should have elided %q", unwanted)
+		}
+	}
+}
+
+func TestBuildModulePrompt_StructuralAtoms(t *testing.T) {
+	prompt := buildModulePrompt(ModuleInput{
+		Name: "svc",
+		Path: "internal/svc",
+		Atoms: []*atoms.Atom{
+			{Name: "Serve", Kind: "function", FilePath: "internal/svc/serve.go"},
+		},
+	}, maxPromptChars)
+
+	if !strings.Contains(prompt, "**Serve** (function) in `internal/svc/serve.go`") {
+		t.Errorf("prompt should list the unit, got:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "Summary:") {
+		t.Error("prompt should omit empty summaries")
+	}
+	if !strings.Contains(prompt, "Summaries are unavailable") {
+		t.Error("prompt should say summaries are unavailable")
+	}
+}
+
+func TestBuildPrompts_IncludeStack(t *testing.T) {
+	prompt := buildModulePrompt(ModuleInput{
+		Name:       "web",
+		Path:       "web",
+		Frameworks: []string{"Next.js (app router)", "Express"},
+	}, maxPromptChars)
+	if !strings.Contains(prompt, "Stack (from its dependency files): Next.js (app router), Express.") {
+		t.Errorf("module prompt should name the stack, got:\n%s", prompt)
+	}
+
+	synth := buildSynthesisPrompt([]ModuleAnalysis{{
+		ModuleName:   "api",
+		ModuleIntent: "Serves orders.",
+		Frameworks:   []string{"Spring Boot"},
+	}}, nil)
+	if !strings.Contains(synth, "Intent: Serves orders.\nStack: Spring Boot\n") {
+		t.Errorf("synthesis prompt should give each module's stack, got:\n%s", synth)
+	}
+}
+
+func TestAnalyzeModules_RetriesTransientFailure(t *testing.T) {
+	// The first call fails; the retry succeeds.
 	mock := &errorLLM{
-		errorOn:   map[int]bool{1: true},
+		errorOn:   map[int]bool{0: true},
+		validResp: validModuleResponse,
+	}
+	da := NewDeepAnalyzer(mock)
+
+	results, err := da.AnalyzeModules([]ModuleInput{sampleModuleInput("auth")}, 1, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeModules returned error: %v", err)
+	}
+	if mock.calls != 2 {
+		t.Errorf("LLM called %d times, want 2", mock.calls)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	if results[0].ModuleIntent == FailedModuleIntent || len(results[0].Wiring) != 2 {
+		t.Errorf("module not analyzed on retry: %+v", results[0])
+	}
+}
+
+// sequenceLLM answers each call with the next of its responses, the last
+// one repeating, and records the prompts.
+type sequenceLLM struct {
+	responses []string
+	prompts   []string
+}
+
+func (m *sequenceLLM) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error) {
+	m.prompts = append(m.prompts, prompt)
+	return json.RawMessage(m.responses[min(len(m.prompts), len(m.responses))-1]), nil
+}
+
+func TestAnalyzeModule_ValidatesResponseSchema(t *testing.T) {
+	const zonesObject = `{"wiring": [], "zones": {"name": "auth", "intent": "login"}, "module_intent": "Handles auth."}`
+
+	t.Run("corrected on retry", func(t *testing.T) {
+		mock := &sequenceLLM{responses: []string{zonesObject, validModuleResponse}}
+		result, err := NewDeepAnalyzer(mock).AnalyzeModule(sampleModuleInput("auth"))
+		if err != nil {
+			t.Fatalf("AnalyzeModule: %v", err)
+		}
+		if len(mock.prompts) != 2 || !strings.Contains(mock.prompts[1], `"zones" must be an array, got object`) {
+			t.Errorf("retry prompt should name the mismatch, got %d prompt(s)", len(mock.prompts))
+		}
+		if len(result.Zones) == 0 {
+			t.Errorf("expected the corrected response's zones, got %+v", result)
+		}
+	})
+
+	t.Run("still wrong", func(t *testing.T) {
+		mock := &sequenceLLM{responses: []string{zonesObject}}
+		_, err := NewDeepAnalyzer(mock).AnalyzeModule(sampleModuleInput("auth"))
+		if !errors.Is(err, llm.ErrSchemaMismatch) {
+			t.Fatalf("expected ErrSchemaMismatch, got %v", err)
+		}
+		if len(mock.prompts) != 2 {
+			t.Errorf("LLM called %d times, want 2 (one corrective retry)", len(mock.prompts))
+		}
+	})
+
+	t.Run("missing intent", func(t *testing.T) {
+		mock := &sequenceLLM{responses: []string{`{"wiring": [], "zones": []}`}}
+		_, err := NewDeepAnalyzer(mock).AnalyzeModule(sampleModuleInput("auth"))
+		if err == nil || !strings.Contains(err.Error(), `"module_intent" is missing`) {
+			t.Errorf("expected a missing module_intent error, got %v", err)
+		}
+	})
+}
+
+func TestAnalyzeModules_KeepsFailedModules(t *testing.T) {
+	// Both attempts for the second module (calls 1 and 2) fail.
+	mock := &errorLLM{
+		errorOn:   map[int]bool{1: true, 2: true},
 		validResp: validModuleResponse,
 	}
 	da := NewDeepAnalyzer(mock)
@@ -430,13 +595,96 @@ func TestAnalyzeModules_SkipsErrors(t *testing.T) {
 		t.Errorf("error should mention 1 module failed, got: %v", err)
 	}
 
-	// 1 of 3 modules errored, so we should get 2 results.
-	if len(results) != 2 {
-		t.Errorf("got %d results, want 2 (1 error skipped)", len(results))
+	// The failed module is kept as a placeholder in its input position.
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	api := results[1]
+	if api.ModuleName != "api" || api.ModuleIntent != FailedModuleIntent || len(api.Wiring) != 0 || len(api.Zones) != 0 {
+		t.Errorf("failed module = %+v, want an empty placeholder", api)
+	}
+	if results[2].ModuleIntent == FailedModuleIntent {
+		t.Error("module after the failure should be analyzed")
 	}
 
 	// Progress should still be called for all 3 modules.
 	if pc := progressCalls.Load(); pc != 3 {
 		t.Errorf("progress called %d times, want 3", pc)
 	}
+
+	// With a single attempt, the first failure is final.
+	mock = &errorLLM{errorOn: map[int]bool{0: true}, validResp: validModuleResponse}
+	da = NewDeepAnalyzer(mock)
+	da.SetModuleAttempts(1)
+	if _, err := da.AnalyzeModules(modules[:1], 1, nil); err == nil || mock.calls != 1 {
+		t.Errorf("SetModuleAttempts(1): err = %v after %d call(s), want an error after 1", err, mock.calls)
+	}
+}
+
+func TestSynthesizeSystem_IncludesDecisionRecords(t *testing.T) {
+	var gotPrompt string
+	client := &promptCapture{resp: validSynthesisResponse, prompt: &gotPrompt}
+	da := NewDeepAnalyzer(client)
+
+	adr := sources.Artifact{
+		Source:   "adr",
+		Category: sources.Knowledge,
+		ID:       "docs/adr/0001-use-postgres.md",
+		Title:    "Use PostgreSQL",
+		Tags:     map[string]string{"type": "adr", "status": "accepted"},
+	}
+	if _, err := da.SynthesizeSystem([]ModuleAnalysis{{ModuleName: "api"}}, adr); err != nil {
+		t.Fatalf("SynthesizeSystem: %v", err)
+	}
+
+	if !strings.Contains(gotPrompt, "Architecture Decision Records") {
+		t.Error("prompt should contain an ADR section")
+	}
+	if !strings.Contains(gotPrompt, "Use PostgreSQL [status: accepted]") {
+		t.Errorf("prompt should list ADR title and status:\n%s", gotPrompt)
+	}
+}
+
+// promptCapture records the last prompt, and optionally the last system
+// prompt, it received.
+type promptCapture struct {
+	resp   string
+	prompt *string
+	system *string
+}
+
+func (p *promptCapture) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error) {
+	*p.prompt = prompt
+	if p.system != nil && opts != nil {
+		*p.system = opts.System
+	}
+	return json.RawMessage(p.resp), nil
+}
+
+func TestDeepAnalyzer_CustomInstructions(t *testing.T) {
+	var prompt, system string
+	da := NewDeepAnalyzer(&promptCapture{resp: validModuleResponse, prompt: &prompt, system: &system})
+	da.SetInstructions("Emphasize public API surface.", "Call out security boundaries.")
+
+	if _, err := da.AnalyzeModule(ModuleInput{Name: "auth", Path: "internal/auth"}); err != nil {
+		t.Fatalf("AnalyzeModule: %v", err)
+	}
+	if !strings.Contains(system, "Emphasize public API surface.") || strings.Contains(system, "security boundaries") {
+		t.Errorf("module system prompt should carry only the module instructions:\n%s", system)
+	}
+	if !strings.Contains(system, "respond with JSON") || !strings.Contains(prompt, `"module_intent"`) {
+		t.Error("module prompts lost the JSON output contract")
+	}
+
+	da = NewDeepAnalyzer(&promptCapture{resp: validSynthesisResponse, prompt: &prompt, system: &system})
+	da.SetInstructions("Emphasize public API surface.", "Call out security boundaries.")
+	if _, err := da.SynthesizeSystem([]ModuleAnalysis{{ModuleName: "auth"}}); err != nil {
+		t.Fatalf("SynthesizeSystem: %v", err)
+	}
+	if !strings.Contains(system, "Call out security boundaries.") || strings.Contains(system, "public API surface") {
+		t.Errorf("synthesis system prompt should carry only the synthesis instructions:\n%s", system)
+	}
+	if !strings.Contains(system, "Respond with JSON.") || !strings.Contains(prompt, `"blueprint"`) {
+		t.Error("synthesis prompts lost the JSON output contract")
+	}
 }
@@ -0,0 +1,222 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxReferencedSymbols caps how many symbol names are listed in the reason
+// of a single intra-package reference edge.
+const maxReferencedSymbols = 5
+
+// StaticGoWiring parses the Go files among relPaths (relative to root) and
+// returns ground-truth dependency edges without calling an LLM: one edge per
+// import of a package inside the scanned code (file -> import path) and one
+// per intra-package reference (file -> file that declares the referenced
+// top-level symbol). An import is inside the scanned code when its path is
+// one of modulePaths, the paths of the scanned Go modules, or lies under
+// one; standard library and third-party imports are left out. Non-Go files
+// and files that fail to parse are ignored. Edges are sorted for
+// deterministic prompts.
+func StaticGoWiring(root string, relPaths, modulePaths []string) []Dependency {
+	type goFile struct {
+		relPath string
+		file    *ast.File
+	}
+
+	fset := token.NewFileSet()
+	// Files grouped by directory + package name, since only files in the
+	// same package can reference each other's unqualified identifiers.
+	packages := make(map[string][]goFile)
+	var deps []Dependency
+
+	for _, rel := range relPaths {
+		if !strings.HasSuffix(rel, ".go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(root, rel), nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+
+		for _, imp := range f.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || !withinModules(path, modulePaths) {
+				continue
+			}
+			deps = append(deps, Dependency{From: rel, To: path, Reason: "imports " + path})
+		}
+
+		key := filepath.Dir(rel) + "|" + f.Name.Name
+		packages[key] = append(packages[key], goFile{relPath: rel, file: f})
+	}
+
+	for _, files := range packages {
+		if len(files) < 2 {
+			continue
+		}
+
+		// Map each top-level symbol to the file that declares it.
+		declaredIn := make(map[string]string)
+		for _, gf := range files {
+			for _, name := range topLevelNames(gf.file) {
+				declaredIn[name] = gf.relPath
+			}
+		}
+
+		for _, gf := range files {
+			refs := make(map[string]map[string]bool) // target file -> symbols
+			ast.Inspect(gf.file, func(n ast.Node) bool {
+				// Skip the selector half of pkg.Name / x.Field: it never
+				// refers to a package-level identifier.
+				if sel, ok := n.(*ast.SelectorExpr); ok {
+					ast.Inspect(sel.X, func(inner ast.Node) bool {
+						if id, ok := inner.(*ast.Ident); ok {
+							recordRef(refs, declaredIn, gf.relPath, id.Name)
+						}
+						return true
+					})
+					return false
+				}
+				if id, ok := n.(*ast.Ident); ok {
+					recordRef(refs, declaredIn, gf.relPath, id.Name)
+				}
+				return true
+			})
+
+			for target, syms := range refs {
+				names := make([]string, 0, len(syms))
+				for s := range syms {
+					names = append(names, s)
+				}
+				sort.Strings(names)
+				if len(names) > maxReferencedSymbols {
+					names = append(names[:maxReferencedSymbols], "...")
+				}
+				deps = append(deps, Dependency{
+					From:   gf.relPath,
+					To:     target,
+					Reason: "references " + strings.Join(names, ", "),
+				})
+			}
+		}
+	}
+
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].From != deps[j].From {
+			return deps[i].From < deps[j].From
+		}
+		return deps[i].To < deps[j].To
+	})
+	return deps
+}
+
+// withinModules reports whether importPath is one of modulePaths or a
+// package under one.
+func withinModules(importPath string, modulePaths []string) bool {
+	for _, m := range modulePaths {
+		if importPath == m || strings.HasPrefix(importPath, m+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// topLevelNames returns the package-level functions, types, vars and consts
+// declared in f. Methods are excluded since they are always referenced
+// through a selector.
+func topLevelNames(f *ast.File) []string {
+	var names []string
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.Name != "init" && d.Name.Name != "main" {
+				names = append(names, d.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, s.Name.Name)
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						if n.Name != "_" {
+							names = append(names, n.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// recordRef notes that file from references name, if name is declared in a
+// different file of the same package.
+func recordRef(refs map[string]map[string]bool, declaredIn map[string]string, from, name string) {
+	target, ok := declaredIn[name]
+	if !ok || target == from {
+		return
+	}
+	if refs[target] == nil {
+		refs[target] = make(map[string]bool)
+	}
+	refs[target][name] = true
+}
+
+// mergeWiring validates the inferred wiring against the static edges and
+// appends the static edges the LLM did not already report (matched on
+// from/to). Inferred edges the static graph contradicts are dropped (see
+// contradictsStatic).
+func mergeWiring(inferred, static []Dependency) []Dependency {
+	if len(static) == 0 {
+		return inferred
+	}
+	seen := make(map[[2]string]bool, len(inferred))
+	merged := inferred[:0]
+	for _, d := range inferred {
+		if contradictsStatic(d, static) {
+			continue
+		}
+		seen[[2]string{d.From, d.To}] = true
+		merged = append(merged, d)
+	}
+	for _, d := range static {
+		key := [2]string{d.From, d.To}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, d)
+	}
+	return merged
+}
+
+// contradictsStatic reports whether the inferred edge d joins two Go files
+// in a way the static graph rules out. Only edges from a file with static
+// edges of its own to another Go file are judged: they hold when the
+// static graph links the two files, or the first imports the package in
+// the second's directory. Edges naming symbols rather than files, and
+// those out of files the static graph knows nothing about, are kept.
+func contradictsStatic(d Dependency, static []Dependency) bool {
+	if !strings.HasSuffix(d.From, ".go") || !strings.HasSuffix(d.To, ".go") {
+		return false
+	}
+	dir := filepath.ToSlash(filepath.Dir(d.To))
+	known := false
+	for _, s := range static {
+		if s.From != d.From {
+			continue
+		}
+		known = true
+		if s.To == d.To || (dir != "." && (s.To == dir || strings.HasSuffix(s.To, "/"+dir))) {
+			return false
+		}
+	}
+	return known
+}
@@ -0,0 +1,149 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGoFile(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	path := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hasEdge(deps []Dependency, from, to string) bool {
+	for _, d := range deps {
+		if d.From == from && d.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStaticGoWiring_DetectsImportsAndReferences(t *testing.T) {
+	dir := t.TempDir()
+	writeGoFile(t, dir, "server/server.go", `package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"example.com/app/store"
+	"github.com/pkg/errors"
+)
+
+func Start() error {
+	fmt.Println("starting", store.DB)
+	return errors.Wrap(http.ListenAndServe(":8080", newRouter()), "serve")
+}
+`)
+	writeGoFile(t, dir, "server/router.go", `package server
+
+import "net/http"
+
+type Router struct{}
+
+func newRouter() http.Handler { return http.NewServeMux() }
+`)
+	writeGoFile(t, dir, "store/store.go", `package store
+
+import "database/sql"
+
+var DB *sql.DB
+`)
+	writeGoFile(t, dir, "README.md", "# not go\n")
+
+	deps := StaticGoWiring(dir, []string{"server/server.go", "server/router.go", "store/store.go", "README.md"}, []string{"example.com/app"})
+
+	for _, want := range [][2]string{
+		{"server/server.go", "example.com/app/store"},
+		{"server/server.go", "server/router.go"},
+	} {
+		if !hasEdge(deps, want[0], want[1]) {
+			t.Errorf("missing edge %s -> %s in %+v", want[0], want[1], deps)
+		}
+	}
+	// Standard library and third-party imports are not part of the project.
+	for _, d := range deps {
+		if !strings.HasPrefix(d.To, "example.com/app/") && !strings.HasSuffix(d.To, ".go") {
+			t.Errorf("unexpected edge to an out-of-project package: %+v", d)
+		}
+	}
+
+	// router.go does not reference anything declared in server.go.
+	if hasEdge(deps, "server/router.go", "server/server.go") {
+		t.Error("unexpected reverse reference edge router.go -> server.go")
+	}
+	for _, d := range deps {
+		if d.From == "server/server.go" && d.To == "server/router.go" && !strings.Contains(d.Reason, "newRouter") {
+			t.Errorf("expected reason to name newRouter, got %q", d.Reason)
+		}
+		if d.From == "README.md" {
+			t.Error("non-Go files must be ignored")
+		}
+	}
+}
+
+func TestAnalyzeModule_KnownImportsInPromptAndMerged(t *testing.T) {
+	mock := &mockLLM{responses: map[string]string{"Analyze the module": validModuleResponse}}
+	da := NewDeepAnalyzer(mock)
+
+	input := sampleModuleInput("auth")
+	input.KnownImports = []Dependency{
+		{From: "internal/auth/login.go", To: "example.com/app/internal/store", Reason: "imports example.com/app/internal/store"},
+	}
+
+	prompt := buildModulePrompt(input, maxPromptChars)
+	if !strings.Contains(prompt, "Known Imports") || !strings.Contains(prompt, "internal/auth/login.go -> example.com/app/internal/store") {
+		t.Errorf("prompt should list known imports:\n%s", prompt)
+	}
+
+	result, err := da.AnalyzeModule(input)
+	if err != nil {
+		t.Fatalf("AnalyzeModule: %v", err)
+	}
+	if !hasEdge(result.Wiring, "internal/auth/login.go", "example.com/app/internal/store") {
+		t.Errorf("static edge should be merged into wiring, got %+v", result.Wiring)
+	}
+	if !hasEdge(result.Wiring, "LoginHandler", "UserStore") {
+		t.Error("LLM-inferred edges should be preserved")
+	}
+}
+
+func TestMergeWiring_DropsEdgesContradictingStaticGraph(t *testing.T) {
+	static := []Dependency{
+		{From: "internal/auth/login.go", To: "example.com/app/internal/store", Reason: "imports example.com/app/internal/store"},
+		{From: "internal/auth/login.go", To: "internal/auth/session.go", Reason: "references newSession"},
+	}
+	inferred := []Dependency{
+		{From: "internal/auth/login.go", To: "internal/store/users.go", Reason: "loads users"},
+		{From: "internal/auth/login.go", To: "internal/auth/session.go", Reason: "starts a session"},
+		{From: "internal/auth/login.go", To: "internal/billing/invoice.go", Reason: "bills the user"},
+		{From: "internal/auth/logout.go", To: "internal/billing/invoice.go", Reason: "unknown to the static graph"},
+		{From: "LoginHandler", To: "UserStore", Reason: "symbol-level edge"},
+	}
+
+	merged := mergeWiring(inferred, static)
+
+	if hasEdge(merged, "internal/auth/login.go", "internal/billing/invoice.go") {
+		t.Error("login.go neither imports billing nor references invoice.go; the edge should be dropped")
+	}
+	for _, want := range [][2]string{
+		{"internal/auth/login.go", "internal/store/users.go"},
+		{"internal/auth/login.go", "internal/auth/session.go"},
+		{"internal/auth/logout.go", "internal/billing/invoice.go"},
+		{"LoginHandler", "UserStore"},
+		{"internal/auth/login.go", "example.com/app/internal/store"},
+	} {
+		if !hasEdge(merged, want[0], want[1]) {
+			t.Errorf("missing edge %s -> %s in %+v", want[0], want[1], merged)
+		}
+	}
+}
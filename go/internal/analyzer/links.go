@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"path"
+	"strings"
+)
+
+// ValidateLinks checks that every wiring edge of modules refers to
+// something the analysis was given: an atom's name, export, import or
+// file, a static import edge's endpoint, or a module. Names resolve
+// across all of inputs, so an edge into another module counts. Edges
+// with an endpoint that resolves to nothing, such as a unit the model
+// invented, are removed if drop is set and marked Unresolved otherwise.
+// It returns how many such edges it found.
+func ValidateLinks(modules []ModuleAnalysis, inputs []ModuleInput, drop bool) int {
+	known := knownUnits(modules, inputs)
+	invalid := 0
+	for i := range modules {
+		kept := modules[i].Wiring[:0]
+		for _, d := range modules[i].Wiring {
+			d.Unresolved = !resolves(known, d.From) || !resolves(known, d.To)
+			if d.Unresolved {
+				invalid++
+				if drop {
+					continue
+				}
+			}
+			kept = append(kept, d)
+		}
+		modules[i].Wiring = kept
+	}
+	return invalid
+}
+
+// knownUnits returns the lowercased names wiring may refer to. Files are
+// known by every trailing part of their path, with and without the
+// extension, so "llm/client.go" and "client" both name
+// "internal/llm/client.go".
+func knownUnits(modules []ModuleAnalysis, inputs []ModuleInput) map[string]bool {
+	known := make(map[string]bool)
+	add := func(name string) {
+		if name = normalizeUnit(name); name != "" {
+			known[name] = true
+		}
+	}
+	addPath := func(p string) {
+		p = normalizeUnit(p)
+		for p != "" {
+			add(p)
+			add(strings.TrimSuffix(p, path.Ext(p)))
+			_, rest, ok := strings.Cut(p, "/")
+			if !ok {
+				break
+			}
+			p = rest
+		}
+	}
+
+	for _, m := range modules {
+		add(m.ModuleName)
+	}
+	for _, in := range inputs {
+		add(in.Name)
+		addPath(in.Path)
+		for _, a := range in.Atoms {
+			add(a.Name)
+			addPath(a.FilePath)
+			for _, e := range a.Exports {
+				add(e)
+			}
+			for _, imp := range a.Imports {
+				addPath(imp)
+			}
+		}
+		for _, d := range in.KnownImports {
+			addPath(d.From)
+			addPath(d.To)
+		}
+	}
+	return known
+}
+
+// resolves reports whether the wiring node name is a known unit, either
+// as a whole or by its last component, so "Client.Complete" and
+// "llm::Client" resolve when "Complete" and "Client" are atoms.
+func resolves(known map[string]bool, name string) bool {
+	name = normalizeUnit(name)
+	if name == "" {
+		return false
+	}
+	if known[name] {
+		return true
+	}
+	if i := strings.LastIndexAny(name, ".:/"); i >= 0 && i < len(name)-1 {
+		return known[name[i+1:]]
+	}
+	return false
+}
+
+// normalizeUnit lowercases a unit name and strips the decoration models
+// add around it: whitespace, backticks, pointer stars and call parens.
+func normalizeUnit(name string) string {
+	name = strings.Trim(strings.TrimSpace(name), "`")
+	name = strings.TrimSuffix(name, "()")
+	name = strings.NewReplacer("*", "", "(", "", ")", "").Replace(name)
+	return strings.ToLower(strings.TrimPrefix(name, "./"))
+}
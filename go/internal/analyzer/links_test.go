@@ -0,0 +1,51 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/divyekant/carto/internal/atoms"
+)
+
+func TestValidateLinks(t *testing.T) {
+	inputs := []ModuleInput{
+		{Name: "api", Path: "api", Atoms: []*atoms.Atom{
+			{Name: "Handler", FilePath: "api/handler.go"},
+		}},
+		{Name: "store", Path: "store", Atoms: []*atoms.Atom{
+			{Name: "UserStore", FilePath: "store/users.go", Exports: []string{"NewUserStore"}},
+		}},
+	}
+	analyses := func() []ModuleAnalysis {
+		return []ModuleAnalysis{
+			{ModuleName: "api", Wiring: []Dependency{
+				{From: "Handler", To: "store.UserStore", Reason: "loads users"},
+				{From: "Handler", To: "SessionCache", Reason: "hallucinated"},
+			}},
+			{ModuleName: "store", Wiring: []Dependency{
+				{From: "users.go", To: "NewUserStore()", Reason: "constructor"},
+			}},
+		}
+	}
+
+	flagged := analyses()
+	if n := ValidateLinks(flagged, inputs, false); n != 1 {
+		t.Errorf("flag: found %d invalid edges, want 1", n)
+	}
+	if got := flagged[0].Wiring; len(got) != 2 || got[0].Unresolved || !got[1].Unresolved {
+		t.Errorf("flag: want only the SessionCache edge marked unresolved, got %+v", got)
+	}
+	if flagged[1].Wiring[0].Unresolved {
+		t.Errorf("flag: file and export edge should resolve, got %+v", flagged[1].Wiring[0])
+	}
+
+	dropped := analyses()
+	if n := ValidateLinks(dropped, inputs, true); n != 1 {
+		t.Errorf("drop: found %d invalid edges, want 1", n)
+	}
+	if got := dropped[0].Wiring; len(got) != 1 || got[0].To != "store.UserStore" {
+		t.Errorf("drop: want only the valid cross-module edge kept, got %+v", got)
+	}
+	if len(dropped[1].Wiring) != 1 {
+		t.Errorf("drop: valid edge removed from store: %+v", dropped[1].Wiring)
+	}
+}
@@ -0,0 +1,112 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// orderByDependencies returns modules ordered so that each module comes
+// after the modules its wiring points into, letting the synthesis prompt
+// describe foundational modules before the ones built on them. A module
+// depends on another when one of its wiring edges targets that module's
+// name or a path under it. Independent modules keep their input order;
+// modules left in a dependency cycle follow in name order.
+func orderByDependencies(modules []ModuleAnalysis) []ModuleAnalysis {
+	deps := make([]map[int]bool, len(modules))
+	dependents := make([][]int, len(modules))
+	for i, m := range modules {
+		deps[i] = make(map[int]bool)
+		for _, d := range m.Wiring {
+			j := moduleFor(modules, d.To)
+			if j < 0 || j == i || deps[i][j] {
+				continue
+			}
+			deps[i][j] = true
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	// Kahn's algorithm, always taking the earliest ready module.
+	remaining := make([]int, len(modules))
+	for i := range modules {
+		remaining[i] = len(deps[i])
+	}
+	placed := make([]bool, len(modules))
+	ordered := make([]ModuleAnalysis, 0, len(modules))
+	for {
+		next := -1
+		for i := range modules {
+			if !placed[i] && remaining[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		placed[next] = true
+		ordered = append(ordered, modules[next])
+		for _, k := range dependents[next] {
+			remaining[k]--
+		}
+	}
+
+	var cyclic []ModuleAnalysis
+	for i, m := range modules {
+		if !placed[i] {
+			cyclic = append(cyclic, m)
+		}
+	}
+	sort.SliceStable(cyclic, func(a, b int) bool { return cyclic[a].ModuleName < cyclic[b].ModuleName })
+	return append(ordered, cyclic...)
+}
+
+// moduleFor returns the index of the module that target names or lies
+// under, preferring the longest matching name, or -1 if none does.
+func moduleFor(modules []ModuleAnalysis, target string) int {
+	best, bestLen := -1, 0
+	for i, m := range modules {
+		name := m.ModuleName
+		if name == "" || len(name) <= bestLen {
+			continue
+		}
+		if target == name || strings.HasPrefix(target, name+"/") || strings.HasSuffix(target, "/"+name) {
+			best, bestLen = i, len(name)
+		}
+	}
+	return best
+}
+
+// Neighbors returns the names of the modules wired directly to one of the
+// named modules, in either direction: modules whose wiring targets one of
+// them, and modules their wiring targets. The named modules themselves are
+// left out; the rest keep their order in modules.
+func Neighbors(modules []ModuleAnalysis, names []string) []string {
+	named := make(map[string]bool, len(names))
+	for _, n := range names {
+		named[n] = true
+	}
+	near := make(map[int]bool)
+	for i, m := range modules {
+		for _, d := range m.Wiring {
+			j := moduleFor(modules, d.To)
+			if j < 0 || j == i {
+				continue
+			}
+			if named[m.ModuleName] {
+				near[j] = true
+			}
+			if named[modules[j].ModuleName] {
+				near[i] = true
+			}
+		}
+	}
+
+	var out []string
+	for i, m := range modules {
+		if near[i] && !named[m.ModuleName] {
+			out = append(out, m.ModuleName)
+		}
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func moduleNames(modules []ModuleAnalysis) string {
+	names := make([]string, len(modules))
+	for i, m := range modules {
+		names[i] = m.ModuleName
+	}
+	return strings.Join(names, " ")
+}
+
+func TestOrderByDependencies(t *testing.T) {
+	// web -> api -> core, api -> example.com/shared/log; cli is independent.
+	modules := []ModuleAnalysis{
+		{ModuleName: "web", Wiring: []Dependency{{From: "web/app.ts", To: "api/routes.go"}}},
+		{ModuleName: "cli"},
+		{ModuleName: "api", Wiring: []Dependency{
+			{From: "api/routes.go", To: "core"},
+			{From: "api/routes.go", To: "example.com/shared/log"},
+			{From: "api/routes.go", To: "api/handlers.go"}, // intra-module
+		}},
+		{ModuleName: "example.com/shared"},
+		{ModuleName: "core"},
+	}
+	got := moduleNames(orderByDependencies(modules))
+	if want := "cli example.com/shared core api web"; got != want {
+		t.Errorf("order = %q, want %q", got, want)
+	}
+}
+
+func TestOrderByDependencies_CyclesFallBackToNameOrder(t *testing.T) {
+	modules := []ModuleAnalysis{
+		{ModuleName: "zeta", Wiring: []Dependency{{To: "alpha"}}},
+		{ModuleName: "alpha", Wiring: []Dependency{{To: "zeta"}}},
+		{ModuleName: "base"},
+	}
+	got := moduleNames(orderByDependencies(modules))
+	if want := "base alpha zeta"; got != want {
+		t.Errorf("order = %q, want %q", got, want)
+	}
+}
+
+func TestBuildSynthesisPrompt_ListsModulesInDependencyOrder(t *testing.T) {
+	modules := []ModuleAnalysis{
+		{ModuleName: "frontend", ModuleIntent: "UI", Wiring: []Dependency{{From: "app.ts", To: "backend"}}},
+		{ModuleName: "backend", ModuleIntent: "API", Wiring: []Dependency{{From: "server.go", To: "storage/db.go"}}},
+		{ModuleName: "storage", ModuleIntent: "persistence"},
+	}
+	prompt := buildSynthesisPrompt(modules, nil)
+
+	last := -1
+	for _, name := range []string{"storage", "backend", "frontend"} {
+		i := strings.Index(prompt, fmt.Sprintf("## Module: %s\n", name))
+		if i < 0 {
+			t.Fatalf("module %s missing from prompt:\n%s", name, prompt)
+		}
+		if i < last {
+			t.Errorf("module %s listed before its dependencies:\n%s", name, prompt)
+		}
+		last = i
+	}
+}
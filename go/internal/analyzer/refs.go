@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// ModuleRefs holds the wiring edges of one module that mention a symbol.
+// Uses are edges from the symbol (what it depends on); UsedBy are edges to
+// it (what depends on it). An edge from the symbol to itself is in both.
+type ModuleRefs struct {
+	Module string       `json:"module"`
+	Uses   []Dependency `json:"uses"`
+	UsedBy []Dependency `json:"used_by"`
+}
+
+// MatchSymbol reports whether the wiring node name refers to symbol,
+// ignoring case. Unless exact, any name containing symbol matches, so
+// "UserStore" finds "UserStore.Get" and "*db.UserStore".
+func MatchSymbol(name, symbol string, exact bool) bool {
+	name, symbol = strings.ToLower(name), strings.ToLower(symbol)
+	if exact {
+		return name == symbol
+	}
+	return strings.Contains(name, symbol)
+}
+
+// FindReferences returns, per module, the wiring edges whose From or To
+// matches symbol (see MatchSymbol). Modules without a match are left out;
+// the rest are sorted by name, and each module's edges keep their order.
+func FindReferences(wiring map[string][]Dependency, symbol string, exact bool) []ModuleRefs {
+	var refs []ModuleRefs
+	for module, edges := range wiring {
+		r := ModuleRefs{Module: module, Uses: []Dependency{}, UsedBy: []Dependency{}}
+		for _, e := range edges {
+			if MatchSymbol(e.From, symbol, exact) {
+				r.Uses = append(r.Uses, e)
+			}
+			if MatchSymbol(e.To, symbol, exact) {
+				r.UsedBy = append(r.UsedBy, e)
+			}
+		}
+		if len(r.Uses) > 0 || len(r.UsedBy) > 0 {
+			refs = append(refs, r)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Module < refs[j].Module })
+	return refs
+}
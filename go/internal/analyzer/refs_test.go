@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindReferences(t *testing.T) {
+	wiring := map[string][]Dependency{
+		"api": {
+			{From: "Handler", To: "UserStore", Reason: "loads users"},
+			{From: "Handler", To: "Logger", Reason: "logs requests"},
+		},
+		"store": {
+			{From: "UserStore", To: "DB", Reason: "queries users"},
+			{From: "userstore.Cache", To: "Redis", Reason: "caches rows"},
+		},
+		"ui": {{From: "Page", To: "Client"}},
+	}
+
+	got := FindReferences(wiring, "userstore", false)
+	want := []ModuleRefs{
+		{Module: "api", Uses: []Dependency{}, UsedBy: []Dependency{{From: "Handler", To: "UserStore", Reason: "loads users"}}},
+		{Module: "store", Uses: []Dependency{
+			{From: "UserStore", To: "DB", Reason: "queries users"},
+			{From: "userstore.Cache", To: "Redis", Reason: "caches rows"},
+		}, UsedBy: []Dependency{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("substring match:\n got %+v\nwant %+v", got, want)
+	}
+
+	got = FindReferences(wiring, "USERSTORE", true)
+	if len(got) != 2 || len(got[1].Uses) != 1 || got[1].Uses[0].To != "DB" {
+		t.Errorf("exact match should skip userstore.Cache, got %+v", got)
+	}
+
+	if got := FindReferences(wiring, "Nothing", false); got != nil {
+		t.Errorf("expected no references, got %+v", got)
+	}
+}
@@ -19,6 +19,7 @@ type Chunk struct {
 	StartLine int
 	EndLine   int
 	Code      string
+	Doc       string // author-written doc comment preceding the code, if any
 }
 
 // Atom is the output of fast-tier analysis -- a clarified, summarized code unit.
@@ -32,6 +33,7 @@ type Atom struct {
 	Exports       []string `json:"exports"`
 	StartLine     int      `json:"start_line"`
 	EndLine       int      `json:"end_line"`
+	Code          string   `json:"code,omitempty"` // the chunk's original source
 }
 
 // LLMClient is the interface the analyzer needs from the LLM package.
@@ -39,20 +41,75 @@ type LLMClient interface {
 	CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error)
 }
 
+// Summary detail levels for SetSummaryDetail.
+const (
+	SummaryBrief    = "brief"    // one line
+	SummaryNormal   = "normal"   // 2-3 sentences (the default)
+	SummaryDetailed = "detailed" // a paragraph
+)
+
+// briefMaxTokens caps the fast tier's output for brief summaries; the
+// clarified code still has to fit alongside the one-line summary.
+const briefMaxTokens = 2048
+
+// summaryInstructions is the SUMMARIZE step of the prompt per detail level.
+var summaryInstructions = map[string]string{
+	SummaryBrief:    "Write a one-line summary (a single short sentence) of what this code does.",
+	SummaryNormal:   "Write a 2-3 sentence summary of what this code does and WHY it exists.",
+	SummaryDetailed: "Write a one-paragraph summary (4-6 sentences) of what this code does, WHY it exists, how it works, and any notable side effects or edge cases.",
+}
+
+// IsSummaryDetail reports whether s names a summary detail level.
+func IsSummaryDetail(s string) bool {
+	_, ok := summaryInstructions[s]
+	return ok
+}
+
 // Analyzer processes code chunks through the fast tier.
 type Analyzer struct {
-	llm       LLMClient
-	maxTokens int
+	llm          LLMClient
+	maxTokens    int
+	instructions string
+	detail       string
 }
 
 // NewAnalyzer creates an Analyzer that uses the given LLM client.
 // Optional maxTokens overrides the default 4096 output token limit.
 func NewAnalyzer(client LLMClient, maxTokens ...int) *Analyzer {
-	mt := 4096
+	mt := llm.DefaultFastMaxTokens
 	if len(maxTokens) > 0 && maxTokens[0] > 0 {
 		mt = maxTokens[0]
 	}
-	return &Analyzer{llm: client, maxTokens: mt}
+	return &Analyzer{llm: client, maxTokens: mt, detail: SummaryNormal}
+}
+
+// SetSummaryDetail sets how long atom summaries should be: SummaryBrief,
+// SummaryNormal or SummaryDetailed. Brief also lowers the output token cap.
+// Anything else, including "", selects SummaryNormal.
+func (a *Analyzer) SetSummaryDetail(detail string) {
+	if !IsSummaryDetail(detail) {
+		detail = SummaryNormal
+	}
+	a.detail = detail
+}
+
+// completionMaxTokens returns the output token cap for chunk analysis.
+func (a *Analyzer) completionMaxTokens() int {
+	if a.detail == SummaryBrief {
+		return min(a.maxTokens, briefMaxTokens)
+	}
+	return a.maxTokens
+}
+
+// SetInstructions appends custom guidance, such as "focus on security
+// implications", to the system prompt of every chunk analysis.
+func (a *Analyzer) SetInstructions(instructions string) {
+	a.instructions = instructions
+}
+
+// systemPrompt returns the system prompt for chunk analysis.
+func (a *Analyzer) systemPrompt() string {
+	return llm.WithInstructions("You are a code analysis assistant. Respond only with valid JSON.", a.instructions)
 }
 
 // llmResponse is the expected JSON shape returned by the LLM.
@@ -63,35 +120,58 @@ type llmResponse struct {
 	Exports       []string `json:"exports"`
 }
 
-// buildPrompt constructs the prompt sent to the fast tier for a given chunk.
-func buildPrompt(chunk Chunk) string {
+// responseSchema is what a response must look like to be decoded into an
+// llmResponse; see llm.CompleteValidJSON.
+var responseSchema = llm.Schema{
+	{Name: "summary", Type: llm.TypeString, Required: true},
+	{Name: "clarified_code", Type: llm.TypeString},
+	{Name: "imports", Type: llm.TypeArray, Items: llm.TypeString},
+	{Name: "exports", Type: llm.TypeArray, Items: llm.TypeString},
+}
+
+// buildPrompt constructs the prompt sent to the fast tier for a given chunk,
+// asking for a summary of the given detail level. When the chunk carries a
+// doc comment it is included ahead of the code, since it states the
+// author's intent.
+func buildPrompt(chunk Chunk, detail string) string {
+	doc := ""
+	if chunk.Doc != "" {
+		doc = "Author's doc comment (use it to inform the summary):\n" + chunk.Doc + "\n\n"
+	}
 	return fmt.Sprintf(`Analyze this %s code unit (%s: %s) from %s.
 
 1. CLARIFY: Rename any cryptic/single-letter variables to meaningful names. Add brief inline comments for complex logic. Keep the code structure identical.
-2. SUMMARIZE: Write a 1-3 sentence summary of what this code does and WHY it exists.
+2. SUMMARIZE: %s
 3. IMPORTS: List any external dependencies this code uses.
 4. EXPORTS: List any symbols this code makes available to other modules.
 
 Respond as JSON:
 {"clarified_code": "...", "summary": "...", "imports": ["..."], "exports": ["..."]}
 
-Code:
+%sCode:
 `+"`"+"`"+"`"+`%s
 %s
 `+"`"+"`"+"`",
 		chunk.Language, chunk.Kind, chunk.Name, chunk.FilePath,
-		chunk.Language, chunk.Code)
+		summaryInstructions[detail], doc, chunk.Language, chunk.Code)
 }
 
 // AnalyzeChunk sends a single code chunk to the fast tier for clarification and
 // summarization, returning the resulting Atom.
 func (a *Analyzer) AnalyzeChunk(chunk Chunk) (*Atom, error) {
-	prompt := buildPrompt(chunk)
+	return a.analyzeChunk(context.Background(), chunk)
+}
+
+// analyzeChunk is AnalyzeChunk with the LLM call traced under ctx's span.
+// Canceling ctx does not abort the call.
+func (a *Analyzer) analyzeChunk(ctx context.Context, chunk Chunk) (*Atom, error) {
+	prompt := buildPrompt(chunk, a.detail)
 
-	raw, err := a.llm.CompleteJSON(prompt, llm.TierFast, &llm.CompleteOptions{
-		System:    "You are a code analysis assistant. Respond only with valid JSON.",
-		MaxTokens: a.maxTokens,
-	})
+	raw, err := llm.CompleteValidJSON(a.llm.CompleteJSON, prompt, llm.TierFast, &llm.CompleteOptions{
+		System:    a.systemPrompt(),
+		MaxTokens: a.completionMaxTokens(),
+		Context:   context.WithoutCancel(ctx),
+	}, responseSchema)
 	if err != nil {
 		return nil, fmt.Errorf("atoms: LLM call failed: %w", err)
 	}
@@ -111,6 +191,7 @@ func (a *Analyzer) AnalyzeChunk(chunk Chunk) (*Atom, error) {
 		Exports:       resp.Exports,
 		StartLine:     chunk.StartLine,
 		EndLine:       chunk.EndLine,
+		Code:          chunk.Code,
 	}
 
 	return atom, nil
@@ -169,7 +250,7 @@ func (a *Analyzer) AnalyzeBatchCtx(ctx context.Context, chunks []Chunk, maxWorke
 				return
 			}
 
-			atom, err := a.AnalyzeChunk(ch)
+			atom, err := a.analyzeChunk(ctx, ch)
 
 			mu.Lock()
 			defer mu.Unlock()
@@ -17,6 +17,8 @@ type mockLLM struct {
 	response string
 	calls    int
 	prompts  []string
+	systems  []string
+	limits   []int
 }
 
 func (m *mockLLM) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error) {
@@ -24,6 +26,10 @@ func (m *mockLLM) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteO
 	defer m.mu.Unlock()
 	m.calls++
 	m.prompts = append(m.prompts, prompt)
+	if opts != nil {
+		m.systems = append(m.systems, opts.System)
+		m.limits = append(m.limits, opts.MaxTokens)
+	}
 	return json.RawMessage(m.response), nil
 }
 
@@ -156,6 +162,85 @@ func TestAnalyzeChunk_PromptContainsCode(t *testing.T) {
 	}
 }
 
+func TestAnalyzeChunk_CustomInstructions(t *testing.T) {
+	mock := &mockLLM{response: validResponse}
+	analyzer := NewAnalyzer(mock)
+	analyzer.SetInstructions("Focus on security implications.")
+
+	if _, err := analyzer.AnalyzeChunk(sampleChunk()); err != nil {
+		t.Fatalf("AnalyzeChunk returned error: %v", err)
+	}
+
+	system := mock.systems[0]
+	if !strings.Contains(system, "Focus on security implications.") {
+		t.Errorf("system prompt should contain the custom instructions:\n%s", system)
+	}
+	if !strings.Contains(system, "Respond only with valid JSON.") {
+		t.Errorf("system prompt lost the JSON instruction:\n%s", system)
+	}
+	if !strings.Contains(mock.prompts[0], `{"clarified_code": "...", "summary": "..."`) {
+		t.Error("prompt lost the JSON response schema")
+	}
+}
+
+func TestAnalyzeChunk_PromptIncludesDoc(t *testing.T) {
+	mock := &mockLLM{response: validResponse}
+	analyzer := NewAnalyzer(mock)
+
+	chunk := sampleChunk()
+	chunk.Doc = "processData validates an incoming payload."
+	if _, err := analyzer.AnalyzeChunk(chunk); err != nil {
+		t.Fatalf("AnalyzeChunk returned error: %v", err)
+	}
+	if !strings.Contains(mock.prompts[0], chunk.Doc) {
+		t.Errorf("prompt does not contain doc comment.\nPrompt:\n%s", mock.prompts[0])
+	}
+
+	// Without a doc the section is omitted entirely.
+	if strings.Contains(buildPrompt(sampleChunk(), SummaryNormal), "doc comment") {
+		t.Error("prompt should not mention a doc comment when none is present")
+	}
+}
+
+func TestAnalyzeChunk_SummaryDetail(t *testing.T) {
+	tests := []struct {
+		detail    string
+		wording   string
+		maxTokens int
+	}{
+		{SummaryBrief, "one-line summary", briefMaxTokens},
+		{SummaryNormal, "2-3 sentence summary", 8192},
+		{SummaryDetailed, "one-paragraph summary", 8192},
+		{"", "2-3 sentence summary", 8192},
+	}
+	for _, tt := range tests {
+		mock := &mockLLM{response: validResponse}
+		analyzer := NewAnalyzer(mock, 8192)
+		analyzer.SetSummaryDetail(tt.detail)
+
+		if _, err := analyzer.AnalyzeChunk(sampleChunk()); err != nil {
+			t.Fatalf("%q: AnalyzeChunk returned error: %v", tt.detail, err)
+		}
+		if !strings.Contains(mock.prompts[0], tt.wording) {
+			t.Errorf("%q: prompt missing %q", tt.detail, tt.wording)
+		}
+		if mock.limits[0] != tt.maxTokens {
+			t.Errorf("%q: MaxTokens = %d, want %d", tt.detail, mock.limits[0], tt.maxTokens)
+		}
+	}
+
+	// Brief never raises a cap that is already lower.
+	mock := &mockLLM{response: validResponse}
+	analyzer := NewAnalyzer(mock, 1000)
+	analyzer.SetSummaryDetail(SummaryBrief)
+	if _, err := analyzer.AnalyzeChunk(sampleChunk()); err != nil {
+		t.Fatalf("AnalyzeChunk returned error: %v", err)
+	}
+	if mock.limits[0] != 1000 {
+		t.Errorf("brief MaxTokens = %d, want 1000", mock.limits[0])
+	}
+}
+
 func TestAnalyzeBatch_Parallel(t *testing.T) {
 	mock := &mockLLM{response: validResponse}
 	analyzer := NewAnalyzer(mock)
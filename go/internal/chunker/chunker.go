@@ -3,9 +3,14 @@
 package chunker
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"strings"
 	"unsafe"
 
+	tree_sitter_kotlin "github.com/alexaandru/go-sitter-forest/kotlin"
+	tree_sitter_swift "github.com/alexaandru/go-sitter-forest/swift"
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
 	tree_sitter_java "github.com/tree-sitter/tree-sitter-java/bindings/go"
@@ -13,36 +18,64 @@ import (
 	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
 	tree_sitter_rust "github.com/tree-sitter/tree-sitter-rust/bindings/go"
 	tree_sitter_typescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
+
+	"github.com/divyekant/carto/internal/scanner"
 )
 
 // Chunk represents a single logical code unit extracted from a source file.
 type Chunk struct {
 	Name      string // function/class/type name
-	Kind      string // "function", "method", "class", "type", "interface", "const", "module"
+	Kind      string // "function", "method", "class", "type", "interface", "component", "const", "module"
 	Language  string // "go", "javascript", etc.
 	FilePath  string // source file path
 	StartLine int    // 1-based start line
 	EndLine   int    // 1-based end line
 	Code      string // raw source code of this chunk
+	Doc       string // leading doc comment or Python docstring, markers stripped
 }
 
 // ChunkOptions configures the chunking behavior.
 type ChunkOptions struct {
-	MaxChunkLines int // default 200 -- if a chunk is bigger, keep it whole but flag it
+	MaxChunkLines int      // default 200 -- if a chunk is bigger, keep it whole but flag it
+	Kinds         []string // chunk Kinds to emit, e.g. "function", "class", "method"; empty emits all
+	MinLines      int      // declarations shorter than this are merged with adjacent ones or dropped; 0 disables
+	NoFallback    bool     // return no chunks, rather than one whole-file chunk, for a parseable file without declarations
 }
 
 // defaultMaxChunkLines is used when ChunkOptions is nil or MaxChunkLines is 0.
 const defaultMaxChunkLines = 200
 
+// ErrParsePanic is wrapped by the error ChunkFile returns when Tree-sitter
+// panicked while parsing or walking a file.
+var ErrParsePanic = errors.New("tree-sitter panicked")
+
+// parseCode parses code with parser; tests replace it to simulate a parser
+// that panics.
+var parseCode = func(parser *tree_sitter.Parser, code []byte) *tree_sitter.Tree {
+	return parser.Parse(code, nil)
+}
+
 // ChunkFile splits a source file into logical code chunks. It uses Tree-sitter
 // for languages with grammar support (Go, JavaScript, TypeScript, Python, Java,
-// Rust) and falls back to returning the entire file as a single "module" chunk
-// for unsupported languages or empty files.
+// Rust, Kotlin, Swift) and falls back to returning the entire file as a single
+// "module" chunk for unsupported languages or files without declarations (see
+// NoFallback). Code in UTF-16 or Latin-1 is transcoded to UTF-8 first, so
+// chunk byte ranges and names are UTF-8.
+//
+// If Tree-sitter panics on a malformed file, the panic is recovered and
+// ChunkFile returns the whole-file chunk together with an error wrapping
+// ErrParsePanic, so callers can keep the fallback and still report the
+// file. A crash inside the C parser itself cannot be recovered.
 func ChunkFile(path string, code []byte, language string, opts *ChunkOptions) ([]Chunk, error) {
 	if len(code) == 0 {
 		return nil, nil
 	}
 
+	code, _, err := scanner.ToUTF8(code)
+	if err != nil {
+		return nil, fmt.Errorf("chunker: %s: %w", path, err)
+	}
+
 	maxLines := defaultMaxChunkLines
 	if opts != nil && opts.MaxChunkLines > 0 {
 		maxLines = opts.MaxChunkLines
@@ -55,18 +88,114 @@ func ChunkFile(path string, code []byte, language string, opts *ChunkOptions) ([
 	}
 
 	chunks, err := chunkWithTreeSitter(path, code, language, langPtr)
+	if errors.Is(err, ErrParsePanic) {
+		log.Printf("chunker: warning: %v; using the whole file as one chunk", err)
+		return enforceMaxLines([]Chunk{wholeFileChunk(path, code, language)}, maxLines), err
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	if len(chunks) == 0 {
-		// Parseable language but no extractable chunks -- return whole file.
+		// Parseable language but no extractable chunks (e.g. a Go file of
+		// only imports and //go:generate directives) -- return whole file,
+		// unless the caller would rather report it as empty.
+		if opts != nil && opts.NoFallback {
+			return nil, nil
+		}
 		return enforceMaxLines([]Chunk{wholeFileChunk(path, code, language)}, maxLines), nil
 	}
 
+	// Filters run after the whole-file fallback on purpose: a file whose
+	// declarations are all filtered out yields no chunks rather than one
+	// big module chunk.
+	if opts != nil {
+		chunks = filterKinds(chunks, opts.Kinds)
+		chunks = mergeSmall(chunks, opts.MinLines)
+	}
+
 	return enforceMaxLines(chunks, maxLines), nil
 }
 
+// filterKinds keeps only chunks whose Kind is listed in kinds. An empty
+// list keeps everything.
+func filterKinds(chunks []Chunk, kinds []string) []Chunk {
+	if len(kinds) == 0 {
+		return chunks
+	}
+	allowed := make(map[string]bool, len(kinds))
+	for _, k := range kinds {
+		allowed[strings.TrimSpace(k)] = true
+	}
+	kept := chunks[:0]
+	for _, c := range chunks {
+		if allowed[c.Kind] {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// mergeSmall folds each run of consecutive chunks shorter than minLines
+// into a single chunk. A run that is still shorter than minLines once
+// merged (a lone one-liner between two large functions) is dropped.
+func mergeSmall(chunks []Chunk, minLines int) []Chunk {
+	if minLines <= 1 {
+		return chunks
+	}
+	var out, run []Chunk
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		merged := mergeChunks(run)
+		if merged.EndLine-merged.StartLine+1 >= minLines {
+			out = append(out, merged)
+		}
+		run = nil
+	}
+	for _, c := range chunks {
+		if c.EndLine-c.StartLine+1 < minLines {
+			run = append(run, c)
+			continue
+		}
+		flush()
+		out = append(out, c)
+	}
+	flush()
+	return out
+}
+
+// mergeChunks combines adjacent chunks into one spanning all of them. The
+// merged Kind is shared when every part agrees and "module" otherwise.
+func mergeChunks(run []Chunk) Chunk {
+	if len(run) == 1 {
+		return run[0]
+	}
+	merged := run[0]
+	names := []string{run[0].Name}
+	codes := []string{run[0].Code}
+	docs := []string{}
+	if run[0].Doc != "" {
+		docs = append(docs, run[0].Doc)
+	}
+	for _, c := range run[1:] {
+		if c.Kind != merged.Kind {
+			merged.Kind = "module"
+		}
+		names = append(names, c.Name)
+		codes = append(codes, c.Code)
+		if c.Doc != "" {
+			docs = append(docs, c.Doc)
+		}
+		merged.EndLine = c.EndLine
+	}
+	merged.Name = strings.Join(names, ", ")
+	merged.Code = strings.Join(codes, "\n")
+	merged.Doc = strings.Join(docs, "\n")
+	return merged
+}
+
 // languagePtr returns the Tree-sitter language pointer for a given language
 // name, or nil if the language is not supported.
 func languagePtr(language string) unsafe.Pointer {
@@ -83,6 +212,10 @@ func languagePtr(language string) unsafe.Pointer {
 		return tree_sitter_java.Language()
 	case "rust":
 		return tree_sitter_rust.Language()
+	case "kotlin":
+		return tree_sitter_kotlin.GetLanguage()
+	case "swift":
+		return tree_sitter_swift.GetLanguage()
 	default:
 		return nil
 	}
@@ -124,14 +257,155 @@ func nodeKindsForLanguage(language string) map[string]string {
 			"struct_item":   "type",
 			"enum_item":     "type",
 		}
+	case "kotlin":
+		// Interfaces are class declarations too; see refineKind.
+		return map[string]string{
+			"function_declaration": "function",
+			"class_declaration":    "class",
+			"object_declaration":   "class",
+		}
+	case "swift":
+		// Structs, enums, actors and extensions parse as class
+		// declarations; see refineKind.
+		return map[string]string{
+			"function_declaration": "function",
+			"class_declaration":    "class",
+			"protocol_declaration": "interface",
+		}
 	default:
 		return nil
 	}
 }
 
+// refineKind narrows the generic Kind from nodeKindsForLanguage using
+// framework and language idioms: a Go type declaration of an interface is
+// an "interface", as is a Kotlin class declaration with the interface
+// keyword; a Swift struct or enum is a "type"; and a JS/TS const holding a
+// function under a PascalCase name (the React convention, e.g.
+// `const Button = () => ...`, optionally wrapped in memo or forwardRef) is
+// a "component", exported or not.
+func refineKind(node *tree_sitter.Node, code []byte, language, kind string) string {
+	switch language {
+	case "go":
+		if node.Kind() == "type_declaration" && goTypeSpec(node, "interface_type") != nil {
+			return "interface"
+		}
+	case "javascript", "typescript":
+		decl := node
+		if node.Kind() == "export_statement" {
+			decl = node.ChildByFieldName("declaration")
+		}
+		if decl != nil && decl.Kind() == "lexical_declaration" && isComponentDecl(decl, code) {
+			return "component"
+		}
+	case "kotlin":
+		if node.Kind() == "class_declaration" && hasChildKind(node, "interface") {
+			return "interface"
+		}
+	case "swift":
+		if dk := node.ChildByFieldName("declaration_kind"); dk != nil && (dk.Kind() == "struct" || dk.Kind() == "enum") {
+			return "type"
+		}
+	}
+	return kind
+}
+
+// hasChildKind reports whether any direct child of node, named or not, is
+// of the given kind, such as a keyword.
+func hasChildKind(node *tree_sitter.Node, kind string) bool {
+	for i := uint(0); i < node.ChildCount(); i++ {
+		if child := node.Child(i); child != nil && child.Kind() == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// goTypeSpec returns the first type_spec of a Go type declaration if its
+// type is a node of typeKind, e.g. "interface_type".
+func goTypeSpec(node *tree_sitter.Node, typeKind string) *tree_sitter.Node {
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		spec := node.NamedChild(i)
+		if spec == nil || spec.Kind() != "type_spec" {
+			continue
+		}
+		if t := spec.ChildByFieldName("type"); t != nil && t.Kind() == typeKind {
+			return spec
+		}
+		return nil
+	}
+	return nil
+}
+
+// goInterfaceMethods breaks the method set of a Go interface declaration
+// out into one "method" chunk per method, named "Interface.Method". An
+// interface with fewer than two methods is already one unit and yields
+// none.
+func goInterfaceMethods(node *tree_sitter.Node, code []byte, path, iface string) []Chunk {
+	spec := goTypeSpec(node, "interface_type")
+	if spec == nil {
+		return nil
+	}
+	body := spec.ChildByFieldName("type")
+
+	var methods []Chunk
+	for i := uint(0); i < body.NamedChildCount(); i++ {
+		elem := body.NamedChild(i)
+		if elem == nil || elem.Kind() != "method_elem" {
+			continue
+		}
+		chunk := nodeToChunk(elem, code, path, "go", "method")
+		chunk.Name = iface + "." + chunk.Name
+		methods = append(methods, *chunk)
+	}
+	if len(methods) < 2 {
+		return nil
+	}
+	return methods
+}
+
+// isComponentDecl reports whether a JS/TS lexical_declaration binds a
+// PascalCase name to a function: an arrow function, a function expression,
+// or a call (memo, forwardRef, ...) whose first argument is one.
+func isComponentDecl(node *tree_sitter.Node, code []byte) bool {
+	for i := uint(0); i < node.NamedChildCount(); i++ {
+		decl := node.NamedChild(i)
+		if decl == nil || decl.Kind() != "variable_declarator" {
+			continue
+		}
+		name, value := decl.ChildByFieldName("name"), decl.ChildByFieldName("value")
+		if name == nil || value == nil || !isPascalCase(name.Utf8Text(code)) {
+			return false
+		}
+		if value.Kind() == "call_expression" {
+			args := value.ChildByFieldName("arguments")
+			if args == nil || args.NamedChildCount() == 0 {
+				return false
+			}
+			value = args.NamedChild(0)
+		}
+		return value != nil && (value.Kind() == "arrow_function" || value.Kind() == "function_expression")
+	}
+	return false
+}
+
+// isPascalCase reports whether name starts with an upper-case ASCII letter
+// and is not all upper case, so constants like MAX_SIZE don't qualify.
+func isPascalCase(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z' && strings.ToUpper(name) != name
+}
+
 // chunkWithTreeSitter parses code using Tree-sitter and extracts top-level
-// declarations as chunks.
-func chunkWithTreeSitter(path string, code []byte, language string, langPtr unsafe.Pointer) ([]Chunk, error) {
+// declarations as chunks. A panic while parsing or walking the tree is
+// returned as an error wrapping ErrParsePanic instead of unwinding the
+// caller's goroutine.
+func chunkWithTreeSitter(path string, code []byte, language string, langPtr unsafe.Pointer) (chunks []Chunk, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			chunks, err = nil, fmt.Errorf("chunker: %s: %w: %v", path, ErrParsePanic, r)
+		}
+	}()
+
 	parser := tree_sitter.NewParser()
 	defer parser.Close()
 
@@ -140,7 +414,7 @@ func chunkWithTreeSitter(path string, code []byte, language string, langPtr unsa
 		return nil, err
 	}
 
-	tree := parser.Parse(code, nil)
+	tree := parseCode(parser, code)
 	if tree == nil {
 		return nil, nil
 	}
@@ -152,7 +426,6 @@ func chunkWithTreeSitter(path string, code []byte, language string, langPtr unsa
 		return nil, nil
 	}
 
-	var chunks []Chunk
 	cursor := root.Walk()
 	defer cursor.Close()
 
@@ -169,9 +442,13 @@ func chunkWithTreeSitter(path string, code []byte, language string, langPtr unsa
 
 		kind := node.Kind()
 		if chunkKind, ok := kinds[kind]; ok {
+			chunkKind = refineKind(node, code, language, chunkKind)
 			chunk := nodeToChunk(node, code, path, language, chunkKind)
 			if chunk != nil {
 				chunks = append(chunks, *chunk)
+				if chunkKind == "interface" && language == "go" {
+					chunks = append(chunks, goInterfaceMethods(node, code, path, chunk.Name)...)
+				}
 			}
 		}
 
@@ -206,9 +483,85 @@ func nodeToChunk(node *tree_sitter.Node, code []byte, path, language, kind strin
 		StartLine: startLine,
 		EndLine:   endLine,
 		Code:      chunkCode,
+		Doc:       extractDoc(node, code, language),
 	}
 }
 
+// isCommentNode reports whether a node kind is a comment in any supported
+// grammar (Java, Rust and Kotlin split line and block comments).
+func isCommentNode(kind string) bool {
+	return kind == "comment" || kind == "line_comment" || kind == "block_comment" || kind == "multiline_comment"
+}
+
+// extractDoc returns the documentation attached to a declaration: the
+// contiguous block of comments immediately preceding it (no blank line in
+// between) or, for Python, the docstring at the top of the body.
+func extractDoc(node *tree_sitter.Node, code []byte, language string) string {
+	if language == "python" {
+		if doc := extractPythonDocstring(node, code); doc != "" {
+			return doc
+		}
+	}
+
+	var comments []string
+	nextRow := node.StartPosition().Row
+	for prev := node.PrevSibling(); prev != nil && isCommentNode(prev.Kind()); prev = prev.PrevSibling() {
+		// A blank line separates this comment from the declaration.
+		if nextRow-prev.EndPosition().Row > 1 {
+			break
+		}
+		comments = append([]string{prev.Utf8Text(code)}, comments...)
+		nextRow = prev.StartPosition().Row
+	}
+	if len(comments) == 0 {
+		return ""
+	}
+	return cleanComment(strings.Join(comments, "\n"))
+}
+
+// extractPythonDocstring returns the string literal that opens a Python
+// function or class body, if any.
+func extractPythonDocstring(node *tree_sitter.Node, code []byte) string {
+	body := node.ChildByFieldName("body")
+	if body == nil || body.NamedChildCount() == 0 {
+		return ""
+	}
+	first := body.NamedChild(0)
+	if first == nil || first.Kind() != "expression_statement" || first.NamedChildCount() == 0 {
+		return ""
+	}
+	str := first.NamedChild(0)
+	if str == nil || str.Kind() != "string" {
+		return ""
+	}
+	return cleanComment(str.Utf8Text(code))
+}
+
+// commentPrefixes are stripped from the start of each comment line, longest
+// first so "///" is not left as "/".
+var commentPrefixes = []string{"///", "//!", "//", "/**", "/*", "#", `"""`, `'''`}
+
+// cleanComment strips comment markers (//, /* */, *, # and Python triple
+// quotes) from each line and trims surrounding blank lines.
+func cleanComment(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		for _, prefix := range commentPrefixes {
+			if strings.HasPrefix(line, prefix) {
+				line = strings.TrimPrefix(line, prefix)
+				break
+			}
+		}
+		for _, suffix := range []string{"*/", `"""`, `'''`} {
+			line = strings.TrimSuffix(line, suffix)
+		}
+		line = strings.TrimPrefix(line, "*")
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
 // extractName attempts to pull a human-readable name from the AST node.
 // Different languages store the name in different child fields.
 func extractName(node *tree_sitter.Node, code []byte, language, kind string) string {
@@ -239,7 +592,7 @@ func extractName(node *tree_sitter.Node, code []byte, language, kind string) str
 	}
 
 	// For export_statement (JS/TS): look inside for declaration name.
-	if kind == "module" && (node.Kind() == "export_statement") {
+	if (kind == "module" || kind == "component") && node.Kind() == "export_statement" {
 		decl := node.ChildByFieldName("declaration")
 		if decl != nil {
 			nameChild := decl.ChildByFieldName("name")
@@ -266,6 +619,17 @@ func extractName(node *tree_sitter.Node, code []byte, language, kind string) str
 		}
 	}
 
+	// Kotlin declarations have no name field: the name is the first
+	// identifier child, after any modifiers or extension receiver.
+	if language == "kotlin" {
+		for i := uint(0); i < node.NamedChildCount(); i++ {
+			child := node.NamedChild(i)
+			if child != nil && (child.Kind() == "type_identifier" || child.Kind() == "simple_identifier") {
+				return child.Utf8Text(code)
+			}
+		}
+	}
+
 	// For Java and Rust impl_item: try the "type" field.
 	if language == "rust" && node.Kind() == "impl_item" {
 		typeChild := node.ChildByFieldName("type")
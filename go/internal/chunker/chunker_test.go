@@ -1,7 +1,14 @@
 package chunker
 
 import (
+	"errors"
+	"math/rand"
+	"strings"
 	"testing"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 func TestChunkGoFile(t *testing.T) {
@@ -161,6 +168,43 @@ func (s *Server) Stop() {
 	assertChunk(t, chunks[2], "Stop", "method", "go", 11, 12)
 }
 
+func TestChunkGoInterface_MethodSet(t *testing.T) {
+	code := []byte(`package store
+
+// Store persists users.
+type Store interface {
+	// Get loads a user by ID.
+	Get(id string) (*User, error)
+	Put(u *User) error
+}
+
+type Closer interface {
+	Close() error
+}
+`)
+
+	chunks, err := ChunkFile("store.go", code, "go", nil)
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("expected 4 chunks (2 interfaces + 2 methods), got %d: %+v", len(chunks), chunks)
+	}
+
+	assertChunk(t, chunks[0], "Store", "interface", "go", 4, 8)
+	assertChunk(t, chunks[1], "Store.Get", "method", "go", 6, 6)
+	assertChunk(t, chunks[2], "Store.Put", "method", "go", 7, 7)
+	// A single-method interface is already one unit.
+	assertChunk(t, chunks[3], "Closer", "interface", "go", 10, 12)
+
+	if chunks[1].Doc != "Get loads a user by ID." {
+		t.Errorf("Store.Get doc = %q", chunks[1].Doc)
+	}
+	if chunks[2].Code != "Put(u *User) error" {
+		t.Errorf("Store.Put code = %q", chunks[2].Code)
+	}
+}
+
 func TestChunkTypeScriptFile(t *testing.T) {
 	code := []byte(`function add(a: number, b: number): number {
   return a + b;
@@ -192,6 +236,34 @@ class Stack<T> {
 	assertChunk(t, chunks[1], "Stack", "class", "typescript", 5, 15)
 }
 
+func TestChunkJavaScriptFile_ReactComponents(t *testing.T) {
+	code := []byte(`const MAX_ITEMS = 10;
+
+const Button = ({ label }) => {
+  return <button>{label}</button>;
+};
+
+export const Card = memo(function Card({ title }) {
+  return <div>{title}</div>;
+});
+
+const formatLabel = (s) => s.trim();
+`)
+
+	chunks, err := ChunkFile("Button.jsx", code, "javascript", nil)
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("expected 4 chunks, got %d: %+v", len(chunks), chunks)
+	}
+
+	assertChunk(t, chunks[0], "MAX_ITEMS", "const", "javascript", 1, 1)
+	assertChunk(t, chunks[1], "Button", "component", "javascript", 3, 5)
+	assertChunk(t, chunks[2], "Card", "component", "javascript", 7, 9)
+	assertChunk(t, chunks[3], "formatLabel", "const", "javascript", 11, 11)
+}
+
 func TestChunkRustFile(t *testing.T) {
 	code := []byte(`struct Point {
     x: f64,
@@ -255,6 +327,90 @@ func TestChunkJavaFile(t *testing.T) {
 	assertChunk(t, chunks[0], "Calculator", "class", "java", 1, 9)
 }
 
+func TestChunkKotlinFile(t *testing.T) {
+	code := []byte(`package app
+
+class Calculator(private val base: Int) {
+    fun add(a: Int): Int {
+        return base + a
+    }
+
+    fun subtract(a: Int): Int {
+        return base - a
+    }
+}
+
+object Registry {
+    val calculators = mutableListOf<Calculator>()
+}
+
+interface Shape {
+    fun area(): Double
+}
+
+fun String.shout(): String = uppercase()
+`)
+
+	chunks, err := ChunkFile("Calculator.kt", code, "kotlin", nil)
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+
+	if len(chunks) != 4 {
+		t.Fatalf("expected 4 chunks (class, object, interface, function), got %d: %+v", len(chunks), chunks)
+	}
+
+	assertChunk(t, chunks[0], "Calculator", "class", "kotlin", 3, 11)
+	assertChunk(t, chunks[1], "Registry", "class", "kotlin", 13, 15)
+	assertChunk(t, chunks[2], "Shape", "interface", "kotlin", 17, 19)
+	// The name is the function's, not the extension receiver's.
+	assertChunk(t, chunks[3], "shout", "function", "kotlin", 21, 21)
+}
+
+func TestChunkSwiftFile(t *testing.T) {
+	code := []byte(`import Foundation
+
+struct Point {
+    var x: Double
+    var y: Double
+
+    func length() -> Double {
+        return (x * x + y * y).squareRoot()
+    }
+
+    func scaled(by factor: Double) -> Point {
+        return Point(x: x * factor, y: y * factor)
+    }
+}
+
+class Canvas {
+    var points: [Point] = []
+}
+
+protocol Drawable {
+    func draw(on canvas: Canvas)
+}
+
+func origin() -> Point {
+    return Point(x: 0, y: 0)
+}
+`)
+
+	chunks, err := ChunkFile("Point.swift", code, "swift", nil)
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+
+	if len(chunks) != 4 {
+		t.Fatalf("expected 4 chunks (struct, class, protocol, function), got %d: %+v", len(chunks), chunks)
+	}
+
+	assertChunk(t, chunks[0], "Point", "type", "swift", 3, 14)
+	assertChunk(t, chunks[1], "Canvas", "class", "swift", 16, 18)
+	assertChunk(t, chunks[2], "Drawable", "interface", "swift", 20, 22)
+	assertChunk(t, chunks[3], "origin", "function", "swift", 24, 26)
+}
+
 func TestChunkCodeContent(t *testing.T) {
 	code := []byte(`package main
 
@@ -408,3 +564,271 @@ func assertChunk(t *testing.T, c Chunk, name, kind, language string, startLine,
 		t.Errorf("expected end line %d, got %d", endLine, c.EndLine)
 	}
 }
+
+func TestChunkGoFile_DocComment(t *testing.T) {
+	code := []byte(`package main
+
+// Unrelated comment separated by a blank line.
+
+// Hello greets the user.
+// It prints to stdout.
+func Hello() {}
+
+/* Config holds server settings. */
+type Config struct{}
+
+func Undocumented() {}
+`)
+
+	chunks, err := ChunkFile("main.go", code, "go", nil)
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d", len(chunks))
+	}
+
+	if want := "Hello greets the user.\nIt prints to stdout."; chunks[0].Doc != want {
+		t.Errorf("Hello doc = %q, want %q", chunks[0].Doc, want)
+	}
+	if want := "Config holds server settings."; chunks[1].Doc != want {
+		t.Errorf("Config doc = %q, want %q", chunks[1].Doc, want)
+	}
+	if chunks[2].Doc != "" {
+		t.Errorf("Undocumented doc = %q, want empty", chunks[2].Doc)
+	}
+}
+
+func TestChunkPythonFile_Docstring(t *testing.T) {
+	code := []byte(`# Module-level helper.
+def add(a, b):
+    """Return the sum of a and b."""
+    return a + b
+
+class Store:
+    """
+    Persists records to disk.
+    """
+    pass
+`)
+
+	chunks, err := ChunkFile("util.py", code, "python", nil)
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d", len(chunks))
+	}
+
+	if want := "Return the sum of a and b."; chunks[0].Doc != want {
+		t.Errorf("add doc = %q, want %q", chunks[0].Doc, want)
+	}
+	if want := "Persists records to disk."; chunks[1].Doc != want {
+		t.Errorf("Store doc = %q, want %q", chunks[1].Doc, want)
+	}
+}
+
+func TestChunkJavaScriptFile_JSDoc(t *testing.T) {
+	code := []byte(`/**
+ * Formats a user's display name.
+ * @param {string} name
+ */
+function formatName(name) {
+  return name.trim();
+}
+`)
+
+	chunks, err := ChunkFile("fmt.js", code, "javascript", nil)
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(chunks))
+	}
+	if want := "Formats a user's display name.\n@param {string} name"; chunks[0].Doc != want {
+		t.Errorf("formatName doc = %q, want %q", chunks[0].Doc, want)
+	}
+}
+
+// smallConstsJS is a JS file dominated by one-line constants with a single
+// real function in the middle.
+var smallConstsJS = []byte(`const A = 1;
+const B = 2;
+const C = 3;
+
+function compute(x) {
+  const y = x * A;
+  return y + B + C;
+}
+
+const D = 4;
+`)
+
+func TestChunkFile_KindsWhitelist(t *testing.T) {
+	chunks, err := ChunkFile("consts.js", smallConstsJS, "javascript", &ChunkOptions{
+		Kinds: []string{"function", "class", "method"},
+	})
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk with consts filtered out, got %d", len(chunks))
+	}
+	assertChunk(t, chunks[0], "compute", "function", "javascript", 5, 8)
+}
+
+func TestChunkFile_KindsWhitelistRemovesEverything(t *testing.T) {
+	code := []byte("const A = 1;\nconst B = 2;\n")
+	chunks, err := ChunkFile("consts.js", code, "javascript", &ChunkOptions{Kinds: []string{"function"}})
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 0 {
+		t.Errorf("expected no chunks (not a whole-file fallback), got %d", len(chunks))
+	}
+}
+
+func TestChunkFile_ImportsOnlyGoFile(t *testing.T) {
+	code := []byte("package gen\n\nimport _ \"embed\"\n\n//go:generate stringer -type=Kind\n")
+
+	chunks, err := ChunkFile("gen.go", code, "go", &ChunkOptions{})
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 whole-file chunk, got %d", len(chunks))
+	}
+	assertChunk(t, chunks[0], "gen.go", "module", "go", 1, 6)
+
+	chunks, err = ChunkFile("gen.go", code, "go", &ChunkOptions{NoFallback: true})
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 0 {
+		t.Errorf("NoFallback: expected no chunks, got %d", len(chunks))
+	}
+}
+
+func TestChunkFile_MinLinesMergesSmallDeclarations(t *testing.T) {
+	chunks, err := ChunkFile("consts.js", smallConstsJS, "javascript", &ChunkOptions{MinLines: 3})
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	// A, B and C merge into one 3-line chunk; the function is kept; the
+	// lone trailing D is below the threshold and dropped.
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, got %d: %+v", len(chunks), chunks)
+	}
+	assertChunk(t, chunks[0], "A, B, C", "const", "javascript", 1, 3)
+	if chunks[0].Code != "const A = 1;\nconst B = 2;\nconst C = 3;" {
+		t.Errorf("merged code = %q", chunks[0].Code)
+	}
+	assertChunk(t, chunks[1], "compute", "function", "javascript", 5, 8)
+}
+
+func TestChunkFile_DefaultOptionsKeepConsts(t *testing.T) {
+	chunks, err := ChunkFile("consts.js", smallConstsJS, "javascript", &ChunkOptions{})
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 5 {
+		t.Errorf("expected 5 chunks with no filters, got %d", len(chunks))
+	}
+}
+
+func TestChunkFile_UTF16LE(t *testing.T) {
+	src := "package main\n\n// Grüße returns a greeting.\nfunc Grüße() string {\n\treturn \"hallo\"\n}\n"
+	code := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(src)) {
+		code = append(code, byte(u), byte(u>>8))
+	}
+
+	chunks, err := ChunkFile("wide.go", code, "go", nil)
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(chunks))
+	}
+	assertChunk(t, chunks[0], "Grüße", "function", "go", 4, 6)
+	assertUTF8Chunks(t, chunks, src)
+}
+
+func TestChunkFile_Latin1(t *testing.T) {
+	code := []byte("def caf\xe9():\n    return 'cr\xe8me'\n")
+
+	chunks, err := ChunkFile("legacy.py", code, "python", nil)
+	if err != nil {
+		t.Fatalf("ChunkFile returned error: %v", err)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(chunks))
+	}
+	assertChunk(t, chunks[0], "café", "function", "python", 1, 2)
+	assertUTF8Chunks(t, chunks, "def café():\n    return 'crème'\n")
+}
+
+func TestChunkFile_RecoversFromParserPanic(t *testing.T) {
+	orig := parseCode
+	t.Cleanup(func() { parseCode = orig })
+	parseCode = func(*tree_sitter.Parser, []byte) *tree_sitter.Tree {
+		var nodes []int
+		_ = nodes[3] // index out of range, as a broken binding might
+		return nil
+	}
+
+	code := []byte("package main\n\nfunc Broken() {\n}\n")
+	chunks, err := ChunkFile("broken.go", code, "go", nil)
+	if !errors.Is(err, ErrParsePanic) {
+		t.Fatalf("ChunkFile error = %v, want one wrapping ErrParsePanic", err)
+	}
+	if !strings.Contains(err.Error(), "broken.go") {
+		t.Errorf("error %q does not name the file", err)
+	}
+	if len(chunks) != 1 || chunks[0].Kind != "module" || chunks[0].Code != string(code) {
+		t.Fatalf("expected the whole file as one module chunk, got %+v", chunks)
+	}
+}
+
+func TestChunkFile_GarbageInputDoesNotPanic(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	fragments := []string{"{", "}", "(", ")", "func ", "class ", "def ", "fn ", "=>", "\"", "'", "`", "/*", "*/", "\n", "\x00", "\xff", "<", ">"}
+	for _, lang := range []string{"go", "javascript", "typescript", "tsx", "python", "java", "rust"} {
+		for i := 0; i < 50; i++ {
+			var b strings.Builder
+			for j := rng.Intn(200); j > 0; j-- {
+				if rng.Intn(3) == 0 {
+					b.WriteByte(byte(rng.Intn(256)))
+				} else {
+					b.WriteString(fragments[rng.Intn(len(fragments))])
+				}
+			}
+			code := []byte(b.String())
+			chunks, err := ChunkFile("fuzz."+lang, code, lang, nil)
+			if err != nil && !errors.Is(err, ErrParsePanic) {
+				continue // e.g. undecodable bytes
+			}
+			if len(code) > 0 && len(chunks) == 0 {
+				t.Errorf("%s input %q: no chunks and no error", lang, code)
+			}
+		}
+	}
+}
+
+// assertUTF8Chunks checks that every chunk is valid UTF-8 and lies within
+// the transcoded source.
+func assertUTF8Chunks(t *testing.T, chunks []Chunk, src string) {
+	t.Helper()
+	lines := strings.Count(src, "\n") + 1
+	for _, c := range chunks {
+		if !utf8.ValidString(c.Name) || !utf8.ValidString(c.Code) {
+			t.Errorf("chunk %q is not valid UTF-8: %q", c.Name, c.Code)
+		}
+		if !strings.Contains(src, c.Code) {
+			t.Errorf("chunk %q code is not a slice of the source: %q", c.Name, c.Code)
+		}
+		if c.StartLine < 1 || c.EndLine > lines || c.StartLine > c.EndLine {
+			t.Errorf("chunk %q has bad line range %d-%d", c.Name, c.StartLine, c.EndLine)
+		}
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Version is the semantic version embedded by the build pipeline.
@@ -23,6 +25,7 @@ type Config struct {
 	LLMProvider   string
 	LLMApiKey     string
 	LLMBaseURL    string
+	LLMHeaders    map[string]string // CARTO_LLM_HEADERS — Name=Value pairs added to every LLM request
 	FastMaxTokens int
 	DeepMaxTokens int
 	GitHubToken   string
@@ -39,6 +42,40 @@ type Config struct {
 	AuditLogFile string // CARTO_AUDIT_LOG — file path for structured audit logs
 	// Profile name — selects a named section in the config file.
 	Profile string // CARTO_PROFILE — defaults to "default"
+	// Chunking fields.
+	ChunkKinds    []string // CARTO_CHUNK_KINDS — comma-separated chunk kinds to analyze; empty means all
+	ChunkMinLines int      // CARTO_CHUNK_MIN_LINES — merge or skip declarations shorter than this
+	// Storage fields.
+	MemoriesNamespace string // CARTO_MEMORIES_NAMESPACE — source tag prefix, {namespace}/{project}/...; default "carto"
+	CompressThreshold int    // CARTO_COMPRESS_THRESHOLD — gzip stored layer content longer than this many bytes; 0 disables
+	// History fields.
+	HistorySince      string // CARTO_HISTORY_SINCE — git date limiting history extraction; empty means "6 months ago"
+	HistoryMaxCommits int    // CARTO_HISTORY_MAX_COMMITS — commits fetched per file; 0 means 50
+	// Prompt fields.
+	Instructions  Instructions // CARTO_{ATOM,MODULE,SYNTHESIS}_INSTRUCTIONS — extra guidance for each analysis stage
+	SummaryDetail string       // CARTO_SUMMARY_DETAIL — atom summary length: brief | normal | detailed; default "normal"
+	// LLM concurrency fields.
+	FastConcurrency int // CARTO_FAST_CONCURRENCY — in-flight fast-tier LLM calls; 0 means MaxConcurrent
+	DeepConcurrency int // CARTO_DEEP_CONCURRENCY — in-flight deep-tier LLM calls; 0 means MaxConcurrent
+	// Budget fields.
+	MaxCost float64 // CARTO_MAX_COST — stop an index run once its estimated LLM spend in dollars crosses this; 0 means no cap
+	// Tracing fields.
+	OTLPEndpoint string // CARTO_OTLP_ENDPOINT — OpenTelemetry collector index runs send trace spans to over OTLP/HTTP; empty disables tracing
+	// Query fields.
+	AutoTierMinScore float64 // CARTO_AUTO_TIER_MIN_SCORE — top result score a --tier auto query settles for before escalating; 0 means 0.5
+	// Source fields.
+	SignalCacheTTL string // CARTO_SIGNAL_CACHE_TTL — Go duration index runs reuse fetched source artifacts for; "0" disables; empty means 6h
+	// Secret references (see ResolveSecretRefs).
+	LLMApiKeyRef   string // file: or cmd: reference LLMApiKey was resolved from, if any
+	llmKeyResolved string // the value resolved from LLMApiKeyRef
+}
+
+// Instructions is custom guidance appended to the system prompt of each
+// analysis stage, e.g. "focus on security implications".
+type Instructions struct {
+	Atom      string `json:"atom,omitempty"`
+	Module    string `json:"module,omitempty"`
+	Synthesis string `json:"synthesis,omitempty"`
 }
 
 // ValidationError holds one or more human-readable config problems.
@@ -86,6 +123,35 @@ func (c Config) Validate() error {
 	if c.MaxConcurrent < 1 {
 		errs = append(errs, fmt.Sprintf("max_concurrent must be ≥ 1, got %d", c.MaxConcurrent))
 	}
+	// Per-tier limits are optional: 0 falls back to MaxConcurrent.
+	if c.FastConcurrency < 0 {
+		errs = append(errs, fmt.Sprintf("fast_concurrency must be ≥ 0, got %d", c.FastConcurrency))
+	}
+	if c.DeepConcurrency < 0 {
+		errs = append(errs, fmt.Sprintf("deep_concurrency must be ≥ 0, got %d", c.DeepConcurrency))
+	}
+	if c.MaxCost < 0 {
+		errs = append(errs, fmt.Sprintf("max_cost must be ≥ 0, got %g", c.MaxCost))
+	}
+	if c.AutoTierMinScore < 0 {
+		errs = append(errs, fmt.Sprintf("auto_tier_min_score must be ≥ 0, got %g", c.AutoTierMinScore))
+	}
+	if c.OTLPEndpoint != "" && !strings.HasPrefix(c.OTLPEndpoint, "http://") && !strings.HasPrefix(c.OTLPEndpoint, "https://") {
+		errs = append(errs, "otlp_endpoint must start with http:// or https://")
+	}
+	if c.SignalCacheTTL != "" {
+		if d, err := time.ParseDuration(c.SignalCacheTTL); err != nil || d < 0 {
+			errs = append(errs, fmt.Sprintf("signal_cache_ttl must be a duration like \"6h\", got %q", c.SignalCacheTTL))
+		}
+	}
+
+	// SummaryDetail must be a known level.
+	switch c.SummaryDetail {
+	case "brief", "normal", "detailed", "":
+		// acceptable
+	default:
+		errs = append(errs, fmt.Sprintf("unknown summary_detail %q (expected brief|normal|detailed)", c.SummaryDetail))
+	}
 
 	if len(errs) > 0 {
 		return &ValidationError{Fields: errs}
@@ -113,96 +179,169 @@ func DefaultConfigFilePath() string {
 
 // persistedConfig is the JSON shape written to the config file.
 type persistedConfig struct {
-	MemoriesURL   string `json:"memories_url,omitempty"`
-	MemoriesKey   string `json:"memories_key,omitempty"`
-	AnthropicKey  string `json:"anthropic_key,omitempty"`
-	FastModel     string `json:"fast_model,omitempty"`
-	DeepModel     string `json:"deep_model,omitempty"`
-	MaxConcurrent int    `json:"max_concurrent,omitempty"`
-	FastMaxTokens int    `json:"fast_max_tokens,omitempty"`
-	DeepMaxTokens int    `json:"deep_max_tokens,omitempty"`
-	LLMProvider   string `json:"llm_provider,omitempty"`
-	LLMApiKey     string `json:"llm_api_key,omitempty"`
-	LLMBaseURL    string `json:"llm_base_url,omitempty"`
-	GitHubToken   string `json:"github_token,omitempty"`
-	JiraToken     string `json:"jira_token,omitempty"`
-	JiraEmail     string `json:"jira_email,omitempty"`
-	JiraBaseURL   string `json:"jira_base_url,omitempty"`
-	LinearToken   string `json:"linear_token,omitempty"`
-	NotionToken   string `json:"notion_token,omitempty"`
-	SlackToken    string `json:"slack_token,omitempty"`
+	MemoriesURL       string        `json:"memories_url,omitempty"`
+	MemoriesKey       string        `json:"memories_key,omitempty"`
+	AnthropicKey      string        `json:"anthropic_key,omitempty"`
+	FastModel         string        `json:"fast_model,omitempty"`
+	DeepModel         string        `json:"deep_model,omitempty"`
+	MaxConcurrent     int           `json:"max_concurrent,omitempty"`
+	FastConcurrency   int           `json:"fast_concurrency,omitempty"`
+	DeepConcurrency   int           `json:"deep_concurrency,omitempty"`
+	MaxCost           float64       `json:"max_cost,omitempty"`
+	OTLPEndpoint      string        `json:"otlp_endpoint,omitempty"`
+	SignalCacheTTL    string        `json:"signal_cache_ttl,omitempty"`
+	AutoTierMinScore  float64       `json:"auto_tier_min_score,omitempty"`
+	FastMaxTokens     int           `json:"fast_max_tokens,omitempty"`
+	DeepMaxTokens     int           `json:"deep_max_tokens,omitempty"`
+	LLMProvider       string        `json:"llm_provider,omitempty"`
+	LLMApiKey         string        `json:"llm_api_key,omitempty"`
+	LLMBaseURL        string        `json:"llm_base_url,omitempty"`
+	GitHubToken       string        `json:"github_token,omitempty"`
+	JiraToken         string        `json:"jira_token,omitempty"`
+	JiraEmail         string        `json:"jira_email,omitempty"`
+	JiraBaseURL       string        `json:"jira_base_url,omitempty"`
+	LinearToken       string        `json:"linear_token,omitempty"`
+	NotionToken       string        `json:"notion_token,omitempty"`
+	SlackToken        string        `json:"slack_token,omitempty"`
+	ChunkKinds        []string      `json:"chunk_kinds,omitempty"`
+	ChunkMinLines     int           `json:"chunk_min_lines,omitempty"`
+	MemoriesNamespace string        `json:"memories_namespace,omitempty"`
+	HistorySince      string        `json:"history_since,omitempty"`
+	HistoryMaxCommits int           `json:"history_max_commits,omitempty"`
+	Instructions      *Instructions `json:"instructions,omitempty"`
+	SummaryDetail     string        `json:"summary_detail,omitempty"`
+
+	// Extra LLM request headers; values are often proxy credentials.
+	LLMHeaders map[string]string `json:"llm_headers,omitempty"`
 }
 
-// ConfigPath is the file path where UI settings are persisted.
-// It defaults to ".carto-server.json" in the projects directory.
+// ConfigPath is the file path where settings are persisted. It is set by
+// the --config flag, or by `carto serve` to ".carto-server.json" in the
+// projects directory. When empty, ActiveConfigPath picks the default.
 var ConfigPath string
 
+// ActiveConfigPath returns the config file Load and Save use, in order of
+// precedence: ConfigPath (the --config flag), the CARTO_CONFIG environment
+// variable, then the XDG default from DefaultConfigFilePath.
+func ActiveConfigPath() string {
+	if ConfigPath != "" {
+		return ConfigPath
+	}
+	if p := os.Getenv("CARTO_CONFIG"); p != "" {
+		return p
+	}
+	return DefaultConfigFilePath()
+}
+
 func Load() Config {
 	cfg := Config{
-		MemoriesURL:   envOr("MEMORIES_URL", "http://localhost:8900"),
-		MemoriesKey:   os.Getenv("MEMORIES_API_KEY"),
-		AnthropicKey:  os.Getenv("ANTHROPIC_API_KEY"),
-		FastModel:     envOr("CARTO_FAST_MODEL", "claude-haiku-4-5-20251001"),
-		DeepModel:     envOr("CARTO_DEEP_MODEL", "claude-opus-4-6"),
-		MaxConcurrent: envOrInt("CARTO_MAX_CONCURRENT", 10),
-		FastMaxTokens: envOrInt("CARTO_FAST_MAX_TOKENS", 4096),
-		DeepMaxTokens: envOrInt("CARTO_DEEP_MAX_TOKENS", 8192),
-		LLMProvider:   envOr("LLM_PROVIDER", "anthropic"),
-		LLMApiKey:     os.Getenv("LLM_API_KEY"),
-		LLMBaseURL:    os.Getenv("LLM_BASE_URL"),
-		GitHubToken:   os.Getenv("GITHUB_TOKEN"),
-		JiraToken:     os.Getenv("JIRA_TOKEN"),
-		JiraEmail:     os.Getenv("JIRA_EMAIL"),
-		JiraBaseURL:   os.Getenv("JIRA_BASE_URL"),
-		LinearToken:   os.Getenv("LINEAR_TOKEN"),
-		NotionToken:   os.Getenv("NOTION_TOKEN"),
-		SlackToken:    os.Getenv("SLACK_TOKEN"),
-		ServerToken:   os.Getenv("CARTO_SERVER_TOKEN"),
-		CORSOrigins:   os.Getenv("CARTO_CORS_ORIGINS"),
-		AuditLogFile:  os.Getenv("CARTO_AUDIT_LOG"),
-		Profile:       envOr("CARTO_PROFILE", "default"),
-	}
-
-	// Overlay persisted settings (only non-empty values override).
-	if ConfigPath != "" {
-		if saved, err := loadPersistedConfig(ConfigPath); err == nil {
-			mergeConfig(&cfg, saved)
-		}
+		MemoriesURL:       envOr("MEMORIES_URL", "http://localhost:8900"),
+		MemoriesKey:       os.Getenv("MEMORIES_API_KEY"),
+		AnthropicKey:      os.Getenv("ANTHROPIC_API_KEY"),
+		FastModel:         envOr("CARTO_FAST_MODEL", "claude-haiku-4-5-20251001"),
+		DeepModel:         envOr("CARTO_DEEP_MODEL", "claude-opus-4-6"),
+		MaxConcurrent:     envOrInt("CARTO_MAX_CONCURRENT", 10),
+		FastConcurrency:   envOrInt("CARTO_FAST_CONCURRENCY", 0),
+		DeepConcurrency:   envOrInt("CARTO_DEEP_CONCURRENCY", 0),
+		MaxCost:           envOrFloat("CARTO_MAX_COST", 0),
+		OTLPEndpoint:      os.Getenv("CARTO_OTLP_ENDPOINT"),
+		SignalCacheTTL:    os.Getenv("CARTO_SIGNAL_CACHE_TTL"),
+		AutoTierMinScore:  envOrFloat("CARTO_AUTO_TIER_MIN_SCORE", 0),
+		FastMaxTokens:     envOrInt("CARTO_FAST_MAX_TOKENS", 4096),
+		DeepMaxTokens:     envOrInt("CARTO_DEEP_MAX_TOKENS", 8192),
+		LLMProvider:       envOr("LLM_PROVIDER", "anthropic"),
+		LLMApiKey:         os.Getenv("LLM_API_KEY"),
+		LLMBaseURL:        os.Getenv("LLM_BASE_URL"),
+		LLMHeaders:        envHeaders("CARTO_LLM_HEADERS"),
+		GitHubToken:       os.Getenv("GITHUB_TOKEN"),
+		JiraToken:         os.Getenv("JIRA_TOKEN"),
+		JiraEmail:         os.Getenv("JIRA_EMAIL"),
+		JiraBaseURL:       os.Getenv("JIRA_BASE_URL"),
+		LinearToken:       os.Getenv("LINEAR_TOKEN"),
+		NotionToken:       os.Getenv("NOTION_TOKEN"),
+		SlackToken:        os.Getenv("SLACK_TOKEN"),
+		ServerToken:       os.Getenv("CARTO_SERVER_TOKEN"),
+		CORSOrigins:       os.Getenv("CARTO_CORS_ORIGINS"),
+		AuditLogFile:      os.Getenv("CARTO_AUDIT_LOG"),
+		Profile:           envOr("CARTO_PROFILE", DefaultProfile),
+		ChunkKinds:        envList("CARTO_CHUNK_KINDS"),
+		ChunkMinLines:     envOrInt("CARTO_CHUNK_MIN_LINES", 0),
+		MemoriesNamespace: envOr("CARTO_MEMORIES_NAMESPACE", "carto"),
+		CompressThreshold: envOrInt("CARTO_COMPRESS_THRESHOLD", 0),
+		HistorySince:      os.Getenv("CARTO_HISTORY_SINCE"),
+		HistoryMaxCommits: envOrInt("CARTO_HISTORY_MAX_COMMITS", 0),
+		Instructions: Instructions{
+			Atom:      os.Getenv("CARTO_ATOM_INSTRUCTIONS"),
+			Module:    os.Getenv("CARTO_MODULE_INSTRUCTIONS"),
+			Synthesis: os.Getenv("CARTO_SYNTHESIS_INSTRUCTIONS"),
+		},
+		SummaryDetail: envOr("CARTO_SUMMARY_DETAIL", "normal"),
 	}
 
+	// Overlay persisted settings (only non-empty values override). An
+	// active profile replaces the flat config file entirely.
+	if name := ActiveProfile(); name != "" {
+		cfg.Profile = name
+	}
+	if saved, err := loadPersistedConfig(EffectiveConfigPath()); err == nil {
+		mergeConfig(&cfg, saved)
+	}
+	cfg.ResolveSecretRefs()
+
 	return cfg
 }
 
-// Save writes the current config to the persisted config file.
+// Save writes the current config to EffectiveConfigPath, creating its
+// directory if needed.
 func Save(cfg Config) error {
-	if ConfigPath == "" {
-		return nil
-	}
+	return writePersistedConfig(EffectiveConfigPath(), cfg)
+}
+
+func writePersistedConfig(path string, cfg Config) error {
 	p := persistedConfig{
-		MemoriesURL:   cfg.MemoriesURL,
-		MemoriesKey:   cfg.MemoriesKey,
-		AnthropicKey:  cfg.AnthropicKey,
-		FastModel:     cfg.FastModel,
-		DeepModel:     cfg.DeepModel,
-		MaxConcurrent: cfg.MaxConcurrent,
-		FastMaxTokens: cfg.FastMaxTokens,
-		DeepMaxTokens: cfg.DeepMaxTokens,
-		LLMProvider:   cfg.LLMProvider,
-		LLMApiKey:     cfg.LLMApiKey,
-		LLMBaseURL:    cfg.LLMBaseURL,
-		GitHubToken:   cfg.GitHubToken,
-		JiraToken:     cfg.JiraToken,
-		JiraEmail:     cfg.JiraEmail,
-		JiraBaseURL:   cfg.JiraBaseURL,
-		LinearToken:   cfg.LinearToken,
-		NotionToken:   cfg.NotionToken,
-		SlackToken:    cfg.SlackToken,
+		MemoriesURL:       cfg.MemoriesURL,
+		MemoriesKey:       cfg.MemoriesKey,
+		AnthropicKey:      cfg.AnthropicKey,
+		FastModel:         cfg.FastModel,
+		DeepModel:         cfg.DeepModel,
+		MaxConcurrent:     cfg.MaxConcurrent,
+		FastConcurrency:   cfg.FastConcurrency,
+		DeepConcurrency:   cfg.DeepConcurrency,
+		MaxCost:           cfg.MaxCost,
+		OTLPEndpoint:      cfg.OTLPEndpoint,
+		SignalCacheTTL:    cfg.SignalCacheTTL,
+		AutoTierMinScore:  cfg.AutoTierMinScore,
+		FastMaxTokens:     cfg.FastMaxTokens,
+		DeepMaxTokens:     cfg.DeepMaxTokens,
+		LLMProvider:       cfg.LLMProvider,
+		LLMApiKey:         cfg.persistedLLMKey(),
+		LLMBaseURL:        cfg.LLMBaseURL,
+		LLMHeaders:        cfg.LLMHeaders,
+		GitHubToken:       cfg.GitHubToken,
+		JiraToken:         cfg.JiraToken,
+		JiraEmail:         cfg.JiraEmail,
+		JiraBaseURL:       cfg.JiraBaseURL,
+		LinearToken:       cfg.LinearToken,
+		NotionToken:       cfg.NotionToken,
+		SlackToken:        cfg.SlackToken,
+		ChunkKinds:        cfg.ChunkKinds,
+		ChunkMinLines:     cfg.ChunkMinLines,
+		MemoriesNamespace: cfg.MemoriesNamespace,
+		HistorySince:      cfg.HistorySince,
+		HistoryMaxCommits: cfg.HistoryMaxCommits,
+		SummaryDetail:     cfg.SummaryDetail,
+	}
+	if cfg.Instructions != (Instructions{}) {
+		p.Instructions = &cfg.Instructions
 	}
 	data, err := json.MarshalIndent(p, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(ConfigPath, data, 0600)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
 }
 
 func loadPersistedConfig(path string) (persistedConfig, error) {
@@ -234,6 +373,24 @@ func mergeConfig(cfg *Config, p persistedConfig) {
 	if p.MaxConcurrent != 0 {
 		cfg.MaxConcurrent = p.MaxConcurrent
 	}
+	if p.FastConcurrency != 0 {
+		cfg.FastConcurrency = p.FastConcurrency
+	}
+	if p.DeepConcurrency != 0 {
+		cfg.DeepConcurrency = p.DeepConcurrency
+	}
+	if p.MaxCost != 0 {
+		cfg.MaxCost = p.MaxCost
+	}
+	if p.OTLPEndpoint != "" {
+		cfg.OTLPEndpoint = p.OTLPEndpoint
+	}
+	if p.SignalCacheTTL != "" {
+		cfg.SignalCacheTTL = p.SignalCacheTTL
+	}
+	if p.AutoTierMinScore != 0 {
+		cfg.AutoTierMinScore = p.AutoTierMinScore
+	}
 	if p.FastMaxTokens != 0 {
 		cfg.FastMaxTokens = p.FastMaxTokens
 	}
@@ -249,6 +406,9 @@ func mergeConfig(cfg *Config, p persistedConfig) {
 	if p.LLMBaseURL != "" {
 		cfg.LLMBaseURL = p.LLMBaseURL
 	}
+	if len(p.LLMHeaders) > 0 {
+		cfg.LLMHeaders = p.LLMHeaders
+	}
 	if p.GitHubToken != "" {
 		cfg.GitHubToken = p.GitHubToken
 	}
@@ -270,6 +430,35 @@ func mergeConfig(cfg *Config, p persistedConfig) {
 	if p.SlackToken != "" {
 		cfg.SlackToken = p.SlackToken
 	}
+	if len(p.ChunkKinds) > 0 {
+		cfg.ChunkKinds = p.ChunkKinds
+	}
+	if p.ChunkMinLines != 0 {
+		cfg.ChunkMinLines = p.ChunkMinLines
+	}
+	if p.MemoriesNamespace != "" {
+		cfg.MemoriesNamespace = p.MemoriesNamespace
+	}
+	if p.HistorySince != "" {
+		cfg.HistorySince = p.HistorySince
+	}
+	if p.HistoryMaxCommits != 0 {
+		cfg.HistoryMaxCommits = p.HistoryMaxCommits
+	}
+	if p.Instructions != nil {
+		if p.Instructions.Atom != "" {
+			cfg.Instructions.Atom = p.Instructions.Atom
+		}
+		if p.Instructions.Module != "" {
+			cfg.Instructions.Module = p.Instructions.Module
+		}
+		if p.Instructions.Synthesis != "" {
+			cfg.Instructions.Synthesis = p.Instructions.Synthesis
+		}
+	}
+	if p.SummaryDetail != "" {
+		cfg.SummaryDetail = p.SummaryDetail
+	}
 }
 
 // IsDocker returns true when running inside a Docker container.
@@ -313,7 +502,7 @@ func MaskSecret(s string) string {
 func (c Config) Redacted() Config {
 	r := c
 	r.AnthropicKey = MaskSecret(c.AnthropicKey)
-	r.LLMApiKey = MaskSecret(c.LLMApiKey)
+	r.LLMApiKey = c.DisplayLLMKey()
 	r.MemoriesKey = MaskSecret(c.MemoriesKey)
 	r.GitHubToken = MaskSecret(c.GitHubToken)
 	r.JiraToken = MaskSecret(c.JiraToken)
@@ -321,9 +510,23 @@ func (c Config) Redacted() Config {
 	r.NotionToken = MaskSecret(c.NotionToken)
 	r.SlackToken = MaskSecret(c.SlackToken)
 	r.ServerToken = MaskSecret(c.ServerToken)
+	r.LLMHeaders = MaskHeaders(c.LLMHeaders)
 	return r
 }
 
+// MaskHeaders returns a copy of headers with every value masked, since
+// extra LLM headers often carry proxy credentials.
+func MaskHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(headers))
+	for k, v := range headers {
+		masked[k] = MaskSecret(v)
+	}
+	return masked
+}
+
 // EffectiveAPIKey returns the API key that will actually be used for LLM calls.
 // LLMApiKey takes priority over the Anthropic-specific AnthropicKey.
 func (c Config) EffectiveAPIKey() string {
@@ -333,6 +536,23 @@ func (c Config) EffectiveAPIKey() string {
 	return c.AnthropicKey
 }
 
+// DefaultSignalCacheTTL is how long fetched source artifacts are reused
+// when SignalCacheTTL is empty.
+const DefaultSignalCacheTTL = 6 * time.Hour
+
+// SignalCacheDuration returns SignalCacheTTL as a duration, or
+// DefaultSignalCacheTTL when it is empty or invalid. Zero disables caching.
+func (c Config) SignalCacheDuration() time.Duration {
+	if c.SignalCacheTTL == "" {
+		return DefaultSignalCacheTTL
+	}
+	d, err := time.ParseDuration(c.SignalCacheTTL)
+	if err != nil || d < 0 {
+		return DefaultSignalCacheTTL
+	}
+	return d
+}
+
 func IsOAuthToken(key string) bool {
 	return len(key) > 0 && strings.HasPrefix(key, "sk-ant-oat01-")
 }
@@ -352,3 +572,73 @@ func envOrInt(key string, fallback int) int {
 	}
 	return fallback
 }
+
+func envOrFloat(key string, fallback float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+// envList splits a comma-separated environment variable, dropping blanks.
+func envList(key string) []string {
+	return SplitList(os.Getenv(key))
+}
+
+// envHeaders parses a Name=Value list from an environment variable,
+// ignoring it if malformed.
+func envHeaders(key string) map[string]string {
+	headers, err := ParseHeaders(os.Getenv(key))
+	if err != nil {
+		return nil
+	}
+	return headers
+}
+
+// ParseHeaders parses comma-separated Name=Value pairs, e.g.
+// "X-Corp-Auth=abc,User-Agent=corp/1.0", trimming spaces around names and
+// values. It returns nil for an empty string.
+func ParseHeaders(s string) (map[string]string, error) {
+	var headers map[string]string
+	for _, pair := range SplitList(s) {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q (expected Name=Value)", pair)
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// FormatHeaders renders headers as the sorted Name=Value list ParseHeaders
+// accepts.
+func FormatHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, k := range names {
+		pairs[i] = k + "=" + headers[k]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// SplitList splits a comma-separated list, trimming spaces and dropping
+// empty entries. It returns nil for an empty string.
+func SplitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
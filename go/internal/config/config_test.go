@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -234,3 +236,145 @@ func TestLoadConfig_CORSOriginsFromEnv(t *testing.T) {
 		t.Errorf("expected CORSOrigins from env, got %q", cfg.CORSOrigins)
 	}
 }
+
+func TestActiveConfigPath_Precedence(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("CARTO_CONFIG", "")
+	orig := ConfigPath
+	t.Cleanup(func() { ConfigPath = orig })
+	ConfigPath = ""
+
+	if got, want := ActiveConfigPath(), filepath.Join(xdg, "carto", "config.json"); got != want {
+		t.Errorf("XDG default: got %q, want %q", got, want)
+	}
+
+	t.Setenv("CARTO_CONFIG", "/env/carto.json")
+	if got := ActiveConfigPath(); got != "/env/carto.json" {
+		t.Errorf("env: got %q, want /env/carto.json", got)
+	}
+
+	ConfigPath = "/flag/carto.json"
+	if got := ActiveConfigPath(); got != "/flag/carto.json" {
+		t.Errorf("flag: got %q, want /flag/carto.json", got)
+	}
+}
+
+func TestSaveLoad_DefaultsToXDG(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("CARTO_CONFIG", "")
+	orig := ConfigPath
+	t.Cleanup(func() { ConfigPath = orig })
+	ConfigPath = ""
+
+	cfg := Load()
+	cfg.FastModel = "xdg-model"
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(xdg, "carto", "config.json")); err != nil {
+		t.Fatalf("expected config under XDG_CONFIG_HOME: %v", err)
+	}
+	if got := Load().FastModel; got != "xdg-model" {
+		t.Errorf("FastModel = %q, want xdg-model", got)
+	}
+}
+
+func TestSaveLoad_Instructions(t *testing.T) {
+	orig := ConfigPath
+	t.Cleanup(func() { ConfigPath = orig })
+	ConfigPath = filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("CARTO_ATOM_INSTRUCTIONS", "from env")
+	t.Setenv("CARTO_MODULE_INSTRUCTIONS", "")
+	t.Setenv("CARTO_SYNTHESIS_INSTRUCTIONS", "")
+
+	cfg := Load()
+	if cfg.Instructions.Atom != "from env" {
+		t.Errorf("Atom = %q, want the env value", cfg.Instructions.Atom)
+	}
+	cfg.Instructions = Instructions{Module: "focus on the public API"}
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got := Load().Instructions
+	want := Instructions{Atom: "from env", Module: "focus on the public API"}
+	if got != want {
+		t.Errorf("Instructions = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got, err := ParseHeaders(" X-Corp-Auth = abc , User-Agent=corp/1.0=beta ")
+	if err != nil {
+		t.Fatalf("ParseHeaders: %v", err)
+	}
+	want := map[string]string{"X-Corp-Auth": "abc", "User-Agent": "corp/1.0=beta"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHeaders = %v, want %v", got, want)
+	}
+	if s := FormatHeaders(got); s != "User-Agent=corp/1.0=beta,X-Corp-Auth=abc" {
+		t.Errorf("FormatHeaders = %q", s)
+	}
+	if _, err := ParseHeaders("X-Corp-Auth"); err == nil {
+		t.Error("expected an error for a header without a value")
+	}
+	if got, err := ParseHeaders(""); got != nil || err != nil {
+		t.Errorf("ParseHeaders(\"\") = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestSaveLoad_LLMHeaders(t *testing.T) {
+	orig := ConfigPath
+	t.Cleanup(func() { ConfigPath = orig })
+	ConfigPath = filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("CARTO_LLM_HEADERS", "X-Corp-Auth=from-env")
+
+	cfg := Load()
+	if cfg.LLMHeaders["X-Corp-Auth"] != "from-env" {
+		t.Errorf("LLMHeaders = %v, want the env value", cfg.LLMHeaders)
+	}
+	cfg.LLMHeaders = map[string]string{"X-Corp-Auth": "saved-secret"}
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := Load().LLMHeaders["X-Corp-Auth"]; got != "saved-secret" {
+		t.Errorf("X-Corp-Auth = %q, want the saved value", got)
+	}
+	if got := cfg.Redacted().LLMHeaders["X-Corp-Auth"]; got == "saved-secret" {
+		t.Error("Redacted() must mask header values")
+	}
+}
+
+func TestLoad_FlagBeatsEnvBeatsXDG(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, model string) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(`{"fast_model": "`+model+`"}`), 0o600)
+		return path
+	}
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	os.MkdirAll(filepath.Join(dir, "carto"), 0o700)
+	write(filepath.Join("carto", "config.json"), "from-xdg")
+	envPath := write("env.json", "from-env")
+	flagPath := write("flag.json", "from-flag")
+	orig := ConfigPath
+	t.Cleanup(func() { ConfigPath = orig })
+
+	ConfigPath = ""
+	t.Setenv("CARTO_CONFIG", "")
+	if got := Load().FastModel; got != "from-xdg" {
+		t.Errorf("XDG only: FastModel = %q", got)
+	}
+
+	t.Setenv("CARTO_CONFIG", envPath)
+	if got := Load().FastModel; got != "from-env" {
+		t.Errorf("env set: FastModel = %q", got)
+	}
+
+	ConfigPath = flagPath
+	if got := Load().FastModel; got != "from-flag" {
+		t.Errorf("flag set: FastModel = %q", got)
+	}
+}
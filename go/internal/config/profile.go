@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile names the flat config file. It is used whenever no named
+// profile is selected, so setups without profiles behave exactly as before.
+const DefaultProfile = "default"
+
+// ProfileName is the profile selected for this invocation by the --profile
+// flag. When empty, ActiveProfile falls back to CARTO_PROFILE and then to
+// the profile chosen with UseProfile.
+var ProfileName string
+
+var profileNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ProfilesDir returns the directory holding named profile snapshots, next
+// to the active config file.
+func ProfilesDir() string {
+	return filepath.Join(filepath.Dir(ActiveConfigPath()), "profiles")
+}
+
+func profilePath(name string) string {
+	return filepath.Join(ProfilesDir(), name+".json")
+}
+
+func activeMarkerPath() string {
+	return filepath.Join(ProfilesDir(), "active")
+}
+
+// ValidateProfileName rejects names that are empty, reserved, or unsafe to
+// use as a file name.
+func ValidateProfileName(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("%q is reserved for the flat config file", DefaultProfile)
+	}
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q (use letters, digits, '.', '_' or '-')", name)
+	}
+	return nil
+}
+
+// ProfileExists reports whether a snapshot named name has been saved.
+func ProfileExists(name string) bool {
+	if ValidateProfileName(name) != nil {
+		return false
+	}
+	_, err := os.Stat(profilePath(name))
+	return err == nil
+}
+
+// SelectedProfile returns the requested profile name, in order of
+// precedence: ProfileName (the --profile flag), CARTO_PROFILE, then the
+// profile chosen with UseProfile. It returns DefaultProfile when none is
+// requested; the name is not checked for existence.
+func SelectedProfile() string {
+	if ProfileName != "" {
+		return ProfileName
+	}
+	if p := os.Getenv("CARTO_PROFILE"); p != "" {
+		return p
+	}
+	if data, err := os.ReadFile(activeMarkerPath()); err == nil {
+		if p := strings.TrimSpace(string(data)); p != "" {
+			return p
+		}
+	}
+	return DefaultProfile
+}
+
+// ActiveProfile returns the selected profile if its snapshot exists, or ""
+// when Load and Save should use the flat config file.
+func ActiveProfile() string {
+	name := SelectedProfile()
+	if !ProfileExists(name) {
+		return ""
+	}
+	return name
+}
+
+// EffectiveConfigPath returns the file Load reads and Save writes: the
+// active profile's snapshot if there is one, otherwise ActiveConfigPath.
+func EffectiveConfigPath() string {
+	if name := ActiveProfile(); name != "" {
+		return profilePath(name)
+	}
+	return ActiveConfigPath()
+}
+
+// ListProfiles returns the names of all saved profiles, sorted.
+func ListProfiles() ([]string, error) {
+	entries, err := os.ReadDir(ProfilesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if ok && !e.IsDir() && ValidateProfileName(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SaveProfile writes cfg as the full snapshot for profile name, replacing
+// any existing snapshot.
+func SaveProfile(name string, cfg Config) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	return writePersistedConfig(profilePath(name), cfg)
+}
+
+// UseProfile makes name the profile selected by default. DefaultProfile
+// switches back to the flat config file.
+func UseProfile(name string) error {
+	if name == DefaultProfile {
+		err := os.Remove(activeMarkerPath())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if !ProfileExists(name) {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	return os.WriteFile(activeMarkerPath(), []byte(name+"\n"), 0o600)
+}
+
+// RemoveProfile deletes the snapshot for name. If it was the profile
+// selected with UseProfile, the flat config file becomes active again.
+func RemoveProfile(name string) error {
+	if !ProfileExists(name) {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	if err := os.Remove(profilePath(name)); err != nil {
+		return err
+	}
+	if data, err := os.ReadFile(activeMarkerPath()); err == nil && strings.TrimSpace(string(data)) == name {
+		return UseProfile(DefaultProfile)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// withProfileDir points the config file at a temp dir and clears any
+// profile selection for the duration of the test.
+func withProfileDir(t *testing.T) {
+	t.Helper()
+	origPath, origProfile := ConfigPath, ProfileName
+	t.Cleanup(func() { ConfigPath, ProfileName = origPath, origProfile })
+	ConfigPath = filepath.Join(t.TempDir(), "config.json")
+	ProfileName = ""
+	t.Setenv("CARTO_PROFILE", "")
+	t.Setenv("LLM_PROVIDER", "")
+	t.Setenv("CARTO_FAST_MODEL", "")
+}
+
+func TestProfiles_FlatConfigWithoutProfiles(t *testing.T) {
+	withProfileDir(t)
+
+	if err := Save(Config{FastModel: "flat-model", MaxConcurrent: 3}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := EffectiveConfigPath(); got != ConfigPath {
+		t.Errorf("EffectiveConfigPath = %q, want the flat file %q", got, ConfigPath)
+	}
+	cfg := Load()
+	if cfg.FastModel != "flat-model" || cfg.Profile != DefaultProfile {
+		t.Errorf("Load = model %q profile %q, want flat-model/default", cfg.FastModel, cfg.Profile)
+	}
+	if names, _ := ListProfiles(); len(names) != 0 {
+		t.Errorf("ListProfiles = %v, want none", names)
+	}
+}
+
+func TestProfiles_AddUseRemove(t *testing.T) {
+	withProfileDir(t)
+	Save(Config{FastModel: "flat-model", MaxConcurrent: 3})
+
+	if err := SaveProfile("work", Config{FastModel: "work-model", LLMProvider: "openai", MaxConcurrent: 5}); err != nil {
+		t.Fatalf("SaveProfile work: %v", err)
+	}
+	if err := SaveProfile("local", Config{FastModel: "llama3", LLMProvider: "ollama", MaxConcurrent: 1}); err != nil {
+		t.Fatalf("SaveProfile local: %v", err)
+	}
+	names, err := ListProfiles()
+	if err != nil || !reflect.DeepEqual(names, []string{"local", "work"}) {
+		t.Fatalf("ListProfiles = %v, %v", names, err)
+	}
+
+	// Saving a profile doesn't switch to it.
+	if got := Load().FastModel; got != "flat-model" {
+		t.Errorf("before use: FastModel = %q, want flat-model", got)
+	}
+
+	if err := UseProfile("work"); err != nil {
+		t.Fatalf("UseProfile: %v", err)
+	}
+	cfg := Load()
+	if cfg.FastModel != "work-model" || cfg.LLMProvider != "openai" || cfg.Profile != "work" {
+		t.Errorf("after use: %+v", cfg)
+	}
+
+	// Save writes to the active profile, leaving the flat file alone.
+	cfg.DeepModel = "work-deep"
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if saved, _ := loadPersistedConfig(ConfigPath); saved.DeepModel != "" {
+		t.Errorf("flat file was modified: %+v", saved)
+	}
+	if got := Load().DeepModel; got != "work-deep" {
+		t.Errorf("profile DeepModel = %q, want work-deep", got)
+	}
+
+	// Removing the active profile falls back to the flat config.
+	if err := RemoveProfile("work"); err != nil {
+		t.Fatalf("RemoveProfile: %v", err)
+	}
+	if cfg := Load(); cfg.FastModel != "flat-model" || cfg.Profile != DefaultProfile {
+		t.Errorf("after remove: model %q profile %q", cfg.FastModel, cfg.Profile)
+	}
+	if err := UseProfile("work"); err == nil {
+		t.Error("UseProfile of a removed profile should fail")
+	}
+}
+
+func TestProfiles_PerInvocationSelection(t *testing.T) {
+	withProfileDir(t)
+	Save(Config{FastModel: "flat-model", MaxConcurrent: 3})
+	SaveProfile("work", Config{FastModel: "work-model", MaxConcurrent: 5})
+	SaveProfile("local", Config{FastModel: "llama3", MaxConcurrent: 1})
+	UseProfile("work")
+
+	t.Setenv("CARTO_PROFILE", "local")
+	if got := Load().FastModel; got != "llama3" {
+		t.Errorf("CARTO_PROFILE=local: FastModel = %q", got)
+	}
+
+	ProfileName = DefaultProfile
+	if got := Load().FastModel; got != "flat-model" {
+		t.Errorf("--profile default: FastModel = %q, want the flat file", got)
+	}
+
+	ProfileName = "work"
+	if got := Load().FastModel; got != "work-model" {
+		t.Errorf("--profile work: FastModel = %q", got)
+	}
+}
+
+func TestValidateProfileName(t *testing.T) {
+	for _, name := range []string{"work", "local-ollama", "team_2", "v1.0"} {
+		if err := ValidateProfileName(name); err != nil {
+			t.Errorf("ValidateProfileName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "default", "../etc", "a/b", ".hidden", "with space"} {
+		if err := ValidateProfileName(name); err == nil {
+			t.Errorf("ValidateProfileName(%q) should fail", name)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfig holds the per-project overrides read from
+// .carto/config.yaml in a project root. Only non-secret settings can be
+// overridden; the file is committed alongside the code, so API keys and
+// tokens are never read from it.
+type ProjectConfig struct {
+	FastModel     string `yaml:"fast_model"`
+	DeepModel     string `yaml:"deep_model"`
+	MaxConcurrent int    `yaml:"max_concurrent"`
+	LLMProvider   string `yaml:"llm_provider"`
+	LLMBaseURL    string `yaml:"llm_base_url"`
+}
+
+// secretKeys are config keys that are ignored (with a warning) when found
+// in a project's .carto/config.yaml.
+var secretKeys = map[string]bool{
+	"anthropic_key": true,
+	"llm_api_key":   true,
+	"memories_key":  true,
+	"github_token":  true,
+	"jira_token":    true,
+	"jira_email":    true,
+	"linear_token":  true,
+	"notion_token":  true,
+	"slack_token":   true,
+	"server_token":  true,
+}
+
+// ProjectConfigPath returns the path of the per-project config file.
+func ProjectConfigPath(rootPath string) string {
+	return filepath.Join(rootPath, ".carto", "config.yaml")
+}
+
+// LoadProjectConfig reads .carto/config.yaml from rootPath. It returns nil
+// (no error) if the file doesn't exist.
+func LoadProjectConfig(rootPath string) (*ProjectConfig, error) {
+	path := ProjectConfigPath(rootPath)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("project config: %w", err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("project config %s: %w", path, err)
+	}
+	var ignored []string
+	for k := range raw {
+		if secretKeys[k] {
+			ignored = append(ignored, k)
+		}
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		log.Printf("config: warning: ignoring secret keys %v in %s — set them in the environment or with 'carto auth set-key'", ignored, path)
+	}
+
+	var pc ProjectConfig
+	if err := yaml.Unmarshal(data, &pc); err != nil {
+		return nil, fmt.Errorf("project config %s: %w", path, err)
+	}
+	return &pc, nil
+}
+
+// Apply returns a copy of cfg with the project's non-empty overrides
+// merged on top. A nil ProjectConfig returns cfg unchanged. The provider and
+// base URL are only taken when cfg holds no LLM API key, so a repo can't
+// redirect the user's key to another host.
+func (pc *ProjectConfig) Apply(cfg Config) Config {
+	if pc == nil {
+		return cfg
+	}
+	if pc.FastModel != "" {
+		cfg.FastModel = pc.FastModel
+	}
+	if pc.DeepModel != "" {
+		cfg.DeepModel = pc.DeepModel
+	}
+	if pc.MaxConcurrent > 0 {
+		cfg.MaxConcurrent = pc.MaxConcurrent
+	}
+	if (pc.LLMProvider != "" || pc.LLMBaseURL != "") && cfg.hasLLMKey() {
+		// The key is the user's, never the repo's, so a repo must not be
+		// able to point the client, and the key, at a host of its choosing.
+		log.Printf("config: warning: ignoring llm_provider and llm_base_url from .carto/config.yaml while an LLM API key is configured")
+		return cfg
+	}
+	if pc.LLMProvider != "" {
+		cfg.LLMProvider = pc.LLMProvider
+	}
+	if pc.LLMBaseURL != "" {
+		cfg.LLMBaseURL = pc.LLMBaseURL
+	}
+	return cfg
+}
+
+// hasLLMKey reports whether cfg carries an API key the LLM client would
+// send with its requests.
+func (c Config) hasLLMKey() bool {
+	return c.AnthropicKey != "" || c.LLMApiKey != "" || c.LLMApiKeyRef != ""
+}
+
+// ForProject loads rootPath's .carto/config.yaml and merges it over cfg.
+// A missing file leaves cfg unchanged.
+func ForProject(cfg Config, rootPath string) (Config, error) {
+	pc, err := LoadProjectConfig(rootPath)
+	if err != nil {
+		return cfg, err
+	}
+	return pc.Apply(cfg), nil
+}
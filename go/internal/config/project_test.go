@@ -0,0 +1,126 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeProjectConfig(t *testing.T, content string) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".carto"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ProjectConfigPath(root), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestForProject_OverridesSelectedFields(t *testing.T) {
+	root := writeProjectConfig(t, `
+deep_model: claude-sonnet-custom
+max_concurrent: 2
+llm_provider: openai
+llm_base_url: http://llm.internal/v1
+`)
+	global := Config{
+		FastModel:     "fast-global",
+		DeepModel:     "deep-global",
+		MaxConcurrent: 10,
+		LLMProvider:   "anthropic",
+		MemoriesURL:   "http://localhost:8900",
+	}
+
+	cfg, err := ForProject(global, root)
+	if err != nil {
+		t.Fatalf("ForProject: %v", err)
+	}
+	if cfg.DeepModel != "claude-sonnet-custom" {
+		t.Errorf("DeepModel = %q, want override", cfg.DeepModel)
+	}
+	if cfg.MaxConcurrent != 2 {
+		t.Errorf("MaxConcurrent = %d, want 2", cfg.MaxConcurrent)
+	}
+	if cfg.LLMProvider != "openai" || cfg.LLMBaseURL != "http://llm.internal/v1" {
+		t.Errorf("provider overrides not applied: %q %q", cfg.LLMProvider, cfg.LLMBaseURL)
+	}
+	if cfg.FastModel != "fast-global" || cfg.MemoriesURL != "http://localhost:8900" {
+		t.Errorf("fields without overrides should keep global values: %+v", cfg)
+	}
+}
+
+func TestForProject_IgnoresSecrets(t *testing.T) {
+	root := writeProjectConfig(t, `
+fast_model: fast-project
+anthropic_key: sk-ant-from-repo
+llm_api_key: from-repo
+memories_key: from-repo
+github_token: from-repo
+memories_url: http://evil.example
+`)
+	global := Config{
+		AnthropicKey: "sk-ant-global",
+		LLMApiKey:    "",
+		MemoriesKey:  "mem-global",
+		GitHubToken:  "gh-global",
+		MemoriesURL:  "http://localhost:8900",
+	}
+
+	cfg, err := ForProject(global, root)
+	if err != nil {
+		t.Fatalf("ForProject: %v", err)
+	}
+	if cfg.FastModel != "fast-project" {
+		t.Errorf("FastModel = %q, want fast-project", cfg.FastModel)
+	}
+	if cfg.AnthropicKey != "sk-ant-global" || cfg.LLMApiKey != "" || cfg.MemoriesKey != "mem-global" || cfg.GitHubToken != "gh-global" {
+		t.Errorf("secrets must not be read from the repo config: %+v", cfg.Redacted())
+	}
+	if cfg.MemoriesURL != "http://localhost:8900" {
+		t.Errorf("memories_url is not overridable, got %q", cfg.MemoriesURL)
+	}
+}
+
+func TestForProject_CannotRedirectLLMKey(t *testing.T) {
+	root := writeProjectConfig(t, `
+deep_model: deep-project
+llm_provider: openai
+llm_base_url: http://attacker.example/v1
+`)
+	for _, global := range []Config{
+		{LLMProvider: "anthropic", AnthropicKey: "sk-ant-global"},
+		{LLMProvider: "openai", LLMApiKey: "sk-global", LLMBaseURL: "https://api.openai.com/v1"},
+		{LLMProvider: "openai", LLMApiKeyRef: "file:/run/secrets/llm", LLMBaseURL: "https://api.openai.com/v1"},
+	} {
+		cfg, err := ForProject(global, root)
+		if err != nil {
+			t.Fatalf("ForProject: %v", err)
+		}
+		if cfg.LLMBaseURL != global.LLMBaseURL || cfg.LLMProvider != global.LLMProvider {
+			t.Errorf("with a key configured, the repo redirected the LLM client to %q %q", cfg.LLMProvider, cfg.LLMBaseURL)
+		}
+		if cfg.DeepModel != "deep-project" {
+			t.Errorf("DeepModel = %q, want the other overrides still applied", cfg.DeepModel)
+		}
+	}
+}
+
+func TestForProject_MissingFile(t *testing.T) {
+	global := Config{DeepModel: "deep-global"}
+	cfg, err := ForProject(global, t.TempDir())
+	if err != nil {
+		t.Fatalf("ForProject: %v", err)
+	}
+	if cfg.DeepModel != "deep-global" {
+		t.Errorf("DeepModel = %q, want unchanged", cfg.DeepModel)
+	}
+}
+
+func TestForProject_InvalidYAML(t *testing.T) {
+	root := writeProjectConfig(t, "deep_model: [unterminated")
+	if _, err := ForProject(Config{}, root); err == nil {
+		t.Error("expected error for malformed config.yaml")
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Secret references let a key be kept out of the config file:
+//
+//	file:/path/to/key          read the key from a file
+//	cmd:my-vault get carto-key run a command and use its stdout
+//
+// The resolved value has surrounding whitespace trimmed.
+const (
+	secretFilePrefix = "file:"
+	secretCmdPrefix  = "cmd:"
+)
+
+// secretCmdTimeout bounds how long a cmd: reference may run.
+const secretCmdTimeout = 30 * time.Second
+
+// IsSecretRef reports whether v is a file: or cmd: secret reference.
+func IsSecretRef(v string) bool {
+	return strings.HasPrefix(v, secretFilePrefix) || strings.HasPrefix(v, secretCmdPrefix)
+}
+
+// ResolveSecret returns the secret a reference points to. Values that are
+// not references are returned unchanged.
+func ResolveSecret(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, secretFilePrefix):
+		path := strings.TrimSpace(strings.TrimPrefix(v, secretFilePrefix))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+
+	case strings.HasPrefix(v, secretCmdPrefix):
+		command := strings.TrimSpace(strings.TrimPrefix(v, secretCmdPrefix))
+		if command == "" {
+			return "", fmt.Errorf("secret command is empty")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), secretCmdTimeout)
+		defer cancel()
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", command)
+		}
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("secret command failed: %w: %s", err, msg)
+			}
+			return "", fmt.Errorf("secret command failed: %w", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	return v, nil
+}
+
+// ResolveSecretRefs replaces a file: or cmd: reference in LLMApiKey with
+// the secret it points to, remembering the reference in LLMApiKeyRef so
+// Save writes the reference back instead of the secret. A reference that
+// fails to resolve leaves LLMApiKey empty and logs a warning.
+func (c *Config) ResolveSecretRefs() {
+	if !IsSecretRef(c.LLMApiKey) {
+		return
+	}
+	ref := c.LLMApiKey
+	secret, err := ResolveSecret(ref)
+	if err != nil {
+		log.Printf("config: warning: llm_api_key %s: %v", ref, err)
+	}
+	c.LLMApiKey = secret
+	c.LLMApiKeyRef = ref
+	c.llmKeyResolved = secret
+}
+
+// LLMKeyRef returns the reference LLMApiKey was resolved from, or "" if the
+// key did not come from one or has since been replaced.
+func (c Config) LLMKeyRef() string {
+	if c.LLMApiKeyRef != "" && c.LLMApiKey == c.llmKeyResolved {
+		return c.LLMApiKeyRef
+	}
+	return ""
+}
+
+// persistedLLMKey returns the llm_api_key value Save should write: the
+// reference when LLMApiKey still holds the secret resolved from it.
+func (c Config) persistedLLMKey() string {
+	if ref := c.LLMKeyRef(); ref != "" {
+		return ref
+	}
+	return c.LLMApiKey
+}
+
+// DisplayLLMKey returns LLMApiKey safe for display: the reference when the
+// key came from one, otherwise the masked key.
+func (c Config) DisplayLLMKey() string {
+	if ref := c.LLMKeyRef(); ref != "" {
+		return ref
+	}
+	return MaskSecret(c.LLMApiKey)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(path, []byte("  sk-from-file\n"), 0o600)
+
+	got, err := ResolveSecret("file:" + path)
+	if err != nil {
+		t.Fatalf("ResolveSecret: %v", err)
+	}
+	if got != "sk-from-file" {
+		t.Errorf("got %q, want sk-from-file", got)
+	}
+
+	if _, err := ResolveSecret("file:" + filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestResolveSecret_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	got, err := ResolveSecret("cmd:echo '  sk-from-cmd  '")
+	if err != nil {
+		t.Fatalf("ResolveSecret: %v", err)
+	}
+	if got != "sk-from-cmd" {
+		t.Errorf("got %q, want sk-from-cmd", got)
+	}
+}
+
+func TestResolveSecret_CommandFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	_, err := ResolveSecret("cmd:echo vault locked >&2; exit 3")
+	if err == nil {
+		t.Fatal("expected error from failing command")
+	}
+	if !strings.Contains(err.Error(), "vault locked") {
+		t.Errorf("error should include stderr, got %v", err)
+	}
+}
+
+func TestResolveSecret_PlainValueUnchanged(t *testing.T) {
+	got, err := ResolveSecret("sk-plain")
+	if err != nil || got != "sk-plain" {
+		t.Errorf("got (%q, %v), want sk-plain", got, err)
+	}
+}
+
+func TestLoadSave_SecretRefNotPersisted(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key")
+	os.WriteFile(keyPath, []byte("sk-secret-value-123456\n"), 0o600)
+
+	cfgPath := filepath.Join(dir, "config.json")
+	os.WriteFile(cfgPath, []byte(`{"llm_api_key": "file:`+keyPath+`"}`), 0o600)
+	orig := ConfigPath
+	t.Cleanup(func() { ConfigPath = orig })
+	ConfigPath = cfgPath
+	t.Setenv("LLM_API_KEY", "")
+
+	cfg := Load()
+	if cfg.LLMApiKey != "sk-secret-value-123456" {
+		t.Fatalf("LLMApiKey = %q, want the resolved secret", cfg.LLMApiKey)
+	}
+	if got := cfg.Redacted().LLMApiKey; got != "file:"+keyPath {
+		t.Errorf("Redacted LLMApiKey = %q, want the reference", got)
+	}
+
+	cfg.FastModel = "other-model"
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, _ := os.ReadFile(cfgPath)
+	if strings.Contains(string(data), "sk-secret-value") {
+		t.Errorf("resolved secret written to config file:\n%s", data)
+	}
+	if !strings.Contains(string(data), "file:"+keyPath) {
+		t.Errorf("reference not preserved in config file:\n%s", data)
+	}
+
+	// Replacing the key persists the new value instead of the reference.
+	cfg.LLMApiKey = "sk-replaced"
+	Save(cfg)
+	if got := Load().LLMApiKey; got != "sk-replaced" {
+		t.Errorf("after replacing key, LLMApiKey = %q", got)
+	}
+}
@@ -0,0 +1,225 @@
+// Package duplicates finds copy-pasted code by fingerprinting chunks with a
+// normalized token hash. Identifiers and literals are replaced by
+// placeholders and whitespace and comments are dropped, so code that was
+// copied and then renamed or reformatted still produces the same
+// fingerprint. It needs only the source tree, no LLM or Memories calls.
+package duplicates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/divyekant/carto/internal/chunker"
+	"github.com/divyekant/carto/internal/scanner"
+)
+
+// DefaultMinLines is the shortest chunk considered when Options.MinLines
+// is 0; shorter declarations (getters, one-line wrappers) repeat naturally.
+const DefaultMinLines = 5
+
+// Options tunes Find.
+type Options struct {
+	MinLines int // skip chunks shorter than this; 0 means DefaultMinLines
+}
+
+// Member is one occurrence of a duplicated code unit.
+type Member struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Module    string `json:"module"`
+	FilePath  string `json:"file_path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// Cluster is a group of code units in different files that share a
+// fingerprint.
+type Cluster struct {
+	Fingerprint string   `json:"fingerprint"`
+	Lines       int      `json:"lines"`        // length of the longest member
+	CrossModule bool     `json:"cross_module"` // members span more than one module
+	Members     []Member `json:"members"`
+}
+
+// Find chunks every file of modules (paths relative to root) and returns
+// the clusters of chunks with identical fingerprints in at least two
+// different files. Clusters are ordered by duplicated lines (Lines times
+// extra copies), largest first. Whole-file fallback chunks are skipped, as
+// they are produced only for languages the chunker can't parse.
+func Find(root string, modules []scanner.Module, opts Options) []Cluster {
+	minLines := opts.MinLines
+	if minLines <= 0 {
+		minLines = DefaultMinLines
+	}
+
+	groups := make(map[string][]Member)
+	for _, mod := range modules {
+		for _, rel := range mod.Files {
+			lang := scanner.DetectLanguage(filepath.Base(rel))
+			if lang == "" {
+				continue
+			}
+			code, err := os.ReadFile(filepath.Join(root, rel))
+			if err != nil {
+				log.Printf("duplicates: warning: cannot read %s: %v", rel, err)
+				continue
+			}
+			chunks, err := chunker.ChunkFile(rel, code, lang, nil)
+			if err != nil {
+				log.Printf("duplicates: warning: chunking failed for %s: %v", rel, err)
+				continue
+			}
+			for _, c := range chunks {
+				if c.Kind == "module" || c.EndLine-c.StartLine+1 < minLines {
+					continue
+				}
+				fp := Fingerprint(c.Code, lang)
+				groups[fp] = append(groups[fp], Member{
+					Name:      c.Name,
+					Kind:      c.Kind,
+					Module:    mod.Name,
+					FilePath:  rel,
+					StartLine: c.StartLine,
+					EndLine:   c.EndLine,
+				})
+			}
+		}
+	}
+
+	var clusters []Cluster
+	for fp, members := range groups {
+		if len(members) < 2 || !spansFiles(members) {
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool {
+			if members[i].FilePath != members[j].FilePath {
+				return members[i].FilePath < members[j].FilePath
+			}
+			return members[i].StartLine < members[j].StartLine
+		})
+		cl := Cluster{Fingerprint: fp, Members: members}
+		for _, m := range members {
+			cl.Lines = max(cl.Lines, m.EndLine-m.StartLine+1)
+			if m.Module != members[0].Module {
+				cl.CrossModule = true
+			}
+		}
+		clusters = append(clusters, cl)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		di := clusters[i].Lines * (len(clusters[i].Members) - 1)
+		dj := clusters[j].Lines * (len(clusters[j].Members) - 1)
+		if di != dj {
+			return di > dj
+		}
+		return clusters[i].Fingerprint < clusters[j].Fingerprint
+	})
+	return clusters
+}
+
+func spansFiles(members []Member) bool {
+	for _, m := range members[1:] {
+		if m.FilePath != members[0].FilePath {
+			return true
+		}
+	}
+	return false
+}
+
+// Fingerprint returns a short hash of code's normalized token stream:
+// comments and whitespace are dropped, identifiers become "id", numbers
+// "0" and string literals `""`, while keywords and punctuation are kept so
+// the structure of the code still counts.
+func Fingerprint(code, language string) string {
+	sum := sha256.Sum256([]byte(strings.Join(normalize(code, language), " ")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// hashComments lists languages whose line comments start with '#'.
+var hashComments = map[string]bool{
+	"python": true, "ruby": true, "shell": true, "perl": true, "r": true,
+	"elixir": true, "yaml": true, "toml": true, "makefile": true, "dockerfile": true,
+}
+
+// keywords are kept verbatim so two units only match when their control
+// flow matches. The set spans the common keywords of the languages the
+// chunker parses; a keyword from one language read as an identifier in
+// another only makes fingerprints slightly more specific.
+var keywords = map[string]bool{
+	"if": true, "else": true, "elif": true, "for": true, "while": true, "do": true,
+	"switch": true, "case": true, "default": true, "break": true, "continue": true,
+	"return": true, "goto": true, "func": true, "function": true, "def": true, "fn": true,
+	"class": true, "struct": true, "interface": true, "type": true, "enum": true,
+	"var": true, "let": true, "const": true, "new": true, "delete": true,
+	"try": true, "catch": true, "finally": true, "throw": true, "throws": true,
+	"raise": true, "except": true, "with": true, "as": true, "yield": true,
+	"async": true, "await": true, "go": true, "defer": true, "select": true,
+	"range": true, "chan": true, "map": true, "match": true, "impl": true,
+	"pub": true, "mut": true, "static": true, "public": true, "private": true,
+	"protected": true, "extends": true, "implements": true, "import": true,
+	"from": true, "package": true, "lambda": true, "in": true, "is": true,
+	"not": true, "and": true, "or": true, "this": true, "self": true, "super": true,
+	"nil": true, "null": true, "None": true, "true": true, "false": true,
+	"True": true, "False": true, "void": true,
+}
+
+// normalize tokenizes code, replacing identifiers and literals with
+// placeholders.
+func normalize(code, language string) []string {
+	var tokens []string
+	src := []rune(code)
+	for i := 0; i < len(src); {
+		r := src[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '/' && i+1 < len(src) && src[i+1] == '/',
+			r == '#' && hashComments[language]:
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(src) && src[i+1] == '*':
+			i += 2
+			for i+1 < len(src) && !(src[i] == '*' && src[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '"' || r == '\'' || r == '`':
+			i++
+			for i < len(src) && src[i] != r {
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			tokens = append(tokens, `""`)
+		case unicode.IsDigit(r):
+			for i < len(src) && (unicode.IsLetter(src[i]) || unicode.IsDigit(src[i]) || src[i] == '.' || src[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, "0")
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			start := i
+			for i < len(src) && (unicode.IsLetter(src[i]) || unicode.IsDigit(src[i]) || src[i] == '_' || src[i] == '$') {
+				i++
+			}
+			if word := string(src[start:i]); keywords[word] {
+				tokens = append(tokens, word)
+			} else {
+				tokens = append(tokens, "id")
+			}
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,108 @@
+package duplicates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/divyekant/carto/internal/scanner"
+)
+
+const sumOrders = `
+// SumOrders adds up the order totals.
+func SumOrders(orders []Order) int {
+	total := 0
+	for _, o := range orders {
+		if o.Paid {
+			total += o.Amount
+		}
+	}
+	return total
+}
+`
+
+// Same logic, renamed and reformatted, in another module.
+const sumInvoices = `
+func TotalInvoices(items []Invoice) int {
+	sum := 0
+	for _, it := range items {
+		if it.Settled { sum += it.Value }
+	}
+
+	return sum
+}
+`
+
+// Similar shape but different control flow.
+const countOrders = `
+func CountOrders(orders []Order) int {
+	n := 0
+	for _, o := range orders {
+		if !o.Paid {
+			continue
+		}
+		n++
+	}
+	return n
+}
+`
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFind_GroupsCopiesAcrossModules(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "orders/sum.go", "package orders\n"+sumOrders+countOrders)
+	writeFile(t, root, "billing/invoices.go", "package billing\n"+sumInvoices)
+
+	modules := []scanner.Module{
+		{Name: "orders", Files: []string{"orders/sum.go"}},
+		{Name: "billing", Files: []string{"billing/invoices.go"}},
+	}
+	clusters := Find(root, modules, Options{})
+	if len(clusters) != 1 {
+		t.Fatalf("expected 1 cluster, got %d: %+v", len(clusters), clusters)
+	}
+	cl := clusters[0]
+	if !cl.CrossModule || len(cl.Members) != 2 {
+		t.Fatalf("cluster = %+v", cl)
+	}
+	if cl.Members[0].Name != "TotalInvoices" || cl.Members[0].FilePath != "billing/invoices.go" ||
+		cl.Members[1].Name != "SumOrders" || cl.Members[1].Module != "orders" {
+		t.Errorf("members = %+v", cl.Members)
+	}
+	for _, m := range cl.Members {
+		if m.Name == "CountOrders" {
+			t.Errorf("distinct function grouped as duplicate: %+v", m)
+		}
+	}
+}
+
+func TestFind_IgnoresSameFileAndShortChunks(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "a.go", "package a\n"+sumOrders+sumInvoices)
+	writeFile(t, root, "b.go", "package a\n\nfunc A() int { return 1 }\n")
+	writeFile(t, root, "c.go", "package a\n\nfunc B() int { return 2 }\n")
+
+	modules := []scanner.Module{{Name: "a", Files: []string{"a.go", "b.go", "c.go"}}}
+	if clusters := Find(root, modules, Options{}); len(clusters) != 0 {
+		t.Errorf("expected no clusters, got %+v", clusters)
+	}
+}
+
+func TestFingerprint_Normalizes(t *testing.T) {
+	a := Fingerprint("x := foo(1, \"a\") // note\n", "go")
+	b := Fingerprint("total   :=   bar(42, \"other\")", "go")
+	if a != b {
+		t.Errorf("renamed and reformatted code should match: %s != %s", a, b)
+	}
+	if c := Fingerprint("return foo(1, \"a\")", "go"); c == a {
+		t.Error("different structure should not match")
+	}
+}
@@ -1,20 +1,53 @@
 package gitclone
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// Errors a failed Clone wraps, so callers can tell a bad token from a
+// flaky network.
+var (
+	ErrAuthFailed = errors.New("authentication failed")
+	ErrNetwork    = errors.New("network error")
+)
+
+// Retry defaults for Clone.
+const (
+	DefaultAttempts = 3
+	DefaultBackoff  = 2 * time.Second
+)
+
+// sleep waits between clone attempts; tests replace it.
+var sleep = time.Sleep
+
 // CloneOptions configures a git clone operation.
 type CloneOptions struct {
-	URL    string
-	Branch string
-	Token  string
-	Depth  int
+	URL          string
+	Branch       string
+	Token        string
+	Depth        int  // history depth; 0 means 1
+	SingleBranch bool // fetch only the cloned branch's history
+
+	// Attempts is how many times a failing clone is tried; 0 means
+	// DefaultAttempts. Authentication failures are never retried.
+	Attempts int
+	// Backoff is the wait before the second attempt, doubling after each
+	// further failure; 0 means DefaultBackoff.
+	Backoff time.Duration
+	// Progress, if set, receives git's progress ("Receiving objects: 40%")
+	// and retry notices as they happen.
+	Progress func(msg string)
 }
 
 // CloneResult holds the result of a successful clone.
@@ -79,7 +112,22 @@ func ParseOwnerRepo(gitURL string) (owner, repo string) {
 	return "", ""
 }
 
-// Clone performs a shallow git clone to a temporary directory.
+// OriginURL returns the fetch URL of the "origin" remote of the repository
+// at dir, or "" if dir is not a git repository or has no origin.
+func OriginURL(dir string) string {
+	cmd := exec.Command("git", "remote", "get-url", "origin")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Clone performs a shallow git clone to a temporary directory. A failed
+// clone is retried with exponential backoff unless git reports an
+// authentication failure; the returned error then wraps ErrAuthFailed, or
+// ErrNetwork when the last attempt could not reach the remote.
 func Clone(opts CloneOptions) (*CloneResult, error) {
 	if opts.URL == "" {
 		return nil, fmt.Errorf("gitclone: URL is required")
@@ -87,10 +135,48 @@ func Clone(opts CloneOptions) (*CloneResult, error) {
 	if opts.Depth == 0 {
 		opts.Depth = 1
 	}
+	if opts.Attempts <= 0 {
+		opts.Attempts = DefaultAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultBackoff
+	}
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
 
+	backoff := opts.Backoff
+	for attempt := 1; ; attempt++ {
+		result, stderr, err := cloneOnce(opts, progress)
+		if err == nil {
+			return result, nil
+		}
+		detail := lastLine(redact(stderr, opts.Token))
+		if detail == "" {
+			detail = err.Error()
+		}
+		if isAuthFailure(stderr) {
+			return nil, fmt.Errorf("gitclone: %w cloning %s (check the access token): %s", ErrAuthFailed, opts.URL, detail)
+		}
+		if attempt >= opts.Attempts {
+			if isNetworkFailure(stderr) {
+				return nil, fmt.Errorf("gitclone: %w cloning %s after %d attempts: %s", ErrNetwork, opts.URL, attempt, detail)
+			}
+			return nil, fmt.Errorf("gitclone: git clone of %s failed after %d attempts: %s", opts.URL, attempt, detail)
+		}
+		progress(fmt.Sprintf("Clone attempt %d/%d failed (%s); retrying in %s", attempt, opts.Attempts, detail, backoff))
+		sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// cloneOnce runs a single git clone into a fresh temporary directory,
+// returning git's error output alongside any failure.
+func cloneOnce(opts CloneOptions, progress func(string)) (*CloneResult, string, error) {
 	tmpDir, err := os.MkdirTemp("", "carto-clone-*")
 	if err != nil {
-		return nil, fmt.Errorf("gitclone: create temp dir: %w", err)
+		return nil, "", fmt.Errorf("gitclone: create temp dir: %w", err)
 	}
 	cleanup := func() { os.RemoveAll(tmpDir) }
 
@@ -103,18 +189,162 @@ func Clone(opts CloneOptions) (*CloneResult, error) {
 		}
 	}
 
-	args := []string{"clone", "--depth", fmt.Sprintf("%d", opts.Depth)}
+	args := []string{"clone", "--progress", "--depth", fmt.Sprintf("%d", opts.Depth)}
+	if opts.SingleBranch {
+		args = append(args, "--single-branch")
+	}
 	if opts.Branch != "" {
 		args = append(args, "--branch", opts.Branch)
 	}
 	args = append(args, cloneURL, tmpDir)
 
 	cmd := exec.Command("git", args...)
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	// Never prompt for credentials: a missing or bad token must fail.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
 		cleanup()
-		return nil, fmt.Errorf("gitclone: git clone failed: %w", err)
+		return nil, "", fmt.Errorf("gitclone: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		cleanup()
+		return nil, "", fmt.Errorf("gitclone: start git: %w", err)
+	}
+	var output bytes.Buffer
+	readProgress(io.TeeReader(stderr, &output), progress)
+	if err := cmd.Wait(); err != nil {
+		cleanup()
+		return nil, output.String(), fmt.Errorf("gitclone: git clone failed: %w", err)
+	}
+
+	return &CloneResult{Dir: tmpDir, Cleanup: cleanup}, "", nil
+}
+
+// readProgress reads git's stderr until EOF and reports progress lines
+// ("Receiving objects:  40% (400/1000)") whenever a phase starts, crosses
+// another 10%, or completes. Git redraws these lines with carriage returns,
+// so both \r and \n end a line.
+func readProgress(r io.Reader, progress func(string)) {
+	sc := bufio.NewScanner(r)
+	sc.Split(scanLinesCR)
+	lastPhase, lastStep := "", -1
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		phase, pct, ok := parseProgress(line)
+		if !ok {
+			continue
+		}
+		step := pct / 10
+		if phase == lastPhase && step == lastStep {
+			continue
+		}
+		lastPhase, lastStep = phase, step
+		progress(line)
+	}
+}
+
+// parseProgress splits a git progress line such as
+// "remote: Counting objects:  45% (9/20)" into its phase and percentage.
+func parseProgress(line string) (phase string, pct int, ok bool) {
+	line = strings.TrimPrefix(line, "remote: ")
+	phase, rest, found := strings.Cut(line, ":")
+	if !found {
+		return "", 0, false
+	}
+	rest = strings.TrimSpace(rest)
+	end := strings.IndexByte(rest, '%')
+	if end < 0 {
+		return "", 0, false
+	}
+	pct, err := strconv.Atoi(strings.TrimSpace(rest[:end]))
+	if err != nil {
+		return "", 0, false
 	}
+	return phase, pct, true
+}
+
+// scanLinesCR is bufio.ScanLines that also ends a line at '\r'.
+func scanLinesCR(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// authFailureMarkers are substrings of git's error output that mean the
+// server rejected the credentials rather than the connection failing.
+var authFailureMarkers = []string{
+	"authentication failed",
+	"could not read username",
+	"could not read password",
+	"invalid username or password",
+	"permission denied (publickey",
+	"the requested url returned error: 401",
+	"the requested url returned error: 403",
+}
 
-	return &CloneResult{Dir: tmpDir, Cleanup: cleanup}, nil
+// isAuthFailure reports whether git's error output describes rejected
+// credentials.
+func isAuthFailure(stderr string) bool {
+	return containsAny(stderr, authFailureMarkers)
+}
+
+// networkFailureMarkers are substrings of git's error output that mean the
+// remote could not be reached or the transfer broke off.
+var networkFailureMarkers = []string{
+	"could not resolve host",
+	"failed to connect",
+	"connection refused",
+	"connection reset",
+	"connection timed out",
+	"operation timed out",
+	"network is unreachable",
+	"early eof",
+	"rpc failed",
+	"unexpected disconnect",
+	"the remote end hung up unexpectedly",
+	"transfer closed",
+	"tls",
+	"ssl",
+}
+
+// isNetworkFailure reports whether git's error output describes an
+// unreachable remote or an interrupted transfer.
+func isNetworkFailure(stderr string) bool {
+	return containsAny(stderr, networkFailureMarkers)
+}
+
+// containsAny reports whether s contains any of the lowercase markers,
+// ignoring case.
+func containsAny(s string, markers []string) bool {
+	lower := strings.ToLower(s)
+	for _, marker := range markers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact removes token from git's output, which can echo the clone URL.
+func redact(s, token string) string {
+	if token == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, token, "***")
+}
+
+// lastLine returns the last non-empty line of s, which for git is usually
+// the "fatal: ..." reason.
+func lastLine(s string) string {
+	lines := strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == '\r' })
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+	return ""
 }
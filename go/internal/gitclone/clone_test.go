@@ -1,9 +1,15 @@
 package gitclone
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIsGitURL(t *testing.T) {
@@ -81,3 +87,127 @@ func TestClone_PublicRepo(t *testing.T) {
 		t.Error("expected .git directory in clone")
 	}
 }
+
+// initBareRepo creates a bare repository at dir holding one commit.
+func initBareRepo(t *testing.T, dir string) {
+	t.Helper()
+	work := t.TempDir()
+	run := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run(work, "init", "-q")
+	os.WriteFile(filepath.Join(work, "main.go"), []byte("package main\n"), 0o644)
+	run(work, "add", ".")
+	run(work, "commit", "-q", "-m", "init")
+	run(work, "clone", "-q", "--bare", work, dir)
+}
+
+func TestClone_RetriesTransientFailure(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "repo.git")
+
+	// The repository only appears after the first attempt has failed.
+	var waits []time.Duration
+	sleep = func(d time.Duration) {
+		waits = append(waits, d)
+		initBareRepo(t, repo)
+	}
+	t.Cleanup(func() { sleep = time.Sleep })
+
+	var messages []string
+	result, err := Clone(CloneOptions{
+		URL:      repo,
+		Backoff:  time.Millisecond,
+		Progress: func(msg string) { messages = append(messages, msg) },
+	})
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer result.Cleanup()
+
+	if len(waits) != 1 || waits[0] != time.Millisecond {
+		t.Errorf("waits = %v, want one retry after 1ms", waits)
+	}
+	if len(messages) == 0 || !strings.Contains(messages[0], "attempt 1/3 failed") {
+		t.Errorf("expected a retry notice, got %q", messages)
+	}
+	if _, err := os.Stat(filepath.Join(result.Dir, "main.go")); err != nil {
+		t.Error("expected main.go in clone")
+	}
+}
+
+func TestClone_AuthFailureIsNotRetried(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	attempts := 0
+	sleep = func(time.Duration) { attempts++ }
+	t.Cleanup(func() { sleep = time.Sleep })
+
+	_, err := Clone(CloneOptions{URL: srv.URL + "/owner/repo.git", Token: "bad-token"})
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("err = %v, want ErrAuthFailed", err)
+	}
+	if attempts != 0 {
+		t.Errorf("auth failure was retried %d times", attempts)
+	}
+	if strings.Contains(err.Error(), "bad-token") {
+		t.Errorf("error leaks the token: %v", err)
+	}
+}
+
+func TestClone_NetworkFailure(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	// Nothing listens on a closed server's address.
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	sleep = func(time.Duration) {}
+	t.Cleanup(func() { sleep = time.Sleep })
+
+	_, err := Clone(CloneOptions{URL: srv.URL + "/owner/repo.git", Attempts: 2})
+	if !errors.Is(err, ErrNetwork) {
+		t.Fatalf("err = %v, want ErrNetwork", err)
+	}
+	if !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("error should report the attempts: %v", err)
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		line  string
+		phase string
+		pct   int
+		ok    bool
+	}{
+		{"Receiving objects:  45% (9/20), 1.2 MiB | 3 MiB/s", "Receiving objects", 45, true},
+		{"remote: Counting objects: 100% (20/20), done.", "Counting objects", 100, true},
+		{"Cloning into '/tmp/x'...", "", 0, false},
+		{"remote: Enumerating objects: 20, done.", "", 0, false},
+	}
+	for _, tt := range tests {
+		phase, pct, ok := parseProgress(tt.line)
+		if phase != tt.phase || pct != tt.pct || ok != tt.ok {
+			t.Errorf("parseProgress(%q) = (%q, %d, %v), want (%q, %d, %v)",
+				tt.line, phase, pct, ok, tt.phase, tt.pct, tt.ok)
+		}
+	}
+}
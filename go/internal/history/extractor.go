@@ -26,24 +26,31 @@ type FileHistory struct {
 	ChurnScore float64   // number of commits as a proxy for complexity
 }
 
+// Defaults for ExtractOptions fields left unset.
+const (
+	DefaultMaxCommits = 50
+	DefaultSince      = "6 months ago"
+)
+
 // ExtractOptions controls how much history to fetch.
 type ExtractOptions struct {
 	MaxCommits int    // default 50 per file
 	Since      string // git date format, default "6 months ago"
+	AllHistory bool   // ignore Since and read each file's entire history
 }
 
 func (o *ExtractOptions) maxCommits() int {
 	if o != nil && o.MaxCommits > 0 {
 		return o.MaxCommits
 	}
-	return 50
+	return DefaultMaxCommits
 }
 
 func (o *ExtractOptions) since() string {
 	if o != nil && o.Since != "" {
 		return o.Since
 	}
-	return "6 months ago"
+	return DefaultSince
 }
 
 // prRefRe matches PR references in commit messages:
@@ -70,20 +77,28 @@ func ParsePRReference(message string) string {
 // into structured commit data. If git is unavailable or the path is not inside
 // a git repo, it returns an empty history without an error.
 func ExtractFileHistory(repoRoot string, relPath string, opts *ExtractOptions) (*FileHistory, error) {
-	maxCommits := opts.maxCommits()
-	since := opts.since()
+	repo, ok := ResolveRepo(repoRoot)
+	if !ok {
+		return &FileHistory{FilePath: relPath}, nil
+	}
+	return extractFileHistory(repo, repoRoot, relPath, opts)
+}
 
+// extractFileHistory is ExtractFileHistory for an already-resolved repo.
+// relPath is relative to repoRoot, which may be below repo.TopLevel.
+func extractFileHistory(repo *Repo, repoRoot string, relPath string, opts *ExtractOptions) (*FileHistory, error) {
 	args := []string{
 		"log",
 		"--follow",
 		"--pretty=format:%H|%an|%aI|%s",
-		fmt.Sprintf("-n%d", maxCommits),
-		fmt.Sprintf("--since=%s", since),
-		"--",
-		relPath,
+		fmt.Sprintf("-n%d", opts.maxCommits()),
 	}
+	if opts == nil || !opts.AllHistory {
+		args = append(args, fmt.Sprintf("--since=%s", opts.since()))
+	}
+	args = append(args, "--", relPath)
 
-	cmd := exec.Command("git", args...)
+	cmd := exec.Command("git", repo.gitArgs(args...)...)
 	cmd.Dir = repoRoot
 
 	out, err := cmd.Output()
@@ -154,6 +169,16 @@ func ExtractBulkHistory(repoRoot string, relPaths []string, opts *ExtractOptions
 	results := make([]*FileHistory, len(relPaths))
 	errs := make([]error, len(relPaths))
 
+	// Resolve the repository once; a non-repo yields empty histories
+	// without spawning git for every file.
+	repo, ok := ResolveRepo(repoRoot)
+	if !ok {
+		for i, p := range relPaths {
+			results[i] = &FileHistory{FilePath: p}
+		}
+		return results, nil
+	}
+
 	sem := make(chan struct{}, maxWorkers)
 	var wg sync.WaitGroup
 
@@ -164,7 +189,7 @@ func ExtractBulkHistory(repoRoot string, relPaths []string, opts *ExtractOptions
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			h, err := ExtractFileHistory(repoRoot, path, opts)
+			h, err := extractFileHistory(repo, repoRoot, path, opts)
 			results[idx] = h
 			errs[idx] = err
 		}(i, p)
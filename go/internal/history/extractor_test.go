@@ -292,3 +292,74 @@ func TestExtractBulkHistory_NonGitDir(t *testing.T) {
 		}
 	}
 }
+
+func TestExtractFileHistory_Worktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	primary := initTestRepo(t)
+	wt := filepath.Join(t.TempDir(), "wt")
+	gitCmd(t, primary, "worktree", "add", "--detach", wt)
+
+	// A linked worktree has a .git file, not a directory.
+	if fi, err := os.Stat(filepath.Join(wt, ".git")); err != nil || fi.IsDir() {
+		t.Fatalf("expected .git file in worktree, got %v, %v", fi, err)
+	}
+
+	h, err := ExtractFileHistory(wt, "hello.txt", &ExtractOptions{MaxCommits: 10, Since: "1 year ago"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.Commits) != 2 {
+		t.Fatalf("expected 2 commits in worktree, got %d", len(h.Commits))
+	}
+}
+
+func TestExtractBulkHistory_BareCloneWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	src := initTestRepo(t)
+	base := t.TempDir()
+	bare := filepath.Join(base, "repo.git")
+	gitCmd(t, base, "clone", "--bare", src, bare)
+	wt := filepath.Join(base, "wt")
+	gitCmd(t, bare, "worktree", "add", "--detach", wt)
+
+	results, err := ExtractBulkHistory(wt, []string{"hello.txt"}, &ExtractOptions{MaxCommits: 10, Since: "1 year ago"}, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Commits) != 2 {
+		t.Fatalf("expected 2 commits from bare-clone worktree, got %+v", results)
+	}
+}
+
+func TestResolveRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	if _, ok := ResolveRepo(t.TempDir()); ok {
+		t.Error("expected non-git dir to be unresolved")
+	}
+
+	dir := initTestRepo(t)
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	repo, ok := ResolveRepo(sub)
+	if !ok {
+		t.Fatal("expected repo to resolve from a subdirectory")
+	}
+	want, _ := filepath.EvalSymlinks(dir)
+	if got, _ := filepath.EvalSymlinks(repo.TopLevel); got != want {
+		t.Errorf("TopLevel = %q, want %q", repo.TopLevel, dir)
+	}
+	if !filepath.IsAbs(repo.GitDir) {
+		t.Errorf("GitDir = %q, want absolute path", repo.GitDir)
+	}
+}
@@ -0,0 +1,87 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// recencyDecay controls how quickly a file's recency bonus decays: a file
+// last touched recencyDecay ago gets half the bonus of one touched today.
+const recencyDecay = 30 * 24 * time.Hour
+
+// Hotspot is a file ranked by combined churn and recency.
+type Hotspot struct {
+	Module     string    `json:"module"`
+	FilePath   string    `json:"file_path"`
+	Churn      float64   `json:"churn"`
+	Authors    int       `json:"authors"`
+	LastCommit time.Time `json:"last_commit"`
+	Score      float64   `json:"score"`
+}
+
+// RankHotspots scores every file in histories (keyed by module name) and
+// returns the top n, highest score first. The score is the churn scaled by
+// a recency factor in [1, 2]: files with equal churn rank by how recently
+// they changed. Files without commits are skipped. n <= 0 returns all.
+func RankHotspots(histories map[string][]*FileHistory, now time.Time, n int) []Hotspot {
+	var spots []Hotspot
+	for module, files := range histories {
+		for _, fh := range files {
+			if fh == nil || len(fh.Commits) == 0 {
+				continue
+			}
+			last := lastCommitTime(fh.Commits)
+			spots = append(spots, Hotspot{
+				Module:     module,
+				FilePath:   fh.FilePath,
+				Churn:      fh.ChurnScore,
+				Authors:    len(fh.Authors),
+				LastCommit: last,
+				Score:      fh.ChurnScore * (1 + recencyFactor(last, now)),
+			})
+		}
+	}
+
+	sort.Slice(spots, func(i, j int) bool {
+		if spots[i].Score != spots[j].Score {
+			return spots[i].Score > spots[j].Score
+		}
+		if spots[i].Module != spots[j].Module {
+			return spots[i].Module < spots[j].Module
+		}
+		return spots[i].FilePath < spots[j].FilePath
+	})
+
+	if n > 0 && len(spots) > n {
+		spots = spots[:n]
+	}
+	return spots
+}
+
+// lastCommitTime returns the most recent parseable commit date.
+func lastCommitTime(commits []CommitInfo) time.Time {
+	var last time.Time
+	for _, c := range commits {
+		t, err := time.Parse(time.RFC3339, c.Date)
+		if err != nil {
+			continue
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// recencyFactor maps the age of last to (0, 1]: 1 for a commit made now,
+// 0.5 at recencyDecay, approaching 0 for old commits.
+func recencyFactor(last, now time.Time) float64 {
+	if last.IsZero() {
+		return 0
+	}
+	age := now.Sub(last)
+	if age < 0 {
+		age = 0
+	}
+	return 1 / (1 + float64(age)/float64(recencyDecay))
+}
@@ -0,0 +1,73 @@
+package history
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Repo identifies the git repository that contains a directory.
+type Repo struct {
+	TopLevel string // working tree root
+	GitDir   string // absolute git dir; outside TopLevel for worktrees and submodules
+}
+
+// ResolveRepo asks git which repository dir belongs to, using
+// `git rev-parse` rather than looking for a literal .git directory. This
+// works for linked worktrees and submodules (where .git is a file pointing
+// elsewhere) and for worktrees of bare clones. It returns false if git is
+// unavailable or dir is not inside a working tree.
+func ResolveRepo(dir string) (*Repo, bool) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel", "--absolute-git-dir")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, false
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 || lines[0] == "" || lines[1] == "" {
+		return nil, false
+	}
+	return &Repo{TopLevel: lines[0], GitDir: lines[1]}, true
+}
+
+// gitArgs prefixes args with explicit --git-dir and --work-tree so git
+// does not need to rediscover the repository from the working directory.
+func (r *Repo) gitArgs(args ...string) []string {
+	return append([]string{"--git-dir=" + r.GitDir, "--work-tree=" + r.TopLevel}, args...)
+}
+
+// ValidateSince checks that git reads since as a past date, as used by
+// ExtractOptions.Since. git silently takes a date it cannot parse to mean
+// "now", so a value that resolves to the current time or later is
+// rejected. Outside a git repository there is no history to limit and any
+// non-empty value is accepted.
+func ValidateSince(dir, since string) error {
+	if strings.TrimSpace(since) == "" {
+		return fmt.Errorf("history since: date is empty")
+	}
+	repo, ok := ResolveRepo(dir)
+	if !ok {
+		return nil
+	}
+
+	start := time.Now().Unix()
+	cmd := exec.Command("git", repo.gitArgs("rev-parse", "--since="+since)...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("history since %q: git rev-parse: %w", since, err)
+	}
+	ageStr, found := strings.CutPrefix(strings.TrimSpace(string(out)), "--max-age=")
+	age, err := strconv.ParseInt(ageStr, 10, 64)
+	if !found || err != nil {
+		return fmt.Errorf("history since %q: unexpected git output %q", since, out)
+	}
+	if age >= start {
+		return fmt.Errorf("history since %q: git does not read this as a past date (try \"1 year ago\" or \"2024-01-01\")", since)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ZoneFiles names the files of one zone. It decodes from the entries of a
+// module's zones layer, which leave Module for the caller to fill in.
+type ZoneFiles struct {
+	Module string   `json:"module"`
+	Name   string   `json:"name"`
+	Files  []string `json:"files"`
+}
+
+// ZoneStaleness reports how long ago any file of a zone was last committed
+// to. A zone is stale when every one of its files has a known last commit
+// older than the threshold. Files with no recorded commits (outside the
+// extracted history window, or untracked) are counted in Unknown and keep
+// the zone from being classified stale; index with full history to date
+// them.
+type ZoneStaleness struct {
+	Module     string    `json:"module"`
+	Zone       string    `json:"zone"`
+	Files      []string  `json:"files"`
+	LastCommit time.Time `json:"last_commit"`
+	AgeDays    int       `json:"age_days"`
+	Unknown    int       `json:"unknown"`
+	Stale      bool      `json:"stale"`
+}
+
+// ClassifyZones computes the staleness of each zone from histories (keyed
+// by module name, matched to zone files by path) as of now. Results are
+// ordered stalest first, then by module and zone name.
+func ClassifyZones(zones []ZoneFiles, histories map[string][]*FileHistory, now time.Time, olderThan time.Duration) []ZoneStaleness {
+	lastByFile := make(map[string]time.Time)
+	for _, files := range histories {
+		for _, fh := range files {
+			if fh == nil || len(fh.Commits) == 0 {
+				continue
+			}
+			if last := lastCommitTime(fh.Commits); last.After(lastByFile[fh.FilePath]) {
+				lastByFile[fh.FilePath] = last
+			}
+		}
+	}
+
+	out := make([]ZoneStaleness, 0, len(zones))
+	for _, z := range zones {
+		zs := ZoneStaleness{Module: z.Module, Zone: z.Name, Files: z.Files}
+		for _, f := range z.Files {
+			last, ok := lastByFile[f]
+			if !ok {
+				zs.Unknown++
+				continue
+			}
+			if last.After(zs.LastCommit) {
+				zs.LastCommit = last
+			}
+		}
+		if !zs.LastCommit.IsZero() {
+			zs.AgeDays = int(now.Sub(zs.LastCommit) / (24 * time.Hour))
+			zs.Stale = zs.Unknown == 0 && now.Sub(zs.LastCommit) > olderThan
+		}
+		out = append(out, zs)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].AgeDays != out[j].AgeDays {
+			return out[i].AgeDays > out[j].AgeDays
+		}
+		if out[i].Module != out[j].Module {
+			return out[i].Module < out[j].Module
+		}
+		return out[i].Zone < out[j].Zone
+	})
+	return out
+}
+
+// ParseAge parses an age threshold such as "1y", "6mo", "90d" or "2w",
+// falling back to time.ParseDuration for forms like "720h". A year is 365
+// days and a month 30 days.
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	day := 24 * time.Hour
+	for _, u := range []struct {
+		suffix string
+		unit   time.Duration
+	}{{"mo", 30 * day}, {"y", 365 * day}, {"w", 7 * day}, {"d", day}} {
+		if num, ok := strings.CutSuffix(s, u.suffix); ok {
+			n, err := strconv.Atoi(num)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid age %q: want a positive count like 1y, 6mo, 90d or 2w", s)
+			}
+			return time.Duration(n) * u.unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q: want a positive count like 1y, 6mo, 90d or 2w", s)
+	}
+	return d, nil
+}
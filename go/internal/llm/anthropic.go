@@ -23,13 +23,11 @@ func (p *AnthropicProvider) Complete(ctx context.Context, req CompletionRequest)
 		tier = TierDeep
 	}
 
+	// A zero MaxTokens falls back to the client's per-tier default.
 	opts := &CompleteOptions{
 		System:    req.System,
 		MaxTokens: req.MaxTokens,
 	}
-	if opts.MaxTokens == 0 {
-		opts.MaxTokens = 4096
-	}
 
 	raw, err := p.client.CompleteJSON(req.User, tier, opts)
 	if err != nil {
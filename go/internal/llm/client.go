@@ -2,13 +2,17 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/divyekant/carto/internal/tracing"
 )
 
 // Tier selects which model class to use.
@@ -28,6 +32,26 @@ const (
 	UserAgent     = "carto/0.3.0 (external, cli)"
 )
 
+// Default output token caps per tier, used when neither Options nor
+// CompleteOptions specify a limit.
+const (
+	DefaultFastMaxTokens = 4096
+	DefaultDeepMaxTokens = 8192
+)
+
+// ErrTruncated is returned (wrapped) by CompleteJSON when the response ends
+// inside an unterminated JSON object because the model hit its max_tokens
+// cap. Callers can detect it with errors.Is and retry with a higher cap.
+var ErrTruncated = errors.New("llm: response truncated at max_tokens")
+
+// ErrNoAPIKey is returned (wrapped) when a completion is requested from the
+// Anthropic API without an API key or OAuth token configured.
+var ErrNoAPIKey = errors.New("llm: no API key configured")
+
+// defaultBaseURL is the Anthropic API, used when Options.BaseURL is empty.
+// Other base URLs may be keyless local proxies.
+const defaultBaseURL = "https://api.anthropic.com"
+
 // Options configures the Anthropic API client.
 type Options struct {
 	APIKey        string
@@ -36,12 +60,86 @@ type Options struct {
 	DeepModel     string
 	MaxConcurrent int
 	IsOAuth       bool
+	FastMaxTokens int // default output cap for fast-tier calls (default 4096)
+	DeepMaxTokens int // default output cap for deep-tier calls (default 8192)
+
+	// FastConcurrency and DeepConcurrency limit each tier's in-flight calls
+	// separately, since deep-tier calls cost more and are rate-limited
+	// differently. Zero means MaxConcurrent.
+	FastConcurrency int
+	DeepConcurrency int
+
+	// ExtraHeaders are added to every completion request, e.g. for a
+	// corporate proxy in front of the API. They never replace the headers
+	// the client manages (see setExtraHeaders).
+	ExtraHeaders map[string]string
+
+	// Embedding tier. Anthropic has no embeddings API, so Embed calls an
+	// OpenAI-compatible /v1/embeddings endpoint or Ollama's /api/embeddings.
+	// APIKey is never sent to the embeddings endpoint.
+	EmbeddingModel     string // required for Embed, e.g. "text-embedding-3-small"
+	EmbeddingProvider  string // "openai" (default) or "ollama"
+	EmbeddingBaseURL   string // default https://api.openai.com or http://localhost:11434
+	EmbeddingAPIKey    string // bearer token for OpenAI-compatible endpoints
+	EmbeddingBatchSize int    // inputs per /v1/embeddings request (default 96)
+
+	// OnUsage, if set, is called after every successful API call with the
+	// token usage the API reported. It must be safe for concurrent use.
+	OnUsage func(tier Tier, inputTokens, outputTokens int)
 }
 
 // CompleteOptions provides per-request overrides.
 type CompleteOptions struct {
 	System    string
 	MaxTokens int
+
+	// Context, if set, cancels the call: waiting for a semaphore slot,
+	// retry backoff and the request itself. Nil means no cancellation.
+	Context context.Context
+}
+
+// context returns the call's context, or context.Background if o is nil
+// or has none.
+func (o *CompleteOptions) context() context.Context {
+	if o == nil || o.Context == nil {
+		return context.Background()
+	}
+	return o.Context
+}
+
+// WithInstructions appends caller-supplied instructions to a system prompt,
+// followed by a reminder that the response format still applies, so custom
+// guidance can shift what the model focuses on without breaking JSON
+// parsing. Blank instructions leave system unchanged.
+func WithInstructions(system, instructions string) string {
+	instructions = strings.TrimSpace(instructions)
+	if instructions == "" {
+		return system
+	}
+	return system + "\n\nAdditional instructions:\n" + instructions +
+		"\n\nWhatever the instructions above say, respond only with JSON in the format requested."
+}
+
+// managedHeaders are set by the clients themselves and can't be replaced
+// by Options.ExtraHeaders, even when a request leaves them unset (an OAuth
+// request deletes X-Api-Key).
+var managedHeaders = map[string]bool{
+	"Authorization":     true,
+	"X-Api-Key":         true,
+	"Anthropic-Version": true,
+	"Anthropic-Beta":    true,
+	"Content-Type":      true,
+}
+
+// setExtraHeaders adds extra to h, skipping managed headers and any header
+// h already has, such as the User-Agent sent with OAuth tokens.
+func setExtraHeaders(h http.Header, extra map[string]string) {
+	for k, v := range extra {
+		if managedHeaders[http.CanonicalHeaderKey(k)] || h.Get(k) != "" {
+			continue
+		}
+		h.Set(k, v)
+	}
 }
 
 // oauthState tracks a refreshable OAuth token.
@@ -54,16 +152,20 @@ type oauthState struct {
 
 // Client is an HTTP-based Anthropic API client.
 type Client struct {
-	opts  Options
-	sem   chan struct{}
-	http  http.Client
-	oauth *oauthState // non-nil when using OAuth tokens
+	opts    Options
+	fastSem chan struct{} // slots for fast-tier calls and embeddings
+	deepSem chan struct{} // slots for deep-tier calls
+	http    http.Client
+	oauth   *oauthState // non-nil when using OAuth tokens
+
+	spendMu sync.Mutex
+	spent   float64 // estimated dollars spent so far; see Spend
 }
 
 // NewClient creates a Client with sensible defaults.
 func NewClient(opts Options) *Client {
 	if opts.BaseURL == "" {
-		opts.BaseURL = "https://api.anthropic.com"
+		opts.BaseURL = defaultBaseURL
 	}
 	if opts.FastModel == "" {
 		opts.FastModel = "claude-haiku-4-5-20251001"
@@ -74,12 +176,24 @@ func NewClient(opts Options) *Client {
 	if opts.MaxConcurrent <= 0 {
 		opts.MaxConcurrent = 10
 	}
+	if opts.FastConcurrency <= 0 {
+		opts.FastConcurrency = opts.MaxConcurrent
+	}
+	if opts.DeepConcurrency <= 0 {
+		opts.DeepConcurrency = opts.MaxConcurrent
+	}
+	if opts.FastMaxTokens <= 0 {
+		opts.FastMaxTokens = DefaultFastMaxTokens
+	}
+	if opts.DeepMaxTokens <= 0 {
+		opts.DeepMaxTokens = DefaultDeepMaxTokens
+	}
 
-	sem := make(chan struct{}, opts.MaxConcurrent)
 	c := &Client{
-		opts: opts,
-		sem:  sem,
-		http: http.Client{Timeout: 5 * time.Minute},
+		opts:    opts,
+		fastSem: make(chan struct{}, opts.FastConcurrency),
+		deepSem: make(chan struct{}, opts.DeepConcurrency),
+		http:    http.Client{Timeout: 5 * time.Minute},
 	}
 
 	if opts.IsOAuth {
@@ -153,6 +267,7 @@ type apiRequest struct {
 	MaxTokens int          `json:"max_tokens"`
 	System    string       `json:"system,omitempty"`
 	Messages  []apiMessage `json:"messages"`
+	Stream    bool         `json:"stream,omitempty"`
 }
 
 type apiMessage struct {
@@ -162,7 +277,14 @@ type apiMessage struct {
 
 // apiResponse is the top-level JSON returned by /v1/messages.
 type apiResponse struct {
-	Content []contentBlock `json:"content"`
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason,omitempty"`
+	Usage      apiUsage       `json:"usage"`
+}
+
+type apiUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 type contentBlock struct {
@@ -170,39 +292,153 @@ type contentBlock struct {
 	Text string `json:"text,omitempty"`
 }
 
+// MaxTokens returns the default output token cap for the given tier.
+func (c *Client) MaxTokens(tier Tier) int {
+	if tier == TierDeep {
+		return c.opts.DeepMaxTokens
+	}
+	return c.opts.FastMaxTokens
+}
+
+// Spend returns the estimated dollars spent on completions by this client
+// so far, at the list price of each tier's model (see Cost).
+func (c *Client) Spend() float64 {
+	c.spendMu.Lock()
+	defer c.spendMu.Unlock()
+	return c.spent
+}
+
+// model returns the model tier calls go to.
+func (c *Client) model(tier Tier) string {
+	if tier == TierDeep {
+		return c.opts.DeepModel
+	}
+	return c.opts.FastModel
+}
+
+// startSpan starts the trace span of one call, a child of the span in
+// opts.Context, with the call's tier and model as attributes.
+func (c *Client) startSpan(name string, tier Tier, opts *CompleteOptions) (context.Context, *tracing.Span) {
+	return tracing.Start(opts.context(), name,
+		tracing.String("llm.tier", string(tier)),
+		tracing.String("llm.model", c.model(tier)))
+}
+
+// recordUsage adds a call's token usage to Spend and its span, and reports
+// it to Options.OnUsage.
+func (c *Client) recordUsage(span *tracing.Span, tier Tier, inputTokens, outputTokens int) {
+	span.SetAttributes(
+		tracing.Int("llm.input_tokens", inputTokens),
+		tracing.Int("llm.output_tokens", outputTokens))
+	c.spendMu.Lock()
+	c.spent += Cost(c.model(tier), inputTokens, outputTokens)
+	c.spendMu.Unlock()
+	if c.opts.OnUsage != nil {
+		c.opts.OnUsage(tier, inputTokens, outputTokens)
+	}
+}
+
+// semaphore returns the semaphore limiting in-flight calls of tier.
+func (c *Client) semaphore(tier Tier) chan struct{} {
+	if tier == TierDeep {
+		return c.deepSem
+	}
+	return c.fastSem
+}
+
 // Complete sends a prompt to the Anthropic Messages API and returns the text
 // from the first text content block.
 func (c *Client) Complete(prompt string, tier Tier, opts *CompleteOptions) (string, error) {
-	// Acquire semaphore slot.
-	c.sem <- struct{}{}
-	defer func() { <-c.sem }()
+	text, _, _, err := c.complete(prompt, tier, opts)
+	return text, err
+}
 
-	model := c.opts.FastModel
-	if tier == TierDeep {
-		model = c.opts.DeepModel
+// complete is Complete but also reports the API stop_reason and the
+// max_tokens cap that was sent, so CompleteJSON can classify truncation.
+func (c *Client) complete(prompt string, tier Tier, opts *CompleteOptions) (_, _ string, _ int, err error) {
+	ctx, span := c.startSpan("llm.complete", tier, opts)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	reqBody, err := c.newAPIRequest(prompt, tier, opts)
+	if err != nil {
+		return "", "", reqBody.MaxTokens, err
 	}
+	maxTokens := reqBody.MaxTokens
 
-	maxTokens := 4096
-	var system string
-	if opts != nil {
-		if opts.MaxTokens > 0 {
-			maxTokens = opts.MaxTokens
+	release, err := c.acquire(ctx, tier)
+	if err != nil {
+		return "", "", maxTokens, err
+	}
+	defer release()
+
+	resp, err := c.send(ctx, tier, reqBody)
+	if err != nil {
+		return "", "", maxTokens, err
+	}
+	respBytes, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return "", "", maxTokens, fmt.Errorf("llm: read response: %w", err)
+	}
+
+	var apiResp apiResponse
+	if err := json.Unmarshal(respBytes, &apiResp); err != nil {
+		return "", "", maxTokens, fmt.Errorf("llm: unmarshal response: %w", err)
+	}
+	c.recordUsage(span, tier, apiResp.Usage.InputTokens, apiResp.Usage.OutputTokens)
+
+	for _, block := range apiResp.Content {
+		if block.Type == "text" {
+			return block.Text, apiResp.StopReason, maxTokens, nil
 		}
-		system = opts.System
 	}
 
-	reqBody := apiRequest{
-		Model:     model,
-		MaxTokens: maxTokens,
-		System:    system,
+	return "", "", maxTokens, fmt.Errorf("llm: no text block in response")
+}
+
+// newAPIRequest builds the request body for a prompt. Its MaxTokens is set
+// even when it returns an error.
+func (c *Client) newAPIRequest(prompt string, tier Tier, opts *CompleteOptions) (apiRequest, error) {
+	req := apiRequest{
+		Model:     c.model(tier),
+		MaxTokens: c.MaxTokens(tier),
 		Messages: []apiMessage{
 			{Role: "user", Content: prompt},
 		},
 	}
+	if c.opts.APIKey == "" && c.opts.BaseURL == defaultBaseURL {
+		return req, fmt.Errorf("%w (set LLM_API_KEY or ANTHROPIC_API_KEY)", ErrNoAPIKey)
+	}
+	if opts != nil {
+		if opts.MaxTokens > 0 {
+			req.MaxTokens = opts.MaxTokens
+		}
+		req.System = opts.System
+	}
+	return req, nil
+}
+
+// acquire takes a slot of the tier's semaphore, giving up if ctx is done
+// first. The returned func releases the slot.
+func (c *Client) acquire(ctx context.Context, tier Tier) (func(), error) {
+	sem := c.semaphore(tier)
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("llm: waiting for a %s-tier slot: %w", tier, ctx.Err())
+	}
+}
 
+// send posts reqBody to the Messages API, retrying rate-limited requests
+// with exponential backoff, and returns the first 200 response with its
+// body unread.
+func (c *Client) send(ctx context.Context, tier Tier, reqBody apiRequest) (*http.Response, error) {
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("llm: marshal request: %w", err)
+		return nil, fmt.Errorf("llm: marshal request: %w", err)
 	}
 
 	endpoint := strings.TrimRight(c.opts.BaseURL, "/") + "/v1/messages"
@@ -210,41 +446,10 @@ func (c *Client) Complete(prompt string, tier Tier, opts *CompleteOptions) (stri
 	// OAuth: add ?beta=true query param (matches WebChat/Claude CLI pattern).
 	if c.opts.IsOAuth {
 		endpoint += "?beta=true"
-	}
-
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(bodyBytes))
-	if err != nil {
-		return "", fmt.Errorf("llm: create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Anthropic-Version", "2023-06-01")
-
-	if c.opts.IsOAuth {
 		// Refresh token if needed (check is inside the lock to avoid races).
 		if err := c.refreshOAuthToken(); err != nil {
-			return "", fmt.Errorf("oauth refresh: %w", err)
-		}
-
-		// Use current access token.
-		token := c.opts.APIKey
-		if c.oauth != nil {
-			c.oauth.mu.Lock()
-			token = c.oauth.accessToken
-			c.oauth.mu.Unlock()
-		}
-
-		req.Header.Set("Authorization", "Bearer "+token)
-		beta := OAuthBeta
-		if tier == TierDeep {
-			beta += "," + ThinkingBeta
+			return nil, fmt.Errorf("oauth refresh: %w", err)
 		}
-		req.Header.Set("Anthropic-Beta", beta)
-		req.Header.Set("User-Agent", UserAgent)
-		// Remove x-api-key if present (belt-and-suspenders).
-		req.Header.Del("X-Api-Key")
-	} else {
-		req.Header.Set("X-Api-Key", c.opts.APIKey)
 	}
 
 	const maxRetries = 3
@@ -254,76 +459,81 @@ func (c *Client) Complete(prompt string, tier Tier, opts *CompleteOptions) (stri
 		if attempt > 0 {
 			// Exponential backoff: 1s, 2s, 4s...
 			backoff := time.Duration(1<<uint(attempt-1)) * time.Second
-			time.Sleep(backoff)
-
-			// Rebuild the request body since the reader was consumed.
-			req, err = http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(bodyBytes))
-			if err != nil {
-				return "", fmt.Errorf("llm: create request: %w", err)
-			}
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("Anthropic-Version", "2023-06-01")
-			if c.opts.IsOAuth {
-				token := c.opts.APIKey
-				if c.oauth != nil {
-					c.oauth.mu.Lock()
-					token = c.oauth.accessToken
-					c.oauth.mu.Unlock()
-				}
-				req.Header.Set("Authorization", "Bearer "+token)
-				beta := OAuthBeta
-				if tier == TierDeep {
-					beta += "," + ThinkingBeta
-				}
-				req.Header.Set("Anthropic-Beta", beta)
-				req.Header.Set("User-Agent", UserAgent)
-				req.Header.Del("X-Api-Key")
-			} else {
-				req.Header.Set("X-Api-Key", c.opts.APIKey)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, fmt.Errorf("llm: send request: %w", ctx.Err())
 			}
 		}
 
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(bodyBytes))
+		if err != nil {
+			return nil, fmt.Errorf("llm: create request: %w", err)
+		}
+		c.setHeaders(req.Header, tier)
+
 		resp, err := c.http.Do(req)
 		if err != nil {
-			return "", fmt.Errorf("llm: send request: %w", err)
+			return nil, fmt.Errorf("llm: send request: %w", err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
 		}
 
 		respBytes, err := io.ReadAll(resp.Body)
 		resp.Body.Close()
 		if err != nil {
-			return "", fmt.Errorf("llm: read response: %w", err)
+			return nil, fmt.Errorf("llm: read response: %w", err)
 		}
 
 		if resp.StatusCode == http.StatusTooManyRequests {
 			lastErr = fmt.Errorf("llm: API returned status %d: %s", resp.StatusCode, string(respBytes))
 			continue
 		}
+		return nil, fmt.Errorf("llm: API returned status %d: %s", resp.StatusCode, string(respBytes))
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("llm: API returned status %d: %s", resp.StatusCode, string(respBytes))
-		}
+	return nil, lastErr
+}
 
-		var apiResp apiResponse
-		if err := json.Unmarshal(respBytes, &apiResp); err != nil {
-			return "", fmt.Errorf("llm: unmarshal response: %w", err)
-		}
+// setHeaders sets the content, version and authentication headers of a
+// Messages API request.
+func (c *Client) setHeaders(h http.Header, tier Tier) {
+	h.Set("Content-Type", "application/json")
+	h.Set("Anthropic-Version", "2023-06-01")
 
-		for _, block := range apiResp.Content {
-			if block.Type == "text" {
-				return block.Text, nil
-			}
+	if c.opts.IsOAuth {
+		// Use current access token.
+		token := c.opts.APIKey
+		if c.oauth != nil {
+			c.oauth.mu.Lock()
+			token = c.oauth.accessToken
+			c.oauth.mu.Unlock()
 		}
 
-		return "", fmt.Errorf("llm: no text block in response")
+		h.Set("Authorization", "Bearer "+token)
+		beta := OAuthBeta
+		if tier == TierDeep {
+			beta += "," + ThinkingBeta
+		}
+		h.Set("Anthropic-Beta", beta)
+		h.Set("User-Agent", UserAgent)
+		// Remove x-api-key if present (belt-and-suspenders).
+		h.Del("X-Api-Key")
+	} else {
+		h.Set("X-Api-Key", c.opts.APIKey)
 	}
-
-	return "", lastErr
+	setExtraHeaders(h, c.opts.ExtraHeaders)
 }
 
 // CompleteJSON calls Complete and extracts the first JSON object from the
 // response, stripping any surrounding markdown fences.
+//
+// If the object is never closed and the API reported stop_reason
+// "max_tokens" (or gave no stop reason at all), the returned error wraps
+// ErrTruncated.
 func (c *Client) CompleteJSON(prompt string, tier Tier, opts *CompleteOptions) (json.RawMessage, error) {
-	text, err := c.Complete(prompt, tier, opts)
+	text, stopReason, maxTokens, err := c.complete(prompt, tier, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -372,6 +582,9 @@ func (c *Client) CompleteJSON(prompt string, tier Tier, opts *CompleteOptions) (
 		}
 	}
 
+	if stopReason == "max_tokens" || stopReason == "" {
+		return nil, fmt.Errorf("llm: incomplete JSON object in response (max_tokens=%d): %w", maxTokens, ErrTruncated)
+	}
 	return nil, fmt.Errorf("llm: incomplete JSON object in response")
 }
 
@@ -2,7 +2,9 @@ package llm
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -145,6 +147,65 @@ func TestClient_Semaphore(t *testing.T) {
 	}
 }
 
+func TestClient_SemaphorePerTier(t *testing.T) {
+	var mu sync.Mutex
+	inflight := map[string]int{}
+	peak := map[string]int{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		inflight[body.Model]++
+		peak[body.Model] = max(peak[body.Model], inflight[body.Model])
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		inflight[body.Model]--
+		mu.Unlock()
+
+		fakeMessagesHandler("ok")(w, r)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Options{
+		APIKey:          "sk-test",
+		BaseURL:         srv.URL,
+		FastModel:       "fast",
+		DeepModel:       "deep",
+		MaxConcurrent:   10,
+		FastConcurrency: 3,
+		DeepConcurrency: 1,
+	})
+
+	var wg sync.WaitGroup
+	for i := range 12 {
+		tier := TierFast
+		if i%3 == 0 {
+			tier = TierDeep
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Complete("test", tier, nil); err != nil {
+				t.Errorf("%s request failed: %v", tier, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Each tier fills its own limit without being held to the other's.
+	if peak["fast"] != 3 {
+		t.Errorf("fast tier peak inflight = %d, want 3", peak["fast"])
+	}
+	if peak["deep"] != 1 {
+		t.Errorf("deep tier peak inflight = %d, want 1", peak["deep"])
+	}
+}
+
 func TestClient_OAuthHeaders(t *testing.T) {
 	var gotHeaders http.Header
 
@@ -222,6 +283,63 @@ func TestClient_OAuthHeaders_FastExcludesThinking(t *testing.T) {
 	}
 }
 
+func TestClient_ExtraHeaders(t *testing.T) {
+	var gotHeaders http.Header
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header.Clone()
+		fakeMessagesHandler("ok")(w, r)
+	}))
+	defer srv.Close()
+
+	extra := map[string]string{
+		"X-Corp-Auth":       "corp-secret",
+		"User-Agent":        "corp-proxy/1.0",
+		"X-Api-Key":         "overridden",
+		"anthropic-version": "1999-01-01",
+		"Authorization":     "Bearer overridden",
+	}
+
+	c := NewClient(Options{APIKey: "test-key", BaseURL: srv.URL, ExtraHeaders: extra})
+	if _, err := c.Complete("hi", TierFast, nil); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if got := gotHeaders.Get("X-Corp-Auth"); got != "corp-secret" {
+		t.Errorf("got X-Corp-Auth %q, want %q", got, "corp-secret")
+	}
+	if got := gotHeaders.Get("User-Agent"); got != "corp-proxy/1.0" {
+		t.Errorf("got User-Agent %q, want %q", got, "corp-proxy/1.0")
+	}
+	if got := gotHeaders.Get("X-Api-Key"); got != "test-key" {
+		t.Errorf("got X-Api-Key %q, want the configured key", got)
+	}
+	if got := gotHeaders.Get("Anthropic-Version"); got != "2023-06-01" {
+		t.Errorf("got Anthropic-Version %q, want %q", got, "2023-06-01")
+	}
+	if got := gotHeaders.Get("Authorization"); got != "" {
+		t.Errorf("API key mode sent Authorization %q", got)
+	}
+
+	// With OAuth the client's own User-Agent and bearer token win, and the
+	// deleted X-Api-Key stays deleted.
+	c = NewClient(Options{APIKey: "oauth-token", BaseURL: srv.URL, IsOAuth: true, ExtraHeaders: extra})
+	if _, err := c.Complete("hi", TierFast, nil); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if got := gotHeaders.Get("X-Corp-Auth"); got != "corp-secret" {
+		t.Errorf("OAuth: got X-Corp-Auth %q, want %q", got, "corp-secret")
+	}
+	if got := gotHeaders.Get("User-Agent"); got != UserAgent {
+		t.Errorf("OAuth: got User-Agent %q, want %q", got, UserAgent)
+	}
+	if got := gotHeaders.Get("Authorization"); got != "Bearer oauth-token" {
+		t.Errorf("OAuth: got Authorization %q", got)
+	}
+	if got := gotHeaders.Get("X-Api-Key"); got != "" {
+		t.Errorf("OAuth: got X-Api-Key %q, want none", got)
+	}
+}
+
 func TestClient_CompleteJSON(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -383,3 +501,132 @@ func TestClient_DeepModel(t *testing.T) {
 		t.Errorf("got model %q, want %q", gotReq.Model, "claude-opus-4-6")
 	}
 }
+
+func TestClient_PerTierMaxTokens(t *testing.T) {
+	var gotReq apiRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotReq)
+		fakeMessagesHandler("ok")(w, r)
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name string
+		opts Options
+		tier Tier
+		call *CompleteOptions
+		want int
+	}{
+		{"fast default", Options{}, TierFast, nil, DefaultFastMaxTokens},
+		{"deep default", Options{}, TierDeep, nil, DefaultDeepMaxTokens},
+		{"fast configured", Options{FastMaxTokens: 2048}, TierFast, nil, 2048},
+		{"deep configured", Options{DeepMaxTokens: 32000}, TierDeep, nil, 32000},
+		{"per-call override", Options{DeepMaxTokens: 32000}, TierDeep, &CompleteOptions{MaxTokens: 100}, 100},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.APIKey = "sk-test"
+			tc.opts.BaseURL = srv.URL
+			c := NewClient(tc.opts)
+
+			if _, err := c.Complete("hi", tc.tier, tc.call); err != nil {
+				t.Fatalf("Complete returned error: %v", err)
+			}
+			if gotReq.MaxTokens != tc.want {
+				t.Errorf("got max_tokens %d, want %d", gotReq.MaxTokens, tc.want)
+			}
+		})
+	}
+}
+
+func TestClient_CompleteJSON_Truncated(t *testing.T) {
+	cases := []struct {
+		name          string
+		stopReason    string
+		wantTruncated bool
+	}{
+		{"max_tokens stop reason", "max_tokens", true},
+		{"missing stop reason", "", true},
+		{"end_turn stop reason", "end_turn", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				resp := map[string]any{
+					"content": []map[string]any{
+						{"type": "text", "text": `{"wiring": [{"from": "a", "to": "b", "reas`},
+					},
+				}
+				if tc.stopReason != "" {
+					resp["stop_reason"] = tc.stopReason
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(resp)
+			}))
+			defer srv.Close()
+
+			c := NewClient(Options{APIKey: "sk-test", BaseURL: srv.URL})
+
+			_, err := c.CompleteJSON("give json", TierDeep, nil)
+			if err == nil {
+				t.Fatal("expected error for incomplete JSON")
+			}
+			if got := errors.Is(err, ErrTruncated); got != tc.wantTruncated {
+				t.Errorf("errors.Is(err, ErrTruncated) = %v, want %v (err: %v)", got, tc.wantTruncated, err)
+			}
+		})
+	}
+}
+
+func TestClient_OnUsageReportsTokens(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"content": []map[string]any{{"type": "text", "text": "ok"}},
+			"usage":   map[string]int{"input_tokens": 12, "output_tokens": 34},
+		})
+	}))
+	defer srv.Close()
+
+	var gotTier Tier
+	var gotIn, gotOut int
+	c := NewClient(Options{
+		APIKey:  "sk-test",
+		BaseURL: srv.URL,
+		OnUsage: func(tier Tier, in, out int) {
+			gotTier, gotIn, gotOut = tier, in, out
+		},
+	})
+
+	if _, err := c.Complete("hi", TierDeep, nil); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if gotTier != TierDeep || gotIn != 12 || gotOut != 34 {
+		t.Errorf("OnUsage got (%s, %d, %d), want (deep, 12, 34)", gotTier, gotIn, gotOut)
+	}
+	// The default deep model, claude-opus-4-6, lists at $5/$25 per million.
+	if got, want := c.Spend(), (12*5+34*25)/1e6; math.Abs(got-want) > 1e-12 {
+		t.Errorf("Spend() = %v, want %v", got, want)
+	}
+}
+
+func TestClient_NoAPIKey(t *testing.T) {
+	c := NewClient(Options{})
+	if _, err := c.Complete("test", TierFast, nil); !errors.Is(err, ErrNoAPIKey) {
+		t.Errorf("Complete without a key: err = %v, want ErrNoAPIKey", err)
+	}
+	if _, err := c.CompleteJSON("test", TierDeep, nil); !errors.Is(err, ErrNoAPIKey) {
+		t.Errorf("CompleteJSON without a key: err = %v, want ErrNoAPIKey", err)
+	}
+
+	// A custom base URL may be a keyless proxy.
+	srv := httptest.NewServer(fakeMessagesHandler("ok"))
+	defer srv.Close()
+	if _, err := NewClient(Options{BaseURL: srv.URL}).Complete("test", TierFast, nil); err != nil {
+		t.Errorf("Complete via keyless base URL: %v", err)
+	}
+}
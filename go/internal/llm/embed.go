@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultEmbeddingBatchSize is the number of inputs sent per request to an
+// OpenAI-compatible embeddings endpoint when Options does not set one.
+const DefaultEmbeddingBatchSize = 96
+
+// ErrNoEmbeddingModel is returned by Embed when Options.EmbeddingModel is
+// not set.
+var ErrNoEmbeddingModel = errors.New("llm: no embedding model configured")
+
+// Embed returns one embedding vector per text, in input order. Inputs are
+// sent in batches; each request holds a fast-tier slot like a fast-tier
+// completion. Ollama's /api/embeddings takes a single prompt, so it is
+// called once per text.
+func (c *Client) Embed(texts []string) ([][]float32, error) {
+	if c.opts.EmbeddingModel == "" {
+		return nil, ErrNoEmbeddingModel
+	}
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	out := make([][]float32, 0, len(texts))
+	if c.opts.EmbeddingProvider == "ollama" {
+		for _, text := range texts {
+			vec, err := c.embedOllama(text)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, vec)
+		}
+		return out, nil
+	}
+
+	size := c.opts.EmbeddingBatchSize
+	if size <= 0 {
+		size = DefaultEmbeddingBatchSize
+	}
+	for start := 0; start < len(texts); start += size {
+		end := min(start+size, len(texts))
+		vecs, err := c.embedOpenAI(texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vecs...)
+	}
+	return out, nil
+}
+
+// embedOpenAI embeds one batch against an OpenAI-compatible endpoint.
+func (c *Client) embedOpenAI(batch []string) ([][]float32, error) {
+	base := c.opts.EmbeddingBaseURL
+	if base == "" {
+		base = "https://api.openai.com"
+	}
+
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	body := map[string]any{"model": c.opts.EmbeddingModel, "input": batch}
+	if err := c.postEmbedding(strings.TrimRight(base, "/")+"/v1/embeddings", c.opts.EmbeddingAPIKey, body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(batch) {
+		return nil, fmt.Errorf("llm: embeddings: got %d vectors for %d inputs", len(resp.Data), len(batch))
+	}
+
+	// The API may return entries out of order; place them by index.
+	vecs := make([][]float32, len(batch))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(batch) || vecs[d.Index] != nil {
+			return nil, fmt.Errorf("llm: embeddings: bad index %d in response", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	return vecs, nil
+}
+
+// embedOllama embeds a single text with Ollama.
+func (c *Client) embedOllama(text string) ([]float32, error) {
+	base := c.opts.EmbeddingBaseURL
+	if base == "" {
+		base = "http://localhost:11434"
+	}
+
+	var resp struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	body := map[string]any{"model": c.opts.EmbeddingModel, "prompt": text}
+	if err := c.postEmbedding(strings.TrimRight(base, "/")+"/api/embeddings", "", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embedding) == 0 {
+		return nil, fmt.Errorf("llm: embeddings: empty vector in response")
+	}
+	return resp.Embedding, nil
+}
+
+// postEmbedding sends one embeddings request while holding a fast-tier slot
+// and decodes the JSON response into v.
+func (c *Client) postEmbedding(endpoint, apiKey string, body any, v any) error {
+	c.fastSem <- struct{}{}
+	defer func() { <-c.fastSem }()
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("llm: embeddings: marshal request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("llm: embeddings: create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("llm: embeddings: request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("llm: embeddings: read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("llm: embeddings: API error %d: %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.Unmarshal(respBody, v); err != nil {
+		return fmt.Errorf("llm: embeddings: decode response: %w", err)
+	}
+	return nil
+}
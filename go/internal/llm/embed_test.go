@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+const testEmbeddingDims = 8
+
+func TestEmbed_OpenAIBatches(t *testing.T) {
+	var mu sync.Mutex
+	var batchSizes []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer embed-key" {
+			t.Errorf("unexpected auth: %q", got)
+		}
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "text-embedding-3-small" {
+			t.Errorf("expected model text-embedding-3-small, got %q", body.Model)
+		}
+		mu.Lock()
+		batchSizes = append(batchSizes, len(body.Input))
+		mu.Unlock()
+
+		// Answer in reverse order; Embed must place vectors by index. The
+		// first component echoes the input so order can be checked.
+		var data []map[string]any
+		for i := len(body.Input) - 1; i >= 0; i-- {
+			vec := make([]float32, testEmbeddingDims)
+			var n float32
+			fmt.Sscanf(body.Input[i], "text-%g", &n)
+			vec[0] = n
+			data = append(data, map[string]any{"index": i, "embedding": vec})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer srv.Close()
+
+	c := NewClient(Options{
+		APIKey:             "anthropic-key",
+		EmbeddingModel:     "text-embedding-3-small",
+		EmbeddingBaseURL:   srv.URL,
+		EmbeddingAPIKey:    "embed-key",
+		EmbeddingBatchSize: 100,
+		MaxConcurrent:      1,
+	})
+
+	texts := make([]string, 250)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text-%d", i)
+	}
+	vecs, err := c.Embed(texts)
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+
+	if fmt.Sprint(batchSizes) != "[100 100 50]" {
+		t.Errorf("batch sizes = %v, want [100 100 50]", batchSizes)
+	}
+	if len(vecs) != len(texts) {
+		t.Fatalf("got %d vectors, want %d", len(vecs), len(texts))
+	}
+	for i, v := range vecs {
+		if len(v) != testEmbeddingDims {
+			t.Fatalf("vector %d has %d dims, want %d", i, len(v), testEmbeddingDims)
+		}
+		if v[0] != float32(i) {
+			t.Fatalf("vector %d belongs to input %v", i, v[0])
+		}
+	}
+}
+
+func TestEmbed_Ollama(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embeddings" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "nomic-embed-text" || body["prompt"] == nil {
+			t.Errorf("unexpected body: %v", body)
+		}
+		calls++
+		json.NewEncoder(w).Encode(map[string]any{"embedding": make([]float32, testEmbeddingDims)})
+	}))
+	defer srv.Close()
+
+	c := NewClient(Options{
+		EmbeddingModel:    "nomic-embed-text",
+		EmbeddingProvider: "ollama",
+		EmbeddingBaseURL:  srv.URL,
+	})
+	vecs, err := c.Embed([]string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if calls != 3 || len(vecs) != 3 || len(vecs[2]) != testEmbeddingDims {
+		t.Errorf("calls = %d, vectors = %d", calls, len(vecs))
+	}
+}
+
+func TestEmbed_Errors(t *testing.T) {
+	if _, err := NewClient(Options{}).Embed([]string{"x"}); !errors.Is(err, ErrNoEmbeddingModel) {
+		t.Errorf("no model: err = %v, want ErrNoEmbeddingModel", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"data": []any{}})
+	}))
+	defer srv.Close()
+	c := NewClient(Options{EmbeddingModel: "m", EmbeddingBaseURL: srv.URL})
+	if _, err := c.Embed([]string{"x", "y"}); err == nil {
+		t.Error("expected error when the response has too few vectors")
+	}
+}
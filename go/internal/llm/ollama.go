@@ -14,6 +14,7 @@ type OllamaProvider struct {
 	baseURL   string
 	fastModel string
 	deepModel string
+	headers   map[string]string // extra request headers; see Options.ExtraHeaders
 	http      http.Client
 }
 
@@ -55,6 +56,7 @@ func (p *OllamaProvider) Complete(ctx context.Context, req CompletionRequest) (s
 		return "", fmt.Errorf("ollama: create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	setExtraHeaders(httpReq.Header, p.headers)
 
 	resp, err := p.http.Do(httpReq)
 	if err != nil {
@@ -16,6 +16,7 @@ type OpenAIProvider struct {
 	apiKey    string
 	fastModel string
 	deepModel string
+	headers   map[string]string // extra request headers; see Options.ExtraHeaders
 	http      http.Client
 }
 
@@ -66,6 +67,7 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (s
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	setExtraHeaders(httpReq.Header, p.headers)
 
 	resp, err := p.http.Do(httpReq)
 	if err != nil {
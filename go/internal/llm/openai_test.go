@@ -64,3 +64,32 @@ func TestOpenAIProvider_DeepTier(t *testing.T) {
 		t.Errorf("expected 'gpt-4o' for deep tier, got '%s'", receivedModel)
 	}
 }
+
+func TestOpenAIProvider_ExtraHeaders(t *testing.T) {
+	var gotHeaders http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header.Clone()
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": "ok"}}},
+		})
+	}))
+	defer srv.Close()
+
+	p, err := NewProvider("openai", Options{
+		BaseURL:      srv.URL,
+		APIKey:       "test-key",
+		ExtraHeaders: map[string]string{"X-Corp-Auth": "corp-secret", "Authorization": "Bearer overridden"},
+	})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	if _, err := p.Complete(context.Background(), CompletionRequest{User: "test"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := gotHeaders.Get("X-Corp-Auth"); got != "corp-secret" {
+		t.Errorf("got X-Corp-Auth %q, want %q", got, "corp-secret")
+	}
+	if got := gotHeaders.Get("Authorization"); got != "Bearer test-key" {
+		t.Errorf("got Authorization %q, want the configured key", got)
+	}
+}
@@ -0,0 +1,53 @@
+package llm
+
+import "strings"
+
+// Price is what a model charges, in US dollars per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// prices are Anthropic list prices by model name prefix, most specific
+// first.
+var prices = []struct {
+	prefix string
+	price  Price
+}{
+	{"claude-opus-4-6", Price{5, 25}},
+	{"claude-opus-4-5", Price{5, 25}},
+	{"claude-opus-4", Price{15, 75}},
+	{"claude-sonnet-4", Price{3, 15}},
+	{"claude-haiku-4-5", Price{1, 5}},
+	{"claude-3-5-haiku", Price{0.8, 4}},
+}
+
+// familyPrices price models no prefix matches by their family name.
+var familyPrices = map[string]Price{
+	"opus":   {15, 75},
+	"sonnet": {3, 15},
+	"haiku":  {1, 5},
+}
+
+// PriceOf returns the list price of model. Models carto does not know are
+// priced like Sonnet, so a spend estimate is never zero.
+func PriceOf(model string) Price {
+	for _, p := range prices {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.price
+		}
+	}
+	for family, price := range familyPrices {
+		if strings.Contains(model, family) {
+			return price
+		}
+	}
+	return familyPrices["sonnet"]
+}
+
+// Cost returns the estimated dollar cost of a call to model that used the
+// given input and output tokens.
+func Cost(model string, inputTokens, outputTokens int) float64 {
+	p := PriceOf(model)
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
@@ -38,13 +38,17 @@ func NewProvider(name string, opts Options) (Provider, error) {
 				baseURL = "https://api.openai.com"
 			}
 		}
-		return NewOpenAIProvider(baseURL, opts.APIKey, opts.FastModel, opts.DeepModel), nil
+		p := NewOpenAIProvider(baseURL, opts.APIKey, opts.FastModel, opts.DeepModel)
+		p.headers = opts.ExtraHeaders
+		return p, nil
 	case "ollama":
 		baseURL := opts.BaseURL
 		if baseURL == "" {
 			baseURL = "http://localhost:11434"
 		}
-		return NewOllamaProvider(baseURL, opts.FastModel, opts.DeepModel), nil
+		p := NewOllamaProvider(baseURL, opts.FastModel, opts.DeepModel)
+		p.headers = opts.ExtraHeaders
+		return p, nil
 	default:
 		return nil, fmt.Errorf("llm: unknown provider %q (supported: anthropic, openai, openrouter, ollama)", name)
 	}
@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ErrSchemaMismatch means a response was valid JSON but not the shape the
+// prompt asked for, such as an object where an array belongs or a
+// required field left out.
+var ErrSchemaMismatch = errors.New("llm: response does not match the expected schema")
+
+// JSON types a Field can require.
+const (
+	TypeString = "string"
+	TypeNumber = "number"
+	TypeBool   = "boolean"
+	TypeArray  = "array"
+	TypeObject = "object"
+)
+
+// Field is one top-level field of the JSON object a prompt asks for.
+type Field struct {
+	Name     string
+	Type     string // one of the Type constants
+	Items    string // for TypeArray: the type of every element; "" allows any
+	Required bool   // absent or null is a mismatch
+}
+
+// Schema is the expected shape of a JSON object response. Fields it does
+// not list are allowed and unchecked.
+type Schema []Field
+
+// Validate checks that raw is a JSON object matching the schema. The
+// returned error wraps ErrSchemaMismatch and lists every problem found.
+func (s Schema) Validate(raw json.RawMessage) error {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return fmt.Errorf("%w: not a JSON object", ErrSchemaMismatch)
+	}
+
+	var problems []string
+	for _, f := range s {
+		v, ok := obj[f.Name]
+		if !ok || jsonType(v) == "null" {
+			if f.Required {
+				problems = append(problems, fmt.Sprintf("%q is missing", f.Name))
+			}
+			continue
+		}
+		if got := jsonType(v); got != f.Type {
+			problems = append(problems, fmt.Sprintf("%q must be %s %s, got %s", f.Name, article(f.Type), f.Type, got))
+			continue
+		}
+		if f.Type != TypeArray || f.Items == "" {
+			continue
+		}
+		var items []json.RawMessage
+		json.Unmarshal(v, &items)
+		for i, item := range items {
+			if got := jsonType(item); got != f.Items {
+				problems = append(problems, fmt.Sprintf("%q[%d] must be %s %s, got %s", f.Name, i, article(f.Items), f.Items, got))
+				break
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrSchemaMismatch, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// jsonType returns the JSON type of a raw value, by its first character.
+func jsonType(v json.RawMessage) string {
+	s := strings.TrimSpace(string(v))
+	if s == "" {
+		return "null"
+	}
+	switch s[0] {
+	case '"':
+		return TypeString
+	case '[':
+		return TypeArray
+	case '{':
+		return TypeObject
+	case 't', 'f':
+		return TypeBool
+	case 'n':
+		return "null"
+	}
+	return TypeNumber
+}
+
+// article returns "an" for types starting with a vowel, else "a".
+func article(typ string) string {
+	if strings.ContainsRune("aeiou", rune(typ[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// CompleteValidJSON calls complete (typically a client's CompleteJSON)
+// and validates the response against schema. On a mismatch it asks once
+// more, telling the model what was wrong; if the second response does
+// not match either, the returned error wraps ErrSchemaMismatch.
+func CompleteValidJSON(complete func(string, Tier, *CompleteOptions) (json.RawMessage, error), prompt string, tier Tier, opts *CompleteOptions, schema Schema) (json.RawMessage, error) {
+	raw, err := complete(prompt, tier, opts)
+	if err != nil {
+		return nil, err
+	}
+	mismatch := schema.Validate(raw)
+	if mismatch == nil {
+		return raw, nil
+	}
+
+	log.Printf("llm: warning: %v; retrying with a correction", mismatch)
+	correction := prompt + "\n\nYour previous response did not match the requested JSON format (" +
+		strings.TrimPrefix(mismatch.Error(), ErrSchemaMismatch.Error()+": ") +
+		"). Respond again with a JSON object in exactly the format requested."
+	raw, err = complete(correction, tier, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.Validate(raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	schema := Schema{
+		{Name: "intent", Type: TypeString, Required: true},
+		{Name: "zones", Type: TypeArray, Items: TypeObject},
+		{Name: "count", Type: TypeNumber},
+	}
+	tests := []struct {
+		name string
+		raw  string
+		want string // substring of the error; "" for valid
+	}{
+		{"valid", `{"intent": "x", "zones": [{"name": "a"}], "count": 2, "extra": true}`, ""},
+		{"optional fields absent or null", `{"intent": "x", "zones": null}`, ""},
+		{"required missing", `{"zones": []}`, `"intent" is missing`},
+		{"required null", `{"intent": null}`, `"intent" is missing`},
+		{"object for array", `{"intent": "x", "zones": {"name": "a"}}`, `"zones" must be an array, got object`},
+		{"wrong element", `{"intent": "x", "zones": [{"name": "a"}, "b"]}`, `"zones"[1] must be an object, got string`},
+		{"number as string", `{"intent": "x", "count": "2"}`, `"count" must be a number, got string`},
+		{"not an object", `["intent"]`, "not a JSON object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tt.raw))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSchemaMismatch) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate = %v, want ErrSchemaMismatch with %q", err, tt.want)
+			}
+		})
+	}
+}
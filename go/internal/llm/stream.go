@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// streamEvent is the JSON data of one server-sent event from a streaming
+// /v1/messages call. Only the fields carto reads are decoded.
+type streamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage apiUsage `json:"usage"`
+	} `json:"message"` // message_start
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"` // content_block_delta
+	Usage apiUsage `json:"usage"` // message_delta
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"` // error
+}
+
+// CompleteStream is Complete over the streaming Messages API: onDelta is
+// called with each piece of response text as it arrives, in order, and
+// the full text is returned at the end. It holds a slot of the tier's
+// semaphore until the stream ends, and a canceled opts.Context stops it
+// mid-stream. onDelta may be nil.
+func (c *Client) CompleteStream(prompt string, tier Tier, opts *CompleteOptions, onDelta func(delta string)) (_ string, err error) {
+	ctx, span := c.startSpan("llm.stream", tier, opts)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	reqBody, err := c.newAPIRequest(prompt, tier, opts)
+	if err != nil {
+		return "", err
+	}
+	reqBody.Stream = true
+
+	release, err := c.acquire(ctx, tier)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	resp, err := c.send(ctx, tier, reqBody)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var (
+		text  strings.Builder
+		usage apiUsage
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // event:, comments and blank separators
+		}
+		var ev streamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &ev); err != nil {
+			return text.String(), fmt.Errorf("llm: decode stream event: %w", err)
+		}
+
+		switch ev.Type {
+		case "message_start":
+			usage.InputTokens = ev.Message.Usage.InputTokens
+		case "content_block_delta":
+			if ev.Delta.Type != "text_delta" {
+				continue
+			}
+			text.WriteString(ev.Delta.Text)
+			if onDelta != nil {
+				onDelta(ev.Delta.Text)
+			}
+		case "message_delta":
+			usage.OutputTokens = ev.Usage.OutputTokens
+		case "error":
+			return text.String(), fmt.Errorf("llm: stream error %s: %s", ev.Error.Type, ev.Error.Message)
+		case "message_stop":
+			c.recordUsage(span, tier, usage.InputTokens, usage.OutputTokens)
+			return text.String(), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return text.String(), fmt.Errorf("llm: read stream: %w", err)
+	}
+	return text.String(), fmt.Errorf("llm: stream ended before message_stop")
+}
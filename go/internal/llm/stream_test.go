@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_CompleteStream_DeliversDeltasInOrder(t *testing.T) {
+	var gotReq apiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotReq)
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type": "message_start", "message": {"usage": {"input_tokens": 12, "output_tokens": 1}}}`,
+			`{"type": "content_block_start", "index": 0, "content_block": {"type": "text", "text": ""}}`,
+			`{"type": "ping"}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "Hello"}}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": ", "}}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "world"}}`,
+			`{"type": "content_block_stop", "index": 0}`,
+			`{"type": "message_delta", "delta": {"stop_reason": "end_turn"}, "usage": {"output_tokens": 3}}`,
+			`{"type": "message_stop"}`,
+		}
+		for _, ev := range events {
+			var typ struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal([]byte(ev), &typ)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, ev)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	var usage [2]int
+	c := NewClient(Options{
+		APIKey:  "test-key",
+		BaseURL: srv.URL,
+		OnUsage: func(_ Tier, in, out int) { usage = [2]int{in, out} },
+	})
+
+	var deltas []string
+	text, err := c.CompleteStream("hi", TierFast, nil, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("CompleteStream: %v", err)
+	}
+	if !gotReq.Stream {
+		t.Error("request did not set stream: true")
+	}
+	if want := []string{"Hello", ", ", "world"}; fmt.Sprint(deltas) != fmt.Sprint(want) {
+		t.Errorf("deltas = %q, want %q", deltas, want)
+	}
+	if text != "Hello, world" {
+		t.Errorf("text = %q, want %q", text, "Hello, world")
+	}
+	if usage != [2]int{12, 3} {
+		t.Errorf("usage = %v, want [12 3]", usage)
+	}
+}
+
+func TestClient_CompleteStream_CanceledWhileWaitingForSlot(t *testing.T) {
+	c := NewClient(Options{APIKey: "test-key", BaseURL: "http://127.0.0.1:1", MaxConcurrent: 1})
+	c.fastSem <- struct{}{} // the only slot is taken
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.CompleteStream("hi", TierFast, &CompleteOptions{Context: ctx}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
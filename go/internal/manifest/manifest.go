@@ -1,6 +1,8 @@
 package manifest
 
 import (
+	"bytes"
+	"compress/gzip"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -8,26 +10,81 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"syscall"
 	"time"
 )
 
+// FileName is the manifest file inside a project's .carto/ directory. It
+// holds gzip-compressed JSON.
+const FileName = "manifest.json.gz"
+
+// legacyFileName is the uncompressed manifest written by older versions.
+// Load falls back to it, and Save removes it once the compressed file is
+// written.
+const legacyFileName = "manifest.json"
+
+// lockFileName is the lock file next to the manifest. Save holds it
+// exclusively for its whole read-merge-write, so concurrent writers, such
+// as a CLI index alongside the server, take turns instead of overwriting
+// each other's entries.
+const lockFileName = "manifest.lock"
+
+// Path returns the manifest path for a project root.
+func Path(projectRoot string) string {
+	return filepath.Join(projectRoot, ".carto", FileName)
+}
+
+// racyWindow is how recent a file's mtime may be for it to go unrecorded:
+// a file written again within the filesystem's timestamp granularity of
+// being hashed can keep the same mtime with different content.
+const racyWindow = 2 * time.Second
+
 // FileEntry tracks the hash and metadata of a single indexed file.
 type FileEntry struct {
 	Hash      string    `json:"hash"`
 	Size      int64     `json:"size"`
+	ModTime   int64     `json:"mod_time,omitempty"` // UnixNano mtime when hashed; 0 if unknown
 	IndexedAt time.Time `json:"indexed_at"`
 }
 
+// unchanged reports whether info matches the recorded size and mtime, so
+// the file need not be rehashed.
+func (e FileEntry) unchanged(info os.FileInfo) bool {
+	return e.ModTime != 0 && info.Size() == e.Size && info.ModTime().UnixNano() == e.ModTime
+}
+
+// stableModTime returns info's mtime in UnixNano, or 0 if it is within
+// racyWindow of now and so not safe to record.
+func stableModTime(info os.FileInfo) int64 {
+	if time.Since(info.ModTime()) < racyWindow {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// FailedFile records a file that failed to chunk or analyze on recent runs.
+type FailedFile struct {
+	Reason     string    `json:"reason"`
+	Attempts   int       `json:"attempts"`       // consecutive failures of the same content
+	Hash       string    `json:"hash,omitempty"` // content hash at the last failure
+	LastFailed time.Time `json:"last_failed"`
+}
+
 // Manifest tracks the state of all indexed files for a project.
 type Manifest struct {
-	Version   string               `json:"version"`
-	Project   string               `json:"project"`
-	IndexedAt time.Time            `json:"indexed_at"`
-	Files     map[string]FileEntry `json:"files"` // keyed by relative path
-	path      string               // on-disk path to manifest.json (not serialized)
-	mu        sync.Mutex           // protects concurrent in-memory access (not serialized)
+	Version     string                `json:"version"`
+	Project     string                `json:"project"`
+	IndexedAt   time.Time             `json:"indexed_at"`
+	Files       map[string]FileEntry  `json:"files"`                  // keyed by relative path
+	FailedFiles map[string]FailedFile `json:"failed_files,omitempty"` // keyed by relative path; see RecordFailure
+	IgnoreHash  string                `json:"ignore_hash,omitempty"`  // scanner.IgnoreHash at last index; a change forces a full rescan
+	path        string                // on-disk path to manifest.json.gz (not serialized)
+	rehashAll   bool                  // skip the mtime+size fast path in DetectChanges (not serialized)
+	touched     map[string]time.Time  // files updated or removed since the last Save, and when (not serialized)
+	touchedFail map[string]time.Time  // failure records changed since the last Save, and when (not serialized)
+	mu          sync.Mutex            // protects concurrent in-memory access (not serialized)
 }
 
 // ChangeSet describes what changed since the last index.
@@ -38,46 +95,38 @@ type ChangeSet struct {
 }
 
 // NewManifest creates a new empty manifest for a project.
-// The manifest file path is set to {projectRoot}/.carto/manifest.json.
+// The manifest file path is set to {projectRoot}/.carto/manifest.json.gz.
 func NewManifest(projectRoot, projectName string) *Manifest {
 	return &Manifest{
 		Version: "1.0",
 		Project: projectName,
 		Files:   make(map[string]FileEntry),
-		path:    filepath.Join(projectRoot, ".carto", "manifest.json"),
+		path:    Path(projectRoot),
 	}
 }
 
-// Load reads a manifest from {projectRoot}/.carto/manifest.json with a shared
-// file lock so concurrent readers don't conflict with writers.
-// If the file does not exist, it returns a new empty manifest (not an error).
+// Load reads a manifest from {projectRoot}/.carto/manifest.json.gz, or from
+// the uncompressed manifest.json written by older versions. Save replaces
+// the file atomically; the shared lock Load takes on it also keeps reads
+// from overlapping the in-place writes of older versions.
+// If neither file exists, it returns a new empty manifest (not an error).
 func Load(projectRoot string) (*Manifest, error) {
-	p := filepath.Join(projectRoot, ".carto", "manifest.json")
-
-	f, err := os.Open(p)
+	data, err := readLocked(Path(projectRoot), true)
+	if os.IsNotExist(err) {
+		data, err = readLocked(filepath.Join(projectRoot, ".carto", legacyFileName), false)
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
 			return NewManifest(projectRoot, ""), nil
 		}
-		return nil, fmt.Errorf("open manifest: %w", err)
-	}
-	defer f.Close()
-
-	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH); err != nil {
-		return nil, fmt.Errorf("lock manifest for reading: %w", err)
-	}
-	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
-
-	data, err := io.ReadAll(f)
-	if err != nil {
-		return nil, fmt.Errorf("read manifest: %w", err)
+		return nil, err
 	}
 
 	var m Manifest
 	if err := json.Unmarshal(data, &m); err != nil {
 		return nil, fmt.Errorf("unmarshal manifest: %w", err)
 	}
-	m.path = p
+	m.path = Path(projectRoot)
 
 	if m.Files == nil {
 		m.Files = make(map[string]FileEntry)
@@ -86,37 +135,196 @@ func Load(projectRoot string) (*Manifest, error) {
 	return &m, nil
 }
 
-// Save writes the manifest to disk as JSON with an exclusive file lock
-// to prevent concurrent writes from corrupting the file.
-// It creates the .carto/ directory if it does not already exist.
+// readLocked reads path under a shared lock, gunzipping it when
+// compressed is set. A missing file returns an error satisfying
+// os.IsNotExist.
+func readLocked(path string, compressed bool) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("open manifest: %w", err)
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH); err != nil {
+		return nil, fmt.Errorf("lock manifest for reading: %w", err)
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	var r io.Reader = f
+	if compressed {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("read manifest: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	return data, nil
+}
+
+// Save writes the manifest to disk as gzip-compressed JSON, then removes
+// any legacy uncompressed manifest. It creates the .carto/ directory if it
+// does not already exist.
+//
+// Save holds an exclusive lock on .carto/manifest.lock while it merges in
+// whatever another process saved since this manifest was loaded (see
+// mergeSaved) and replaces the file. The new file is written beside the
+// old one and renamed over it, so readers see either the old manifest or
+// the new one, never a partial write.
 func (m *Manifest) Save() error {
 	dir := filepath.Dir(m.path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return fmt.Errorf("create manifest dir: %w", err)
 	}
 
+	unlock, err := lockExclusive(filepath.Join(dir, lockFileName))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	m.mu.Lock()
+	m.mergeSaved()
 	m.IndexedAt = time.Now()
-	data, err := json.MarshalIndent(m, "", "  ")
+	data, err := json.Marshal(m)
 	m.mu.Unlock()
 	if err != nil {
 		return fmt.Errorf("marshal manifest: %w", err)
 	}
 
-	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("compress manifest: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compress manifest: %w", err)
+	}
+
+	if err := writeAtomic(m.path, buf.Bytes()); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.touched, m.touchedFail = nil, nil
+	m.mu.Unlock()
+
+	legacy := filepath.Join(dir, legacyFileName)
+	if err := os.Remove(legacy); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove legacy manifest: %w", err)
+	}
+	return nil
+}
+
+// mergeSaved folds the manifest on disk into m, so that what another
+// process saved since m was loaded is not lost. For a file m has not
+// touched since its last Save, the disk wins: entries another writer added
+// or re-indexed are taken, and entries it removed are dropped. For a file
+// m updated or removed, m wins unless the disk entry was indexed later
+// still. Failure records merge the same way by LastFailed. An unreadable
+// manifest on disk has nothing to contribute and is replaced.
+// The caller holds m.mu and the manifest lock.
+func (m *Manifest) mergeSaved() {
+	data, err := readLocked(m.path, true)
 	if err != nil {
-		return fmt.Errorf("open manifest for writing: %w", err)
+		return
+	}
+	var saved Manifest
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return
 	}
-	defer f.Close()
 
+	for relPath, e := range saved.Files {
+		cur, ok := m.Files[relPath]
+		at, touched := m.touched[relPath]
+		switch {
+		case ok && !e.IndexedAt.After(cur.IndexedAt):
+		case !ok && touched && !e.IndexedAt.After(at):
+		default:
+			m.Files[relPath] = e
+		}
+	}
+	for relPath := range m.Files {
+		if _, ok := saved.Files[relPath]; !ok {
+			if _, touched := m.touched[relPath]; !touched {
+				delete(m.Files, relPath)
+			}
+		}
+	}
+
+	for relPath, f := range saved.FailedFiles {
+		cur, ok := m.FailedFiles[relPath]
+		at, touched := m.touchedFail[relPath]
+		switch {
+		case ok && !f.LastFailed.After(cur.LastFailed):
+		case !ok && touched && !f.LastFailed.After(at):
+		default:
+			if m.FailedFiles == nil {
+				m.FailedFiles = make(map[string]FailedFile)
+			}
+			m.FailedFiles[relPath] = f
+		}
+	}
+	for relPath := range m.FailedFiles {
+		if _, ok := saved.FailedFiles[relPath]; !ok {
+			if _, touched := m.touchedFail[relPath]; !touched {
+				delete(m.FailedFiles, relPath)
+			}
+		}
+	}
+}
+
+// lockExclusive takes an exclusive lock on the file at path, creating it
+// if needed, and returns the func that releases it.
+func lockExclusive(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open manifest lock: %w", err)
+	}
 	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
-		return fmt.Errorf("lock manifest: %w", err)
+		f.Close()
+		return nil, fmt.Errorf("lock manifest: %w", err)
 	}
-	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// writeAtomic replaces the file at path with data by writing a temporary
+// file in the same directory and renaming it over path.
+func writeAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create manifest temp file: %w", err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op once renamed
 
 	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
 		return fmt.Errorf("write manifest: %w", err)
 	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace manifest: %w", err)
+	}
 	return nil
 }
 
@@ -136,8 +344,20 @@ func (m *Manifest) ComputeHash(filePath string) (string, error) {
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// SetRehashAll makes DetectChanges hash every tracked file instead of
+// trusting an unchanged size and mtime.
+func (m *Manifest) SetRehashAll(rehash bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rehashAll = rehash
+}
+
 // DetectChanges compares a list of current file paths (relative to projectRoot)
 // against the manifest to determine what has been added, modified, or removed.
+// A tracked file whose size and mtime match its entry is taken as unchanged
+// without reading it (see SetRehashAll); otherwise its SHA-256 decides, so a
+// file that was only touched is not reported as modified. Such files get
+// their recorded mtime refreshed so the next run can skip them again.
 func (m *Manifest) DetectChanges(currentFiles []string, projectRoot string) (*ChangeSet, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -158,6 +378,14 @@ func (m *Manifest) DetectChanges(currentFiles []string, projectRoot string) (*Ch
 		}
 
 		absPath := filepath.Join(projectRoot, relPath)
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return nil, fmt.Errorf("stat %s: %w", relPath, err)
+		}
+		if !m.rehashAll && entry.unchanged(info) {
+			continue
+		}
+
 		hash, err := m.ComputeHash(absPath)
 		if err != nil {
 			return nil, fmt.Errorf("compute hash for %s: %w", relPath, err)
@@ -165,6 +393,11 @@ func (m *Manifest) DetectChanges(currentFiles []string, projectRoot string) (*Ch
 
 		if hash != entry.Hash {
 			cs.Modified = append(cs.Modified, relPath)
+			continue
+		}
+		if entry.Size == info.Size() {
+			entry.ModTime = stableModTime(info)
+			m.Files[relPath] = entry
 		}
 	}
 
@@ -174,6 +407,7 @@ func (m *Manifest) DetectChanges(currentFiles []string, projectRoot string) (*Ch
 			cs.Removed = append(cs.Removed, relPath)
 		}
 	}
+	sort.Strings(cs.Removed)
 
 	return cs, nil
 }
@@ -182,6 +416,7 @@ func (m *Manifest) DetectChanges(currentFiles []string, projectRoot string) (*Ch
 func (m *Manifest) UpdateFile(relPath, hash string, size int64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.touched = stamp(m.touched, relPath)
 	m.Files[relPath] = FileEntry{
 		Hash:      hash,
 		Size:      size,
@@ -189,11 +424,75 @@ func (m *Manifest) UpdateFile(relPath, hash string, size int64) {
 	}
 }
 
-// RemoveFile deletes a file entry from the manifest.
+// UpdateFileInfo is UpdateFile for a file just hashed, also recording its
+// mtime (unless written too recently to trust) so DetectChanges can skip
+// rehashing it while it stays untouched.
+func (m *Manifest) UpdateFileInfo(relPath, hash string, info os.FileInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.touched = stamp(m.touched, relPath)
+	m.Files[relPath] = FileEntry{
+		Hash:      hash,
+		Size:      info.Size(),
+		ModTime:   stableModTime(info),
+		IndexedAt: time.Now(),
+	}
+}
+
+// RemoveFile deletes a file entry, and any failure record, from the manifest.
 func (m *Manifest) RemoveFile(relPath string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.Files, relPath)
+	delete(m.FailedFiles, relPath)
+	m.touched = stamp(m.touched, relPath)
+	m.touchedFail = stamp(m.touchedFail, relPath)
+}
+
+// stamp records relPath in set with the current time, allocating set if
+// needed, and returns it.
+func stamp(set map[string]time.Time, relPath string) map[string]time.Time {
+	if set == nil {
+		set = make(map[string]time.Time)
+	}
+	set[relPath] = time.Now()
+	return set
+}
+
+// RecordFailure notes that the file with content hash failed for reason,
+// returning its updated record. Attempts count consecutive failures of the
+// same content: a changed hash starts again at one.
+func (m *Manifest) RecordFailure(relPath, hash, reason string) FailedFile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.FailedFiles == nil {
+		m.FailedFiles = make(map[string]FailedFile)
+	}
+	f := m.FailedFiles[relPath]
+	if f.Hash != hash {
+		f.Attempts = 0
+	}
+	f.Reason, f.Hash, f.LastFailed = reason, hash, time.Now()
+	f.Attempts++
+	m.FailedFiles[relPath] = f
+	m.touchedFail = stamp(m.touchedFail, relPath)
+	return f
+}
+
+// Failure returns the failure record of a file, if it has one.
+func (m *Manifest) Failure(relPath string) (FailedFile, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.FailedFiles[relPath]
+	return f, ok
+}
+
+// ClearFailure drops the failure record of a file that has now succeeded.
+func (m *Manifest) ClearFailure(relPath string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.FailedFiles, relPath)
+	m.touchedFail = stamp(m.touchedFail, relPath)
 }
 
 // IsEmpty returns true if no files are tracked in the manifest.
@@ -1,22 +1,25 @@
 package manifest
 
 import (
+	"compress/gzip"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewManifest(t *testing.T) {
 	root := "/tmp/fake-project"
 	m := NewManifest(root, "my-project")
 
-	// path must end with .carto/manifest.json
-	wantSuffix := filepath.Join(".carto", "manifest.json")
+	// path must end with .carto/manifest.json.gz
+	wantSuffix := filepath.Join(".carto", "manifest.json.gz")
 	if !strings.HasSuffix(m.path, wantSuffix) {
 		t.Errorf("path = %q, want suffix %q", m.path, wantSuffix)
 	}
@@ -88,6 +91,64 @@ func TestSaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestSave_WritesGzip(t *testing.T) {
+	root := t.TempDir()
+	m := NewManifest(root, "gz-project")
+	m.UpdateFile("a.go", "h1", 10)
+	if err := m.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	f, err := os.Open(Path(root))
+	if err != nil {
+		t.Fatalf("open %s: %v", FileName, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("manifest is not gzip: %v", err)
+	}
+	var raw Manifest
+	if err := json.NewDecoder(zr).Decode(&raw); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if raw.Project != "gz-project" || raw.Files["a.go"].Hash != "h1" {
+		t.Errorf("unexpected contents: project %q, files %v", raw.Project, raw.Files)
+	}
+}
+
+func TestLoad_LegacyUncompressed(t *testing.T) {
+	root := t.TempDir()
+	cartoDir := filepath.Join(root, ".carto")
+	os.MkdirAll(cartoDir, 0o755)
+	legacy := filepath.Join(cartoDir, "manifest.json")
+	os.WriteFile(legacy, []byte(`{"version":"1.0","project":"old","files":{"a.go":{"hash":"h1","size":3}}}`), 0o644)
+
+	m, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if m.Project != "old" || m.Files["a.go"].Hash != "h1" {
+		t.Fatalf("legacy manifest not read: project %q, files %v", m.Project, m.Files)
+	}
+
+	// Saving migrates to the compressed file and drops the legacy one.
+	m.UpdateFile("b.go", "h2", 4)
+	if err := m.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy manifest.json should be removed, stat err = %v", err)
+	}
+	reloaded, err := Load(root)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(reloaded.Files) != 2 {
+		t.Errorf("reloaded %d files, want 2", len(reloaded.Files))
+	}
+}
+
 func TestLoad_NoFile(t *testing.T) {
 	root := t.TempDir()
 
@@ -260,6 +321,104 @@ func TestDetectChanges_Mixed(t *testing.T) {
 	}
 }
 
+// recordFile writes content to relPath under root with an mtime safely in
+// the past and records it in m as freshly indexed.
+func recordFile(t *testing.T, m *Manifest, root, relPath, content string) {
+	t.Helper()
+	path := filepath.Join(root, relPath)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", relPath, err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("chtimes %s: %v", relPath, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat %s: %v", relPath, err)
+	}
+	hash, err := m.ComputeHash(path)
+	if err != nil {
+		t.Fatalf("ComputeHash: %v", err)
+	}
+	m.UpdateFileInfo(relPath, hash, info)
+}
+
+func TestDetectChanges_TouchedButUnchanged(t *testing.T) {
+	root := t.TempDir()
+	m := NewManifest(root, "test")
+	recordFile(t, m, root, "touched.go", "package a\n")
+	recordFile(t, m, root, "edited.go", "package b\n")
+
+	// touched.go only gets a new mtime; edited.go gets new content too.
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(root, "touched.go"), now, now); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "edited.go"), []byte("package c\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cs, err := m.DetectChanges([]string{"touched.go", "edited.go"}, root)
+	if err != nil {
+		t.Fatalf("DetectChanges: %v", err)
+	}
+	if len(cs.Modified) != 1 || cs.Modified[0] != "edited.go" {
+		t.Errorf("Modified = %v, want [edited.go]", cs.Modified)
+	}
+}
+
+func TestDetectChanges_MtimeFastPath(t *testing.T) {
+	root := t.TempDir()
+	m := NewManifest(root, "test")
+	recordFile(t, m, root, "same.go", "package a\n")
+	if m.Files["same.go"].ModTime == 0 {
+		t.Fatal("UpdateFileInfo did not record the mtime")
+	}
+
+	// Rewrite the file with same-size content and restore its mtime: the
+	// fast path trusts size+mtime and does not read it...
+	path := filepath.Join(root, "same.go")
+	info, _ := os.Stat(path)
+	if err := os.WriteFile(path, []byte("package b\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	cs, err := m.DetectChanges([]string{"same.go"}, root)
+	if err != nil {
+		t.Fatalf("DetectChanges: %v", err)
+	}
+	if len(cs.Modified) != 0 {
+		t.Errorf("fast path: Modified = %v, want empty", cs.Modified)
+	}
+
+	// ...while SetRehashAll hashes it and finds the change.
+	m.SetRehashAll(true)
+	cs, err = m.DetectChanges([]string{"same.go"}, root)
+	if err != nil {
+		t.Fatalf("DetectChanges: %v", err)
+	}
+	if len(cs.Modified) != 1 {
+		t.Errorf("rehash all: Modified = %v, want [same.go]", cs.Modified)
+	}
+}
+
+func TestUpdateFileInfo_SkipsRecentMtime(t *testing.T) {
+	root := t.TempDir()
+	m := NewManifest(root, "test")
+	path := filepath.Join(root, "fresh.go")
+	if err := os.WriteFile(path, []byte("package a\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	info, _ := os.Stat(path)
+	m.UpdateFileInfo("fresh.go", "hash", info)
+	if got := m.Files["fresh.go"].ModTime; got != 0 {
+		t.Errorf("ModTime = %d, want 0 for a file written just now", got)
+	}
+}
+
 func TestUpdateFile(t *testing.T) {
 	root := t.TempDir()
 	m := NewManifest(root, "test")
@@ -337,3 +496,101 @@ func TestManifest_ConcurrentSave(t *testing.T) {
 		t.Error("expected files after concurrent saves")
 	}
 }
+
+// Separate manifests saved at once, as by concurrent index runs on one
+// project, must each keep the others' entries rather than the last writer
+// winning.
+func TestSave_ConcurrentWritersKeepAllEntries(t *testing.T) {
+	dir := t.TempDir()
+	const writers = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			m, err := Load(dir)
+			if err != nil {
+				t.Errorf("writer %d: load: %v", idx, err)
+				return
+			}
+			m.UpdateFile(fmt.Sprintf("file%d.go", idx), fmt.Sprintf("hash%d", idx), int64(idx))
+			if err := m.Save(); err != nil {
+				t.Errorf("writer %d: save: %v", idx, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	f, err := os.Open(Path(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("manifest is not gzip: %v", err)
+	}
+	var loaded Manifest
+	if err := json.NewDecoder(zr).Decode(&loaded); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v", err)
+	}
+	if len(loaded.Files) != writers {
+		t.Errorf("manifest has %d files, want %d", len(loaded.Files), writers)
+	}
+	for i := 0; i < writers; i++ {
+		e, ok := loaded.Files[fmt.Sprintf("file%d.go", i)]
+		if !ok || e.Hash != fmt.Sprintf("hash%d", i) {
+			t.Errorf("file%d.go: got %+v, present=%v", i, e, ok)
+		}
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, ".carto", "*.tmp"))
+	if len(leftovers) > 0 {
+		t.Errorf("temp files left behind: %v", leftovers)
+	}
+}
+
+// A writer's stale copy of an entry must not undo another writer's removal
+// or newer update.
+func TestSave_MergeKeepsRemovalsAndNewerEntries(t *testing.T) {
+	dir := t.TempDir()
+	base := NewManifest(dir, "test")
+	base.UpdateFile("gone.go", "h1", 1)
+	base.UpdateFile("shared.go", "old", 1)
+	if err := base.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := Load(dir)
+	b, _ := Load(dir)
+
+	a.RemoveFile("gone.go")
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+	b.UpdateFile("shared.go", "new", 2)
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	// a saves again with its stale shared.go; b's newer entry must stay.
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Files["shared.go"].Hash; got != "new" {
+		t.Errorf("shared.go hash = %q, want the newer %q", got, "new")
+	}
+	// b loaded gone.go before a removed it but never touched it, so its
+	// saves must not bring it back.
+	if _, ok := loaded.Files["gone.go"]; ok {
+		t.Error("gone.go was resurrected by a writer that loaded it before its removal")
+	}
+	if a.Files["shared.go"].Hash != "new" {
+		t.Error("Save should leave the manifest holding the merged entries")
+	}
+}
@@ -0,0 +1,118 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/divyekant/carto/internal/scanner"
+)
+
+// MetaFileName is the project metadata file inside a project's .carto/
+// directory. It is kept apart from the manifest so that re-indexing,
+// which rewrites the manifest, never touches user-assigned metadata.
+const MetaFileName = "meta.json"
+
+// MetaPath returns the metadata path for a project root.
+func MetaPath(projectRoot string) string {
+	return filepath.Join(projectRoot, ".carto", MetaFileName)
+}
+
+// Meta is user-assigned metadata for a project.
+type Meta struct {
+	Tags map[string]string `json:"tags,omitempty"` // e.g. team=payments
+}
+
+// LoadMeta reads {projectRoot}/.carto/meta.json. If the file does not
+// exist, it returns empty metadata (not an error).
+func LoadMeta(projectRoot string) (*Meta, error) {
+	data, err := os.ReadFile(MetaPath(projectRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Meta{Tags: map[string]string{}}, nil
+		}
+		return nil, fmt.Errorf("read project meta: %w", err)
+	}
+
+	var m Meta
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("unmarshal project meta: %w", err)
+	}
+	if m.Tags == nil {
+		m.Tags = map[string]string{}
+	}
+	return &m, nil
+}
+
+// SaveMeta writes m to {projectRoot}/.carto/meta.json.
+func SaveMeta(projectRoot string, m *Meta) error {
+	if err := os.MkdirAll(filepath.Join(projectRoot, ".carto"), 0o755); err != nil {
+		return fmt.Errorf("create meta dir: %w", err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal project meta: %w", err)
+	}
+	if err := os.WriteFile(MetaPath(projectRoot), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write project meta: %w", err)
+	}
+	return nil
+}
+
+// Matches reports whether the project carries every tag in filters with
+// the same value.
+func (m *Meta) Matches(filters map[string]string) bool {
+	for k, v := range filters {
+		if got, ok := m.Tags[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ProjectFilter selects projects for batch operations such as index-all.
+// The zero value selects every project.
+type ProjectFilter struct {
+	Name string            // glob the project name must match, e.g. "billing-*"; empty matches all
+	Tags map[string]string // tags the project must carry, all of them (see Matches)
+}
+
+// Selects reports whether the project called name, stored at projectRoot,
+// passes f. Its metadata is only read when f filters on tags.
+func (f ProjectFilter) Selects(name, projectRoot string) bool {
+	if f.Name != "" && !scanner.GlobMatch(f.Name, name) {
+		return false
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+	meta, err := LoadMeta(projectRoot)
+	return err == nil && meta.Matches(f.Tags)
+}
+
+// ParseTag splits a "key=value" tag. The key must be non-empty; the value
+// may be empty.
+func ParseTag(s string) (key, value string, err error) {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("invalid tag %q: want key=value", s)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+// ParseTags parses a list of "key=value" tags into a map. A later tag
+// overrides an earlier one with the same key.
+func ParseTags(list []string) (map[string]string, error) {
+	tags := make(map[string]string, len(list))
+	for _, s := range list {
+		k, v, err := ParseTag(s)
+		if err != nil {
+			return nil, err
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
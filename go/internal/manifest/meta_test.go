@@ -0,0 +1,102 @@
+package manifest
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMeta_SaveLoadRoundTrip(t *testing.T) {
+	root := t.TempDir()
+
+	m, err := LoadMeta(root)
+	if err != nil {
+		t.Fatalf("LoadMeta on missing file: %v", err)
+	}
+	if len(m.Tags) != 0 {
+		t.Fatalf("Tags = %v, want empty", m.Tags)
+	}
+
+	m.Tags["team"] = "payments"
+	m.Tags["tier"] = "1"
+	if err := SaveMeta(root, m); err != nil {
+		t.Fatalf("SaveMeta: %v", err)
+	}
+
+	got, err := LoadMeta(root)
+	if err != nil {
+		t.Fatalf("LoadMeta: %v", err)
+	}
+	if got.Tags["team"] != "payments" || got.Tags["tier"] != "1" || len(got.Tags) != 2 {
+		t.Errorf("Tags = %v, want team=payments tier=1", got.Tags)
+	}
+
+	// Saving the manifest leaves the metadata alone.
+	mf := NewManifest(root, "demo")
+	mf.UpdateFile("main.go", "abc", 1)
+	if err := mf.Save(); err != nil {
+		t.Fatalf("mf.Save: %v", err)
+	}
+	if _, err := os.Stat(MetaPath(root)); err != nil {
+		t.Fatalf("meta.json gone after manifest save: %v", err)
+	}
+}
+
+func TestMeta_Matches(t *testing.T) {
+	m := &Meta{Tags: map[string]string{"team": "payments", "env": "prod"}}
+	tests := []struct {
+		filters map[string]string
+		want    bool
+	}{
+		{nil, true},
+		{map[string]string{"team": "payments"}, true},
+		{map[string]string{"team": "payments", "env": "prod"}, true},
+		{map[string]string{"team": "search"}, false},
+		{map[string]string{"owner": "alice"}, false},
+	}
+	for _, tt := range tests {
+		if got := m.Matches(tt.filters); got != tt.want {
+			t.Errorf("Matches(%v) = %v, want %v", tt.filters, got, tt.want)
+		}
+	}
+}
+
+func TestProjectFilter_Selects(t *testing.T) {
+	root := t.TempDir()
+	if err := SaveMeta(root, &Meta{Tags: map[string]string{"team": "payments"}}); err != nil {
+		t.Fatal(err)
+	}
+	untagged := t.TempDir()
+
+	tests := []struct {
+		filter ProjectFilter
+		name   string
+		root   string
+		want   bool
+	}{
+		{ProjectFilter{}, "billing-api", untagged, true},
+		{ProjectFilter{Name: "billing-*"}, "billing-api", untagged, true},
+		{ProjectFilter{Name: "billing-*"}, "search", untagged, false},
+		{ProjectFilter{Tags: map[string]string{"team": "payments"}}, "billing-api", root, true},
+		{ProjectFilter{Tags: map[string]string{"team": "payments"}}, "billing-api", untagged, false},
+		{ProjectFilter{Name: "search*", Tags: map[string]string{"team": "payments"}}, "billing-api", root, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Selects(tt.name, tt.root); got != tt.want {
+			t.Errorf("%+v.Selects(%q) = %v, want %v", tt.filter, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	if k, v, err := ParseTag("team=payments"); err != nil || k != "team" || v != "payments" {
+		t.Errorf("ParseTag(team=payments) = %q, %q, %v", k, v, err)
+	}
+	if k, v, err := ParseTag("team="); err != nil || k != "team" || v != "" {
+		t.Errorf("ParseTag(team=) = %q, %q, %v", k, v, err)
+	}
+	for _, bad := range []string{"team", "=payments", ""} {
+		if _, _, err := ParseTag(bad); err == nil {
+			t.Errorf("ParseTag(%q) should fail", bad)
+		}
+	}
+}
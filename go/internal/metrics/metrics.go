@@ -0,0 +1,225 @@
+// Package metrics is a small, dependency-free collector that renders
+// counters, gauges and histograms in the Prometheus text exposition format
+// (version 0.0.4). It covers exactly what the Carto server exports; it is
+// not a general-purpose client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the Content-Type of the exposition format written by
+// Registry.Write.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultDurationBuckets are histogram upper bounds, in seconds, suited to
+// indexing runs that take from seconds to an hour.
+var DefaultDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600}
+
+// collector is implemented by every metric kind.
+type collector interface {
+	write(w io.Writer)
+}
+
+// Registry holds metrics in registration order.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	r.collectors = append(r.collectors, c)
+	r.mu.Unlock()
+}
+
+// Write renders every registered metric in Prometheus text format.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// meta holds the name, help text and label names shared by all kinds.
+type meta struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (m meta) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, kind)
+}
+
+// labelKey joins label values into a map key.
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// formatLabels renders {a="x",b="y"} for the given names and values, plus
+// optional extra pairs (used for histogram "le").
+func formatLabels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var parts []string
+	for i, n := range names {
+		parts = append(parts, n+"="+strconv.Quote(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// ── Counter ───────────────────────────────────────────────────────────────
+
+// CounterVec is a monotonically increasing counter partitioned by labels.
+// With no label names it behaves as a single counter.
+type CounterVec struct {
+	meta
+	mu     sync.Mutex
+	values map[string]float64
+	order  map[string][]string
+}
+
+// NewCounter registers a counter with the given label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		meta:   meta{name: name, help: help, labels: labels},
+		values: make(map[string]float64),
+		order:  make(map[string][]string),
+	}
+	r.register(c)
+	return c
+}
+
+// Add increases the counter for the given label values by delta. Negative
+// deltas are ignored.
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 || len(labelValues) != len(c.labels) {
+		return
+	}
+	key := labelKey(labelValues)
+	c.mu.Lock()
+	c.values[key] += delta
+	c.order[key] = labelValues
+	c.mu.Unlock()
+}
+
+// Inc increments the counter for the given label values by one.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.header(w, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.labels) == 0 {
+		fmt.Fprintf(w, "%s %s\n", c.name, formatFloat(c.values[""]))
+		return
+	}
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, c.order[key]), formatFloat(c.values[key]))
+	}
+}
+
+// ── Gauge ─────────────────────────────────────────────────────────────────
+
+// GaugeFunc is a gauge whose value is computed at scrape time.
+type GaugeFunc struct {
+	meta
+	fn func() float64
+}
+
+// NewGaugeFunc registers a gauge that calls fn on every scrape.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{meta: meta{name: name, help: help}, fn: fn}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.header(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+// ── Histogram ─────────────────────────────────────────────────────────────
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	meta
+	buckets []float64
+	mu      sync.Mutex
+	counts  []uint64 // per bucket, non-cumulative; last entry is +Inf
+	sum     float64
+	count   uint64
+}
+
+// NewHistogram registers a histogram with the given bucket upper bounds.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &Histogram{
+		meta:    meta{name: name, help: help},
+		buckets: b,
+		counts:  make([]uint64, len(b)+1),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records a single value.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.header(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var cumulative uint64
+	for i, le := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(nil, nil, "le", formatFloat(le)), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(nil, nil, "le", "+Inf"), h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.name, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.name, h.count)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WritesPrometheusText(t *testing.T) {
+	reg := NewRegistry()
+	calls := reg.NewCounter("demo_calls_total", "Calls.", "tier")
+	plain := reg.NewCounter("demo_plain_total", "Plain.")
+	hist := reg.NewHistogram("demo_seconds", "Durations.", []float64{1, 10})
+	reg.NewGaugeFunc("demo_active", "Active.", func() float64 { return 3 })
+
+	calls.Inc("fast")
+	calls.Add(2, "deep")
+	calls.Add(-5, "deep") // ignored: counters never decrease
+	plain.Inc()
+	hist.Observe(0.5)
+	hist.Observe(1)
+	hist.Observe(42)
+
+	var buf bytes.Buffer
+	reg.Write(&buf)
+	out := buf.String()
+
+	for _, want := range []string{
+		"# HELP demo_calls_total Calls.\n# TYPE demo_calls_total counter\n",
+		`demo_calls_total{tier="deep"} 2`,
+		`demo_calls_total{tier="fast"} 1`,
+		"demo_plain_total 1",
+		`demo_seconds_bucket{le="1"} 2`,
+		`demo_seconds_bucket{le="10"} 2`,
+		`demo_seconds_bucket{le="+Inf"} 3`,
+		"demo_seconds_sum 43.5",
+		"demo_seconds_count 3",
+		"# TYPE demo_active gauge\ndemo_active 3",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n%s", want, out)
+		}
+	}
+}
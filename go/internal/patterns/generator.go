@@ -13,12 +13,23 @@ import (
 // Input contains the data needed to generate pattern files.
 type Input struct {
 	ProjectName string
+	Namespace   string          // Memories source tag prefix; empty means "carto"
 	Blueprint   string          // from SystemSynthesis
 	Patterns    []string        // from SystemSynthesis
 	Zones       []Zone          // aggregated from all modules
 	Modules     []ModuleSummary // brief info about each module
 }
 
+// sourceRoot returns the source tag prefix of the project's entries,
+// {namespace}/{project}, without a trailing slash.
+func (in Input) sourceRoot() string {
+	ns := strings.Trim(in.Namespace, "/")
+	if ns == "" {
+		ns = "carto"
+	}
+	return ns + "/" + in.ProjectName
+}
+
 // Zone is a business domain grouping.
 type Zone struct {
 	Name   string
@@ -100,7 +111,7 @@ func GenerateCLAUDE(input Input) string {
 	b.WriteString("```bash\n")
 	b.WriteString("curl -s -X POST \"$MEMORIES_URL/search\" \\\n")
 	b.WriteString("  -H \"Content-Type: application/json\" -H \"X-API-Key: $MEMORIES_API_KEY\" \\\n")
-	fmt.Fprintf(&b, "  -d '{\"query\": \"functionName OR fileName\", \"k\": 5, \"hybrid\": true, \"source_prefix\": \"carto/%s/\"}'\n", input.ProjectName)
+	fmt.Fprintf(&b, "  -d '{\"query\": \"functionName OR fileName\", \"k\": 5, \"hybrid\": true, \"source_prefix\": \"%s/\"}'\n", input.sourceRoot())
 	b.WriteString("```\n\n")
 	b.WriteString("**What to search for:** the function/class you are changing, the file path, ")
 	b.WriteString("and related component names to check wiring dependencies.\n\n")
@@ -109,8 +120,8 @@ func GenerateCLAUDE(input Input) string {
 	b.WriteString("### After Changes: Write Back\n\n")
 	b.WriteString("After completing a feature, fix, or refactor, write the change back so the index ")
 	b.WriteString("stays current without a full re-index.\n\n")
-	b.WriteString("**Source tag convention:** `carto/")
-	b.WriteString(input.ProjectName)
+	b.WriteString("**Source tag convention:** `")
+	b.WriteString(input.sourceRoot())
 	b.WriteString("/{module}/layer:{layer}`\n\n")
 	b.WriteString("Use `layer:atoms` for code-level changes. Use `layer:wiring` for new cross-component dependencies.\n\n")
 	b.WriteString("**Atom format** (match this exactly):\n")
@@ -124,14 +135,14 @@ func GenerateCLAUDE(input Input) string {
 	b.WriteString("```\n")
 	b.WriteString("memory_add({\n")
 	b.WriteString("  text: \"handleAuth (function) in src/auth/handler.go:15-42\\nSummary: Validates JWT tokens and extracts user claims.\\nImports: jwt, context\\nExports: handleAuth\",\n")
-	fmt.Fprintf(&b, "  source: \"carto/%s/MODULE_NAME/layer:atoms\"\n", input.ProjectName)
+	fmt.Fprintf(&b, "  source: \"%s/MODULE_NAME/layer:atoms\"\n", input.sourceRoot())
 	b.WriteString("})\n")
 	b.WriteString("```\n\n")
 	b.WriteString("**Using curl** (fallback):\n")
 	b.WriteString("```bash\n")
 	b.WriteString("curl -s -X POST \"$MEMORIES_URL/memory/add\" \\\n")
 	b.WriteString("  -H \"Content-Type: application/json\" -H \"X-API-Key: $MEMORIES_API_KEY\" \\\n")
-	fmt.Fprintf(&b, "  -d '{\"text\": \"SUMMARY\", \"source\": \"carto/%s/MODULE_NAME/layer:atoms\"}'\n", input.ProjectName)
+	fmt.Fprintf(&b, "  -d '{\"text\": \"SUMMARY\", \"source\": \"%s/MODULE_NAME/layer:atoms\"}'\n", input.sourceRoot())
 	b.WriteString("```\n\n")
 	b.WriteString("Replace `MODULE_NAME` with the relevant module.\n\n")
 	b.WriteString("**When to write back:** new functions/types, changed signatures, new dependencies, ")
@@ -142,7 +153,7 @@ func GenerateCLAUDE(input Input) string {
 	b.WriteString("The Coding Patterns section above reflects conventions discovered across this codebase. ")
 	b.WriteString("Follow them when writing new code. When you discover a new pattern, add it:\n\n")
 	b.WriteString("```\n")
-	fmt.Fprintf(&b, "memory_add({ text: \"Pattern: description\", source: \"carto/%s/_system/layer:patterns\" })\n", input.ProjectName)
+	fmt.Fprintf(&b, "memory_add({ text: \"Pattern: description\", source: \"%s/_system/layer:patterns\" })\n", input.sourceRoot())
 	b.WriteString("```\n\n")
 
 	b.WriteString("---\n*Generated by Carto v1.1.0*\n")
@@ -194,9 +205,9 @@ func GenerateCursorRules(input Input) string {
 	b.WriteString("Working with the Carto Index:\n")
 	b.WriteString("This project is indexed by Carto. Query before editing, write back after changes.\n\n")
 	b.WriteString("Query before editing (search for the function/file you are changing):\n")
-	fmt.Fprintf(&b, "  curl -s -X POST \"$MEMORIES_URL/search\" -H \"Content-Type: application/json\" -H \"X-API-Key: $MEMORIES_API_KEY\" -d '{\"query\": \"SEARCH_TERM\", \"k\": 5, \"hybrid\": true, \"source_prefix\": \"carto/%s/\"}'\n\n", input.ProjectName)
+	fmt.Fprintf(&b, "  curl -s -X POST \"$MEMORIES_URL/search\" -H \"Content-Type: application/json\" -H \"X-API-Key: $MEMORIES_API_KEY\" -d '{\"query\": \"SEARCH_TERM\", \"k\": 5, \"hybrid\": true, \"source_prefix\": \"%s/\"}'\n\n", input.sourceRoot())
 	b.WriteString("Write back after changes (use atom format: name (kind) in file:line-line | Summary | Imports | Exports):\n")
-	fmt.Fprintf(&b, "  curl -s -X POST \"$MEMORIES_URL/memory/add\" -H \"Content-Type: application/json\" -H \"X-API-Key: $MEMORIES_API_KEY\" -d '{\"text\": \"SUMMARY\", \"source\": \"carto/%s/MODULE/layer:atoms\"}'\n\n", input.ProjectName)
+	fmt.Fprintf(&b, "  curl -s -X POST \"$MEMORIES_URL/memory/add\" -H \"Content-Type: application/json\" -H \"X-API-Key: $MEMORIES_API_KEY\" -d '{\"text\": \"SUMMARY\", \"source\": \"%s/MODULE/layer:atoms\"}'\n\n", input.sourceRoot())
 	b.WriteString("Layers: atoms (code units), wiring (dependencies), patterns (conventions).\n")
 	b.WriteString("Write back after: new functions, changed signatures, bug fixes, refactors, deletions.\n")
 	b.WriteString("Follow the Patterns section above when writing new code.\n\n")
@@ -0,0 +1,81 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/divyekant/carto/internal/llm"
+)
+
+// ErrBudgetExceeded means the run's estimated LLM spend crossed
+// Config.MaxCost. Run returns it with the partial Result.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Spender is implemented by LLM clients that estimate the dollars their
+// calls have cost so far, such as *llm.Client. Config.MaxCost needs one.
+type Spender interface {
+	Spend() float64
+}
+
+// budgetClient wraps the run's LLM client to enforce Config.MaxCost. It
+// cancels the run once the spend crosses the cap, or before a call that,
+// at the average cost of the calls so far, would cross it. Calls already
+// in flight still finish, so the spend can overshoot the cap by up to
+// one call per worker.
+type budgetClient struct {
+	LLMClient
+	spender Spender
+	limit   float64
+	cancel  context.CancelCauseFunc
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (b *budgetClient) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error) {
+	if err := b.check(true); err != nil {
+		return nil, err
+	}
+	raw, err := b.LLMClient.CompleteJSON(prompt, tier, opts)
+	b.mu.Lock()
+	b.calls++
+	b.mu.Unlock()
+	b.check(false)
+	return raw, err
+}
+
+func (b *budgetClient) Spend() float64 {
+	return b.spender.Spend()
+}
+
+// check cancels the run if the spend has crossed the cap or, with
+// project set, if one more call of average cost would cross it.
+func (b *budgetClient) check(project bool) error {
+	spent := b.spender.Spend()
+	b.mu.Lock()
+	calls := b.calls
+	b.mu.Unlock()
+
+	projected := spent
+	if project && calls > 0 {
+		projected += spent / float64(calls)
+	}
+	if projected <= b.limit {
+		return nil
+	}
+	b.cancel(ErrBudgetExceeded)
+	return fmt.Errorf("pipeline: %w", ErrBudgetExceeded)
+}
+
+// budgetError is the error a run stopped by its budget returns, with the
+// spend so far.
+func budgetError(cfg Config) error {
+	spent := 0.0
+	if s, ok := cfg.LLMClient.(Spender); ok {
+		spent = s.Spend()
+	}
+	return fmt.Errorf("pipeline: %w: spent $%.2f of the $%.2f cap", ErrBudgetExceeded, spent, cfg.MaxCost)
+}
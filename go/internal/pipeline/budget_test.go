@@ -0,0 +1,62 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pricedLLM is a mockLLM whose every call costs a fixed price.
+type pricedLLM struct {
+	mockLLM
+	price float64
+}
+
+func (m *pricedLLM) Spend() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return float64(m.calls) * m.price
+}
+
+func TestRun_MaxCostHaltsRun(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/costly\n\ngo 1.21\n"), 0o644)
+	var src strings.Builder
+	src.WriteString("package main\n")
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&src, "\nfunc f%d() int {\n\treturn %d\n}\n", i, i)
+	}
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte(src.String()), 0o644)
+
+	llmClient := &pricedLLM{price: 0.01}
+	mem := &mockMemories{healthy: true}
+	result, err := Run(Config{
+		ProjectName:    "costly",
+		RootPath:       dir,
+		LLMClient:      llmClient,
+		MemoriesClient: mem,
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+		MaxCost:        0.05,
+	})
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "spent $0.05 of the $0.05 cap") {
+		t.Errorf("error should report the spend so far: %v", err)
+	}
+	if result == nil {
+		t.Fatal("expected a partial result with ErrBudgetExceeded")
+	}
+	// A $0.05 cap at $0.01 a call allows five calls; the run must stop
+	// there rather than analyze all 30 functions, deep analysis and synthesis.
+	if llmClient.calls != 5 {
+		t.Errorf("made %d LLM calls, want the run to halt after 5", llmClient.calls)
+	}
+	if result.Synthesis != nil {
+		t.Error("run reached synthesis despite the exhausted budget")
+	}
+}
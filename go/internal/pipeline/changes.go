@@ -0,0 +1,162 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/divyekant/carto/internal/analyzer"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+// Changes describes how a run's architecture differs from what was stored
+// by the previous run. Modules with nothing stored are new and not
+// reported; only their absence of history is known.
+type Changes struct {
+	PatternsAdded   []string       `json:"patterns_added,omitempty"`
+	PatternsRemoved []string       `json:"patterns_removed,omitempty"`
+	IntentsChanged  []IntentChange `json:"intents_changed,omitempty"`
+	ZonesAdded      []ZoneChange   `json:"zones_added,omitempty"`
+	ZonesRemoved    []ZoneChange   `json:"zones_removed,omitempty"`
+}
+
+// IntentChange records a module whose analyzed intent changed.
+type IntentChange struct {
+	Module string `json:"module"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// ZoneChange names a zone that appeared in or disappeared from a module.
+type ZoneChange struct {
+	Module string `json:"module"`
+	Zone   string `json:"zone"`
+}
+
+// Empty reports whether no architectural change was detected.
+func (c *Changes) Empty() bool {
+	return c == nil || len(c.PatternsAdded)+len(c.PatternsRemoved)+
+		len(c.IntentsChanged)+len(c.ZonesAdded)+len(c.ZonesRemoved) == 0
+}
+
+// previousState is the architecture stored by the last run, loaded before
+// the store phase overwrites it.
+type previousState struct {
+	patterns []string
+	intents  map[string]string
+	zones    map[string][]analyzer.Zone
+}
+
+// loadPrevious reads the stored patterns and each module's intent and
+// zones. Read failures are logged and treated as "nothing stored", so a
+// diff is best-effort and never fails the run.
+func loadPrevious(store *storage.Store, modules []string) previousState {
+	prev := previousState{
+		intents: make(map[string]string),
+		zones:   make(map[string][]analyzer.Zone),
+	}
+
+	var patterns []string
+	if latestJSON(store, "_system", storage.LayerPatterns, &patterns) {
+		prev.patterns = patterns
+	}
+	for _, mod := range modules {
+		var zones []analyzer.Zone
+		if latestJSON(store, mod, storage.LayerZones, &zones) {
+			prev.zones[mod] = zones
+		}
+		results, err := store.RetrieveLayer(mod, storage.LayerIntent)
+		if err != nil {
+			log.Printf("pipeline: warning: failed to load previous intent for %s: %v", mod, err)
+			continue
+		}
+		if len(results) > 0 {
+			prev.intents[mod] = results[len(results)-1].Text
+		}
+	}
+	return prev
+}
+
+// latestJSON decodes the most recently stored entry of a layer into v. It
+// reports whether an entry was found and decoded.
+func latestJSON(store *storage.Store, module, layer string, v any) bool {
+	results, err := store.RetrieveLayer(module, layer)
+	if err != nil {
+		log.Printf("pipeline: warning: failed to load previous %s for %s: %v", layer, module, err)
+		return false
+	}
+	for i := len(results) - 1; i >= 0; i-- {
+		if json.Unmarshal([]byte(results[i].Text), v) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// diffArchitecture compares this run's analyses and synthesis with the
+// previous state. It returns nil when nothing changed.
+func diffArchitecture(prev previousState, analyses []analyzer.ModuleAnalysis, synthesis *analyzer.SystemSynthesis) *Changes {
+	c := &Changes{}
+
+	if synthesis != nil && prev.patterns != nil {
+		c.PatternsAdded, c.PatternsRemoved = diffStrings(prev.patterns, synthesis.Patterns)
+	}
+
+	for _, ma := range analyses {
+		mod := ma.ModuleName
+		if before, ok := prev.intents[mod]; ok && strings.TrimSpace(before) != strings.TrimSpace(ma.ModuleIntent) {
+			c.IntentsChanged = append(c.IntentsChanged, IntentChange{Module: mod, Before: before, After: ma.ModuleIntent})
+		}
+		prevZones, ok := prev.zones[mod]
+		if !ok {
+			continue
+		}
+		added, removed := diffStrings(zoneNames(prevZones), zoneNames(ma.Zones))
+		for _, z := range added {
+			c.ZonesAdded = append(c.ZonesAdded, ZoneChange{Module: mod, Zone: z})
+		}
+		for _, z := range removed {
+			c.ZonesRemoved = append(c.ZonesRemoved, ZoneChange{Module: mod, Zone: z})
+		}
+	}
+
+	if c.Empty() {
+		return nil
+	}
+	return c
+}
+
+func zoneNames(zones []analyzer.Zone) []string {
+	names := make([]string, len(zones))
+	for i, z := range zones {
+		names[i] = z.Name
+	}
+	return names
+}
+
+// diffStrings returns the entries of after missing from before (added) and
+// of before missing from after (removed), compared case- and
+// whitespace-insensitively and sorted.
+func diffStrings(before, after []string) (added, removed []string) {
+	norm := func(s string) string { return strings.ToLower(strings.Join(strings.Fields(s), " ")) }
+	inBefore := make(map[string]bool, len(before))
+	for _, s := range before {
+		inBefore[norm(s)] = true
+	}
+	inAfter := make(map[string]bool, len(after))
+	for _, s := range after {
+		inAfter[norm(s)] = true
+		if !inBefore[norm(s)] && norm(s) != "" {
+			added = append(added, s)
+		}
+	}
+	for _, s := range before {
+		if !inAfter[norm(s)] && norm(s) != "" {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
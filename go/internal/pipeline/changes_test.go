@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/divyekant/carto/internal/llm"
+)
+
+// evolvingLLM answers like mockLLM but with configurable module analysis
+// and synthesis, so consecutive runs can see a different architecture.
+type evolvingLLM struct {
+	mockLLM
+	zones    string
+	intent   string
+	patterns string
+}
+
+func (m *evolvingLLM) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error) {
+	if tier != llm.TierDeep {
+		return m.mockLLM.CompleteJSON(prompt, tier, opts)
+	}
+	if strings.Contains(prompt, "Synthesize") {
+		return json.RawMessage(fmt.Sprintf(`{"blueprint": "b", "patterns": %s}`, m.patterns)), nil
+	}
+	return json.RawMessage(fmt.Sprintf(`{"module_name": "", "wiring": [], "zones": %s, "module_intent": %q}`, m.zones, m.intent)), nil
+}
+
+func TestRun_ReportsArchitectureChanges(t *testing.T) {
+	dir := createTempProject(t)
+	mem := &mockMemories{healthy: true}
+
+	first := &evolvingLLM{
+		zones:    `[{"name": "core", "intent": "logic", "files": ["main.go"]}, {"name": "legacy", "intent": "old", "files": ["helper.go"]}]`,
+		intent:   "A small demo tool.",
+		patterns: `["dependency injection", "table-driven tests"]`,
+	}
+	result, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      first,
+		MemoriesClient: mem,
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+	})
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if result.Changes != nil {
+		t.Errorf("first run should report no changes, got %+v", result.Changes)
+	}
+
+	second := &evolvingLLM{
+		zones:    `[{"name": "core", "intent": "logic", "files": ["main.go"]}, {"name": "api", "intent": "http", "files": ["helper.go"]}]`,
+		intent:   "An HTTP service.",
+		patterns: `["dependency injection", "functional options"]`,
+	}
+	result, err = Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      second,
+		MemoriesClient: mem,
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+	})
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+
+	c := result.Changes
+	if c == nil {
+		t.Fatal("second run reported no changes")
+	}
+	if fmt.Sprint(c.PatternsAdded) != "[functional options]" || fmt.Sprint(c.PatternsRemoved) != "[table-driven tests]" {
+		t.Errorf("patterns: added %v, removed %v", c.PatternsAdded, c.PatternsRemoved)
+	}
+	if len(c.ZonesAdded) != 1 || c.ZonesAdded[0].Zone != "api" {
+		t.Errorf("ZonesAdded = %+v, want api", c.ZonesAdded)
+	}
+	if len(c.ZonesRemoved) != 1 || c.ZonesRemoved[0].Zone != "legacy" {
+		t.Errorf("ZonesRemoved = %+v, want legacy", c.ZonesRemoved)
+	}
+	if len(c.IntentsChanged) != 1 || c.IntentsChanged[0].Before != "A small demo tool." || c.IntentsChanged[0].After != "An HTTP service." {
+		t.Errorf("IntentsChanged = %+v", c.IntentsChanged)
+	}
+
+	// A third run with the same answers reports nothing.
+	result, err = Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      second,
+		MemoriesClient: mem,
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+	})
+	if err != nil {
+		t.Fatalf("third run: %v", err)
+	}
+	if result.Changes != nil {
+		t.Errorf("unchanged run reported %+v", result.Changes)
+	}
+}
@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/divyekant/carto/internal/analyzer"
+	"github.com/divyekant/carto/internal/sources"
+)
+
+// Phases a run can resume from (Config.ResumeFrom), for iterating on the
+// deep analysis and synthesis prompts without redoing the earlier phases.
+const (
+	ResumeAnalysis  = "analysis"
+	ResumeSynthesis = "synthesis"
+)
+
+// ErrNoSavedPhases means Config.ResumeFrom was set but no run with
+// Config.SavePhases has saved the outputs it needs.
+var ErrNoSavedPhases = errors.New("no saved phase outputs")
+
+// Files in DebugDir holding a SavePhases run's intermediate outputs.
+const (
+	inputsFile    = "module_inputs.json"   // per-module atoms, history and signals
+	decisionsFile = "decisions.json"       // ADRs passed to synthesis
+	analysesFile  = "module_analyses.json" // deep analysis output
+)
+
+// DebugDir is where runs with Config.SavePhases keep their intermediate
+// phase outputs: {projectRoot}/.carto/debug.
+func DebugDir(projectRoot string) string {
+	return filepath.Join(projectRoot, ".carto", "debug")
+}
+
+// savePhase writes one phase output to the project's DebugDir.
+func savePhase(root, name string, v any) error {
+	dir := DebugDir(root)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("save %s: %w", name, err)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("save %s: %w", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("save %s: %w", name, err)
+	}
+	return nil
+}
+
+// loadPhase reads one phase output saved by savePhase into v.
+func loadPhase(root, name string, v any) error {
+	data, err := os.ReadFile(filepath.Join(DebugDir(root), name))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s not found in %s; run once with phase outputs saved first", ErrNoSavedPhases, name, DebugDir(root))
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("load %s: %w", name, err)
+	}
+	return nil
+}
+
+// resume re-runs deep analysis (from ResumeAnalysis) and system synthesis
+// on the phase outputs saved by an earlier SavePhases run. It does not
+// scan, chunk, or read history and signals, and it stores nothing: the
+// Result holds just the fresh analyses and synthesis. Resuming from
+// analysis saves the new analyses, so a later resume from synthesis uses
+// them.
+func resume(ctx context.Context, cfg Config) (*Result, error) {
+	if cfg.ResumeFrom != ResumeAnalysis && cfg.ResumeFrom != ResumeSynthesis {
+		return nil, fmt.Errorf("pipeline: cannot resume from %q (want %s or %s)", cfg.ResumeFrom, ResumeAnalysis, ResumeSynthesis)
+	}
+	progress := cfg.ProgressFn
+	if progress == nil {
+		progress = func(string, int, int) {}
+	}
+	logFn := cfg.LogFn
+	if logFn == nil {
+		logFn = func(string, string) {}
+	}
+
+	var decisions []sources.Artifact
+	if err := loadPhase(cfg.RootPath, decisionsFile, &decisions); err != nil {
+		return nil, fmt.Errorf("pipeline: %w", err)
+	}
+
+	deepAnalyzer := analyzer.NewDeepAnalyzer(cfg.LLMClient, cfg.DeepMaxTokens)
+	deepAnalyzer.SetModuleAttempts(cfg.DeepAttempts)
+	deepAnalyzer.SetInstructions(cfg.Instructions.Module, cfg.Instructions.Synthesis)
+
+	result := &Result{}
+	if cfg.ResumeFrom == ResumeAnalysis {
+		var inputs []analyzer.ModuleInput
+		if err := loadPhase(cfg.RootPath, inputsFile, &inputs); err != nil {
+			return nil, fmt.Errorf("pipeline: %w", err)
+		}
+		logFn("info", fmt.Sprintf("Resuming: deep analysis of %d saved module(s)...", len(inputs)))
+		analyses, err := deepAnalyzer.AnalyzeModulesCtx(ctx, inputs, cfg.MaxWorkers, func(done, total int) {
+			progress("analysis", done, total)
+		})
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+		if errors.Is(context.Cause(ctx), ErrBudgetExceeded) {
+			return result, budgetError(cfg)
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return result, fmt.Errorf("pipeline: %w after %s", ErrTimedOut, cfg.Timeout)
+		}
+		if ctx.Err() != nil {
+			return result, context.Canceled
+		}
+		if err := savePhase(cfg.RootPath, analysesFile, analyses); err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+		result.InvalidLinks = validateLinks(cfg, analyses, inputs, logFn)
+		result.ModuleAnalyses = analyses
+	} else if err := loadPhase(cfg.RootPath, analysesFile, &result.ModuleAnalyses); err != nil {
+		return nil, fmt.Errorf("pipeline: %w", err)
+	}
+	result.Modules = len(result.ModuleAnalyses)
+
+	var wiring []analyzer.Dependency
+	for _, ma := range result.ModuleAnalyses {
+		wiring = append(wiring, ma.Wiring...)
+	}
+	result.Cycles = analyzer.FindCycles(wiring)
+
+	if len(result.ModuleAnalyses) > 0 {
+		logFn("info", "Resuming: system synthesis...")
+		progress("synthesis", 0, 1)
+		synthesis, err := deepAnalyzer.SynthesizeSystemCtx(ctx, result.ModuleAnalyses, decisions...)
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+		} else {
+			result.Synthesis = synthesis
+		}
+		progress("synthesis", 1, 1)
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/divyekant/carto/internal/llm"
+)
+
+func TestRun_ResumeFromSynthesis(t *testing.T) {
+	dir := createTempProject(t)
+	llmClient := &mockLLM{}
+
+	first, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      llmClient,
+		MemoriesClient: &mockMemories{healthy: true},
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+		SavePhases:     true,
+	})
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	for _, name := range []string{inputsFile, decisionsFile, analysesFile} {
+		if _, err := os.Stat(filepath.Join(DebugDir(dir), name)); err != nil {
+			t.Errorf("phase output %s not saved: %v", name, err)
+		}
+	}
+
+	// Remove the sources: a resumed run must not need to scan them.
+	os.Remove(filepath.Join(dir, "main.go"))
+	os.RemoveAll(filepath.Join(dir, "pkg"))
+
+	llmClient.mu.Lock()
+	llmClient.tiers = nil
+	llmClient.mu.Unlock()
+	mem := &mockMemories{healthy: true}
+	var phases []string
+
+	result, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      llmClient,
+		MemoriesClient: mem,
+		MaxWorkers:     1,
+		ResumeFrom:     ResumeSynthesis,
+		ProgressFn: func(phase string, done, total int) {
+			if len(phases) == 0 || phases[len(phases)-1] != phase {
+				phases = append(phases, phase)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("resumed run: %v", err)
+	}
+
+	if strings.Join(phases, ",") != "synthesis" {
+		t.Errorf("phases = %v, want only synthesis", phases)
+	}
+	llmClient.mu.Lock()
+	tiers := llmClient.tiers
+	llmClient.mu.Unlock()
+	if len(tiers) != 1 || tiers[0] != llm.TierDeep {
+		t.Errorf("LLM calls = %v, want one deep-tier synthesis call", tiers)
+	}
+	if len(mem.getMemories()) != 0 || len(mem.getDeletions()) != 0 {
+		t.Error("resumed run should not touch Memories")
+	}
+	if len(result.ModuleAnalyses) != len(first.ModuleAnalyses) {
+		t.Errorf("ModuleAnalyses = %d, want the %d saved", len(result.ModuleAnalyses), len(first.ModuleAnalyses))
+	}
+	if result.Synthesis == nil || result.Synthesis.Blueprint == "" {
+		t.Errorf("expected a fresh synthesis, got %+v", result.Synthesis)
+	}
+}
+
+func TestRun_ResumeWithoutSavedPhases(t *testing.T) {
+	_, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       t.TempDir(),
+		LLMClient:      &mockLLM{},
+		MemoriesClient: &mockMemories{healthy: true},
+		ResumeFrom:     ResumeAnalysis,
+	})
+	if !errors.Is(err, ErrNoSavedPhases) {
+		t.Errorf("expected ErrNoSavedPhases, got %v", err)
+	}
+}
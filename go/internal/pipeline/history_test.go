@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/divyekant/carto/internal/history"
+)
+
+// git runs a git command in dir with a fixed identity and commit date.
+func git(t *testing.T, dir string, date time.Time, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@test.com"}, args...)...)
+	cmd.Dir = dir
+	stamp := date.Format(time.RFC3339)
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+stamp, "GIT_COMMITTER_DATE="+stamp)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// storedCommits returns how many commits of main.go the stored history
+// layers hold.
+func storedCommits(t *testing.T, mem *mockMemories) int {
+	t.Helper()
+	n := 0
+	for _, m := range mem.getMemories() {
+		if !strings.HasSuffix(m.source, "/layer:history") {
+			continue
+		}
+		var histories []*history.FileHistory
+		if err := json.Unmarshal([]byte(m.text), &histories); err != nil {
+			t.Fatalf("decode history: %v", err)
+		}
+		for _, h := range histories {
+			if h != nil && h.FilePath == "main.go" {
+				n += len(h.Commits)
+			}
+		}
+	}
+	return n
+}
+
+func TestRun_HistoryOptions(t *testing.T) {
+	dir := createTempProject(t)
+	now := time.Now()
+	git(t, dir, now, "init", "-q")
+	git(t, dir, now, "add", ".")
+	git(t, dir, now.AddDate(-2, 0, 0), "commit", "-q", "-m", "initial")
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+	git(t, dir, now, "commit", "-q", "-am", "recent change")
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() { _ = 1 }\n"), 0o644)
+	git(t, dir, now, "commit", "-q", "-am", "another recent change")
+
+	run := func(since string, maxCommits int) (int, error) {
+		mem := &mockMemories{healthy: true}
+		_, err := Run(Config{
+			ProjectName:       "test-project",
+			RootPath:          dir,
+			LLMClient:         &mockLLM{},
+			MemoriesClient:    mem,
+			MaxWorkers:        1,
+			SkipSkillFiles:    true,
+			HistorySince:      since,
+			HistoryMaxCommits: maxCommits,
+		})
+		return storedCommits(t, mem), err
+	}
+
+	tests := []struct {
+		since      string
+		maxCommits int
+		want       int
+	}{
+		{"", 0, 2},            // default window: 6 months
+		{"3 years ago", 0, 3}, // wider window reaches the initial commit
+		{"3 years ago", 1, 1}, // capped per file
+	}
+	for _, tt := range tests {
+		got, err := run(tt.since, tt.maxCommits)
+		if err != nil {
+			t.Fatalf("Run(since=%q, max=%d): %v", tt.since, tt.maxCommits, err)
+		}
+		if got != tt.want {
+			t.Errorf("since=%q max=%d: stored %d commits of main.go, want %d", tt.since, tt.maxCommits, got, tt.want)
+		}
+	}
+
+	if _, err := run("not a date", 0); err == nil || !strings.Contains(err.Error(), "not a date") {
+		t.Errorf("invalid since: err = %v, want a validation error", err)
+	}
+}
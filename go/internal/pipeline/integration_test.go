@@ -12,6 +12,7 @@ import (
 	"context"
 
 	"github.com/divyekant/carto/internal/llm"
+	"github.com/divyekant/carto/internal/manifest"
 	"github.com/divyekant/carto/internal/sources"
 	"github.com/divyekant/carto/internal/storage"
 )
@@ -394,9 +395,9 @@ func TestIntegration_FullPipeline(t *testing.T) {
 
 	// ── Verify manifest was created ─────────────────────────────────
 
-	manifestPath := filepath.Join(dir, ".carto", "manifest.json")
+	manifestPath := manifest.Path(dir)
 	if _, statErr := os.Stat(manifestPath); os.IsNotExist(statErr) {
-		t.Fatal("manifest.json was not created at .carto/manifest.json")
+		t.Fatal("manifest was not created at .carto/manifest.json.gz")
 	}
 
 	// ── Second run: incremental with no changes ─────────────────────
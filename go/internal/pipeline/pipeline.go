@@ -6,12 +6,17 @@ package pipeline
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"context"
 
@@ -25,6 +30,19 @@ import (
 	"github.com/divyekant/carto/internal/scanner"
 	"github.com/divyekant/carto/internal/sources"
 	"github.com/divyekant/carto/internal/storage"
+	"github.com/divyekant/carto/internal/tracing"
+)
+
+// Errors returned (wrapped) by Run, for callers to tell failure modes apart
+// with errors.Is.
+var (
+	// ErrMemoriesUnreachable means the pre-flight Memories health check failed.
+	ErrMemoriesUnreachable = errors.New("memories server unreachable")
+	// ErrModuleNotFound means Config.ModuleFilter matched no detected module.
+	ErrModuleNotFound = errors.New("module not found")
+	// ErrTimedOut means Config.Timeout elapsed before the run finished. Run
+	// returns it with the partial Result.
+	ErrTimedOut = errors.New("index timed out")
 )
 
 // LLMClient is the interface shared by atoms.LLMClient and analyzer.LLMClient.
@@ -35,20 +53,82 @@ type LLMClient interface {
 
 // Config holds all the dependencies the pipeline needs.
 type Config struct {
-	Ctx            context.Context // optional: cancel to stop the pipeline mid-run
-	ProjectName    string
-	RootPath       string
-	LLMClient      LLMClient
-	MemoriesClient storage.MemoriesAPI
-	SourceRegistry *sources.Registry // unified source registry (replaces SignalRegistry + KnowledgeRegistry)
-	MaxWorkers     int
-	ProgressFn     func(phase string, done, total int) // optional progress callback
-	LogFn          func(level, msg string)              // optional log callback
-	Incremental    bool                                 // use manifest for incremental indexing
-	ModuleFilter   string                               // optional: index only this module
-	FastMaxTokens  int                                  // optional: override fast-tier max tokens (default 4096)
-	DeepMaxTokens  int                                  // optional: override deep-tier max tokens (default 8192)
-	SkipSkillFiles bool                                 // if true, skip generating CLAUDE.md and .cursorrules
+	Ctx               context.Context // optional: cancel to stop the pipeline mid-run
+	ProjectName       string
+	RootPath          string
+	LLMClient         LLMClient
+	MemoriesClient    storage.MemoriesAPI
+	SourceRegistry    *sources.Registry // unified source registry (replaces SignalRegistry + KnowledgeRegistry)
+	MaxWorkers        int
+	ProgressFn        func(phase string, done, total int) // optional progress callback
+	LogFn             func(level, msg string)             // optional log callback
+	Incremental       bool                                // use manifest for incremental indexing
+	RehashAll         bool                                // incremental: hash every file instead of trusting unchanged mtime+size
+	ScopedSynthesis   bool                                // incremental: synthesize only changed modules and their wiring neighbors into the stored blueprint
+	ModuleFilter      string                              // optional: index only this module
+	FileList          []string                            // optional: index exactly these relative paths, skipping change detection; non-nil but empty indexes nothing
+	FastMaxTokens     int                                 // optional: override fast-tier max tokens (default 4096)
+	DeepMaxTokens     int                                 // optional: override deep-tier max tokens (default 8192)
+	DeepAttempts      int                                 // optional: deep analysis attempts per module (default 2)
+	SkipSkillFiles    bool                                // if true, skip generating CLAUDE.md and .cursorrules
+	IncludeGlobs      []string                            // optional: index only files matching one of these globs
+	ExcludeGlobs      []string                            // optional: skip files matching any of these globs
+	IncludeGenerated  bool                                // if true, run atom analysis on generated files too
+	IncludeSubmodules bool                                // if true, index git submodules as separate modules instead of skipping them
+	ChunkKinds        []string                            // optional: chunk kinds to analyze (e.g. function, class); empty means all
+	ChunkMinLines     int                                 // optional: merge or skip declarations shorter than this
+	MemoriesNamespace string                              // optional: source tag prefix (default "carto")
+	LayerBackends     map[string]storage.MemoriesAPI      // optional: layer -> Memories backend; other layers use MemoriesClient
+	CompressThreshold int                                 // optional: gzip stored content longer than this; 0 disables
+	HistorySince      string                              // optional: git date limiting history (default "6 months ago")
+	HistoryMaxCommits int                                 // optional: commits of history per file (default 50)
+	FullHistory       bool                                // if true, ignore HistorySince and read each file's entire history
+	ModulesOnly       bool                                // if true, skip fast-tier atom analysis and give deep analysis chunk names and kinds only
+	Instructions      Instructions                        // optional: extra guidance appended to the analysis system prompts
+	SummaryDetail     string                              // optional: atom summary length, brief | normal | detailed (default normal)
+	NoFileFallback    bool                                // if true, report declaration-less files in Result.EmptyFiles instead of analyzing each whole
+	NoStoreSource     bool                                // if true, keep each atom's original source out of its stored memory
+	RetryFailed       bool                                // if true, retry files skipped for failing MaxFileAttempts runs in a row
+	MaxFileAttempts   int                                 // optional: runs a file may fail before it is skipped (default 2)
+	SavePhases        bool                                // debugging: save intermediate phase outputs to DebugDir
+	ResumeFrom        string                              // debugging: re-run only analysis | synthesis on saved phase outputs
+	Timeout           time.Duration                       // optional: cancel the run after this long and return ErrTimedOut
+	MaxCost           float64                             // optional: stop the run once the LLM client's estimated spend in dollars crosses this and return ErrBudgetExceeded; needs a Spender
+	ValidateLinks     string                              // optional: after deep analysis, LinksFlag or LinksDrop wiring edges that refer to no known unit; empty skips the check
+}
+
+// Ways to treat wiring edges that refer to no known unit (Config.ValidateLinks).
+const (
+	LinksFlag = "flag" // keep them, marked Dependency.Unresolved
+	LinksDrop = "drop" // remove them
+)
+
+// compatibilityChecker is implemented by Memories backends that can tell
+// whether the server's API version is supported (see
+// storage.MemoriesClient.CheckCompatible).
+type compatibilityChecker interface {
+	CheckCompatible() error
+}
+
+// Instructions holds custom guidance, such as "emphasize public API
+// surface", appended to the system prompt of each analysis stage. The
+// stages' JSON output contracts are unaffected.
+type Instructions struct {
+	Atom      string
+	Module    string
+	Synthesis string
+}
+
+// DefaultMaxFileAttempts is how many runs in a row a file may fail to chunk
+// or analyze before later runs skip it.
+const DefaultMaxFileAttempts = 2
+
+// FailedFile is a file that failed to chunk or analyze, with the reason
+// for its latest failure and how many runs in a row it has failed.
+type FailedFile struct {
+	Path     string
+	Reason   string
+	Attempts int
 }
 
 // Result holds the output of a full pipeline run.
@@ -56,8 +136,18 @@ type Result struct {
 	Modules        int
 	FilesIndexed   int
 	AtomsCreated   int
+	Truncated      int                     // stored entries cut to fit the Memories content limit
+	Unstored       int                     // memories Memories rejected even after splitting and retries; each batch is also in Errors
+	EmptyFiles     []string                // files (relative to the root) that produced no chunks and so no atoms
+	FailedFiles    []FailedFile            // files that failed to chunk or analyze on this run
+	SkippedFailed  []FailedFile            // files skipped for failing Config.MaxFileAttempts runs in a row
+	MissingFiles   []string                // Config.FileList paths the scan did not find, which were skipped
+	Cycles         [][]string              // circular dependencies in the combined wiring; see analyzer.FindCycles
+	InvalidLinks   int                     // wiring edges Config.ValidateLinks found referring to no known unit
+	LanguageStats  []scanner.LanguageStats // files and bytes per language of the scanned files
 	ModuleAnalyses []analyzer.ModuleAnalysis
 	Synthesis      *analyzer.SystemSynthesis
+	Changes        *Changes // architecture changes since the previous run; nil if none
 	Errors         []error
 }
 
@@ -67,19 +157,62 @@ type Result struct {
 //  3. History + Signals — extract git history and external signals
 //  4. Deep Analysis — per-module wiring/zones analysis and system synthesis
 //  5. Store — persist all layers to Memories and update manifest
-func Run(cfg Config) (*Result, error) {
+func Run(cfg Config) (_ *Result, err error) {
 	ctx := cfg.Ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
-
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	if cfg.MaxCost > 0 {
+		spender, ok := cfg.LLMClient.(Spender)
+		if !ok {
+			return nil, fmt.Errorf("pipeline: MaxCost is set but the LLM client does not report its spend")
+		}
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		cfg.LLMClient = &budgetClient{LLMClient: cfg.LLMClient, spender: spender, limit: cfg.MaxCost, cancel: cancel}
+	}
+	ctx, runSpan := tracing.Start(ctx, "index", tracing.String("carto.project", cfg.ProjectName))
+	defer func() {
+		runSpan.RecordError(err)
+		runSpan.End()
+	}()
+
+	if cfg.ValidateLinks != "" && cfg.ValidateLinks != LinksFlag && cfg.ValidateLinks != LinksDrop {
+		return nil, fmt.Errorf("pipeline: cannot validate links with %q (want %s or %s)", cfg.ValidateLinks, LinksFlag, LinksDrop)
+	}
 	if cfg.MaxWorkers <= 0 {
 		cfg.MaxWorkers = 4
 	}
+	if cfg.MaxFileAttempts <= 0 {
+		cfg.MaxFileAttempts = DefaultMaxFileAttempts
+	}
+	if cfg.ResumeFrom != "" {
+		return resume(ctx, cfg)
+	}
+	if cfg.HistorySince != "" && !cfg.FullHistory {
+		if err := history.ValidateSince(cfg.RootPath, cfg.HistorySince); err != nil {
+			return nil, fmt.Errorf("pipeline: %w", err)
+		}
+	}
 
-	// Pre-flight: verify Memories server is reachable.
-	if healthy, err := cfg.MemoriesClient.Health(); err != nil || !healthy {
-		return nil, fmt.Errorf("pipeline: memories server unreachable at startup — verify MEMORIES_URL and ensure the server is running")
+	// Pre-flight: verify every Memories backend is reachable and speaks an
+	// API version the client supports.
+	backends := storage.Backends{Default: cfg.MemoriesClient, Layers: cfg.LayerBackends}
+	for _, backend := range backends.All() {
+		if healthy, err := backend.Health(); err != nil || !healthy {
+			return nil, fmt.Errorf("pipeline: %w at startup — verify MEMORIES_URL and ensure the server is running", ErrMemoriesUnreachable)
+		}
+		if c, ok := backend.(compatibilityChecker); ok {
+			if err := c.CheckCompatible(); errors.Is(err, storage.ErrIncompatibleServer) {
+				return nil, fmt.Errorf("pipeline: %w — upgrade carto or the Memories server", err)
+			}
+		}
 	}
 
 	result := &Result{}
@@ -92,6 +225,15 @@ func Run(cfg Config) (*Result, error) {
 		logFn = func(string, string) {}
 	}
 
+	// phase ends the trace span of the current phase, if any, and starts
+	// the span of the named one, which phaseCtx carries.
+	phaseCtx, phaseSpan := ctx, (*tracing.Span)(nil)
+	phase := func(name string) {
+		phaseSpan.End()
+		phaseCtx, phaseSpan = tracing.Start(ctx, name)
+	}
+	defer func() { phaseSpan.End() }()
+
 	// cancelled is a helper to check for context cancellation.
 	cancelled := func() bool {
 		select {
@@ -102,14 +244,42 @@ func Run(cfg Config) (*Result, error) {
 		}
 	}
 
+	// stopErr is returned with the partial result when the run is
+	// cancelled: ErrTimedOut if Config.Timeout elapsed, ErrBudgetExceeded
+	// if the spend crossed Config.MaxCost, else context.Canceled.
+	stopErr := func() error {
+		if errors.Is(context.Cause(ctx), ErrBudgetExceeded) {
+			return budgetError(cfg)
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("pipeline: %w after %s", ErrTimedOut, cfg.Timeout)
+		}
+		return context.Canceled
+	}
+
 	// ── Phase 1: Scan ──────────────────────────────────────────────────
+	phase("scan")
 	logFn("info", fmt.Sprintf("Scanning %s...", cfg.RootPath))
 	progress("scan", 0, 1)
 
-	scanResult, err := scanner.Scan(cfg.RootPath)
+	scanResult, err := scanner.ScanWithOptions(cfg.RootPath, scanner.Options{IncludeSubmodules: cfg.IncludeSubmodules})
 	if err != nil {
 		return nil, fmt.Errorf("pipeline: scan failed: %w", err)
 	}
+	// Remember everything the ignore rules let through, before the
+	// per-run glob filters narrow it, for ignore-change reconciliation.
+	scanned := make(map[string]bool, len(scanResult.Files))
+	for _, f := range scanResult.Files {
+		scanned[f.RelPath] = true
+	}
+	scannedModules := make(map[string]bool, len(scanResult.Modules))
+	for _, m := range scanResult.Modules {
+		scannedModules[m.Name] = true
+	}
+	allModules := slices.Clone(scanResult.Modules)
+	scanResult.FilterGlobs(cfg.IncludeGlobs, cfg.ExcludeGlobs)
+	result.LanguageStats = scanResult.LanguageStats
+	sortModules(scanResult.Modules)
 
 	progress("scan", 1, 1)
 
@@ -125,13 +295,22 @@ func Run(cfg Config) (*Result, error) {
 		for i, m := range scanResult.Modules {
 			available[i] = m.Name
 		}
-		return nil, fmt.Errorf("pipeline: module %q not found. available: %v", cfg.ModuleFilter, available)
+		return nil, fmt.Errorf("pipeline: %w: %q (available: %v)", ErrModuleNotFound, cfg.ModuleFilter, available)
 	}
 	if len(modules) == 0 {
-		logFn("info", "No modules found, nothing to index")
+		logFn("info", "No modules found, nothing to index (the ignore rules or include/exclude globs left no files)")
 		return result, nil
 	}
 	logFn("info", fmt.Sprintf("Found %d module(s) with %d total files", len(modules), countModuleFiles(modules)))
+	var fallback []string
+	for _, m := range modules {
+		if m.Fallback {
+			fallback = append(fallback, m.Name)
+		}
+	}
+	if len(fallback) > 0 {
+		logFn("warn", fmt.Sprintf("No manifest (go.mod, package.json, ...) covers %d module(s); grouped their files by top-level directory: %s", len(fallback), strings.Join(fallback, ", ")))
+	}
 
 	// Load/create manifest — always track indexed files so subsequent runs
 	// can use --incremental. In non-incremental mode we still save at the end.
@@ -144,19 +323,67 @@ func Run(cfg Config) (*Result, error) {
 		logFn("warn", "Failed to load manifest, starting fresh")
 		mf = manifest.NewManifest(cfg.RootPath, cfg.ProjectName)
 	}
+	mf.SetRehashAll(cfg.RehashAll)
+
+	// Files newly matched by .gitignore/.cartoignore are still in the
+	// manifest and in Memories, but change detection only sees the scanned
+	// files and would never report them as removed. When the ignore rules
+	// changed, force a full rescan and reconcile. A module-filtered run
+	// reconciles nothing, so it keeps the old hash for the next run.
+	incremental := cfg.Incremental
+	ignoreChanged := !mf.IsEmpty() && mf.IgnoreHash != scanResult.IgnoreHash
+	if ignoreChanged {
+		logFn("info", "Ignore rules changed since last run, forcing a full rescan")
+		incremental = false
+		if cfg.ModuleFilter == "" {
+			errs := reconcileIgnored(mf, newStore(cfg), scanned, scannedModules, logFn)
+			result.Errors = append(result.Errors, errs...)
+		}
+	}
+
+	// Generated files (protobuf stubs, "Code generated ... DO NOT EDIT.")
+	// are tracked and counted but skipped for atom analysis by default.
+	generated := make(map[string]bool)
+	if !cfg.IncludeGenerated {
+		for _, f := range scanResult.Files {
+			if f.Generated {
+				generated[f.RelPath] = true
+			}
+		}
+	}
 
 	// Build a set of files that need indexing (respecting incremental mode).
 	type moduleWork struct {
 		module       scanner.Module
 		filesToIndex []string // relative paths of files to process
+		atomFiles    []string // filesToIndex minus generated files
+	}
+
+	// A file list from the caller, such as a build system that knows what
+	// changed, stands in for change detection: the listed files the scan
+	// found are indexed, the rest reported and skipped.
+	var listed map[string]bool
+	if cfg.FileList != nil {
+		listed, result.MissingFiles = matchFileList(cfg.FileList, scanResult.Files)
+		if n := len(result.MissingFiles); n > 0 {
+			logFn("warn", fmt.Sprintf("Skipping %d listed file(s) not found in the scan", n))
+		}
 	}
 
 	var work []moduleWork
 	totalFiles := 0
+	skippedGenerated := 0
 
 	for _, mod := range modules {
 		files := mod.Files
-		if cfg.Incremental && !mf.IsEmpty() {
+		if listed != nil {
+			files = nil
+			for _, f := range mod.Files {
+				if listed[f] {
+					files = append(files, f)
+				}
+			}
+		} else if incremental && !mf.IsEmpty() {
 			changed, detectErr := mf.DetectChanges(files, scanResult.Root)
 			if detectErr != nil {
 				log.Printf("pipeline: warning: change detection failed for %s: %v", mod.Name, detectErr)
@@ -165,36 +392,81 @@ func Run(cfg Config) (*Result, error) {
 				// Only process added and modified files.
 				files = append(changed.Added, changed.Modified...)
 
+				// The manifest covers every module, so its other files show
+				// up as removed too. Only this module's own removed files
+				// clear it; those of modules no longer scanned are just
+				// dropped from the manifest. Files the include/exclude
+				// globs leave out of this run are still there and keep
+				// their entries and stored atoms.
+				var removed []string
+				for _, rp := range changed.Removed {
+					if scanned[rp] {
+						continue
+					}
+					switch owningModule(allModules, rp) {
+					case mod.Name:
+						removed = append(removed, rp)
+					case "":
+						mf.RemoveFile(rp)
+					}
+				}
+
 				// Clean removed files from Memories.
-				if len(changed.Removed) > 0 {
-					store := storage.NewStore(cfg.MemoriesClient, cfg.ProjectName)
+				if len(removed) > 0 {
+					store := newStore(cfg)
 					if clearErr := store.ClearModule(mod.Name); clearErr != nil {
 						log.Printf("pipeline: warning: failed to clear module %s: %v", mod.Name, clearErr)
 						result.Errors = append(result.Errors, clearErr)
 					}
 					// Remove from manifest.
-					for _, rp := range changed.Removed {
+					for _, rp := range removed {
 						mf.RemoveFile(rp)
 					}
 				}
 			}
 		}
 
+		// Files that failed on each of the last MaxFileAttempts runs are
+		// skipped until their content changes or RetryFailed is set.
+		if !cfg.RetryFailed && len(mf.FailedFiles) > 0 {
+			files = skipFailedFiles(mf, files, scanResult.Root, cfg.MaxFileAttempts, result)
+		}
+
 		if len(files) == 0 {
 			continue
 		}
 
-		work = append(work, moduleWork{module: mod, filesToIndex: files})
+		atomFiles := files
+		if len(generated) > 0 {
+			atomFiles = nil
+			for _, f := range files {
+				if generated[f] {
+					skippedGenerated++
+					continue
+				}
+				atomFiles = append(atomFiles, f)
+			}
+		}
+
+		work = append(work, moduleWork{module: mod, filesToIndex: files, atomFiles: atomFiles})
 		totalFiles += len(files)
 	}
 
+	if skippedGenerated > 0 {
+		logFn("info", fmt.Sprintf("Skipping atom analysis for %d generated file(s)", skippedGenerated))
+	}
+	if n := len(result.SkippedFailed); n > 0 {
+		logFn("warn", fmt.Sprintf("Skipping %d file(s) that failed on each of the last %d runs (use --retry-failed to retry)", n, cfg.MaxFileAttempts))
+	}
+
 	result.FilesIndexed = totalFiles
 
 	if cancelled() {
-		return result, context.Canceled
+		return result, stopErr()
 	}
 
 	// ── Phase 2: Chunk + Atoms (parallel per module) ───────────────────
+	phase("atoms")
 	logFn("info", fmt.Sprintf("Chunking and analyzing %d files across %d module(s)...", totalFiles, len(work)))
 
 	type moduleAtoms struct {
@@ -203,8 +475,12 @@ func Run(cfg Config) (*Result, error) {
 	}
 
 	atomAnalyzer := atoms.NewAnalyzer(cfg.LLMClient, cfg.FastMaxTokens)
+	atomAnalyzer.SetInstructions(cfg.Instructions.Atom)
+	atomAnalyzer.SetSummaryDetail(cfg.SummaryDetail)
 	moduleAtomsList := make([]moduleAtoms, len(work))
 	var atomErrors []error
+	failedFiles := make(map[string]string)    // relative path -> reason the file failed to chunk
+	analysisFailed := make(map[string]string) // relative path -> reason no unit of the file was analyzed
 
 	atomsDone := 0
 	var atomsMu sync.Mutex
@@ -237,8 +513,16 @@ func Run(cfg Config) (*Result, error) {
 			if cancelled() {
 				return
 			}
-
-			allChunks, chunkErrs := chunkModuleFiles(mw.module, mw.filesToIndex, scanResult.Root)
+			mctx, span := tracing.Start(phaseCtx, "module",
+				tracing.String("carto.module", mw.module.Name),
+				tracing.Int("carto.files", len(mw.atomFiles)))
+			defer span.End()
+
+			allChunks, emptyFiles, chunkFailed, chunkErrs := chunkModuleFiles(mw.module, mw.atomFiles, scanResult.Root, &chunker.ChunkOptions{
+				Kinds:      cfg.ChunkKinds,
+				MinLines:   cfg.ChunkMinLines,
+				NoFallback: cfg.NoFileFallback,
+			})
 
 			if cancelled() {
 				return
@@ -255,11 +539,26 @@ func Run(cfg Config) (*Result, error) {
 					StartLine: c.StartLine,
 					EndLine:   c.EndLine,
 					Code:      c.Code,
+					Doc:       c.Doc,
 				}
 			}
 
-			// Analyze atoms.
-			analyzed, analyzeErr := atomAnalyzer.AnalyzeBatchCtx(ctx, atomChunks, cfg.MaxWorkers, nil)
+			// Analyze atoms, or in modules-only mode keep just the chunk
+			// structure for deep analysis without any fast-tier calls.
+			var analyzed []*atoms.Atom
+			var analyzeErr error
+			if cfg.ModulesOnly {
+				analyzed = structuralAtoms(atomChunks)
+			} else {
+				analyzed, analyzeErr = atomAnalyzer.AnalyzeBatchCtx(mctx, atomChunks, cfg.MaxWorkers, nil)
+			}
+			span.SetAttributes(tracing.Int("carto.atoms", len(analyzed)))
+			sortAtoms(analyzed)
+
+			var unanalyzed map[string]string
+			if ctx.Err() == nil {
+				unanalyzed = unanalyzedFiles(allChunks, analyzed, scanResult.Root)
+			}
 
 			atomsMu.Lock()
 			moduleAtomsList[idx] = moduleAtoms{module: mw.module, atoms: analyzed}
@@ -267,6 +566,9 @@ func Run(cfg Config) (*Result, error) {
 				atomErrors = append(atomErrors, analyzeErr)
 			}
 			atomErrors = append(atomErrors, chunkErrs...)
+			maps.Copy(failedFiles, chunkFailed)
+			maps.Copy(analysisFailed, unanalyzed)
+			result.EmptyFiles = append(result.EmptyFiles, emptyFiles...)
 			atomsDone++
 			d := atomsDone
 			atomsMu.Unlock()
@@ -276,17 +578,21 @@ func Run(cfg Config) (*Result, error) {
 
 	wg.Wait()
 	result.Errors = append(result.Errors, atomErrors...)
+	sort.Strings(result.EmptyFiles)
 
-	// Count total atoms.
-	for _, ma := range moduleAtomsList {
-		result.AtomsCreated += len(ma.atoms)
+	// Count total atoms. Modules-only runs produce no atom summaries.
+	if !cfg.ModulesOnly {
+		for _, ma := range moduleAtomsList {
+			result.AtomsCreated += len(ma.atoms)
+		}
 	}
 
 	if cancelled() {
-		return result, context.Canceled
+		return result, stopErr()
 	}
 
 	// ── Phase 3: History + Signals (parallel per module) ───────────────
+	phase("history")
 	logFn("info", fmt.Sprintf("Extracted %d atoms. Fetching git history and signals...", result.AtomsCreated))
 
 	type moduleContext struct {
@@ -324,12 +630,14 @@ func Run(cfg Config) (*Result, error) {
 			if cancelled() {
 				return
 			}
+			mctx, span := tracing.Start(phaseCtx, "module", tracing.String("carto.module", mw.module.Name))
+			defer span.End()
 
 			// Extract git history.
 			histories, histErr := history.ExtractBulkHistory(
 				scanResult.Root,
 				mw.filesToIndex,
-				&history.ExtractOptions{MaxCommits: 50, Since: "6 months ago"},
+				&history.ExtractOptions{MaxCommits: cfg.HistoryMaxCommits, Since: cfg.HistorySince, AllHistory: cfg.FullHistory},
 				cfg.MaxWorkers,
 			)
 
@@ -347,7 +655,7 @@ func Run(cfg Config) (*Result, error) {
 					RepoRoot:   scanResult.Root,
 				}
 				var srcErr error
-				arts, srcErr = cfg.SourceRegistry.FetchModule(ctx, req)
+				arts, srcErr = cfg.SourceRegistry.FetchModule(mctx, req)
 				if srcErr != nil {
 					contextMu.Lock()
 					contextErrors = append(contextErrors, srcErr)
@@ -378,7 +686,7 @@ func Run(cfg Config) (*Result, error) {
 			Project:  cfg.ProjectName,
 			RepoRoot: scanResult.Root,
 		}
-		pArts, pErr := cfg.SourceRegistry.FetchAllProject(ctx, req)
+		pArts, pErr := cfg.SourceRegistry.FetchAllProject(phaseCtx, req)
 		if pErr != nil {
 			result.Errors = append(result.Errors, pErr)
 		}
@@ -389,37 +697,91 @@ func Run(cfg Config) (*Result, error) {
 	}
 
 	if cancelled() {
-		return result, context.Canceled
+		return result, stopErr()
 	}
 
 	// ── Phase 4: Deep Analysis ─────────────────────────────────────────
+	phase("analysis")
 	logFn("info", fmt.Sprintf("Running deep analysis on %d module(s)...", len(work)))
 	deepAnalyzer := analyzer.NewDeepAnalyzer(cfg.LLMClient, cfg.DeepMaxTokens)
+	deepAnalyzer.SetModuleAttempts(cfg.DeepAttempts)
+	deepAnalyzer.SetInstructions(cfg.Instructions.Module, cfg.Instructions.Synthesis)
+
+	// Imports of any scanned Go module are in-project; the rest (standard
+	// library, third-party) stay out of the static graph.
+	var goModulePaths []string
+	for _, m := range allModules {
+		if m.Type == "go" {
+			goModulePaths = append(goModulePaths, m.Name)
+		}
+	}
 
 	// Build ModuleInput for each module.
 	inputs := make([]analyzer.ModuleInput, len(work))
 	for i, w := range work {
 		inputs[i] = analyzer.ModuleInput{
-			Name:    w.module.Name,
-			Path:    w.module.Path,
-			Atoms:   moduleAtomsList[i].atoms,
-			History: moduleContexts[i].history,
-			Signals: moduleContexts[i].artifacts,
+			Name:       w.module.Name,
+			Path:       w.module.Path,
+			Atoms:      moduleAtomsList[i].atoms,
+			History:    moduleContexts[i].history,
+			Signals:    moduleContexts[i].artifacts,
+			Frameworks: w.module.Frameworks,
+		}
+		// Go modules get a cheap static import graph as ground truth. It
+		// covers all of the module's files, not just those re-indexed, since
+		// inferred edges are checked against it.
+		if w.module.Type == "go" {
+			inputs[i].KnownImports = analyzer.StaticGoWiring(scanResult.Root, w.module.Files, goModulePaths)
 		}
 	}
 
-	moduleAnalyses, deepErr := deepAnalyzer.AnalyzeModulesCtx(ctx, inputs, cfg.MaxWorkers, func(done, total int) {
+	var decisions []sources.Artifact
+	for _, art := range projectArtifacts {
+		if art.Tags["type"] == "adr" {
+			decisions = append(decisions, art)
+		}
+	}
+	if cfg.SavePhases {
+		for name, v := range map[string]any{inputsFile: inputs, decisionsFile: decisions} {
+			if err := savePhase(cfg.RootPath, name, v); err != nil {
+				result.Errors = append(result.Errors, err)
+			}
+		}
+	}
+
+	moduleAnalyses, deepErr := deepAnalyzer.AnalyzeModulesCtx(phaseCtx, inputs, cfg.MaxWorkers, func(done, total int) {
 		progress("analysis", done, total)
 	})
 	if deepErr != nil {
 		result.Errors = append(result.Errors, deepErr)
 	}
 	result.ModuleAnalyses = moduleAnalyses
+	if cfg.SavePhases && !cancelled() {
+		if err := savePhase(cfg.RootPath, analysesFile, moduleAnalyses); err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+	}
+
+	result.InvalidLinks = validateLinks(cfg, moduleAnalyses, inputs, logFn)
+
+	// Look for circular dependencies across every module's wiring.
+	var wiring []analyzer.Dependency
+	for _, ma := range moduleAnalyses {
+		wiring = append(wiring, ma.Wiring...)
+	}
+	result.Cycles = analyzer.FindCycles(wiring)
 
 	// System synthesis.
 	if len(moduleAnalyses) > 0 {
+		phase("synthesis")
 		progress("synthesis", 0, 1)
-		synthesis, synthErr := deepAnalyzer.SynthesizeSystem(moduleAnalyses)
+		var synthesis *analyzer.SystemSynthesis
+		var synthErr error
+		if cfg.ScopedSynthesis && incremental {
+			synthesis, synthErr = synthesizeScoped(phaseCtx, newStore(cfg), deepAnalyzer, moduleAnalyses, scannedModules, decisions, logFn)
+		} else {
+			synthesis, synthErr = deepAnalyzer.SynthesizeSystemCtx(phaseCtx, moduleAnalyses, decisions...)
+		}
 		if synthErr != nil {
 			result.Errors = append(result.Errors, synthErr)
 		} else {
@@ -429,41 +791,76 @@ func Run(cfg Config) (*Result, error) {
 	}
 
 	if cancelled() {
-		return result, context.Canceled
+		return result, stopErr()
+	}
+
+	// A file none of whose units could be analyzed counts as failed, unless
+	// the LLM failed across the board: no atom of any file and no module
+	// analysis succeeded, which points at the LLM rather than the files.
+	if result.AtomsCreated > 0 || deepErr == nil {
+		maps.Copy(failedFiles, analysisFailed)
 	}
 
 	// ── Phase 5: Store ─────────────────────────────────────────────────
+	phase("store")
 	logFn("info", "Storing results in Memories...")
-	store := storage.NewStore(cfg.MemoriesClient, cfg.ProjectName)
+	store := newStore(cfg)
+	store.SetCompression(cfg.CompressThreshold)
+
+	// Diff against what the previous run stored before overwriting it.
+	workModules := make([]string, len(work))
+	for i, w := range work {
+		workModules[i] = w.module.Name
+	}
+	result.Changes = diffArchitecture(loadPrevious(store, workModules), moduleAnalyses, result.Synthesis)
+
 	storeDone := 0
-	// Total store ops: per-module layers (5 each) + system-wide (2).
-	storeTotal := len(work)*5 + 2
+	// Total store ops: per-module layers (6 each) + system-wide (2).
+	storeTotal := len(work)*6 + 2
 
 	for i, w := range work {
 		if cancelled() {
-			return result, context.Canceled
+			// Keep the manifest entries of modules already stored so the
+			// next incremental run picks up where this one stopped.
+			if mf != nil && i > 0 {
+				mf.Project = cfg.ProjectName
+				if err := mf.Save(); err != nil {
+					log.Printf("pipeline: warning: failed to save manifest: %v", err)
+					result.Errors = append(result.Errors, err)
+				}
+			}
+			return result, stopErr()
 		}
 
 		modName := w.module.Name
+		_, modSpan := tracing.Start(phaseCtx, "module", tracing.String("carto.module", modName))
 
 		// For non-incremental runs, clear existing module data before storing
-		// to prevent duplicate entries accumulating in Memories.
-		if !cfg.Incremental {
+		// to prevent duplicate entries accumulating in Memories. A file list
+		// is an incremental set: the module's unlisted files keep theirs.
+		if !incremental && cfg.FileList == nil {
 			if err := store.ClearModule(modName); err != nil {
 				log.Printf("pipeline: warning: failed to clear module %s before re-storing: %v", modName, err)
 			}
 		}
 
-		// Store atoms individually for better searchability and to avoid
-		// truncation when the total atoms JSON exceeds the 49K content limit.
-		atomEntries := make([]string, len(moduleAtomsList[i].atoms))
-		for j, a := range moduleAtomsList[i].atoms {
-			atomEntries[j] = formatAtomEntry(a)
+		// Store a short module summary plus one memory per atom, each under
+		// its own source tag, so no atom is lost to the 49K content limit.
+		modAtoms := moduleAtomsList[i].atoms
+		atomEntries := make([]storage.AtomEntry, len(modAtoms))
+		for j, a := range modAtoms {
+			atomEntries[j] = storage.AtomEntry{
+				Key:  fmt.Sprintf("%s:%d", atomPath(scanResult.Root, a.FilePath), a.StartLine),
+				Text: formatAtomEntry(a),
+			}
+			if !cfg.NoStoreSource {
+				atomEntries[j].Code = storedSource(a.Code)
+				atomEntries[j].CodeLine = a.StartLine
+			}
 		}
-		if len(atomEntries) > 0 {
-			if err := store.StoreBatch(modName, "atoms", atomEntries); err != nil {
-				log.Printf("pipeline: warning: failed to store atoms for %s: %v", modName, err)
-				result.Errors = append(result.Errors, err)
+		if len(atomEntries) > 0 && !cfg.ModulesOnly {
+			if err := store.StoreAtoms(modName, formatAtomSummary(modName, modAtoms), atomEntries); err != nil {
+				result.storeFailed("atoms", modName, err)
 			}
 		}
 		storeDone++
@@ -492,44 +889,60 @@ func Run(cfg Config) (*Result, error) {
 		// Store wiring and zones from module analysis (if available).
 		if ma := findModuleAnalysis(moduleAnalyses, modName); ma != nil {
 			if wiringJSON, err := json.Marshal(ma.Wiring); err == nil {
-				if err := store.StoreLayer(modName, "wiring", string(wiringJSON)); err != nil {
-					log.Printf("pipeline: warning: failed to store wiring for %s: %v", modName, err)
-					result.Errors = append(result.Errors, err)
+				if err := store.StoreWiring(modName, string(wiringJSON), graphEdges(ma.Wiring)); err != nil {
+					result.storeFailed("wiring", modName, err)
 				}
 			}
 			storeDone++
 			progress("store", storeDone, storeTotal)
 
 			if zonesJSON, err := json.Marshal(ma.Zones); err == nil {
-				if err := store.StoreLayer(modName, "zones", string(zonesJSON)); err != nil {
-					log.Printf("pipeline: warning: failed to store zones for %s: %v", modName, err)
+				if err := store.StoreZones(modName, string(zonesJSON), graphZones(ma.Zones)); err != nil {
+					result.storeFailed("zones", modName, err)
+				}
+			}
+			storeDone++
+			progress("store", storeDone, storeTotal)
+
+			if ma.ModuleIntent != "" {
+				if err := store.StoreLayer(modName, storage.LayerIntent, ma.ModuleIntent); err != nil {
+					log.Printf("pipeline: warning: failed to store intent for %s: %v", modName, err)
 					result.Errors = append(result.Errors, err)
 				}
 			}
 			storeDone++
 			progress("store", storeDone, storeTotal)
 		} else {
-			storeDone += 2
+			storeDone += 3
 			progress("store", storeDone, storeTotal)
 		}
 
-		// Update manifest for each file in this module.
-		if mf != nil {
+		// Update manifest for each file in this module. Modules-only runs
+		// leave it alone so a later incremental run still analyzes atoms.
+		if mf != nil && !cfg.ModulesOnly {
 			for _, relPath := range w.filesToIndex {
+				if reason, failed := failedFiles[relPath]; failed {
+					recordFailure(mf, scanResult.Root, relPath, reason, result)
+					continue
+				}
+				mf.ClearFailure(relPath)
 				absPath := filepath.Join(scanResult.Root, relPath)
+				// Stat before hashing so a write in between leaves a newer
+				// mtime than the one recorded, forcing a rehash next run.
+				info, statErr := os.Stat(absPath)
+				if statErr != nil {
+					continue
+				}
 				hash, hashErr := mf.ComputeHash(absPath)
 				if hashErr != nil {
 					log.Printf("pipeline: warning: hash failed for %s: %v", relPath, hashErr)
 					result.Errors = append(result.Errors, fmt.Errorf("hash failed for %s: %w", relPath, hashErr))
 					continue
 				}
-				info, statErr := os.Stat(absPath)
-				if statErr != nil {
-					continue
-				}
-				mf.UpdateFile(relPath, hash, info.Size())
+				mf.UpdateFileInfo(relPath, hash, info)
 			}
 		}
+		modSpan.End()
 	}
 
 	// Store system-wide blueprint and patterns.
@@ -571,9 +984,19 @@ func Run(cfg Config) (*Result, error) {
 		}
 	}
 
+	if result.Truncated = store.Truncated(); result.Truncated > 0 {
+		logFn("warn", fmt.Sprintf("%d stored entries exceeded the Memories content limit and were truncated", result.Truncated))
+	}
+	if result.Unstored > 0 {
+		logFn("warn", fmt.Sprintf("%d memories could not be stored in Memories; see the errors for which layers", result.Unstored))
+	}
+
 	// Save manifest.
 	if mf != nil {
 		mf.Project = cfg.ProjectName
+		if !ignoreChanged || cfg.ModuleFilter == "" {
+			mf.IgnoreHash = scanResult.IgnoreHash
+		}
 		if err := mf.Save(); err != nil {
 			log.Printf("pipeline: warning: failed to save manifest: %v", err)
 			result.Errors = append(result.Errors, err)
@@ -582,10 +1005,12 @@ func Run(cfg Config) (*Result, error) {
 
 	// ── Phase 6: Generate Skill Files ─────────────────────────────────
 	if !cfg.SkipSkillFiles && result.Synthesis != nil {
+		phase("skillfiles")
 		logFn("info", "Generating skill files (CLAUDE.md, .cursorrules)...")
 		progress("skillfiles", 0, 1)
 
 		input := buildPatternsInput(cfg.ProjectName, result.Synthesis, result.ModuleAnalyses)
+		input.Namespace = cfg.MemoriesNamespace
 		if err := patterns.WriteFiles(cfg.RootPath, input, "all"); err != nil {
 			log.Printf("pipeline: warning: failed to write skill files: %v", err)
 			result.Errors = append(result.Errors, err)
@@ -596,6 +1021,90 @@ func Run(cfg Config) (*Result, error) {
 	return result, nil
 }
 
+// matchFileList returns the set of listed paths that are among the
+// scanned files, and the ones that are not. Listed paths are relative to
+// the project root; they are cleaned and may use either separator.
+func matchFileList(list []string, files []scanner.FileInfo) (map[string]bool, []string) {
+	scanned := make(map[string]bool, len(files))
+	for _, f := range files {
+		scanned[f.RelPath] = true
+	}
+	listed := make(map[string]bool, len(list))
+	var missing []string
+	for _, p := range list {
+		rel := filepath.ToSlash(filepath.Clean(p))
+		if scanned[rel] {
+			listed[rel] = true
+		} else if !slices.Contains(missing, p) {
+			missing = append(missing, p)
+		}
+	}
+	return listed, missing
+}
+
+// atomPath returns the path an atom's key names its file by: relative to
+// the project root, in slash form, so keys don't depend on where the
+// project was checked out and path globs (see storage.Ranking) match them.
+func atomPath(root, file string) string {
+	if rel, err := filepath.Rel(root, file); err == nil && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	return file
+}
+
+// owningModule returns the name of the scanned module relPath lies in,
+// the nearest one when modules nest, or "" if none does.
+func owningModule(modules []scanner.Module, relPath string) string {
+	name, depth := "", -1
+	for _, m := range modules {
+		if len(m.RelPath) <= depth {
+			continue
+		}
+		if m.RelPath == "" || relPath == m.RelPath || strings.HasPrefix(relPath, m.RelPath+"/") {
+			name, depth = m.Name, len(m.RelPath)
+		}
+	}
+	return name
+}
+
+// reconcileIgnored removes manifest entries for files that are no longer
+// scanned and clears stored modules that no longer exist at all, e.g. a
+// directory module that is now ignored. Modules that still exist are
+// cleared and rewritten by the store phase of the full run.
+func reconcileIgnored(mf *manifest.Manifest, store *storage.Store, scanned, scannedModules map[string]bool, logFn func(level, msg string)) []error {
+	var stale []string
+	for relPath := range mf.Files {
+		if !scanned[relPath] {
+			stale = append(stale, relPath)
+		}
+	}
+	for _, relPath := range stale {
+		mf.RemoveFile(relPath)
+	}
+	if len(stale) > 0 {
+		logFn("info", fmt.Sprintf("Dropped %d now-ignored or deleted file(s) from the manifest", len(stale)))
+	}
+
+	stored, err := store.ListModules()
+	if err != nil {
+		log.Printf("pipeline: warning: cannot list stored modules: %v", err)
+		return []error{err}
+	}
+	var errs []error
+	for _, mod := range stored {
+		// Underscore scopes (_system, _signals, ...) are project-wide.
+		if strings.HasPrefix(mod, "_") || scannedModules[mod] {
+			continue
+		}
+		logFn("info", fmt.Sprintf("Clearing stale module %s", mod))
+		if err := store.ClearModule(mod); err != nil {
+			log.Printf("pipeline: warning: failed to clear module %s: %v", mod, err)
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
 // filterModules returns only the module matching the given name.
 func filterModules(modules []scanner.Module, name string) []scanner.Module {
 	for _, m := range modules {
@@ -607,9 +1116,13 @@ func filterModules(modules []scanner.Module, name string) []scanner.Module {
 }
 
 // chunkModuleFiles reads and chunks all files for a module.
-// It returns the concatenated chunks and any non-fatal errors encountered.
-func chunkModuleFiles(mod scanner.Module, filesToIndex []string, scanRoot string) ([]chunker.Chunk, []error) {
+// It returns the concatenated chunks, the files that produced no chunks,
+// the files that could not be read or chunked (with the reason), and any
+// non-fatal errors encountered.
+func chunkModuleFiles(mod scanner.Module, filesToIndex []string, scanRoot string, opts *chunker.ChunkOptions) ([]chunker.Chunk, []string, map[string]string, []error) {
 	var allChunks []chunker.Chunk
+	var empty []string
+	failed := make(map[string]string)
 	var errs []error
 
 	for _, relPath := range filesToIndex {
@@ -619,22 +1132,152 @@ func chunkModuleFiles(mod scanner.Module, filesToIndex []string, scanRoot string
 		if err != nil {
 			log.Printf("pipeline: warning: cannot read %s: %v", relPath, err)
 			errs = append(errs, err)
+			failed[relPath] = "read failed: " + err.Error()
 			continue
 		}
 
 		lang := scanner.DetectLanguage(filepath.Base(relPath))
 
-		chunks, err := chunker.ChunkFile(absPath, code, lang, nil)
-		if err != nil {
+		chunks, err := chunker.ChunkFile(absPath, code, lang, opts)
+		if errors.Is(err, chunker.ErrParsePanic) {
+			// The parser choked on the file; keep its whole-file chunk
+			// but report the error.
+			errs = append(errs, err)
+		} else if err != nil {
 			log.Printf("pipeline: warning: chunking failed for %s: %v", relPath, err)
 			errs = append(errs, err)
+			failed[relPath] = "chunking failed: " + err.Error()
+			continue
+		}
+		if len(chunks) == 0 {
+			empty = append(empty, relPath)
 			continue
 		}
 
 		allChunks = append(allChunks, chunks...)
 	}
 
-	return allChunks, errs
+	return allChunks, empty, failed, errs
+}
+
+// unanalyzedFiles returns the files, relative to scanRoot, that had chunks
+// but none of whose chunks became an atom, each with a failure reason.
+func unanalyzedFiles(chunks []chunker.Chunk, analyzed []*atoms.Atom, scanRoot string) map[string]string {
+	units := make(map[string]int)
+	for _, c := range chunks {
+		units[c.FilePath]++
+	}
+	for _, a := range analyzed {
+		delete(units, a.FilePath)
+	}
+	failed := make(map[string]string, len(units))
+	for path, n := range units {
+		relPath, err := filepath.Rel(scanRoot, path)
+		if err != nil {
+			continue
+		}
+		failed[filepath.ToSlash(relPath)] = fmt.Sprintf("analysis failed for all %d unit(s)", n)
+	}
+	return failed
+}
+
+// skipFailedFiles drops from files those whose failure record has reached
+// maxAttempts and whose content is unchanged since, adding each to
+// result.SkippedFailed.
+func skipFailedFiles(mf *manifest.Manifest, files []string, root string, maxAttempts int, result *Result) []string {
+	kept := make([]string, 0, len(files))
+	for _, relPath := range files {
+		f, ok := mf.Failure(relPath)
+		if ok && f.Attempts >= maxAttempts {
+			hash, err := mf.ComputeHash(filepath.Join(root, relPath))
+			if err == nil && hash == f.Hash {
+				result.SkippedFailed = append(result.SkippedFailed, FailedFile{Path: relPath, Reason: f.Reason, Attempts: f.Attempts})
+				continue
+			}
+		}
+		kept = append(kept, relPath)
+	}
+	return kept
+}
+
+// recordFailure notes in the manifest that relPath failed on this run and
+// adds it to result.FailedFiles. The file keeps no manifest entry, so an
+// incremental run picks it up again.
+func recordFailure(mf *manifest.Manifest, root, relPath, reason string, result *Result) {
+	hash, _ := mf.ComputeHash(filepath.Join(root, relPath))
+	f := mf.RecordFailure(relPath, hash, reason)
+	result.FailedFiles = append(result.FailedFiles, FailedFile{Path: relPath, Reason: reason, Attempts: f.Attempts})
+}
+
+// newStore returns the project's Store, routing any layers configured in
+// LayerBackends to their own backend.
+func newStore(cfg Config) *storage.Store {
+	store := storage.NewStore(cfg.MemoriesClient, cfg.ProjectName, cfg.MemoriesNamespace)
+	if len(cfg.LayerBackends) > 0 {
+		store.SetLayerBackends(cfg.LayerBackends)
+	}
+	return store
+}
+
+// structuralAtoms turns chunks into atoms carrying only their name, kind,
+// file and line range, for modules-only runs that skip atom analysis.
+func structuralAtoms(chunks []atoms.Chunk) []*atoms.Atom {
+	out := make([]*atoms.Atom, len(chunks))
+	for i, c := range chunks {
+		out[i] = &atoms.Atom{
+			Name:      c.Name,
+			Kind:      c.Kind,
+			FilePath:  c.FilePath,
+			StartLine: c.StartLine,
+			EndLine:   c.EndLine,
+		}
+	}
+	return out
+}
+
+// sortModules orders modules by name, then path, and each module's files
+// by path, so every phase visits them, and stores their results, in the
+// same order on every run.
+func sortModules(modules []scanner.Module) {
+	sort.SliceStable(modules, func(i, j int) bool {
+		if modules[i].Name != modules[j].Name {
+			return modules[i].Name < modules[j].Name
+		}
+		return modules[i].RelPath < modules[j].RelPath
+	})
+	for _, m := range modules {
+		sort.Strings(m.Files)
+	}
+}
+
+// sortAtoms orders atoms by file, then start line, for reproducible
+// stored output.
+func sortAtoms(list []*atoms.Atom) {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		return a.StartLine < b.StartLine
+	})
+}
+
+// validateLinks checks the modules' wiring against the units in inputs
+// when Config.ValidateLinks is set, and returns how many edges referred
+// to none of them.
+func validateLinks(cfg Config, analyses []analyzer.ModuleAnalysis, inputs []analyzer.ModuleInput, logFn func(level, msg string)) int {
+	if cfg.ValidateLinks == "" {
+		return 0
+	}
+	n := analyzer.ValidateLinks(analyses, inputs, cfg.ValidateLinks == LinksDrop)
+	if n > 0 {
+		verb := "Flagged"
+		if cfg.ValidateLinks == LinksDrop {
+			verb = "Dropped"
+		}
+		logFn("warn", fmt.Sprintf("%s %d wiring edge(s) referring to no known unit", verb, n))
+	}
+	return n
 }
 
 // findModuleAnalysis looks up a ModuleAnalysis by module name.
@@ -647,6 +1290,36 @@ func findModuleAnalysis(analyses []analyzer.ModuleAnalysis, name string) *analyz
 	return nil
 }
 
+// storeFailed records the failure to store a module's layer. When a batch
+// stored only some of its memories, the ones left out are counted in
+// Unstored so the partial failure shows in the run's summary.
+func (r *Result) storeFailed(layer, module string, err error) {
+	var batchErr *storage.BatchError
+	if errors.As(err, &batchErr) {
+		r.Unstored += len(batchErr.Failed)
+	}
+	log.Printf("pipeline: warning: failed to store %s for %s: %v", layer, module, err)
+	r.Errors = append(r.Errors, fmt.Errorf("store %s for %s: %w", layer, module, err))
+}
+
+// graphEdges converts a module's wiring for storage as single edges.
+func graphEdges(wiring []analyzer.Dependency) []storage.GraphEdge {
+	edges := make([]storage.GraphEdge, len(wiring))
+	for i, d := range wiring {
+		edges[i] = storage.GraphEdge{From: d.From, To: d.To, Reason: d.Reason, Unresolved: d.Unresolved}
+	}
+	return edges
+}
+
+// graphZones converts a module's zones for storage as single zones.
+func graphZones(zones []analyzer.Zone) []storage.GraphZone {
+	out := make([]storage.GraphZone, len(zones))
+	for i, z := range zones {
+		out[i] = storage.GraphZone{Name: z.Name, Intent: z.Intent, Files: z.Files}
+	}
+	return out
+}
+
 // countModuleFiles counts the total files across all modules.
 func countModuleFiles(modules []scanner.Module) int {
 	n := 0
@@ -681,6 +1354,42 @@ func buildPatternsInput(projectName string, synthesis *analyzer.SystemSynthesis,
 	return input
 }
 
+// formatAtomSummary lists a module's atoms by file. It is stored as the
+// module-level atoms memory; the atoms themselves are stored separately.
+func formatAtomSummary(module string, list []*atoms.Atom) string {
+	var files []string
+	byFile := make(map[string][]string)
+	for _, a := range list {
+		if _, ok := byFile[a.FilePath]; !ok {
+			files = append(files, a.FilePath)
+		}
+		byFile[a.FilePath] = append(byFile[a.FilePath], fmt.Sprintf("%s (%s)", a.Name, a.Kind))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Module %s: %d atoms in %d files\n", module, len(list), len(files))
+	for _, f := range files {
+		fmt.Fprintf(&b, "%s: %s\n", f, strings.Join(byFile[f], ", "))
+	}
+	return b.String()
+}
+
+// maxStoredSource caps the original source stored with each atom, in bytes.
+const maxStoredSource = 8 * 1024
+
+// storedSource returns code cut to maxStoredSource at a line boundary,
+// noting the cut.
+func storedSource(code string) string {
+	if len(code) <= maxStoredSource {
+		return code
+	}
+	cut := code[:maxStoredSource]
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i+1]
+	}
+	return cut + "… (truncated)\n"
+}
+
 // formatAtomEntry formats an atom as a searchable text entry for storage.
 func formatAtomEntry(a *atoms.Atom) string {
 	var b strings.Builder
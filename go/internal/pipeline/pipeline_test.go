@@ -2,16 +2,24 @@ package pipeline
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"context"
 
 	"github.com/divyekant/carto/internal/llm"
+	"github.com/divyekant/carto/internal/manifest"
+	"github.com/divyekant/carto/internal/scanner"
 	"github.com/divyekant/carto/internal/sources"
 	"github.com/divyekant/carto/internal/storage"
 )
@@ -101,7 +109,22 @@ func (m *mockMemories) Search(query string, opts storage.SearchOptions) ([]stora
 }
 
 func (m *mockMemories) ListBySource(source string, limit, offset int) ([]storage.SearchResult, error) {
-	return nil, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []storage.SearchResult
+	for _, mem := range m.memories {
+		if strings.HasPrefix(mem.source, source) {
+			out = append(out, storage.SearchResult{Text: mem.text, Source: mem.source})
+		}
+	}
+	if offset >= len(out) {
+		return nil, nil
+	}
+	out = out[offset:]
+	if limit > 0 && limit < len(out) {
+		out = out[:limit]
+	}
+	return out, nil
 }
 
 func (m *mockMemories) Count(sourcePrefix string) (int, error) {
@@ -112,7 +135,15 @@ func (m *mockMemories) DeleteBySource(prefix string) (int, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.deletions = append(m.deletions, prefix)
-	return 0, nil
+	kept := m.memories[:0]
+	for _, mem := range m.memories {
+		if !strings.HasPrefix(mem.source, prefix) {
+			kept = append(kept, mem)
+		}
+	}
+	deleted := len(m.memories) - len(kept)
+	m.memories = kept
+	return deleted, nil
 }
 
 func (m *mockMemories) getDeletions() []string {
@@ -339,6 +370,149 @@ func TestRun_ModuleFilter(t *testing.T) {
 	if !strings.Contains(err.Error(), "not found") {
 		t.Errorf("expected 'not found' in error, got: %v", err)
 	}
+	if !errors.Is(err, ErrModuleNotFound) {
+		t.Errorf("errors.Is(err, ErrModuleNotFound) = false for %v", err)
+	}
+}
+
+// indexedFiles returns the sorted relative paths recorded in the manifest
+// after a run.
+func indexedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	mf, err := manifest.Load(dir)
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	var files []string
+	for rel := range mf.Files {
+		files = append(files, filepath.ToSlash(rel))
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestRun_FileListIndexesOnlyListedFiles(t *testing.T) {
+	dir := createTempProject(t)
+	if err := os.WriteFile(filepath.Join(dir, "pkg", "more.go"), []byte("package pkg\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// pkg/util.go was indexed before and is in the same module as the
+	// listed files; its stored atoms must survive a run that skips it.
+	mem := &mockMemories{healthy: true}
+	seed := storage.NewStore(mem, "test-project")
+	if err := seed.StoreAtoms("example.com/testproject", "summary", []storage.AtomEntry{{Key: "pkg/util.go:3", Text: "Add adds"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      &mockLLM{},
+		MemoriesClient: mem,
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+		FileList:       []string{"./main.go", "pkg/more.go", "gone.go"},
+	})
+	if err != nil {
+		t.Fatalf("Run returned fatal error: %v", err)
+	}
+
+	if result.FilesIndexed != 2 {
+		t.Errorf("FilesIndexed = %d, want 2", result.FilesIndexed)
+	}
+	if got := indexedFiles(t, dir); !slices.Equal(got, []string{"main.go", "pkg/more.go"}) {
+		t.Errorf("indexed files = %v, want only the listed ones", got)
+	}
+	if !slices.Equal(result.MissingFiles, []string{"gone.go"}) {
+		t.Errorf("MissingFiles = %v, want [gone.go]", result.MissingFiles)
+	}
+	var kept bool
+	for _, m := range mem.getMemories() {
+		if strings.HasSuffix(m.source, "/layer:atoms/pkg/util.go:3") {
+			kept = true
+		}
+	}
+	if !kept {
+		t.Error("stored atoms of the unlisted pkg/util.go were deleted")
+	}
+}
+
+func TestRun_IncludeGlobRestrictsFiles(t *testing.T) {
+	dir := createTempProject(t)
+	result, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      &mockLLM{},
+		MemoriesClient: &mockMemories{healthy: true},
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+		IncludeGlobs:   []string{"pkg/**"},
+	})
+	if err != nil {
+		t.Fatalf("Run returned fatal error: %v", err)
+	}
+	if result.FilesIndexed != 1 {
+		t.Errorf("FilesIndexed: got %d, want 1", result.FilesIndexed)
+	}
+	if got := indexedFiles(t, dir); len(got) != 1 || got[0] != "pkg/util.go" {
+		t.Errorf("expected only pkg/util.go indexed, got %v", got)
+	}
+}
+
+func TestRun_ExcludeGlobRemovesFiles(t *testing.T) {
+	dir := createTempProject(t)
+	_, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      &mockLLM{},
+		MemoriesClient: &mockMemories{healthy: true},
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+		ExcludeGlobs:   []string{"**/util.go"},
+	})
+	if err != nil {
+		t.Fatalf("Run returned fatal error: %v", err)
+	}
+	got := indexedFiles(t, dir)
+	if slices.Contains(got, "pkg/util.go") {
+		t.Errorf("excluded file was indexed: %v", got)
+	}
+	if !slices.Contains(got, "main.go") {
+		t.Errorf("expected main.go to still be indexed, got %v", got)
+	}
+}
+
+func TestRun_SkipsGeneratedFilesForAtoms(t *testing.T) {
+	gen := "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pkg\n\nfunc Marshal() {}\n\nfunc Unmarshal() {}\n"
+
+	run := func(includeGenerated bool) *Result {
+		dir := createTempProject(t)
+		if err := os.WriteFile(filepath.Join(dir, "pkg", "api.pb.go"), []byte(gen), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		result, err := Run(Config{
+			ProjectName:      "test-project",
+			RootPath:         dir,
+			LLMClient:        &mockLLM{},
+			MemoriesClient:   &mockMemories{healthy: true},
+			MaxWorkers:       1,
+			SkipSkillFiles:   true,
+			IncludeGenerated: includeGenerated,
+		})
+		if err != nil {
+			t.Fatalf("Run returned fatal error: %v", err)
+		}
+		return result
+	}
+
+	skipped, included := run(false), run(true)
+	if skipped.FilesIndexed != included.FilesIndexed {
+		t.Errorf("generated files should still be counted: %d vs %d", skipped.FilesIndexed, included.FilesIndexed)
+	}
+	if skipped.AtomsCreated >= included.AtomsCreated {
+		t.Errorf("expected fewer atoms when skipping generated files: %d vs %d", skipped.AtomsCreated, included.AtomsCreated)
+	}
 }
 
 func TestRun_IncrementalManifest(t *testing.T) {
@@ -366,9 +540,9 @@ func TestRun_IncrementalManifest(t *testing.T) {
 	}
 
 	// Verify manifest was created.
-	manifestPath := filepath.Join(dir, ".carto", "manifest.json")
+	manifestPath := manifest.Path(dir)
 	if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
-		t.Fatal("manifest.json was not created after first run")
+		t.Fatal("manifest was not created after first run")
 	}
 
 	llmClient.mu.Lock()
@@ -405,6 +579,304 @@ func TestRun_IncrementalManifest(t *testing.T) {
 	}
 }
 
+func TestRun_IncrementalExcludeKeepsFilteredFiles(t *testing.T) {
+	dir := createTempProject(t)
+	mem := &mockMemories{healthy: true}
+	run := func(exclude []string) {
+		t.Helper()
+		if _, err := Run(Config{
+			ProjectName:    "test-project",
+			RootPath:       dir,
+			LLMClient:      &mockLLM{},
+			MemoriesClient: mem,
+			MaxWorkers:     1,
+			Incremental:    true,
+			SkipSkillFiles: true,
+			ExcludeGlobs:   exclude,
+		}); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	}
+
+	run(nil)
+	var utilAtoms int
+	for _, m := range mem.getMemories() {
+		if strings.Contains(m.source, "util.go") {
+			utilAtoms++
+		}
+	}
+	if utilAtoms == 0 {
+		t.Fatal("first run stored no atoms for pkg/util.go")
+	}
+
+	// Excluding a file for one run must not treat it as deleted.
+	run([]string{"**/util.go"})
+	var kept int
+	for _, m := range mem.getMemories() {
+		if strings.Contains(m.source, "util.go") {
+			kept++
+		}
+	}
+	if kept != utilAtoms {
+		t.Errorf("pkg/util.go has %d stored atoms after the excluded run, want %d", kept, utilAtoms)
+	}
+	mf, err := manifest.Load(dir)
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	for _, f := range []string{"main.go", "pkg/util.go"} {
+		if _, ok := mf.Files[f]; !ok {
+			t.Errorf("manifest lost %s after an incremental run excluding pkg/util.go", f)
+		}
+	}
+}
+
+// wiringLLM is a mockLLM whose module analysis reports one wiring edge.
+type wiringLLM struct {
+	mockLLM
+	from, to string
+}
+
+func (m *wiringLLM) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error) {
+	if tier == llm.TierDeep && !strings.Contains(prompt, "Synthesize") {
+		return json.RawMessage(fmt.Sprintf(`{
+			"wiring": [{"from": %q, "to": %q, "reason": "calls Helper"}],
+			"module_intent": "A test module."
+		}`, m.from, m.to)), nil
+	}
+	return m.mockLLM.CompleteJSON(prompt, tier, opts)
+}
+
+func TestRun_IncrementalKeepsWiringOfUnchangedFiles(t *testing.T) {
+	dir := createTempProject(t)
+	for rel, code := range map[string]string{
+		"pkg/helper.go":  "package pkg\n\nfunc Helper() int { return 1 }\n",
+		"pkg/run.go":     "package pkg\n\nimport \"example.com/testproject/pkg/sub\"\n\nfunc Run() int { return sub.Do() + Helper() }\n",
+		"pkg/sub/sub.go": "package sub\n\nfunc Do() int { return 2 }\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, rel)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(code), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(mem *mockMemories) {
+		t.Helper()
+		if _, err := Run(Config{
+			ProjectName:    "test-project",
+			RootPath:       dir,
+			LLMClient:      &wiringLLM{from: "pkg/run.go", to: "pkg/helper.go"},
+			MemoriesClient: mem,
+			MaxWorkers:     1,
+			Incremental:    true,
+			SkipSkillFiles: true,
+		}); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	}
+	run(&mockMemories{healthy: true})
+
+	// Only run.go changes. Its call to Helper in the unchanged helper.go
+	// must still count as a static edge, not contradict the LLM's.
+	if err := os.WriteFile(filepath.Join(dir, "pkg/run.go"), []byte("package pkg\n\nimport \"example.com/testproject/pkg/sub\"\n\n// Run adds.\nfunc Run() int { return sub.Do() + Helper() }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mem := &mockMemories{healthy: true}
+	run(mem)
+	var found bool
+	for _, m := range mem.getMemories() {
+		if strings.HasSuffix(m.source, "/layer:wiring/edge:pkg/run.go->pkg/helper.go") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("the incremental run dropped the wiring edge pkg/run.go -> pkg/helper.go")
+	}
+}
+
+func TestRun_AtomKeysAreRelativeToRoot(t *testing.T) {
+	dir := createTempProject(t)
+	mem := &mockMemories{healthy: true}
+	if _, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      &mockLLM{},
+		MemoriesClient: mem,
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+	}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	var found bool
+	for _, m := range mem.getMemories() {
+		if strings.Contains(m.source, dir) {
+			t.Errorf("source %q names the checkout path", m.source)
+		}
+		if strings.Contains(m.source, "/layer:atoms/pkg/util.go:") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("no atom stored under the relative key pkg/util.go:{line}")
+	}
+}
+
+// failingFileLLM fails every fast-tier analysis of code containing marker.
+type failingFileLLM struct {
+	mockLLM
+	marker string
+}
+
+func (m *failingFileLLM) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error) {
+	if tier == llm.TierFast && strings.Contains(prompt, m.marker) {
+		return nil, errors.New("model returned invalid JSON")
+	}
+	return m.mockLLM.CompleteJSON(prompt, tier, opts)
+}
+
+func TestRun_SkipsRepeatedlyFailingFiles(t *testing.T) {
+	dir := createTempProject(t)
+	llmClient := &failingFileLLM{marker: "func Add("}
+	mem := &mockMemories{healthy: true}
+	run := func(retryFailed bool) *Result {
+		t.Helper()
+		result, err := Run(Config{
+			ProjectName:    "test-project",
+			RootPath:       dir,
+			LLMClient:      llmClient,
+			MemoriesClient: mem,
+			MaxWorkers:     1,
+			Incremental:    true,
+			SkipSkillFiles: true,
+			RetryFailed:    retryFailed,
+		})
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return result
+	}
+
+	// The first two runs analyze pkg/util.go and record each failure.
+	for attempt := 1; attempt <= 2; attempt++ {
+		result := run(false)
+		if len(result.FailedFiles) != 1 || result.FailedFiles[0].Path != "pkg/util.go" || result.FailedFiles[0].Attempts != attempt {
+			t.Fatalf("run %d FailedFiles = %+v, want pkg/util.go at attempt %d", attempt, result.FailedFiles, attempt)
+		}
+		if len(result.SkippedFailed) != 0 {
+			t.Errorf("run %d SkippedFailed = %+v, want none", attempt, result.SkippedFailed)
+		}
+	}
+
+	mf, err := manifest.Load(dir)
+	if err != nil {
+		t.Fatalf("load manifest: %v", err)
+	}
+	f, ok := mf.Failure("pkg/util.go")
+	if !ok || f.Attempts != 2 || !strings.Contains(f.Reason, "analysis failed") {
+		t.Errorf("manifest failure = %+v (found %v), want 2 analysis failures", f, ok)
+	}
+	if _, indexed := mf.Files["pkg/util.go"]; indexed {
+		t.Error("failed file should not be recorded as indexed")
+	}
+
+	// The third run skips it without calling the LLM.
+	llmClient.mu.Lock()
+	callsBefore := llmClient.calls
+	llmClient.mu.Unlock()
+	result := run(false)
+	if len(result.SkippedFailed) != 1 || result.SkippedFailed[0].Path != "pkg/util.go" {
+		t.Fatalf("third run SkippedFailed = %+v, want pkg/util.go", result.SkippedFailed)
+	}
+	if result.FilesIndexed != 0 || len(result.FailedFiles) != 0 {
+		t.Errorf("third run indexed %d file(s), failed %+v; want none", result.FilesIndexed, result.FailedFiles)
+	}
+	llmClient.mu.Lock()
+	callsAfter := llmClient.calls
+	llmClient.mu.Unlock()
+	if callsAfter != callsBefore {
+		t.Errorf("third run made %d LLM call(s), want 0", callsAfter-callsBefore)
+	}
+
+	// RetryFailed processes it again.
+	result = run(true)
+	if len(result.SkippedFailed) != 0 {
+		t.Errorf("retry run SkippedFailed = %+v, want none", result.SkippedFailed)
+	}
+	if len(result.FailedFiles) != 1 || result.FailedFiles[0].Attempts != 3 {
+		t.Errorf("retry run FailedFiles = %+v, want pkg/util.go at attempt 3", result.FailedFiles)
+	}
+}
+
+func TestRun_GitignoreChangeClearsNowIgnoredFiles(t *testing.T) {
+	dir := createTempProject(t)
+	// A nested module that will be ignored between runs.
+	vendorDir := filepath.Join(dir, "thirdparty")
+	if err := os.MkdirAll(vendorDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vendorDir, "go.mod"), []byte("module example.com/thirdparty\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vendorDir, "lib.go"), []byte("package thirdparty\n\nfunc Lib() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mem := &mockMemories{healthy: true}
+	run := func() {
+		t.Helper()
+		if _, err := Run(Config{
+			ProjectName:    "test-project",
+			RootPath:       dir,
+			LLMClient:      &mockLLM{},
+			MemoriesClient: mem,
+			MaxWorkers:     2,
+			Incremental:    true,
+			SkipSkillFiles: true,
+		}); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	}
+	storedUnder := func(module string) int {
+		n := 0
+		for _, m := range mem.getMemories() {
+			if strings.HasPrefix(m.source, "carto/test-project/"+module+"/") {
+				n++
+			}
+		}
+		return n
+	}
+
+	run()
+	if storedUnder("example.com/thirdparty") == 0 {
+		t.Fatal("expected memories for the thirdparty module after the first run")
+	}
+	if !slices.Contains(indexedFiles(t, dir), "thirdparty/lib.go") {
+		t.Fatal("expected thirdparty/lib.go in the manifest after the first run")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("thirdparty/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run()
+
+	if n := storedUnder("example.com/thirdparty"); n != 0 {
+		t.Errorf("expected now-ignored module memories to be cleared, %d remain", n)
+	}
+	for _, f := range indexedFiles(t, dir) {
+		if strings.HasPrefix(f, "thirdparty/") {
+			t.Errorf("now-ignored file %s still in manifest", f)
+		}
+	}
+	mf, err := manifest.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf.IgnoreHash == "" {
+		t.Error("expected ignore hash to be recorded in the manifest")
+	}
+}
+
 func TestRun_ProgressPhases(t *testing.T) {
 	dir := createTempProject(t)
 	llmClient := &mockLLM{}
@@ -643,6 +1115,172 @@ func TestRun_AtomsStoredIndividually(t *testing.T) {
 	}
 }
 
+func TestRun_DeterministicStoredOutput(t *testing.T) {
+	dir := createTempProject(t)
+	files := map[string]string{
+		"pkg/more.go":      "package pkg\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n\nfunc Mul(a, b int) int {\n\treturn a * b\n}\n",
+		"web/package.json": `{"name": "web"}`,
+		"web/app.js":       "function start() {\n  return 1\n}\n\nfunction stop() {\n  return 0\n}\n",
+		"web/lib.js":       "function util() {\n  return 2\n}\n",
+	}
+	for rel, content := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, rel)), 0o755)
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+
+	run := func() (string, []string) {
+		t.Helper()
+		mem := &mockMemories{healthy: true}
+		result, err := Run(Config{
+			ProjectName:    "test-project",
+			RootPath:       dir,
+			LLMClient:      &mockLLM{},
+			MemoriesClient: mem,
+			MaxWorkers:     4,
+			SkipSkillFiles: true,
+		})
+		if err != nil {
+			t.Fatalf("Run returned fatal error: %v", err)
+		}
+		var b strings.Builder
+		for _, m := range mem.getMemories() {
+			fmt.Fprintf(&b, "%s\n%s\n", m.source, m.text)
+		}
+		var modules []string
+		for _, ma := range result.ModuleAnalyses {
+			modules = append(modules, ma.ModuleName)
+		}
+		return b.String(), modules
+	}
+
+	first, modules := run()
+	if !sort.StringsAreSorted(modules) || len(modules) != 2 {
+		t.Errorf("modules = %v, want both modules in name order", modules)
+	}
+	for i := 0; i < 3; i++ {
+		if again, _ := run(); again != first {
+			t.Fatalf("run %d stored different output:\n%s\n--- first run ---\n%s", i+2, again, first)
+		}
+	}
+}
+
+func TestRun_ModulesOnlySkipsAtomAnalysis(t *testing.T) {
+	dir := createTempProject(t)
+	llmClient := &mockLLM{}
+	mem := &mockMemories{healthy: true}
+
+	result, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      llmClient,
+		MemoriesClient: mem,
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+		ModulesOnly:    true,
+	})
+	if err != nil {
+		t.Fatalf("Run returned fatal error: %v", err)
+	}
+
+	llmClient.mu.Lock()
+	for _, tier := range llmClient.tiers {
+		if tier == llm.TierFast {
+			t.Errorf("made a fast-tier call in modules-only mode")
+		}
+	}
+	llmClient.mu.Unlock()
+
+	if result.AtomsCreated != 0 {
+		t.Errorf("AtomsCreated: got %d, want 0", result.AtomsCreated)
+	}
+	if len(result.ModuleAnalyses) < 1 {
+		t.Errorf("ModuleAnalyses: got %d, want >= 1", len(result.ModuleAnalyses))
+	}
+	if result.Synthesis == nil || result.Synthesis.Blueprint == "" {
+		t.Fatalf("expected a synthesized blueprint, got %+v", result.Synthesis)
+	}
+	for _, m := range mem.getMemories() {
+		if strings.Contains(m.source, "layer:atoms") {
+			t.Errorf("stored atom memory %q in modules-only mode", m.source)
+		}
+	}
+}
+
+func TestRun_DeclarationlessFiles(t *testing.T) {
+	dir := createTempProject(t)
+	gen := "package main\n\nimport _ \"embed\"\n\n//go:generate stringer -type=Kind\n"
+	if err := os.WriteFile(filepath.Join(dir, "gen.go"), []byte(gen), 0o644); err != nil {
+		t.Fatalf("write gen.go: %v", err)
+	}
+
+	run := func(noFallback bool) *Result {
+		t.Helper()
+		result, err := Run(Config{
+			ProjectName:    "test-project",
+			RootPath:       dir,
+			LLMClient:      &mockLLM{},
+			MemoriesClient: &mockMemories{healthy: true},
+			MaxWorkers:     1,
+			SkipSkillFiles: true,
+			NoFileFallback: noFallback,
+		})
+		if err != nil {
+			t.Fatalf("Run returned fatal error: %v", err)
+		}
+		return result
+	}
+
+	withFallback := run(false)
+	if len(withFallback.EmptyFiles) != 0 {
+		t.Errorf("with fallback: EmptyFiles = %v, want none", withFallback.EmptyFiles)
+	}
+
+	without := run(true)
+	if !reflect.DeepEqual(without.EmptyFiles, []string{"gen.go"}) {
+		t.Errorf("NoFileFallback: EmptyFiles = %v, want [gen.go]", without.EmptyFiles)
+	}
+	if without.AtomsCreated != withFallback.AtomsCreated-1 {
+		t.Errorf("NoFileFallback: AtomsCreated = %d, want one fewer than %d", without.AtomsCreated, withFallback.AtomsCreated)
+	}
+}
+
+func TestRun_StoresModuleIntent(t *testing.T) {
+	dir := createTempProject(t)
+	mem := &mockMemories{healthy: true}
+
+	result, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       dir,
+		LLMClient:      &mockLLM{},
+		MemoriesClient: mem,
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+	})
+	if err != nil {
+		t.Fatalf("Run returned fatal error: %v", err)
+	}
+	if result.Modules == 0 {
+		t.Fatal("expected at least one module")
+	}
+
+	store := storage.NewStore(mem, "test-project")
+	mods, err := scanner.Scan(dir)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	for _, mod := range mods.Modules {
+		results, err := store.RetrieveLayer(mod.Name, storage.LayerIntent)
+		if err != nil {
+			t.Fatalf("RetrieveLayer(%s): %v", mod.Name, err)
+		}
+		if len(results) != 1 || results[0].Text != "A test module for pipeline validation." {
+			t.Errorf("intent for %s = %+v", mod.Name, results)
+		}
+	}
+}
+
 func TestRun_GeneratesSkillFiles(t *testing.T) {
 	// Verify the pipeline generates CLAUDE.md and .cursorrules after indexing.
 	dir := createTempProject(t)
@@ -742,6 +1380,116 @@ func TestRun_MemoriesUnhealthy(t *testing.T) {
 	if !strings.Contains(err.Error(), "unreachable") {
 		t.Errorf("expected 'unreachable' in error, got: %v", err)
 	}
+	if !errors.Is(err, ErrMemoriesUnreachable) {
+		t.Errorf("errors.Is(err, ErrMemoriesUnreachable) = false for %v", err)
+	}
+}
+
+// slowLLM delays every call so a run outlasts a short Config.Timeout.
+// incompatibleMemories is a healthy backend whose server API version the
+// client does not support.
+type incompatibleMemories struct{ mockMemories }
+
+func (m *incompatibleMemories) CheckCompatible() error {
+	return fmt.Errorf("%w: server 3.0.0 speaks API v2", storage.ErrIncompatibleServer)
+}
+
+func TestRun_MemoriesIncompatible(t *testing.T) {
+	_, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       createTempProject(t),
+		LLMClient:      &mockLLM{},
+		MemoriesClient: &incompatibleMemories{mockMemories{healthy: true}},
+		MaxWorkers:     1,
+	})
+	if !errors.Is(err, storage.ErrIncompatibleServer) {
+		t.Errorf("expected ErrIncompatibleServer at pre-flight, got %v", err)
+	}
+}
+
+// partialMemories stores all but the first memory of each batch and
+// reports that one as not stored, as the real client does when a memory
+// is still rejected after splitting and retries.
+type partialMemories struct{ mockMemories }
+
+func (m *partialMemories) AddBatch(memories []storage.Memory) error {
+	if err := m.mockMemories.AddBatch(memories[1:]); err != nil {
+		return err
+	}
+	return &storage.BatchError{Total: len(memories), Failed: []int{0}, Err: errors.New("memories API error 413")}
+}
+
+func TestRun_PartialBatchFailureIsReported(t *testing.T) {
+	result, err := Run(Config{
+		ProjectName:    "test-project",
+		RootPath:       createTempProject(t),
+		LLMClient:      &mockLLM{},
+		MemoriesClient: &partialMemories{mockMemories{healthy: true}},
+		MaxWorkers:     1,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Unstored == 0 {
+		t.Error("Unstored = 0, want the memories the batches left out")
+	}
+	var found bool
+	for _, e := range result.Errors {
+		var batchErr *storage.BatchError
+		if errors.As(e, &batchErr) && strings.Contains(e.Error(), "store atoms for") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Errors = %v, want the atoms batch failure", result.Errors)
+	}
+}
+
+type slowLLM struct {
+	mockLLM
+	delay time.Duration
+}
+
+func (m *slowLLM) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error) {
+	time.Sleep(m.delay)
+	return m.mockLLM.CompleteJSON(prompt, tier, opts)
+}
+
+func TestRun_Timeout(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/slow\n\ngo 1.21\n"), 0o644)
+	var src strings.Builder
+	src.WriteString("package main\n")
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&src, "\nfunc f%d() int {\n\treturn %d\n}\n", i, i)
+	}
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte(src.String()), 0o644)
+
+	start := time.Now()
+	result, err := Run(Config{
+		ProjectName:    "slow",
+		RootPath:       dir,
+		LLMClient:      &slowLLM{delay: 20 * time.Millisecond},
+		MemoriesClient: &mockMemories{healthy: true},
+		MaxWorkers:     1,
+		SkipSkillFiles: true,
+		Timeout:        100 * time.Millisecond,
+	})
+	if !errors.Is(err, ErrTimedOut) {
+		t.Fatalf("expected ErrTimedOut, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("run took %s after a 100ms timeout", elapsed)
+	}
+	if result == nil {
+		t.Fatal("expected a partial result with ErrTimedOut")
+	}
+	if result.Modules != 1 || result.FilesIndexed == 0 {
+		t.Errorf("partial result = %d modules, %d files; want the scan counts", result.Modules, result.FilesIndexed)
+	}
+	if result.AtomsCreated >= 30 {
+		t.Errorf("AtomsCreated = %d, want fewer than all 30 units", result.AtomsCreated)
+	}
 }
 
 func TestRun_CancelledContext(t *testing.T) {
@@ -770,3 +1518,21 @@ func TestRun_CancelledContext(t *testing.T) {
 		t.Errorf("expected 0 stored memories, got %d", len(mem.getMemories()))
 	}
 }
+
+func TestStoredSource_CapsAtLineBoundary(t *testing.T) {
+	short := "func A() {}\n"
+	if got := storedSource(short); got != short {
+		t.Errorf("short source changed: %q", got)
+	}
+
+	line := strings.Repeat("x", 99) + "\n"
+	long := strings.Repeat(line, 200) // 20k bytes
+	got := storedSource(long)
+	if len(got) > maxStoredSource+len("… (truncated)\n") {
+		t.Errorf("stored %d bytes, want at most about %d", len(got), maxStoredSource)
+	}
+	body, ok := strings.CutSuffix(got, "… (truncated)\n")
+	if !ok || !strings.HasSuffix(body, "\n") || len(body)%len(line) != 0 {
+		t.Errorf("source not cut at a line boundary: ...%q", got[len(got)-30:])
+	}
+}
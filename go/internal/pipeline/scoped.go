@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/divyekant/carto/internal/analyzer"
+	"github.com/divyekant/carto/internal/sources"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+// synthesizeScoped is system synthesis for incremental runs with
+// Config.ScopedSynthesis. Rather than synthesizing the whole system from
+// this run's analyses alone, it updates the stored blueprint and patterns
+// for the freshly analyzed modules plus their direct wiring neighbors,
+// whose analyses it loads from the store. The stored analyses of every
+// other module are neither read in full nor touched. Without a stored
+// blueprint to update, it synthesizes the whole system from the fresh and
+// stored analyses.
+func synthesizeScoped(ctx context.Context, store *storage.Store, deep *analyzer.DeepAnalyzer, fresh []analyzer.ModuleAnalysis, scanned map[string]bool, decisions []sources.Artifact, logFn func(level, msg string)) (*analyzer.SystemSynthesis, error) {
+	stored, err := storedWiring(store, fresh, scanned)
+	if err != nil {
+		return nil, fmt.Errorf("load stored wiring: %w", err)
+	}
+
+	var prior analyzer.SystemSynthesis
+	results, err := store.RetrieveLayer("_system", storage.LayerBlueprint)
+	if err != nil {
+		return nil, fmt.Errorf("load stored blueprint: %w", err)
+	}
+	if len(results) > 0 {
+		prior.Blueprint = results[len(results)-1].Text
+	}
+	if strings.TrimSpace(prior.Blueprint) == "" {
+		logFn("info", "No stored blueprint to update, synthesizing the whole system")
+		for i := range stored {
+			loadStoredAnalysis(store, &stored[i])
+		}
+		return deep.SynthesizeSystemCtx(ctx, slices.Concat(fresh, stored), decisions...)
+	}
+	latestJSON(store, "_system", storage.LayerPatterns, &prior.Patterns)
+
+	changed := make([]string, len(fresh))
+	for i, ma := range fresh {
+		changed[i] = ma.ModuleName
+	}
+	neighbors := analyzer.Neighbors(slices.Concat(fresh, stored), changed)
+	scope := slices.Clone(fresh)
+	for _, ma := range stored {
+		if slices.Contains(neighbors, ma.ModuleName) {
+			loadStoredAnalysis(store, &ma)
+			scope = append(scope, ma)
+		}
+	}
+
+	logFn("info", fmt.Sprintf("Updating the stored blueprint for %d changed module(s) and %d neighbor(s)", len(fresh), len(neighbors)))
+	return deep.UpdateSynthesisCtx(ctx, prior, scope, decisions...)
+}
+
+// storedWiring returns, in name order, the stored wiring of every scanned
+// module not analyzed in this run, as analyses holding just the name and
+// wiring. Modules with no stored wiring are left out.
+func storedWiring(store *storage.Store, fresh []analyzer.ModuleAnalysis, scanned map[string]bool) ([]analyzer.ModuleAnalysis, error) {
+	byModule, err := store.RetrieveLayerAllModules(storage.LayerWiring)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []analyzer.ModuleAnalysis
+	for mod, results := range byModule {
+		if !scanned[mod] || findModuleAnalysis(fresh, mod) != nil {
+			continue
+		}
+		for i := len(results) - 1; i >= 0; i-- {
+			var wiring []analyzer.Dependency
+			if json.Unmarshal([]byte(results[i].Text), &wiring) == nil {
+				stored = append(stored, analyzer.ModuleAnalysis{ModuleName: mod, Wiring: wiring})
+				break
+			}
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].ModuleName < stored[j].ModuleName })
+	return stored, nil
+}
+
+// loadStoredAnalysis fills in a stored module's zones and intent. Read
+// failures are logged and leave them empty.
+func loadStoredAnalysis(store *storage.Store, ma *analyzer.ModuleAnalysis) {
+	latestJSON(store, ma.ModuleName, storage.LayerZones, &ma.Zones)
+	results, err := store.RetrieveLayer(ma.ModuleName, storage.LayerIntent)
+	if err != nil {
+		log.Printf("pipeline: warning: failed to load stored intent for %s: %v", ma.ModuleName, err)
+		return
+	}
+	if len(results) > 0 {
+		ma.ModuleIntent = results[len(results)-1].Text
+	}
+}
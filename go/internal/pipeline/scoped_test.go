@@ -0,0 +1,134 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/divyekant/carto/internal/llm"
+)
+
+// wiredLLM answers module analysis with wiring from web to api, and
+// records the deep analyses and synthesis prompts it is asked for.
+type wiredLLM struct {
+	mu         sync.Mutex
+	analyzed   []string
+	syntheses  []string
+	updateSeen bool
+}
+
+func (m *wiredLLM) CompleteJSON(prompt string, tier llm.Tier, opts *llm.CompleteOptions) (json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case tier == llm.TierFast:
+		return json.RawMessage(`{"clarified_code": "function f() {}", "summary": "A function.", "imports": [], "exports": ["f"]}`), nil
+	case strings.HasPrefix(prompt, "Synthesize"), strings.HasPrefix(prompt, "Update"):
+		m.syntheses = append(m.syntheses, prompt)
+		if strings.HasPrefix(prompt, "Update") {
+			m.updateSeen = true
+			return json.RawMessage(`{"blueprint": "Billing is independent; web calls the reworked api.", "patterns": ["thin handlers"]}`), nil
+		}
+		return json.RawMessage(`{"blueprint": "Billing is independent; web calls api.", "patterns": ["thin handlers"]}`), nil
+	}
+
+	for _, mod := range []string{"api", "web", "billing"} {
+		if strings.HasPrefix(prompt, `Analyze the module "`+mod+`"`) {
+			m.analyzed = append(m.analyzed, mod)
+			wiring := `[]`
+			if mod == "web" {
+				wiring = `[{"from": "web/app.js", "to": "api", "reason": "fetches data"}]`
+			}
+			return json.RawMessage(`{"wiring": ` + wiring + `, "zones": [{"name": "` + mod + `-core", "intent": "core"}], "module_intent": "The ` + mod + ` module."}`), nil
+		}
+	}
+	return json.RawMessage(`{}`), nil
+}
+
+func TestRun_ScopedSynthesisUpdatesOnlyChangedModulesAndNeighbors(t *testing.T) {
+	dir := t.TempDir()
+	for rel, content := range map[string]string{
+		"api/package.json":     `{"name": "api"}`,
+		"api/index.js":         "function serve() {\n  return 1\n}\n",
+		"web/package.json":     `{"name": "web"}`,
+		"web/app.js":           "function render() {\n  return 2\n}\n",
+		"billing/package.json": `{"name": "billing"}`,
+		"billing/charge.js":    "function charge() {\n  return 3\n}\n",
+	} {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, rel)), 0o755)
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", rel, err)
+		}
+	}
+
+	llmClient := &wiredLLM{}
+	mem := &mockMemories{healthy: true}
+	run := func() *Result {
+		t.Helper()
+		result, err := Run(Config{
+			ProjectName:     "test-project",
+			RootPath:        dir,
+			LLMClient:       llmClient,
+			MemoriesClient:  mem,
+			MaxWorkers:      1,
+			SkipSkillFiles:  true,
+			Incremental:     true,
+			ScopedSynthesis: true,
+		})
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return result
+	}
+
+	run()
+	if llmClient.updateSeen {
+		t.Fatal("first run has no blueprint to update and should synthesize the whole system")
+	}
+	billing := func() string {
+		var b strings.Builder
+		for _, m := range mem.getMemories() {
+			if strings.HasPrefix(m.source, "carto/test-project/billing/") {
+				b.WriteString(m.source + "\n" + m.text + "\n")
+			}
+		}
+		return b.String()
+	}
+	billingBefore := billing()
+	if billingBefore == "" {
+		t.Fatal("first run stored nothing for billing")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "api", "index.js"), []byte("function serve() {\n  return 42\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	llmClient.analyzed, llmClient.syntheses = nil, nil
+
+	result := run()
+
+	if len(llmClient.analyzed) != 1 || llmClient.analyzed[0] != "api" {
+		t.Errorf("deep analysis ran for %v, want only api", llmClient.analyzed)
+	}
+	if len(llmClient.syntheses) != 1 || !llmClient.updateSeen {
+		t.Fatalf("want one blueprint update, got %d synthesis prompt(s)", len(llmClient.syntheses))
+	}
+	prompt := llmClient.syntheses[0]
+	for _, want := range []string{"Billing is independent; web calls api.", "## Module: api", "## Module: web", "The web module."} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("update prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "## Module: billing") {
+		t.Errorf("update prompt should leave out billing, which is not wired to api:\n%s", prompt)
+	}
+	if result.Synthesis == nil || !strings.Contains(result.Synthesis.Blueprint, "reworked") {
+		t.Errorf("Synthesis = %+v, want the updated blueprint", result.Synthesis)
+	}
+	if after := billing(); after != billingBefore {
+		t.Errorf("billing's stored analysis changed:\nbefore:\n%s\nafter:\n%s", billingBefore, after)
+	}
+}
@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/divyekant/carto/internal/llm"
+	"github.com/divyekant/carto/internal/tracing"
+)
+
+func TestRun_EmitsTraceSpans(t *testing.T) {
+	// One response that satisfies the atom, module and synthesis schemas.
+	answer, _ := json.Marshal(map[string]any{
+		"summary": "does things", "imports": []string{}, "exports": []string{},
+		"wiring": []any{}, "zones": []any{}, "module_intent": "a test module",
+		"blueprint": "a test system", "patterns": []string{},
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"content": [{"type": "text", "text": %q}], "stop_reason": "end_turn", "usage": {"input_tokens": 100, "output_tokens": 20}}`, answer)
+	}))
+	defer srv.Close()
+
+	exp := &tracing.InMemoryExporter{}
+	tracer := tracing.NewTracer(exp)
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	result, err := Run(Config{
+		ProjectName:    "traced",
+		RootPath:       createTempProject(t),
+		LLMClient:      llm.NewClient(llm.Options{APIKey: "test-key", BaseURL: srv.URL, FastModel: "claude-haiku-4-5", DeepModel: "claude-opus-4-6"}),
+		MemoriesClient: &mockMemories{healthy: true},
+		MaxWorkers:     2,
+		SkipSkillFiles: true,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	spans := exp.Spans()
+	byID := make(map[[8]byte]tracing.SpanData)
+	for _, s := range spans {
+		byID[s.SpanID] = s
+	}
+	parentName := func(s tracing.SpanData) string { return byID[s.ParentID].Name }
+
+	var root tracing.SpanData
+	phases := make(map[string]bool)
+	modules := make(map[string]int) // phase -> module spans under it
+	var fast, deep int
+	for _, s := range spans {
+		switch s.Name {
+		case "index":
+			root = s
+		case "scan", "atoms", "history", "analysis", "synthesis", "store":
+			if parentName(s) != "index" {
+				t.Errorf("phase span %q has parent %q, want index", s.Name, parentName(s))
+			}
+			phases[s.Name] = true
+		case "module":
+			if s.Attr("carto.module") == nil {
+				t.Errorf("module span under %s has no carto.module attribute", parentName(s))
+			}
+			modules[parentName(s)]++
+		case "llm.complete":
+			if s.Attr("llm.input_tokens") != int64(100) || s.Attr("llm.output_tokens") != int64(20) {
+				t.Errorf("llm span tokens = %v/%v, want 100/20", s.Attr("llm.input_tokens"), s.Attr("llm.output_tokens"))
+			}
+			switch s.Attr("llm.tier") {
+			case "fast":
+				fast++
+				if s.Attr("llm.model") != "claude-haiku-4-5" || parentName(s) != "module" {
+					t.Errorf("fast llm span: model %v under %q, want claude-haiku-4-5 under a module", s.Attr("llm.model"), parentName(s))
+				}
+			case "deep":
+				deep++
+				if s.Attr("llm.model") != "claude-opus-4-6" {
+					t.Errorf("deep llm span model = %v", s.Attr("llm.model"))
+				}
+			}
+		}
+	}
+
+	if root.Name == "" || root.Attr("carto.project") != "traced" {
+		t.Fatalf("no index span for the project among %d spans", len(spans))
+	}
+	for _, s := range spans {
+		if s.TraceID != root.TraceID {
+			t.Errorf("span %q is outside the run's trace", s.Name)
+		}
+	}
+	for _, p := range []string{"scan", "atoms", "history", "analysis", "synthesis", "store"} {
+		if !phases[p] {
+			t.Errorf("missing %s phase span", p)
+		}
+	}
+	for _, p := range []string{"atoms", "history", "analysis", "store"} {
+		if modules[p] != 1 {
+			t.Errorf("%d module span(s) under %s, want 1", modules[p], p)
+		}
+	}
+	// One fast-tier call per atom; one module analysis and the synthesis
+	// on the deep tier.
+	if fast != result.AtomsCreated || deep != 2 {
+		t.Errorf("llm spans: %d fast, %d deep; want %d and 2", fast, deep, result.AtomsCreated)
+	}
+}
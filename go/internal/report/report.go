@@ -0,0 +1,335 @@
+// Package report renders a self-contained HTML architecture report for an
+// indexed project from the layers stored in Memories. The output has inline
+// CSS and an inline SVG dependency graph, so it can be opened offline or
+// attached to a ticket without any external assets.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/divyekant/carto/internal/history"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+// defaultHotspots is how many hotspot files the report lists.
+const defaultHotspots = 15
+
+// Report is everything rendered into the HTML page.
+type Report struct {
+	Project     string
+	GeneratedAt time.Time
+	Blueprint   string
+	Patterns    []string
+	Modules     []Module
+	Edges       []Edge
+	Hotspots    []history.Hotspot
+}
+
+// Module is one module's section of the report.
+type Module struct {
+	Name   string
+	Intent string // the module's intent, as stored in the intent layer
+	Zones  []Zone
+	Wiring []Dependency
+}
+
+// Zone is a business domain grouping, as stored in the zones layer.
+type Zone struct {
+	Name   string   `json:"name"`
+	Intent string   `json:"intent"`
+	Files  []string `json:"files"`
+}
+
+// Dependency is a wiring edge, as stored in the wiring layer.
+type Dependency struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+}
+
+// Edge is a module-level dependency derived from wiring edges that cross
+// module boundaries.
+type Edge struct {
+	From  string
+	To    string
+	Count int
+}
+
+// Load assembles a Report for project from the stored blueprint, patterns,
+// intent, zones, wiring and history layers. It only reads from Memories;
+// nothing is re-analyzed.
+func Load(store *storage.Store, project string, now time.Time) (*Report, error) {
+	r := &Report{Project: project, GeneratedAt: now}
+
+	if res, err := store.RetrieveLayer("_system", storage.LayerBlueprint); err != nil {
+		return nil, fmt.Errorf("report: retrieve blueprint: %w", err)
+	} else if len(res) > 0 {
+		r.Blueprint = res[0].Text
+	}
+
+	if res, err := store.RetrieveLayer("_system", storage.LayerPatterns); err == nil && len(res) > 0 {
+		json.Unmarshal([]byte(res[0].Text), &r.Patterns)
+	}
+
+	intents, err := store.RetrieveLayerAllModules(storage.LayerIntent)
+	if err != nil {
+		return nil, fmt.Errorf("report: retrieve intents: %w", err)
+	}
+	zones, err := store.RetrieveLayerAllModules(storage.LayerZones)
+	if err != nil {
+		return nil, fmt.Errorf("report: retrieve zones: %w", err)
+	}
+	wiring, err := store.RetrieveLayerAllModules(storage.LayerWiring)
+	if err != nil {
+		return nil, fmt.Errorf("report: retrieve wiring: %w", err)
+	}
+	histories, err := store.RetrieveHistory()
+	if err != nil {
+		return nil, fmt.Errorf("report: retrieve history: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for _, m := range []map[string][]storage.SearchResult{intents, zones, wiring} {
+		for name := range m {
+			// Underscore-prefixed pseudo-modules (_system, _knowledge)
+			// hold project-wide data, not code.
+			if !strings.HasPrefix(name, "_") {
+				names[name] = true
+			}
+		}
+	}
+	for name := range histories {
+		if !strings.HasPrefix(name, "_") {
+			names[name] = true
+		}
+	}
+
+	for name := range names {
+		mod := Module{Name: name}
+		// The most recently stored intent is the current one.
+		for i := len(intents[name]) - 1; i >= 0; i-- {
+			if text := strings.TrimSpace(intents[name][i].Text); text != "" {
+				mod.Intent = text
+				break
+			}
+		}
+		for _, res := range zones[name] {
+			var zs []Zone
+			if json.Unmarshal([]byte(res.Text), &zs) == nil {
+				mod.Zones = append(mod.Zones, zs...)
+			}
+		}
+		for _, res := range wiring[name] {
+			var deps []Dependency
+			if json.Unmarshal([]byte(res.Text), &deps) == nil {
+				mod.Wiring = append(mod.Wiring, deps...)
+			}
+		}
+		r.Modules = append(r.Modules, mod)
+	}
+	sort.Slice(r.Modules, func(i, j int) bool { return r.Modules[i].Name < r.Modules[j].Name })
+
+	r.Edges = moduleEdges(r.Modules, histories)
+	r.Hotspots = history.RankHotspots(histories, now, defaultHotspots)
+	return r, nil
+}
+
+// moduleEdges lifts file-level wiring to module-level edges. A file is owned
+// by a module if it appears in that module's history or as the source of
+// one of its wiring edges; an edge whose target is owned by (or named
+// after) another module becomes a module -> module edge.
+func moduleEdges(modules []Module, histories map[string][]*history.FileHistory) []Edge {
+	owner := make(map[string]string)
+	isModule := make(map[string]bool, len(modules))
+	for _, m := range modules {
+		isModule[m.Name] = true
+		for _, fh := range histories[m.Name] {
+			owner[fh.FilePath] = m.Name
+		}
+		for _, d := range m.Wiring {
+			if _, ok := owner[d.From]; !ok {
+				owner[d.From] = m.Name
+			}
+		}
+	}
+
+	counts := make(map[[2]string]int)
+	for _, m := range modules {
+		for _, d := range m.Wiring {
+			target, ok := owner[d.To]
+			if !ok && isModule[d.To] {
+				target = d.To
+			}
+			if target == "" || target == m.Name {
+				continue
+			}
+			counts[[2]string{m.Name, target}]++
+		}
+	}
+
+	edges := make([]Edge, 0, len(counts))
+	for k, n := range counts {
+		edges = append(edges, Edge{From: k[0], To: k[1], Count: n})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// Render writes the report as a single self-contained HTML document.
+func Render(w io.Writer, r *Report) error {
+	return pageTemplate.Execute(w, struct {
+		*Report
+		Graph template.HTML
+	}{r, renderGraph(r.Modules, r.Edges)})
+}
+
+// renderGraph draws modules on a circle with arrows for module-level edges.
+// Names are escaped here because the result is injected as trusted HTML.
+func renderGraph(modules []Module, edges []Edge) template.HTML {
+	if len(modules) == 0 {
+		return ""
+	}
+
+	const size = 520.0
+	center := size / 2
+	radius := center - 90
+	if len(modules) == 1 {
+		radius = 0
+	}
+
+	pos := make(map[string][2]float64, len(modules))
+	for i, m := range modules {
+		angle := 2*math.Pi*float64(i)/float64(len(modules)) - math.Pi/2
+		pos[m.Name] = [2]float64{center + radius*math.Cos(angle), center + radius*math.Sin(angle)}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" role="img" aria-label="Module dependency graph">`, size, size)
+	b.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="7" markerHeight="7" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#8a8170"/></marker></defs>`)
+
+	const nodeRadius = 28.0
+	for _, e := range edges {
+		from, to := pos[e.From], pos[e.To]
+		dx, dy := to[0]-from[0], to[1]-from[1]
+		dist := math.Hypot(dx, dy)
+		if dist == 0 {
+			continue
+		}
+		// Stop lines at the node border so arrowheads stay visible.
+		ux, uy := dx/dist, dy/dist
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" class="edge" marker-end="url(#arrow)"><title>%s → %s (%d)</title></line>`,
+			from[0]+ux*nodeRadius, from[1]+uy*nodeRadius, to[0]-ux*nodeRadius, to[1]-uy*nodeRadius,
+			html.EscapeString(e.From), html.EscapeString(e.To), e.Count)
+	}
+	for _, m := range modules {
+		p := pos[m.Name]
+		fmt.Fprintf(&b, `<g class="node"><circle cx="%.1f" cy="%.1f" r="%.0f"/><text x="%.1f" y="%.1f">%s</text></g>`,
+			p[0], p[1], nodeRadius, p[0], p[1]+nodeRadius+16, html.EscapeString(m.Name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+var pageTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02")
+	},
+	"paragraphs": func(s string) []string {
+		var out []string
+		for _, p := range strings.Split(s, "\n\n") {
+			if p = strings.TrimSpace(p); p != "" {
+				out = append(out, p)
+			}
+		}
+		return out
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Project}} — Carto architecture report</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; color: #2b2925; background: #faf8f4; margin: 0; line-height: 1.55; }
+main { max-width: 960px; margin: 0 auto; padding: 2rem 1.5rem 4rem; }
+h1 { margin-bottom: 0; }
+h2 { border-bottom: 2px solid #d4a030; padding-bottom: .25rem; margin-top: 2.5rem; }
+h3 { margin-bottom: .25rem; }
+.meta { color: #8a8170; margin-top: .25rem; }
+.module { background: #fff; border: 1px solid #e6e1d6; border-radius: 6px; padding: .75rem 1.25rem; margin: 1rem 0; }
+table { border-collapse: collapse; width: 100%; font-size: .9rem; }
+th, td { text-align: left; padding: .35rem .5rem; border-bottom: 1px solid #e6e1d6; vertical-align: top; }
+th { color: #8a8170; font-weight: 600; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: .85em; }
+.empty { color: #8a8170; font-style: italic; }
+svg { width: 100%; max-width: 520px; display: block; margin: 0 auto; }
+svg .edge { stroke: #8a8170; stroke-width: 1.5; }
+svg .node circle { fill: #fdf3dc; stroke: #d4a030; stroke-width: 2; }
+svg .node text { font-size: 12px; text-anchor: middle; fill: #2b2925; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Project}}</h1>
+<p class="meta">Architecture report generated by Carto on {{date .GeneratedAt}}</p>
+
+<h2>Blueprint</h2>
+{{with .Blueprint}}{{range paragraphs .}}<p>{{.}}</p>
+{{end}}{{else}}<p class="empty">No blueprint stored. Run a full index to generate one.</p>{{end}}
+
+<h2>Patterns</h2>
+{{with .Patterns}}<ul>
+{{range .}}<li>{{.}}</li>
+{{end}}</ul>{{else}}<p class="empty">No patterns stored.</p>{{end}}
+
+<h2>Module Dependencies</h2>
+{{if .Modules}}{{.Graph}}
+{{with .Edges}}<table>
+<tr><th>From</th><th>To</th><th>Edges</th></tr>
+{{range .}}<tr><td>{{.From}}</td><td>{{.To}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>{{else}}<p class="empty">No cross-module dependencies detected.</p>{{end}}{{else}}<p class="empty">No modules indexed.</p>{{end}}
+
+<h2>Hotspots</h2>
+{{with .Hotspots}}<table>
+<tr><th>File</th><th>Module</th><th>Churn</th><th>Authors</th><th>Last commit</th></tr>
+{{range .}}<tr><td><code>{{.FilePath}}</code></td><td>{{.Module}}</td><td>{{printf "%.0f" .Churn}}</td><td>{{.Authors}}</td><td>{{date .LastCommit}}</td></tr>
+{{end}}</table>{{else}}<p class="empty">No history stored.</p>{{end}}
+
+<h2>Modules</h2>
+{{range .Modules}}<section class="module" id="module-{{.Name}}">
+<h3>{{.Name}}</h3>
+{{with .Intent}}{{range paragraphs .}}<p>{{.}}</p>
+{{end}}{{else}}<p class="empty">No intent stored.</p>{{end}}
+{{with .Zones}}<table>
+<tr><th>Zone</th><th>Intent</th><th>Files</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.Intent}}</td><td>{{len .Files}}</td></tr>
+{{end}}</table>{{else}}<p class="empty">No zones stored.</p>{{end}}
+{{with .Wiring}}<details><summary>{{len .}} wiring edges</summary>
+<table>
+<tr><th>From</th><th>To</th><th>Reason</th></tr>
+{{range .}}<tr><td><code>{{.From}}</code></td><td><code>{{.To}}</code></td><td>{{.Reason}}</td></tr>
+{{end}}</table>
+</details>{{end}}
+</section>
+{{else}}<p class="empty">No modules indexed.</p>
+{{end}}
+</main>
+</body>
+</html>
+`))
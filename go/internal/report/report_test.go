@@ -0,0 +1,128 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/divyekant/carto/internal/history"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+// fakeMemories serves stored layers from an in-memory map keyed by source.
+type fakeMemories struct {
+	bySource map[string][]string
+}
+
+func (f *fakeMemories) Health() (bool, error)                 { return true, nil }
+func (f *fakeMemories) AddMemory(storage.Memory) (int, error) { return 0, nil }
+func (f *fakeMemories) AddBatch([]storage.Memory) error       { return nil }
+func (f *fakeMemories) DeleteBySource(string) (int, error)    { return 0, nil }
+func (f *fakeMemories) Count(string) (int, error)             { return 0, nil }
+func (f *fakeMemories) Search(string, storage.SearchOptions) ([]storage.SearchResult, error) {
+	return nil, nil
+}
+
+func (f *fakeMemories) ListBySource(source string, limit, offset int) ([]storage.SearchResult, error) {
+	var out []storage.SearchResult
+	for src, texts := range f.bySource {
+		if !strings.HasPrefix(src, source) {
+			continue
+		}
+		for _, t := range texts {
+			out = append(out, storage.SearchResult{Text: t, Source: src})
+		}
+	}
+	if offset >= len(out) {
+		return nil, nil
+	}
+	out = out[offset:]
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRender_IncludesBlueprintAndModuleSections(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	mem := &fakeMemories{bySource: map[string][]string{
+		"carto/shop/_system/layer:blueprint": {"Shop is a storefront with an API and a worker."},
+		"carto/shop/_system/layer:patterns":  {mustJSON(t, []string{"Handlers delegate to services"})},
+		"carto/shop/api/layer:intent":        {"Serves the storefront HTTP API."},
+		"carto/shop/api/layer:zones": {mustJSON(t, []Zone{
+			{Name: "checkout", Intent: "Takes payment for a cart", Files: []string{"api/checkout.go"}},
+		})},
+		"carto/shop/api/layer:wiring": {mustJSON(t, []Dependency{
+			{From: "api/checkout.go", To: "worker/queue.go", Reason: "enqueues fulfilment"},
+		})},
+		"carto/shop/worker/layer:history": {mustJSON(t, []*history.FileHistory{
+			{FilePath: "worker/queue.go", Authors: []string{"ana"}, ChurnScore: 4,
+				Commits: []history.CommitInfo{{Date: now.Add(-24 * time.Hour).Format(time.RFC3339)}}},
+		})},
+	}}
+
+	rep, err := Load(storage.NewStore(mem, "shop"), "shop", now)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(rep.Modules) != 2 {
+		t.Fatalf("expected modules api and worker, got %+v", rep.Modules)
+	}
+	if rep.Modules[0].Intent != "Serves the storefront HTTP API." || rep.Modules[1].Intent != "" {
+		t.Errorf("expected the api intent only, got %q and %q", rep.Modules[0].Intent, rep.Modules[1].Intent)
+	}
+	if len(rep.Edges) != 1 || rep.Edges[0].From != "api" || rep.Edges[0].To != "worker" {
+		t.Errorf("expected api -> worker module edge, got %+v", rep.Edges)
+	}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, rep); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"Shop is a storefront with an API and a worker.",
+		`<section class="module" id="module-api">`,
+		`<section class="module" id="module-worker">`,
+		"Serves the storefront HTTP API.",
+		"No intent stored.",
+		"Takes payment for a cart",
+		"Handlers delegate to services",
+		"<svg",
+		"worker/queue.go",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+	if strings.Contains(out, "<link") || strings.Contains(out, "<script src") {
+		t.Error("report must not reference external assets")
+	}
+}
+
+func TestRender_EscapesStoredText(t *testing.T) {
+	rep := &Report{
+		Project:   "x",
+		Blueprint: "<script>alert(1)</script>",
+		Modules:   []Module{{Name: "<b>mod</b>"}},
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, rep); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if strings.Contains(buf.String(), "<script>alert") || strings.Contains(buf.String(), "<b>mod</b>") {
+		t.Error("stored text must be HTML-escaped")
+	}
+}
@@ -0,0 +1,126 @@
+package scanner
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Source encodings recorded on FileInfo.Encoding.
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingUTF8BOM = "utf-8-bom"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+	EncodingLatin1  = "latin-1"
+)
+
+// ErrNotText is returned by ToUTF8 for content that does not decode as
+// text in any supported encoding.
+var ErrNotText = errors.New("scanner: content is not decodable text")
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// DetectEncoding guesses the encoding of content from its byte order mark
+// and, without one, whether it is valid UTF-8. Any other content is taken
+// to be Latin-1. content may be a file header: a multi-byte sequence cut
+// off at the end does not count as invalid.
+func DetectEncoding(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, bomUTF8):
+		return EncodingUTF8BOM
+	case bytes.HasPrefix(content, bomUTF16LE):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(content, bomUTF16BE):
+		return EncodingUTF16BE
+	}
+	if utf8.Valid(trimPartialRune(content)) {
+		return EncodingUTF8
+	}
+	return EncodingLatin1
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence from the end of b.
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		c := b[len(b)-i]
+		if !utf8.RuneStart(c) {
+			continue
+		}
+		if !utf8.FullRune(b[len(b)-i:]) {
+			return b[:len(b)-i]
+		}
+		break
+	}
+	return b
+}
+
+// ToUTF8 transcodes content to UTF-8 without a byte order mark and returns
+// the encoding it was detected as. It returns ErrNotText for UTF-16 with
+// an odd length or unpaired surrogates, and for non-UTF-8 content with
+// control characters that text files do not contain.
+func ToUTF8(content []byte) ([]byte, string, error) {
+	enc := DetectEncoding(content)
+	switch enc {
+	case EncodingUTF8:
+		return content, enc, nil
+	case EncodingUTF8BOM:
+		return content[len(bomUTF8):], enc, nil
+	case EncodingUTF16LE, EncodingUTF16BE:
+		out, err := decodeUTF16(content[2:], enc == EncodingUTF16BE)
+		return out, enc, err
+	default:
+		out, err := decodeLatin1(content)
+		return out, enc, err
+	}
+}
+
+func decodeUTF16(b []byte, bigEndian bool) ([]byte, error) {
+	if len(b)%2 != 0 {
+		return nil, ErrNotText
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		} else {
+			units[i] = uint16(b[2*i+1])<<8 | uint16(b[2*i])
+		}
+	}
+
+	out := make([]byte, 0, len(units))
+	for i := 0; i < len(units); i++ {
+		r := rune(units[i])
+		if utf16.IsSurrogate(r) {
+			if i+1 >= len(units) {
+				return nil, ErrNotText
+			}
+			r = utf16.DecodeRune(r, rune(units[i+1]))
+			if r == utf8.RuneError {
+				return nil, ErrNotText
+			}
+			i++
+		}
+		if r == 0 {
+			return nil, ErrNotText
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return out, nil
+}
+
+func decodeLatin1(b []byte) ([]byte, error) {
+	out := make([]byte, 0, len(b)+len(b)/8)
+	for _, c := range b {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\v' {
+			return nil, ErrNotText
+		}
+		out = utf8.AppendRune(out, rune(c))
+	}
+	return out, nil
+}
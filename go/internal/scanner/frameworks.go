@@ -0,0 +1,299 @@
+package scanner
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// frameworkFiles maps key files at a module's root to the framework their
+// presence implies, whatever the dependency files say.
+var frameworkFiles = map[string]string{
+	"next.config.js":   "Next.js",
+	"next.config.mjs":  "Next.js",
+	"next.config.ts":   "Next.js",
+	"nuxt.config.js":   "Nuxt",
+	"nuxt.config.ts":   "Nuxt",
+	"angular.json":     "Angular",
+	"svelte.config.js": "SvelteKit",
+	"remix.config.js":  "Remix",
+	"astro.config.mjs": "Astro",
+	"manage.py":        "Django",
+	"artisan":          "Laravel",
+	"config/routes.rb": "Rails",
+	"pubspec.yaml":     "Flutter",
+	"serverless.yml":   "Serverless Framework",
+	"serverless.yaml":  "Serverless Framework",
+}
+
+// frameworkDeps maps dependency names, per dependency file kind, to the
+// framework they name. Names are matched exactly after lowercasing, except
+// Java coordinates, which are matched as substrings of the build file.
+var frameworkDeps = map[string][]struct{ dep, framework string }{
+	"node": {
+		{"next", "Next.js"},
+		{"nuxt", "Nuxt"},
+		{"@angular/core", "Angular"},
+		{"@sveltejs/kit", "SvelteKit"},
+		{"@remix-run/react", "Remix"},
+		{"astro", "Astro"},
+		{"@nestjs/core", "NestJS"},
+		{"express", "Express"},
+		{"fastify", "Fastify"},
+		{"koa", "Koa"},
+		{"react", "React"},
+		{"vue", "Vue"},
+		{"svelte", "Svelte"},
+		{"electron", "Electron"},
+		{"react-native", "React Native"},
+	},
+	"python": {
+		{"django", "Django"},
+		{"flask", "Flask"},
+		{"fastapi", "FastAPI"},
+		{"starlette", "Starlette"},
+		{"tornado", "Tornado"},
+		{"celery", "Celery"},
+		{"sqlalchemy", "SQLAlchemy"},
+		{"pytorch-lightning", "PyTorch Lightning"},
+		{"torch", "PyTorch"},
+		{"tensorflow", "TensorFlow"},
+		{"streamlit", "Streamlit"},
+	},
+	"java": {
+		{"spring-boot", "Spring Boot"},
+		{"org.springframework.boot", "Spring Boot"},
+		{"org.springframework", "Spring"},
+		{"io.quarkus", "Quarkus"},
+		{"io.micronaut", "Micronaut"},
+		{"io.dropwizard", "Dropwizard"},
+		{"io.vertx", "Vert.x"},
+	},
+	"rust": {
+		{"actix-web", "Actix Web"},
+		{"axum", "Axum"},
+		{"rocket", "Rocket"},
+		{"warp", "Warp"},
+		{"tauri", "Tauri"},
+		{"bevy", "Bevy"},
+		{"tokio", "Tokio"},
+	},
+	"go": {
+		{"github.com/gin-gonic/gin", "Gin"},
+		{"github.com/labstack/echo/v4", "Echo"},
+		{"github.com/gofiber/fiber/v2", "Fiber"},
+		{"github.com/go-chi/chi/v5", "chi"},
+		{"github.com/gorilla/mux", "Gorilla"},
+		{"google.golang.org/grpc", "gRPC"},
+		{"github.com/spf13/cobra", "Cobra"},
+		{"gorm.io/gorm", "GORM"},
+	},
+}
+
+// depFileKinds maps dependency files at a module's root to the kind of
+// dependencies they list.
+var depFileKinds = map[string]string{
+	"package.json":         "node",
+	"requirements.txt":     "python",
+	"requirements-dev.txt": "python",
+	"pyproject.toml":       "python",
+	"setup.py":             "python",
+	"setup.cfg":            "python",
+	"Pipfile":              "python",
+	"pom.xml":              "java",
+	"build.gradle":         "java",
+	"build.gradle.kts":     "java",
+	"Cargo.toml":           "rust",
+	"go.mod":               "go",
+}
+
+// detectFrameworks returns the frameworks a module is built on, read from
+// the dependency files and key files at its root, such as "Spring Boot"
+// from a pom.xml or "Next.js (app router)" from next.config.js and an app/
+// directory. No file is parsed beyond finding dependency names, and a
+// module with none of these files has no frameworks. files are the
+// module's paths relative to the scan root, relPath its directory.
+func detectFrameworks(rootPath, relPath string, files []string) []string {
+	var found []string
+	add := func(fw string) {
+		for _, f := range found {
+			if f == fw {
+				return
+			}
+		}
+		found = append(found, fw)
+	}
+
+	atRoot := func(rel string) (string, bool) {
+		if relPath == "" {
+			return rel, true
+		}
+		rest, ok := strings.CutPrefix(rel, relPath+"/")
+		return rest, ok
+	}
+
+	for _, rel := range files {
+		local, ok := atRoot(filepath.ToSlash(rel))
+		if !ok {
+			continue
+		}
+		if fw := frameworkFiles[local]; fw != "" {
+			add(fw)
+		}
+		kind, ok := depFileKinds[local]
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(rootPath, rel))
+		if err != nil {
+			continue
+		}
+		deps := dependencyNames(kind, local, data)
+		for _, d := range frameworkDeps[kind] {
+			if (kind == "java" && strings.Contains(string(data), d.dep)) || deps[d.dep] {
+				add(d.framework)
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+	kept := make([]string, 0, len(found))
+	for _, fw := range found {
+		if slices.ContainsFunc(frameworkBases[fw], func(b string) bool { return slices.Contains(found, b) }) {
+			continue
+		}
+		if fw == "Next.js" {
+			fw += nextRouter(relPath, files)
+		}
+		kept = append(kept, fw)
+	}
+	return kept
+}
+
+// frameworkBases maps a framework to the more specific ones built on it,
+// which make it redundant when found too: a Spring Boot service is not
+// also listed as Spring.
+var frameworkBases = map[string][]string{
+	"Spring": {"Spring Boot"},
+	"React":  {"Next.js", "Remix"},
+	"Vue":    {"Nuxt"},
+	"Svelte": {"SvelteKit"},
+}
+
+// nextRouter tells a Next.js app's routers apart by where its pages live:
+// " (app router)" for app/ or src/app/, " (pages router)" for pages/ or
+// src/pages/, and "" when neither is found.
+func nextRouter(relPath string, files []string) string {
+	pages := false
+	for _, rel := range files {
+		rel = filepath.ToSlash(rel)
+		if relPath != "" {
+			rel = strings.TrimPrefix(rel, relPath+"/")
+		}
+		rel = strings.TrimPrefix(rel, "src/")
+		dir, _, _ := strings.Cut(rel, "/")
+		base := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+		switch {
+		case dir == "app" && (base == "page" || base == "layout"):
+			return " (app router)"
+		case dir == "pages":
+			pages = true
+		}
+	}
+	if pages {
+		return " (pages router)"
+	}
+	return ""
+}
+
+// pythonDepName matches the distribution name at the start of a
+// requirement such as "Django>=4.2" or "fastapi[all]".
+var pythonDepName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*`)
+
+// dependencyNames returns the lowercased dependency names listed in a
+// dependency file. Java build files are matched by substring instead and
+// return none.
+func dependencyNames(kind, name string, data []byte) map[string]bool {
+	deps := make(map[string]bool)
+	switch kind {
+	case "node":
+		var pkg struct {
+			Dependencies     map[string]string `json:"dependencies"`
+			DevDependencies  map[string]string `json:"devDependencies"`
+			PeerDependencies map[string]string `json:"peerDependencies"`
+		}
+		if json.Unmarshal(data, &pkg) != nil {
+			return deps
+		}
+		for _, m := range []map[string]string{pkg.Dependencies, pkg.DevDependencies, pkg.PeerDependencies} {
+			for dep := range m {
+				deps[strings.ToLower(dep)] = true
+			}
+		}
+	case "python":
+		// Requirements files list one requirement per line; pyproject.toml,
+		// setup.py, setup.cfg and Pipfile list them as quoted strings or
+		// TOML keys. Taking the name at the start of every line and every
+		// quoted string covers all of them.
+		text := string(data)
+		var candidates []string
+		for _, line := range strings.Split(text, "\n") {
+			line, _, _ = strings.Cut(line, "#")
+			candidates = append(candidates, strings.TrimSpace(line))
+		}
+		if name != "requirements.txt" && name != "requirements-dev.txt" {
+			candidates = append(candidates, quotedStrings(text)...)
+		}
+		for _, c := range candidates {
+			if dep := pythonDepName.FindString(c); dep != "" {
+				deps[strings.ReplaceAll(strings.ToLower(dep), "_", "-")] = true
+			}
+		}
+	case "rust":
+		// Keys of the [dependencies] tables, `axum = "0.7"` or
+		// `tokio = { version = "1", ... }`, and [dependencies.tokio] tables.
+		inDeps := false
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "[") {
+				header := strings.Trim(line, "[]")
+				inDeps = strings.HasSuffix(header, "dependencies")
+				if _, dep, ok := strings.Cut(header, "dependencies."); ok {
+					deps[strings.ToLower(dep)] = true
+				}
+				continue
+			}
+			if key, _, ok := strings.Cut(line, "="); ok && inDeps {
+				deps[strings.ToLower(strings.Trim(strings.TrimSpace(key), `"`))] = true
+			}
+		}
+	case "go":
+		// Module paths in require directives, single-line or in a block.
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), "require "))
+			if len(fields) >= 2 && strings.Contains(fields[0], "/") {
+				deps[strings.ToLower(fields[0])] = true
+			}
+		}
+	}
+	return deps
+}
+
+// quotedStrings returns the contents of the single- and double-quoted
+// strings in text.
+func quotedStrings(text string) []string {
+	var out []string
+	for _, quote := range []string{`"`, `'`} {
+		parts := strings.Split(text, quote)
+		for i := 1; i < len(parts); i += 2 {
+			out = append(out, parts[i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// generatedSuffixes are filename endings produced by common code generators
+// (protoc, gqlgen, build_runner, Windows Forms, ...).
+var generatedSuffixes = []string{
+	".pb.go",
+	".pb.gw.go",
+	"_gen.go",
+	".gen.go",
+	"_generated.go",
+	"_pb2.py",
+	"_pb2_grpc.py",
+	".pb.ts",
+	".pb.js",
+	"_pb.js",
+	"_pb.d.ts",
+	".g.dart",
+	".freezed.dart",
+	".designer.cs",
+	".g.cs",
+}
+
+// generatedMarker matches the standard Go header (see go help generate)
+// and the markers other ecosystems use for generated files.
+var generatedMarker = regexp.MustCompile(`^(//|#|/\*|\*|--)?\s*(Code generated .* DO NOT EDIT\.?|@generated\b|<auto-generated)`)
+
+// generatedHeaderLines is how many leading lines are inspected for a
+// generated-code marker.
+const generatedHeaderLines = 10
+
+// IsGeneratedName reports whether a file name matches a known generated
+// file naming pattern such as "api.pb.go" or "schema.generated.ts".
+func IsGeneratedName(name string) bool {
+	lower := strings.ToLower(name)
+	if strings.Contains(lower, ".generated.") {
+		return true
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGeneratedMarker reports whether the first few lines of header contain
+// a generated-code marker like "// Code generated by protoc-gen-go. DO NOT EDIT."
+func hasGeneratedMarker(header []byte) bool {
+	sc := bufio.NewScanner(bytes.NewReader(header))
+	for i := 0; i < generatedHeaderLines && sc.Scan(); i++ {
+		if generatedMarker.MatchString(strings.TrimSpace(sc.Text())) {
+			return true
+		}
+	}
+	return false
+}
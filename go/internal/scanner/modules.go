@@ -3,6 +3,7 @@ package scanner
 import (
 	"encoding/json"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -15,6 +16,19 @@ type Module struct {
 	Type     string   // "go", "node", "java-maven", "java-gradle", "python", "rust", etc.
 	Manifest string   // path to manifest file (go.mod, package.json, etc.)
 	Files    []string // relative paths of files belonging to this module
+
+	// Submodule is the origin URL of the git submodule the module lies in
+	// (its path when .gitmodules gives no URL), or "" outside submodules.
+	Submodule string
+
+	// Fallback marks an "unknown" module made for files outside every
+	// manifest's tree, grouped by top-level directory.
+	Fallback bool
+
+	// Frameworks are the frameworks the module is built on, such as
+	// "Spring Boot" or "Next.js (app router)", detected from its
+	// dependency files and key files (see detectFrameworks).
+	Frameworks []string
 }
 
 // manifestDetectors maps manifest filenames to functions that return
@@ -34,15 +48,37 @@ var manifestDetectors = map[string]struct {
 	"setup.py":         {moduleType: "python", parseName: nil},
 }
 
+// nonSourceLanguages are languages whose files alone don't make a
+// directory a fallback module: docs and data rather than code.
+var nonSourceLanguages = map[string]bool{
+	"":                 true,
+	"markdown":         true,
+	"restructuredtext": true,
+	"json":             true,
+	"yaml":             true,
+	"toml":             true,
+	"xml":              true,
+}
+
 // DetectModules finds module boundaries within the scanned file set.
 // It looks for manifest files and groups files under their nearest module root.
-// If no manifests are found, the entire root is treated as a single module.
+// Source files outside every manifest's tree are grouped into fallback
+// "unknown" modules, one per top-level directory, with files directly in
+// the root in a module named after it. With no manifests at all, that root
+// module also keeps every other file, so there is always at least one module.
 func DetectModules(rootPath string, files []FileInfo) []Module {
+	return detectModules(rootPath, files, nil)
+}
+
+// detectModules is DetectModules with each submodule directory also made a
+// module boundary, so submodule files never join the parent's modules.
+func detectModules(rootPath string, files []FileInfo, submodules []Submodule) []Module {
 	type moduleInfo struct {
 		name     string
 		relPath  string
 		modType  string
 		manifest string
+		fallback bool
 	}
 
 	var modules []moduleInfo
@@ -82,20 +118,59 @@ func DetectModules(rootPath string, files []FileInfo) []Module {
 		})
 	}
 
-	// If no modules found, treat root as a single module
-	if len(modules) == 0 {
-		allPaths := make([]string, len(files))
-		for i, f := range files {
-			allPaths[i] = f.RelPath
-		}
-		return []Module{{
-			Name:     filepath.Base(rootPath),
-			Path:     rootPath,
-			RelPath:  "",
-			Type:     "unknown",
-			Manifest: "",
-			Files:    allPaths,
-		}}
+	// Submodule roots without a manifest of their own still bound a module.
+	// The root then needs one too, or its files would go unassigned.
+	hasModule := make(map[string]bool, len(modules))
+	for _, m := range modules {
+		hasModule[m.relPath] = true
+	}
+	for _, sub := range submodules {
+		relPath := filepath.FromSlash(sub.Path)
+		if hasModule[relPath] {
+			continue
+		}
+		if len(modules) == 0 {
+			modules = append(modules, moduleInfo{name: filepath.Base(rootPath), modType: "unknown"})
+			hasModule[""] = true
+		}
+		modules = append(modules, moduleInfo{name: path.Base(sub.Path), relPath: relPath, modType: "unknown"})
+		hasModule[relPath] = true
+	}
+
+	// Group source files no module covers by top-level directory. Without
+	// any manifest, the root is always a module, keeping non-source files.
+	if !hasModule[""] {
+		covered := func(rel string) bool {
+			for dir := range hasModule {
+				if strings.HasPrefix(rel, dir+"/") {
+					return true
+				}
+			}
+			return false
+		}
+		noManifest := len(modules) == 0
+		for _, f := range files {
+			if nonSourceLanguages[f.Language] || covered(f.RelPath) {
+				continue
+			}
+			top, _, nested := strings.Cut(f.RelPath, "/")
+			if !nested {
+				top = ""
+			}
+			if hasModule[top] {
+				continue
+			}
+			name := top
+			if top == "" {
+				name = filepath.Base(rootPath)
+			}
+			modules = append(modules, moduleInfo{name: name, relPath: top, modType: "unknown", fallback: true})
+			hasModule[top] = true
+		}
+		if noManifest && !hasModule[""] {
+			modules = append(modules, moduleInfo{name: filepath.Base(rootPath), modType: "unknown", fallback: true})
+			hasModule[""] = true
+		}
 	}
 
 	// Sort modules by RelPath depth (deepest first) so that file assignment
@@ -132,20 +207,34 @@ func DetectModules(rootPath string, files []FileInfo) []Module {
 		_ = assigned
 	}
 
-	result := make([]Module, len(modules))
+	result := make([]Module, 0, len(modules))
 	for i, m := range modules {
+		// A fallback root module left with no files would be empty, unless
+		// there are no other modules at all.
+		if m.fallback && len(moduleFiles[i]) == 0 && len(modules) > 1 {
+			continue
+		}
 		absPath := rootPath
 		if m.relPath != "" {
 			absPath = filepath.Join(rootPath, m.relPath)
 		}
-		result[i] = Module{
+		mod := Module{
 			Name:     m.name,
 			Path:     absPath,
 			RelPath:  m.relPath,
 			Type:     m.modType,
 			Manifest: m.manifest,
 			Files:    moduleFiles[i],
+			Fallback: m.fallback,
+		}
+		mod.Frameworks = detectFrameworks(rootPath, m.relPath, moduleFiles[i])
+		if sub := submoduleAt(submodules, m.relPath); sub != nil {
+			mod.Submodule = sub.URL
+			if sub.URL == "" {
+				mod.Submodule = sub.Path
+			}
 		}
+		result = append(result, mod)
 	}
 
 	return result
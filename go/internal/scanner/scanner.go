@@ -2,26 +2,48 @@ package scanner
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
 // FileInfo holds metadata about a single scanned source file.
 type FileInfo struct {
-	Path     string // absolute path
-	RelPath  string // relative to scan root
-	Language string // detected language name
-	Size     int64
+	Path      string // absolute path
+	RelPath   string // relative to scan root
+	Language  string // detected language name
+	Size      int64
+	Generated bool   // produced by a code generator (name pattern or header marker)
+	Encoding  string // source encoding, e.g. EncodingUTF8; see ToUTF8
 }
 
 // ScanResult contains everything discovered during a scan.
 type ScanResult struct {
-	Root    string
-	Files   []FileInfo
-	Modules []Module
+	Root          string
+	Files         []FileInfo
+	Modules       []Module
+	IgnoreHash    string          // see IgnoreHash; empty when no ignore files exist
+	LanguageStats []LanguageStats // see CountLanguages
 }
 
+// OtherLanguage labels files whose language DetectLanguage does not know.
+const OtherLanguage = "other"
+
+// LanguageStats counts the scanned files and bytes of one language.
+type LanguageStats struct {
+	Language string `json:"language"`
+	Files    int    `json:"files"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// IgnoreFiles are the ignore files read from the scan root, in order.
+// Rules in .cartoignore apply after .gitignore, so a "!pattern" there can
+// re-include something git ignores.
+var IgnoreFiles = []string{".gitignore", ".cartoignore"}
+
 // Directories that are always skipped during scanning.
 var skipDirs = map[string]bool{
 	"node_modules": true,
@@ -100,17 +122,44 @@ func readHeader(path string, n int) []byte {
 	return buf[:nr]
 }
 
+// readText reads a file and transcodes it to UTF-8. It reports false when
+// the file cannot be read or is not decodable text.
+func readText(path string) ([]byte, bool) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	text, _, err := ToUTF8(content)
+	return text, err == nil
+}
+
+// Options adjusts a scan. The zero value is what Scan uses.
+type Options struct {
+	// IncludeSubmodules scans the git submodules listed in .gitmodules,
+	// each as a separate module, instead of skipping their directories.
+	IncludeSubmodules bool
+}
+
 // Scan walks the file tree at rootPath and returns all source files and
-// detected modules. It respects .gitignore patterns and skips common
-// non-code directories and lock files.
+// detected modules. It respects .gitignore and .cartoignore patterns and
+// skips common non-code directories, git submodules and lock files.
 func Scan(rootPath string) (*ScanResult, error) {
+	return ScanWithOptions(rootPath, Options{})
+}
+
+// ScanWithOptions is Scan with the behavior adjusted by opts.
+func ScanWithOptions(rootPath string, opts Options) (*ScanResult, error) {
 	rootPath, err := filepath.Abs(rootPath)
 	if err != nil {
 		return nil, err
 	}
+	submodules := ReadGitmodules(rootPath)
 
-	// Load .gitignore patterns from the root
-	ignorer := loadGitignore(filepath.Join(rootPath, ".gitignore"))
+	// Load .gitignore and .cartoignore patterns from the root
+	ignorer := &gitignorer{}
+	for _, name := range IgnoreFiles {
+		ignorer.rules = append(ignorer.rules, loadGitignore(filepath.Join(rootPath, name)).rules...)
+	}
 
 	var files []FileInfo
 
@@ -137,6 +186,10 @@ func Scan(rootPath string) (*ScanResult, error) {
 			if skipDirs[name] {
 				return filepath.SkipDir
 			}
+			// Submodules are separate repositories; skip them unless asked.
+			if !opts.IncludeSubmodules && submoduleAt(submodules, relPath) != nil {
+				return filepath.SkipDir
+			}
 			// Check gitignore for directories
 			if ignorer.isIgnored(relPath, true) {
 				return filepath.SkipDir
@@ -144,14 +197,23 @@ func Scan(rootPath string) (*ScanResult, error) {
 			return nil
 		}
 
-		// Skip lock files
-		if lockFiles[name] {
+		// Skip lock files, and the .git file pointing a submodule or
+		// worktree checkout at its repository.
+		if lockFiles[name] || name == ".git" {
 			return nil
 		}
 
 		// Skip binary files — check extension first (fast path), then
-		// read first 512 bytes for null-byte detection if needed.
-		if isBinary(name, readHeader(path, 512)) {
+		// look for null bytes in the header. The header is 1024 bytes so
+		// it can also serve the generated-code marker check; only its
+		// first 512 bytes are checked for null bytes.
+		header := readHeader(path, 1024)
+		encoding := DetectEncoding(header)
+		if encoding == EncodingUTF16LE || encoding == EncodingUTF16BE {
+			// UTF-16 text is full of null bytes; only the extension counts.
+			header = nil
+		}
+		if isBinary(name, header) {
 			return nil
 		}
 
@@ -165,13 +227,25 @@ func Scan(rootPath string) (*ScanResult, error) {
 			return nil
 		}
 
+		// Text not in UTF-8 must decode in full, or it is skipped like a
+		// binary. The marker check then runs on the decoded header.
+		if encoding != EncodingUTF8 && encoding != EncodingUTF8BOM {
+			text, ok := readText(path)
+			if !ok {
+				return nil
+			}
+			header = text[:min(len(text), 1024)]
+		}
+
 		lang := DetectLanguage(name)
 
 		files = append(files, FileInfo{
-			Path:     path,
-			RelPath:  relPath,
-			Language: lang,
-			Size:     info.Size(),
+			Path:      path,
+			RelPath:   relPath,
+			Language:  lang,
+			Size:      info.Size(),
+			Generated: IsGeneratedName(name) || hasGeneratedMarker(header),
+			Encoding:  encoding,
 		})
 
 		return nil
@@ -180,15 +254,72 @@ func Scan(rootPath string) (*ScanResult, error) {
 		return nil, err
 	}
 
-	modules := DetectModules(rootPath, files)
+	if !opts.IncludeSubmodules {
+		submodules = nil
+	}
+	modules := detectModules(rootPath, files, submodules)
 
 	return &ScanResult{
-		Root:    rootPath,
-		Files:   files,
-		Modules: modules,
+		Root:          rootPath,
+		Files:         files,
+		Modules:       modules,
+		IgnoreHash:    IgnoreHash(rootPath),
+		LanguageStats: CountLanguages(files),
 	}, nil
 }
 
+// CountLanguages totals files and bytes per language, largest first, with
+// ties in name order. Files of unknown language count as OtherLanguage.
+func CountLanguages(files []FileInfo) []LanguageStats {
+	index := make(map[string]int)
+	var stats []LanguageStats
+	for _, f := range files {
+		lang := f.Language
+		if lang == "" {
+			lang = OtherLanguage
+		}
+		i, ok := index[lang]
+		if !ok {
+			i = len(stats)
+			index[lang] = i
+			stats = append(stats, LanguageStats{Language: lang})
+		}
+		stats[i].Files++
+		stats[i].Bytes += f.Size
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].Language < stats[j].Language
+	})
+	return stats
+}
+
+// IgnoreHash returns a SHA-256 digest over the names and contents of the
+// ignore files at rootPath, or "" if none exist. A change in the digest
+// between runs means the set of scanned files may have shrunk even though
+// no source file changed.
+func IgnoreHash(rootPath string) string {
+	h := sha256.New()
+	found := false
+	for _, name := range IgnoreFiles {
+		data, err := os.ReadFile(filepath.Join(rootPath, name))
+		if err != nil {
+			continue
+		}
+		found = true
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	if !found {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // gitignoreRule represents a single parsed .gitignore pattern.
 type gitignoreRule struct {
 	pattern  string
@@ -302,6 +433,63 @@ func matchesRule(relPath string, rule gitignoreRule) bool {
 	return false
 }
 
+// FilterGlobs restricts the scan result to files matching at least one
+// include glob (when any are given) and no exclude glob. Modules' file lists
+// are filtered the same way; modules left without files are dropped, and
+// LanguageStats is recounted. Patterns without a slash also match the file's base name, so "*.go" and
+// "**/*.go" are equivalent.
+func (r *ScanResult) FilterGlobs(include, exclude []string) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return
+	}
+
+	keep := func(relPath string) bool {
+		if len(include) > 0 && !matchesAnyGlob(include, relPath) {
+			return false
+		}
+		return !matchesAnyGlob(exclude, relPath)
+	}
+
+	files := r.Files[:0]
+	for _, f := range r.Files {
+		if keep(f.RelPath) {
+			files = append(files, f)
+		}
+	}
+	r.Files = files
+
+	modules := r.Modules[:0]
+	for _, m := range r.Modules {
+		var kept []string
+		for _, rel := range m.Files {
+			if keep(rel) {
+				kept = append(kept, rel)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		m.Files = kept
+		modules = append(modules, m)
+	}
+	r.Modules = modules
+	r.LanguageStats = CountLanguages(r.Files)
+}
+
+// matchesAnyGlob reports whether relPath matches any of the patterns.
+func matchesAnyGlob(patterns []string, relPath string) bool {
+	for _, p := range patterns {
+		p = filepath.FromSlash(p)
+		if globMatch(p, relPath) {
+			return true
+		}
+		if !strings.ContainsRune(p, filepath.Separator) && globMatch(p, filepath.Base(relPath)) {
+			return true
+		}
+	}
+	return false
+}
+
 // globMatch matches a pattern against a string, supporting:
 // - * matches any sequence of non-separator characters
 // - ** matches any sequence including separators (any number of path components)
@@ -310,6 +498,13 @@ func globMatch(pattern, name string) bool {
 	return doGlobMatch(pattern, name)
 }
 
+// GlobMatch reports whether name matches pattern with the same syntax as
+// the include and exclude patterns, for matching outside file scanning
+// such as project names.
+func GlobMatch(pattern, name string) bool {
+	return globMatch(pattern, name)
+}
+
 func doGlobMatch(pattern, name string) bool {
 	for len(pattern) > 0 {
 		switch {
@@ -3,8 +3,11 @@ package scanner
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"unicode/utf16"
 )
 
 // helper: create a file with optional content
@@ -228,6 +231,110 @@ func TestScan_GitignoreDirectoryPattern(t *testing.T) {
 	}
 }
 
+func TestScan_RespectsCartoignore(t *testing.T) {
+	root := t.TempDir()
+
+	createFile(t, filepath.Join(root, ".gitignore"), "*.gen.txt\n")
+	createFile(t, filepath.Join(root, ".cartoignore"), "fixtures/\n!keep.gen.txt\n")
+	createFile(t, filepath.Join(root, "fixtures", "big.json"), "{}")
+	createFile(t, filepath.Join(root, "keep.gen.txt"), "kept")
+	createFile(t, filepath.Join(root, "drop.gen.txt"), "dropped")
+	createFile(t, filepath.Join(root, "main.go"), "package main")
+
+	result, err := Scan(root)
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, f := range result.Files {
+		got[f.RelPath] = true
+	}
+	if got[filepath.Join("fixtures", "big.json")] || got["drop.gen.txt"] {
+		t.Errorf("ignored files were scanned: %v", got)
+	}
+	if !got["keep.gen.txt"] || !got["main.go"] {
+		t.Errorf("expected keep.gen.txt (re-included by .cartoignore) and main.go, got %v", got)
+	}
+}
+
+func TestScan_Submodules(t *testing.T) {
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n")
+	createFile(t, filepath.Join(root, "main.go"), "package main\n")
+	createFile(t, filepath.Join(root, ".gitmodules"), `[submodule "vendored lib"]
+	path = third_party/lib
+	url = https://example.com/lib.git
+`)
+	createFile(t, filepath.Join(root, "third_party", "lib", "lib.c"), "int lib(void) { return 0; }\n")
+	createFile(t, filepath.Join(root, "third_party", "lib", ".git"), "gitdir: ../../.git/modules/lib\n")
+
+	result, err := Scan(root)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	for _, f := range result.Files {
+		if filepath.Dir(f.RelPath) == filepath.Join("third_party", "lib") {
+			t.Errorf("submodule file %s scanned by default", f.RelPath)
+		}
+	}
+	if len(result.Modules) != 1 {
+		t.Errorf("expected only the root module by default, got %d", len(result.Modules))
+	}
+
+	result, err = ScanWithOptions(root, Options{IncludeSubmodules: true})
+	if err != nil {
+		t.Fatalf("ScanWithOptions: %v", err)
+	}
+	var sub, app *Module
+	for i := range result.Modules {
+		switch result.Modules[i].RelPath {
+		case filepath.Join("third_party", "lib"):
+			sub = &result.Modules[i]
+		case "":
+			app = &result.Modules[i]
+		}
+	}
+	if sub == nil || app == nil {
+		t.Fatalf("expected root and submodule modules, got %+v", result.Modules)
+	}
+	if sub.Name != "lib" || sub.Submodule != "https://example.com/lib.git" {
+		t.Errorf("submodule module = %q from %q, want lib from its URL", sub.Name, sub.Submodule)
+	}
+	if want := []string{filepath.Join("third_party", "lib", "lib.c")}; !reflect.DeepEqual(sub.Files, want) {
+		t.Errorf("submodule files = %v, want %v", sub.Files, want)
+	}
+	if app.Submodule != "" {
+		t.Errorf("root module tagged as submodule %q", app.Submodule)
+	}
+	for _, f := range app.Files {
+		if f == filepath.Join("third_party", "lib", "lib.c") {
+			t.Error("submodule file also assigned to the root module")
+		}
+	}
+}
+
+func TestIgnoreHash(t *testing.T) {
+	root := t.TempDir()
+	if h := IgnoreHash(root); h != "" {
+		t.Errorf("expected empty hash with no ignore files, got %q", h)
+	}
+
+	createFile(t, filepath.Join(root, ".gitignore"), "*.log\n")
+	first := IgnoreHash(root)
+	if first == "" {
+		t.Fatal("expected a hash once .gitignore exists")
+	}
+	if again := IgnoreHash(root); again != first {
+		t.Errorf("hash not stable: %q vs %q", first, again)
+	}
+
+	createFile(t, filepath.Join(root, ".cartoignore"), "vendor/\n")
+	if IgnoreHash(root) == first {
+		t.Error("adding .cartoignore should change the hash")
+	}
+}
+
 // --- Module Detection Tests ---
 
 func TestDetectModules_GoModule(t *testing.T) {
@@ -358,6 +465,75 @@ func TestDetectModules_NoManifest(t *testing.T) {
 	}
 }
 
+func TestDetectModules_NestedNoManifest(t *testing.T) {
+	root := t.TempDir()
+	var files []FileInfo
+	for _, rel := range []string{
+		"services/billing/internal/charge/charge.py",
+		"services/billing/internal/charge/refund.py",
+		"tools/scripts/deploy/run.sh",
+		"docs/guide/intro.md",
+	} {
+		createFile(t, filepath.Join(root, rel), "x")
+		files = append(files, FileInfo{Path: filepath.Join(root, rel), RelPath: rel, Language: DetectLanguage(rel)})
+	}
+
+	modules := DetectModules(root, files)
+	byName := map[string]Module{}
+	for _, m := range modules {
+		byName[m.Name] = m
+		if m.Type != "unknown" || !m.Fallback {
+			t.Errorf("module %q: type %q, fallback %v; want an unknown fallback module", m.Name, m.Type, m.Fallback)
+		}
+	}
+	if len(modules) != 3 {
+		t.Fatalf("got %d modules %v, want services, tools and the root", len(modules), byName)
+	}
+	if got := byName["services"].Files; len(got) != 2 || byName["services"].RelPath != "services" {
+		t.Errorf("services module = %+v, want both charge files", byName["services"])
+	}
+	if got := byName["tools"].Files; len(got) != 1 {
+		t.Errorf("tools module files = %v, want run.sh", got)
+	}
+	// Docs alone don't make a module; the root keeps them.
+	if got := byName[filepath.Base(root)].Files; len(got) != 1 || got[0] != "docs/guide/intro.md" {
+		t.Errorf("root module files = %v, want the docs", got)
+	}
+}
+
+func TestDetectModules_FilesOutsideManifestTrees(t *testing.T) {
+	root := t.TempDir()
+	var files []FileInfo
+	for _, rel := range []string{
+		"services/api/go.mod",
+		"services/api/main.go",
+		"scripts/ci/lint/check.sh",
+		"README.md",
+	} {
+		content := "x"
+		if strings.HasSuffix(rel, "go.mod") {
+			content = "module example.com/api\n"
+		}
+		createFile(t, filepath.Join(root, rel), content)
+		files = append(files, FileInfo{Path: filepath.Join(root, rel), RelPath: rel, Language: DetectLanguage(rel)})
+	}
+
+	modules := DetectModules(root, files)
+	var names []string
+	for _, m := range modules {
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "example.com/api,scripts" {
+		t.Fatalf("modules = %v, want the api module and a scripts fallback", names)
+	}
+	for _, m := range modules {
+		if m.Name == "scripts" && (len(m.Files) != 1 || !m.Fallback) {
+			t.Errorf("scripts module = %+v, want a fallback holding check.sh", m)
+		}
+	}
+}
+
 func TestDetectModules_RustCargo(t *testing.T) {
 	root := t.TempDir()
 
@@ -383,6 +559,77 @@ func TestDetectModules_RustCargo(t *testing.T) {
 	}
 }
 
+func TestScan_DetectsFrameworks(t *testing.T) {
+	root := t.TempDir()
+
+	// A Next.js app router frontend...
+	createFile(t, filepath.Join(root, "web", "package.json"), `{
+  "name": "web",
+  "dependencies": {"next": "14.2.3", "react": "18.3.1", "react-dom": "18.3.1"},
+  "devDependencies": {"typescript": "5.4.5"}
+}`)
+	createFile(t, filepath.Join(root, "web", "next.config.js"), "module.exports = {}\n")
+	createFile(t, filepath.Join(root, "web", "app", "layout.tsx"), "export default function RootLayout() { return null }\n")
+	createFile(t, filepath.Join(root, "web", "app", "dashboard", "page.tsx"), "export default function Page() { return null }\n")
+
+	// ...a Spring Boot service...
+	createFile(t, filepath.Join(root, "api", "pom.xml"), `<project>
+  <parent>
+    <groupId>org.springframework.boot</groupId>
+    <artifactId>spring-boot-starter-parent</artifactId>
+    <version>3.2.5</version>
+  </parent>
+  <artifactId>api</artifactId>
+  <dependencies>
+    <dependency>
+      <groupId>org.springframework.boot</groupId>
+      <artifactId>spring-boot-starter-web</artifactId>
+    </dependency>
+  </dependencies>
+</project>
+`)
+	createFile(t, filepath.Join(root, "api", "src", "main", "java", "App.java"), "class App {}\n")
+
+	// ...a Django app with only a requirements file...
+	createFile(t, filepath.Join(root, "admin", "requirements.txt"), "# web\nDjango>=4.2,<5\ndjango-environ==0.11\npsycopg[binary]\n")
+	createFile(t, filepath.Join(root, "admin", "views.py"), "def index(request):\n    pass\n")
+
+	// ...and a plain Go module.
+	createFile(t, filepath.Join(root, "tools", "go.mod"), "module example.com/tools\n\ngo 1.22\n")
+	createFile(t, filepath.Join(root, "tools", "main.go"), "package main\n")
+
+	result, err := Scan(root)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	got := make(map[string][]string)
+	for _, m := range result.Modules {
+		got[m.RelPath] = m.Frameworks
+	}
+	want := map[string][]string{
+		"web":   {"Next.js (app router)"},
+		"api":   {"Spring Boot"},
+		"admin": {"Django"},
+		"tools": nil,
+	}
+	for rel, fw := range want {
+		if !reflect.DeepEqual(got[rel], fw) {
+			t.Errorf("module %s frameworks = %q, want %q", rel, got[rel], fw)
+		}
+	}
+}
+
+func TestDetectFrameworks_NextPagesRouter(t *testing.T) {
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "package.json"), `{"dependencies": {"next": "13.0.0", "react": "18.2.0"}}`)
+	createFile(t, filepath.Join(root, "src", "pages", "index.tsx"), "export default function Home() { return null }\n")
+
+	got := detectFrameworks(root, "", []string{"package.json", "src/pages/index.tsx"})
+	if want := []string{"Next.js (pages router)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("frameworks = %q, want %q", got, want)
+	}
+}
+
 // --- Binary File Detection Tests ---
 
 func TestIsBinary_Extension(t *testing.T) {
@@ -550,6 +797,31 @@ func TestScan_FileInfoHasCorrectLanguage(t *testing.T) {
 	}
 }
 
+func TestScan_LanguageStats(t *testing.T) {
+	root := t.TempDir()
+
+	createFile(t, filepath.Join(root, "main.go"), "package main\n")                // 13 bytes
+	createFile(t, filepath.Join(root, "util.go"), "package main\n\nfunc f() {}\n") // 26 bytes
+	createFile(t, filepath.Join(root, "web", "app.ts"), "export {}\n")
+	createFile(t, filepath.Join(root, "web", "a.ts"), "let a = 1\n")
+	createFile(t, filepath.Join(root, "web", "b.ts"), "let b = 2\n")
+	createFile(t, filepath.Join(root, "notes.xyz"), "misc")
+
+	result, err := Scan(root)
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+
+	want := []LanguageStats{
+		{Language: "go", Files: 2, Bytes: 13 + 26},
+		{Language: "typescript", Files: 3, Bytes: 30},
+		{Language: OtherLanguage, Files: 1, Bytes: 4},
+	}
+	if !reflect.DeepEqual(result.LanguageStats, want) {
+		t.Errorf("LanguageStats = %+v, want %+v", result.LanguageStats, want)
+	}
+}
+
 func TestScan_FileInfoHasCorrectSize(t *testing.T) {
 	root := t.TempDir()
 
@@ -637,3 +909,154 @@ func splitPath(path string) []string {
 	}
 	return parts
 }
+
+// --- Generated File Detection Tests ---
+
+func TestScan_DetectsGoGeneratedHeader(t *testing.T) {
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	createFile(t, filepath.Join(root, "enum_string.go"),
+		"// Code generated by \"stringer -type=Color\"; DO NOT EDIT.\n\npackage main\n")
+	// The marker must be a standalone comment line, not a mention in code.
+	createFile(t, filepath.Join(root, "doc.go"),
+		"package main\n\n// Files with Code generated markers are skipped.\nvar x = 1\n")
+
+	result, err := Scan(root)
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, f := range result.Files {
+		got[f.RelPath] = f.Generated
+	}
+	if !got["enum_string.go"] {
+		t.Error("expected enum_string.go to be marked generated")
+	}
+	if got["main.go"] || got["doc.go"] {
+		t.Error("hand-written files must not be marked generated")
+	}
+	if _, ok := got["enum_string.go"]; !ok {
+		t.Error("generated files must still be scanned and counted")
+	}
+}
+
+func TestIsGeneratedName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"api.pb.go", true},
+		{"api.pb.gw.go", true},
+		{"models_gen.go", true},
+		{"schema.generated.ts", true},
+		{"service_pb2.py", true},
+		{"api.go", false},
+		{"generator.go", false},
+		{"pb.go", false},
+	}
+	for _, tt := range tests {
+		if got := IsGeneratedName(tt.name); got != tt.want {
+			t.Errorf("IsGeneratedName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScan_MarksPbGoAsGenerated(t *testing.T) {
+	root := t.TempDir()
+	// No header marker: the naming pattern alone is enough.
+	createFile(t, filepath.Join(root, "proto", "user.pb.go"), "package proto\n")
+
+	result, err := Scan(root)
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if len(result.Files) != 1 || !result.Files[0].Generated {
+		t.Errorf("expected user.pb.go to be marked generated, got %+v", result.Files)
+	}
+}
+
+// --- Encoding Tests ---
+
+// utf16LE encodes s as UTF-16LE with a byte order mark.
+func utf16LE(s string) []byte {
+	out := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		want    string
+		wantEnc string
+	}{
+		{"utf-8", []byte("héllo"), "héllo", EncodingUTF8},
+		{"utf-8 bom", []byte("\xEF\xBB\xBFhéllo"), "héllo", EncodingUTF8BOM},
+		{"utf-16le", utf16LE("héllo 😀"), "héllo 😀", EncodingUTF16LE},
+		{"utf-16be", []byte{0xFE, 0xFF, 0x00, 'h', 0x00, 0xE9}, "hé", EncodingUTF16BE},
+		{"latin-1", []byte("caf\xe9\n"), "café\n", EncodingLatin1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, enc, err := ToUTF8(tt.in)
+			if err != nil {
+				t.Fatalf("ToUTF8: %v", err)
+			}
+			if string(got) != tt.want || enc != tt.wantEnc {
+				t.Errorf("ToUTF8 = %q, %s; want %q, %s", got, enc, tt.want, tt.wantEnc)
+			}
+		})
+	}
+
+	for _, bad := range [][]byte{
+		{0xFF, 0xFE, 'a'},            // odd-length UTF-16
+		{0xFF, 0xFE, 0x00, 0xD8},     // unpaired surrogate
+		[]byte("\xe9\x01\x02binary"), // control characters
+	} {
+		if _, _, err := ToUTF8(bad); err != ErrNotText {
+			t.Errorf("ToUTF8(%q) err = %v, want ErrNotText", bad, err)
+		}
+	}
+}
+
+func TestDetectEncoding_TruncatedHeader(t *testing.T) {
+	// A header cut inside a multi-byte rune is still UTF-8.
+	header := []byte("// héllo")[:5]
+	if enc := DetectEncoding(header); enc != EncodingUTF8 {
+		t.Errorf("DetectEncoding(%q) = %s, want %s", header, enc, EncodingUTF8)
+	}
+}
+
+func TestScan_RecordsEncoding(t *testing.T) {
+	root := t.TempDir()
+	createFile(t, filepath.Join(root, "main.go"), "package main\n")
+	os.WriteFile(filepath.Join(root, "wide.go"), utf16LE("package main\n\n// Grüße\nfunc Wide() {}\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "legacy.py"), []byte("# caf\xe9\ndef legacy():\n    pass\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "blob.custom"), []byte("\xe9\x01\x02\x03garbage"), 0o644)
+
+	result, err := Scan(root)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	got := map[string]string{}
+	for _, f := range result.Files {
+		got[f.RelPath] = f.Encoding
+	}
+	want := map[string]string{
+		"main.go":   EncodingUTF8,
+		"wide.go":   EncodingUTF16LE,
+		"legacy.py": EncodingLatin1,
+	}
+	for path, enc := range want {
+		if got[path] != enc {
+			t.Errorf("%s: encoding %q, want %q", path, got[path], enc)
+		}
+	}
+	if _, ok := got["blob.custom"]; ok {
+		t.Error("undecodable file should be skipped like a binary")
+	}
+}
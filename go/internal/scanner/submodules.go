@@ -0,0 +1,80 @@
+package scanner
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Submodule is a git submodule declared in the scan root's .gitmodules.
+type Submodule struct {
+	Path string // relative to the scan root, slash-separated
+	URL  string // remote the submodule is cloned from; may be empty
+}
+
+// ReadGitmodules parses the .gitmodules file at rootPath, returning its
+// submodules in path order. A missing or unreadable file yields none.
+func ReadGitmodules(rootPath string) []Submodule {
+	f, err := os.Open(filepath.Join(rootPath, ".gitmodules"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var subs []Submodule
+	var cur *Submodule
+	flush := func() {
+		if cur != nil && cur.Path != "" {
+			subs = append(subs, *cur)
+		}
+		cur = nil
+	}
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			flush()
+			if strings.HasPrefix(line, "[submodule") {
+				cur = &Submodule{}
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "path":
+			cur.Path = path.Clean(strings.Trim(filepath.ToSlash(value), "/"))
+		case "url":
+			cur.URL = value
+		}
+	}
+	flush()
+
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Path < subs[j].Path })
+	return subs
+}
+
+// submoduleAt returns the submodule whose directory is relPath or contains
+// it, or nil.
+func submoduleAt(subs []Submodule, relPath string) *Submodule {
+	relPath = filepath.ToSlash(relPath)
+	for i := range subs {
+		if relPath == subs[i].Path || strings.HasPrefix(relPath, subs[i].Path+"/") {
+			return &subs[i]
+		}
+	}
+	return nil
+}
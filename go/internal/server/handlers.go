@@ -4,21 +4,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/divyekant/carto/internal/analyzer"
 	"github.com/divyekant/carto/internal/config"
+	"github.com/divyekant/carto/internal/duplicates"
 	"github.com/divyekant/carto/internal/gitclone"
+	"github.com/divyekant/carto/internal/history"
 	"github.com/divyekant/carto/internal/llm"
 	"github.com/divyekant/carto/internal/manifest"
 	"github.com/divyekant/carto/internal/pipeline"
+	"github.com/divyekant/carto/internal/scanner"
 	"github.com/divyekant/carto/internal/sources"
 	"github.com/divyekant/carto/internal/storage"
 )
@@ -37,6 +43,8 @@ type ProjectInfo struct {
 	Path      string    `json:"path"`
 	IndexedAt time.Time `json:"indexed_at"`
 	FileCount int       `json:"file_count"`
+
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
 // writeJSON marshals v as JSON and writes it to the response with the given status.
@@ -52,13 +60,20 @@ func writeError(w http.ResponseWriter, status int, msg string) {
 }
 
 // handleListProjects scans projectsDir for subdirectories that contain a
-// .carto/manifest.json and returns their metadata as a JSON array.
+// .carto manifest and returns their metadata as a JSON array.
 func (s *Server) handleListProjects(w http.ResponseWriter, r *http.Request) {
 	if s.projectsDir == "" {
 		writeJSON(w, http.StatusOK, []ProjectInfo{})
 		return
 	}
 
+	// ?filter=key=value (repeatable) keeps projects carrying every tag.
+	filters, err := manifest.ParseTags(r.URL.Query()["filter"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	entries, err := os.ReadDir(s.projectsDir)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to read projects directory")
@@ -80,12 +95,17 @@ func (s *Server) handleListProjects(w http.ResponseWriter, r *http.Request) {
 		if mf.IsEmpty() && mf.Project == "" {
 			continue
 		}
+		meta, err := manifest.LoadMeta(projectRoot)
+		if err != nil || !meta.Matches(filters) {
+			continue
+		}
 
 		projects = append(projects, ProjectInfo{
 			Name:      mf.Project,
 			Path:      projectRoot,
 			IndexedAt: mf.IndexedAt,
 			FileCount: len(mf.Files),
+			Tags:      meta.Tags,
 		})
 	}
 
@@ -102,6 +122,13 @@ type queryRequest struct {
 	Project string `json:"project"`
 	Tier    string `json:"tier"`
 	K       int    `json:"k"`
+	Explain bool   `json:"explain"`
+	Group   bool   `json:"group"` // collapse results about the same atom across layers
+	Zone    string `json:"zone"`  // restrict to one business domain of the project (requires project)
+
+	// With tier "auto": the top result score to settle for before
+	// escalating; 0 means the configured default.
+	MinScore float64 `json:"min_score,omitempty"`
 }
 
 // queryResultItem is a single result in the query response.
@@ -110,6 +137,54 @@ type queryResultItem struct {
 	Source string  `json:"source"`
 	Score  float64 `json:"score"`
 	Layer  string  `json:"layer,omitempty"`
+
+	Layers  []string             `json:"layers,omitempty"` // with group, every layer the result stands for
+	Explain *storage.Explanation `json:"explain,omitempty"`
+
+	// For atoms stored with their source: the original code and its
+	// first line number, split out of Text.
+	Code     string `json:"code,omitempty"`
+	CodeLine int    `json:"code_line,omitempty"`
+}
+
+// newQueryResultItem converts a search result, attaching its explanation
+// (parsed under namespace) when requested.
+func newQueryResultItem(sr storage.SearchResult, explain bool, namespace string) queryResultItem {
+	item := queryResultItem{
+		Text:   sr.Text,
+		Source: sr.Source,
+		Score:  sr.Score,
+	}
+	if explain {
+		e := storage.ExplainIn(namespace, sr)
+		item.Explain = &e
+	}
+	item.Text, item.Code, item.CodeLine = storage.SplitAtomCode(sr.Text)
+	return item
+}
+
+// queryResultItems flattens matched search results into at most req.K
+// items, first collapsing those about the same atom when req.Group is set.
+func queryResultItems(matched []storage.SearchResult, req queryRequest, namespace string) []queryResultItem {
+	var items []queryResultItem
+	if req.Group {
+		for _, g := range storage.GroupResults(namespace, matched) {
+			item := newQueryResultItem(g.SearchResult, req.Explain, namespace)
+			item.Layers = g.Layers
+			items = append(items, item)
+			if len(items) >= req.K {
+				break
+			}
+		}
+		return items
+	}
+	for _, sr := range matched {
+		items = append(items, newQueryResultItem(sr, req.Explain, namespace))
+		if len(items) >= req.K {
+			break
+		}
+	}
+	return items
 }
 
 // handleQuery searches the memories index. If a project is specified, it uses
@@ -133,6 +208,50 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 	if req.K == 0 {
 		req.K = 10
 	}
+	if req.Zone != "" && req.Project == "" {
+		writeError(w, http.StatusBadRequest, "zone requires a project")
+		return
+	}
+	if storage.Tier(req.Tier) == storage.TierAuto && (req.Project == "" || req.Zone != "") {
+		writeError(w, http.StatusBadRequest, "tier auto requires a project and does not support zone")
+		return
+	}
+	if req.MinScore < 0 {
+		writeError(w, http.StatusBadRequest, "min_score must not be negative")
+		return
+	}
+	if !s.hasIndexedProject() {
+		writeError(w, http.StatusServiceUnavailable, "no projects have been indexed yet; index a project before querying")
+		return
+	}
+
+	namespace := s.memoriesNamespace()
+	if storage.Tier(req.Tier) == storage.TierAuto {
+		s.handleAutoQuery(w, r, req, namespace)
+		return
+	}
+	cacheKey := queryCacheKey{namespace, req.Project, req.Text, req.Tier, req.K, req.Explain, req.Group, req.Zone}
+	if items, ok := s.queryCache.get(cacheKey); ok {
+		s.metrics.queries.Inc()
+		s.metrics.queryCacheHits.Inc()
+		writeQueryResults(w, r, req, items, namespace)
+		return
+	}
+
+	// Expand a zone to the files and modules it covers before searching.
+	var zone *storage.ZoneScope
+	if req.Zone != "" {
+		var err error
+		zone, err = storage.NewStore(s.memoriesClient, req.Project, namespace).ResolveZone(req.Zone)
+		if errors.Is(err, storage.ErrZoneNotFound) {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("zone %q not found in project %s", req.Zone, req.Project))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
 
 	// Search with optional project scoping via source prefix.
 	sourcePrefix := ""
@@ -141,11 +260,16 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 		Hybrid: true,
 	}
 	if req.Project != "" {
-		sourcePrefix = fmt.Sprintf("carto/%s/", req.Project)
+		sourcePrefix = storage.ProjectPrefix(namespace, req.Project)
 		opts.SourcePrefix = sourcePrefix
-		// Request extra results so we have enough after filtering.
+	}
+	if req.Project != "" || req.Group {
+		// Request extra results so we have enough after filtering or grouping.
 		opts.K = req.K * 3
 	}
+	if zone != nil {
+		opts.K = req.K * 5 // most of the project falls outside the zone
+	}
 
 	results, err := s.memoriesClient.Search(req.Text, opts)
 	if err != nil {
@@ -153,46 +277,121 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var items []queryResultItem
+	var matched []storage.SearchResult
 	for _, sr := range results {
 		if sourcePrefix != "" && !strings.HasPrefix(sr.Source, sourcePrefix) {
 			continue
 		}
-		items = append(items, queryResultItem{
-			Text:   sr.Text,
-			Source: sr.Source,
-			Score:  sr.Score,
-		})
-		if len(items) >= req.K {
-			break
-		}
+		matched = append(matched, sr)
 	}
 
 	// Fallback: if search returned no project-matching results, use ListBySource
 	// to retrieve all memories for the project. This works around search APIs
 	// that don't support source-prefix filtering.
-	if len(items) == 0 && sourcePrefix != "" {
-		listed, listErr := s.memoriesClient.ListBySource(sourcePrefix, req.K*5, 0)
-		if listErr == nil {
-			for _, sr := range listed {
-				items = append(items, queryResultItem{
-					Text:   sr.Text,
-					Source: sr.Source,
-					Score:  sr.Score,
-				})
-				if len(items) >= req.K {
-					break
-				}
+	if len(matched) == 0 && sourcePrefix != "" {
+		if listed, listErr := s.memoriesClient.ListBySource(sourcePrefix, req.K*5, 0); listErr == nil {
+			matched = listed
+		}
+	}
+
+	if req.Project != "" {
+		ranking, err := s.projectRanking(req.Project)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		matched = ranking.Apply(namespace, matched)
+	}
+
+	if zone != nil {
+		inZone := matched[:0]
+		for _, sr := range matched {
+			if zone.Contains(namespace, sr) {
+				inZone = append(inZone, sr)
 			}
 		}
+		matched = inZone
 	}
 
+	items := queryResultItems(matched, req, namespace)
 	if items == nil {
 		items = []queryResultItem{}
 	}
+	s.queryCache.put(cacheKey, items)
+	s.metrics.queries.Inc()
+	writeQueryResults(w, r, req, items, namespace)
+}
+
+// handleAutoQuery answers a tier "auto" query: the project is searched at
+// mini first and the tier raised while the results are weak (see
+// storage.Store.SearchAuto). The JSON response adds tier_used. These
+// queries bypass the query cache, whose key has no room for min_score.
+func (s *Server) handleAutoQuery(w http.ResponseWriter, r *http.Request, req queryRequest, namespace string) {
+	opts := storage.AutoOptions{MinScore: req.MinScore}
+	if opts.MinScore == 0 {
+		s.cfgMu.RLock()
+		opts.MinScore = s.cfg.AutoTierMinScore
+		s.cfgMu.RUnlock()
+	}
+	searchK := req.K
+	if req.Group {
+		searchK = req.K * 3
+	}
+	ranking, err := s.projectRanking(req.Project)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	store := storage.NewStore(s.memoriesClient, req.Project, namespace)
+	store.SetRanking(ranking)
+	matched, tier, err := store.SearchAuto(req.Text, searchK, opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	items := queryResultItems(matched, req, namespace)
+	if items == nil {
+		items = []queryResultItem{}
+	}
+	s.metrics.queries.Inc()
+	if format := queryFormat(r.Header.Get("Accept")); format != formatJSON {
+		writeQueryMarkdown(w, format, req.Text, items, namespace)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": items, "tier_used": tier})
+}
+
+// projectRanking loads the path weights of a project's
+// .carto/ranking.yaml, or nil if it has none.
+func (s *Server) projectRanking(project string) (*storage.Ranking, error) {
+	if !filepath.IsLocal(project) {
+		return nil, nil
+	}
+	ranking, err := storage.LoadRanking(filepath.Join(s.projectsDir, project))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ranking config: %w", err)
+	}
+	return ranking, nil
+}
+
+// writeQueryResults writes query results as JSON, or as a markdown
+// document when the Accept header prefers text/markdown or text/plain.
+func writeQueryResults(w http.ResponseWriter, r *http.Request, req queryRequest, items []queryResultItem, namespace string) {
+	if format := queryFormat(r.Header.Get("Accept")); format != formatJSON {
+		writeQueryMarkdown(w, format, req.Text, items, namespace)
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]any{"results": items})
 }
 
+// memoriesNamespace returns the configured source tag namespace.
+func (s *Server) memoriesNamespace() string {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg.MemoriesNamespace
+}
+
 // redactKey masks the middle of an API key, showing the first 8 and last 4
 // characters with **** in between. Keys shorter than 16 characters are fully
 // redacted to avoid leaking too much of short keys.
@@ -206,6 +405,15 @@ func redactKey(key string) string {
 	return key[:8] + "****" + key[len(key)-4:]
 }
 
+// redactLLMKey returns the LLM key for display: its file:/cmd: reference
+// when it was resolved from one (never the secret), otherwise redacted.
+func redactLLMKey(cfg config.Config) string {
+	if ref := cfg.LLMKeyRef(); ref != "" {
+		return ref
+	}
+	return redactKey(cfg.LLMApiKey)
+}
+
 // configResponse is the JSON shape returned by GET /api/config.
 type configResponse struct {
 	MemoriesURL   string `json:"memories_url"`
@@ -244,7 +452,7 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 		FastMaxTokens: cfg.FastMaxTokens,
 		DeepMaxTokens: cfg.DeepMaxTokens,
 		LLMProvider:   cfg.LLMProvider,
-		LLMApiKey:     redactKey(cfg.LLMApiKey),
+		LLMApiKey:     redactLLMKey(cfg),
 		LLMBaseURL:    cfg.LLMBaseURL,
 		GitHubToken:   redactKey(cfg.GitHubToken),
 		JiraToken:     redactKey(cfg.JiraToken),
@@ -263,6 +471,13 @@ func (s *Server) handlePatchConfig(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid JSON body")
 		return
 	}
+	// file: and cmd: references would let API callers read files or run
+	// commands on the server; they may only come from the config file or
+	// environment.
+	if v, ok := patch["llm_api_key"].(string); ok && config.IsSecretRef(v) {
+		writeError(w, http.StatusBadRequest, "llm_api_key references (file:, cmd:) can only be set in the config file or environment")
+		return
+	}
 
 	s.cfgMu.Lock()
 	for key, val := range patch {
@@ -364,6 +579,15 @@ type indexRequest struct {
 	Incremental bool   `json:"incremental"`
 	Module      string `json:"module"`
 	Project     string `json:"project"`
+	Timeout     string `json:"timeout,omitempty"` // Go duration, e.g. "30m"; stops the run when it elapses
+
+	// Clone options for URL indexing.
+	Depth        int  `json:"depth,omitempty"`         // history depth; 0 means 1
+	SingleBranch bool `json:"single_branch,omitempty"` // fetch only the cloned branch
+
+	NoStoreSource  bool `json:"no_store_source,omitempty"` // keep original source out of atom memories
+	RetryFailed    bool `json:"retry_failed,omitempty"`    // retry files skipped after failing repeatedly
+	RefreshSignals bool `json:"refresh_signals,omitempty"` // fetch external sources again instead of reusing cached artifacts
 }
 
 // handleStartIndex launches an asynchronous pipeline.Run for the given path.
@@ -374,6 +598,16 @@ func (s *Server) handleStartIndex(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid JSON body")
 		return
 	}
+	if req.Timeout != "" {
+		if d, err := time.ParseDuration(req.Timeout); err != nil || d < 0 {
+			writeError(w, http.StatusBadRequest, "invalid timeout: want a duration like \"30m\"")
+			return
+		}
+	}
+	if req.Depth < 0 {
+		writeError(w, http.StatusBadRequest, "depth must be ≥ 0")
+		return
+	}
 
 	// If a Git URL is provided, it takes precedence over path.
 	if req.URL != "" {
@@ -386,9 +620,9 @@ func (s *Server) handleStartIndex(w http.ResponseWriter, r *http.Request) {
 			projectName = gitclone.ParseRepoName(req.URL)
 		}
 
-		run := s.runs.Start(projectName)
-		if run == nil {
-			writeError(w, http.StatusConflict, "index already running for project "+projectName)
+		run, err := s.runs.Start(projectName)
+		if errors.Is(err, ErrIndexRunning) {
+			writeError(w, http.StatusConflict, err.Error())
 			return
 		}
 
@@ -422,9 +656,9 @@ func (s *Server) handleStartIndex(w http.ResponseWriter, r *http.Request) {
 		projectName = filepath.Base(absPath)
 	}
 
-	run := s.runs.Start(projectName)
-	if run == nil {
-		writeError(w, http.StatusConflict, "index already running for project "+projectName)
+	run, err := s.runs.Start(projectName)
+	if errors.Is(err, ErrIndexRunning) {
+		writeError(w, http.StatusConflict, err.Error())
 		return
 	}
 
@@ -441,47 +675,68 @@ func (s *Server) handleStartIndex(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// runPipeline runs an index. Tests replace it to inspect the config a run
+// is started with.
+var runPipeline = pipeline.Run
+
+// cloneRepo and newSignalCache are replaced by tests of runs from a Git URL.
+var (
+	cloneRepo      = gitclone.Clone
+	newSignalCache = sources.NewCache
+)
+
 // runIndex executes the pipeline in a goroutine and sends progress/result via the IndexRun.
 func (s *Server) runIndex(run *IndexRun, projectName, absPath string, req indexRequest, cfg config.Config) {
 	defer s.runs.Finish(projectName)
 
 	start := time.Now()
 
+	// Per-project .carto/config.yaml overrides the global model settings.
+	cfg, err := config.ForProject(cfg, absPath)
+	if err != nil {
+		run.SendError(err)
+		return
+	}
+
 	apiKey := cfg.LLMApiKey
 	if apiKey == "" {
 		apiKey = cfg.AnthropicKey
 	}
+	if apiKey == "" && cfg.LLMProvider != "ollama" {
+		run.SendError(fmt.Errorf("%w (set LLM_API_KEY or ANTHROPIC_API_KEY)", llm.ErrNoAPIKey))
+		return
+	}
 
 	llmClient := llm.NewClient(llm.Options{
-		APIKey:        apiKey,
-		FastModel:     cfg.FastModel,
-		DeepModel:     cfg.DeepModel,
-		MaxConcurrent: cfg.MaxConcurrent,
-		IsOAuth:       config.IsOAuthToken(apiKey),
-		BaseURL:       cfg.LLMBaseURL,
+		APIKey:          apiKey,
+		FastModel:       cfg.FastModel,
+		DeepModel:       cfg.DeepModel,
+		MaxConcurrent:   cfg.MaxConcurrent,
+		FastConcurrency: cfg.FastConcurrency,
+		DeepConcurrency: cfg.DeepConcurrency,
+		IsOAuth:         config.IsOAuthToken(apiKey),
+		BaseURL:         cfg.LLMBaseURL,
+		FastMaxTokens:   cfg.FastMaxTokens,
+		DeepMaxTokens:   cfg.DeepMaxTokens,
+		ExtraHeaders:    cfg.LLMHeaders,
+		OnUsage:         s.metrics.observeLLM,
 	})
 
 	// Build unified source registry from .carto/sources.yaml (if present)
 	// and auto-detected sources (git, GitHub, PDFs).
 	yamlCfg, _ := sources.LoadSourcesConfig(absPath)
 	owner, repo := gitclone.ParseOwnerRepo(req.URL)
-	srcRegistry := sources.BuildRegistry(absPath, yamlCfg, sources.Credentials{
-		GitHubToken: cfg.GitHubToken,
-		GitHubOwner: owner,
-		GitHubRepo:  repo,
-		JiraToken:   cfg.JiraToken,
-		JiraEmail:   cfg.JiraEmail,
-		JiraBaseURL: cfg.JiraBaseURL,
-		LinearToken: cfg.LinearToken,
-		NotionToken: cfg.NotionToken,
-		SlackToken:  cfg.SlackToken,
-	})
+	srcRegistry := sources.BuildRegistry(absPath, yamlCfg, sourceCredentials(cfg, projectName, owner, repo))
+	srcRegistry.SetCache(newSignalCache(s.signalCacheRoot(projectName, absPath, req), cfg.SignalCacheDuration(), req.RefreshSignals))
 
 	// Create a fresh Memories client from the current config so Settings
 	// changes take effect without server restart.
 	memoriesClient := storage.NewMemoriesClient(config.ResolveURL(cfg.MemoriesURL), cfg.MemoriesKey)
 
-	result, err := pipeline.Run(pipeline.Config{
+	// Validated by handleStartIndex.
+	timeout, _ := time.ParseDuration(req.Timeout)
+
+	result, err := runPipeline(pipeline.Config{
 		Ctx:               run.Ctx,
 		ProjectName:       projectName,
 		RootPath:          absPath,
@@ -495,17 +750,37 @@ func (s *Server) runIndex(run *IndexRun, projectName, absPath string, req indexR
 		LogFn: func(level, msg string) {
 			run.SendLog(level, msg)
 		},
-		Incremental:   req.Incremental,
-		ModuleFilter:  req.Module,
-		FastMaxTokens: cfg.FastMaxTokens,
-		DeepMaxTokens: cfg.DeepMaxTokens,
+		Incremental:       req.Incremental,
+		ModuleFilter:      req.Module,
+		FastMaxTokens:     cfg.FastMaxTokens,
+		DeepMaxTokens:     cfg.DeepMaxTokens,
+		ChunkKinds:        cfg.ChunkKinds,
+		ChunkMinLines:     cfg.ChunkMinLines,
+		MemoriesNamespace: cfg.MemoriesNamespace,
+		CompressThreshold: cfg.CompressThreshold,
+		HistorySince:      cfg.HistorySince,
+		HistoryMaxCommits: cfg.HistoryMaxCommits,
+		Instructions:      pipeline.Instructions(cfg.Instructions),
+		SummaryDetail:     cfg.SummaryDetail,
+		NoStoreSource:     req.NoStoreSource,
+		RetryFailed:       req.RetryFailed,
+		Timeout:           timeout,
+		MaxCost:           cfg.MaxCost,
 	})
 	if err != nil {
 		if err == context.Canceled {
 			run.SendStopped()
 			return
 		}
-		run.SendError(err.Error())
+		if errors.Is(err, pipeline.ErrTimedOut) && result != nil {
+			run.SendLog("warn", fmt.Sprintf("Timed out with partial results: %d modules, %d files, %d atoms",
+				result.Modules, result.FilesIndexed, result.AtomsCreated))
+		}
+		if errors.Is(err, pipeline.ErrBudgetExceeded) && result != nil {
+			run.SendLog("warn", fmt.Sprintf("Budget of $%.2f exceeded with partial results: %d modules, %d files, %d atoms",
+				cfg.MaxCost, result.Modules, result.FilesIndexed, result.AtomsCreated))
+		}
+		run.SendError(err)
 		return
 	}
 
@@ -516,29 +791,54 @@ func (s *Server) runIndex(run *IndexRun, projectName, absPath string, req indexR
 		errMsgs[i] = e.Error()
 	}
 
+	var failed, skipped []string
+	for _, f := range result.FailedFiles {
+		failed = append(failed, f.Path)
+	}
+	for _, f := range result.SkippedFailed {
+		skipped = append(skipped, f.Path)
+	}
+
 	run.SendResult(IndexResult{
-		Modules: result.Modules,
-		Files:   result.FilesIndexed,
-		Atoms:   result.AtomsCreated,
-		Errors:  len(result.Errors),
-		Elapsed: elapsed,
-		ErrMsgs: errMsgs,
+		Modules:   result.Modules,
+		Files:     result.FilesIndexed,
+		Atoms:     result.AtomsCreated,
+		Truncated: result.Truncated,
+		Unstored:  result.Unstored,
+		Failed:    failed,
+		Skipped:   skipped,
+		Errors:    len(result.Errors),
+		Elapsed:   elapsed,
+		ErrMsgs:   errMsgs,
 	})
 }
 
+// signalCacheRoot returns the project root the signal cache of a run lives
+// under. A clone is deleted after its run, so runs from a Git URL keep the
+// cache in the project's directory under projectsDir instead, where the
+// next run of the project finds it.
+func (s *Server) signalCacheRoot(projectName, absPath string, req indexRequest) string {
+	if req.URL == "" || s.projectsDir == "" {
+		return absPath
+	}
+	return filepath.Join(s.projectsDir, projectName)
+}
+
 // runIndexFromURL clones a Git repo, runs the pipeline, then cleans up.
 func (s *Server) runIndexFromURL(run *IndexRun, projectName string, req indexRequest, cfg config.Config) {
 	run.SendLog("info", fmt.Sprintf("Cloning %s...", req.URL))
 
 	token := cfg.GitHubToken
-	cloneResult, err := gitclone.Clone(gitclone.CloneOptions{
-		URL:    req.URL,
-		Branch: req.Branch,
-		Token:  token,
-		Depth:  1,
+	cloneResult, err := cloneRepo(gitclone.CloneOptions{
+		URL:          req.URL,
+		Branch:       req.Branch,
+		Token:        token,
+		Depth:        req.Depth,
+		SingleBranch: req.SingleBranch,
+		Progress:     func(msg string) { run.SendLog("info", msg) },
 	})
 	if err != nil {
-		run.SendError(err.Error())
+		run.SendError(err)
 		s.runs.Finish(projectName)
 		return
 	}
@@ -546,13 +846,10 @@ func (s *Server) runIndexFromURL(run *IndexRun, projectName string, req indexReq
 
 	run.SendLog("info", "Clone complete. Starting pipeline...")
 
-	localReq := indexRequest{
-		Path:        cloneResult.Dir,
-		Incremental: req.Incremental,
-		Module:      req.Module,
-		Project:     projectName,
-		URL:         req.URL,
-	}
+	// Copy the whole request so every run option reaches runIndex.
+	localReq := req
+	localReq.Path = cloneResult.Dir
+	localReq.Project = projectName
 	// runIndex handles Finish internally via defer.
 	s.runIndex(run, projectName, cloneResult.Dir, localReq, cfg)
 }
@@ -576,6 +873,27 @@ func (s *Server) handleStopIndex(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleCancelIndex aborts an active indexing run. The run's SSE stream
+// ends with a "cancelled" event. Repeating the request while the run is
+// still winding down succeeds again; once it has finished, 404 is returned.
+func (s *Server) handleCancelIndex(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "project name is required")
+		return
+	}
+
+	if !s.runs.Abort(name) {
+		writeError(w, http.StatusNotFound, "no active index run for project "+name)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"project": name,
+		"status":  "cancelling",
+	})
+}
+
 // handleProgress streams SSE events for an active indexing run.
 func (s *Server) handleProgress(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
@@ -826,6 +1144,336 @@ func (s *Server) handleGetProject(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// projectFile is one entry of GET /api/projects/{name}/files.
+type projectFile struct {
+	Path      string `json:"path"`
+	Language  string `json:"language"`
+	Module    string `json:"module"`
+	Size      int64  `json:"size"`
+	Hash      string `json:"hash"`
+	IndexedAt string `json:"indexed_at"`
+}
+
+// handleListFiles lists the files recorded in a project's manifest, sorted
+// by path, with their detected language and owning module. ?lang and
+// ?module filter the list; ?limit (default 100) and ?offset page through
+// it, and total counts the files matching the filters.
+func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	projPath := filepath.Join(s.projectsDir, name)
+	q := r.URL.Query()
+
+	limit, offset := 100, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	if info, err := os.Stat(projPath); err != nil || !info.IsDir() {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+	mf, err := manifest.Load(projPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load manifest: "+err.Error())
+		return
+	}
+	if mf.IsEmpty() && mf.Project == "" {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+
+	infos := make([]scanner.FileInfo, 0, len(mf.Files))
+	for rel := range mf.Files {
+		infos = append(infos, scanner.FileInfo{Path: filepath.Join(projPath, rel), RelPath: rel})
+	}
+	moduleOf := make(map[string]string, len(infos))
+	for _, m := range scanner.DetectModules(projPath, infos) {
+		for _, f := range m.Files {
+			moduleOf[f] = m.Name
+		}
+	}
+
+	files := []projectFile{}
+	for rel, e := range mf.Files {
+		lang := scanner.DetectLanguage(rel)
+		if lang == "" {
+			lang = scanner.OtherLanguage
+		}
+		if v := q.Get("lang"); v != "" && v != lang {
+			continue
+		}
+		if v := q.Get("module"); v != "" && v != moduleOf[rel] {
+			continue
+		}
+		indexedAt := ""
+		if !e.IndexedAt.IsZero() {
+			indexedAt = e.IndexedAt.Format(time.RFC3339)
+		}
+		files = append(files, projectFile{
+			Path:      rel,
+			Language:  lang,
+			Module:    moduleOf[rel],
+			Size:      e.Size,
+			Hash:      e.Hash,
+			IndexedAt: indexedAt,
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	total := len(files)
+	start := offset
+	if start > total {
+		start = total
+	}
+	end := total
+	if limit < total-start {
+		end = start + limit
+	}
+	page := files[start:end]
+	writeJSON(w, http.StatusOK, map[string]any{
+		"project": name,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+		"files":   page,
+	})
+}
+
+// handleBlueprint returns a readable overview of an indexed project: its
+// stored blueprint, the top ?patterns=N patterns (default 5, 0 for all)
+// and each module's one-line intent. No search is run. A project with no
+// stored analysis is 404.
+func (s *Server) handleBlueprint(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	maxPatterns := 5
+	if v := r.URL.Query().Get("patterns"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "patterns must be a non-negative integer")
+			return
+		}
+		maxPatterns = n
+	}
+
+	store := storage.NewStore(s.memoriesClient, name, s.memoriesNamespace())
+	overview, err := store.Overview(maxPatterns)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "failed to read blueprint: "+err.Error())
+		return
+	}
+	if !overview.Indexed() {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("project %q is not indexed yet", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"project":   name,
+		"blueprint": overview.Blueprint,
+		"patterns":  overview.Patterns,
+		"modules":   overview.Modules,
+	})
+}
+
+// handleHotspots ranks a project's files by churn and recency using the
+// stored history layers of all modules. ?limit=N caps the result (default 10).
+func (s *Server) handleHotspots(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	store := storage.NewStore(s.memoriesClient, name, s.memoriesNamespace())
+	histories, err := store.RetrieveHistory()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "failed to read history: "+err.Error())
+		return
+	}
+
+	spots := history.RankHotspots(histories, time.Now(), limit)
+	if spots == nil {
+		spots = []history.Hotspot{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"project": name, "hotspots": spots})
+}
+
+// handleStale lists a project's zones whose files have all gone untouched
+// for longer than ?older_than (default 1y; see history.ParseAge), using the
+// stored zones and history layers. ?all=true returns every zone with its
+// staleness instead of only the stale ones.
+func (s *Server) handleStale(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	olderThan := "1y"
+	if v := r.URL.Query().Get("older_than"); v != "" {
+		olderThan = v
+	}
+	threshold, err := history.ParseAge(olderThan)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	all := r.URL.Query().Get("all") == "true"
+
+	store := storage.NewStore(s.memoriesClient, name, s.memoriesNamespace())
+	zoneLayers, err := store.RetrieveLayerAllModules(storage.LayerZones)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "failed to read zones: "+err.Error())
+		return
+	}
+	histories, err := store.RetrieveHistory()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "failed to read history: "+err.Error())
+		return
+	}
+
+	var zones []history.ZoneFiles
+	for module, results := range zoneLayers {
+		for _, res := range results {
+			var zs []history.ZoneFiles
+			if err := json.Unmarshal([]byte(res.Text), &zs); err != nil {
+				continue
+			}
+			for _, z := range zs {
+				z.Module = module
+				zones = append(zones, z)
+			}
+		}
+	}
+
+	out := []history.ZoneStaleness{}
+	for _, z := range history.ClassifyZones(zones, histories, time.Now(), threshold) {
+		if all || z.Stale {
+			out = append(out, z)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"project": name, "older_than": olderThan, "zones": out})
+}
+
+// handleCycles reports circular dependencies in the project's stored
+// wiring, combined across modules.
+func (s *Server) handleCycles(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	store := storage.NewStore(s.memoriesClient, name, s.memoriesNamespace())
+	wiringLayers, err := store.RetrieveLayerAllModules(storage.LayerWiring)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "failed to read wiring: "+err.Error())
+		return
+	}
+
+	var edges []analyzer.Dependency
+	for _, results := range wiringLayers {
+		for _, res := range results {
+			var deps []analyzer.Dependency
+			if err := json.Unmarshal([]byte(res.Text), &deps); err != nil {
+				continue
+			}
+			edges = append(edges, deps...)
+		}
+	}
+
+	cycles := analyzer.FindCycles(edges)
+	if cycles == nil {
+		cycles = [][]string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"project": name, "cycles": cycles})
+}
+
+// handleRefs finds the stored wiring edges that mention ?symbol=X, grouped
+// by module: what the symbol uses and what uses it. Matching ignores case
+// and is by substring unless ?exact=true.
+func (s *Server) handleRefs(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	symbol := strings.TrimSpace(r.URL.Query().Get("symbol"))
+	if symbol == "" {
+		writeError(w, http.StatusBadRequest, "symbol is required")
+		return
+	}
+	exact := r.URL.Query().Get("exact") == "true"
+
+	store := storage.NewStore(s.memoriesClient, name, s.memoriesNamespace())
+	wiringLayers, err := store.RetrieveLayerAllModules(storage.LayerWiring)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "failed to read wiring: "+err.Error())
+		return
+	}
+
+	wiring := make(map[string][]analyzer.Dependency, len(wiringLayers))
+	for module, results := range wiringLayers {
+		for _, res := range results {
+			var deps []analyzer.Dependency
+			if err := json.Unmarshal([]byte(res.Text), &deps); err != nil {
+				continue
+			}
+			wiring[module] = append(wiring[module], deps...)
+		}
+	}
+
+	refs := analyzer.FindReferences(wiring, symbol, exact)
+	if refs == nil {
+		refs = []analyzer.ModuleRefs{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"project": name, "symbol": symbol, "exact": exact, "modules": refs})
+}
+
+// handleDuplicates reports clusters of copy-pasted code in a project's
+// source tree (see duplicates.Find). ?min_lines=N skips shorter code units
+// (default 5) and ?cross_module=true keeps only clusters spanning modules.
+func (s *Server) handleDuplicates(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	projPath := filepath.Join(s.projectsDir, name)
+	q := r.URL.Query()
+
+	minLines := duplicates.DefaultMinLines
+	if v := q.Get("min_lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "min_lines must be a positive integer")
+			return
+		}
+		minLines = n
+	}
+	crossModule := q.Get("cross_module") == "true"
+
+	if info, err := os.Stat(projPath); err != nil || !info.IsDir() {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+	result, err := scanner.Scan(projPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "scan failed: "+err.Error())
+		return
+	}
+
+	clusters := []duplicates.Cluster{}
+	for _, cl := range duplicates.Find(projPath, result.Modules, duplicates.Options{MinLines: minLines}) {
+		if cl.CrossModule || !crossModule {
+			clusters = append(clusters, cl)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"project": name, "clusters": clusters})
+}
+
 // handleDeleteProject removes the .carto/ directory for a project.
 func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
@@ -840,28 +1488,53 @@ func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to delete project: "+err.Error())
 		return
 	}
+	// It may have been the last one; look again on the next check.
+	s.indexed.Store(false)
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
-// handleIndexAll accepts a POST to re-index all projects. It starts indexing
-// each project up to maxIndexAllConcurrency at a time and returns 202 immediately.
+// maxIndexAllConcurrency bounds how many pipeline runs an index-all request
+// executes at once.
+const maxIndexAllConcurrency = 3
+
+// handleIndexAll accepts a POST to re-index all projects under projectsDir.
+// Runs are incremental unless ?changed=false, in which case every project is
+// fully re-indexed. ?filter=<glob> keeps only projects whose name matches,
+// and each ?tag=key=value only projects carrying that tag (see
+// manifest.ProjectFilter). Projects are queued in the RunManager and
+// executed by a pool of maxIndexAllConcurrency workers; projects that
+// already have an active run are skipped. Returns 202 immediately — poll
+// GET /api/projects/index-all for aggregate progress.
 func (s *Server) handleIndexAll(w http.ResponseWriter, r *http.Request) {
-	changedOnly := r.URL.Query().Get("changed") == "true"
+	incremental := r.URL.Query().Get("changed") != "false"
 
 	if s.projectsDir == "" {
 		writeError(w, http.StatusBadRequest, "projects directory not configured")
 		return
 	}
 
+	tags, err := manifest.ParseTags(r.URL.Query()["tag"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := manifest.ProjectFilter{Name: r.URL.Query().Get("filter"), Tags: tags}
+
 	entries, err := os.ReadDir(s.projectsDir)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to read projects directory")
 		return
 	}
 
-	// Collect indexable projects.
-	var projectPaths []struct{ name, path string }
+	// Collect indexable projects that pass the filter.
+	type indexAllJob struct {
+		name, path string
+		run        *IndexRun
+	}
+	var jobs []indexAllJob
+	var names, skipped []string
+	total := 0
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -875,45 +1548,81 @@ func (s *Server) handleIndexAll(w http.ResponseWriter, r *http.Request) {
 		if name == "" {
 			name = entry.Name()
 		}
-		projectPaths = append(projectPaths, struct{ name, path string }{name, projectRoot})
+		if !filter.Selects(name, projectRoot) {
+			continue
+		}
+		total++
+
+		run, err := s.runs.Start(name)
+		if errors.Is(err, ErrIndexRunning) {
+			skipped = append(skipped, name)
+			continue
+		}
+		run.MarkQueued()
+		jobs = append(jobs, indexAllJob{name: name, path: projectRoot, run: run})
+		names = append(names, name)
 	}
 
-	if len(projectPaths) == 0 {
+	if total == 0 {
 		writeJSON(w, http.StatusOK, map[string]any{"status": "no_projects", "started": 0})
 		return
 	}
 
-	// Launch indexing with a concurrency limiter (max 3 concurrent).
-	const maxConcurrency = 3
-	sem := make(chan struct{}, maxConcurrency)
+	s.runs.StartBatch(names, skipped, incremental)
 
 	s.cfgMu.RLock()
 	cfg := s.cfg
 	s.cfgMu.RUnlock()
 
-	started := 0
-	for _, p := range projectPaths {
-		run := s.runs.Start(p.name)
-		if run == nil {
-			continue // already running
-		}
-		started++
-		go func(run *IndexRun, name, path string) {
-			sem <- struct{}{} // acquire
-			defer func() { <-sem }() // release
-			req := indexRequest{Path: path, Project: name}
-			s.runIndex(run, name, path, req, cfg)
-		}(run, p.name, p.path)
+	queue := make(chan indexAllJob, len(jobs))
+	for _, j := range jobs {
+		queue <- j
 	}
+	close(queue)
 
+	workers := maxIndexAllConcurrency
+	if len(jobs) < workers {
+		workers = len(jobs)
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for j := range queue {
+				// A queued run may have been stopped before it got a slot.
+				if j.run.Ctx.Err() != nil {
+					j.run.SendStopped()
+					s.runs.Finish(j.name)
+					continue
+				}
+				j.run.MarkRunning()
+				req := indexRequest{Path: j.path, Project: j.name, Incremental: incremental}
+				s.runIndex(j.run, j.name, j.path, req, cfg)
+			}
+		}()
+	}
+
+	if skipped == nil {
+		skipped = []string{}
+	}
 	writeJSON(w, http.StatusAccepted, map[string]any{
-		"status":       "started",
-		"started":      started,
-		"total":        len(projectPaths),
-		"changed_only": changedOnly,
+		"status":      "started",
+		"started":     len(jobs),
+		"skipped":     skipped,
+		"total":       total,
+		"incremental": incremental,
 	})
 }
 
+// handleIndexAllProgress returns aggregate progress for the most recent
+// index-all request.
+func (s *Server) handleIndexAllProgress(w http.ResponseWriter, r *http.Request) {
+	progress := s.runs.BatchProgress()
+	if progress == nil {
+		writeError(w, http.StatusNotFound, "no index-all run has been started")
+		return
+	}
+	writeJSON(w, http.StatusOK, progress)
+}
+
 // sourcesResponse is the JSON shape returned by GET /api/projects/{name}/sources.
 type sourcesResponse struct {
 	Sources     map[string]map[string]string `json:"sources"`
@@ -922,6 +1631,60 @@ type sourcesResponse struct {
 
 // handleGetSources returns the parsed .carto/sources.yaml for a project
 // plus boolean availability of global credentials.
+// sourceCredentials collects the integration credentials from cfg for
+// sources.BuildRegistry. owner and repo enable GitHub auto-detection;
+// project enables its CARTO_PROJ_* environment overrides.
+func sourceCredentials(cfg config.Config, project, owner, repo string) sources.Credentials {
+	return sources.Credentials{
+		GitHubToken: cfg.GitHubToken,
+		GitHubOwner: owner,
+		GitHubRepo:  repo,
+		JiraToken:   cfg.JiraToken,
+		JiraEmail:   cfg.JiraEmail,
+		JiraBaseURL: cfg.JiraBaseURL,
+		LinearToken: cfg.LinearToken,
+		NotionToken: cfg.NotionToken,
+		SlackToken:  cfg.SlackToken,
+		Project:     project,
+	}
+}
+
+// handleTestSources builds the project's source registry with the current
+// credentials and probes each source's connectivity and auth.
+func (s *Server) handleTestSources(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	projPath := filepath.Join(s.projectsDir, name)
+
+	if info, err := os.Stat(projPath); err != nil || !info.IsDir() {
+		writeError(w, http.StatusNotFound, "project not found")
+		return
+	}
+
+	yamlCfg, err := sources.LoadSourcesConfig(projPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read sources config: "+err.Error())
+		return
+	}
+
+	s.cfgMu.RLock()
+	cfg := s.cfg
+	s.cfgMu.RUnlock()
+
+	owner, repo := gitclone.ParseOwnerRepo(gitclone.OriginURL(projPath))
+	reg := sources.BuildRegistry(projPath, yamlCfg, sourceCredentials(cfg, name, owner, repo))
+	results := reg.ValidateAll(r.Context())
+
+	ok := true
+	for _, res := range results {
+		ok = ok && res.OK
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"project": name,
+		"ok":      ok,
+		"results": results,
+	})
+}
+
 func (s *Server) handleGetSources(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
 	projPath := filepath.Join(s.projectsDir, name)
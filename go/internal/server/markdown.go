@@ -0,0 +1,129 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/divyekant/carto/internal/scanner"
+	"github.com/divyekant/carto/internal/storage"
+)
+
+// Response formats /api/query negotiates with the Accept header.
+const (
+	formatJSON     = "application/json"
+	formatMarkdown = "text/markdown"
+	formatText     = "text/plain"
+)
+
+// queryFormat picks the response format for an Accept header: the
+// supported type with the highest quality, the earliest listed on a tie.
+// JSON is the default, including for */* and unparseable headers.
+func queryFormat(accept string) string {
+	best, bestQ := formatJSON, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if _, err := fmt.Sscanf(v, "%g", &q); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case formatJSON, formatMarkdown, formatText:
+		case "*/*", "application/*":
+			mediaType = formatJSON
+		default:
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
+
+// markdownLayers is the order of the sections of a markdown query
+// response: the system-wide picture first, then the details.
+var markdownLayers = []string{
+	storage.LayerBlueprint,
+	storage.LayerPatterns,
+	storage.LayerZones,
+	storage.LayerIntent,
+	storage.LayerWiring,
+	storage.LayerAtoms,
+	storage.LayerHistory,
+	storage.LayerSignals,
+}
+
+// writeQueryMarkdown writes query results as a markdown document, ready
+// to paste into a prompt: one section per layer, each result attributed
+// to its source, and atom code in fences. contentType is text/markdown or
+// text/plain.
+func writeQueryMarkdown(w http.ResponseWriter, contentType, query string, items []queryResultItem, namespace string) {
+	byLayer := make(map[string][]queryResultItem)
+	for _, item := range items {
+		_, _, layer, _ := storage.ParseSourceTagIn(namespace, item.Source)
+		if !slices.Contains(markdownLayers, layer) {
+			layer = "" // not written by carto, or an unknown layer
+		}
+		byLayer[layer] = append(byLayer[layer], item)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Carto context: %s\n\n", query)
+	if len(items) == 0 {
+		b.WriteString("No results.\n")
+	}
+	section := func(title string, items []queryResultItem) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "## %s\n\n", title)
+		for _, item := range items {
+			writeMarkdownItem(&b, item)
+		}
+	}
+	for _, layer := range markdownLayers {
+		section(strings.ToUpper(layer[:1])+layer[1:], byLayer[layer])
+	}
+	section("Other", byLayer[""])
+
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
+
+// writeMarkdownItem writes one result: its text, any stored code in a
+// fence, and its source and score.
+func writeMarkdownItem(b *strings.Builder, item queryResultItem) {
+	b.WriteString(strings.TrimSpace(item.Text))
+	b.WriteString("\n\n")
+	if item.Code != "" {
+		// The fence must be longer than any backtick run in the code.
+		fence := "```"
+		for strings.Contains(item.Code, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(b, "%s%s\n%s\n%s\n\n", fence, codeLanguage(item.Source), strings.TrimRight(item.Code, "\n"), fence)
+	}
+	fmt.Fprintf(b, "_Source: `%s` (score %.2f)_\n\n", item.Source, item.Score)
+}
+
+// codeLanguage guesses an atom's fence language from the file path in its
+// source tag, {prefix}/layer:atoms/{path}:{line}.
+func codeLanguage(source string) string {
+	_, key, ok := strings.Cut(source, "/layer:"+storage.LayerAtoms+"/")
+	if !ok {
+		return ""
+	}
+	if i := strings.LastIndex(key, ":"); i > 0 {
+		key = key[:i]
+	}
+	return scanner.DetectLanguage(key)
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/divyekant/carto/internal/llm"
+	"github.com/divyekant/carto/internal/metrics"
+)
+
+// serverMetrics holds the Prometheus collectors exported on /metrics.
+type serverMetrics struct {
+	registry      *metrics.Registry
+	indexRuns     *metrics.CounterVec
+	indexDuration *metrics.Histogram
+	llmCalls      *metrics.CounterVec
+	llmTokens     *metrics.CounterVec
+	queries       *metrics.CounterVec
+
+	queryCacheHits *metrics.CounterVec
+}
+
+// newServerMetrics registers the server's collectors. Active runs are read
+// from runs at scrape time.
+func newServerMetrics(runs *RunManager) *serverMetrics {
+	reg := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: reg,
+		indexRuns: reg.NewCounter("carto_index_runs_total",
+			"Index runs finished, by final status.", "status"),
+		indexDuration: reg.NewHistogram("carto_index_duration_seconds",
+			"Wall-clock duration of finished index runs.", metrics.DefaultDurationBuckets),
+		llmCalls: reg.NewCounter("carto_llm_calls_total",
+			"Successful LLM API calls, by tier.", "tier"),
+		llmTokens: reg.NewCounter("carto_llm_tokens_total",
+			"LLM tokens consumed, by tier and direction (input or output).", "tier", "direction"),
+		queries: reg.NewCounter("carto_queries_total",
+			"Queries served by POST /api/query."),
+		queryCacheHits: reg.NewCounter("carto_query_cache_hits_total",
+			"Queries served from the query result cache."),
+	}
+	reg.NewGaugeFunc("carto_active_runs", "Index runs currently queued or running.", func() float64 {
+		return float64(runs.ActiveCount())
+	})
+	return m
+}
+
+// observeRun records a finished index run. It is the RunManager's onFinish
+// hook.
+func (m *serverMetrics) observeRun(status string, elapsed time.Duration) {
+	m.indexRuns.Inc(status)
+	m.indexDuration.Observe(elapsed.Seconds())
+}
+
+// observeLLM records one LLM call. It is passed to llm.Options.OnUsage.
+func (m *serverMetrics) observeLLM(tier llm.Tier, inputTokens, outputTokens int) {
+	m.llmCalls.Inc(string(tier))
+	m.llmTokens.Add(float64(inputTokens), string(tier), "input")
+	m.llmTokens.Add(float64(outputTokens), string(tier), "output")
+}
+
+// EnableMetrics exposes Prometheus metrics at GET /metrics. Like /healthz
+// it lives outside /api/ so scrapers do not need the bearer token.
+func (s *Server) EnableMetrics() {
+	s.mux.HandleFunc("GET /metrics", s.handlePrometheusMetrics)
+}
+
+// handlePrometheusMetrics writes all collectors in Prometheus text format.
+func (s *Server) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metrics.ContentType)
+	s.metrics.registry.Write(w)
+}
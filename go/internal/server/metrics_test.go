@@ -109,7 +109,7 @@ func TestMetricsEndpoint_ActiveRunsCount(t *testing.T) {
 	baseline := resp.ActiveRuns
 
 	// Start a run.
-	run := srv.runs.Start("metrics-test")
+	run, _ := srv.runs.Start("metrics-test")
 	if run == nil {
 		t.Fatal("expected to start run")
 	}
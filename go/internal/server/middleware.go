@@ -175,6 +175,19 @@ func (l *loggingResponseWriter) Write(b []byte) (int, error) {
 	return l.ResponseWriter.Write(b)
 }
 
+// Flush forwards to the underlying writer so SSE streams (which require
+// http.Flusher) keep working behind the logging middleware.
+func (l *loggingResponseWriter) Flush() {
+	if f, ok := l.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (l *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
+
 // loggingMiddleware emits a structured JSON log line for every HTTP request,
 // including method, path, response status code, latency in milliseconds, and
 // the X-Request-ID for log correlation. Compatible with Datadog, CloudWatch,
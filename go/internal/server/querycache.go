@@ -0,0 +1,117 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Defaults for the query result cache; see Server.SetQueryCache.
+const (
+	DefaultQueryCacheSize = 256
+	DefaultQueryCacheTTL  = 5 * time.Minute
+)
+
+// queryCacheKey identifies a query by everything that affects its results.
+type queryCacheKey struct {
+	namespace string
+	project   string // "" for unscoped queries
+	text      string
+	tier      string
+	k         int
+	explain   bool
+	group     bool
+	zone      string
+}
+
+type queryCacheEntry struct {
+	key     queryCacheKey
+	items   []queryResultItem
+	expires time.Time
+}
+
+// queryCache is a bounded LRU of POST /api/query results with a TTL, so
+// the UI re-issuing the same query while navigating doesn't hit Memories
+// each time. A nil *queryCache is valid and caches nothing.
+type queryCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // front = most recently used
+	entries map[queryCacheKey]*list.Element
+	now     func() time.Time
+}
+
+// newQueryCache returns a cache holding up to size results for ttl each,
+// or nil (no caching) when size or ttl is not positive.
+func newQueryCache(size int, ttl time.Duration) *queryCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &queryCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[queryCacheKey]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// get returns the cached results for key if present and unexpired.
+func (c *queryCache) get(key queryCacheKey) ([]queryResultItem, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*queryCacheEntry)
+	if c.now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.items, true
+}
+
+// put stores items under key, evicting the least recently used entry when
+// the cache is full.
+func (c *queryCache) put(key queryCacheKey, items []queryResultItem) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &queryCacheEntry{key: key, items: items, expires: c.now().Add(c.ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(e)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*queryCacheEntry).key)
+	}
+}
+
+// invalidate drops the cached results of project, and of unscoped queries,
+// which may include it. It is called when the project's index changes.
+func (c *queryCache) invalidate(project string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, el := range c.entries {
+		if key.project == project || key.project == "" {
+			c.order.Remove(el)
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newQueryCache(2, time.Minute)
+	a := queryCacheKey{project: "p", text: "a"}
+	b := queryCacheKey{project: "p", text: "b"}
+	d := queryCacheKey{project: "p", text: "d"}
+
+	c.put(a, []queryResultItem{{Text: "A"}})
+	c.put(b, []queryResultItem{{Text: "B"}})
+	c.get(a) // a is now more recent than b
+	c.put(d, []queryResultItem{{Text: "D"}})
+
+	if _, ok := c.get(b); ok {
+		t.Error("b should have been evicted")
+	}
+	if items, ok := c.get(a); !ok || items[0].Text != "A" {
+		t.Errorf("a: got %v, %v", items, ok)
+	}
+	if _, ok := c.get(d); !ok {
+		t.Error("d should be cached")
+	}
+}
+
+func TestQueryCache_ExpiresAfterTTL(t *testing.T) {
+	now := time.Now()
+	c := newQueryCache(10, time.Minute)
+	c.now = func() time.Time { return now }
+	key := queryCacheKey{text: "q"}
+	c.put(key, []queryResultItem{})
+
+	now = now.Add(59 * time.Second)
+	if _, ok := c.get(key); !ok {
+		t.Error("entry should still be fresh")
+	}
+	now = now.Add(2 * time.Second)
+	if _, ok := c.get(key); ok {
+		t.Error("entry should have expired")
+	}
+}
+
+func TestQueryCache_InvalidateDropsProjectAndUnscoped(t *testing.T) {
+	c := newQueryCache(10, time.Minute)
+	mine := queryCacheKey{project: "mine", text: "q"}
+	other := queryCacheKey{project: "other", text: "q"}
+	all := queryCacheKey{text: "q"}
+	for _, k := range []queryCacheKey{mine, other, all} {
+		c.put(k, nil)
+	}
+
+	c.invalidate("mine")
+	if _, ok := c.get(mine); ok {
+		t.Error("mine should be invalidated")
+	}
+	if _, ok := c.get(all); ok {
+		t.Error("unscoped query may include mine and should be invalidated")
+	}
+	if _, ok := c.get(other); !ok {
+		t.Error("other project's entry should survive")
+	}
+
+	disabled := newQueryCache(0, time.Minute)
+	disabled.put(mine, nil)
+	if _, ok := disabled.get(mine); ok {
+		t.Error("disabled cache should not store entries")
+	}
+}
@@ -1,11 +1,17 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
+	"html"
 	"io/fs"
 	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
 
 	"github.com/divyekant/carto/internal/config"
+	"github.com/divyekant/carto/internal/manifest"
 	"github.com/divyekant/carto/internal/storage"
 )
 
@@ -27,12 +33,22 @@ func (s *Server) routes() {
 	s.mux.HandleFunc("GET /api/projects/runs", s.handleListRuns)
 	s.mux.HandleFunc("POST /api/projects/index", s.handleStartIndex)
 	s.mux.HandleFunc("POST /api/projects/index-all", s.handleIndexAll)
+	s.mux.HandleFunc("GET /api/projects/index-all", s.handleIndexAllProgress)
 	s.mux.HandleFunc("GET /api/projects/{name}", s.handleGetProject)
 	s.mux.HandleFunc("DELETE /api/projects/{name}", s.handleDeleteProject)
 	s.mux.HandleFunc("GET /api/projects/{name}/progress", s.handleProgress)
 	s.mux.HandleFunc("POST /api/projects/{name}/stop", s.handleStopIndex)
+	s.mux.HandleFunc("DELETE /api/projects/{name}/index", s.handleCancelIndex)
 	s.mux.HandleFunc("GET /api/projects/{name}/sources", s.handleGetSources)
 	s.mux.HandleFunc("PUT /api/projects/{name}/sources", s.handlePutSources)
+	s.mux.HandleFunc("POST /api/projects/{name}/sources/test", s.handleTestSources)
+	s.mux.HandleFunc("GET /api/projects/{name}/files", s.handleListFiles)
+	s.mux.HandleFunc("GET /api/projects/{name}/blueprint", s.handleBlueprint)
+	s.mux.HandleFunc("GET /api/projects/{name}/hotspots", s.handleHotspots)
+	s.mux.HandleFunc("GET /api/projects/{name}/stale", s.handleStale)
+	s.mux.HandleFunc("GET /api/projects/{name}/cycles", s.handleCycles)
+	s.mux.HandleFunc("GET /api/projects/{name}/refs", s.handleRefs)
+	s.mux.HandleFunc("GET /api/projects/{name}/duplicates", s.handleDuplicates)
 
 	// ── Query & search ─────────────────────────────────────────────────────
 	s.mux.HandleFunc("POST /api/query", s.handleQuery)
@@ -67,11 +83,39 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{
 		"status":           "ok",
 		"memories_healthy": healthy,
+		"ready":            s.hasIndexedProject(),
 		"docker":           config.IsDocker(),
 		"version":          config.Version,
 	})
 }
 
+// hasIndexedProject reports whether the server has anything to answer
+// queries from: at least one project in projectsDir with a manifest. A
+// server without a projects directory cannot tell and always reports
+// true. Once a project is found the answer is kept until a project is
+// deleted, so queries don't rescan the directory.
+func (s *Server) hasIndexedProject() bool {
+	if s.projectsDir == "" || s.indexed.Load() {
+		return true
+	}
+	entries, err := os.ReadDir(s.projectsDir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		mf, err := manifest.Load(filepath.Join(s.projectsDir, entry.Name()))
+		if err != nil || (mf.IsEmpty() && mf.Project == "") {
+			continue
+		}
+		s.indexed.Store(true)
+		return true
+	}
+	return false
+}
+
 // handleHealthz is the Kubernetes-standard root-level liveness endpoint.
 // Unlike /api/health/live it lives outside the /api/ prefix so it works
 // out-of-the-box with Docker HEALTHCHECK and load balancers whose probe
@@ -176,8 +220,10 @@ func (s *Server) handleSPA(w http.ResponseWriter, r *http.Request) {
 	f, err := s.webFS.Open(fsPath)
 	if err == nil {
 		f.Close()
-		http.FileServerFS(s.webFS).ServeHTTP(w, r)
-		return
+		if fsPath != "index.html" {
+			http.FileServerFS(s.webFS).ServeHTTP(w, r)
+			return
+		}
 	}
 
 	// SPA fallback: serve index.html for client-side routing.
@@ -186,6 +232,26 @@ func (s *Server) handleSPA(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "index.html not found", http.StatusInternalServerError)
 		return
 	}
+	if s.basePath != "" {
+		data = withBasePath(data, s.basePath)
+	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Write(data)
 }
+
+// rootRelativeAttr matches src and href attributes holding a root-relative
+// URL, but not a protocol-relative one ("//host/...").
+var rootRelativeAttr = regexp.MustCompile(`((?:src|href)=")/([^/])`)
+
+// withBasePath rewrites index.html for an app served under basePath: asset
+// URLs get the prefix, and a carto-base-path meta tag tells the SPA where
+// its API and client-side routes live.
+func withBasePath(page []byte, basePath string) []byte {
+	page = rootRelativeAttr.ReplaceAll(page, []byte("${1}"+basePath+"/${2}"))
+	meta := `<meta name="carto-base-path" content="` + html.EscapeString(basePath) + `" />`
+	if i := bytes.Index(page, []byte("<head>")); i >= 0 {
+		i += len("<head>")
+		return append(page[:i:i], append([]byte(meta), page[i:]...)...)
+	}
+	return append([]byte(meta), page...)
+}
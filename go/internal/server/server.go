@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/divyekant/carto/internal/config"
 	"github.com/divyekant/carto/internal/storage"
@@ -17,7 +19,11 @@ type Server struct {
 	memoriesClient *storage.MemoriesClient
 	projectsDir    string
 	runs           *RunManager
+	metrics        *serverMetrics
 	webFS          fs.FS
+	basePath       string // URL prefix every route is served under; see SetBasePath
+	queryCache     *queryCache
+	indexed        atomic.Bool // projectsDir is known to hold an indexed project; see hasIndexedProject
 	mux            *http.ServeMux
 	// handler is the fully-composed middleware chain wrapping mux.
 	// ServeHTTP delegates to handler instead of mux directly so all
@@ -43,8 +49,14 @@ func New(cfg config.Config, memoriesClient *storage.MemoriesClient, projectsDir
 		projectsDir:    projectsDir,
 		runs:           NewRunManager(),
 		webFS:          webFS,
+		queryCache:     newQueryCache(DefaultQueryCacheSize, DefaultQueryCacheTTL),
 		mux:            http.NewServeMux(),
 	}
+	s.metrics = newServerMetrics(s.runs)
+	s.runs.onFinish = func(project, status string, elapsed time.Duration) {
+		s.metrics.observeRun(status, elapsed)
+		s.queryCache.invalidate(project)
+	}
 	s.routes()
 
 	// Build CORS allowed-origins list from config.
@@ -72,9 +84,45 @@ func New(cfg config.Config, memoriesClient *storage.MemoriesClient, projectsDir
 	return s
 }
 
-// ServeHTTP implements http.Handler — delegates to the full middleware chain.
+// SetBasePath serves every route, including /healthz and the SPA, under
+// prefix (e.g. "/carto") for deployments behind a reverse proxy on a
+// subpath. Requests outside the prefix get 404. An empty prefix or "/"
+// serves from the root.
+func (s *Server) SetBasePath(prefix string) {
+	s.basePath = strings.TrimRight("/"+strings.Trim(prefix, "/"), "/")
+}
+
+// BasePath returns the normalized prefix set by SetBasePath, e.g. "/carto",
+// or "" when serving from the root.
+func (s *Server) BasePath() string { return s.basePath }
+
+// SetQueryCache resizes the cache of POST /api/query results: up to size
+// results are kept for ttl each, and a project's entries are dropped when
+// an index run for it finishes. A size or ttl of 0 disables caching.
+func (s *Server) SetQueryCache(size int, ttl time.Duration) {
+	s.queryCache = newQueryCache(size, ttl)
+}
+
+// ServeHTTP implements http.Handler — delegates to the full middleware chain,
+// with the base path stripped when one is set.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.handler.ServeHTTP(w, r)
+	if s.basePath == "" {
+		s.handler.ServeHTTP(w, r)
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, s.basePath)
+	switch {
+	case !ok || (rest != "" && rest[0] != '/'):
+		http.NotFound(w, r)
+	case rest == "":
+		target := s.basePath + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	default:
+		http.StripPrefix(s.basePath, s.handler).ServeHTTP(w, r)
+	}
 }
 
 // Start runs the HTTP server on the given address.
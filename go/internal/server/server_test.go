@@ -1,17 +1,28 @@
 package server
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
 	"time"
 
+	"github.com/divyekant/carto/internal/analyzer"
 	"github.com/divyekant/carto/internal/config"
+	"github.com/divyekant/carto/internal/gitclone"
+	"github.com/divyekant/carto/internal/llm"
+	"github.com/divyekant/carto/internal/manifest"
+	"github.com/divyekant/carto/internal/pipeline"
 	"github.com/divyekant/carto/internal/sources"
 	"github.com/divyekant/carto/internal/storage"
 )
@@ -20,6 +31,20 @@ import (
 // /healthz tests
 // =========================================================================
 
+// TestMain points the default config file at a temporary directory so
+// commands that persist settings never touch the user's real config.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "carto-config-")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.Unsetenv("CARTO_CONFIG")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestHealthzEndpoint_ReturnsOK(t *testing.T) {
 	// /healthz must return 200 with status:ok whenever the process is alive.
 	memoriesClient := storage.NewMemoriesClient("http://127.0.0.1:1", "test-key")
@@ -220,6 +245,53 @@ func TestListProjects(t *testing.T) {
 	}
 }
 
+func TestListProjects_FilterByTag(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, team := range map[string]string{"payments-api": "payments", "search-api": "search"} {
+		root := filepath.Join(tmpDir, name)
+		mf := manifest.NewManifest(root, name)
+		mf.UpdateFile("main.go", "abc", 1)
+		if err := mf.Save(); err != nil {
+			t.Fatalf("save manifest: %v", err)
+		}
+		if err := manifest.SaveMeta(root, &manifest.Meta{Tags: map[string]string{"team": team}}); err != nil {
+			t.Fatalf("save meta: %v", err)
+		}
+	}
+
+	memoriesClient := storage.NewMemoriesClient("http://127.0.0.1:1", "test-key")
+	srv := New(config.Config{}, memoriesClient, tmpDir, nil)
+
+	list := func(query string) (int, []ProjectInfo) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/projects"+query, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		var projects []ProjectInfo
+		json.NewDecoder(w.Body).Decode(&projects)
+		return w.Code, projects
+	}
+
+	code, projects := list("")
+	if code != http.StatusOK || len(projects) != 2 {
+		t.Fatalf("unfiltered: status %d, %d project(s), want 200 and 2", code, len(projects))
+	}
+	for _, p := range projects {
+		if p.Tags["team"] == "" {
+			t.Errorf("%s: tags not reported: %+v", p.Name, p)
+		}
+	}
+
+	code, projects = list("?filter=team=payments")
+	if code != http.StatusOK || len(projects) != 1 || projects[0].Name != "payments-api" {
+		t.Errorf("filter team=payments: status %d, projects %+v, want only payments-api", code, projects)
+	}
+
+	if code, _ := list("?filter=team"); code != http.StatusBadRequest {
+		t.Errorf("malformed filter: status %d, want 400", code)
+	}
+}
+
 func TestQueryEndpoint(t *testing.T) {
 	// Mock memories server that returns search results for POST /search.
 	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -265,6 +337,357 @@ func TestQueryEndpoint(t *testing.T) {
 	}
 }
 
+func TestQueryEndpoint_Explain(t *testing.T) {
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{
+				{"id": 1, "text": "handleAuth", "score": 0.9, "source": "carto/myproj/auth/layer:atoms",
+					"vector_score": 0.7, "lexical_score": 0.2},
+			},
+		})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, "test-key"), "", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"text": "auth", "explain": true}`))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Results []queryResultItem `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Explain == nil {
+		t.Fatalf("expected one explained result, got %+v", resp.Results)
+	}
+	e := resp.Results[0].Explain
+	if e.Module != "auth" || e.Layer != "atoms" || e.VectorScore == nil || *e.VectorScore != 0.7 {
+		t.Errorf("unexpected explanation: %+v", e)
+	}
+}
+
+func TestQueryEndpoint_AutoTierReportsTierUsed(t *testing.T) {
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{
+				{"id": 1, "text": "auth zone", "score": 0.2, "source": "carto/myproj/auth/layer:zones"},
+				{"id": 2, "text": "handleAuth", "score": 0.9, "source": "carto/myproj/auth/layer:atoms"},
+				{"id": 3, "text": "auth -> store", "score": 0.8, "source": "carto/myproj/auth/layer:wiring"},
+			},
+		})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, "test-key"), "", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"text": "auth", "project": "myproj", "tier": "auto"}`))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Results  []queryResultItem `json:"results"`
+		TierUsed string            `json:"tier_used"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.TierUsed != "standard" {
+		t.Errorf("tier_used = %q, want standard", resp.TierUsed)
+	}
+	if len(resp.Results) != 3 || resp.Results[0].Source != "carto/myproj/auth/layer:atoms" {
+		t.Errorf("unexpected results: %+v", resp.Results)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"text": "auth", "tier": "auto"}`))
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("tier auto without a project: expected 400, got %d", w.Code)
+	}
+}
+
+func TestQueryEndpoint_GroupCollapsesLayers(t *testing.T) {
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{
+				{"id": 1, "text": "auth wiring", "score": 0.6, "source": "carto/myproj/auth/layer:wiring"},
+				{"id": 2, "text": "func Login", "score": 0.9, "source": "carto/myproj/auth/layer:atoms/login.go:12"},
+				{"id": 3, "text": "auth zones", "score": 0.4, "source": "carto/myproj/auth/layer:zones"},
+			},
+		})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, "test-key"), "", nil)
+	query := func(body string) []queryResultItem {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Results []queryResultItem `json:"results"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Results
+	}
+
+	if got := query(`{"text": "login", "project": "myproj", "tier": "full"}`); len(got) != 3 {
+		t.Fatalf("ungrouped: expected 3 results, got %d", len(got))
+	}
+
+	got := query(`{"text": "login", "project": "myproj", "tier": "full", "group": true}`)
+	if len(got) != 1 {
+		t.Fatalf("grouped: expected 1 result, got %d: %+v", len(got), got)
+	}
+	if got[0].Text != "func Login" || got[0].Score != 0.9 {
+		t.Errorf("grouped result = %q (%.2f), want the best-scoring atom", got[0].Text, got[0].Score)
+	}
+	if want := []string{"atoms", "wiring", "zones"}; !reflect.DeepEqual(got[0].Layers, want) {
+		t.Errorf("layers = %v, want %v", got[0].Layers, want)
+	}
+}
+
+func TestQueryEndpoint_AtomSourceCode(t *testing.T) {
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{
+				{"id": 1, "text": "Login (function)\n--- source (line 12) ---\nfunc Login() {}\n", "score": 0.9, "source": "carto/myproj/auth/layer:atoms/login.go:12"},
+				{"id": 2, "text": "auth zones", "score": 0.4, "source": "carto/myproj/auth/layer:zones"},
+			},
+		})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, "test-key"), "", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"text": "login"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Results []queryResultItem `json:"results"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", resp.Results)
+	}
+	atom := resp.Results[0]
+	if atom.Text != "Login (function)" || atom.Code != "func Login() {}\n" || atom.CodeLine != 12 {
+		t.Errorf("atom result = %+v, want its source split out", atom)
+	}
+	if zones := resp.Results[1]; zones.Code != "" || zones.Text != "auth zones" {
+		t.Errorf("zones result = %+v, want no code", zones)
+	}
+}
+
+func TestQueryEndpoint_MarkdownResponse(t *testing.T) {
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{
+				{"id": 1, "text": "Login (function)\n--- source (line 12) ---\nfunc Login() {}\n", "score": 0.9, "source": "carto/myproj/auth/layer:atoms/login.go:12"},
+				{"id": 2, "text": "auth zones", "score": 0.4, "source": "carto/myproj/auth/layer:zones"},
+			},
+		})
+	}))
+	defer memSrv.Close()
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, "test-key"), "", nil)
+
+	query := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"text": "login"}`))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := query("text/markdown")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") {
+		t.Errorf("Content-Type = %q, want text/markdown", ct)
+	}
+	want := "# Carto context: login\n\n" +
+		"## Zones\n\n" +
+		"auth zones\n\n" +
+		"_Source: `carto/myproj/auth/layer:zones` (score 0.40)_\n\n" +
+		"## Atoms\n\n" +
+		"Login (function)\n\n" +
+		"```go\nfunc Login() {}\n```\n\n" +
+		"_Source: `carto/myproj/auth/layer:atoms/login.go:12` (score 0.90)_\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("markdown body:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The cached results render the same way, and plain text gets the
+	// markdown too.
+	if w := query("text/plain"); !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") || w.Body.String() != want {
+		t.Errorf("text/plain response = %q %q", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	// JSON stays the default, and wins when preferred.
+	for _, accept := range []string{"", "*/*", "application/json, text/markdown;q=0.5"} {
+		if w := query(accept); !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("Accept %q: Content-Type = %q, want JSON", accept, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestQueryEndpoint_ZoneScopesResults(t *testing.T) {
+	zones := `[{"name": "Authentication", "intent": "login", "files": ["auth/login.go", "auth/token.go"]}]`
+	stored := []map[string]any{
+		{"id": 1, "text": zones, "source": "carto/app/auth/layer:zones"},
+		{"id": 2, "text": `[{"name": "Billing", "files": ["billing/pay.go"]}]`, "source": "carto/app/billing/layer:zones"},
+	}
+	hits := []map[string]any{
+		{"id": 3, "text": "func Login", "score": 0.9, "source": "carto/app/auth/layer:atoms/auth/login.go:12"},
+		{"id": 4, "text": "func Pay", "score": 0.8, "source": "carto/app/billing/layer:atoms/billing/pay.go:3"},
+		{"id": 5, "text": "auth wiring", "score": 0.7, "source": "carto/app/auth/layer:wiring"},
+		{"id": 6, "text": "func Health", "score": 0.6, "source": "carto/app/auth/layer:atoms/auth/health.go:1"},
+		{"id": 7, "text": "billing wiring", "score": 0.5, "source": "carto/app/billing/layer:wiring"},
+	}
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search" {
+			json.NewEncoder(w).Encode(map[string]any{"results": hits})
+			return
+		}
+		var page []map[string]any
+		if r.URL.Query().Get("offset") == "0" {
+			for _, m := range stored {
+				if strings.HasPrefix(m["source"].(string), r.URL.Query().Get("source")) {
+					page = append(page, m)
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"memories": page})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, "test-key"), "", nil)
+	query := func(body string) (int, []queryResultItem) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(body)))
+		var resp struct {
+			Results []queryResultItem `json:"results"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp.Results
+	}
+
+	code, got := query(`{"text": "login", "project": "app", "zone": "authentication"}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var texts []string
+	for _, r := range got {
+		texts = append(texts, r.Text)
+	}
+	if want := []string{"func Login", "auth wiring"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("zone results = %q, want %q", texts, want)
+	}
+
+	if code, _ := query(`{"text": "login", "project": "app", "zone": "shipping"}`); code != http.StatusNotFound {
+		t.Errorf("unknown zone: expected 404, got %d", code)
+	}
+	if code, _ := query(`{"text": "login", "zone": "authentication"}`); code != http.StatusBadRequest {
+		t.Errorf("zone without project: expected 400, got %d", code)
+	}
+}
+
+func TestQueryEndpoint_CachesUntilIndexFinishes(t *testing.T) {
+	var searches atomic.Int32
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search" {
+			searches.Add(1)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []map[string]any{
+				{"id": 1, "text": "handleAuth", "score": 0.9, "source": "carto/myproj/auth/layer:atoms"},
+			},
+		})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, "test-key"), "", nil)
+	query := func(body string) []queryResultItem {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Results []queryResultItem `json:"results"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Results
+	}
+
+	first := query(`{"text": "auth", "project": "myproj"}`)
+	second := query(`{"text": "auth", "project": "myproj"}`)
+	if got := searches.Load(); got != 1 {
+		t.Errorf("identical query: expected 1 search, got %d", got)
+	}
+	if !reflect.DeepEqual(first, second) || len(second) != 1 {
+		t.Errorf("cached results differ: %+v vs %+v", first, second)
+	}
+
+	query(`{"text": "auth", "project": "myproj", "k": 5}`)
+	if got := searches.Load(); got != 2 {
+		t.Errorf("different k: expected a new search (2 total), got %d", got)
+	}
+
+	// Finishing another project's run keeps myproj's entries.
+	srv.runs.Start("other")
+	srv.runs.Finish("other")
+	query(`{"text": "auth", "project": "myproj"}`)
+	if got := searches.Load(); got != 2 {
+		t.Errorf("after unrelated run: expected 2 searches, got %d", got)
+	}
+
+	srv.runs.Start("myproj")
+	srv.runs.Finish("myproj")
+	query(`{"text": "auth", "project": "myproj"}`)
+	if got := searches.Load(); got != 3 {
+		t.Errorf("after myproj run: expected cache invalidated (3 searches), got %d", got)
+	}
+}
+
+func TestQueryEndpoint_CacheDisabled(t *testing.T) {
+	var searches atomic.Int32
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search" {
+			searches.Add(1)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"results": []map[string]any{}})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, "test-key"), "", nil)
+	srv.SetQueryCache(0, 0)
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"text": "auth"}`)))
+	}
+	if got := searches.Load(); got != 2 {
+		t.Errorf("cache disabled: expected 2 searches, got %d", got)
+	}
+}
+
 func TestQueryEndpoint_FallbackToListBySource(t *testing.T) {
 	// Simulates the real-world issue: search returns results from non-matching
 	// sources (e.g. "claude-code/..."), so the project source prefix filter
@@ -460,12 +883,40 @@ func TestPatchConfig(t *testing.T) {
 	}
 }
 
+func TestConfig_LLMKeySecretRef(t *testing.T) {
+	cfg := config.Config{LLMApiKey: "cmd:echo sk-llm-resolved-secret-value"}
+	cfg.ResolveSecretRefs()
+	if cfg.LLMApiKey != "sk-llm-resolved-secret-value" {
+		t.Fatalf("LLMApiKey not resolved: %q", cfg.LLMApiKey)
+	}
+	srv := New(cfg, nil, "", nil)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if strings.Contains(w.Body.String(), `"sk-llm-resolved-secret-value"`) {
+		t.Fatalf("GET /api/config leaked the resolved secret: %s", w.Body.String())
+	}
+	var resp configResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.LLMApiKey != "cmd:echo sk-llm-resolved-secret-value" {
+		t.Errorf("llm_api_key = %q, want the reference", resp.LLMApiKey)
+	}
+
+	// References cannot be set over the API.
+	patchReq := httptest.NewRequest(http.MethodPatch, "/api/config", strings.NewReader(`{"llm_api_key": "cmd:cat /etc/passwd"}`))
+	pw := httptest.NewRecorder()
+	srv.ServeHTTP(pw, patchReq)
+	if pw.Code != http.StatusBadRequest {
+		t.Errorf("PATCH with cmd: reference: expected 400, got %d", pw.Code)
+	}
+}
+
 func TestStartIndex_Conflict(t *testing.T) {
 	memoriesClient := storage.NewMemoriesClient("http://127.0.0.1:1", "test-key")
 	srv := New(config.Config{}, memoriesClient, "", nil)
 
 	// Manually start a run to simulate an in-progress index.
-	run := srv.runs.Start("myproject")
+	run, _ := srv.runs.Start("myproject")
 	if run == nil {
 		t.Fatal("expected to start run")
 	}
@@ -491,6 +942,80 @@ func TestStartIndex_Conflict(t *testing.T) {
 	srv.runs.Finish("myproject")
 }
 
+func TestCancelIndex(t *testing.T) {
+	srv := New(config.Config{}, nil, "", nil)
+
+	// Simulate runIndex: block until the run's context is cancelled.
+	run, _ := srv.runs.Start("bigproj")
+	if run == nil {
+		t.Fatal("expected to start run")
+	}
+	release := make(chan struct{})
+	go func() {
+		<-run.Ctx.Done()
+		<-release
+		run.SendStopped()
+		srv.runs.Finish("bigproj")
+	}()
+
+	cancelReq := func() int {
+		req := httptest.NewRequest(http.MethodDelete, "/api/projects/bigproj/index", nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := cancelReq(); code != http.StatusOK {
+		t.Fatalf("first cancel: expected 200, got %d", code)
+	}
+	// Repeating while the run winds down is harmless.
+	if code := cancelReq(); code != http.StatusOK {
+		t.Fatalf("repeat cancel: expected 200, got %d", code)
+	}
+	if run.Ctx.Err() == nil {
+		t.Fatal("expected run context to be cancelled")
+	}
+	close(release)
+	<-run.done
+
+	if n := srv.runs.ActiveCount(); n != 0 {
+		t.Errorf("expected no active runs after cancel, got %d", n)
+	}
+	if code := cancelReq(); code != http.StatusNotFound {
+		t.Errorf("cancel after finish: expected 404, got %d", code)
+	}
+
+	var status string
+	for _, rs := range srv.runs.ListRuns() {
+		if rs.Project == "bigproj" {
+			status = rs.Status
+		}
+	}
+	if status != "cancelled" {
+		t.Errorf("run status = %q, want cancelled", status)
+	}
+
+	// Late SSE clients get the final cancelled event.
+	req := httptest.NewRequest(http.MethodGet, "/api/projects/bigproj/progress", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), "event: cancelled") {
+		t.Errorf("expected cancelled SSE event, got %q", w.Body.String())
+	}
+}
+
+func TestCancelIndex_NoActiveRun(t *testing.T) {
+	srv := New(config.Config{}, nil, "", nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/projects/idle/index", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 func TestStartIndex_MissingPath(t *testing.T) {
 	memoriesClient := storage.NewMemoriesClient("http://127.0.0.1:1", "test-key")
 	srv := New(config.Config{}, memoriesClient, "", nil)
@@ -512,6 +1037,24 @@ func TestStartIndex_MissingPath(t *testing.T) {
 	}
 }
 
+func TestStartIndex_InvalidTimeout(t *testing.T) {
+	memoriesClient := storage.NewMemoriesClient("http://127.0.0.1:1", "test-key")
+	srv := New(config.Config{}, memoriesClient, "", nil)
+
+	body := strings.NewReader(`{"path": "/tmp/x", "timeout": "soon"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/projects/index", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if srv.runs.Get("x") != nil {
+		t.Error("expected no run to start with an invalid timeout")
+	}
+}
+
 func TestSSE_NoActiveRun(t *testing.T) {
 	memoriesClient := storage.NewMemoriesClient("http://127.0.0.1:1", "test-key")
 	srv := New(config.Config{}, memoriesClient, "", nil)
@@ -531,11 +1074,92 @@ func TestSSE_NoActiveRun(t *testing.T) {
 	}
 }
 
+func TestSSE_HeartbeatWhileIdle(t *testing.T) {
+	orig := sseHeartbeatInterval
+	sseHeartbeatInterval = 20 * time.Millisecond
+	t.Cleanup(func() { sseHeartbeatInterval = orig })
+
+	memoriesClient := storage.NewMemoriesClient("http://127.0.0.1:1", "test-key")
+	srv := New(config.Config{}, memoriesClient, "", nil)
+	run, _ := srv.runs.Start("idle")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/projects/idle/progress")
+	if err != nil {
+		t.Fatalf("GET progress: %v", err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+	next := func() string {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream closed early")
+			}
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for SSE output")
+		}
+		return ""
+	}
+
+	// The run sends nothing, so the stream carries only heartbeats.
+	for beats := 0; beats < 2; {
+		switch line := next(); line {
+		case ": heartbeat":
+			beats++
+		case "":
+		default:
+			t.Fatalf("unexpected line while idle: %q", line)
+		}
+	}
+
+	// Real events still come through.
+	run.SendProgress("atoms", 1, 2)
+	for {
+		if line := next(); strings.HasPrefix(line, "event: ") {
+			if line != "event: progress" {
+				t.Errorf("got %q, want event: progress", line)
+			}
+			break
+		}
+	}
+}
+
+func TestIndexErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("pipeline: %w at startup", pipeline.ErrMemoriesUnreachable), "memories_unreachable"},
+		{fmt.Errorf("pipeline: %w: %q", pipeline.ErrModuleNotFound, "x"), "module_not_found"},
+		{fmt.Errorf("%w (set LLM_API_KEY)", llm.ErrNoAPIKey), "no_api_key"},
+		{fmt.Errorf("pipeline: %w after 1m0s", pipeline.ErrTimedOut), "timed_out"},
+		{fmt.Errorf("gitclone: %w cloning x", gitclone.ErrAuthFailed), "clone_auth_failed"},
+		{fmt.Errorf("gitclone: %w cloning x after 3 attempts", gitclone.ErrNetwork), "clone_network_error"},
+		{errors.New("disk full"), ""},
+	}
+	for _, tt := range tests {
+		if got := indexErrorCode(tt.err); got != tt.want {
+			t.Errorf("indexErrorCode(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
 func TestRunManager_StartAndFinish(t *testing.T) {
 	mgr := NewRunManager()
 
 	// Start a run.
-	run := mgr.Start("project1")
+	run, _ := mgr.Start("project1")
 	if run == nil {
 		t.Fatal("expected to start run")
 	}
@@ -547,13 +1171,16 @@ func TestRunManager_StartAndFinish(t *testing.T) {
 	}
 
 	// Starting the same project should fail.
-	dup := mgr.Start("project1")
+	dup, err := mgr.Start("project1")
 	if dup != nil {
 		t.Error("expected nil when starting duplicate run")
 	}
+	if !errors.Is(err, ErrIndexRunning) {
+		t.Errorf("duplicate Start: err = %v, want ErrIndexRunning", err)
+	}
 
 	// Different project should succeed.
-	run2 := mgr.Start("project2")
+	run2, _ := mgr.Start("project2")
 	if run2 == nil {
 		t.Fatal("expected to start run for different project")
 	}
@@ -568,7 +1195,7 @@ func TestRunManager_StartAndFinish(t *testing.T) {
 	}
 
 	// Should be able to start project1 again (replaces finished run).
-	run3 := mgr.Start("project1")
+	run3, _ := mgr.Start("project1")
 	if run3 == nil {
 		t.Error("expected to start run after finishing")
 	}
@@ -581,7 +1208,7 @@ func TestRunManager_StartAndFinish(t *testing.T) {
 func TestRunManager_LastRunPersists(t *testing.T) {
 	mgr := NewRunManager()
 
-	run := mgr.Start("persist-test")
+	run, _ := mgr.Start("persist-test")
 	if run == nil {
 		t.Fatal("expected to start run")
 	}
@@ -648,11 +1275,120 @@ func TestRunIndex_UsesCurrentConfig(t *testing.T) {
 	srv.cfgMu.RUnlock()
 }
 
-func TestSPAFallback(t *testing.T) {
-	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	}))
-	defer memSrv.Close()
+func TestRunIndex_PassesConfiguredMaxCost(t *testing.T) {
+	started := make(chan pipeline.Config, 1)
+	runPipeline = func(cfg pipeline.Config) (*pipeline.Result, error) {
+		started <- cfg
+		return nil, pipeline.ErrBudgetExceeded
+	}
+	t.Cleanup(func() { runPipeline = pipeline.Run })
+
+	srv := New(config.Config{AnthropicKey: "sk-ant-test", MaxCost: 2.5}, nil, "", nil)
+	body := strings.NewReader(fmt.Sprintf(`{"path": %q, "project": "budgeted"}`, t.TempDir()))
+	req := httptest.NewRequest(http.MethodPost, "/api/projects/index", body)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	select {
+	case cfg := <-started:
+		if cfg.MaxCost != 2.5 {
+			t.Errorf("pipeline MaxCost = %v, want the configured 2.5", cfg.MaxCost)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("index run did not start")
+	}
+	// Wait for the run to report the budget error.
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		var status RunStatus
+		for _, r := range srv.runs.ListRuns() {
+			if r.Project == "budgeted" {
+				status = r
+			}
+		}
+		if status.Status == "error" {
+			if !strings.Contains(status.Error, "budget") {
+				t.Errorf("run error = %q, want the budget error", status.Error)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("index run did not finish: %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunIndexFromURL_KeepsSignalCacheAndRefresh(t *testing.T) {
+	cloneDir := t.TempDir()
+	cloneRepo = func(gitclone.CloneOptions) (*gitclone.CloneResult, error) {
+		return &gitclone.CloneResult{Dir: cloneDir, Cleanup: func() {}}, nil
+	}
+	type cacheArgs struct {
+		root    string
+		refresh bool
+	}
+	caches := make(chan cacheArgs, 1)
+	newSignalCache = func(root string, ttl time.Duration, refresh bool) *sources.Cache {
+		caches <- cacheArgs{root, refresh}
+		return sources.NewCache(root, ttl, refresh)
+	}
+	runPipeline = func(pipeline.Config) (*pipeline.Result, error) {
+		return nil, context.Canceled
+	}
+	t.Cleanup(func() {
+		cloneRepo = gitclone.Clone
+		newSignalCache = sources.NewCache
+		runPipeline = pipeline.Run
+	})
+
+	projectsDir := t.TempDir()
+	srv := New(config.Config{AnthropicKey: "sk-ant-test"}, nil, projectsDir, nil)
+	body := strings.NewReader(`{"url": "https://github.com/acme/shop.git", "refresh_signals": true}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/projects/index", body)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	select {
+	case got := <-caches:
+		// The clone is deleted after the run; the cache must outlive it.
+		if want := filepath.Join(projectsDir, "shop"); got.root != want {
+			t.Errorf("signal cache root = %q, want %q", got.root, want)
+		}
+		if !got.refresh {
+			t.Error("refresh_signals was not passed on to the run")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("index run did not start")
+	}
+	// Let the run finish before the stubs are restored.
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		var status RunStatus
+		for _, r := range srv.runs.ListRuns() {
+			if r.Project == "shop" {
+				status = r
+			}
+		}
+		if status.Status == "stopped" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("index run did not finish: %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSPAFallback(t *testing.T) {
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer memSrv.Close()
 
 	// Create a minimal in-memory FS for testing.
 	testFS := fstest.MapFS{
@@ -694,6 +1430,63 @@ func TestSPAFallback(t *testing.T) {
 	}
 }
 
+func TestBasePath(t *testing.T) {
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer memSrv.Close()
+
+	testFS := fstest.MapFS{
+		"index.html": {Data: []byte(`<html><head><link rel="icon" href="/favicon.svg" />` +
+			`<link href="https://fonts.example.com/x.css" /><script src="/assets/index-abc.js"></script>` +
+			`</head><body>Carto</body></html>`)},
+		"assets/index-abc.js": {Data: []byte("console.log('app')")},
+	}
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, "test-key"), "", testFS)
+	srv.SetBasePath("/carto/")
+	if got := srv.BasePath(); got != "/carto" {
+		t.Fatalf("BasePath() = %q, want /carto", got)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	for _, path := range []string{"/carto/api/health", "/carto/healthz", "/carto/assets/index-abc.js"} {
+		if w := get(path); w.Code != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, w.Code)
+		}
+	}
+	for _, path := range []string{"/", "/api/health", "/healthz", "/query", "/cartography/api/health"} {
+		if w := get(path); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d", path, w.Code)
+		}
+	}
+	if w := get("/carto"); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/carto/" {
+		t.Errorf("GET /carto: got %d to %q, want a redirect to /carto/", w.Code, w.Header().Get("Location"))
+	}
+
+	for _, path := range []string{"/carto/", "/carto/query"} {
+		w := get(path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", path, w.Code)
+		}
+		body := w.Body.String()
+		for _, want := range []string{
+			`<meta name="carto-base-path" content="/carto" />`,
+			`href="/carto/favicon.svg"`,
+			`src="/carto/assets/index-abc.js"`,
+			`href="https://fonts.example.com/x.css"`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("GET %s: index.html missing %s:\n%s", path, want, body)
+			}
+		}
+	}
+}
+
 func TestGetProjectSources(t *testing.T) {
 	tmp := t.TempDir()
 	projDir := filepath.Join(tmp, "myproj")
@@ -737,6 +1530,65 @@ func TestGetProjectSources(t *testing.T) {
 	}
 }
 
+func TestTestProjectSources(t *testing.T) {
+	tmp := t.TempDir()
+	projDir := filepath.Join(tmp, "myproj")
+	os.MkdirAll(filepath.Join(projDir, ".carto"), 0o755)
+
+	// Jira without a base URL fails to configure; git has no remote probe.
+	yamlData := []byte("sources:\n  jira:\n    project: PROJ\n")
+	os.WriteFile(filepath.Join(projDir, ".carto", "sources.yaml"), yamlData, 0o644)
+
+	srv := New(config.Config{}, nil, tmp, nil)
+
+	req := httptest.NewRequest("POST", "/api/projects/myproj/sources/test", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		OK      bool `json:"ok"`
+		Results []struct {
+			Source string `json:"source"`
+			OK     bool   `json:"ok"`
+			Error  string `json:"error"`
+		} `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	if resp.OK {
+		t.Error("expected overall ok=false")
+	}
+	got := map[string]bool{}
+	for _, r := range resp.Results {
+		got[r.Source] = r.OK
+		if r.Source == "jira" && !strings.Contains(r.Error, "configure") {
+			t.Errorf("jira error = %q, want configure failure", r.Error)
+		}
+	}
+	if ok, found := got["git"]; !found || !ok {
+		t.Errorf("expected git OK, got %+v", resp.Results)
+	}
+	if ok, found := got["jira"]; !found || ok {
+		t.Errorf("expected jira FAIL, got %+v", resp.Results)
+	}
+}
+
+func TestTestProjectSources_NotFound(t *testing.T) {
+	srv := New(config.Config{}, nil, t.TempDir(), nil)
+
+	req := httptest.NewRequest("POST", "/api/projects/missing/sources/test", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != 404 {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
 func TestGetProjectSources_NoYAML(t *testing.T) {
 	tmp := t.TempDir()
 	projDir := filepath.Join(tmp, "myproj")
@@ -898,6 +1750,106 @@ func TestGetProjectDetail_NotFound(t *testing.T) {
 	}
 }
 
+func TestListFiles_EnrichesAndPaginates(t *testing.T) {
+	tmp := t.TempDir()
+	projDir := filepath.Join(tmp, "myproj")
+	os.MkdirAll(filepath.Join(projDir, ".carto"), 0o755)
+	os.MkdirAll(filepath.Join(projDir, "web"), 0o755)
+	os.WriteFile(filepath.Join(projDir, "web", "package.json"), []byte(`{"name":"web-app"}`), 0o644)
+
+	indexedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	entry := func(size int) map[string]any {
+		return map[string]any{"hash": "h", "size": size, "indexed_at": indexedAt}
+	}
+	mf := map[string]any{
+		"version": "1.0",
+		"project": "myproj",
+		"files": map[string]any{
+			"go.mod":           entry(10),
+			"main.go":          entry(100),
+			"util.go":          entry(200),
+			"web/package.json": entry(20),
+			"web/app.ts":       entry(300),
+		},
+	}
+	mfData, _ := json.Marshal(mf)
+	os.WriteFile(filepath.Join(projDir, ".carto", "manifest.json"), mfData, 0o644)
+
+	srv := New(config.Config{}, nil, tmp, nil)
+	get := func(query string) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/projects/myproj/files"+query, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", query, w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+	paths := func(resp map[string]any) []string {
+		var out []string
+		for _, f := range resp["files"].([]any) {
+			out = append(out, f.(map[string]any)["path"].(string))
+		}
+		return out
+	}
+
+	resp := get("")
+	if resp["total"] != float64(5) {
+		t.Errorf("expected total 5, got %v", resp["total"])
+	}
+	files := resp["files"].([]any)
+	if len(files) != 5 {
+		t.Fatalf("expected 5 files, got %d", len(files))
+	}
+	app := files[3].(map[string]any)
+	if app["path"] != "web/app.ts" || app["language"] != "typescript" || app["module"] != "web-app" {
+		t.Errorf("web/app.ts: got %v", app)
+	}
+	if app["size"] != float64(300) || app["indexed_at"] != indexedAt {
+		t.Errorf("web/app.ts metadata: got %v", app)
+	}
+	if main := files[1].(map[string]any); main["language"] != "go" || main["module"] != "myproj" {
+		t.Errorf("main.go: got %v", main)
+	}
+
+	resp = get("?limit=2&offset=1")
+	if got := paths(resp); !reflect.DeepEqual(got, []string{"main.go", "util.go"}) {
+		t.Errorf("page: got %v", got)
+	}
+	if resp["total"] != float64(5) || resp["limit"] != float64(2) || resp["offset"] != float64(1) {
+		t.Errorf("page metadata: got %v", resp)
+	}
+	if got := get("?offset=10")["files"].([]any); len(got) != 0 {
+		t.Errorf("offset past end: got %v, want an empty list", got)
+	}
+
+	if got := paths(get("?lang=go")); !reflect.DeepEqual(got, []string{"main.go", "util.go"}) {
+		t.Errorf("lang=go: got %v", got)
+	}
+	if got := paths(get("?module=web-app")); !reflect.DeepEqual(got, []string{"web/app.ts", "web/package.json"}) {
+		t.Errorf("module=web-app: got %v", got)
+	}
+}
+
+func TestListFiles_BadRequests(t *testing.T) {
+	srv := New(config.Config{}, nil, t.TempDir(), nil)
+
+	for path, want := range map[string]int{
+		"/api/projects/missing/files":          http.StatusNotFound,
+		"/api/projects/missing/files?limit=0":  http.StatusBadRequest,
+		"/api/projects/missing/files?offset=x": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s: expected %d, got %d", path, want, w.Code)
+		}
+	}
+}
+
 func TestDeleteProject(t *testing.T) {
 	tmp := t.TempDir()
 	projDir := filepath.Join(tmp, "myproj")
@@ -990,3 +1942,763 @@ func TestIndexAll_NoProjectsDir(t *testing.T) {
 		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
 	}
 }
+
+func TestIndexAll_StartsAndTracksSeededProjects(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"alpha", "beta"} {
+		root := filepath.Join(dir, name)
+		os.MkdirAll(root, 0o755)
+		os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644)
+		if err := manifest.NewManifest(root, name).Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Unreachable Memories server so runs fail fast without network access.
+	srv := New(config.Config{MemoriesURL: "http://127.0.0.1:1"}, nil, dir, nil)
+
+	req := httptest.NewRequest("POST", "/api/projects/index-all?changed=false", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["started"] != float64(2) || resp["total"] != float64(2) {
+		t.Errorf("expected 2 started of 2, got %v", resp)
+	}
+	if resp["incremental"] != false {
+		t.Errorf("changed=false should request full runs, got %v", resp["incremental"])
+	}
+
+	// Poll aggregate progress until both runs have finished.
+	var progress IndexAllProgress
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		req = httptest.NewRequest("GET", "/api/projects/index-all", nil)
+		w = httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("progress: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		json.Unmarshal(w.Body.Bytes(), &progress)
+		if progress.Queued == 0 && progress.Running == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("runs did not finish: %+v", progress)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if progress.Total != 2 || len(progress.Runs) != 2 {
+		t.Fatalf("expected 2 tracked runs, got %+v", progress)
+	}
+	seen := map[string]bool{}
+	for _, run := range progress.Runs {
+		seen[run.Project] = true
+	}
+	if !seen["alpha"] || !seen["beta"] {
+		t.Errorf("expected alpha and beta in progress, got %+v", progress.Runs)
+	}
+}
+
+func TestIndexAll_FilterAndTagSelectProjects(t *testing.T) {
+	dir := t.TempDir()
+	seeded := map[string]string{ // project -> team tag
+		"billing-api":    "payments",
+		"billing-worker": "search",
+		"ledger":         "payments",
+		"search-api":     "",
+	}
+	for name, team := range seeded {
+		root := filepath.Join(dir, name)
+		os.MkdirAll(root, 0o755)
+		os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644)
+		if err := manifest.NewManifest(root, name).Save(); err != nil {
+			t.Fatal(err)
+		}
+		if team != "" {
+			if err := manifest.SaveMeta(root, &manifest.Meta{Tags: map[string]string{"team": team}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	srv := New(config.Config{MemoriesURL: "http://127.0.0.1:1"}, nil, dir, nil)
+
+	req := httptest.NewRequest("POST", "/api/projects/index-all?changed=false&filter=billing-*&tag=team=payments", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["started"] != float64(1) || resp["total"] != float64(1) {
+		t.Errorf("expected 1 started of 1, got %v", resp)
+	}
+	// Wait for the run to finish so it doesn't write into the removed
+	// temp dir.
+	var progress *IndexAllProgress
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		progress = srv.runs.BatchProgress()
+		if progress == nil || (progress.Queued == 0 && progress.Running == 0) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run did not finish: %+v", progress)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if progress == nil || len(progress.Runs) != 1 || progress.Runs[0].Project != "billing-api" {
+		t.Fatalf("expected only billing-api scheduled, got %+v", progress)
+	}
+
+	req = httptest.NewRequest("POST", "/api/projects/index-all?tag=team", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("malformed tag: expected 400, got %d", w.Code)
+	}
+}
+
+func TestIndexAll_SkipsRunningProjects(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "busy")
+	os.MkdirAll(root, 0o755)
+	if err := manifest.NewManifest(root, "busy").Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := New(config.Config{}, nil, dir, nil)
+	if _, err := srv.runs.Start("busy"); err != nil {
+		t.Fatalf("failed to start run: %v", err)
+	}
+	defer srv.runs.Finish("busy")
+
+	req := httptest.NewRequest("POST", "/api/projects/index-all", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["started"] != float64(0) {
+		t.Errorf("expected 0 started, got %v", resp["started"])
+	}
+	if skipped, _ := resp["skipped"].([]any); len(skipped) != 1 || skipped[0] != "busy" {
+		t.Errorf("expected busy to be skipped, got %v", resp["skipped"])
+	}
+}
+
+func TestPrometheusMetrics_AfterRun(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644)
+
+	// Unreachable Memories server so the run fails fast.
+	srv := New(config.Config{MemoriesURL: "http://127.0.0.1:1"}, nil, "", nil)
+	srv.EnableMetrics()
+
+	body := strings.NewReader(`{"path": "` + dir + `", "project": "metrics-demo"}`)
+	req := httptest.NewRequest("POST", "/api/projects/index", body)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for srv.runs.ActiveCount() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("run did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	srv.metrics.observeLLM(llm.TierFast, 120, 30)
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected Prometheus text content type, got %q", ct)
+	}
+
+	out := w.Body.String()
+	for _, want := range []string{
+		"# TYPE carto_index_runs_total counter",
+		`carto_index_runs_total{status="error"} 1`,
+		"carto_index_duration_seconds_count 1",
+		`carto_llm_calls_total{tier="fast"} 1`,
+		`carto_llm_tokens_total{tier="fast",direction="input"} 120`,
+		`carto_llm_tokens_total{tier="fast",direction="output"} 30`,
+		"carto_queries_total 0",
+		"carto_active_runs 0",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestPrometheusMetrics_DisabledByDefault(t *testing.T) {
+	srv := New(config.Config{}, nil, "", nil)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		t.Errorf("/metrics should not be served unless enabled, got %d", w.Code)
+	}
+}
+
+// =========================================================================
+// /api/projects/{name}/blueprint
+// =========================================================================
+
+func TestBlueprintEndpoint_ReturnsOverview(t *testing.T) {
+	patterns, _ := json.Marshal([]string{"Handlers return JSON errors", "Stores wrap a Memories client", "Config comes from env"})
+	memories := []map[string]any{
+		{"id": 1, "text": "Carto indexes codebases into layered context.", "source": "carto/proj/_system/layer:blueprint"},
+		{"id": 2, "text": string(patterns), "source": "carto/proj/_system/layer:patterns"},
+		{"id": 3, "text": "Serves the REST API.\nRoutes live in routes.go.", "source": "carto/proj/api/layer:intent"},
+		{"id": 4, "text": "Persists layers in Memories.", "source": "carto/proj/store/layer:intent"},
+		{"id": 5, "text": "atom text", "source": "carto/proj/api/layer:atoms"},
+	}
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") != "0" {
+			json.NewEncoder(w).Encode(map[string]any{"memories": []any{}})
+			return
+		}
+		var page []map[string]any
+		for _, m := range memories {
+			if strings.HasPrefix(m["source"].(string), r.URL.Query().Get("source")) {
+				page = append(page, m)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"memories": page})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, ""), t.TempDir(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/projects/proj/blueprint?patterns=2", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Project   string                 `json:"project"`
+		Blueprint string                 `json:"blueprint"`
+		Patterns  []string               `json:"patterns"`
+		Modules   []storage.ModuleIntent `json:"modules"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Project != "proj" || resp.Blueprint != "Carto indexes codebases into layered context." {
+		t.Errorf("unexpected project or blueprint: %+v", resp)
+	}
+	if len(resp.Patterns) != 2 || resp.Patterns[0] != "Handlers return JSON errors" {
+		t.Errorf("patterns = %v, want the top 2", resp.Patterns)
+	}
+	want := []storage.ModuleIntent{
+		{Name: "api", Intent: "Serves the REST API."},
+		{Name: "store", Intent: "Persists layers in Memories."},
+	}
+	if !reflect.DeepEqual(resp.Modules, want) {
+		t.Errorf("modules = %+v, want %+v", resp.Modules, want)
+	}
+}
+
+func TestBlueprintEndpoint_NotIndexed(t *testing.T) {
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"memories": []any{}})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, ""), t.TempDir(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/projects/fresh/blueprint", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a project with no analysis, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "not indexed yet") {
+		t.Errorf("error should say the project is not indexed yet: %s", w.Body.String())
+	}
+}
+
+// =========================================================================
+// /api/projects/{name}/hotspots
+// =========================================================================
+
+func TestHotspotsEndpoint_RanksByChurnAndRecency(t *testing.T) {
+	now := time.Now().UTC()
+	day := 24 * time.Hour
+	date := func(ago time.Duration) string { return now.Add(-ago).Format(time.RFC3339) }
+
+	apiHistory := []map[string]any{
+		// High churn, recent: should rank first.
+		{"FilePath": "api/handlers.go", "ChurnScore": 10, "Authors": []string{"a", "b"},
+			"Commits": []map[string]any{{"Hash": "1", "Date": date(1 * day)}}},
+		// Same churn as store.go below but much more recent.
+		{"FilePath": "api/routes.go", "ChurnScore": 4, "Authors": []string{"a"},
+			"Commits": []map[string]any{{"Hash": "2", "Date": date(2 * day)}}},
+	}
+	storeHistory := []map[string]any{
+		{"FilePath": "store/store.go", "ChurnScore": 4, "Authors": []string{"c"},
+			"Commits": []map[string]any{{"Hash": "3", "Date": date(300 * day)}}},
+		// No commits: excluded.
+		{"FilePath": "store/empty.go", "ChurnScore": 0, "Authors": []string{}},
+	}
+	apiJSON, _ := json.Marshal(apiHistory)
+	storeJSON, _ := json.Marshal(storeHistory)
+
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/memories" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("offset") != "0" {
+			json.NewEncoder(w).Encode(map[string]any{"memories": []any{}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"memories": []map[string]any{
+				{"id": 1, "text": string(apiJSON), "source": "carto/proj/api/layer:history"},
+				{"id": 2, "text": string(storeJSON), "source": "carto/proj/store/layer:history"},
+				{"id": 3, "text": "atom text", "source": "carto/proj/api/layer:atoms"},
+			},
+		})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, ""), t.TempDir(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/projects/proj/hotspots?limit=5", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Hotspots []struct {
+			Module   string  `json:"module"`
+			FilePath string  `json:"file_path"`
+			Churn    float64 `json:"churn"`
+			Authors  int     `json:"authors"`
+		} `json:"hotspots"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	want := []string{"api/handlers.go", "api/routes.go", "store/store.go"}
+	if len(resp.Hotspots) != len(want) {
+		t.Fatalf("expected %d hotspots, got %+v", len(want), resp.Hotspots)
+	}
+	for i, path := range want {
+		if resp.Hotspots[i].FilePath != path {
+			t.Errorf("rank %d: got %s, want %s", i, resp.Hotspots[i].FilePath, path)
+		}
+	}
+	if resp.Hotspots[0].Module != "api" || resp.Hotspots[0].Authors != 2 {
+		t.Errorf("unexpected top hotspot: %+v", resp.Hotspots[0])
+	}
+}
+
+func TestHotspotsEndpoint_InvalidLimit(t *testing.T) {
+	srv := New(config.Config{}, storage.NewMemoriesClient("http://127.0.0.1:1", ""), t.TempDir(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/projects/proj/hotspots?limit=abc", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid limit, got %d", w.Code)
+	}
+}
+
+// =========================================================================
+// /api/projects/{name}/stale
+// =========================================================================
+
+func TestStaleEndpoint_ClassifiesZonesByLastCommit(t *testing.T) {
+	now := time.Now().UTC()
+	day := 24 * time.Hour
+	date := func(ago time.Duration) string { return now.Add(-ago).Format(time.RFC3339) }
+	file := func(path string, ago ...time.Duration) map[string]any {
+		commits := []map[string]any{}
+		for _, a := range ago {
+			commits = append(commits, map[string]any{"Hash": path, "Date": date(a)})
+		}
+		return map[string]any{"FilePath": path, "Commits": commits}
+	}
+
+	historyJSON, _ := json.Marshal([]map[string]any{
+		file("billing/legacy.go", 800*day, 900*day),
+		file("billing/export.go", 500*day),
+		file("billing/invoice.go", 3*day, 700*day),
+		file("billing/tax.go", 30*day),
+		file("billing/old.go", 600*day),
+	})
+	zonesJSON, _ := json.Marshal([]map[string]any{
+		// Every file untouched for over a year: stale.
+		{"name": "legacy-export", "files": []string{"billing/legacy.go", "billing/export.go"}},
+		// One recently changed file keeps the zone active.
+		{"name": "invoicing", "files": []string{"billing/invoice.go", "billing/old.go"}},
+		{"name": "tax", "files": []string{"billing/tax.go"}},
+		// A file without recorded commits leaves the zone undated.
+		{"name": "reports", "files": []string{"billing/old.go", "billing/report.go"}},
+	})
+
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") != "0" {
+			json.NewEncoder(w).Encode(map[string]any{"memories": []any{}})
+			return
+		}
+		var page []map[string]any
+		for _, m := range []map[string]any{
+			{"id": 1, "text": string(historyJSON), "source": "carto/proj/billing/layer:history"},
+			{"id": 2, "text": string(zonesJSON), "source": "carto/proj/billing/layer:zones"},
+		} {
+			if strings.HasPrefix(m["source"].(string), r.URL.Query().Get("source")) {
+				page = append(page, m)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"memories": page})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, ""), t.TempDir(), nil)
+
+	type zoneState struct {
+		Stale   bool
+		AgeDays int
+		Unknown int
+	}
+	get := func(query string) map[string]zoneState {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/proj/stale"+query, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET stale%s: expected 200, got %d: %s", query, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Zones []struct {
+				Module  string `json:"module"`
+				Zone    string `json:"zone"`
+				Stale   bool   `json:"stale"`
+				AgeDays int    `json:"age_days"`
+				Unknown int    `json:"unknown"`
+			} `json:"zones"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		got := make(map[string]zoneState)
+		for _, z := range resp.Zones {
+			if z.Module != "billing" {
+				t.Errorf("zone %s: module = %q, want billing", z.Zone, z.Module)
+			}
+			got[z.Zone] = zoneState{z.Stale, z.AgeDays, z.Unknown}
+		}
+		return got
+	}
+
+	stale := get("?older_than=1y")
+	if len(stale) != 1 || !stale["legacy-export"].Stale || stale["legacy-export"].AgeDays != 500 {
+		t.Errorf("older_than=1y: got %+v, want only legacy-export, 500 days old", stale)
+	}
+
+	all := get("?older_than=1y&all=true")
+	if len(all) != 4 {
+		t.Fatalf("all=true: got %d zones, want 4: %+v", len(all), all)
+	}
+	if all["invoicing"].Stale || all["invoicing"].AgeDays != 3 {
+		t.Errorf("invoicing = %+v, want active, 3 days old", all["invoicing"])
+	}
+	if all["reports"].Stale || all["reports"].Unknown != 1 {
+		t.Errorf("reports = %+v, want not stale with 1 undated file", all["reports"])
+	}
+
+	// A shorter threshold also catches the tax zone.
+	if got := get("?older_than=2w"); !got["tax"].Stale || len(got) != 2 {
+		t.Errorf("older_than=2w: got %+v, want legacy-export and tax", got)
+	}
+}
+
+func TestCyclesEndpoint(t *testing.T) {
+	wiring := func(edges ...[2]string) string {
+		deps := []map[string]string{}
+		for _, e := range edges {
+			deps = append(deps, map[string]string{"from": e[0], "to": e[1], "reason": "calls"})
+		}
+		data, _ := json.Marshal(deps)
+		return string(data)
+	}
+	memories := []map[string]any{
+		// A→B→C→A, spread over three modules.
+		{"id": 1, "text": wiring([2]string{"A", "B"}), "source": "carto/cyclic/a/layer:wiring"},
+		{"id": 2, "text": wiring([2]string{"B", "C"}), "source": "carto/cyclic/b/layer:wiring"},
+		{"id": 3, "text": wiring([2]string{"C", "A"}, [2]string{"C", "D"}), "source": "carto/cyclic/c/layer:wiring"},
+		{"id": 4, "text": wiring([2]string{"A", "B"}, [2]string{"B", "C"}), "source": "carto/acyclic/a/layer:wiring"},
+	}
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") != "0" {
+			json.NewEncoder(w).Encode(map[string]any{"memories": []any{}})
+			return
+		}
+		var page []map[string]any
+		for _, m := range memories {
+			if strings.HasPrefix(m["source"].(string), r.URL.Query().Get("source")) {
+				page = append(page, m)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"memories": page})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, ""), t.TempDir(), nil)
+	get := func(project string) [][]string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/"+project+"/cycles", nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s cycles: expected 200, got %d: %s", project, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Cycles [][]string `json:"cycles"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Cycles == nil {
+			t.Errorf("%s: cycles should be an empty array, not null", project)
+		}
+		return resp.Cycles
+	}
+
+	if got := get("cyclic"); len(got) != 1 || strings.Join(got[0], ",") != "A,B,C" {
+		t.Errorf("cyclic: got %v, want [[A B C]]", got)
+	}
+	if got := get("acyclic"); len(got) != 0 {
+		t.Errorf("acyclic: got %v, want none", got)
+	}
+}
+
+func TestRefsEndpoint(t *testing.T) {
+	wiring := func(edges ...[3]string) string {
+		deps := []map[string]string{}
+		for _, e := range edges {
+			deps = append(deps, map[string]string{"from": e[0], "to": e[1], "reason": e[2]})
+		}
+		data, _ := json.Marshal(deps)
+		return string(data)
+	}
+	memories := []map[string]any{
+		{"id": 1, "text": wiring([3]string{"Handler", "UserStore", "loads users"}), "source": "carto/app/api/layer:wiring"},
+		{"id": 2, "text": wiring([3]string{"UserStore", "DB", "queries users"}, [3]string{"UserStoreCache", "Redis", "caches"}), "source": "carto/app/store/layer:wiring"},
+		{"id": 3, "text": wiring([3]string{"Page", "Client", "renders"}), "source": "carto/app/ui/layer:wiring"},
+	}
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") != "0" {
+			json.NewEncoder(w).Encode(map[string]any{"memories": []any{}})
+			return
+		}
+		var page []map[string]any
+		for _, m := range memories {
+			if strings.HasPrefix(m["source"].(string), r.URL.Query().Get("source")) {
+				page = append(page, m)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"memories": page})
+	}))
+	defer memSrv.Close()
+
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, ""), t.TempDir(), nil)
+	get := func(query string) []analyzer.ModuleRefs {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/app/refs?"+query, nil)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET refs?%s: expected 200, got %d: %s", query, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Modules []analyzer.ModuleRefs `json:"modules"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Modules
+	}
+
+	got := get("symbol=userstore")
+	if len(got) != 2 || got[0].Module != "api" || got[1].Module != "store" {
+		t.Fatalf("expected refs in api and store, got %+v", got)
+	}
+	// Reverse: api's Handler depends on UserStore.
+	if len(got[0].UsedBy) != 1 || got[0].UsedBy[0].From != "Handler" || got[0].UsedBy[0].Reason != "loads users" {
+		t.Errorf("api used_by = %+v", got[0].UsedBy)
+	}
+	// Forward: UserStore (and, by substring, UserStoreCache) depend on others.
+	if len(got[1].Uses) != 2 || got[1].Uses[0].To != "DB" || len(got[1].UsedBy) != 0 {
+		t.Errorf("store uses = %+v, used_by = %+v", got[1].Uses, got[1].UsedBy)
+	}
+
+	got = get("symbol=UserStore&exact=true")
+	if len(got) != 2 || len(got[1].Uses) != 1 {
+		t.Errorf("exact: expected UserStoreCache excluded, got %+v", got)
+	}
+
+	if got := get("symbol=Nothing"); got == nil || len(got) != 0 {
+		t.Errorf("no match: expected an empty array, got %#v", got)
+	}
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/projects/app/refs", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("missing symbol: expected 400, got %d", w.Code)
+	}
+}
+
+func TestStaleEndpoint_InvalidThreshold(t *testing.T) {
+	srv := New(config.Config{}, storage.NewMemoriesClient("http://127.0.0.1:1", ""), t.TempDir(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/projects/proj/stale?older_than=soon", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid older_than, got %d", w.Code)
+	}
+}
+
+func TestDuplicatesEndpoint(t *testing.T) {
+	tmp := t.TempDir()
+	proj := filepath.Join(tmp, "proj")
+	body := "(items []int) int {\n\ttotal := 0\n\tfor _, v := range items {\n\t\tif v > 0 {\n\t\t\ttotal += v\n\t\t}\n\t}\n\treturn total\n}\n"
+	for rel, content := range map[string]string{
+		"a/go.mod":  "module example.com/a\n",
+		"a/sum.go":  "package a\n\nfunc Sum" + body,
+		"b/go.mod":  "module example.com/b\n",
+		"b/add.go":  "package b\n\nfunc Add" + body,
+		"b/diff.go": "package b\n\nfunc Diff(a, b int) int {\n\tif a > b {\n\t\treturn a - b\n\t}\n\treturn b - a\n}\n",
+	} {
+		os.MkdirAll(filepath.Dir(filepath.Join(proj, rel)), 0o755)
+		os.WriteFile(filepath.Join(proj, rel), []byte(content), 0o644)
+	}
+
+	srv := New(config.Config{}, nil, tmp, nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/projects/proj/duplicates?cross_module=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Clusters []struct {
+			CrossModule bool `json:"cross_module"`
+			Members     []struct {
+				Name     string `json:"name"`
+				FilePath string `json:"file_path"`
+			} `json:"members"`
+		} `json:"clusters"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Clusters) != 1 || !resp.Clusters[0].CrossModule || len(resp.Clusters[0].Members) != 2 {
+		t.Fatalf("clusters = %+v", resp.Clusters)
+	}
+	if m := resp.Clusters[0].Members; m[0].Name != "Sum" || m[1].Name != "Add" {
+		t.Errorf("members = %+v", m)
+	}
+
+	for path, want := range map[string]int{
+		"/api/projects/missing/duplicates":           http.StatusNotFound,
+		"/api/projects/proj/duplicates?min_lines=-1": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s: expected %d, got %d", path, want, w.Code)
+		}
+	}
+}
+
+func TestQueryEndpoint_UnavailableUntilProjectIndexed(t *testing.T) {
+	memSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/search":
+			w.Write([]byte(`{"results": [{"id": 1, "text": "auth handler", "score": 0.9, "source": "carto/proj/auth/layer:atoms"}]}`))
+		case "/health":
+			w.Write([]byte(`{"status": "ok"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer memSrv.Close()
+
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "notyet"), 0o755)
+	srv := New(config.Config{}, storage.NewMemoriesClient(memSrv.URL, "test-key"), tmp, nil)
+
+	ready := func() bool {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+		var resp struct {
+			Ready bool `json:"ready"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode health: %v", err)
+		}
+		return resp.Ready
+	}
+	query := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"text": "auth"}`)))
+		return w
+	}
+
+	if ready() {
+		t.Error("health reports ready with no indexed projects")
+	}
+	w := query()
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("query before indexing: expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "no projects have been indexed") {
+		t.Errorf("503 body should say why: %s", w.Body.String())
+	}
+
+	os.MkdirAll(filepath.Join(tmp, "proj", ".carto"), 0o755)
+	mf := `{"version": "1.0", "project": "proj", "files": {"auth.go": {"hash": "abc", "size": 10}}}`
+	os.WriteFile(filepath.Join(tmp, "proj", ".carto", "manifest.json"), []byte(mf), 0o644)
+
+	if !ready() {
+		t.Error("health should report ready once a project is indexed")
+	}
+	if w := query(); w.Code != http.StatusOK {
+		t.Errorf("query after indexing: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -3,10 +3,15 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/divyekant/carto/internal/gitclone"
+	"github.com/divyekant/carto/internal/llm"
+	"github.com/divyekant/carto/internal/pipeline"
 )
 
 // ProgressEvent is sent over SSE to report indexing progress.
@@ -18,12 +23,16 @@ type ProgressEvent struct {
 
 // IndexResult is the final summary sent when an index run completes.
 type IndexResult struct {
-	Modules int           `json:"modules"`
-	Files   int           `json:"files"`
-	Atoms   int           `json:"atoms"`
-	Errors  int           `json:"errors"`
-	Elapsed time.Duration `json:"elapsed"`
-	ErrMsgs []string      `json:"error_messages,omitempty"`
+	Modules   int           `json:"modules"`
+	Files     int           `json:"files"`
+	Atoms     int           `json:"atoms"`
+	Truncated int           `json:"truncated,omitempty"`
+	Unstored  int           `json:"unstored,omitempty"`       // memories Memories did not accept
+	Failed    []string      `json:"failed_files,omitempty"`   // files that failed to chunk or analyze
+	Skipped   []string      `json:"skipped_failed,omitempty"` // files skipped after failing repeatedly
+	Errors    int           `json:"errors"`
+	Elapsed   time.Duration `json:"elapsed"`
+	ErrMsgs   []string      `json:"error_messages,omitempty"`
 }
 
 // IndexRun tracks a single in-flight indexing run for a project.
@@ -36,6 +45,11 @@ type IndexRun struct {
 	lastEvent *sseEvent // buffered final event for late-connecting clients
 	finished  bool
 	stopped   bool // true if cancelled via Stop
+	aborted   bool // true if cancelled via Abort (DELETE .../index)
+	queued    bool // waiting for an index-all worker slot
+	startedAt time.Time
+
+	progress *ProgressEvent // most recent progress event, for aggregate status
 
 	// Stored result/error for the runs API so the UI can restore state.
 	FinalResult *IndexResult
@@ -50,7 +64,11 @@ type sseEvent struct {
 
 // SendProgress sends a progress event to the SSE stream.
 func (r *IndexRun) SendProgress(phase string, done, total int) {
-	data, _ := json.Marshal(ProgressEvent{Phase: phase, Done: done, Total: total})
+	ev := ProgressEvent{Phase: phase, Done: done, Total: total}
+	r.mu.Lock()
+	r.progress = &ev
+	r.mu.Unlock()
+	data, _ := json.Marshal(ev)
 	select {
 	case r.events <- sseEvent{Event: "progress", Data: string(data)}:
 	default:
@@ -72,6 +90,21 @@ func (r *IndexRun) SendResult(result IndexResult) {
 	}
 }
 
+// MarkQueued flags the run as waiting for a worker slot.
+func (r *IndexRun) MarkQueued() {
+	r.mu.Lock()
+	r.queued = true
+	r.mu.Unlock()
+}
+
+// MarkRunning clears the queued flag once a worker picks the run up.
+func (r *IndexRun) MarkRunning() {
+	r.mu.Lock()
+	r.queued = false
+	r.startedAt = time.Now()
+	r.mu.Unlock()
+}
+
 // SendLog sends a log message event to the SSE stream.
 func (r *IndexRun) SendLog(level, msg string) {
 	data, _ := json.Marshal(map[string]string{"level": level, "message": msg})
@@ -83,8 +116,14 @@ func (r *IndexRun) SendLog(level, msg string) {
 
 // SendError sends a pipeline error event.
 // Uses "pipeline_error" to avoid collision with the SSE built-in "error" event.
-func (r *IndexRun) SendError(msg string) {
-	data, _ := json.Marshal(map[string]string{"message": msg})
+// Known failure modes carry a machine-readable "code" (see indexErrorCode).
+func (r *IndexRun) SendError(err error) {
+	msg := err.Error()
+	payload := map[string]string{"message": msg}
+	if code := indexErrorCode(err); code != "" {
+		payload["code"] = code
+	}
+	data, _ := json.Marshal(payload)
 	ev := sseEvent{Event: "pipeline_error", Data: string(data)}
 	r.mu.Lock()
 	r.lastEvent = &ev
@@ -96,11 +135,16 @@ func (r *IndexRun) SendError(msg string) {
 	}
 }
 
-// SendStopped sends a stopped event when the run is cancelled by user.
+// SendStopped sends the final event for a run whose context was cancelled:
+// "cancelled" if it was aborted via RunManager.Abort, "stopped" otherwise.
 func (r *IndexRun) SendStopped() {
-	data, _ := json.Marshal(map[string]string{"message": "Indexing stopped by user"})
-	ev := sseEvent{Event: "stopped", Data: string(data)}
 	r.mu.Lock()
+	event, msg := "stopped", "Indexing stopped by user"
+	if r.aborted {
+		event, msg = "cancelled", "Indexing cancelled"
+	}
+	data, _ := json.Marshal(map[string]string{"message": msg})
+	ev := sseEvent{Event: event, Data: string(data)}
 	r.lastEvent = &ev
 	r.stopped = true
 	r.mu.Unlock()
@@ -110,8 +154,15 @@ func (r *IndexRun) SendStopped() {
 	}
 }
 
+// sseHeartbeatInterval is how long WriteSSE lets the stream sit idle before
+// sending a heartbeat comment, keeping proxies (nginx drops idle
+// connections after 60s by default) from closing it.
+var sseHeartbeatInterval = 15 * time.Second
+
 // WriteSSE streams events to the HTTP response as text/event-stream.
-// It blocks until the run completes or the client disconnects.
+// It blocks until the run completes or the client disconnects. While no
+// event is sent it writes a ": heartbeat" comment line, which EventSource
+// clients ignore, every sseHeartbeatInterval.
 func (r *IndexRun) WriteSSE(w http.ResponseWriter, req *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -137,11 +188,18 @@ func (r *IndexRun) WriteSSE(w http.ResponseWriter, req *http.Request) {
 	}
 	r.mu.Unlock()
 
+	heartbeat := time.NewTimer(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
 	ctx := req.Context()
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+			heartbeat.Reset(sseHeartbeatInterval)
 		case ev, ok := <-r.events:
 			if !ok {
 				// Channel closed — run finished. Send last event if we missed it.
@@ -156,6 +214,7 @@ func (r *IndexRun) WriteSSE(w http.ResponseWriter, req *http.Request) {
 			}
 			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, ev.Data)
 			flusher.Flush()
+			heartbeat.Reset(sseHeartbeatInterval)
 		case <-r.done:
 			// Drain remaining events then send last event.
 			for {
@@ -181,11 +240,49 @@ func (r *IndexRun) WriteSSE(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// ErrIndexRunning is returned (wrapped) by RunManager.Start when the project
+// already has an unfinished run.
+var ErrIndexRunning = errors.New("index already running")
+
+// indexErrorCode classifies a failed run's error for the "code" field of
+// the pipeline_error event, or returns "" for an unclassified error.
+func indexErrorCode(err error) string {
+	switch {
+	case errors.Is(err, pipeline.ErrMemoriesUnreachable):
+		return "memories_unreachable"
+	case errors.Is(err, pipeline.ErrModuleNotFound):
+		return "module_not_found"
+	case errors.Is(err, llm.ErrNoAPIKey):
+		return "no_api_key"
+	case errors.Is(err, pipeline.ErrTimedOut):
+		return "timed_out"
+	case errors.Is(err, gitclone.ErrAuthFailed):
+		return "clone_auth_failed"
+	case errors.Is(err, gitclone.ErrNetwork):
+		return "clone_network_error"
+	}
+	return ""
+}
+
 // RunManager tracks active indexing runs by project name.
 type RunManager struct {
 	mu       sync.Mutex
 	runs     map[string]*IndexRun
 	lastRuns map[string]RunStatus
+	batch    *indexAllBatch // most recent index-all request, if any
+
+	// onFinish, if set, is called with the project, final status and
+	// duration of every run (used for metrics and cache invalidation).
+	onFinish func(project, status string, elapsed time.Duration)
+}
+
+// indexAllBatch records which projects an index-all request covered so
+// aggregate progress can be reported after individual runs finish.
+type indexAllBatch struct {
+	projects    []string
+	skipped     []string
+	incremental bool
+	startedAt   time.Time
 }
 
 // NewRunManager creates an empty RunManager.
@@ -196,9 +293,10 @@ func NewRunManager() *RunManager {
 	}
 }
 
-// Start creates a new IndexRun for the given project.
-// Returns nil if a run is already active (and not finished) for that project.
-func (m *RunManager) Start(project string) *IndexRun {
+// Start creates a new IndexRun for the given project. It returns an error
+// wrapping ErrIndexRunning if a run is already active (and not finished)
+// for that project.
+func (m *RunManager) Start(project string) (*IndexRun, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -207,20 +305,21 @@ func (m *RunManager) Start(project string) *IndexRun {
 		done := existing.finished
 		existing.mu.Unlock()
 		if !done {
-			return nil // still running
+			return nil, fmt.Errorf("%w for project %s", ErrIndexRunning, project)
 		}
 		// Old run finished — replace it.
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	run := &IndexRun{
-		Ctx:    ctx,
-		Cancel: cancel,
-		events: make(chan sseEvent, 100),
-		done:   make(chan struct{}),
+		Ctx:       ctx,
+		Cancel:    cancel,
+		events:    make(chan sseEvent, 100),
+		done:      make(chan struct{}),
+		startedAt: time.Now(),
 	}
 	m.runs[project] = run
-	return run
+	return run, nil
 }
 
 // Finish marks the run as done. The run stays in the map for 30 seconds
@@ -237,7 +336,9 @@ func (m *RunManager) Finish(project string) {
 
 	// Snapshot for persistent last-run tracking.
 	status := RunStatus{Project: project}
-	if run.stopped {
+	if run.aborted {
+		status.Status = "cancelled"
+	} else if run.stopped {
 		status.Status = "stopped"
 	} else if run.FinalError != "" {
 		status.Status = "error"
@@ -249,12 +350,17 @@ func (m *RunManager) Finish(project string) {
 		status.Status = "complete"
 	}
 	m.lastRuns[project] = status
+	elapsed := time.Since(run.startedAt)
 
 	run.mu.Unlock()
 	close(run.done)
 	close(run.events)
 	m.mu.Unlock()
 
+	if m.onFinish != nil {
+		m.onFinish(project, status.Status, elapsed)
+	}
+
 	// Clean up after a delay so late SSE clients can still connect.
 	go func() {
 		time.Sleep(30 * time.Second)
@@ -266,6 +372,18 @@ func (m *RunManager) Finish(project string) {
 
 // Stop cancels the active run for a project. Returns false if no active run.
 func (m *RunManager) Stop(project string) bool {
+	return m.cancel(project, false)
+}
+
+// Abort cancels the active run for a project and marks it aborted, so it
+// finishes with status "cancelled" and its stream ends with a "cancelled"
+// event. Aborting a run that is already cancelling is a no-op that still
+// returns true. Returns false if no active run.
+func (m *RunManager) Abort(project string) bool {
+	return m.cancel(project, true)
+}
+
+func (m *RunManager) cancel(project string, abort bool) bool {
 	m.mu.Lock()
 	run, exists := m.runs[project]
 	m.mu.Unlock()
@@ -273,15 +391,33 @@ func (m *RunManager) Stop(project string) bool {
 		return false
 	}
 	run.mu.Lock()
-	done := run.finished
-	run.mu.Unlock()
-	if done {
+	if run.finished {
+		run.mu.Unlock()
 		return false
 	}
+	if abort {
+		run.aborted = true
+	}
+	run.mu.Unlock()
 	run.Cancel()
 	return true
 }
 
+// ActiveCount returns how many runs are queued or running.
+func (m *RunManager) ActiveCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, run := range m.runs {
+		run.mu.Lock()
+		if !run.finished {
+			n++
+		}
+		run.mu.Unlock()
+	}
+	return n
+}
+
 // Get returns the active run for a project, or nil if none is active.
 func (m *RunManager) Get(project string) *IndexRun {
 	m.mu.Lock()
@@ -291,10 +427,39 @@ func (m *RunManager) Get(project string) *IndexRun {
 
 // RunStatus is the JSON shape returned by the runs endpoint.
 type RunStatus struct {
-	Project string       `json:"project"`
-	Status  string       `json:"status"` // "running", "complete", "error"
-	Result  *IndexResult `json:"result,omitempty"`
-	Error   string       `json:"error,omitempty"`
+	Project  string         `json:"project"`
+	Status   string         `json:"status"` // "queued", "running", "complete", "error", "stopped", "cancelled"
+	Progress *ProgressEvent `json:"progress,omitempty"`
+	Result   *IndexResult   `json:"result,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// status snapshots the run's current state for the runs API.
+func (r *IndexRun) status(project string) RunStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := RunStatus{Project: project}
+	switch {
+	case !r.finished && r.queued:
+		status.Status = "queued"
+	case !r.finished:
+		status.Status = "running"
+		status.Progress = r.progress
+	case r.aborted:
+		status.Status = "cancelled"
+	case r.stopped:
+		status.Status = "stopped"
+	case r.FinalError != "":
+		status.Status = "error"
+		status.Error = r.FinalError
+	case r.FinalResult != nil:
+		status.Status = "complete"
+		status.Result = r.FinalResult
+	default:
+		status.Status = "complete"
+	}
+	return status
 }
 
 // ListRuns returns the status of all tracked runs.
@@ -306,23 +471,7 @@ func (m *RunManager) ListRuns() []RunStatus {
 	var runs []RunStatus
 
 	for name, run := range m.runs {
-		run.mu.Lock()
-		status := RunStatus{Project: name}
-		if !run.finished {
-			status.Status = "running"
-		} else if run.stopped {
-			status.Status = "stopped"
-		} else if run.FinalError != "" {
-			status.Status = "error"
-			status.Error = run.FinalError
-		} else if run.FinalResult != nil {
-			status.Status = "complete"
-			status.Result = run.FinalResult
-		} else {
-			status.Status = "complete"
-		}
-		run.mu.Unlock()
-		runs = append(runs, status)
+		runs = append(runs, run.status(name))
 		seen[name] = true
 	}
 
@@ -334,3 +483,79 @@ func (m *RunManager) ListRuns() []RunStatus {
 
 	return runs
 }
+
+// IndexAllProgress is the aggregate status of the most recent index-all
+// request, returned by GET /api/projects/index-all.
+type IndexAllProgress struct {
+	Total       int         `json:"total"`
+	Queued      int         `json:"queued"`
+	Running     int         `json:"running"`
+	Complete    int         `json:"complete"`
+	Failed      int         `json:"failed"`
+	Stopped     int         `json:"stopped"`
+	Skipped     []string    `json:"skipped"`
+	Incremental bool        `json:"incremental"`
+	StartedAt   time.Time   `json:"started_at"`
+	Runs        []RunStatus `json:"runs"`
+}
+
+// StartBatch records a new index-all request covering projects. Projects in
+// skipped already had an active run and were not restarted.
+func (m *RunManager) StartBatch(projects, skipped []string, incremental bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batch = &indexAllBatch{
+		projects:    projects,
+		skipped:     skipped,
+		incremental: incremental,
+		startedAt:   time.Now(),
+	}
+}
+
+// BatchProgress returns the aggregate progress of the most recent index-all
+// request, or nil if none has been made.
+func (m *RunManager) BatchProgress() *IndexAllProgress {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.batch == nil {
+		return nil
+	}
+
+	p := &IndexAllProgress{
+		Total:       len(m.batch.projects),
+		Skipped:     m.batch.skipped,
+		Incremental: m.batch.incremental,
+		StartedAt:   m.batch.startedAt,
+		Runs:        make([]RunStatus, 0, len(m.batch.projects)),
+	}
+	if p.Skipped == nil {
+		p.Skipped = []string{}
+	}
+
+	for _, name := range m.batch.projects {
+		var status RunStatus
+		if run, ok := m.runs[name]; ok {
+			status = run.status(name)
+		} else if last, ok := m.lastRuns[name]; ok {
+			status = last
+		} else {
+			continue
+		}
+
+		switch status.Status {
+		case "queued":
+			p.Queued++
+		case "running":
+			p.Running++
+		case "complete":
+			p.Complete++
+		case "error":
+			p.Failed++
+		case "stopped", "cancelled":
+			p.Stopped++
+		}
+		p.Runs = append(p.Runs, status)
+	}
+	return p
+}
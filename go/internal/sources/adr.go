@@ -0,0 +1,155 @@
+package sources
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultADRDirs are the conventional Architecture Decision Record
+// locations, relative to the repo root.
+var defaultADRDirs = []string{
+	"docs/adr",
+	"doc/adr",
+	"docs/decisions",
+	"doc/decisions",
+	"docs/architecture/decisions",
+}
+
+// adrFilePattern matches common ADR filenames: "0001-use-postgres.md",
+// "001-title.md" and "adr-use-postgres.md".
+var adrFilePattern = regexp.MustCompile(`(?i)^(\d{3,4}-.+|adr-.+)\.md$`)
+
+// adrTitlePrefix strips a leading "1. " or "ADR-0001: " from headings.
+var adrTitlePrefix = regexp.MustCompile(`(?i)^(adr[-\s]?\d+[:.]?\s*|\d+[.:]\s*)`)
+
+// ADRSource reads Architecture Decision Records from the repository.
+type ADRSource struct {
+	dirs []string // relative to repo root unless absolute
+}
+
+// NewADRSource creates an ADR source that scans the default ADR directories.
+func NewADRSource() *ADRSource {
+	return &ADRSource{dirs: defaultADRDirs}
+}
+
+func (a *ADRSource) Name() string { return "adr" }
+func (a *ADRSource) Scope() Scope { return ProjectScope }
+func (a *ADRSource) Local() bool  { return true }
+
+// Configure accepts an optional comma-separated "dirs" setting that replaces
+// the default ADR directories.
+func (a *ADRSource) Configure(cfg SourceConfig) error {
+	if v := cfg.Settings["dirs"]; v != "" {
+		var dirs []string
+		for _, d := range strings.Split(v, ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				dirs = append(dirs, d)
+			}
+		}
+		a.dirs = dirs
+	}
+	return nil
+}
+
+func (a *ADRSource) Fetch(_ context.Context, req FetchRequest) ([]Artifact, error) {
+	var artifacts []Artifact
+	for _, dir := range a.dirs {
+		absDir := dir
+		if !filepath.IsAbs(dir) {
+			absDir = filepath.Join(req.RepoRoot, dir)
+		}
+
+		entries, err := os.ReadDir(absDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return artifacts, fmt.Errorf("adr: read dir: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !adrFilePattern.MatchString(entry.Name()) {
+				continue
+			}
+
+			absPath := filepath.Join(absDir, entry.Name())
+			data, err := os.ReadFile(absPath)
+			if err != nil {
+				continue
+			}
+
+			relPath := absPath
+			if rel, err := filepath.Rel(req.RepoRoot, absPath); err == nil {
+				relPath = filepath.ToSlash(rel)
+			}
+
+			title, status := parseADR(string(data))
+			if title == "" {
+				title = strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			}
+
+			tags := map[string]string{"type": "adr"}
+			if status != "" {
+				tags["status"] = status
+			}
+
+			artifacts = append(artifacts, Artifact{
+				Source:   "adr",
+				Category: Knowledge,
+				ID:       relPath,
+				Title:    title,
+				Body:     string(data),
+				URL:      "file://" + absPath,
+				Files:    []string{relPath},
+				Tags:     tags,
+			})
+		}
+	}
+	return artifacts, nil
+}
+
+// parseADR extracts the title (first level-1 heading) and status from an
+// ADR. Status is read from either a "## Status" section (first non-empty
+// line below it) or an inline "Status: accepted" line.
+func parseADR(content string) (title, status string) {
+	inStatus := false
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+
+		switch {
+		case title == "" && strings.HasPrefix(line, "# "):
+			title = adrTitlePrefix.ReplaceAllString(strings.TrimSpace(line[2:]), "")
+		case strings.HasPrefix(line, "#"):
+			heading := strings.ToLower(strings.TrimSpace(strings.TrimLeft(line, "#")))
+			inStatus = heading == "status"
+		case inStatus && line != "":
+			if status == "" {
+				status = strings.ToLower(strings.Trim(line, "*_ "))
+			}
+			inStatus = false
+		default:
+			// Inline form, optionally bolded: "**Status:** Accepted".
+			plain := strings.TrimLeft(line, "*_")
+			if status == "" && strings.HasPrefix(strings.ToLower(plain), "status:") {
+				status = strings.ToLower(strings.Trim(plain[len("status:"):], "*_ "))
+			}
+		}
+	}
+	return title, status
+}
+
+// hasADRDir reports whether any default ADR directory exists under root.
+func hasADRDir(root string) bool {
+	for _, dir := range defaultADRDirs {
+		if info, err := os.Stat(filepath.Join(root, dir)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package sources
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeADR(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestADRSource_Fetch_DiscoversADRs(t *testing.T) {
+	root := t.TempDir()
+	writeADR(t, root, "docs/adr/0001-use-postgres.md", "# 1. Use PostgreSQL\n\n## Status\n\nAccepted\n\n## Context\n\nWe need a database.\n")
+	writeADR(t, root, "docs/adr/adr-event-sourcing.md", "# Event sourcing for orders\n\n**Status:** Superseded\n")
+	writeADR(t, root, "doc/decisions/002-grpc.md", "Status: proposed\n\nNo heading here.\n")
+	writeADR(t, root, "docs/adr/README.md", "# Index of decisions\n")
+	writeADR(t, root, "docs/adr/template.md", "# Title\n")
+
+	src := NewADRSource()
+	arts, err := src.Fetch(context.Background(), FetchRequest{Project: "test", RepoRoot: root})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	byID := make(map[string]Artifact)
+	for _, a := range arts {
+		byID[a.ID] = a
+	}
+	if len(byID) != 3 {
+		t.Fatalf("expected 3 ADRs, got %d: %+v", len(byID), arts)
+	}
+
+	pg := byID["docs/adr/0001-use-postgres.md"]
+	if pg.Title != "Use PostgreSQL" {
+		t.Errorf("title = %q, want %q", pg.Title, "Use PostgreSQL")
+	}
+	if pg.Tags["type"] != "adr" || pg.Tags["status"] != "accepted" {
+		t.Errorf("unexpected tags: %v", pg.Tags)
+	}
+	if pg.Category != Knowledge {
+		t.Errorf("category = %q, want %q", pg.Category, Knowledge)
+	}
+
+	if got := byID["docs/adr/adr-event-sourcing.md"].Tags["status"]; got != "superseded" {
+		t.Errorf("bold inline status = %q, want superseded", got)
+	}
+
+	grpc := byID["doc/decisions/002-grpc.md"]
+	if grpc.Title != "002-grpc" || grpc.Tags["status"] != "proposed" {
+		t.Errorf("fallback title/status wrong: %q %v", grpc.Title, grpc.Tags)
+	}
+}
+
+func TestBuildRegistry_AutoDetectsADRs(t *testing.T) {
+	root := t.TempDir()
+	writeADR(t, root, "docs/adr/0001-record.md", "# Record decisions\n")
+
+	reg := BuildRegistry(root, nil, Credentials{})
+	found := false
+	for _, name := range reg.SourceNames() {
+		if name == "adr" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected adr source to be auto-registered, got %v", reg.SourceNames())
+	}
+}
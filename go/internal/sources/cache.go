@@ -0,0 +1,120 @@
+package sources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Local is implemented by sources that only read the local checkout, such
+// as git history and ADR files. They are cheap to fetch and must reflect
+// local edits at once, so the registry never caches them.
+type Local interface {
+	Local() bool
+}
+
+// Cache keeps each source's fetched artifacts on disk for a while, so
+// index runs in quick succession reuse the tickets, PRs and pages fetched
+// by the first instead of calling slow, rate-limited APIs again. Entries
+// live in {projectRoot}/.carto/signalcache/, one file per source and
+// module.
+type Cache struct {
+	dir     string
+	ttl     time.Duration
+	refresh bool
+	now     func() time.Time
+}
+
+// NewCache creates a cache for the project at projectRoot whose entries are
+// reused for ttl after they are fetched. With refresh set, no entry is
+// reused, so every source is fetched again and its entry rewritten.
+func NewCache(projectRoot string, ttl time.Duration, refresh bool) *Cache {
+	return &Cache{
+		dir:     filepath.Join(projectRoot, ".carto", "signalcache"),
+		ttl:     ttl,
+		refresh: refresh,
+		now:     time.Now,
+	}
+}
+
+// cacheEntry is the on-disk form of one source's cached fetch.
+type cacheEntry struct {
+	Source    string     `json:"source"`
+	Module    string     `json:"module,omitempty"`
+	FetchedAt time.Time  `json:"fetched_at"`
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// path returns the file caching source's artifacts for module, which is
+// empty for project-scope sources. Module names can hold slashes, so they
+// are hashed.
+func (c *Cache) path(source, module string) string {
+	name := source
+	if module != "" {
+		sum := sha256.Sum256([]byte(module))
+		name += "-" + hex.EncodeToString(sum[:8])
+	}
+	return filepath.Join(c.dir, name+".json")
+}
+
+// get returns the artifacts cached for source and module, if an entry
+// exists and is younger than the TTL.
+func (c *Cache) get(source, module string) ([]Artifact, bool) {
+	if c.refresh || c.ttl <= 0 {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.path(source, module))
+	if err != nil {
+		return nil, false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil || e.Source != source || e.Module != module {
+		return nil, false
+	}
+	if age := c.now().Sub(e.FetchedAt); age < 0 || age >= c.ttl {
+		return nil, false
+	}
+	return e.Artifacts, true
+}
+
+// put caches the artifacts source fetched for module, replacing the file
+// atomically so a concurrent run never reads half an entry.
+func (c *Cache) put(source, module string, arts []Artifact) error {
+	if c.ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(cacheEntry{Source: source, Module: module, FetchedAt: c.now(), Artifacts: arts})
+	if err != nil {
+		return fmt.Errorf("encode cache entry: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+	path := c.path(source, module)
+	tmp, err := os.CreateTemp(c.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	return nil
+}
+
+// isLocal reports whether src is a Local source.
+func isLocal(src Source) bool {
+	l, ok := src.(Local)
+	return ok && l.Local()
+}
@@ -7,15 +7,24 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // SourcesYAML is the parsed representation of .carto/sources.yaml.
 type SourcesYAML struct {
+	Fetch   FetchOptions           `yaml:"fetch"`
 	Sources map[string]SourceEntry `yaml:"sources"`
 }
 
+// FetchOptions tunes how the registry fetches from its sources. A source's
+// own "timeout" setting overrides Timeout for that source.
+type FetchOptions struct {
+	Timeout       string `yaml:"timeout"`        // e.g. "30s"; "0" disables the timeout
+	MaxConcurrent int    `yaml:"max_concurrent"` // sources fetched at once
+}
+
 // SourceEntry is a single source definition in the yaml file.
 type SourceEntry struct {
 	// Flat key-value settings (e.g., "project: PROJ", "url: https://...").
@@ -93,6 +102,15 @@ func SaveSourcesConfig(projectRoot string, cfg *SourcesYAML) error {
 	}
 
 	var buf bytes.Buffer
+	if cfg.Fetch != (FetchOptions{}) {
+		buf.WriteString("fetch:\n")
+		if cfg.Fetch.Timeout != "" {
+			buf.WriteString("  timeout: " + cfg.Fetch.Timeout + "\n")
+		}
+		if cfg.Fetch.MaxConcurrent != 0 {
+			buf.WriteString(fmt.Sprintf("  max_concurrent: %d\n", cfg.Fetch.MaxConcurrent))
+		}
+	}
 	buf.WriteString("sources:\n")
 
 	// Sort source names for deterministic output.
@@ -150,14 +168,66 @@ type Credentials struct {
 	LinearToken string
 	NotionToken string
 	SlackToken  string
+
+	// Project, when set, lets CARTO_PROJ_<PROJECT>_* environment variables
+	// override the fields above for that project (see ForProject).
+	Project string
+}
+
+// projectEnvKey returns the environment variable holding key for project:
+// CARTO_PROJ_ followed by the project name upper-cased with every
+// character other than a letter or digit replaced by "_", then "_" and key.
+// For example, project "payments-api" and key "GITHUB_TOKEN" give
+// CARTO_PROJ_PAYMENTS_API_GITHUB_TOKEN.
+func projectEnvKey(project, key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, project)
+	return "CARTO_PROJ_" + name + "_" + key
+}
+
+// ForProject returns c with any credentials set in the project's
+// CARTO_PROJ_<PROJECT>_<VAR> environment variables taking precedence,
+// where <VAR> is the global variable name (GITHUB_TOKEN, JIRA_TOKEN,
+// JIRA_EMAIL, JIRA_BASE_URL, LINEAR_TOKEN, NOTION_TOKEN, SLACK_TOKEN).
+// This lets one server index several repos with distinct tokens without
+// writing them to disk.
+func (c Credentials) ForProject(project string) Credentials {
+	if project == "" {
+		return c
+	}
+	for key, field := range map[string]*string{
+		"GITHUB_TOKEN":  &c.GitHubToken,
+		"JIRA_TOKEN":    &c.JiraToken,
+		"JIRA_EMAIL":    &c.JiraEmail,
+		"JIRA_BASE_URL": &c.JiraBaseURL,
+		"LINEAR_TOKEN":  &c.LinearToken,
+		"NOTION_TOKEN":  &c.NotionToken,
+		"SLACK_TOKEN":   &c.SlackToken,
+	} {
+		if v := os.Getenv(projectEnvKey(project, key)); v != "" {
+			*field = v
+		}
+	}
+	c.Project = project
+	return c
 }
 
 // BuildRegistry creates a fully configured source registry by combining:
 // 1. Auto-detected sources (git is always registered)
 // 2. YAML-configured sources
-// 3. Credentials from the app config
+// 3. Credentials from the app config, overridden per project from the
+// environment when creds.Project is set
 func BuildRegistry(rootPath string, yamlCfg *SourcesYAML, creds Credentials) *Registry {
 	reg := NewRegistry()
+	creds = creds.ForProject(creds.Project)
 
 	// Always register git (module-scoped, auto-detected).
 	reg.Register(NewGitSource(rootPath))
@@ -168,8 +238,22 @@ func BuildRegistry(rootPath string, yamlCfg *SourcesYAML, creds Credentials) *Re
 		return reg
 	}
 
-	// Configure sources from YAML.
-	for name, entry := range yamlCfg.Sources {
+	if v := yamlCfg.Fetch.Timeout; v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			reg.SetDefaultTimeout(d)
+		}
+	}
+	reg.SetMaxConcurrent(yamlCfg.Fetch.MaxConcurrent)
+
+	// Configure sources from YAML, in name order so registration (and
+	// anything derived from it) is the same on every run.
+	names := make([]string, 0, len(yamlCfg.Sources))
+	for name := range yamlCfg.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entry := yamlCfg.Sources[name]
 		src := createSourceByName(name)
 		if src == nil {
 			continue
@@ -194,15 +278,26 @@ func BuildRegistry(rootPath string, yamlCfg *SourcesYAML, creds Credentials) *Re
 		mapYAMLKeys(name, cfg.Settings)
 
 		if err := src.Configure(cfg); err != nil {
-			// Skip misconfigured sources.
+			// Skip misconfigured sources, but remember why for ValidateAll.
+			reg.configErrors[name] = err
 			continue
 		}
 		reg.Register(src)
+
+		// Optional per-source fetch timeout, e.g. "timeout: 30s".
+		if v := entry.Settings["timeout"]; v != "" {
+			if d, err := time.ParseDuration(v); err == nil {
+				reg.SetTimeout(src.Name(), d)
+			}
+		}
 	}
 
 	return reg
 }
 
+// ConfigurableTypes are the source names sources.yaml accepts, sorted.
+var ConfigurableTypes = []string{"adr", "github", "jira", "linear", "local-pdf", "notion", "slack", "web"}
+
 // createSourceByName returns a new unconfigured source for the given name.
 func createSourceByName(name string) Source {
 	switch name {
@@ -220,6 +315,8 @@ func createSourceByName(name string) Source {
 		return NewWebSource()
 	case "local-pdf":
 		return NewPDFSource()
+	case "adr":
+		return NewADRSource()
 	default:
 		return nil
 	}
@@ -315,6 +412,11 @@ func autoDetectSources(reg *Registry, rootPath string, creds Credentials) {
 		}
 	}
 
+	// Auto-detect Architecture Decision Records (docs/adr, doc/decisions, ...).
+	if hasADRDir(rootPath) {
+		reg.Register(NewADRSource())
+	}
+
 	// NOTE: Jira, Linear, Notion, Slack, and Web require project-specific
 	// settings (project_key, team_key, database_id, channel_id, urls) that
 	// cannot be auto-detected. Use .carto/sources.yaml to configure them.
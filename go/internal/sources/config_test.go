@@ -3,7 +3,9 @@ package sources
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestParseSourcesConfig(t *testing.T) {
@@ -153,6 +155,7 @@ sources:
 func TestSaveSourcesConfig(t *testing.T) {
 	dir := t.TempDir()
 	cfg := &SourcesYAML{
+		Fetch: FetchOptions{Timeout: "30s", MaxConcurrent: 3},
 		Sources: map[string]SourceEntry{
 			"github": {Settings: map[string]string{"owner": "test", "repo": "app"}},
 			"jira":   {Settings: map[string]string{"project": "PROJ"}},
@@ -171,6 +174,9 @@ func TestSaveSourcesConfig(t *testing.T) {
 	if loaded.Sources["jira"].Settings["project"] != "PROJ" {
 		t.Fatalf("expected project=PROJ")
 	}
+	if loaded.Fetch != cfg.Fetch {
+		t.Errorf("fetch options = %+v, want %+v", loaded.Fetch, cfg.Fetch)
+	}
 }
 
 func TestSaveSourcesConfig_CreatesDir(t *testing.T) {
@@ -379,3 +385,80 @@ func TestAutoDetectSources(t *testing.T) {
 		t.Errorf("expected local-pdf in auto-detected sources (docs/ exists), got %v", names3)
 	}
 }
+
+func TestBuildRegistry_ProjectEnvCredentials(t *testing.T) {
+	t.Setenv("CARTO_PROJ_PAYMENTS_API_GITHUB_TOKEN", "payments-token")
+
+	githubToken := func(creds Credentials) string {
+		creds.GitHubOwner, creds.GitHubRepo = "acme", "payments"
+		reg := BuildRegistry(t.TempDir(), nil, creds)
+		for _, s := range reg.sources {
+			if gh, ok := s.(*GitHubSource); ok {
+				return gh.token
+			}
+		}
+		t.Fatal("github source not registered")
+		return ""
+	}
+
+	global := Credentials{GitHubToken: "global-token", SlackToken: "global-slack"}
+	if got := githubToken(global); got != "global-token" {
+		t.Errorf("without project: token = %q, want global-token", got)
+	}
+
+	global.Project = "payments-api"
+	if got := githubToken(global); got != "payments-token" {
+		t.Errorf("with project: token = %q, want payments-token", got)
+	}
+
+	// Unset project variables keep the global value.
+	if got := global.ForProject("payments-api").SlackToken; got != "global-slack" {
+		t.Errorf("SlackToken = %q, want global-slack", got)
+	}
+	if got := global.ForProject("billing").GitHubToken; got != "global-token" {
+		t.Errorf("other project: GitHubToken = %q, want global-token", got)
+	}
+}
+
+func TestBuildRegistry_YAMLSourcesInNameOrder(t *testing.T) {
+	root := t.TempDir()
+	yamlCfg := &SourcesYAML{Sources: map[string]SourceEntry{
+		"web":       {Settings: map[string]string{"urls": "https://example.com"}},
+		"local-pdf": {Settings: map[string]string{"dir": root}},
+		"adr":       {},
+	}}
+
+	want := []string{"git", "adr", "local-pdf", "web"}
+	for i := 0; i < 5; i++ {
+		got := BuildRegistry(root, yamlCfg, Credentials{}).SourceNames()
+		if !slices.Equal(got, want) {
+			t.Fatalf("SourceNames() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBuildRegistry_FetchOptions(t *testing.T) {
+	yamlCfg, err := ParseSourcesConfig([]byte(`
+fetch:
+  timeout: 15s
+  max_concurrent: 2
+sources:
+  web:
+    urls: https://example.com
+    timeout: 5s
+`))
+	if err != nil {
+		t.Fatalf("ParseSourcesConfig: %v", err)
+	}
+
+	reg := BuildRegistry(t.TempDir(), yamlCfg, Credentials{})
+	if reg.defaultTimeout != 15*time.Second {
+		t.Errorf("defaultTimeout = %v, want 15s", reg.defaultTimeout)
+	}
+	if reg.maxConcurrent != 2 {
+		t.Errorf("maxConcurrent = %d, want 2", reg.maxConcurrent)
+	}
+	if got := reg.timeouts["web"]; got != 5*time.Second {
+		t.Errorf("web timeout = %v, want 5s", got)
+	}
+}
@@ -25,6 +25,7 @@ func NewGitSource(repoRoot string) *GitSource {
 
 func (g *GitSource) Name() string { return "git" }
 func (g *GitSource) Scope() Scope { return ModuleScope }
+func (g *GitSource) Local() bool  { return true }
 
 func (g *GitSource) Configure(cfg SourceConfig) error {
 	if root, ok := cfg.Settings["repo_root"]; ok {
@@ -60,6 +60,24 @@ func (g *GitHubSource) Fetch(ctx context.Context, req FetchRequest) ([]Artifact,
 	return artifacts, nil
 }
 
+// Validate checks the token against /user (when one is set) and that the
+// configured repository is reachable.
+func (g *GitHubSource) Validate(ctx context.Context) error {
+	if g.token != "" {
+		var user ghUser
+		if err := g.apiGet(ctx, "/user", &user); err != nil {
+			return fmt.Errorf("github: token check: %w", err)
+		}
+	}
+	var repo struct {
+		FullName string `json:"full_name"`
+	}
+	if err := g.apiGet(ctx, fmt.Sprintf("/repos/%s/%s", g.owner, g.repo), &repo); err != nil {
+		return fmt.Errorf("github: repo %s/%s: %w", g.owner, g.repo, err)
+	}
+	return nil
+}
+
 type ghIssue struct {
 	Number      int           `json:"number"`
 	Title       string        `json:"title"`
@@ -110,3 +110,38 @@ func TestGitHubSource_Fetch(t *testing.T) {
 }
 
 var _ Source = (*GitHubSource)(nil)
+
+func TestGitHubSource_Validate(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good" {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"login": "octocat"})
+	})
+	mux.HandleFunc("/repos/user/repo", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"full_name": "user/repo"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		token   string
+		wantErr bool
+	}{
+		{token: "good"},
+		{token: "bad", wantErr: true},
+	} {
+		src := NewGitHubSource()
+		src.baseURL = srv.URL
+		src.Configure(SourceConfig{
+			Settings:    map[string]string{"owner": "user", "repo": "repo"},
+			Credentials: map[string]string{"github_token": tc.token},
+		})
+		err := src.Validate(context.Background())
+		if (err != nil) != tc.wantErr {
+			t.Errorf("token %q: Validate() error = %v, wantErr %v", tc.token, err, tc.wantErr)
+		}
+	}
+}
@@ -62,6 +62,15 @@ func (j *JiraSource) Fetch(ctx context.Context, req FetchRequest) ([]Artifact, e
 	return issues, nil
 }
 
+// Validate checks the credentials against /rest/api/3/myself.
+func (j *JiraSource) Validate(ctx context.Context) error {
+	var me jiraUser
+	if err := j.apiGet(ctx, "/rest/api/3/myself", &me); err != nil {
+		return fmt.Errorf("jira: credentials check: %w", err)
+	}
+	return nil
+}
+
 // jiraSearchResponse is the top-level response from /rest/api/3/search.
 type jiraSearchResponse struct {
 	Issues []jiraIssue `json:"issues"`
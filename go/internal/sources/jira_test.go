@@ -194,3 +194,36 @@ func TestJiraSource_Fetch(t *testing.T) {
 		t.Errorf("second issue Tags[type] = %q, want %q", b.Tags["type"], "Story")
 	}
 }
+
+func TestJiraSource_Validate(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/3/myself", func(w http.ResponseWriter, r *http.Request) {
+		if _, pass, ok := r.BasicAuth(); !ok || pass != "good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"accountId": "abc"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		token   string
+		wantErr bool
+	}{
+		{token: "good"},
+		{token: "bad", wantErr: true},
+	} {
+		src := NewJiraSource()
+		if err := src.Configure(SourceConfig{
+			Settings:    map[string]string{"base_url": srv.URL, "project_key": "PROJ"},
+			Credentials: map[string]string{"jira_email": "alice@example.com", "jira_token": tc.token},
+		}); err != nil {
+			t.Fatalf("Configure: %v", err)
+		}
+		err := src.Validate(context.Background())
+		if (err != nil) != tc.wantErr {
+			t.Errorf("token %q: Validate() error = %v, wantErr %v", tc.token, err, tc.wantErr)
+		}
+	}
+}
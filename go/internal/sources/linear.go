@@ -120,6 +120,44 @@ func (l *LinearSource) Fetch(ctx context.Context, req FetchRequest) ([]Artifact,
 	return artifacts, nil
 }
 
+// Validate checks the token by querying the authenticated viewer.
+func (l *LinearSource) Validate(ctx context.Context) error {
+	body, err := json.Marshal(map[string]string{"query": "{ viewer { id } }"})
+	if err != nil {
+		return fmt.Errorf("linear: marshal query: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("linear: build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if l.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+l.token)
+	}
+
+	resp, err := l.http.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("linear: request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("linear: API returned %d", resp.StatusCode)
+	}
+
+	var gql struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&gql); err != nil {
+		return fmt.Errorf("linear: decode response: %w", err)
+	}
+	if len(gql.Errors) > 0 {
+		return fmt.Errorf("linear: %s", gql.Errors[0].Message)
+	}
+	return nil
+}
+
 // collectLabelNames joins label names into a comma-separated string.
 func collectLabelNames(labels []linearLabel) string {
 	names := make([]string, 0, len(labels))
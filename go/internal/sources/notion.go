@@ -44,6 +44,17 @@ func (n *NotionSource) Configure(cfg SourceConfig) error {
 	return nil
 }
 
+// Validate checks the integration token against /users/me.
+func (n *NotionSource) Validate(ctx context.Context) error {
+	var me struct {
+		ID string `json:"id"`
+	}
+	if err := n.notionRequest(ctx, "GET", "/users/me", nil, &me); err != nil {
+		return fmt.Errorf("notion: token check: %w", err)
+	}
+	return nil
+}
+
 func (n *NotionSource) Fetch(ctx context.Context, req FetchRequest) ([]Artifact, error) {
 	pages, err := n.queryDatabase(ctx)
 	if err != nil {
@@ -3,41 +3,78 @@ package sources
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode"
 
 	pdflib "github.com/ledongthuc/pdf"
 )
 
+// defaultMinCharsPerPage is the average number of non-whitespace characters
+// per page below which a PDF is treated as image-only (scanned).
+const defaultMinCharsPerPage = 20
+
 // PDFSource reads PDF files from a configured directory.
 type PDFSource struct {
-	dir string
+	dir             string
+	maxPages        int      // 0 = all pages
+	minCharsPerPage int      // below this average a PDF is considered image-only
+	ocrCommand      []string // optional: command + args; the PDF path is appended
+
+	diagnostics []string
 }
 
 // NewPDFSource creates a PDF knowledge source.
 func NewPDFSource() *PDFSource {
-	return &PDFSource{}
+	return &PDFSource{minCharsPerPage: defaultMinCharsPerPage}
 }
 
 func (p *PDFSource) Name() string { return "local-pdf" }
 func (p *PDFSource) Scope() Scope { return ProjectScope }
 
+// Configure reads the "dir" setting plus optional "max_pages",
+// "min_chars_per_page" and "ocr_command". The OCR command receives the PDF
+// path as its last argument and must print the recognised text to stdout.
 func (p *PDFSource) Configure(cfg SourceConfig) error {
 	dir := cfg.Settings["dir"]
 	if dir == "" {
 		return fmt.Errorf("local-pdf: 'dir' setting is required")
 	}
 	p.dir = dir
+
+	if v := cfg.Settings["max_pages"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("local-pdf: invalid max_pages %q", v)
+		}
+		p.maxPages = n
+	}
+	if v := cfg.Settings["min_chars_per_page"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("local-pdf: invalid min_chars_per_page %q", v)
+		}
+		p.minCharsPerPage = n
+	}
+	p.ocrCommand = strings.Fields(cfg.Settings["ocr_command"])
 	return nil
 }
 
-func (p *PDFSource) Fetch(_ context.Context, _ FetchRequest) ([]Artifact, error) {
+// Diagnostics returns the problems noted during the last Fetch, such as
+// PDFs that could not be read or appear to contain only scanned images.
+func (p *PDFSource) Diagnostics() []string { return p.diagnostics }
+
+func (p *PDFSource) Fetch(ctx context.Context, _ FetchRequest) ([]Artifact, error) {
 	entries, err := os.ReadDir(p.dir)
 	if err != nil {
 		return nil, fmt.Errorf("local-pdf: read dir: %w", err)
 	}
 
+	p.diagnostics = nil
 	var artifacts []Artifact
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), ".pdf") {
@@ -45,12 +82,29 @@ func (p *PDFSource) Fetch(_ context.Context, _ FetchRequest) ([]Artifact, error)
 		}
 
 		absPath := filepath.Join(p.dir, entry.Name())
-		text, err := extractPDFText(absPath)
+		text, pages, err := extractPDFText(absPath, p.maxPages)
 		if err != nil {
+			p.diagnose(entry.Name(), "extract text: %v", err)
 			continue
 		}
-		if strings.TrimSpace(text) == "" {
-			continue
+
+		tags := map[string]string{"format": "pdf"}
+		if p.imageOnly(text, pages) {
+			if len(p.ocrCommand) == 0 {
+				p.diagnose(entry.Name(), "PDF appears image-only; OCR not available")
+				continue
+			}
+			ocrText, err := p.runOCR(ctx, absPath)
+			if err != nil {
+				p.diagnose(entry.Name(), "PDF appears image-only; OCR failed: %v", err)
+				continue
+			}
+			if p.imageOnly(ocrText, pages) {
+				p.diagnose(entry.Name(), "PDF appears image-only; OCR produced no usable text")
+				continue
+			}
+			text = ocrText
+			tags["ocr"] = "true"
 		}
 
 		title := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
@@ -61,21 +115,61 @@ func (p *PDFSource) Fetch(_ context.Context, _ FetchRequest) ([]Artifact, error)
 			Title:    title,
 			Body:     text,
 			URL:      "file://" + absPath,
-			Tags:     map[string]string{"format": "pdf"},
+			Tags:     tags,
 		})
 	}
 	return artifacts, nil
 }
 
-func extractPDFText(path string) (string, error) {
-	f, reader, err := pdflib.Open(path)
+// imageOnly reports whether text is too sparse for the number of pages read,
+// which usually means the PDF is a scan without a text layer.
+func (p *PDFSource) imageOnly(text string, pages int) bool {
+	chars := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			chars++
+		}
+	}
+	if chars == 0 {
+		return true
+	}
+	if pages < 1 {
+		pages = 1
+	}
+	return chars/pages < p.minCharsPerPage
+}
+
+func (p *PDFSource) runOCR(ctx context.Context, path string) (string, error) {
+	args := append(append([]string{}, p.ocrCommand[1:]...), path)
+	out, err := exec.CommandContext(ctx, p.ocrCommand[0], args...).Output()
 	if err != nil {
 		return "", err
 	}
+	return string(out), nil
+}
+
+func (p *PDFSource) diagnose(file, format string, args ...any) {
+	msg := file + ": " + fmt.Sprintf(format, args...)
+	p.diagnostics = append(p.diagnostics, msg)
+	log.Printf("local-pdf: %s", msg)
+}
+
+// extractPDFText returns the plain text of up to maxPages pages (0 = all)
+// and the number of pages it read.
+func extractPDFText(path string, maxPages int) (string, int, error) {
+	f, reader, err := pdflib.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
 	defer f.Close()
 
+	n := reader.NumPage()
+	if maxPages > 0 && n > maxPages {
+		n = maxPages
+	}
+
 	var sb strings.Builder
-	for i := 1; i <= reader.NumPage(); i++ {
+	for i := 1; i <= n; i++ {
 		page := reader.Page(i)
 		if page.V.IsNull() {
 			continue
@@ -87,5 +181,5 @@ func extractPDFText(path string) (string, error) {
 		sb.WriteString(text)
 		sb.WriteString("\n\n")
 	}
-	return sb.String(), nil
+	return sb.String(), n, nil
 }
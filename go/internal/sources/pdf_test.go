@@ -1,9 +1,12 @@
 package sources
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -71,4 +74,126 @@ func TestPDFSource_ArtifactCategory(t *testing.T) {
 	}
 }
 
+// writeTestPDF writes a minimal single-font PDF with one page per entry in
+// pages. An empty entry produces a page with no text layer, like a scan.
+func writeTestPDF(t *testing.T, path string, pages ...string) {
+	t.Helper()
+	var objs []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objs = append(objs,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	for i, text := range pages {
+		content := ""
+		if text != "" {
+			content = fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		}
+		objs = append(objs,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for i, obj := range objs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPDFSource_Fetch_TextPDF(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPDF(t, filepath.Join(dir, "design.pdf"),
+		"Carto indexes codebases into layered context",
+		"Second page about the storage layer")
+
+	src := NewPDFSource()
+	if err := src.Configure(SourceConfig{Settings: map[string]string{"dir": dir, "max_pages": "1"}}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	artifacts, err := src.Fetch(context.Background(), FetchRequest{Project: "test"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(artifacts) != 1 {
+		t.Fatalf("expected 1 artifact, got %d (diagnostics: %v)", len(artifacts), src.Diagnostics())
+	}
+	body := artifacts[0].Body
+	if !strings.Contains(body, "Carto indexes codebases") {
+		t.Errorf("body missing page 1 text: %q", body)
+	}
+	if strings.Contains(body, "storage layer") {
+		t.Errorf("max_pages=1 should skip page 2: %q", body)
+	}
+	if len(src.Diagnostics()) != 0 {
+		t.Errorf("unexpected diagnostics: %v", src.Diagnostics())
+	}
+}
+
+func TestPDFSource_Fetch_ImageOnlyPDF(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPDF(t, filepath.Join(dir, "scan.pdf"), "", "")
+
+	src := NewPDFSource()
+	src.Configure(SourceConfig{Settings: map[string]string{"dir": dir}})
+	artifacts, err := src.Fetch(context.Background(), FetchRequest{Project: "test"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(artifacts) != 0 {
+		t.Errorf("expected no artifact for image-only PDF, got %d", len(artifacts))
+	}
+	diags := src.Diagnostics()
+	if len(diags) != 1 || !strings.Contains(diags[0], "scan.pdf: PDF appears image-only; OCR not available") {
+		t.Errorf("diagnostics = %v", diags)
+	}
+}
+
+func TestPDFSource_Fetch_OCRFallback(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPDF(t, filepath.Join(dir, "scan.pdf"), "")
+	ocr := filepath.Join(t.TempDir(), "fake-ocr")
+	os.WriteFile(ocr, []byte("#!/bin/sh\necho 'Recognised text from a scanned architecture diagram'\n"), 0o755)
+
+	src := NewPDFSource()
+	src.Configure(SourceConfig{Settings: map[string]string{"dir": dir, "ocr_command": ocr}})
+	artifacts, err := src.Fetch(context.Background(), FetchRequest{Project: "test"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(artifacts) != 1 {
+		t.Fatalf("expected 1 OCR artifact, got %d (diagnostics: %v)", len(artifacts), src.Diagnostics())
+	}
+	if !strings.Contains(artifacts[0].Body, "Recognised text") || artifacts[0].Tags["ocr"] != "true" {
+		t.Errorf("artifact = %+v", artifacts[0])
+	}
+}
+
+func TestPDFSource_Configure_InvalidSettings(t *testing.T) {
+	for _, key := range []string{"max_pages", "min_chars_per_page"} {
+		src := NewPDFSource()
+		err := src.Configure(SourceConfig{Settings: map[string]string{"dir": "docs", key: "many"}})
+		if err == nil {
+			t.Errorf("expected error for %s=many", key)
+		}
+	}
+}
+
 var _ Source = (*PDFSource)(nil)
@@ -3,17 +3,40 @@ package sources
 import (
 	"context"
 	"log"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
+	"time"
+)
+
+const (
+	// DefaultFetchTimeout bounds how long a single source may take to fetch
+	// before its results are abandoned.
+	DefaultFetchTimeout = 60 * time.Second
+
+	// DefaultMaxConcurrentFetches is how many sources are fetched at once.
+	DefaultMaxConcurrentFetches = 4
 )
 
 // Registry holds all configured sources and dispatches fetch calls.
 type Registry struct {
-	sources []Source
+	sources        []Source
+	defaultTimeout time.Duration
+	timeouts       map[string]time.Duration // per-source overrides by name
+	maxConcurrent  int
+	configErrors   map[string]error // sources skipped by BuildRegistry, by name
+	cache          *Cache           // nil fetches every source on every call
 }
 
 // NewRegistry creates an empty source registry.
 func NewRegistry() *Registry {
-	return &Registry{}
+	return &Registry{
+		defaultTimeout: DefaultFetchTimeout,
+		timeouts:       make(map[string]time.Duration),
+		maxConcurrent:  DefaultMaxConcurrentFetches,
+		configErrors:   make(map[string]error),
+	}
 }
 
 // Register adds a source to the registry.
@@ -21,6 +44,30 @@ func (r *Registry) Register(s Source) {
 	r.sources = append(r.sources, s)
 }
 
+// SetDefaultTimeout sets the fetch timeout for sources without an override.
+// Zero or negative disables the timeout.
+func (r *Registry) SetDefaultTimeout(d time.Duration) {
+	r.defaultTimeout = d
+}
+
+// SetTimeout overrides the fetch timeout for the named source.
+func (r *Registry) SetTimeout(name string, d time.Duration) {
+	r.timeouts[name] = d
+}
+
+// SetMaxConcurrent sets how many sources are fetched in parallel.
+func (r *Registry) SetMaxConcurrent(n int) {
+	if n > 0 {
+		r.maxConcurrent = n
+	}
+}
+
+// SetCache makes fetches reuse the artifacts c holds for a source, and
+// cache what they fetch, except for Local sources. Nil turns caching off.
+func (r *Registry) SetCache(c *Cache) {
+	r.cache = c
+}
+
 // SourceNames returns the names of all registered sources.
 func (r *Registry) SourceNames() []string {
 	names := make([]string, len(r.sources))
@@ -30,69 +77,186 @@ func (r *Registry) SourceNames() []string {
 	return names
 }
 
+// Sources returns the registered sources in registration order.
+func (r *Registry) Sources() []Source {
+	return slices.Clone(r.sources)
+}
+
+// Skipped returns, by name, the sources BuildRegistry left out because
+// they failed to configure, with the reason.
+func (r *Registry) Skipped() map[string]error {
+	return maps.Clone(r.configErrors)
+}
+
 // FetchAllProject fetches artifacts from all ProjectScope sources concurrently.
 // Individual source errors are logged but do not prevent other sources from running.
 func (r *Registry) FetchAllProject(ctx context.Context, req FetchRequest) ([]Artifact, error) {
-	var projectSources []Source
+	return r.fetchScope(ctx, ProjectScope, req), nil
+}
+
+// FetchModule fetches artifacts from all ModuleScope sources concurrently.
+// Only module-scoped sources (e.g. git) are invoked.
+func (r *Registry) FetchModule(ctx context.Context, req FetchRequest) ([]Artifact, error) {
+	return r.fetchScope(ctx, ModuleScope, req), nil
+}
+
+// fetchScope fetches from every source of the given scope through a pool of
+// maxConcurrent workers, each fetch bounded by its source's timeout. Failed
+// or timed-out sources are logged and skipped. The merged slice is sorted by
+// source name, then newest first, so results are stable across runs
+// regardless of which source finished first.
+func (r *Registry) fetchScope(ctx context.Context, scope Scope, req FetchRequest) []Artifact {
+	var scoped []Source
 	for _, s := range r.sources {
-		if s.Scope() == ProjectScope {
-			projectSources = append(projectSources, s)
+		if s.Scope() == scope {
+			scoped = append(scoped, s)
 		}
 	}
-
-	if len(projectSources) == 0 {
-		return nil, nil
+	if len(scoped) == 0 {
+		return nil
 	}
 
-	type result struct {
-		artifacts []Artifact
-		err       error
-		name      string
+	workers := r.maxConcurrent
+	if workers <= 0 {
+		workers = DefaultMaxConcurrentFetches
 	}
+	sem := make(chan struct{}, workers)
 
-	results := make(chan result, len(projectSources))
-	var wg sync.WaitGroup
-
-	for _, s := range projectSources {
+	var (
+		mu  sync.Mutex
+		all []Artifact
+		wg  sync.WaitGroup
+	)
+	for _, s := range scoped {
 		wg.Add(1)
 		go func(src Source) {
 			defer wg.Done()
-			arts, err := src.Fetch(ctx, req)
-			results <- result{artifacts: arts, err: err, name: src.Name()}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			arts, err := r.fetchCached(ctx, src, req)
+			if err != nil {
+				if req.Module != "" {
+					log.Printf("sources: warning: %s failed for module %s: %v", src.Name(), req.Module, err)
+				} else {
+					log.Printf("sources: warning: %s failed: %v", src.Name(), err)
+				}
+				return
+			}
+			mu.Lock()
+			all = append(all, arts...)
+			mu.Unlock()
 		}(s)
 	}
+	wg.Wait()
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	var all []Artifact
-	for res := range results {
-		if res.err != nil {
-			log.Printf("sources: warning: %s failed: %v", res.name, res.err)
-			continue
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Source != all[j].Source {
+			return all[i].Source < all[j].Source
 		}
-		all = append(all, res.artifacts...)
+		return all[i].Date.After(all[j].Date)
+	})
+	return all
+}
+
+// fetchCached returns src's cached artifacts for req.Module when the
+// registry has a fresh cache entry, and otherwise fetches them and caches
+// the result. Failed fetches are not cached.
+func (r *Registry) fetchCached(ctx context.Context, src Source, req FetchRequest) ([]Artifact, error) {
+	if r.cache == nil || isLocal(src) {
+		return r.fetchWithTimeout(ctx, src, req)
+	}
+	if arts, ok := r.cache.get(src.Name(), req.Module); ok {
+		return arts, nil
+	}
+	arts, err := r.fetchWithTimeout(ctx, src, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.cache.put(src.Name(), req.Module, arts); err != nil {
+		log.Printf("sources: warning: cache %s: %v", src.Name(), err)
 	}
+	return arts, nil
+}
 
-	return all, nil
+// fetchWithTimeout runs src.Fetch under the source's timeout. Sources are
+// required to honour ctx, so Fetch is waited on rather than abandoned at the
+// deadline; no goroutine outlives the fetch.
+func (r *Registry) fetchWithTimeout(ctx context.Context, src Source, req FetchRequest) ([]Artifact, error) {
+	ctx, cancel := r.withTimeout(ctx, src)
+	defer cancel()
+	arts, err := src.Fetch(ctx, req)
+	if err == nil && ctx.Err() != nil {
+		// Results finished after the deadline are dropped like a failure,
+		// so a timeout means the same thing for every source.
+		return nil, ctx.Err()
+	}
+	return arts, err
 }
 
-// FetchModule fetches artifacts from all ModuleScope sources.
-// Only module-scoped sources (e.g. git) are invoked.
-func (r *Registry) FetchModule(ctx context.Context, req FetchRequest) ([]Artifact, error) {
-	var all []Artifact
-	for _, s := range r.sources {
-		if s.Scope() != ModuleScope {
-			continue
-		}
-		arts, err := s.Fetch(ctx, req)
-		if err != nil {
-			log.Printf("sources: warning: %s failed for module %s: %v", s.Name(), req.Module, err)
-			continue
-		}
-		all = append(all, arts...)
+// withTimeout derives a context bounded by src's fetch timeout: its own
+// override if set, else the registry default. Zero or negative means no
+// timeout.
+func (r *Registry) withTimeout(ctx context.Context, src Source) (context.Context, context.CancelFunc) {
+	timeout := r.defaultTimeout
+	if d, ok := r.timeouts[src.Name()]; ok {
+		timeout = d
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
-	return all, nil
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ValidationResult is the outcome of checking one source.
+type ValidationResult struct {
+	Source string `json:"source"`
+	OK     bool   `json:"ok"`
+	Probed bool   `json:"probed"` // false when the source has no connectivity probe
+	Error  string `json:"error,omitempty"`
+}
+
+// ValidateAll probes every registered source through the same worker pool
+// and per-source timeouts as fetching. Sources that BuildRegistry skipped
+// because they failed to configure are reported as failures too. Results
+// are sorted by source name.
+func (r *Registry) ValidateAll(ctx context.Context) []ValidationResult {
+	results := make([]ValidationResult, len(r.sources))
+
+	workers := r.maxConcurrent
+	if workers <= 0 {
+		workers = DefaultMaxConcurrentFetches
+	}
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for i, s := range r.sources {
+		wg.Add(1)
+		go func(i int, src Source) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			probed, err := r.validateWithTimeout(ctx, src)
+			res := ValidationResult{Source: src.Name(), OK: err == nil, Probed: probed}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			results[i] = res
+		}(i, s)
+	}
+	wg.Wait()
+
+	for name, err := range r.configErrors {
+		results = append(results, ValidationResult{Source: name, Error: "configure: " + err.Error()})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Source < results[j].Source })
+	return results
+}
+
+// validateWithTimeout runs Validate under the source's fetch timeout.
+func (r *Registry) validateWithTimeout(ctx context.Context, src Source) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx, src)
+	defer cancel()
+	return Validate(ctx, src)
 }
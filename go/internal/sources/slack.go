@@ -70,6 +70,22 @@ func (s *SlackSource) Fetch(ctx context.Context, req FetchRequest) ([]Artifact,
 	return artifacts, nil
 }
 
+// Validate checks the token with auth.test. Slack reports auth failures
+// with HTTP 200 and ok=false, so the body is inspected.
+func (s *SlackSource) Validate(ctx context.Context) error {
+	var resp struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := s.slackGet(ctx, "/auth.test", &resp); err != nil {
+		return fmt.Errorf("slack: auth.test: %w", err)
+	}
+	if !resp.OK {
+		return fmt.Errorf("slack: auth.test: %s", resp.Error)
+	}
+	return nil
+}
+
 // --- Slack API types ---
 
 type slackMessage struct {
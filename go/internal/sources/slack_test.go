@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -195,3 +196,41 @@ func contains(s, sub string) bool {
 	}
 	return false
 }
+
+func TestSlackSource_Validate(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth.test", func(w http.ResponseWriter, r *http.Request) {
+		// Slack reports bad tokens with HTTP 200 and ok=false.
+		if r.Header.Get("Authorization") != "Bearer xoxb-good" {
+			json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": "invalid_auth"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"ok": true})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		token   string
+		wantErr string
+	}{
+		{token: "xoxb-good"},
+		{token: "xoxb-bad", wantErr: "invalid_auth"},
+	} {
+		src := NewSlackSource()
+		src.baseURL = srv.URL
+		if err := src.Configure(SourceConfig{
+			Settings:    map[string]string{"channel_id": "C12345"},
+			Credentials: map[string]string{"slack_token": tc.token},
+		}); err != nil {
+			t.Fatalf("Configure: %v", err)
+		}
+		err := src.Validate(context.Background())
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("token %q: unexpected error %v", tc.token, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("token %q: error = %v, want containing %q", tc.token, err, tc.wantErr)
+		}
+	}
+}
@@ -56,9 +56,30 @@ type SourceConfig struct {
 }
 
 // Source is the unified interface for all external integrations.
+//
+// Fetch must honour ctx: the registry cancels it when the source's fetch
+// timeout passes and waits for Fetch to return, so a source that ignores
+// cancellation stalls its fetch until it finishes.
 type Source interface {
 	Name() string
 	Scope() Scope
 	Configure(cfg SourceConfig) error
 	Fetch(ctx context.Context, req FetchRequest) ([]Artifact, error)
 }
+
+// Validator is optionally implemented by sources that can cheaply check
+// their credentials and connectivity (e.g. GitHub /user, Slack auth.test)
+// without fetching any data. Like Fetch, Validate must honour ctx.
+type Validator interface {
+	Validate(ctx context.Context) error
+}
+
+// Validate probes src if it implements Validator. Sources without a probe
+// are treated as valid; probed reports whether a check actually ran.
+func Validate(ctx context.Context, src Source) (probed bool, err error) {
+	v, ok := src.(Validator)
+	if !ok {
+		return false, nil
+	}
+	return true, v.Validate(ctx)
+}
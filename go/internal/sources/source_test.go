@@ -3,6 +3,11 @@ package sources
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -198,3 +203,265 @@ func TestRegistry_Sources(t *testing.T) {
 		t.Fatalf("expected 2 sources, got %d", len(names))
 	}
 }
+
+// slowSource waits before returning, like a source stuck on a slow HTTP
+// call, and gives up early when its context is cancelled.
+type slowSource struct {
+	name      string
+	delay     time.Duration
+	artifacts []Artifact
+	returned  atomic.Bool // set once Fetch has returned
+}
+
+func (s *slowSource) Name() string                     { return s.name }
+func (s *slowSource) Scope() Scope                     { return ProjectScope }
+func (s *slowSource) Configure(cfg SourceConfig) error { return nil }
+func (s *slowSource) Fetch(ctx context.Context, req FetchRequest) ([]Artifact, error) {
+	defer s.returned.Store(true)
+	select {
+	case <-time.After(s.delay):
+		return s.artifacts, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestRegistry_FetchAllProject_RunsSourcesInParallel(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&slowSource{name: "slow", delay: 300 * time.Millisecond,
+		artifacts: []Artifact{{Source: "slow", ID: "s1"}}})
+	reg.Register(&slowSource{name: "fast", delay: 200 * time.Millisecond,
+		artifacts: []Artifact{{Source: "fast", ID: "f1"}}})
+
+	start := time.Now()
+	all, err := reg.FetchAllProject(context.Background(), FetchRequest{Project: "test"})
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("FetchAllProject: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 artifacts, got %d", len(all))
+	}
+	// Sequential would take ~500ms; parallel should be close to the slow source alone.
+	if elapsed >= 450*time.Millisecond {
+		t.Errorf("expected parallel fetch (~300ms), took %v", elapsed)
+	}
+	// Deterministic order: sorted by source name regardless of finish order.
+	if all[0].Source != "fast" || all[1].Source != "slow" {
+		t.Errorf("expected artifacts ordered fast, slow; got %s, %s", all[0].Source, all[1].Source)
+	}
+}
+
+func TestRegistry_FetchAllProject_TimesOutSlowSource(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetTimeout("stuck", 50*time.Millisecond)
+	stuck := &slowSource{name: "stuck", delay: 2 * time.Second,
+		artifacts: []Artifact{{Source: "stuck", ID: "x"}}}
+	reg.Register(stuck)
+	reg.Register(&mockSource{name: "ok", scope: ProjectScope,
+		artifacts: []Artifact{{Source: "ok", ID: "1"}}})
+
+	start := time.Now()
+	all, _ := reg.FetchAllProject(context.Background(), FetchRequest{Project: "test"})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timed-out source should be abandoned, took %v", elapsed)
+	}
+	if len(all) != 1 || all[0].Source != "ok" {
+		t.Errorf("expected only the ok source's artifact, got %+v", all)
+	}
+	// The fetch is cancelled and waited on, not left running in the background.
+	if !stuck.returned.Load() {
+		t.Error("stuck source's Fetch still running after FetchAllProject returned")
+	}
+}
+
+func TestRegistry_FetchAllProject_SortsBySourceThenNewestFirst(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Now()
+	reg := NewRegistry()
+	reg.Register(&mockSource{name: "jira", scope: ProjectScope, artifacts: []Artifact{
+		{Source: "jira", ID: "old", Date: now.Add(-2 * day)},
+		{Source: "jira", ID: "new", Date: now},
+	}})
+	reg.Register(&mockSource{name: "github", scope: ProjectScope, artifacts: []Artifact{
+		{Source: "github", ID: "g", Date: now.Add(-day)},
+	}})
+
+	all, _ := reg.FetchAllProject(context.Background(), FetchRequest{Project: "test"})
+	var ids []string
+	for _, a := range all {
+		ids = append(ids, a.ID)
+	}
+	if got := strings.Join(ids, ","); got != "g,new,old" {
+		t.Errorf("expected order g,new,old; got %s", got)
+	}
+}
+
+// countingSource is a mockSource that counts its fetches.
+type countingSource struct {
+	mockSource
+	mu      sync.Mutex
+	fetches int
+}
+
+func (c *countingSource) Fetch(ctx context.Context, req FetchRequest) ([]Artifact, error) {
+	c.mu.Lock()
+	c.fetches++
+	c.mu.Unlock()
+	return c.mockSource.Fetch(ctx, req)
+}
+
+// localSource is a countingSource that reads only the local checkout.
+type localSource struct{ countingSource }
+
+func (l *localSource) Local() bool { return true }
+
+func TestRegistry_CachesFetchesWithinTTL(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	jira := &countingSource{mockSource: mockSource{name: "jira", scope: ProjectScope,
+		artifacts: []Artifact{{Source: "jira", Category: Signal, ID: "PROJ-1", Title: "Ticket", Files: []string{"a.go"}}}}}
+	adr := &localSource{countingSource{mockSource: mockSource{name: "adr", scope: ProjectScope}}}
+
+	// run fetches with a fresh registry and cache, as each index run does.
+	run := func(refresh bool) []Artifact {
+		t.Helper()
+		reg := NewRegistry()
+		reg.Register(jira)
+		reg.Register(adr)
+		cache := NewCache(root, time.Hour, refresh)
+		cache.now = func() time.Time { return now }
+		reg.SetCache(cache)
+		all, err := reg.FetchAllProject(context.Background(), FetchRequest{Project: "test"})
+		if err != nil {
+			t.Fatalf("FetchAllProject: %v", err)
+		}
+		return all
+	}
+
+	run(false)
+	now = now.Add(30 * time.Minute)
+	second := run(false)
+	if jira.fetches != 1 {
+		t.Errorf("second run within the TTL fetched jira again: %d fetches", jira.fetches)
+	}
+	if len(second) != 1 || second[0].ID != "PROJ-1" || len(second[0].Files) != 1 {
+		t.Errorf("cached artifacts = %+v, want the fetched ticket", second)
+	}
+	if adr.fetches != 2 {
+		t.Errorf("local source fetched %d times, want every run", adr.fetches)
+	}
+
+	run(true)
+	if jira.fetches != 2 {
+		t.Errorf("refresh should fetch again: %d fetches", jira.fetches)
+	}
+
+	now = now.Add(time.Hour)
+	run(false)
+	if jira.fetches != 3 {
+		t.Errorf("run after the TTL should fetch again: %d fetches", jira.fetches)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".carto", "signalcache", "jira.json")); err != nil {
+		t.Errorf("cache entry not written: %v", err)
+	}
+}
+
+func TestRegistry_CacheSkipsFailedFetches(t *testing.T) {
+	root := t.TempDir()
+	flaky := &countingSource{mockSource: mockSource{name: "github", scope: ProjectScope, fetchErr: fmt.Errorf("rate limited")}}
+	for i := 0; i < 2; i++ {
+		reg := NewRegistry()
+		reg.Register(flaky)
+		reg.SetCache(NewCache(root, time.Hour, false))
+		reg.FetchAllProject(context.Background(), FetchRequest{Project: "test"})
+	}
+	if flaky.fetches != 2 {
+		t.Errorf("a failed fetch must not be cached: %d fetches, want 2", flaky.fetches)
+	}
+}
+
+// validatingSource is a mockSource that also implements Validator.
+type validatingSource struct {
+	mockSource
+	validateErr error
+}
+
+func (v *validatingSource) Validate(ctx context.Context) error { return v.validateErr }
+
+func TestRegistry_ValidateAll(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(&validatingSource{mockSource: mockSource{name: "slack"}, validateErr: fmt.Errorf("invalid_auth")})
+	reg.Register(&validatingSource{mockSource: mockSource{name: "github"}})
+	reg.Register(&mockSource{name: "adr"})
+	reg.configErrors["jira"] = fmt.Errorf("jira: base_url required")
+
+	results := reg.ValidateAll(context.Background())
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d: %+v", len(results), results)
+	}
+
+	want := []ValidationResult{
+		{Source: "adr", OK: true},
+		{Source: "github", OK: true, Probed: true},
+		{Source: "jira", Error: "configure: jira: base_url required"},
+		{Source: "slack", Probed: true, Error: "invalid_auth"},
+	}
+	for i, w := range want {
+		if results[i] != w {
+			t.Errorf("results[%d] = %+v, want %+v", i, results[i], w)
+		}
+	}
+}
+
+func TestRegistry_ValidateAll_TimesOutSlowProbe(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetTimeout("slow", 20*time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	reg.Register(&blockingValidator{mockSource: mockSource{name: "slow"}, release: release})
+
+	results := reg.ValidateAll(context.Background())
+	if len(results) != 1 || results[0].OK {
+		t.Fatalf("expected slow probe to fail, got %+v", results)
+	}
+	if !strings.Contains(results[0].Error, "deadline") {
+		t.Errorf("expected deadline error, got %q", results[0].Error)
+	}
+}
+
+// blockingValidator blocks until released or its context is cancelled.
+type blockingValidator struct {
+	mockSource
+	release chan struct{}
+}
+
+func (b *blockingValidator) Validate(ctx context.Context) error {
+	select {
+	case <-b.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestBuildRegistry_RecordsConfigureErrors(t *testing.T) {
+	yamlCfg := &SourcesYAML{Sources: map[string]SourceEntry{
+		"jira": {Settings: map[string]string{"project": "PROJ"}},
+	}}
+	reg := BuildRegistry(t.TempDir(), yamlCfg, Credentials{})
+
+	results := reg.ValidateAll(context.Background())
+	var jira *ValidationResult
+	for i := range results {
+		if results[i].Source == "jira" {
+			jira = &results[i]
+		}
+	}
+	if jira == nil {
+		t.Fatalf("expected a jira result, got %+v", results)
+	}
+	if jira.OK || !strings.HasPrefix(jira.Error, "configure: ") {
+		t.Errorf("jira result = %+v, want configure failure", *jira)
+	}
+}
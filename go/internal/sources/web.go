@@ -1,10 +1,14 @@
 package sources
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"regexp"
 	"strings"
@@ -80,20 +84,35 @@ func (w *WebSource) fetchURL(ctx context.Context, url string) (Artifact, error)
 		return Artifact{}, fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebFetchBytes))
 	if err != nil {
 		return Artifact{}, fmt.Errorf("read body: %w", err)
 	}
 
-	html := string(body)
-	title := extractTitle(html)
-	if title == "" {
-		title = url
-	}
-	text := stripHTML(html)
-	text = truncateBody(text, 5000)
-
 	contentType := resp.Header.Get("Content-Type")
+	kind := webContentKind(contentType, url, body)
+
+	title := url
+	var text string
+	switch kind {
+	case "webpage":
+		page := string(body)
+		if t := extractTitle(page); t != "" {
+			title = t
+		}
+		text = htmlToText(page)
+	case "json":
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, body, "", "  "); err != nil {
+			return Artifact{}, fmt.Errorf("invalid JSON: %w", err)
+		}
+		text = buf.String()
+	case "markdown", "text":
+		text = strings.TrimSpace(string(body))
+	default:
+		return Artifact{}, fmt.Errorf("unsupported content type %q", contentType)
+	}
+	text = truncateBody(text, maxWebBodyLen)
 
 	return Artifact{
 		Source:   "web",
@@ -104,10 +123,54 @@ func (w *WebSource) fetchURL(ctx context.Context, url string) (Artifact, error)
 		URL:      url,
 		Date:     time.Now(),
 		Author:   "",
-		Tags:     map[string]string{"type": "webpage", "content_type": contentType},
+		Tags:     map[string]string{"type": kind, "content_type": contentType},
 	}, nil
 }
 
+const (
+	maxWebFetchBytes = 4 << 20 // bytes read from a response
+	maxWebBodyLen    = 5000    // characters of extracted text kept
+)
+
+// webContentKind classifies a response as "webpage", "json", "markdown" or
+// "text" from its Content-Type header, falling back to the URL extension
+// and content sniffing when the header is missing or generic. Anything
+// else is returned as "binary".
+func webContentKind(contentType, url string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return "webpage"
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return "json"
+	case mediaType == "text/markdown" || mediaType == "text/x-markdown":
+		return "markdown"
+	case mediaType != "" && mediaType != "text/plain" && mediaType != "application/octet-stream":
+		if strings.HasPrefix(mediaType, "text/") {
+			return "text"
+		}
+		return "binary"
+	}
+
+	// No useful header: sniff.
+	path := strings.ToLower(strings.SplitN(strings.SplitN(url, "?", 2)[0], "#", 2)[0])
+	if strings.HasSuffix(path, ".md") || strings.HasSuffix(path, ".markdown") {
+		return "markdown"
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return "json"
+	}
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+	switch {
+	case sniffed == "text/html":
+		return "webpage"
+	case strings.HasPrefix(sniffed, "text/"):
+		return "text"
+	}
+	return "binary"
+}
+
 var reTitle = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
 
 // extractTitle pulls the content of the first <title> tag.
@@ -120,17 +183,34 @@ func extractTitle(html string) string {
 }
 
 var (
-	reScript = regexp.MustCompile(`(?is)<script[^>]*>.*?</script>`)
-	reStyle  = regexp.MustCompile(`(?is)<style[^>]*>.*?</style>`)
-	reTags   = regexp.MustCompile(`<[^>]+>`)
-	reSpaces = regexp.MustCompile(`\s+`)
+	reScript   = regexp.MustCompile(`(?is)<script[^>]*>.*?</script>`)
+	reStyle    = regexp.MustCompile(`(?is)<style[^>]*>.*?</style>`)
+	reNoscript = regexp.MustCompile(`(?is)<noscript[^>]*>.*?</noscript>`)
+	reHead     = regexp.MustCompile(`(?is)<head[^>]*>.*?</head>`)
+	reComment  = regexp.MustCompile(`(?s)<!--.*?-->`)
+	reBlock    = regexp.MustCompile(`(?i)<(?:br|/?(?:p|div|h[1-6]|li|ul|ol|tr|table|section|article|pre|blockquote|header|footer))\b[^>]*>`)
+	reTags     = regexp.MustCompile(`<[^>]+>`)
+	reBlanks   = regexp.MustCompile(`[ \t\r\f\v\x{00A0}]+`)
 )
 
-// stripHTML removes script/style blocks, HTML tags, and collapses whitespace.
-func stripHTML(html string) string {
-	s := reScript.ReplaceAllString(html, " ")
+// htmlToText extracts the readable text of an HTML page: the document head,
+// scripts, styles and comments are dropped, block elements become line
+// breaks, entities are decoded, and blank lines are removed.
+func htmlToText(page string) string {
+	s := reHead.ReplaceAllString(page, " ")
+	s = reScript.ReplaceAllString(s, " ")
 	s = reStyle.ReplaceAllString(s, " ")
+	s = reNoscript.ReplaceAllString(s, " ")
+	s = reComment.ReplaceAllString(s, " ")
+	s = reBlock.ReplaceAllString(s, "\n")
 	s = reTags.ReplaceAllString(s, " ")
-	s = reSpaces.ReplaceAllString(s, " ")
-	return strings.TrimSpace(s)
+	s = html.UnescapeString(s)
+
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(reBlanks.ReplaceAllString(line, " ")); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
 }
@@ -174,3 +174,73 @@ func TestWebSource_Fetch_SkipsBadURLs(t *testing.T) {
 		t.Errorf("Title = %q, want %q", artifacts[0].Title, "Good Page")
 	}
 }
+
+func TestWebSource_Fetch_ContentTypes(t *testing.T) {
+	const docsHTML = `<html><head><title>API Docs</title><meta name="x" content="head only"></head>
+<body><!-- nav --><nav><a href="/">Home</a></nav>
+<h1>Getting&nbsp;started</h1><p>Call <code>Serve()</code> &amp; wait.</p>
+<ul><li>fast</li><li>safe</li></ul><noscript>enable JS</noscript></body></html>`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(docsHTML))
+		case "/api.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"name":"carto","tags":["a","b"]}`))
+		case "/sniffed":
+			w.Header()["Content-Type"] = nil // suppress net/http's own sniffing
+			w.Write([]byte(`[{"id":1}]`))
+		case "/README.md":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("# Title\n\n<b>kept</b> as written\n"))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n"))
+		}
+	}))
+	defer srv.Close()
+
+	src := NewWebSource()
+	paths := []string{"/docs", "/api.json", "/sniffed", "/README.md", "/logo.png"}
+	var urls []string
+	for _, p := range paths {
+		urls = append(urls, srv.URL+p)
+	}
+	if err := src.Configure(SourceConfig{Settings: map[string]string{"urls": strings.Join(urls, ",")}}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	artifacts, err := src.Fetch(context.Background(), FetchRequest{Project: "test"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	byURL := map[string]Artifact{}
+	for _, a := range artifacts {
+		byURL[strings.TrimPrefix(a.URL, srv.URL)] = a
+	}
+	if _, ok := byURL["/logo.png"]; ok || len(artifacts) != 4 {
+		t.Fatalf("expected 4 artifacts with the image skipped, got %d", len(artifacts))
+	}
+
+	docs := byURL["/docs"]
+	if docs.Title != "API Docs" || docs.Tags["type"] != "webpage" {
+		t.Errorf("docs: title %q, type %q", docs.Title, docs.Tags["type"])
+	}
+	if want := "Home\nGetting started\nCall Serve() & wait.\nfast\nsafe"; docs.Body != want {
+		t.Errorf("docs body = %q, want %q", docs.Body, want)
+	}
+
+	api := byURL["/api.json"]
+	if want := "{\n  \"name\": \"carto\",\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ]\n}"; api.Body != want || api.Tags["type"] != "json" {
+		t.Errorf("json body = %q (type %q), want pretty-printed", api.Body, api.Tags["type"])
+	}
+	if sniffed := byURL["/sniffed"]; sniffed.Tags["type"] != "json" || !strings.Contains(sniffed.Body, "\"id\": 1") {
+		t.Errorf("sniffed = %+v, want JSON", sniffed)
+	}
+
+	md := byURL["/README.md"]
+	if md.Tags["type"] != "markdown" || md.Body != "# Title\n\n<b>kept</b> as written" {
+		t.Errorf("markdown = %q (type %q), want passed through", md.Body, md.Tags["type"])
+	}
+}
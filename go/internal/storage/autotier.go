@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TierAuto is not a tier of its own: SearchAuto starts at mini and moves
+// up to standard, then full, until the results are good enough.
+const TierAuto Tier = "auto"
+
+// Defaults for AutoOptions fields left at zero.
+const (
+	DefaultAutoMinScore   = 0.5
+	DefaultAutoMinResults = 3
+)
+
+// AutoOptions decides when SearchAuto settles on a tier's results.
+type AutoOptions struct {
+	MinScore   float64 // the top result must score at least this; 0 means DefaultAutoMinScore
+	MinResults int     // at least this many results must come back; 0 means DefaultAutoMinResults
+}
+
+// autoTiers is the order SearchAuto tries tiers in.
+var autoTiers = []Tier{TierMini, TierStandard, TierFull}
+
+// SearchTier searches the project's memories for text and returns the k
+// best results from layers tier retrieves, highest score first after the
+// Store's ranking weights. Each backend holding one of those layers is
+// searched once.
+func (s *Store) SearchTier(text string, tier Tier, k int) ([]SearchResult, error) {
+	layers, ok := tierLayers[tier]
+	if !ok {
+		return nil, fmt.Errorf("unknown tier: %s", tier)
+	}
+	if k <= 0 {
+		k = 10
+	}
+
+	var backends []MemoriesAPI
+	for _, layer := range layers {
+		if b := s.backends.For(layer); !containsBackend(backends, b) {
+			backends = append(backends, b)
+		}
+	}
+
+	// Request extra results so k remain after dropping other layers.
+	opts := SearchOptions{K: k * 3, Hybrid: true, SourcePrefix: s.projectPrefix()}
+	var matched []SearchResult
+	for _, b := range backends {
+		results, err := b.Search(text, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			project, _, layer, ok := ParseSourceTagIn(s.namespace, r.Source)
+			if ok && project == s.project && tier.Includes(layer) {
+				matched = append(matched, r)
+			}
+		}
+	}
+	matched = s.ranking.Apply(s.namespace, matched)
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].Score > matched[j].Score })
+	if len(matched) > k {
+		matched = matched[:k]
+	}
+	return matched, nil
+}
+
+// SearchAuto runs SearchTier at mini, standard and full in turn and
+// returns the first results whose top score and count meet opts, along
+// with the tier they came from. When no tier meets them, the full tier's
+// results are returned.
+func (s *Store) SearchAuto(text string, k int, opts AutoOptions) ([]SearchResult, Tier, error) {
+	if opts.MinScore <= 0 {
+		opts.MinScore = DefaultAutoMinScore
+	}
+	if opts.MinResults <= 0 {
+		opts.MinResults = DefaultAutoMinResults
+	}
+	if k > 0 && opts.MinResults > k {
+		opts.MinResults = k
+	}
+
+	var results []SearchResult
+	var tier Tier
+	for _, tier = range autoTiers {
+		var err error
+		results, err = s.SearchTier(text, tier, k)
+		if err != nil {
+			return nil, "", fmt.Errorf("search tier %s: %w", tier, err)
+		}
+		if len(results) >= opts.MinResults && results[0].Score >= opts.MinScore {
+			break
+		}
+	}
+	return results, tier, nil
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+// searchingMemories returns canned search results, filtered by source
+// prefix like the real API.
+type searchingMemories struct {
+	*mockMemories
+	hits     []SearchResult
+	searches int
+}
+
+func (m *searchingMemories) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	m.searches++
+	var out []SearchResult
+	for _, r := range m.hits {
+		if len(out) < opts.K && strings.HasPrefix(r.Source, opts.SourcePrefix) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func TestSearchAuto_EscalatesPastWeakMiniResults(t *testing.T) {
+	mem := &searchingMemories{mockMemories: newMockMemories(), hits: []SearchResult{
+		{ID: 1, Source: "carto/proj/api/layer:atoms/api/handler.go:Login", Score: 0.91},
+		{ID: 2, Source: "carto/proj/api/layer:wiring", Score: 0.84},
+		{ID: 3, Source: "carto/proj/api/layer:atoms/api/token.go:Issue", Score: 0.77},
+		{ID: 4, Source: "carto/proj/_system/layer:blueprint", Score: 0.21},
+		{ID: 5, Source: "carto/proj/api/layer:history", Score: 0.95},
+		{ID: 6, Source: "carto/other/api/layer:zones", Score: 0.99},
+	}}
+	store := NewStore(mem, "proj")
+
+	results, tier, err := store.SearchAuto("how does login work", 5, AutoOptions{})
+	if err != nil {
+		t.Fatalf("SearchAuto: %v", err)
+	}
+	if tier != TierStandard {
+		t.Errorf("tier used = %q, want %q", tier, TierStandard)
+	}
+	if mem.searches != 2 {
+		t.Errorf("searches = %d, want 2 (mini, then standard)", mem.searches)
+	}
+	var ids []int
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	if len(ids) != 4 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 || ids[3] != 4 {
+		t.Errorf("result IDs = %v, want [1 2 3 4]: standard layers of proj only, best first", ids)
+	}
+}
+
+func TestSearchAuto_StopsAtMiniWhenStrong(t *testing.T) {
+	mem := &searchingMemories{mockMemories: newMockMemories(), hits: []SearchResult{
+		{ID: 1, Source: "carto/proj/_system/layer:blueprint", Score: 0.8},
+		{ID: 2, Source: "carto/proj/api/layer:zones", Score: 0.7},
+		{ID: 3, Source: "carto/proj/db/layer:zones", Score: 0.6},
+	}}
+	store := NewStore(mem, "proj")
+
+	_, tier, err := store.SearchAuto("what is this", 10, AutoOptions{})
+	if err != nil {
+		t.Fatalf("SearchAuto: %v", err)
+	}
+	if tier != TierMini || mem.searches != 1 {
+		t.Errorf("tier used = %q after %d searches, want mini after 1", tier, mem.searches)
+	}
+}
+
+func TestSearchAuto_ThresholdAndFallbackToFull(t *testing.T) {
+	mem := &searchingMemories{mockMemories: newMockMemories(), hits: []SearchResult{
+		{ID: 1, Source: "carto/proj/api/layer:atoms/api/handler.go:Login", Score: 0.6},
+		{ID: 2, Source: "carto/proj/api/layer:wiring", Score: 0.5},
+		{ID: 3, Source: "carto/proj/_system/layer:blueprint", Score: 0.4},
+	}}
+	store := NewStore(mem, "proj")
+
+	results, tier, err := store.SearchAuto("login", 10, AutoOptions{MinScore: 0.9})
+	if err != nil {
+		t.Fatalf("SearchAuto: %v", err)
+	}
+	if tier != TierFull {
+		t.Errorf("tier used = %q, want full when no tier meets the threshold", tier)
+	}
+	if len(results) != 3 {
+		t.Errorf("got %d results, want the full tier's 3", len(results))
+	}
+}
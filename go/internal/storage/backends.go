@@ -0,0 +1,93 @@
+package storage
+
+import "sort"
+
+// Backends routes layers to Memories instances, e.g. atoms to a
+// project-local instance and signals or knowledge to a shared one. Layers
+// without an entry in Layers use Default.
+//
+// Project-scope artifacts are stored under the "_signals", "_knowledge" and
+// "_context" scopes; they route by that scope name without the underscore,
+// so a "signals" entry covers both module and project signals.
+type Backends struct {
+	Default MemoriesAPI
+	Layers  map[string]MemoriesAPI
+}
+
+// For returns the backend that stores layer.
+func (b Backends) For(layer string) MemoriesAPI {
+	if m, ok := b.Layers[layer]; ok && m != nil {
+		return m
+	}
+	return b.Default
+}
+
+// All returns every distinct backend once, Default first, in a stable
+// order for a given configuration.
+func (b Backends) All() []MemoriesAPI {
+	all := []MemoriesAPI{b.Default}
+	for _, layer := range sortedKeys(b.Layers) {
+		m := b.Layers[layer]
+		if m == nil || containsBackend(all, m) {
+			continue
+		}
+		all = append(all, m)
+	}
+	return all
+}
+
+// SetLayerBackends routes the given layers to their own Memories backends.
+// The backend passed to NewStore keeps every other layer. Lookups by module
+// and layer go to the routed backend; project-wide listings and deletes
+// merge across all of them.
+func (s *Store) SetLayerBackends(layers map[string]MemoriesAPI) {
+	s.backends.Layers = layers
+}
+
+// backendFor returns the backend holding module's layer, routing
+// project-scope artifact scopes by their own name.
+func (s *Store) backendFor(module, layer string) MemoriesAPI {
+	switch module {
+	case "_signals", "_knowledge", "_context":
+		return s.backends.For(module[1:])
+	}
+	return s.backends.For(layer)
+}
+
+// listProject pages through every memory of the project on every backend,
+// calling fn with each page.
+func (s *Store) listProject(fn func(page []SearchResult)) error {
+	const pageSize = 500
+	prefix := s.projectPrefix()
+	for _, backend := range s.backends.All() {
+		for offset := 0; ; offset += pageSize {
+			page, err := backend.ListBySource(prefix, pageSize, offset)
+			if err != nil {
+				return err
+			}
+			fn(page)
+			if len(page) < pageSize {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func containsBackend(list []MemoriesAPI, m MemoriesAPI) bool {
+	for _, x := range list {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]MemoriesAPI) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLayerBackends_RoutesAndMerges(t *testing.T) {
+	local := newMockMemories()
+	shared := newMockMemories()
+	s := NewStore(shared, "proj")
+	s.SetLayerBackends(map[string]MemoriesAPI{LayerAtoms: local})
+
+	if err := s.StoreAtoms("api", "atoms summary", []AtomEntry{{Key: "a.go:1", Text: "atom a"}}); err != nil {
+		t.Fatalf("StoreAtoms: %v", err)
+	}
+	if err := s.StoreLayer("api", LayerBlueprint, "the blueprint"); err != nil {
+		t.Fatalf("StoreLayer: %v", err)
+	}
+
+	for _, m := range local.memories {
+		if !strings.Contains(m.Source, "/layer:atoms") {
+			t.Errorf("local backend got non-atom memory %s", m.Source)
+		}
+	}
+	if len(local.memories) != 2 {
+		t.Errorf("local backend: got %d memories, want the atoms summary and 1 atom", len(local.memories))
+	}
+	if len(shared.memories) != 1 || shared.memories[0].Source != "carto/proj/api/layer:blueprint" {
+		t.Errorf("shared backend: got %+v, want only the blueprint", shared.memories)
+	}
+
+	got, err := s.RetrieveByTier("api", TierFull)
+	if err != nil {
+		t.Fatalf("RetrieveByTier: %v", err)
+	}
+	if len(got[LayerAtoms]) != 2 {
+		t.Errorf("atoms: got %d results, want 2", len(got[LayerAtoms]))
+	}
+	if len(got[LayerBlueprint]) != 1 || got[LayerBlueprint][0].Text != "the blueprint" {
+		t.Errorf("blueprint: got %+v", got[LayerBlueprint])
+	}
+
+	modules, err := s.ListModules()
+	if err != nil || len(modules) != 1 || modules[0] != "api" {
+		t.Errorf("ListModules = %v, %v; want [api]", modules, err)
+	}
+
+	if err := s.ClearModule("api"); err != nil {
+		t.Fatalf("ClearModule: %v", err)
+	}
+	if len(local.deleted) != 1 || len(shared.deleted) != 1 {
+		t.Errorf("ClearModule deleted from local %v and shared %v, want both", local.deleted, shared.deleted)
+	}
+}
+
+func TestLayerBackends_ProjectScopeArtifacts(t *testing.T) {
+	local := newMockMemories()
+	kb := newMockMemories()
+	s := NewStore(local, "proj")
+	s.SetLayerBackends(map[string]MemoriesAPI{"knowledge": kb})
+
+	if err := s.StoreLayer("_knowledge", "notion/page-1", "doc"); err != nil {
+		t.Fatalf("StoreLayer: %v", err)
+	}
+	if err := s.StoreLayer("_signals", "github/1", "issue"); err != nil {
+		t.Fatalf("StoreLayer: %v", err)
+	}
+
+	if len(kb.memories) != 1 || kb.memories[0].Text != "doc" {
+		t.Errorf("knowledge backend: got %+v, want the doc", kb.memories)
+	}
+	if len(local.memories) != 1 || local.memories[0].Text != "issue" {
+		t.Errorf("default backend: got %+v, want the issue", local.memories)
+	}
+}
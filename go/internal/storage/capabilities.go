@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+// API versions of the Memories server this client can talk to.
+const (
+	MinAPIVersion = 1
+	MaxAPIVersion = 1
+)
+
+// ErrIncompatibleServer is returned by CheckCompatible when the Memories
+// server speaks an API version outside MinAPIVersion..MaxAPIVersion.
+var ErrIncompatibleServer = errors.New("incompatible memories server")
+
+// Features a Memories server can advertise in Capabilities.Features.
+const (
+	FeatureUpsert       = "upsert"        // replaces a memory whose content_id it already holds
+	FeatureHybridSearch = "hybrid_search" // blends vector and lexical scores in /search
+)
+
+// Capabilities is what a Memories server reports at GET /capabilities.
+type Capabilities struct {
+	Version    string   `json:"version"`     // server release, for messages only
+	APIVersion int      `json:"api_version"` // 0 if the server does not say
+	Features   []string `json:"features"`
+}
+
+// legacyCapabilities is assumed for servers without a /capabilities
+// endpoint, which predate it. Upsert is not assumed: a server that stores
+// a second copy of a content_id would double-insert on every retry, so
+// the client deletes before adding instead.
+var legacyCapabilities = Capabilities{
+	Version:    "unknown",
+	APIVersion: MinAPIVersion,
+	Features:   []string{FeatureHybridSearch},
+}
+
+// Has reports whether the server advertises feature.
+func (c Capabilities) Has(feature string) bool {
+	return slices.Contains(c.Features, feature)
+}
+
+// Capabilities returns what the server supports, asking it on first use
+// and caching the answer. A server without the endpoint, or answering it
+// with anything but a capabilities document, gets the legacy capabilities.
+// An unreachable server or a server error is not cached, so the next call
+// asks again.
+func (c *MemoriesClient) Capabilities() (Capabilities, error) {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	if c.caps != nil {
+		return *c.caps, nil
+	}
+
+	resp, err := c.request(http.MethodGet, "/capabilities", nil)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		text, _ := io.ReadAll(resp.Body)
+		return Capabilities{}, fmt.Errorf("memories API error %d: %s", resp.StatusCode, text)
+	}
+	caps := legacyCapabilities
+	var got Capabilities
+	if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&got) == nil &&
+		(got.APIVersion != 0 || got.Features != nil) {
+		caps = got
+	}
+	c.caps = &caps
+	return caps, nil
+}
+
+// CheckCompatible returns ErrIncompatibleServer, with the versions
+// involved, if the server's API version is one this client cannot use.
+func (c *MemoriesClient) CheckCompatible() error {
+	caps, err := c.Capabilities()
+	if err != nil {
+		return fmt.Errorf("read memories capabilities: %w", err)
+	}
+	if caps.APIVersion != 0 && (caps.APIVersion < MinAPIVersion || caps.APIVersion > MaxAPIVersion) {
+		return fmt.Errorf("%w: server %s speaks API v%d, carto supports v%d–v%d",
+			ErrIncompatibleServer, caps.Version, caps.APIVersion, MinAPIVersion, MaxAPIVersion)
+	}
+	return nil
+}
+
+// supports reports whether the server advertises feature, assuming the
+// legacy capabilities when they cannot be read.
+func (c *MemoriesClient) supports(feature string) bool {
+	caps, err := c.Capabilities()
+	if err != nil {
+		return legacyCapabilities.Has(feature)
+	}
+	return caps.Has(feature)
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// capabilitiesServer is a Memories server advertising caps (or 404 on
+// /capabilities if caps is ""). It records the hybrid flag of each search
+// and counts capability requests.
+func capabilitiesServer(t *testing.T, caps string, capsCalls *atomic.Int32, hybrid *[]bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/capabilities":
+			capsCalls.Add(1)
+			if caps == "" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(caps))
+		case "/search":
+			var body struct {
+				Hybrid bool `json:"hybrid"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			*hybrid = append(*hybrid, body.Hybrid)
+			w.Write([]byte(`{"results": []}`))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMemoriesClient_AdaptsToCapabilities(t *testing.T) {
+	tests := []struct {
+		name        string
+		caps        string
+		wantHybrid  bool
+		wantUpserts bool
+	}{
+		{"vector-only server", `{"version": "1.4.0", "api_version": 1, "features": ["upsert"]}`, false, true},
+		{"hybrid server without upsert", `{"version": "1.2.0", "api_version": 1, "features": ["hybrid_search"]}`, true, false},
+		{"legacy server", "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var capsCalls atomic.Int32
+			var hybrid []bool
+			client := NewMemoriesClient(capabilitiesServer(t, tt.caps, &capsCalls, &hybrid).URL, "test-key")
+
+			for range 2 {
+				if _, err := client.Search("auth", SearchOptions{K: 5, Hybrid: true}); err != nil {
+					t.Fatalf("Search: %v", err)
+				}
+			}
+			if len(hybrid) != 2 || hybrid[0] != tt.wantHybrid || hybrid[1] != tt.wantHybrid {
+				t.Errorf("searches sent hybrid=%v, want %v", hybrid, tt.wantHybrid)
+			}
+			if got := client.Upserts(); got != tt.wantUpserts {
+				t.Errorf("Upserts() = %v, want %v", got, tt.wantUpserts)
+			}
+			if n := capsCalls.Load(); n != 1 {
+				t.Errorf("capabilities requested %d times, want 1 (cached)", n)
+			}
+			if err := client.CheckCompatible(); err != nil {
+				t.Errorf("CheckCompatible: %v", err)
+			}
+		})
+	}
+}
+
+func TestMemoriesClient_CheckCompatible_RejectsNewerAPI(t *testing.T) {
+	var capsCalls atomic.Int32
+	var hybrid []bool
+	srv := capabilitiesServer(t, `{"version": "3.0.0", "api_version": 2, "features": ["upsert"]}`, &capsCalls, &hybrid)
+
+	err := NewMemoriesClient(srv.URL, "test-key").CheckCompatible()
+	if !errors.Is(err, ErrIncompatibleServer) {
+		t.Fatalf("expected ErrIncompatibleServer, got %v", err)
+	}
+}
+
+func TestMemoriesClient_LegacyServerDeletesBeforeAdd(t *testing.T) {
+	// A server without /capabilities that stores every add, even one whose
+	// content_id it already holds.
+	var (
+		mu       sync.Mutex
+		stored   []SearchResult
+		nextID   int
+		capsSeen bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/capabilities":
+			capsSeen = true
+			http.NotFound(w, r)
+		case r.URL.Path == "/memories":
+			var page []SearchResult
+			for _, m := range stored {
+				if strings.HasPrefix(m.Source, r.URL.Query().Get("source")) {
+					page = append(page, m)
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"memories": page})
+		case r.URL.Path == "/memory/delete-by-prefix":
+			var body struct {
+				SourcePrefix string `json:"source_prefix"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			kept := stored[:0]
+			for _, m := range stored {
+				if !strings.HasPrefix(m.Source, body.SourcePrefix) {
+					kept = append(kept, m)
+				}
+			}
+			count := len(stored) - len(kept)
+			stored = kept
+			json.NewEncoder(w).Encode(map[string]int{"count": count})
+		case r.URL.Path == "/memory/add":
+			var m Memory
+			json.NewDecoder(r.Body).Decode(&m)
+			nextID++
+			stored = append(stored, SearchResult{ID: nextID, Text: m.Text, Source: m.Source})
+			json.NewEncoder(w).Encode(map[string]int{"id": nextID})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	s := NewStore(NewMemoriesClient(srv.URL, "test-key"), "proj")
+	for range 2 { // e.g. a retry after a partial failure
+		if err := s.StoreLayer("auth", LayerWiring, "wiring"); err != nil {
+			t.Fatalf("StoreLayer: %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !capsSeen {
+		t.Error("client never asked the server for its capabilities")
+	}
+	if len(stored) != 1 {
+		t.Errorf("legacy server holds %d copies of the wiring layer, want 1", len(stored))
+	}
+}
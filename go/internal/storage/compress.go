@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"strings"
+)
+
+// compressedPrefix marks content that Store wrote gzip-compressed and
+// base64-encoded. MemoriesClient strips it on retrieval.
+const compressedPrefix = "carto-gzip:"
+
+// DefaultCompressThreshold is the content length above which a Store with
+// compression enabled compresses content it writes.
+const DefaultCompressThreshold = 8 * 1024
+
+// SetCompression makes the Store gzip+base64 any content longer than
+// threshold bytes before writing it. Compressed memories are stored
+// compactly but are not meaningful to vector search, so only enable this
+// for deployments that retrieve layers by source rather than by search.
+// A threshold <= 0 disables compression.
+func (s *Store) SetCompression(threshold int) {
+	s.compressAbove = threshold
+}
+
+// encode compresses content when compression is enabled, the content is
+// over the threshold, and the encoded form is actually shorter.
+func (s *Store) encode(content string) string {
+	if s.compressAbove <= 0 || len(content) <= s.compressAbove {
+		return content
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		return content
+	}
+	if err := zw.Close(); err != nil {
+		return content
+	}
+	encoded := compressedPrefix + base64.StdEncoding.EncodeToString(buf.Bytes())
+	if len(encoded) >= len(content) {
+		return content
+	}
+	return encoded
+}
+
+// DecodeContent returns the original text of content written by a Store
+// with compression enabled. Other content, and content that fails to
+// decode, is returned unchanged.
+func DecodeContent(content string) string {
+	data, ok := strings.CutPrefix(content, compressedPrefix)
+	if !ok {
+		return content
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return content
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return content
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		return content
+	}
+	return string(out)
+}
+
+// decodeResults decodes the text of every result in place.
+func decodeResults(results []SearchResult) []SearchResult {
+	for i := range results {
+		results[i].Text = DecodeContent(results[i].Text)
+	}
+	return results
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStoreCompression_RoundTrip(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+	s.SetCompression(DefaultCompressThreshold)
+
+	large := strings.Repeat("func Handler() returns the same JSON shape\n", 1000)
+	s.StoreLayer("api", LayerZones, large)
+	s.StoreLayer("api", LayerWiring, "small")
+
+	stored := mock.memories[0].Text
+	if !strings.HasPrefix(stored, compressedPrefix) {
+		t.Fatalf("large content not compressed: %.40q", stored)
+	}
+	if len(stored) >= len(large) {
+		t.Errorf("compressed %d bytes to %d", len(large), len(stored))
+	}
+	if got := DecodeContent(stored); got != large {
+		t.Error("decoded content differs from original")
+	}
+	if mock.memories[1].Text != "small" {
+		t.Errorf("small content should be stored as-is, got %q", mock.memories[1].Text)
+	}
+}
+
+func TestStoreCompression_DisabledByDefault(t *testing.T) {
+	mock := newMockMemories()
+	large := strings.Repeat("x", 2*DefaultCompressThreshold)
+	NewStore(mock, "proj").StoreLayer("api", LayerZones, large)
+	if mock.memories[0].Text != large {
+		t.Error("content compressed without SetCompression")
+	}
+}
+
+func TestDecodeContent_PassesThroughPlainAndInvalid(t *testing.T) {
+	for _, in := range []string{"plain text", compressedPrefix + "not base64!", compressedPrefix + "aGVsbG8="} {
+		if got := DecodeContent(in); got != in {
+			t.Errorf("DecodeContent(%q) = %q, want unchanged", in, got)
+		}
+	}
+}
+
+func TestMemoriesClient_ListBySource_DecodesCompressed(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+	s.SetCompression(1)
+	original := strings.Repeat("zone: billing handles invoices\n", 100)
+	s.StoreLayer("billing", LayerZones, original)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"memories": []map[string]any{
+			{"id": 1, "text": mock.memories[0].Text, "source": mock.memories[0].Source},
+		}})
+	}))
+	defer srv.Close()
+
+	results, err := NewStore(NewMemoriesClient(srv.URL, ""), "proj").RetrieveLayer("billing", LayerZones)
+	if err != nil {
+		t.Fatalf("RetrieveLayer: %v", err)
+	}
+	if len(results) != 1 || results[0].Text != original {
+		t.Errorf("retrieved content was not decoded")
+	}
+}
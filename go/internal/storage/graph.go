@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GraphEdge is one wiring edge, stored as its own memory beside the
+// module's wiring layer so a search can surface the edge itself.
+type GraphEdge struct {
+	From       string
+	To         string
+	Reason     string
+	Unresolved bool
+}
+
+// GraphZone is one business-domain zone, stored as its own memory beside
+// the module's zones layer.
+type GraphZone struct {
+	Name   string
+	Intent string
+	Files  []string
+}
+
+// edgeTag returns the source tag of one wiring edge. It extends the wiring
+// layer tag, as atom tags extend the atoms layer's.
+// Format: {namespace}/{project}/{module}/layer:wiring/edge:{from}->{to}
+func (s *Store) edgeTag(module string, e GraphEdge) string {
+	return s.sourceTag(module, LayerWiring) + "/edge:" + e.From + "->" + e.To
+}
+
+// zoneTag returns the source tag of one zone.
+// Format: {namespace}/{project}/{module}/layer:zones/zone:{name}
+func (s *Store) zoneTag(module, name string) string {
+	return s.sourceTag(module, LayerZones) + "/zone:" + name
+}
+
+// isGraphEntry reports whether source tags a single edge or zone memory
+// rather than a wiring or zones layer. Readers of those layers expect the
+// JSON the layer holds and skip these.
+func isGraphEntry(source string) bool {
+	i := strings.Index(source, "/layer:")
+	if i < 0 {
+		return false
+	}
+	rest := source[i+len("/layer:"):]
+	return strings.HasPrefix(rest, LayerWiring+"/edge:") || strings.HasPrefix(rest, LayerZones+"/zone:")
+}
+
+// StoreWiring stores a module's wiring layer, the JSON of all its edges,
+// plus one memory per edge whose text names both ends, so a query about
+// how two units connect can find the edge directly.
+func (s *Store) StoreWiring(module, content string, edges []GraphEdge) error {
+	memories := make([]Memory, 0, len(edges))
+	for _, e := range edges {
+		text := fmt.Sprintf("Wiring edge in module %s: %s -> %s", module, e.From, e.To)
+		if e.Reason != "" {
+			text += "\nReason: " + e.Reason
+		}
+		if e.Unresolved {
+			text += "\nUnresolved: one end matches no known code unit."
+		}
+		memories = append(memories, s.graphMemory(s.edgeTag(module, e), text))
+	}
+	return s.storeWithEntries(module, LayerWiring, content, memories)
+}
+
+// StoreZones stores a module's zones layer, the JSON of all its zones,
+// plus one memory per zone with its intent and files.
+func (s *Store) StoreZones(module, content string, zones []GraphZone) error {
+	memories := make([]Memory, 0, len(zones))
+	for _, z := range zones {
+		text := fmt.Sprintf("Zone %s in module %s", z.Name, module)
+		if z.Intent != "" {
+			text += ": " + z.Intent
+		}
+		if len(z.Files) > 0 {
+			text += "\nFiles: " + strings.Join(z.Files, ", ")
+		}
+		memories = append(memories, s.graphMemory(s.zoneTag(module, z.Name), text))
+	}
+	return s.storeWithEntries(module, LayerZones, content, memories)
+}
+
+// graphMemory builds the memory for one edge or zone, truncated and
+// marked as in StoreLayer if it is oversized.
+func (s *Store) graphMemory(tag, text string) Memory {
+	text, meta := s.truncateMarked(text)
+	m := Memory{Text: s.encode(text), Source: tag, Metadata: meta}
+	m.ContentID = ContentID(tag, m.Text)
+	return m
+}
+
+// storeWithEntries stores content as module's layer together with entries
+// tagged under it. Everything previously stored under the layer tag is
+// deleted first, even on a backend that upserts, so edges and zones that
+// are gone from this run don't linger.
+func (s *Store) storeWithEntries(module, layer, content string, entries []Memory) error {
+	head := s.layerMemory(module, layer, content)
+	backend := s.backendFor(module, layer)
+	if _, err := backend.DeleteBySource(head.Source); err != nil {
+		return err
+	}
+	if _, err := backend.AddMemory(head); err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return backend.AddBatch(entries)
+}
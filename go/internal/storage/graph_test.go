@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+// textSearchMemories answers searches with the stored memories under the
+// source prefix whose text contains every query term.
+type textSearchMemories struct {
+	*mockMemories
+}
+
+func (m *textSearchMemories) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	var out []SearchResult
+	for i, mem := range m.memories {
+		if !strings.HasPrefix(mem.Source, opts.SourcePrefix) {
+			continue
+		}
+		matches := true
+		for _, term := range strings.Fields(query) {
+			if !strings.Contains(mem.Text, term) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			out = append(out, SearchResult{ID: i + 1, Text: mem.Text, Source: mem.Source, Score: 1})
+		}
+	}
+	return out, nil
+}
+
+func TestStoreWiring_EdgesAreSearchable(t *testing.T) {
+	mem := &textSearchMemories{newMockMemories()}
+	store := NewStore(mem, "proj")
+
+	edges := []GraphEdge{
+		{From: "handler.Login", To: "store.Users", Reason: "looks up the account"},
+		{From: "handler.Login", To: "token.Issue", Reason: "issues a session token"},
+	}
+	if err := store.StoreWiring("api", `[{"from":"handler.Login","to":"store.Users"}]`, edges); err != nil {
+		t.Fatalf("StoreWiring: %v", err)
+	}
+
+	results, err := mem.Search("handler.Login store.Users", SearchOptions{SourcePrefix: ProjectPrefix("", "proj")})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	want := "carto/proj/api/layer:wiring/edge:handler.Login->store.Users"
+	var edge *SearchResult
+	for i, r := range results {
+		if strings.Contains(r.Source, "/edge:") {
+			if edge != nil {
+				t.Fatalf("search for both ends matched several edges: %+v", results)
+			}
+			edge = &results[i]
+		}
+	}
+	if edge == nil || edge.Source != want {
+		t.Fatalf("search for both ends = %+v, want the edge %s", results, want)
+	}
+	if !strings.Contains(edge.Text, "looks up the account") {
+		t.Errorf("edge text %q lacks its reason", edge.Text)
+	}
+	if _, module, layer, ok := ParseSourceTag(edge.Source); !ok || module != "api" || layer != LayerWiring {
+		t.Errorf("edge tag parses as module %q layer %q (ok=%v), want api wiring", module, layer, ok)
+	}
+
+	// Readers of the wiring layer still get only the JSON blob.
+	layer, err := store.RetrieveLayer("api", LayerWiring)
+	if err != nil {
+		t.Fatalf("RetrieveLayer: %v", err)
+	}
+	if len(layer) != 1 || !strings.HasPrefix(layer[0].Text, "[") {
+		t.Errorf("RetrieveLayer(wiring) = %+v, want just the JSON blob", layer)
+	}
+	graph, err := store.RetrieveGraph("api", LayerWiring)
+	if err != nil {
+		t.Fatalf("RetrieveGraph: %v", err)
+	}
+	if len(graph) != 2 {
+		t.Errorf("RetrieveGraph(wiring) returned %d edges, want 2", len(graph))
+	}
+	stats, err := store.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.WiringEdges != 1 {
+		t.Errorf("Stats().WiringEdges = %d, want 1 from the blob", stats.WiringEdges)
+	}
+}
+
+func TestStoreZones_ReplacesStaleZones(t *testing.T) {
+	mem := &textSearchMemories{newMockMemories()}
+	store := NewStore(mem, "proj")
+
+	if err := store.StoreZones("api", `[{"name":"auth"},{"name":"billing"}]`, []GraphZone{
+		{Name: "auth", Intent: "login and sessions", Files: []string{"api/login.go"}},
+		{Name: "billing", Intent: "invoices"},
+	}); err != nil {
+		t.Fatalf("StoreZones: %v", err)
+	}
+	if err := store.StoreZones("api", `[{"name":"auth"}]`, []GraphZone{
+		{Name: "auth", Intent: "login and sessions", Files: []string{"api/login.go"}},
+	}); err != nil {
+		t.Fatalf("StoreZones again: %v", err)
+	}
+
+	graph, err := store.RetrieveGraph("api", LayerZones)
+	if err != nil {
+		t.Fatalf("RetrieveGraph: %v", err)
+	}
+	if len(graph) != 1 || graph[0].Source != "carto/proj/api/layer:zones/zone:auth" {
+		t.Fatalf("zones after re-store = %+v, want only auth", graph)
+	}
+	if !strings.Contains(graph[0].Text, "api/login.go") {
+		t.Errorf("zone text %q lacks its files", graph[0].Text)
+	}
+
+	scope, err := store.ResolveZone("auth")
+	if err != nil {
+		t.Fatalf("ResolveZone: %v", err)
+	}
+	if len(scope.Modules) != 1 || scope.Modules[0] != "api" {
+		t.Errorf("ResolveZone(auth).Modules = %v, want [api]", scope.Modules)
+	}
+}
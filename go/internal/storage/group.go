@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GroupedResult stands for every search result about the same atom of a
+// module, whichever layer it came from. It carries the best-scoring member.
+type GroupedResult struct {
+	SearchResult
+	Layers []string `json:"layers"` // contributing layers, best-scoring first
+}
+
+// GroupResults collapses results that describe the same atom across layers
+// into one result each, ranked by their best score. Results under an atom
+// tag ({module}/layer:atoms/{key}) group by module and atom key; results of
+// module-wide layers (wiring, zones, history, ...) fold into their module's
+// best-scoring atom, or group on their own when the module has no atom
+// among the results. Sources not written by Store group only with results
+// of the identical source.
+func GroupResults(namespace string, results []SearchResult) []GroupedResult {
+	sorted := make([]SearchResult, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Score > sorted[j].Score })
+
+	type group struct {
+		result GroupedResult
+		atom   string // atom key the group stands for, "" if none yet
+	}
+	var groups []*group
+	byKey := make(map[string]*group)    // module + atom key, or raw source
+	byModule := make(map[string]*group) // a module's first group
+
+	add := func(g *group, layer string) {
+		if layer != "" && !slices.Contains(g.result.Layers, layer) {
+			g.result.Layers = append(g.result.Layers, layer)
+		}
+	}
+	start := func(r SearchResult, layer string) *group {
+		g := &group{result: GroupedResult{SearchResult: r}}
+		add(g, layer)
+		groups = append(groups, g)
+		return g
+	}
+
+	for _, r := range sorted {
+		project, module, layer, ok := ParseSourceTagIn(namespace, r.Source)
+		if !ok {
+			if byKey[r.Source] == nil {
+				byKey[r.Source] = start(r, "")
+			}
+			continue
+		}
+		moduleKey := project + "/" + module
+		atom := atomKey(r.Source, layer)
+		if atom == "" {
+			if g := byModule[moduleKey]; g != nil {
+				add(g, layer)
+			} else {
+				byModule[moduleKey] = start(r, layer)
+			}
+			continue
+		}
+
+		key := moduleKey + "\x00" + atom
+		g := byKey[key]
+		if g == nil {
+			if m := byModule[moduleKey]; m != nil && m.atom == "" {
+				g = m
+			} else {
+				g = start(r, layer)
+			}
+			g.atom = atom
+			byKey[key] = g
+			if byModule[moduleKey] == nil {
+				byModule[moduleKey] = g
+			}
+		}
+		add(g, layer)
+	}
+
+	out := make([]GroupedResult, len(groups))
+	for i, g := range groups {
+		out[i] = g.result
+	}
+	return out
+}
+
+// atomKey returns what follows the layer name in a source tag (an atom's
+// file:line key), or "" for a layer-wide tag.
+func atomKey(source, layer string) string {
+	i := strings.Index(source, "/layer:"+layer+"/")
+	if i < 0 {
+		return ""
+	}
+	return source[i+len("/layer:"+layer+"/"):]
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupResults_CollapsesLayersOfOneAtom(t *testing.T) {
+	results := []SearchResult{
+		{ID: 1, Text: "auth wiring", Score: 0.7, Source: "carto/proj/auth/layer:wiring"},
+		{ID: 2, Text: "func Login", Score: 0.9, Source: "carto/proj/auth/layer:atoms/login.go:12"},
+		{ID: 3, Text: "auth zones", Score: 0.5, Source: "carto/proj/auth/layer:zones"},
+		{ID: 4, Text: "func Render", Score: 0.6, Source: "carto/proj/ui/layer:atoms/render.go:3"},
+		{ID: 5, Text: "func Logout", Score: 0.4, Source: "carto/proj/auth/layer:atoms/login.go:40"},
+		{ID: 6, Text: "notes", Score: 0.3, Source: "external/notes"},
+	}
+
+	got := GroupResults("", results)
+
+	var ids []int
+	for _, g := range got {
+		ids = append(ids, g.ID)
+	}
+	if want := []int{2, 4, 5, 6}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("group representatives = %v, want %v", ids, want)
+	}
+	if want := []string{LayerAtoms, LayerWiring, LayerZones}; !reflect.DeepEqual(got[0].Layers, want) {
+		t.Errorf("login.go:12 layers = %v, want %v", got[0].Layers, want)
+	}
+	if want := []string{LayerAtoms}; !reflect.DeepEqual(got[2].Layers, want) {
+		t.Errorf("a second atom of the module should stay separate, got layers %v", got[2].Layers)
+	}
+	if got[3].Layers != nil {
+		t.Errorf("foreign source layers = %v, want none", got[3].Layers)
+	}
+}
+
+func TestGroupResults_ModuleLayersWithoutAtom(t *testing.T) {
+	results := []SearchResult{
+		{ID: 1, Score: 0.8, Source: "team/proj/api/layer:zones"},
+		{ID: 2, Score: 0.6, Source: "team/proj/api/layer:wiring"},
+		{ID: 3, Score: 0.5, Source: "team/proj/api/layer:atoms/h.go:1"},
+	}
+
+	got := GroupResults("team", results)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 group, got %d: %+v", len(got), got)
+	}
+	if got[0].ID != 1 || got[0].Score != 0.8 {
+		t.Errorf("representative = %+v, want the best-scoring zones result", got[0].SearchResult)
+	}
+	if want := []string{LayerZones, LayerWiring, LayerAtoms}; !reflect.DeepEqual(got[0].Layers, want) {
+		t.Errorf("layers = %v, want %v", got[0].Layers, want)
+	}
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"encoding/json"
+
+	"github.com/divyekant/carto/internal/history"
+)
+
+// RetrieveHistory returns the file histories stored in the history layer
+// of every module, keyed by module name. Entries that are not a JSON list
+// of file histories are skipped; a module whose entries are all skipped
+// is still listed, with no histories.
+func (s *Store) RetrieveHistory() (map[string][]*history.FileHistory, error) {
+	layers, err := s.RetrieveLayerAllModules(LayerHistory)
+	if err != nil {
+		return nil, err
+	}
+	histories := make(map[string][]*history.FileHistory, len(layers))
+	for module, results := range layers {
+		var all []*history.FileHistory
+		for _, res := range results {
+			var fhs []*history.FileHistory
+			if json.Unmarshal([]byte(res.Text), &fhs) == nil {
+				all = append(all, fhs...)
+			}
+		}
+		histories[module] = all
+	}
+	return histories, nil
+}
@@ -0,0 +1,21 @@
+package storage
+
+import "testing"
+
+func TestRetrieveHistory(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+	s.StoreLayer("api", LayerHistory, `[{"FilePath": "api/login.go", "Authors": ["ana"]}, {"FilePath": "api/find.go"}]`)
+	s.StoreLayer("web", LayerHistory, `not json`)
+
+	histories, err := s.RetrieveHistory()
+	if err != nil {
+		t.Fatalf("RetrieveHistory: %v", err)
+	}
+	if api := histories["api"]; len(api) != 2 || api[0].FilePath != "api/login.go" || api[1].FilePath != "api/find.go" {
+		t.Errorf("api histories = %+v, want login.go and find.go", api)
+	}
+	if web, ok := histories["web"]; !ok || len(web) != 0 {
+		t.Errorf("web histories = %+v (listed %v), want listed with none", web, ok)
+	}
+}
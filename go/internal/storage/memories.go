@@ -2,31 +2,61 @@ package storage
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 )
 
 // Memory represents a document to store in the Memories index.
+// ContentID is the upsert key: adding a memory whose ContentID is already
+// stored replaces it rather than storing a duplicate.
 type Memory struct {
 	Text        string         `json:"text"`
 	Source      string         `json:"source"`
 	Metadata    map[string]any `json:"metadata,omitempty"`
 	Deduplicate bool           `json:"deduplicate"`
+	ContentID   string         `json:"content_id,omitempty"` // see ContentID
+}
+
+// ContentID returns the deterministic ID of a memory: a SHA-256 digest of
+// its source tag and text. Storing the same content twice, as a retry after
+// a partial failure does, yields the same ID.
+func ContentID(source, text string) string {
+	h := sha256.New()
+	h.Write([]byte(source))
+	h.Write([]byte{0})
+	h.Write([]byte(text))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// withContentID returns m with its ContentID filled in if empty.
+func withContentID(m Memory) Memory {
+	if m.ContentID == "" {
+		m.ContentID = ContentID(m.Source, m.Text)
+	}
+	return m
 }
 
 // SearchResult represents a single result returned from Memories.
+// VectorScore and LexicalScore are the hybrid-search components of Score;
+// they are nil when the Memories server does not report them.
 type SearchResult struct {
-	ID     int            `json:"id"`
-	Text   string         `json:"text"`
-	Score  float64        `json:"score"`
-	Source string         `json:"source"`
-	Meta   map[string]any `json:"metadata,omitempty"`
+	ID           int            `json:"id"`
+	Text         string         `json:"text"`
+	Score        float64        `json:"score"`
+	Source       string         `json:"source"`
+	Meta         map[string]any `json:"metadata,omitempty"`
+	VectorScore  *float64       `json:"vector_score,omitempty"`
+	LexicalScore *float64       `json:"lexical_score,omitempty"`
 }
 
 // SearchOptions controls search behaviour.
@@ -37,11 +67,17 @@ type SearchOptions struct {
 	SourcePrefix string  `json:"source_prefix,omitempty"`
 }
 
-// MemoriesClient talks to the Memories REST API.
+// MemoriesClient talks to the Memories REST API, adapting to the features
+// the server advertises (see Capabilities).
 type MemoriesClient struct {
 	baseURL string
 	apiKey  string
 	http    http.Client
+
+	capsMu sync.Mutex
+	caps   *Capabilities // cached by Capabilities
+
+	retryBackoff time.Duration // first wait before retrying a batch; 0 means defaultRetryBackoff
 }
 
 // NewMemoriesClient creates a client for the given base URL and API key.
@@ -91,9 +127,15 @@ func (c *MemoriesClient) Health() (bool, error) {
 	return resp.StatusCode == http.StatusOK, nil
 }
 
-// AddMemory stores a single memory and returns its assigned ID.
+// Upserts reports whether the Memories server replaces a memory whose
+// content_id it already holds, which makes AddMemory and AddBatch safe to
+// retry. Without it, Store clears what it replaces first.
+func (c *MemoriesClient) Upserts() bool { return c.supports(FeatureUpsert) }
+
+// AddMemory stores a single memory and returns its assigned ID. The memory
+// is sent with its ContentID as the upsert key.
 func (c *MemoriesClient) AddMemory(m Memory) (int, error) {
-	resp, err := c.request(http.MethodPost, "/memory/add", m)
+	resp, err := c.request(http.MethodPost, "/memory/add", withContentID(m))
 	if err != nil {
 		return 0, err
 	}
@@ -115,53 +157,160 @@ func (c *MemoriesClient) AddMemory(m Memory) (int, error) {
 
 const batchSize = 500
 
-// AddBatch stores memories in chunks of batchSize. The Memories server handles
-// internal chunking. Continues on individual batch failures and returns the
-// first error encountered.
+// maxBatchAttempts is how many times AddBatch sends a batch that fails
+// transiently before giving up on it.
+const maxBatchAttempts = 3
+
+// defaultRetryBackoff is the wait before the first retry of a failed
+// batch; each later retry waits twice as long.
+const defaultRetryBackoff = time.Second
+
+// BatchError reports the memories of an AddBatch call that were not
+// stored. The others were stored, so a caller can tell which landed.
+type BatchError struct {
+	Total  int   // memories passed to AddBatch
+	Failed []int // indexes into them of the memories not stored, ascending
+	Err    error // the first failure
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d memories not stored: %v", len(e.Failed), e.Total, e.Err)
+}
+
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// AddBatch stores memories in chunks of batchSize. A chunk the server
+// rejects as too large is halved until it fits, and one that fails
+// transiently (a network error, 429 or 5xx) is retried with exponential
+// backoff. Chunks that still fail don't stop the rest: the result is a
+// *BatchError naming the memories that weren't stored, or nil. Each memory
+// carries its ContentID, so retrying a batch doesn't store it twice.
 func (c *MemoriesClient) AddBatch(memories []Memory) error {
+	results := c.AddBatchResults(memories)
+	var batchErr *BatchError
+	for i, err := range results {
+		if err == nil {
+			continue
+		}
+		if batchErr == nil {
+			batchErr = &BatchError{Total: len(memories), Err: err}
+		}
+		batchErr.Failed = append(batchErr.Failed, i)
+	}
+	if batchErr == nil {
+		return nil
+	}
+	return batchErr
+}
+
+// AddBatchResults stores memories as AddBatch does and returns one result
+// per memory: nil if it was stored, otherwise the error that kept it out.
+func (c *MemoriesClient) AddBatchResults(memories []Memory) []error {
+	results := make([]error, len(memories))
 	total := (len(memories) + batchSize - 1) / batchSize
-	var firstErr error
 	for i := 0; i < len(memories); i += batchSize {
 		end := i + batchSize
 		if end > len(memories) {
 			end = len(memories)
 		}
-		batch := memories[i:end]
+		batch := make([]Memory, end-i)
+		for j, m := range memories[i:end] {
+			batch[j] = withContentID(m)
+		}
 		batchNum := i/batchSize + 1
 
 		log.Printf("storage: storing batch %d/%d (%d memories)", batchNum, total, len(batch))
+		c.addChunk(batch, results[i:end])
+	}
+	return results
+}
 
-		payload := struct {
-			Memories []Memory `json:"memories"`
-		}{Memories: batch}
+// addChunk stores batch, recording each memory's outcome in results. A
+// chunk the server finds too large is split in half and each half stored
+// on its own; a single memory that is still too large fails.
+func (c *MemoriesClient) addChunk(batch []Memory, results []error) {
+	err := c.postBatch(batch)
+	var apiErr *batchStatusError
+	if errors.As(err, &apiErr) && apiErr.status == http.StatusRequestEntityTooLarge && len(batch) > 1 {
+		half := len(batch) / 2
+		log.Printf("storage: batch of %d memories too large, splitting in two", len(batch))
+		c.addChunk(batch[:half], results[:half])
+		c.addChunk(batch[half:], results[half:])
+		return
+	}
+	if err != nil {
+		log.Printf("storage: warning: %d memories not stored: %v", len(batch), err)
+	}
+	for i := range results {
+		results[i] = err
+	}
+}
+
+// batchStatusError is a non-200 response to an add-batch request.
+type batchStatusError struct {
+	status int
+	body   string
+}
 
-		resp, err := c.request(http.MethodPost, "/memory/add-batch", payload)
+func (e *batchStatusError) Error() string {
+	return fmt.Sprintf("memories API error %d: %s", e.status, e.body)
+}
+
+// transient reports whether the request may succeed if sent again.
+func (e *batchStatusError) transient() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+// postBatch sends one add-batch request, retrying network errors and
+// transient statuses with exponential backoff.
+func (c *MemoriesClient) postBatch(batch []Memory) error {
+	payload := struct {
+		Memories []Memory `json:"memories"`
+	}{Memories: batch}
+
+	backoff := c.retryBackoff
+	if backoff == 0 {
+		backoff = defaultRetryBackoff
+	}
+	var err error
+	for attempt := 0; attempt < maxBatchAttempts; attempt++ {
+		if attempt > 0 {
+			log.Printf("storage: retrying batch of %d memories in %s: %v", len(batch), backoff, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var resp *http.Response
+		resp, err = c.request(http.MethodPost, "/memory/add-batch", payload)
 		if err != nil {
-			log.Printf("storage: warning: batch %d/%d failed: %v", batchNum, total, err)
-			if firstErr == nil {
-				firstErr = fmt.Errorf("batch %d: %w", batchNum, err)
-			}
 			continue
 		}
+		text, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
 
-		if resp.StatusCode != http.StatusOK {
-			text, _ := io.ReadAll(resp.Body)
-			log.Printf("storage: warning: batch %d/%d returned %d: %s", batchNum, total, resp.StatusCode, text)
-			if firstErr == nil {
-				firstErr = fmt.Errorf("batch %d: memories API error %d: %s", batchNum, resp.StatusCode, text)
-			}
+		statusErr := &batchStatusError{status: resp.StatusCode, body: string(text)}
+		if !statusErr.transient() {
+			return statusErr
 		}
+		err = statusErr
 	}
-	return firstErr
+	return err
 }
 
-// Search queries the Memories index with the given options.
+// Search queries the Memories index with the given options. Compressed
+// content (see Store.SetCompression) is decoded in the results. A hybrid
+// search falls back to vector-only on a server without hybrid search.
 func (c *MemoriesClient) Search(query string, opts SearchOptions) ([]SearchResult, error) {
 	k := opts.K
 	if k == 0 {
 		k = 10
 	}
+	if opts.Hybrid && !c.supports(FeatureHybridSearch) {
+		opts.Hybrid = false
+	}
 
 	payload := struct {
 		Query        string  `json:"query"`
@@ -194,10 +343,11 @@ func (c *MemoriesClient) Search(query string, opts SearchOptions) ([]SearchResul
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
-	return result.Results, nil
+	return decodeResults(result.Results), nil
 }
 
-// ListBySource fetches memories matching a source prefix with pagination.
+// ListBySource fetches memories matching a source prefix with pagination,
+// decoding compressed content.
 func (c *MemoriesClient) ListBySource(source string, limit, offset int) ([]SearchResult, error) {
 	if limit == 0 {
 		limit = 100
@@ -223,7 +373,7 @@ func (c *MemoriesClient) ListBySource(source string, limit, offset int) ([]Searc
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
-	return result.Memories, nil
+	return decodeResults(result.Memories), nil
 }
 
 // DeleteMemory removes a memory by ID. Tolerates 404 (already deleted).
@@ -2,9 +2,12 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestMemoriesClient_Health(t *testing.T) {
@@ -106,8 +109,43 @@ func TestMemoriesClient_AddMemory(t *testing.T) {
 	}
 }
 
+func TestMemoriesClient_AddBatchSendsContentIDs(t *testing.T) {
+	var received []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Memories []map[string]any `json:"memories"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		received = append(received, body.Memories...)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := NewMemoriesClient(srv.URL, "key")
+	batch := []Memory{{Text: "a", Source: "s"}, {Text: "b", Source: "s"}}
+	client.AddBatch(batch)
+	client.AddBatch(batch) // a retry sends the same upsert keys
+
+	if len(received) != 4 {
+		t.Fatalf("expected 4 memories sent, got %d", len(received))
+	}
+	if received[0]["content_id"] != ContentID("s", "a") || received[1]["content_id"] != ContentID("s", "b") {
+		t.Errorf("content IDs = %v, %v", received[0]["content_id"], received[1]["content_id"])
+	}
+	if received[0]["content_id"] != received[2]["content_id"] {
+		t.Error("retry sent a different content ID for identical content")
+	}
+	if batch[0].ContentID != "" {
+		t.Error("AddBatch modified the caller's memories")
+	}
+}
+
 func TestMemoriesClient_Search(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/capabilities" {
+			http.NotFound(w, r)
+			return
+		}
 		if r.URL.Path != "/search" {
 			t.Errorf("unexpected path: %s", r.URL.Path)
 		}
@@ -301,3 +339,96 @@ func TestMemoriesClient_Search_WithSourcePrefix(t *testing.T) {
 		t.Fatalf("expected 1 result, got %d", len(results))
 	}
 }
+
+func TestMemoriesClient_AddBatchSplitsTooLargeBatches(t *testing.T) {
+	const limit = 130 // the most memories the server accepts in one request
+	stored := map[string]bool{}
+	var requests, rejected int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Memories []Memory `json:"memories"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests++
+		if len(body.Memories) > limit {
+			rejected++
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		for _, m := range body.Memories {
+			stored[m.Text] = true
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	memories := make([]Memory, 700)
+	for i := range memories {
+		memories[i] = Memory{Text: fmt.Sprintf("memory %d", i), Source: "s"}
+	}
+	client := NewMemoriesClient(srv.URL, "key")
+	if err := client.AddBatch(memories); err != nil {
+		t.Fatalf("AddBatch: %v", err)
+	}
+
+	if len(stored) != len(memories) {
+		t.Errorf("stored %d memories, want all %d", len(stored), len(memories))
+	}
+	// 500 splits to 250 and then 125s before fitting; the remaining 200
+	// splits once to 100s.
+	if rejected != 4 {
+		t.Errorf("rejected %d requests, want 4 (500, 250, 250, 200)", rejected)
+	}
+	if requests != 4+4+2 {
+		t.Errorf("sent %d requests, want 10", requests)
+	}
+}
+
+func TestMemoriesClient_AddBatchRetriesTransientErrors(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := NewMemoriesClient(srv.URL, "key")
+	client.retryBackoff = time.Millisecond
+	if err := client.AddBatch([]Memory{{Text: "a", Source: "s"}}); err != nil {
+		t.Fatalf("AddBatch: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("sent %d requests, want 2 (503, then the retry)", requests)
+	}
+}
+
+func TestMemoriesClient_AddBatchReportsUnstoredMemories(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Memories []Memory `json:"memories"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, m := range body.Memories {
+			if m.Text == "huge" {
+				http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := NewMemoriesClient(srv.URL, "key")
+	err := client.AddBatch([]Memory{{Text: "a"}, {Text: "huge"}, {Text: "b"}, {Text: "c"}})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("AddBatch error = %v, want a *BatchError", err)
+	}
+	if batchErr.Total != 4 || len(batchErr.Failed) != 1 || batchErr.Failed[0] != 1 {
+		t.Errorf("BatchError = %+v, want only index 1 of 4 failed", batchErr)
+	}
+}
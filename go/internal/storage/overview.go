@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Overview is the quick answer to "what is this project?", read from the
+// stored analysis without a search: the system blueprint, the leading
+// patterns and what each module is for.
+type Overview struct {
+	Blueprint string         `json:"blueprint"`
+	Patterns  []string       `json:"patterns"`
+	Modules   []ModuleIntent `json:"modules"`
+}
+
+// ModuleIntent is a module's name and the first line of its stored intent.
+type ModuleIntent struct {
+	Name   string `json:"name"`
+	Intent string `json:"intent"`
+}
+
+// Indexed reports whether any analysis was found for the overview.
+func (o *Overview) Indexed() bool {
+	return o.Blueprint != "" || len(o.Patterns) > 0 || len(o.Modules) > 0
+}
+
+// Overview reads the project's blueprint and patterns from the _system
+// scope and the intent layer of every module, keeping the most recent
+// entry of each. maxPatterns caps the patterns returned; 0 keeps all.
+func (s *Store) Overview(maxPatterns int) (*Overview, error) {
+	o := &Overview{Patterns: []string{}, Modules: []ModuleIntent{}}
+
+	blueprint, err := s.RetrieveLayer("_system", LayerBlueprint)
+	if err != nil {
+		return nil, err
+	}
+	o.Blueprint = latestText(blueprint)
+
+	patterns, err := s.RetrieveLayer("_system", LayerPatterns)
+	if err != nil {
+		return nil, err
+	}
+	if text := latestText(patterns); text != "" {
+		json.Unmarshal([]byte(text), &o.Patterns)
+	}
+	if maxPatterns > 0 && len(o.Patterns) > maxPatterns {
+		o.Patterns = o.Patterns[:maxPatterns]
+	}
+
+	intents, err := s.RetrieveLayerAllModules(LayerIntent)
+	if err != nil {
+		return nil, err
+	}
+	for module, results := range intents {
+		if module == "_system" {
+			continue
+		}
+		if intent := latestText(results); intent != "" {
+			line, _, _ := strings.Cut(intent, "\n")
+			o.Modules = append(o.Modules, ModuleIntent{Name: module, Intent: strings.TrimSpace(line)})
+		}
+	}
+	sort.Slice(o.Modules, func(i, j int) bool { return o.Modules[i].Name < o.Modules[j].Name })
+	return o, nil
+}
+
+// latestText returns the trimmed text of the last non-empty result, the
+// most recently stored.
+func latestText(results []SearchResult) string {
+	for i := len(results) - 1; i >= 0; i-- {
+		if text := strings.TrimSpace(results[i].Text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
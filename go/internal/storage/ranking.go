@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/divyekant/carto/internal/scanner"
+)
+
+// PathWeight multiplies the score of results from files matching Glob.
+type PathWeight struct {
+	Glob   string
+	Weight float64
+}
+
+// Ranking re-weights search results by the file they come from, so that
+// tests, examples or generated code can rank below the implementation.
+// A nil Ranking leaves results as they are.
+type Ranking struct {
+	Weights []PathWeight // in file order; the first matching glob applies
+
+	// Root is the project root the file was loaded from. Absolute file
+	// paths under it, as in atoms indexed before keys were made relative,
+	// are matched relative to it.
+	Root string
+}
+
+// RankingPath returns the path of a project's ranking file.
+func RankingPath(rootPath string) string {
+	return filepath.Join(rootPath, ".carto", "ranking.yaml")
+}
+
+// LoadRanking reads .carto/ranking.yaml from rootPath. The file maps globs,
+// in the syntax of the include and exclude patterns, to score multipliers:
+//
+//	weights:
+//	  "**/*_test.go": 0.5
+//	  "cmd/**": 1.5
+//
+// It returns nil (no error) if the file doesn't exist.
+func LoadRanking(rootPath string) (*Ranking, error) {
+	path := RankingPath(rootPath)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ranking: %w", err)
+	}
+	root, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("ranking: %w", err)
+	}
+	// Decode the weights as a node so they keep the order of the file.
+	var raw struct {
+		Weights yaml.Node `yaml:"weights"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("ranking %s: %w", path, err)
+	}
+	r := &Ranking{Root: root}
+	if raw.Weights.Kind == 0 {
+		return r, nil
+	}
+	if raw.Weights.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("ranking %s: weights must map globs to numbers", path)
+	}
+	for i := 0; i+1 < len(raw.Weights.Content); i += 2 {
+		glob := raw.Weights.Content[i].Value
+		var weight float64
+		if err := raw.Weights.Content[i+1].Decode(&weight); err != nil {
+			return nil, fmt.Errorf("ranking %s: weight for %q: %w", path, glob, err)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("ranking %s: weight for %q must not be negative", path, glob)
+		}
+		r.Weights = append(r.Weights, PathWeight{Glob: glob, Weight: weight})
+	}
+	return r, nil
+}
+
+// Weight returns the multiplier for results from file, a path relative to
+// the project root or an absolute one under Root: the weight of the first
+// glob that matches it, or 1.
+func (r *Ranking) Weight(file string) float64 {
+	if r == nil {
+		return 1
+	}
+	if filepath.IsAbs(file) && r.Root != "" {
+		rel, err := filepath.Rel(r.Root, file)
+		if err != nil || !filepath.IsLocal(rel) {
+			return 1
+		}
+		file = filepath.ToSlash(rel)
+	}
+	for _, w := range r.Weights {
+		if scanner.GlobMatch(w.Glob, file) {
+			return w.Weight
+		}
+	}
+	return 1
+}
+
+// Apply multiplies the score of each atom result by the weight of its file
+// and re-sorts results by score, highest first. Results not tied to a file
+// keep their score. results is modified in place and returned.
+func (r *Ranking) Apply(namespace string, results []SearchResult) []SearchResult {
+	if r == nil || len(r.Weights) == 0 {
+		return results
+	}
+	for i := range results {
+		if file := resultFile(namespace, results[i]); file != "" {
+			results[i].Score *= r.Weight(file)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results
+}
+
+// resultFile returns the file an atom result comes from, or "" for a
+// result of any other layer.
+func resultFile(namespace string, r SearchResult) string {
+	_, _, layer, ok := ParseSourceTagIn(namespace, r.Source)
+	if !ok || layer != LayerAtoms {
+		return ""
+	}
+	key := atomKey(r.Source, layer)
+	if i := strings.LastIndexByte(key, ':'); i > 0 {
+		key = key[:i]
+	}
+	return key
+}
+
+// SetRanking sets the path weights SearchTier and SearchAuto apply to
+// their results. Nil, the default, leaves scores unchanged.
+func (s *Store) SetRanking(r *Ranking) {
+	s.ranking = r
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRanking(t *testing.T, content string) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".carto"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(RankingPath(root), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestSearchTier_RankingReordersResults(t *testing.T) {
+	root := writeRanking(t, `weights:
+  "**/*_test.go": 0.5
+  "cmd/**": 1.5
+`)
+	ranking, err := LoadRanking(root)
+	if err != nil {
+		t.Fatalf("LoadRanking: %v", err)
+	}
+
+	// Atom keys are file:line, the file relative to the project root; atoms
+	// indexed by older versions name it by its absolute path.
+	hits := []SearchResult{
+		{ID: 1, Source: "carto/proj/api/layer:atoms/" + filepath.ToSlash(root) + "/internal/auth/login_test.go:12", Score: 0.9},
+		{ID: 2, Source: "carto/proj/api/layer:atoms/internal/auth/login.go:30", Score: 0.8},
+		{ID: 3, Source: "carto/proj/cli/layer:atoms/" + filepath.ToSlash(root) + "/cmd/carto/login.go:41", Score: 0.5},
+		{ID: 4, Source: "carto/proj/api/layer:wiring", Score: 0.6},
+	}
+	search := func(r *Ranking) []int {
+		mem := &searchingMemories{mockMemories: newMockMemories(), hits: append([]SearchResult(nil), hits...)}
+		store := NewStore(mem, "proj")
+		store.SetRanking(r)
+		results, err := store.SearchTier("login", TierFull, 10)
+		if err != nil {
+			t.Fatalf("SearchTier: %v", err)
+		}
+		var ids []int
+		for _, r := range results {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	if got := search(nil); len(got) != 4 || got[0] != 1 || got[1] != 2 || got[2] != 4 || got[3] != 3 {
+		t.Errorf("without ranking: IDs = %v, want [1 2 4 3] by raw score", got)
+	}
+	// The test file drops to 0.45 and cmd/ rises to 0.75.
+	if got := search(ranking); len(got) != 4 || got[0] != 2 || got[1] != 3 || got[2] != 4 || got[3] != 1 {
+		t.Errorf("with ranking: IDs = %v, want [2 3 4 1]", got)
+	}
+}
+
+func TestLoadRanking(t *testing.T) {
+	if r, err := LoadRanking(t.TempDir()); err != nil || r != nil {
+		t.Errorf("LoadRanking without a file = %v, %v; want nil, nil", r, err)
+	}
+
+	r, err := LoadRanking(writeRanking(t, `weights:
+  "cmd/**/*_test.go": 0.2
+  "cmd/**": 2
+`))
+	if err != nil {
+		t.Fatalf("LoadRanking: %v", err)
+	}
+	if w := r.Weight("cmd/carto/main_test.go"); w != 0.2 {
+		t.Errorf("Weight(cmd test) = %v, want the first matching glob's 0.2", w)
+	}
+	if w := r.Weight("cmd/carto/main.go"); w != 2 {
+		t.Errorf("Weight(cmd/carto/main.go) = %v, want 2", w)
+	}
+	if w := r.Weight("internal/api/handler.go"); w != 1 {
+		t.Errorf("Weight(unmatched) = %v, want 1", w)
+	}
+	if w := r.Weight(filepath.Join(r.Root, "cmd", "carto", "main.go")); w != 2 {
+		t.Errorf("Weight(absolute path under the root) = %v, want 2", w)
+	}
+	if w := r.Weight("/elsewhere/cmd/carto/main.go"); w != 1 {
+		t.Errorf("Weight(absolute path outside the root) = %v, want 1", w)
+	}
+
+	if _, err := LoadRanking(writeRanking(t, "weights:\n  \"**\": -1\n")); err == nil {
+		t.Error("LoadRanking accepted a negative weight")
+	}
+}
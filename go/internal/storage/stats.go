@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ProjectStats summarizes how much analysis is stored for a project.
+type ProjectStats struct {
+	ModulesAnalyzed int  `json:"modules_analyzed"` // modules with stored zones or intent
+	Atoms           int  `json:"atoms"`
+	Zones           int  `json:"zones"`
+	WiringEdges     int  `json:"wiring_edges"`
+	Blueprint       bool `json:"blueprint"`
+	Patterns        int  `json:"patterns"`
+}
+
+// Stats pages through the project's memories once and counts its stored
+// analysis. Zones, wiring and patterns are counted from the most recent
+// entry of each layer; atoms are counted by distinct atom tag, so an atom
+// split across several memories counts once.
+func (s *Store) Stats() (*ProjectStats, error) {
+	latest := make(map[string]map[string]string) // module -> layer -> text
+	atoms := make(map[string]bool)
+	err := s.listProject(func(page []SearchResult) {
+		for _, r := range page {
+			project, module, layer, ok := ParseSourceTagIn(s.namespace, r.Source)
+			if !ok || project != s.project || isGraphEntry(r.Source) {
+				continue
+			}
+			if layer == LayerAtoms && strings.HasPrefix(r.Source, s.sourceTag(module, LayerAtoms)+"/") {
+				atoms[r.Source] = true
+				continue
+			}
+			if latest[module] == nil {
+				latest[module] = make(map[string]string)
+			}
+			latest[module][layer] = r.Text
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &ProjectStats{Atoms: len(atoms)}
+	for module, layers := range latest {
+		if module == "_system" {
+			stats.Blueprint = strings.TrimSpace(layers[LayerBlueprint]) != ""
+			stats.Patterns = jsonLen(layers[LayerPatterns])
+			continue
+		}
+		_, hasZones := layers[LayerZones]
+		_, hasIntent := layers[LayerIntent]
+		if hasZones || hasIntent {
+			stats.ModulesAnalyzed++
+		}
+		stats.Zones += jsonLen(layers[LayerZones])
+		stats.WiringEdges += jsonLen(layers[LayerWiring])
+	}
+	return stats, nil
+}
+
+// jsonLen returns the length of a JSON array, or 0 for anything else.
+func jsonLen(text string) int {
+	var items []json.RawMessage
+	if json.Unmarshal([]byte(text), &items) != nil {
+		return 0
+	}
+	return len(items)
+}
@@ -0,0 +1,58 @@
+package storage
+
+import "testing"
+
+func TestStats_CountsStoredAnalysis(t *testing.T) {
+	mem := newMockMemories()
+	store := NewStore(mem, "proj")
+
+	// api is fully analyzed; its zones were re-stored, so only the latest
+	// entry counts.
+	store.StoreAtoms("api", "2 atoms", []AtomEntry{
+		{Key: "handler.go:10", Text: "func Handle()"},
+		{Key: "router.go:3", Text: "func Route()"},
+	})
+	store.StoreLayer("api", LayerZones, `[{"name": "old"}]`)
+	store.StoreLayer("api", LayerZones, `[{"name": "http"}, {"name": "auth"}]`)
+	store.StoreLayer("api", LayerWiring, `[{"from": "a", "to": "b"}, {"from": "b", "to": "c"}, {"from": "c", "to": "d"}]`)
+	store.StoreLayer("api", LayerIntent, "Serves HTTP.")
+
+	// db only has an intent and one atom.
+	store.StoreAtoms("db", "1 atom", []AtomEntry{{Key: "db.go:1", Text: "func Open()"}})
+	store.StoreLayer("db", LayerIntent, "Wraps storage.")
+
+	// web has atoms but no analysis.
+	store.StoreAtoms("web", "1 atom", []AtomEntry{{Key: "app.js:1", Text: "function app()"}})
+
+	store.StoreLayer("_system", LayerBlueprint, "# Blueprint")
+	store.StoreLayer("_system", LayerPatterns, `["repository", "middleware"]`)
+
+	// Another project's memories are ignored.
+	NewStore(mem, "other").StoreLayer("x", LayerZones, `[{"name": "z"}]`)
+
+	stats, err := store.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	want := ProjectStats{
+		ModulesAnalyzed: 2,
+		Atoms:           4,
+		Zones:           2,
+		WiringEdges:     3,
+		Blueprint:       true,
+		Patterns:        2,
+	}
+	if *stats != want {
+		t.Errorf("Stats = %+v, want %+v", *stats, want)
+	}
+}
+
+func TestStats_EmptyProject(t *testing.T) {
+	stats, err := NewStore(newMockMemories(), "proj").Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if *stats != (ProjectStats{}) {
+		t.Errorf("Stats = %+v, want zero", *stats)
+	}
+}
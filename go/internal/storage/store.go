@@ -3,6 +3,10 @@ package storage
 import (
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 )
 
 // Layer constants for tagging in Memories.
@@ -12,6 +16,7 @@ const (
 	LayerSignals   = "signals"   // Layer 1c
 	LayerWiring    = "wiring"    // Layer 2
 	LayerZones     = "zones"     // Layer 3
+	LayerIntent    = "intent"    // Layer 3: module intent
 	LayerBlueprint = "blueprint" // Layer 4
 	LayerPatterns  = "patterns"  // Layer 5
 )
@@ -23,6 +28,7 @@ var allLayers = []string{
 	LayerSignals,
 	LayerWiring,
 	LayerZones,
+	LayerIntent,
 	LayerBlueprint,
 	LayerPatterns,
 }
@@ -58,57 +64,276 @@ type MemoriesAPI interface {
 	Count(sourcePrefix string) (int, error)
 }
 
+// DefaultNamespace is the source tag prefix used when none is configured.
+const DefaultNamespace = "carto"
+
 // Store provides domain-specific Memories storage for carto layers.
 type Store struct {
-	memories MemoriesAPI
-	project  string
+	backends      Backends
+	project       string
+	namespace     string
+	compressAbove int      // see SetCompression; 0 disables
+	ranking       *Ranking // see SetRanking; nil leaves scores unchanged
+	truncated     atomic.Int64
 }
 
-// NewStore creates a Store scoped to a project name.
-func NewStore(memories MemoriesAPI, project string) *Store {
-	return &Store{memories: memories, project: project}
+// NewStore creates a Store scoped to a project name. An optional namespace
+// replaces DefaultNamespace as the first segment of every source tag, so
+// several teams or tools can share one Memories instance.
+func NewStore(memories MemoriesAPI, project string, namespace ...string) *Store {
+	ns := DefaultNamespace
+	if len(namespace) > 0 && namespace[0] != "" {
+		ns = strings.Trim(namespace[0], "/")
+	}
+	return &Store{backends: Backends{Default: memories}, project: project, namespace: ns}
+}
+
+// ProjectPrefix returns the source prefix shared by every entry of a
+// project: {namespace}/{project}/. An empty namespace means DefaultNamespace.
+func ProjectPrefix(namespace, project string) string {
+	if namespace = strings.Trim(namespace, "/"); namespace == "" {
+		namespace = DefaultNamespace
+	}
+	return namespace + "/" + project + "/"
+}
+
+// projectPrefix returns the source prefix of the Store's project.
+func (s *Store) projectPrefix() string {
+	return ProjectPrefix(s.namespace, s.project)
 }
 
 // sourceTag returns the Memories source tag for a module and layer.
-// Format: carto/{project}/{module}/layer:{layer}
+// Format: {namespace}/{project}/{module}/layer:{layer}
 func (s *Store) sourceTag(module, layer string) string {
-	return fmt.Sprintf("carto/%s/%s/layer:%s", s.project, module, layer)
+	return fmt.Sprintf("%s%s/layer:%s", s.projectPrefix(), module, layer)
+}
+
+// atomTag returns the source tag of a single atom. It extends the atoms
+// layer tag, so a prefix listing of the layer returns every atom.
+// Format: {namespace}/{project}/{module}/layer:atoms/{key}
+func (s *Store) atomTag(module, key string) string {
+	return s.sourceTag(module, LayerAtoms) + "/" + key
+}
+
+// ParseSourceTag splits a source tag of the form
+// carto/{project}/{module}/layer:{layer} into its parts. Module names may
+// contain slashes; anything after the layer name (such as an atom key) is
+// ignored. ok is false for sources not written by Store.
+func ParseSourceTag(source string) (project, module, layer string, ok bool) {
+	return ParseSourceTagIn(DefaultNamespace, source)
+}
+
+// ParseSourceTagIn is ParseSourceTag for tags under a custom namespace.
+func ParseSourceTagIn(namespace, source string) (project, module, layer string, ok bool) {
+	if namespace = strings.Trim(namespace, "/"); namespace == "" {
+		namespace = DefaultNamespace
+	}
+	rest, found := strings.CutPrefix(source, namespace+"/")
+	if !found {
+		return "", "", "", false
+	}
+	project, rest, found = strings.Cut(rest, "/")
+	if !found {
+		return "", "", "", false
+	}
+	i := strings.Index(rest, "/layer:")
+	if i <= 0 {
+		return "", "", "", false
+	}
+	layer, _, _ = strings.Cut(rest[i+len("/layer:"):], "/")
+	return project, rest[:i], layer, true
+}
+
+// Explanation describes where a search result came from and how it was
+// scored. It backs `carto query --explain` and the API's explain option.
+type Explanation struct {
+	Project      string   `json:"project,omitempty"`
+	Module       string   `json:"module,omitempty"`
+	Layer        string   `json:"layer,omitempty"`
+	Tier         Tier     `json:"tier,omitempty"` // smallest tier that retrieves Layer
+	VectorScore  *float64 `json:"vector_score,omitempty"`
+	LexicalScore *float64 `json:"lexical_score,omitempty"`
+}
+
+// Explain parses a result's source tag and collects its score components.
+func Explain(r SearchResult) Explanation {
+	return ExplainIn(DefaultNamespace, r)
+}
+
+// ExplainIn is Explain for results stored under a custom namespace.
+func ExplainIn(namespace string, r SearchResult) Explanation {
+	e := Explanation{VectorScore: r.VectorScore, LexicalScore: r.LexicalScore}
+	if project, module, layer, ok := ParseSourceTagIn(namespace, r.Source); ok {
+		e.Project, e.Module, e.Layer = project, module, layer
+		e.Tier = layerTier(layer)
+	}
+	return e
+}
+
+// Includes reports whether retrieving tier t returns layer.
+func (t Tier) Includes(layer string) bool {
+	for _, l := range tierLayers[t] {
+		if l == layer {
+			return true
+		}
+	}
+	return false
+}
+
+// layerTier returns the smallest tier whose retrieval includes layer, or ""
+// when no tier does (e.g. patterns).
+func layerTier(layer string) Tier {
+	for _, tier := range []Tier{TierMini, TierStandard, TierFull} {
+		if tier.Includes(layer) {
+			return tier
+		}
+	}
+	return ""
 }
 
 // StoreLayer stores content in Memories with the appropriate source tag.
-// Content exceeding 49000 chars is truncated at the last newline boundary.
+// Content exceeding 49000 chars is truncated at the last newline boundary;
+// the stored text then ends with a "...[truncated N chars]" note and the
+// memory carries truncated:true metadata. Storing the same layer again
+// replaces it (see clearSources).
 func (s *Store) StoreLayer(module, layer, content string) error {
-	if len(content) > maxContentLen {
-		log.Printf("storage: warning: content truncated from %d to %d chars for source %s", len(content), maxContentLen, s.sourceTag(module, layer))
-		content = truncate(content, maxContentLen)
+	m := s.layerMemory(module, layer, content)
+	backend := s.backendFor(module, layer)
+	if err := clearSources(backend, m.Source, []Memory{m}); err != nil {
+		return err
 	}
-	_, err := s.memories.AddMemory(Memory{
-		Text:   content,
-		Source: s.sourceTag(module, layer),
-	})
+	_, err := backend.AddMemory(m)
 	return err
 }
 
+// layerMemory builds the memory StoreLayer stores for content.
+func (s *Store) layerMemory(module, layer, content string) Memory {
+	m := Memory{Source: s.sourceTag(module, layer)}
+	if len(content) > maxContentLen {
+		log.Printf("storage: warning: content truncated from %d to %d chars for source %s", len(content), maxContentLen, m.Source)
+	}
+	content, m.Metadata = s.truncateMarked(content)
+	m.Text = s.encode(content)
+	m.ContentID = ContentID(m.Source, m.Text)
+	return m
+}
+
 // StoreBatch stores multiple entries for a layer. Each entry gets the same
 // source tag. Useful for storing individual atoms or other granular data.
+// Oversized entries are truncated and marked as in StoreLayer.
 func (s *Store) StoreBatch(module, layer string, entries []string) error {
 	tag := s.sourceTag(module, layer)
 	memories := make([]Memory, len(entries))
 	for i, entry := range entries {
+		text, meta := s.truncateMarked(entry)
 		memories[i] = Memory{
-			Text:   truncate(entry, maxContentLen),
-			Source: tag,
+			Text:     s.encode(text),
+			Source:   tag,
+			Metadata: meta,
+		}
+		memories[i].ContentID = ContentID(tag, memories[i].Text)
+	}
+	backend := s.backendFor(module, layer)
+	if err := clearSources(backend, tag, memories); err != nil {
+		return err
+	}
+	return backend.AddBatch(memories)
+}
+
+// Truncated returns how many entries this Store has truncated to fit the
+// Memories content limit.
+func (s *Store) Truncated() int {
+	return int(s.truncated.Load())
+}
+
+// truncateMarked fits content to maxContentLen. Content that has to be cut
+// gets a trailing note saying how much was dropped, is counted in
+// Truncated, and comes back with truncated:true metadata for its memory.
+func (s *Store) truncateMarked(content string) (string, map[string]any) {
+	if len(content) <= maxContentLen {
+		return content, nil
+	}
+	s.truncated.Add(1)
+	return truncateWithNote(content, maxContentLen), map[string]any{"truncated": true}
+}
+
+// AtomEntry is one atom to be stored under its own source tag.
+type AtomEntry struct {
+	Key  string // unique within the module, e.g. "internal/api/handler.go:42"
+	Text string
+
+	// Code is the atom's original source, stored after Text so results
+	// can show it (see SplitAtomCode); CodeLine is its first line number.
+	Code     string
+	CodeLine int
+}
+
+// atomCodeMarker introduces the original source in an atom memory:
+// "\n--- source (line 42) ---\n" followed by the code.
+const atomCodeMarker = "\n--- source (line "
+
+// text returns the memory text of the entry, with its code appended.
+func (a AtomEntry) text() string {
+	if a.Code == "" {
+		return a.Text
+	}
+	return fmt.Sprintf("%s%s%d) ---\n%s", strings.TrimRight(a.Text, "\n"), atomCodeMarker, a.CodeLine, a.Code)
+}
+
+// SplitAtomCode separates an atom memory's text from the original source
+// stored with it, returning the text, the code and the code's first line.
+// Text without stored source is returned whole, with "" and 0.
+func SplitAtomCode(text string) (body, code string, line int) {
+	i := strings.Index(text, atomCodeMarker)
+	if i < 0 {
+		return text, "", 0
+	}
+	header, code, ok := strings.Cut(text[i+len(atomCodeMarker):], ") ---\n")
+	if !ok {
+		return text, "", 0
+	}
+	line, err := strconv.Atoi(header)
+	if err != nil {
+		return text, "", 0
+	}
+	return text[:i], code, line
+}
+
+// StoreAtoms stores a module's atoms layer: a short summary memory under
+// the layer tag plus one memory per atom under atomTag. Unlike StoreLayer,
+// nothing is truncated: an atom longer than the content limit is split
+// into several memories sharing its tag.
+func (s *Store) StoreAtoms(module, summary string, atoms []AtomEntry) error {
+	head := s.layerMemory(module, LayerAtoms, summary)
+	memories := make([]Memory, 0, len(atoms))
+	for _, a := range atoms {
+		tag := s.atomTag(module, a.Key)
+		for _, part := range split(a.text(), maxContentLen) {
+			text := s.encode(part)
+			memories = append(memories, Memory{Text: text, Source: tag, ContentID: ContentID(tag, text)})
 		}
 	}
-	return s.memories.AddBatch(memories)
+
+	backend := s.backendFor(module, LayerAtoms)
+	if err := clearSources(backend, head.Source, append([]Memory{head}, memories...)); err != nil {
+		return err
+	}
+	if _, err := backend.AddMemory(head); err != nil {
+		return err
+	}
+	if len(memories) == 0 {
+		return nil
+	}
+	return backend.AddBatch(memories)
 }
 
 // RetrieveByTier retrieves context at the requested tier level.
-// Returns a map keyed by layer name containing the search results for each layer.
+// Returns a map keyed by layer name containing the search results for each
+// layer, each read from the backend that layer is routed to.
 //
 //   - mini: zones + blueprint
-//   - standard: mini + atoms + wiring
-//   - full: standard + history + signals
+//   - standard: mini + atom summary + wiring
+//   - full: standard + individual atoms + history + signals
 func (s *Store) RetrieveByTier(module string, tier Tier) (map[string][]SearchResult, error) {
 	layers, ok := tierLayers[tier]
 	if !ok {
@@ -121,29 +346,229 @@ func (s *Store) RetrieveByTier(module string, tier Tier) (map[string][]SearchRes
 		if err != nil {
 			return nil, fmt.Errorf("retrieve layer %s: %w", layer, err)
 		}
+		if layer == LayerAtoms && tier != TierFull {
+			results = exactSource(results, s.sourceTag(module, layer))
+		}
 		result[layer] = results
 	}
 	return result, nil
 }
 
-// RetrieveLayer retrieves all entries for a specific layer using ListBySource.
+// RetrieveLayer retrieves all entries for a specific layer using
+// ListBySource, paging until the layer is exhausted. For the atoms layer
+// this includes the module summary and every individual atom; the single
+// edge and zone memories of the wiring and zones layers are left out (see
+// RetrieveGraph).
 func (s *Store) RetrieveLayer(module, layer string) ([]SearchResult, error) {
-	return s.memories.ListBySource(s.sourceTag(module, layer), 0, 0)
+	const pageSize = 500
+	backend := s.backendFor(module, layer)
+	var all []SearchResult
+	for offset := 0; ; offset += pageSize {
+		page, err := backend.ListBySource(s.sourceTag(module, layer), pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range page {
+			if !isGraphEntry(r.Source) {
+				all = append(all, r)
+			}
+		}
+		if len(page) < pageSize {
+			return all, nil
+		}
+	}
+}
+
+// RetrieveGraph retrieves the single edge or zone memories stored beside
+// module's wiring or zones layer by StoreWiring and StoreZones.
+func (s *Store) RetrieveGraph(module, layer string) ([]SearchResult, error) {
+	const pageSize = 500
+	backend := s.backendFor(module, layer)
+	var all []SearchResult
+	for offset := 0; ; offset += pageSize {
+		page, err := backend.ListBySource(s.sourceTag(module, layer)+"/", pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range page {
+			if isGraphEntry(r.Source) {
+				all = append(all, r)
+			}
+		}
+		if len(page) < pageSize {
+			return all, nil
+		}
+	}
+}
+
+// exactSource keeps only results whose source is exactly tag, dropping
+// entries stored under longer tags such as individual atoms.
+func exactSource(results []SearchResult, tag string) []SearchResult {
+	var kept []SearchResult
+	for _, r := range results {
+		if r.Source == tag {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// RetrieveLayerAllModules retrieves every entry of a layer across all
+// modules of the project, keyed by module name, leaving out single edge
+// and zone memories as RetrieveLayer does. It pages through the
+// project's memories, so prefer RetrieveLayer when the module is known.
+func (s *Store) RetrieveLayerAllModules(layer string) (map[string][]SearchResult, error) {
+	byModule := make(map[string][]SearchResult)
+	err := s.listProject(func(page []SearchResult) {
+		for _, r := range page {
+			project, module, l, ok := ParseSourceTagIn(s.namespace, r.Source)
+			if !ok || project != s.project || l != layer || isGraphEntry(r.Source) {
+				continue
+			}
+			byModule[module] = append(byModule[module], r)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return byModule, nil
+}
+
+// ListModules returns the sorted names of every module with at least one
+// stored entry for the project, including system scopes like "_system".
+func (s *Store) ListModules() ([]string, error) {
+	seen := make(map[string]bool)
+	err := s.listProject(func(page []SearchResult) {
+		for _, r := range page {
+			if project, module, _, ok := ParseSourceTagIn(s.namespace, r.Source); ok && project == s.project {
+				seen[module] = true
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]string, 0, len(seen))
+	for m := range seen {
+		modules = append(modules, m)
+	}
+	sort.Strings(modules)
+	return modules, nil
 }
 
 // ClearModule deletes all entries for a module across all layers
-// using a single bulk delete with the module prefix.
+// using a single bulk delete with the module prefix on each backend.
 func (s *Store) ClearModule(module string) error {
-	prefix := fmt.Sprintf("carto/%s/%s/", s.project, module)
-	_, err := s.memories.DeleteBySource(prefix)
-	return err
+	return s.deleteBySource(s.projectPrefix() + module + "/")
 }
 
 // ClearProject deletes all entries for the entire project.
 func (s *Store) ClearProject() error {
-	prefix := fmt.Sprintf("carto/%s/", s.project)
-	_, err := s.memories.DeleteBySource(prefix)
-	return err
+	return s.deleteBySource(s.projectPrefix())
+}
+
+// deleteBySource deletes prefix from every backend, stopping at the first
+// failure.
+func (s *Store) deleteBySource(prefix string) error {
+	for _, backend := range s.backends.All() {
+		if _, err := backend.DeleteBySource(prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upserter is implemented by backends that replace a memory whose
+// ContentID they already hold instead of storing it twice.
+type upserter interface {
+	Upserts() bool
+}
+
+// memoryDeleter is implemented by backends that can delete a single
+// memory by ID.
+type memoryDeleter interface {
+	DeleteMemory(id int) error
+}
+
+// clearSources makes storing memories again idempotent on a backend that
+// does not upsert: it first deletes what the backend already holds under
+// the memories' exact source tags, all of which must lie under prefix.
+// When nothing else lies under prefix, that is one prefix delete; otherwise
+// the matching memories are deleted one by one.
+func clearSources(backend MemoriesAPI, prefix string, memories []Memory) error {
+	if u, ok := backend.(upserter); ok && u.Upserts() {
+		return nil
+	}
+	sources := make(map[string]bool, len(memories))
+	for _, m := range memories {
+		sources[m.Source] = true
+	}
+
+	const pageSize = 500
+	var ids []int
+	exclusive := true
+	for offset := 0; ; offset += pageSize {
+		page, err := backend.ListBySource(prefix, pageSize, offset)
+		if err != nil {
+			return fmt.Errorf("list %s before storing: %w", prefix, err)
+		}
+		for _, r := range page {
+			if sources[r.Source] {
+				ids = append(ids, r.ID)
+			} else {
+				exclusive = false
+			}
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if exclusive {
+		_, err := backend.DeleteBySource(prefix)
+		return err
+	}
+	d, ok := backend.(memoryDeleter)
+	if !ok {
+		log.Printf("storage: warning: cannot replace %d memories under %s on a backend without per-memory delete; they may be stored twice", len(ids), prefix)
+		return nil
+	}
+	for _, id := range ids {
+		if err := d.DeleteMemory(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// split breaks content into pieces of at most maxLen characters, cutting
+// at newline boundaries where possible.
+func split(content string, maxLen int) []string {
+	var parts []string
+	for len(content) > maxLen {
+		part := truncate(content, maxLen)
+		parts = append(parts, part)
+		content = strings.TrimPrefix(content[len(part):], "\n")
+	}
+	return append(parts, content)
+}
+
+// truncationNoteReserve is the room kept for the note truncateWithNote
+// appends, which fits any realistic dropped-character count.
+const truncationNoteReserve = 40
+
+// truncateWithNote shortens content like truncate, then appends a
+// "\n...[truncated N chars]" note so readers know the text is incomplete.
+// The result is at most maxLen characters.
+func truncateWithNote(content string, maxLen int) string {
+	if len(content) <= maxLen {
+		return content
+	}
+	cut := truncate(content, maxLen-truncationNoteReserve)
+	return cut + fmt.Sprintf("\n...[truncated %d chars]", len(content)-len(cut))
 }
 
 // truncate shortens content to at most maxLen characters. It cuts at the last
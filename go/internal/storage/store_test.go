@@ -38,15 +38,38 @@ func (m *mockMemories) Search(query string, opts SearchOptions) ([]SearchResult,
 }
 
 func (m *mockMemories) ListBySource(source string, limit, offset int) ([]SearchResult, error) {
-	if results, ok := m.results[source]; ok {
-		return results, nil
+	results, ok := m.results[source]
+	if !ok {
+		// Like the real API, match stored memories by source prefix.
+		for i, mem := range m.memories {
+			if strings.HasPrefix(mem.Source, source) {
+				results = append(results, SearchResult{ID: i + 1, Text: mem.Text, Source: mem.Source})
+			}
+		}
 	}
-	return nil, nil
+	if limit > 0 {
+		if offset >= len(results) {
+			return nil, nil
+		}
+		results = results[offset:]
+		if len(results) > limit {
+			results = results[:limit]
+		}
+	}
+	return results, nil
 }
 
 func (m *mockMemories) DeleteBySource(prefix string) (int, error) {
 	m.deleted = append(m.deleted, prefix)
-	return 0, nil
+	kept := m.memories[:0]
+	for _, mem := range m.memories {
+		if !strings.HasPrefix(mem.Source, prefix) {
+			kept = append(kept, mem)
+		}
+	}
+	n := len(m.memories) - len(kept)
+	m.memories = kept
+	return n, nil
 }
 
 func (m *mockMemories) Count(sourcePrefix string) (int, error) {
@@ -132,6 +155,64 @@ func TestStoreLayer_Truncation(t *testing.T) {
 	}
 }
 
+func TestStoreLayer_TruncationMarker(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+
+	long := strings.Repeat("a", 60000) // no newlines: hard cut
+	if err := s.StoreLayer("mod", LayerBlueprint, "short"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.StoreLayer("mod", LayerPatterns, long); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	short := mock.memories[0]
+	if short.Metadata != nil || strings.Contains(short.Text, "[truncated") {
+		t.Errorf("untruncated memory was marked: metadata=%v text=%q", short.Metadata, short.Text)
+	}
+
+	cut := mock.memories[1]
+	if cut.Metadata["truncated"] != true {
+		t.Errorf("truncated memory metadata = %v, want truncated:true", cut.Metadata)
+	}
+	if len(cut.Text) > maxContentLen {
+		t.Errorf("stored %d chars, want <= %d", len(cut.Text), maxContentLen)
+	}
+	keptLen := strings.Index(cut.Text, "\n")
+	if keptLen < 0 || strings.Trim(cut.Text[:keptLen], "a") != "" {
+		t.Fatalf("stored text should be the kept content followed by a note, got %q...", cut.Text[:80])
+	}
+	wantNote := fmt.Sprintf("\n...[truncated %d chars]", len(long)-keptLen)
+	if !strings.HasSuffix(cut.Text, wantNote) {
+		t.Errorf("stored text ends %q, want note %q", cut.Text[keptLen:], wantNote)
+	}
+	if got := s.Truncated(); got != 1 {
+		t.Errorf("Truncated() = %d, want 1", got)
+	}
+}
+
+func TestStoreBatch_TruncationMarker(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+
+	entries := []string{"small", strings.Repeat("line\n", 12000)}
+	if err := s.StoreBatch("mod", LayerSignals, entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	batch := mock.batches[0]
+	if batch[0].Metadata != nil || batch[0].Text != "small" {
+		t.Errorf("small entry changed: %+v", batch[0])
+	}
+	if batch[1].Metadata["truncated"] != true || !strings.Contains(batch[1].Text, "...[truncated ") {
+		t.Errorf("oversized entry not marked: metadata=%v", batch[1].Metadata)
+	}
+	if got := s.Truncated(); got != 1 {
+		t.Errorf("Truncated() = %d, want 1", got)
+	}
+}
+
 func TestStoreBatch(t *testing.T) {
 	mock := newMockMemories()
 	s := NewStore(mock, "proj")
@@ -163,6 +244,145 @@ func TestStoreBatch(t *testing.T) {
 	}
 }
 
+func TestStoreAtoms_NothingDropped(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+
+	// 300 atoms of ~400 chars each: far more than one memory can hold.
+	var entries []AtomEntry
+	total := 0
+	for i := 0; i < 300; i++ {
+		text := fmt.Sprintf("atom %d\n%s", i, strings.Repeat("y", 400))
+		total += len(text)
+		entries = append(entries, AtomEntry{Key: fmt.Sprintf("big.go:%d", i*10+1), Text: text})
+	}
+	if total <= maxContentLen {
+		t.Fatalf("test atoms total %d chars; want more than %d", total, maxContentLen)
+	}
+
+	if err := s.StoreAtoms("big", "Module big: 300 atoms", entries); err != nil {
+		t.Fatalf("StoreAtoms: %v", err)
+	}
+
+	got, err := s.RetrieveLayer("big", LayerAtoms)
+	if err != nil {
+		t.Fatalf("RetrieveLayer: %v", err)
+	}
+	if len(got) != 301 {
+		t.Fatalf("expected summary + 300 atoms, got %d", len(got))
+	}
+
+	byTag := make(map[string]string)
+	for _, r := range got {
+		byTag[r.Source] = r.Text
+	}
+	if byTag["carto/proj/big/layer:atoms"] != "Module big: 300 atoms" {
+		t.Errorf("summary memory missing or wrong: %q", byTag["carto/proj/big/layer:atoms"])
+	}
+	for _, e := range entries {
+		if text := byTag["carto/proj/big/layer:atoms/"+e.Key]; text != e.Text {
+			t.Errorf("atom %s not stored intact (got %d chars, want %d)", e.Key, len(text), len(e.Text))
+		}
+	}
+}
+
+func TestStoreAtoms_SplitsOversizedAtom(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+
+	line := strings.Repeat("z", 99) + "\n"
+	huge := strings.Repeat(line, 1200) // 120k chars
+	if err := s.StoreAtoms("m", "summary", []AtomEntry{{Key: "huge.go:1", Text: huge}}); err != nil {
+		t.Fatalf("StoreAtoms: %v", err)
+	}
+
+	var parts []string
+	for _, mem := range mock.memories {
+		if mem.Source != "carto/proj/m/layer:atoms/huge.go:1" {
+			continue
+		}
+		if len(mem.Text) > maxContentLen {
+			t.Errorf("part exceeds content limit: %d chars", len(mem.Text))
+		}
+		parts = append(parts, mem.Text)
+	}
+	if len(parts) < 3 {
+		t.Errorf("expected the atom split into >= 3 parts, got %d", len(parts))
+	}
+	// Parts are cut at newlines, which split drops.
+	if joined := strings.Join(parts, "\n"); joined != huge {
+		t.Errorf("split parts do not reassemble the atom (%d vs %d chars)", len(joined), len(huge))
+	}
+}
+
+func TestStoreAtoms_KeepsSourceCode(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+
+	code := "func Login(u User) error {\n\treturn check(u)\n}\n"
+	if err := s.StoreAtoms("auth", "summary", []AtomEntry{
+		{Key: "login.go:12", Text: "Login (function) in login.go:12-14\nSummary: logs in\n", Code: code, CodeLine: 12},
+		{Key: "logout.go:3", Text: "Logout (function)"},
+	}); err != nil {
+		t.Fatalf("StoreAtoms: %v", err)
+	}
+
+	results, err := s.RetrieveLayer("auth", LayerAtoms)
+	if err != nil {
+		t.Fatalf("RetrieveLayer: %v", err)
+	}
+	found := 0
+	for _, r := range results {
+		body, gotCode, line := SplitAtomCode(r.Text)
+		switch r.Source {
+		case "carto/proj/auth/layer:atoms/login.go:12":
+			found++
+			if gotCode != code || line != 12 {
+				t.Errorf("login code = %q (line %d), want %q (line 12)", gotCode, line, code)
+			}
+			if body != "Login (function) in login.go:12-14\nSummary: logs in" {
+				t.Errorf("login body = %q", body)
+			}
+		case "carto/proj/auth/layer:atoms/logout.go:3":
+			found++
+			if gotCode != "" || body != "Logout (function)" {
+				t.Errorf("atom without source split into %q / %q", body, gotCode)
+			}
+		}
+	}
+	if found != 2 {
+		t.Errorf("found %d of 2 atoms in %+v", found, results)
+	}
+}
+
+func TestRetrieveByTier_AtomsSummaryVsFull(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+
+	if err := s.StoreAtoms("api", "summary", []AtomEntry{
+		{Key: "a.go:1", Text: "atom a"},
+		{Key: "b.go:1", Text: "atom b"},
+	}); err != nil {
+		t.Fatalf("StoreAtoms: %v", err)
+	}
+
+	standard, err := s.RetrieveByTier("api", TierStandard)
+	if err != nil {
+		t.Fatalf("standard: %v", err)
+	}
+	if got := standard[LayerAtoms]; len(got) != 1 || got[0].Text != "summary" {
+		t.Errorf("standard tier atoms = %+v, want only the summary", got)
+	}
+
+	full, err := s.RetrieveByTier("api", TierFull)
+	if err != nil {
+		t.Fatalf("full: %v", err)
+	}
+	if got := full[LayerAtoms]; len(got) != 3 {
+		t.Errorf("full tier atoms = %d entries, want summary + 2 atoms", len(got))
+	}
+}
+
 func TestRetrieveByTier_Mini(t *testing.T) {
 	mock := newMockMemories()
 	s := NewStore(mock, "proj")
@@ -338,3 +558,145 @@ func TestTruncate(t *testing.T) {
 		}
 	})
 }
+
+func TestParseSourceTag(t *testing.T) {
+	project, module, layer, ok := ParseSourceTag("carto/proj/internal/api/layer:atoms")
+	if !ok || project != "proj" || module != "internal/api" || layer != "atoms" {
+		t.Errorf("got (%q, %q, %q, %v)", project, module, layer, ok)
+	}
+	project, module, layer, ok = ParseSourceTag("carto/proj/api/layer:atoms/internal/api/handler.go:42")
+	if !ok || project != "proj" || module != "api" || layer != "atoms" {
+		t.Errorf("atom tag: got (%q, %q, %q, %v)", project, module, layer, ok)
+	}
+	for _, bad := range []string{"src/a", "carto/proj", "carto/proj/mod", "carto/proj/layer:atoms"} {
+		if _, _, _, ok := ParseSourceTag(bad); ok {
+			t.Errorf("ParseSourceTag(%q) should fail", bad)
+		}
+	}
+}
+
+func TestNamespace_TagsAndDeletes(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj", "team-a/carto")
+
+	if err := s.StoreLayer("auth", LayerZones, "[]"); err != nil {
+		t.Fatalf("StoreLayer: %v", err)
+	}
+	if err := s.StoreAtoms("auth", "summary", []AtomEntry{{Key: "a.go:1", Text: "atom"}}); err != nil {
+		t.Fatalf("StoreAtoms: %v", err)
+	}
+	for _, m := range mock.memories {
+		if !strings.HasPrefix(m.Source, "team-a/carto/proj/auth/layer:") {
+			t.Errorf("source %q does not use the namespace", m.Source)
+		}
+	}
+
+	results, err := s.RetrieveByTier("auth", TierMini)
+	if err != nil {
+		t.Fatalf("RetrieveByTier: %v", err)
+	}
+	if len(results[LayerZones]) != 1 {
+		t.Errorf("expected the namespaced zones entry, got %v", results[LayerZones])
+	}
+	modules, err := s.ListModules()
+	if err != nil || fmt.Sprint(modules) != "[auth]" {
+		t.Errorf("ListModules = %v, %v", modules, err)
+	}
+
+	// The default namespace does not see the team's entries.
+	if results, _ := NewStore(mock, "proj").RetrieveLayer("auth", LayerZones); len(results) != 0 {
+		t.Errorf("default namespace retrieved %d namespaced entries", len(results))
+	}
+
+	s.ClearModule("auth")
+	s.ClearProject()
+	if fmt.Sprint(mock.deleted) != "[team-a/carto/proj/auth/ team-a/carto/proj/]" {
+		t.Errorf("delete prefixes = %v", mock.deleted)
+	}
+}
+
+func TestParseSourceTagIn(t *testing.T) {
+	project, module, layer, ok := ParseSourceTagIn("team-a/carto", "team-a/carto/proj/api/layer:wiring")
+	if !ok || project != "proj" || module != "api" || layer != "wiring" {
+		t.Errorf("got (%q, %q, %q, %v)", project, module, layer, ok)
+	}
+	if _, _, _, ok := ParseSourceTagIn("team-a/carto", "carto/proj/api/layer:wiring"); ok {
+		t.Error("tag from another namespace should not parse")
+	}
+	if got := ProjectPrefix("", "proj"); got != "carto/proj/" {
+		t.Errorf("ProjectPrefix default = %q", got)
+	}
+}
+
+func TestExplain_ComponentScores(t *testing.T) {
+	vec, lex := 0.82, 0.31
+	e := Explain(SearchResult{
+		Source:       "carto/proj/api/layer:wiring",
+		Score:        0.9,
+		VectorScore:  &vec,
+		LexicalScore: &lex,
+	})
+	if e.Project != "proj" || e.Module != "api" || e.Layer != LayerWiring {
+		t.Errorf("unexpected origin: %+v", e)
+	}
+	if e.Tier != TierStandard {
+		t.Errorf("Tier = %q, want %q", e.Tier, TierStandard)
+	}
+	if e.VectorScore == nil || *e.VectorScore != vec || e.LexicalScore == nil || *e.LexicalScore != lex {
+		t.Errorf("component scores not carried: %+v", e)
+	}
+
+	plain := Explain(SearchResult{Source: "carto/proj/_system/layer:patterns"})
+	if plain.Tier != "" || plain.VectorScore != nil {
+		t.Errorf("expected no tier or components for patterns, got %+v", plain)
+	}
+}
+
+func TestStore_RestoringIdenticalContentIsIdempotent(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+
+	store := func() {
+		t.Helper()
+		if err := s.StoreLayer("auth", LayerWiring, "wiring"); err != nil {
+			t.Fatalf("StoreLayer: %v", err)
+		}
+		if err := s.StoreBatch("auth", LayerSignals, []string{"s1", "s2"}); err != nil {
+			t.Fatalf("StoreBatch: %v", err)
+		}
+		atoms := []AtomEntry{{Key: "a.go:1", Text: "func A"}, {Key: "a.go:10", Text: "func B"}}
+		if err := s.StoreAtoms("auth", "summary", atoms); err != nil {
+			t.Fatalf("StoreAtoms: %v", err)
+		}
+	}
+
+	store()
+	first := len(mock.memories)
+	if first != 6 {
+		t.Fatalf("expected 6 memories after the first store, got %d", first)
+	}
+	store() // e.g. a retry after a partial failure
+	if got := len(mock.memories); got != first {
+		t.Errorf("re-storing identical content: %d memories, want %d", got, first)
+	}
+	for _, m := range mock.memories {
+		if m.ContentID != ContentID(m.Source, m.Text) {
+			t.Errorf("memory %s has content ID %q", m.Source, m.ContentID)
+		}
+	}
+}
+
+// upsertingMemories is a mockMemories that upserts on ContentID.
+type upsertingMemories struct{ *mockMemories }
+
+func (u upsertingMemories) Upserts() bool { return true }
+
+func TestStore_UpsertingBackendSkipsDeletes(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(upsertingMemories{mock}, "proj")
+	s.StoreLayer("auth", LayerWiring, "wiring")
+	s.StoreLayer("auth", LayerWiring, "wiring")
+	if len(mock.deleted) != 0 {
+		t.Errorf("expected no deletes on an upserting backend, got %v", mock.deleted)
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrZoneNotFound is returned by ResolveZone when no module of the project
+// has a stored zone of the requested name.
+var ErrZoneNotFound = errors.New("zone not found")
+
+// ZoneScope is the part of a project one zone covers: the files it lists
+// and the modules that define it.
+type ZoneScope struct {
+	Name    string   `json:"name"`
+	Files   []string `json:"files"`
+	Modules []string `json:"modules"`
+}
+
+// ResolveZone reads the project's zones layer and returns the scope of the
+// zone called name, ignoring case. A zone defined by several modules
+// covers the files of all of them.
+func (s *Store) ResolveZone(name string) (*ZoneScope, error) {
+	byModule, err := s.RetrieveLayerAllModules(LayerZones)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &ZoneScope{Name: name}
+	for module, results := range byModule {
+		for _, r := range results {
+			var zones []struct {
+				Name  string   `json:"name"`
+				Files []string `json:"files"`
+			}
+			if json.Unmarshal([]byte(r.Text), &zones) != nil {
+				continue
+			}
+			for _, z := range zones {
+				if !strings.EqualFold(z.Name, name) {
+					continue
+				}
+				scope.Name = z.Name
+				scope.Files = append(scope.Files, z.Files...)
+				if !slices.Contains(scope.Modules, module) {
+					scope.Modules = append(scope.Modules, module)
+				}
+			}
+		}
+	}
+	if len(scope.Modules) == 0 {
+		return nil, ErrZoneNotFound
+	}
+	sort.Strings(scope.Files)
+	scope.Files = slices.Compact(scope.Files)
+	sort.Strings(scope.Modules)
+	return scope, nil
+}
+
+// Contains reports whether a search result falls within the zone. An atom
+// is inside when its file is one of the zone's files; a result of any other
+// layer (wiring, the atoms summary, ...) when its module defines the zone.
+// Sources not written by Store are outside.
+func (z *ZoneScope) Contains(namespace string, r SearchResult) bool {
+	_, module, layer, ok := ParseSourceTagIn(namespace, r.Source)
+	if !ok {
+		return false
+	}
+	if key := atomKey(r.Source, layer); layer == LayerAtoms && key != "" {
+		file := key
+		if i := strings.LastIndexByte(key, ':'); i > 0 {
+			file = key[:i]
+		}
+		return z.hasFile(file)
+	}
+	return slices.Contains(z.Modules, module)
+}
+
+// hasFile reports whether file is one of the zone's files. Zone files come
+// from the LLM, so a path that is a suffix of the other (at a directory
+// boundary) or a listed directory ("internal/auth/") also matches.
+func (z *ZoneScope) hasFile(file string) bool {
+	for _, f := range z.Files {
+		switch {
+		case f == file,
+			strings.HasSuffix(f, "/") && strings.HasPrefix(file, f),
+			strings.HasSuffix(file, "/"+f),
+			strings.HasSuffix(f, "/"+file):
+			return true
+		}
+	}
+	return false
+}
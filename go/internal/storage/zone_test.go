@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveZone(t *testing.T) {
+	mock := newMockMemories()
+	s := NewStore(mock, "proj")
+	s.StoreLayer("api", LayerZones, `[{"name": "Auth", "files": ["api/login.go"]}, {"name": "Search", "files": ["api/find.go"]}]`)
+	s.StoreLayer("web", LayerZones, `[{"name": "auth", "files": ["web/session/", "api/login.go"]}]`)
+
+	zone, err := s.ResolveZone("AUTH")
+	if err != nil {
+		t.Fatalf("ResolveZone: %v", err)
+	}
+	if want := []string{"api/login.go", "web/session/"}; !reflect.DeepEqual(zone.Files, want) {
+		t.Errorf("files = %v, want %v", zone.Files, want)
+	}
+	if want := []string{"api", "web"}; !reflect.DeepEqual(zone.Modules, want) {
+		t.Errorf("modules = %v, want %v", zone.Modules, want)
+	}
+
+	if _, err := s.ResolveZone("billing"); !errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("unknown zone: err = %v, want ErrZoneNotFound", err)
+	}
+}
+
+func TestZoneScope_Contains(t *testing.T) {
+	zone := &ZoneScope{Files: []string{"api/login.go", "web/session/", "token.go"}, Modules: []string{"api"}}
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"carto/proj/api/layer:atoms/api/login.go:12", true},
+		{"carto/proj/api/layer:atoms/api/find.go:3", false},
+		{"carto/proj/web/layer:atoms/web/session/store.go:1", true},
+		{"carto/proj/api/layer:atoms/internal/auth/token.go:5", true}, // suffix match
+		{"carto/proj/api/layer:wiring", true},
+		{"carto/proj/api/layer:atoms", true},
+		{"carto/proj/web/layer:wiring", false},
+		{"external/notes", false},
+	}
+	for _, tt := range tests {
+		if got := zone.Contains("", SearchResult{Source: tt.source}); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ServiceName is the service.name resource attribute of exported spans.
+const ServiceName = "carto"
+
+// OTLPExporter posts spans to an OpenTelemetry collector's OTLP/HTTP
+// traces endpoint as JSON.
+type OTLPExporter struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewOTLPExporter creates an exporter for the collector at endpoint, e.g.
+// "http://localhost:4318". As with OTEL_EXPORTER_OTLP_ENDPOINT, an
+// endpoint without a path gets the standard /v1/traces.
+func NewOTLPExporter(endpoint string) (*OTLPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("tracing: invalid OTLP endpoint %q: want http(s)://host[:port][/path]", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return &OTLPExporter{url: u.String(), httpClient: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// Export posts one ExportTraceServiceRequest holding spans.
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(otlpRequest(spans))
+	if err != nil {
+		return fmt.Errorf("tracing: encode spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("tracing: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("tracing: export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("tracing: export spans: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// The OTLP/HTTP JSON encoding of the trace export request. IDs are hex,
+// and 64-bit integers are decimal strings.
+type (
+	otlpKeyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+)
+
+// OTLP span kind and status codes.
+const (
+	spanKindInternal = 1
+	statusOK         = 1
+	statusError      = 2
+)
+
+func otlpRequest(spans []SpanData) map[string]any {
+	out := make([]otlpSpan, len(spans))
+	for i, d := range spans {
+		s := otlpSpan{
+			TraceID:           hex.EncodeToString(d.TraceID[:]),
+			SpanID:            hex.EncodeToString(d.SpanID[:]),
+			Name:              d.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(d.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(d.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(d.Attrs),
+			Status:            otlpStatus{Code: statusOK},
+		}
+		if d.ParentID != [8]byte{} {
+			s.ParentSpanID = hex.EncodeToString(d.ParentID[:])
+		}
+		if d.Err != "" {
+			s.Status = otlpStatus{Code: statusError, Message: d.Err}
+		}
+		out[i] = s
+	}
+	return map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": otlpAttributes([]Attr{String("service.name", ServiceName)}),
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/divyekant/carto"},
+				"spans": out,
+			}},
+		}},
+	}
+}
+
+func otlpAttributes(attrs []Attr) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var v map[string]any
+		switch x := a.Value.(type) {
+		case string:
+			v = map[string]any{"stringValue": x}
+		case bool:
+			v = map[string]any{"boolValue": x}
+		case int64:
+			v = map[string]any{"intValue": strconv.FormatInt(x, 10)}
+		case float64:
+			v = map[string]any{"doubleValue": x}
+		default:
+			v = map[string]any{"stringValue": fmt.Sprint(x)}
+		}
+		out = append(out, otlpKeyValue{Key: a.Key, Value: v})
+	}
+	return out
+}
+
+// Setup turns tracing on, exporting to the OTLP collector at endpoint, and
+// returns the func that flushes the remaining spans and turns it off
+// again. An empty endpoint leaves tracing off.
+func Setup(endpoint string) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exp, err := NewOTLPExporter(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	t := NewTracer(exp)
+	SetTracer(t)
+	return func(ctx context.Context) error {
+		SetTracer(nil)
+		return t.Shutdown(ctx)
+	}, nil
+}
@@ -0,0 +1,223 @@
+// Package tracing is a small, dependency-free tracer that records
+// OpenTelemetry-compatible spans and exports them over OTLP/HTTP with JSON
+// encoding. It covers exactly what Carto traces, index run phases, modules
+// and LLM calls; it is not a general-purpose SDK.
+//
+// Until SetTracer installs a Tracer, Start returns a nil *Span whose
+// methods do nothing, so instrumented code costs one atomic load per span.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Attr is a span attribute. Value is a string, bool, int64 or float64.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attr { return Attr{key, value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attr { return Attr{key, int64(value)} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attr { return Attr{key, value} }
+
+// Float returns a floating-point attribute.
+func Float(key string, value float64) Attr { return Attr{key, value} }
+
+// SpanData is a finished span as handed to an Exporter.
+type SpanData struct {
+	TraceID  [16]byte
+	SpanID   [8]byte
+	ParentID [8]byte // zero for a root span
+	Name     string
+	Start    time.Time
+	End      time.Time
+	Attrs    []Attr
+	Err      string // the error recorded on the span, if any
+}
+
+// Attr returns the value of the attribute key, or nil if the span has none.
+func (d SpanData) Attr(key string) any {
+	for _, a := range d.Attrs {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return nil
+}
+
+// Exporter sends finished spans somewhere.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// batchSize is how many finished spans a Tracer buffers before exporting
+// them.
+const batchSize = 256
+
+// Tracer buffers finished spans and exports them in batches.
+type Tracer struct {
+	exporter Exporter
+
+	mu      sync.Mutex
+	pending []SpanData
+	wg      sync.WaitGroup
+	errs    []error
+}
+
+// NewTracer creates a Tracer that exports to exp.
+func NewTracer(exp Exporter) *Tracer {
+	return &Tracer{exporter: exp}
+}
+
+// Shutdown exports the buffered spans, waits for exports in progress and
+// returns the first export error since the last Shutdown.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+	if len(batch) > 0 {
+		t.export(ctx, batch)
+	}
+	t.wg.Wait()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var err error
+	if len(t.errs) > 0 {
+		err = t.errs[0]
+	}
+	t.errs = nil
+	return err
+}
+
+func (t *Tracer) finish(d SpanData) {
+	t.mu.Lock()
+	t.pending = append(t.pending, d)
+	var batch []SpanData
+	if len(t.pending) >= batchSize {
+		batch, t.pending = t.pending, nil
+	}
+	t.mu.Unlock()
+	if batch != nil {
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			t.export(context.Background(), batch)
+		}()
+	}
+}
+
+func (t *Tracer) export(ctx context.Context, batch []SpanData) {
+	if err := t.exporter.Export(ctx, batch); err != nil {
+		t.mu.Lock()
+		t.errs = append(t.errs, err)
+		t.mu.Unlock()
+	}
+}
+
+var current atomic.Pointer[Tracer]
+
+// SetTracer installs t as the tracer Start records spans with. Nil turns
+// tracing off.
+func SetTracer(t *Tracer) {
+	current.Store(t)
+}
+
+// Span is a span in progress. A nil *Span, returned while tracing is off,
+// is valid and ignores every call.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+type spanKey struct{}
+
+// Start starts a span named name, the child of the span in ctx if any,
+// and returns a context carrying it.
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	t := current.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{tracer: t, data: SpanData{Name: name, Start: time.Now(), Attrs: attrs}}
+	if parent, _ := ctx.Value(spanKey{}).(*Span); parent != nil {
+		s.data.TraceID = parent.data.TraceID
+		s.data.ParentID = parent.data.SpanID
+	} else {
+		rand.Read(s.data.TraceID[:])
+	}
+	rand.Read(s.data.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Attrs = append(s.data.Attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed with err. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Err = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span. Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	d := s.data
+	s.mu.Unlock()
+	s.tracer.finish(d)
+}
+
+// InMemoryExporter keeps exported spans in memory, for tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// Export appends spans to those kept.
+func (e *InMemoryExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mu.Unlock()
+	return nil
+}
+
+// Spans returns the spans exported so far, in the order they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStart_NoTracerIsNoop(t *testing.T) {
+	SetTracer(nil)
+	ctx, span := Start(context.Background(), "idle", String("k", "v"))
+	if span != nil {
+		t.Fatalf("Start without a tracer returned a span: %+v", span)
+	}
+	if ctx != context.Background() {
+		t.Error("Start without a tracer should return ctx unchanged")
+	}
+	// A nil span ignores every call.
+	span.SetAttributes(Int("n", 1))
+	span.RecordError(errors.New("boom"))
+	span.End()
+}
+
+func TestOTLPExporter_PostsSpanHierarchy(t *testing.T) {
+	var gotPath, gotType string
+	var body struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []otlpKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotType = r.URL.Path, r.Header.Get("Content-Type")
+		raw, _ := io.ReadAll(r.Body)
+		json.Unmarshal(raw, &body)
+	}))
+	defer srv.Close()
+
+	shutdown, err := Setup(srv.URL)
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	ctx, parent := Start(context.Background(), "index", String("carto.project", "demo"))
+	_, child := Start(ctx, "llm.complete", Int("llm.input_tokens", 12))
+	child.RecordError(errors.New("rate limited"))
+	child.End()
+	parent.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if gotPath != "/v1/traces" || gotType != "application/json" {
+		t.Errorf("posted %s to %s, want application/json to /v1/traces", gotType, gotPath)
+	}
+	if len(body.ResourceSpans) != 1 || len(body.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected request shape: %+v", body)
+	}
+	if attrs := body.ResourceSpans[0].Resource.Attributes; len(attrs) != 1 || attrs[0].Value["stringValue"] != ServiceName {
+		t.Errorf("resource attributes = %+v, want service.name=%s", attrs, ServiceName)
+	}
+	spans := body.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+	c, p := spans[0], spans[1] // in the order they ended
+	if c.TraceID != p.TraceID || c.ParentSpanID != p.SpanID || p.ParentSpanID != "" {
+		t.Errorf("child %s/%s parent %s, want it under root %s/%s", c.TraceID, c.ParentSpanID, c.SpanID, p.TraceID, p.SpanID)
+	}
+	if len(c.Attributes) != 1 || c.Attributes[0].Value["intValue"] != "12" {
+		t.Errorf("child attributes = %+v, want llm.input_tokens as intValue \"12\"", c.Attributes)
+	}
+	if c.Status.Code != statusError || c.Status.Message != "rate limited" || p.Status.Code != statusOK {
+		t.Errorf("statuses = %+v / %+v, want error on the child only", c.Status, p.Status)
+	}
+
+	// Tracing is off again after shutdown.
+	if _, span := Start(context.Background(), "after"); span != nil {
+		t.Error("Start after shutdown returned a span")
+	}
+}
+
+func TestNewOTLPExporter_RejectsBadEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"localhost:4318", "ftp://collector", "http://"} {
+		if _, err := NewOTLPExporter(endpoint); err == nil {
+			t.Errorf("NewOTLPExporter(%q) accepted a bad endpoint", endpoint)
+		}
+	}
+}
@@ -46,6 +46,7 @@ func Index(path string, opts IndexOptions) (*IndexResult, error) {
 		DeepModel:     cfg.DeepModel,
 		MaxConcurrent: cfg.MaxConcurrent,
 		BaseURL:       cfg.LLMBaseURL,
+		ExtraHeaders:  cfg.LLMHeaders,
 	})
 
 	memoriesClient := storage.NewMemoriesClient(cfg.MemoriesURL, cfg.MemoriesKey)
@@ -59,14 +60,15 @@ func Index(path string, opts IndexOptions) (*IndexResult, error) {
 	}
 
 	result, err := pipeline.Run(pipeline.Config{
-		ProjectName:    projectName,
-		RootPath:       path,
-		LLMClient:      llmClient,
-		MemoriesClient: memoriesClient,
-		SourceRegistry: registry,
-		MaxWorkers:     cfg.MaxConcurrent,
-		Incremental:    opts.Incremental,
-		ModuleFilter:   opts.Module,
+		ProjectName:       projectName,
+		RootPath:          path,
+		LLMClient:         llmClient,
+		MemoriesClient:    memoriesClient,
+		SourceRegistry:    registry,
+		MaxWorkers:        cfg.MaxConcurrent,
+		Incremental:       opts.Incremental,
+		ModuleFilter:      opts.Module,
+		MemoriesNamespace: cfg.MemoriesNamespace,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("carto: index: %w", err)
@@ -105,7 +107,7 @@ func Query(text string, opts QueryOptions) ([]QueryResult, error) {
 
 	searchOpts := storage.SearchOptions{K: opts.K, Hybrid: true}
 	if opts.Project != "" {
-		searchOpts.SourcePrefix = fmt.Sprintf("carto/%s/", opts.Project)
+		searchOpts.SourcePrefix = storage.ProjectPrefix(cfg.MemoriesNamespace, opts.Project)
 		searchOpts.K = opts.K * 3
 	}
 
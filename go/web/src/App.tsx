@@ -10,6 +10,7 @@ import Query from './pages/Query'
 import Settings from './pages/Settings'
 import ProjectDetail from './pages/ProjectDetail'
 import About from './pages/About'
+import { BASE_PATH } from './lib/api'
 
 function App() {
   return (
@@ -17,7 +18,7 @@ function App() {
       {/* AuthGuard gates the entire app when CARTO_SERVER_TOKEN is set on the server.
           When auth is not configured, it renders children immediately with no overhead. */}
       <AuthGuard>
-        <BrowserRouter>
+        <BrowserRouter basename={BASE_PATH || undefined}>
           {/* Top-level ErrorBoundary prevents unhandled React errors from
               leaving users with a blank white screen. */}
           <ErrorBoundary>
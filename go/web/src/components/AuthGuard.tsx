@@ -1,6 +1,7 @@
 import { useState, useEffect, type ReactNode } from 'react'
 import { Input } from '@/components/ui/input'
 import { Button } from '@/components/ui/button'
+import { API_BASE } from '@/lib/api'
 
 interface AuthGuardProps {
   children: ReactNode
@@ -33,7 +34,7 @@ export function AuthGuard({ children }: AuthGuardProps) {
         // Try a protected endpoint with the stored token (if any).
         // /api/health bypasses auth so we probe /api/projects instead.
         const storedToken = localStorage.getItem('carto_token') ?? ''
-        const res = await fetch('/api/projects', {
+        const res = await fetch(`${API_BASE}/projects`, {
           headers: storedToken ? { Authorization: `Bearer ${storedToken}` } : {},
         })
         if (res.status === 401) {
@@ -62,7 +63,7 @@ export function AuthGuard({ children }: AuthGuardProps) {
     setError('')
     try {
       // Validate the token against a protected endpoint.
-      const res = await fetch('/api/projects', {
+      const res = await fetch(`${API_BASE}/projects`, {
         headers: { Authorization: `Bearer ${token}` },
       })
       if (res.ok || res.status !== 401) {
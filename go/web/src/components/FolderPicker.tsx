@@ -1,5 +1,6 @@
 import { useEffect, useState } from 'react'
 import { Button } from '@/components/ui/button'
+import { API_BASE } from '@/lib/api'
 
 interface BrowseResult {
   current: string
@@ -21,7 +22,7 @@ export function FolderPicker({ value, onChange }: FolderPickerProps) {
   function browse(path: string) {
     setLoading(true)
     const params = path ? `?path=${encodeURIComponent(path)}` : ''
-    fetch(`/api/browse${params}`)
+    fetch(`${API_BASE}/browse${params}`)
       .then(r => r.json())
       .then((result: BrowseResult) => {
         setData(result)
@@ -2,6 +2,7 @@ import { useState, useEffect } from 'react'
 import { NavLink, Outlet } from 'react-router-dom'
 import { cn } from '@/lib/utils'
 import { useTheme } from './ThemeProvider'
+import { API_BASE } from '@/lib/api'
 
 const navItems = [
   { to: '/', label: 'Dashboard', icon: '◫' },
@@ -130,7 +131,7 @@ export function Layout() {
   const [health, setHealth] = useState<{ memories_healthy: boolean } | null>(null)
 
   useEffect(() => {
-    fetch('/api/health').then(r => r.json()).then(setHealth).catch(() => {})
+    fetch(`${API_BASE}/health`).then(r => r.json()).then(setHealth).catch(() => {})
   }, [])
 
   return (
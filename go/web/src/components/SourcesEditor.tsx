@@ -5,6 +5,7 @@ import { Input } from '@/components/ui/input'
 import { Label } from '@/components/ui/label'
 import { Badge } from '@/components/ui/badge'
 import { Switch } from '@/components/ui/switch'
+import { API_BASE } from '@/lib/api'
 
 interface SourceDef {
   key: string
@@ -78,7 +79,7 @@ export function SourcesEditor({ projectName }: SourcesEditorProps) {
   const [saving, setSaving] = useState(false)
 
   useEffect(() => {
-    fetch(`/api/projects/${encodeURIComponent(projectName)}/sources`)
+    fetch(`${API_BASE}/projects/${encodeURIComponent(projectName)}/sources`)
       .then(r => r.json())
       .then(data => {
         setSources(data.sources || {})
@@ -124,7 +125,7 @@ export function SourcesEditor({ projectName }: SourcesEditorProps) {
         }
       }
 
-      const res = await fetch(`/api/projects/${encodeURIComponent(projectName)}/sources`, {
+      const res = await fetch(`${API_BASE}/projects/${encodeURIComponent(projectName)}/sources`, {
         method: 'PUT',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ sources: payload }),
@@ -11,8 +11,16 @@
  *   const result = await apiFetch('/projects/index', { method: 'POST', body: JSON.stringify(payload) })
  */
 
+/**
+ * Path prefix the app is served under, e.g. '/carto' behind a reverse proxy.
+ * `carto serve --base-path` injects it into index.html as a meta tag; it is
+ * empty when the app is served from the root.
+ */
+export const BASE_PATH =
+  document.querySelector<HTMLMetaElement>('meta[name="carto-base-path"]')?.content ?? ''
+
 /** Base URL prefix for all API requests. Change here to update all callers. */
-export const API_BASE = '/api'
+export const API_BASE = `${BASE_PATH}/api`
 
 /** Typed error thrown by apiFetch when the server returns a non-OK status. */
 export class ApiError extends Error {
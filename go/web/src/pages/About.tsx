@@ -6,6 +6,7 @@
 // canonical constants (version, features, etc.).
 
 import { useEffect, useState } from 'react'
+import { API_BASE } from '@/lib/api'
 
 interface ColorEntry {
   name: string
@@ -86,7 +87,7 @@ export default function About() {
   const [data, setData] = useState<AboutData>(FALLBACK)
 
   useEffect(() => {
-    fetch('/api/about')
+    fetch(`${API_BASE}/about`)
       .then((r) => r.json())
       .then((json: AboutData) => setData(json))
       .catch(() => {
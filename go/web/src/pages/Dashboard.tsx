@@ -12,6 +12,7 @@ import {
 } from '@/components/ui/table'
 import { Section, StatCard } from '@/components/Section'
 import { cn } from '@/lib/utils'
+import { API_BASE } from '@/lib/api'
 
 interface Project {
   name: string
@@ -76,9 +77,9 @@ export default function Dashboard() {
 
   useEffect(() => {
     Promise.all([
-      fetch('/api/projects').then(r => r.json()),
-      fetch('/api/health').then(r => r.json()),
-      fetch('/api/projects/runs').then(r => r.json()).catch(() => []),
+      fetch(`${API_BASE}/projects`).then(r => r.json()),
+      fetch(`${API_BASE}/health`).then(r => r.json()),
+      fetch(`${API_BASE}/projects/runs`).then(r => r.json()).catch(() => []),
     ]).then(([projData, healthData, runsData]) => {
       setProjects(Array.isArray(projData) ? projData : projData.projects || [])
       setHealth(healthData)
@@ -90,7 +91,7 @@ export default function Dashboard() {
     }).catch(console.error)
       .finally(() => setLoading(false))
 
-    fetch('/api/stats').then(r => r.json()).then(setStats).catch(() => {})
+    fetch(`${API_BASE}/stats`).then(r => r.json()).then(setStats).catch(() => {})
   }, [])
 
   return (
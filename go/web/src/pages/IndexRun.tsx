@@ -10,6 +10,7 @@ import { Switch } from '@/components/ui/switch'
 import { ProgressBar } from '@/components/ProgressBar'
 import { Section } from '@/components/Section'
 import { cn } from '@/lib/utils'
+import { API_BASE } from '@/lib/api'
 
 type PageState = 'idle' | 'starting' | 'running' | 'complete' | 'error' | 'stopped'
 
@@ -79,7 +80,7 @@ export default function IndexRun() {
   }, [searchParams])
 
   useEffect(() => {
-    fetch('/api/projects/runs')
+    fetch(`${API_BASE}/projects/runs`)
       .then(r => r.json())
       .then((runs: Array<{ project: string; status: string; result?: CompleteData; error?: string }>) => {
         if (Array.isArray(runs)) setRecentRuns(runs)
@@ -96,7 +97,7 @@ export default function IndexRun() {
           } else if (lastRun.status === 'error' && lastRun.error) {
             setErrorMsg(lastRun.error)
             setPageState('error')
-          } else if (lastRun.status === 'stopped') {
+          } else if (lastRun.status === 'stopped' || lastRun.status === 'cancelled') {
             setPageState('stopped')
           }
         }
@@ -135,7 +136,7 @@ export default function IndexRun() {
       }
       if (module.trim()) body.module = module.trim()
 
-      const res = await fetch('/api/projects/index', {
+      const res = await fetch(`${API_BASE}/projects/index`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(body),
@@ -160,7 +161,7 @@ export default function IndexRun() {
   }
 
   function connectSSE(projectName: string) {
-    const es = new EventSource(`/api/projects/${encodeURIComponent(projectName)}/progress`)
+    const es = new EventSource(`${API_BASE}/projects/${encodeURIComponent(projectName)}/progress`)
     eventSourceRef.current = es
 
     es.addEventListener('progress', (e) => {
@@ -203,6 +204,14 @@ export default function IndexRun() {
       es.close()
     })
 
+    es.addEventListener('cancelled', () => {
+      setLogs(prev => [...prev, { level: 'warn', message: 'Indexing cancelled', timestamp: Date.now() }])
+      setPageState('stopped')
+      setStopping(false)
+      toast('Indexing cancelled')
+      es.close()
+    })
+
     es.onerror = () => {
       if (stateRef.current === 'running') {
         setErrorMsg('Connection to progress stream lost')
@@ -217,7 +226,7 @@ export default function IndexRun() {
     if (!projectName) return
     setStopping(true)
     try {
-      await fetch(`/api/projects/${encodeURIComponent(projectName)}/stop`, { method: 'POST' })
+      await fetch(`${API_BASE}/projects/${encodeURIComponent(projectName)}/stop`, { method: 'POST' })
     } catch {
       setStopping(false)
       toast.error('Failed to stop indexing')
@@ -8,6 +8,7 @@ import { Label } from '@/components/ui/label'
 import { Switch } from '@/components/ui/switch'
 import { SourcesEditor } from '@/components/SourcesEditor'
 import { ProgressBar } from '@/components/ProgressBar'
+import { API_BASE } from '@/lib/api'
 
 interface Project {
   name: string
@@ -72,7 +73,7 @@ export default function ProjectDetail() {
   }, [])
 
   useEffect(() => {
-    fetch('/api/projects')
+    fetch(`${API_BASE}/projects`)
       .then(r => r.json())
       .then((data: Project[]) => {
         const projects = Array.isArray(data) ? data : (data as any).projects || []
@@ -85,7 +86,7 @@ export default function ProjectDetail() {
 
   useEffect(() => {
     if (!name) return
-    fetch('/api/projects/runs')
+    fetch(`${API_BASE}/projects/runs`)
       .then(r => r.json())
       .then((runs: Array<{ project: string; status: string; result?: CompleteData; error?: string }>) => {
         const myRun = runs.find(r => r.project === name)
@@ -100,7 +101,7 @@ export default function ProjectDetail() {
         } else if (myRun.status === 'error' && myRun.error) {
           setErrorMsg(myRun.error)
           setPageState('error')
-        } else if (myRun.status === 'stopped') {
+        } else if (myRun.status === 'stopped' || myRun.status === 'cancelled') {
           setPageState('stopped')
         }
       })
@@ -111,7 +112,7 @@ export default function ProjectDetail() {
   function connectSSE(projectName: string) {
     // Close any existing connection to prevent duplicate listeners
     eventSourceRef.current?.close()
-    const es = new EventSource(`/api/projects/${encodeURIComponent(projectName)}/progress`)
+    const es = new EventSource(`${API_BASE}/projects/${encodeURIComponent(projectName)}/progress`)
     eventSourceRef.current = es
 
     es.addEventListener('progress', (e) => {
@@ -154,6 +155,14 @@ export default function ProjectDetail() {
       es.close()
     })
 
+    es.addEventListener('cancelled', () => {
+      setLogs(prev => [...prev, { level: 'warn', message: 'Indexing cancelled', timestamp: Date.now() }])
+      setPageState('stopped')
+      setStopping(false)
+      toast('Indexing cancelled')
+      es.close()
+    })
+
     es.onerror = () => {
       if (stateRef.current === 'running') {
         setErrorMsg('Connection to progress stream lost')
@@ -168,7 +177,7 @@ export default function ProjectDetail() {
     if (!name) return
     setStopping(true)
     try {
-      await fetch(`/api/projects/${encodeURIComponent(name)}/stop`, { method: 'POST' })
+      await fetch(`${API_BASE}/projects/${encodeURIComponent(name)}/stop`, { method: 'POST' })
     } catch {
       setStopping(false)
       toast.error('Failed to stop indexing')
@@ -191,7 +200,7 @@ export default function ProjectDetail() {
       const trimmedModule = moduleFilter.trim()
       if (trimmedModule) body.module = trimmedModule
 
-      const res = await fetch('/api/projects/index', {
+      const res = await fetch(`${API_BASE}/projects/index`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(body),
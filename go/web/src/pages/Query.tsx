@@ -12,6 +12,7 @@ import {
 import { cn } from '@/lib/utils'
 import { QueryResult } from '@/components/QueryResult'
 import { Section } from '@/components/Section'
+import { API_BASE } from '@/lib/api'
 
 interface Project {
   name: string
@@ -46,7 +47,7 @@ export default function Query() {
   const [visibleCount, setVisibleCount] = useState(PAGE_SIZE)
 
   useEffect(() => {
-    fetch('/api/projects')
+    fetch(`${API_BASE}/projects`)
       .then(r => r.json())
       .then(data => {
         const projs = (Array.isArray(data) ? data : data.projects || []) as Project[]
@@ -64,7 +65,7 @@ export default function Query() {
     setSearched(false)
 
     try {
-      const res = await fetch('/api/query', {
+      const res = await fetch(`${API_BASE}/query`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ text: text.trim(), project, tier, k }),
@@ -13,6 +13,7 @@ import {
 } from '@/components/ui/select'
 import { Section } from '@/components/Section'
 import { cn } from '@/lib/utils'
+import { API_BASE } from '@/lib/api'
 
 // Matches the Go configResponse JSON shape exactly
 interface Config {
@@ -369,8 +370,8 @@ export default function Settings() {
 
   useEffect(() => {
     Promise.all([
-      fetch('/api/config').then(r => r.json()),
-      fetch('/api/health').then(r => r.json()),
+      fetch(`${API_BASE}/config`).then(r => r.json()),
+      fetch(`${API_BASE}/health`).then(r => r.json()),
     ]).then(([configData, healthData]) => {
       const memoriesUrl = configData.memories_url?.replace('host.docker.internal', 'localhost') || configData.memories_url
       setConfig({ ...configData, memories_url: memoriesUrl })
@@ -445,7 +446,7 @@ export default function Settings() {
       if (config.notion_token && !config.notion_token.includes('****')) patch.notion_token = config.notion_token
       if (config.slack_token && !config.slack_token.includes('****')) patch.slack_token = config.slack_token
 
-      const res = await fetch('/api/config', {
+      const res = await fetch(`${API_BASE}/config`, {
         method: 'PATCH',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(patch),
@@ -469,7 +470,7 @@ export default function Settings() {
     setConnectionStatus('testing')
     setConnectionError(null)
     try {
-      const res = await fetch('/api/test-memories', {
+      const res = await fetch(`${API_BASE}/test-memories`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({